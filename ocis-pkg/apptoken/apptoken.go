@@ -0,0 +1,249 @@
+// Package apptoken provides the restrictions which can be attached to app tokens.
+// The restrictions are stored as an additional scope of the app password. The proxy
+// enforces the access and network restrictions, the resource restrictions are reva
+// token scopes which are enforced by the services accessing the storage.
+package apptoken
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/owncloud/reva/v2/pkg/auth/scope"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+)
+
+// ScopeKey is the key of the restrictions in the token scope of an app password.
+// It must not start with the name of a reva scope, otherwise reva would try to verify it.
+const ScopeKey = "apptoken:restrictions"
+
+// Access defines which operations an app token may be used for
+type Access string
+
+const (
+	// AccessFull allows all operations, this is the default
+	AccessFull Access = "full"
+	// AccessRead allows only reading operations
+	AccessRead Access = "read"
+	// AccessUpload allows only creating folders and uploading files
+	AccessUpload Access = "upload"
+)
+
+var (
+	_readMethods = []string{
+		http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND", "REPORT", "SEARCH",
+	}
+	_uploadMethods = []string{
+		http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodPost, http.MethodPatch, "MKCOL",
+	}
+)
+
+// Restrictions limit what an app token can be used for
+type Restrictions struct {
+	// Resources are the ids of the spaces or folders the token is restricted to
+	Resources []string `json:"resources,omitempty"`
+	// Access restricts the token to reading or uploading
+	Access Access `json:"access,omitempty"`
+	// Networks are the IP ranges in CIDR notation the token may be used from
+	Networks []string `json:"networks,omitempty"`
+}
+
+// IsZero returns true if the restrictions do not restrict anything
+func (r *Restrictions) IsZero() bool {
+	return r == nil || (len(r.Resources) == 0 && (r.Access == "" || r.Access == AccessFull) && len(r.Networks) == 0)
+}
+
+// Validate checks the syntax of the restrictions and normalizes single IP addresses to networks
+func (r *Restrictions) Validate() error {
+	switch r.Access {
+	case "", AccessFull, AccessRead, AccessUpload:
+	default:
+		return fmt.Errorf("invalid access '%s', must be one of '%s', '%s' or '%s'", r.Access, AccessFull, AccessRead, AccessUpload)
+	}
+
+	for _, res := range r.Resources {
+		id, err := storagespace.ParseID(res)
+		if err != nil || id.GetSpaceId() == "" {
+			return fmt.Errorf("invalid resource id '%s'", res)
+		}
+	}
+
+	for i, n := range r.Networks {
+		if !strings.Contains(n, "/") {
+			ip := net.ParseIP(n)
+			if ip == nil {
+				return fmt.Errorf("invalid ip address '%s'", n)
+			}
+			if ip.To4() != nil {
+				n += "/32"
+			} else {
+				n += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			return fmt.Errorf("invalid ip range '%s'", n)
+		}
+		r.Networks[i] = ipNet.String()
+	}
+
+	return nil
+}
+
+// AllowsMethod returns true if the http method may be used with the access of the restrictions
+func (r *Restrictions) AllowsMethod(method string) bool {
+	if r == nil {
+		return true
+	}
+	switch r.Access {
+	case AccessRead:
+		return containsMethod(_readMethods, method)
+	case AccessUpload:
+		return containsMethod(_uploadMethods, method)
+	default:
+		return true
+	}
+}
+
+// AllowsAddress returns true if the token may be used from the remote address, which may contain a port
+func (r *Restrictions) AllowsAddress(addr string) bool {
+	if r == nil || len(r.Networks) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range r.Networks {
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			continue
+		}
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scopes returns the token scope of an app password with the restrictions. Tokens without resource
+// restrictions get the owner scope. Tokens restricted to resources get a share scope for each resource
+// instead, reva only allows them to access the resources and the items below them.
+func Scopes(r *Restrictions) (map[string]*authpb.Scope, error) {
+	if r.IsZero() || len(r.Resources) == 0 {
+		scopes, err := scope.AddOwnerScope(map[string]*authpb.Scope{})
+		if err != nil {
+			return nil, err
+		}
+		return AddScope(r, scopes)
+	}
+
+	scopes := map[string]*authpb.Scope{}
+	for _, res := range r.Resources {
+		id, err := storagespace.ParseID(res)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource id '%s'", res)
+		}
+		if id.GetOpaqueId() == "" {
+			id.OpaqueId = id.GetSpaceId()
+		}
+		share := &collaboration.Share{
+			Id:         &collaboration.ShareId{OpaqueId: ScopeKey + ":" + res},
+			ResourceId: &id,
+		}
+		if scopes, err = scope.AddShareScope(share, r.role(), scopes); err != nil {
+			return nil, err
+		}
+	}
+	return AddScope(r, scopes)
+}
+
+// AddScope adds the restrictions to the token scope of an app password
+func AddScope(r *Restrictions, scopes map[string]*authpb.Scope) (map[string]*authpb.Scope, error) {
+	if r.IsZero() {
+		return scopes, nil
+	}
+
+	val, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// reva takes the roles of all scopes into account, the role must not grant more than the share scopes
+	role := authpb.Role_ROLE_OWNER
+	if len(r.Resources) > 0 || r.Access == AccessRead || r.Access == AccessUpload {
+		role = r.role()
+	}
+
+	if scopes == nil {
+		scopes = make(map[string]*authpb.Scope)
+	}
+	scopes[ScopeKey] = &authpb.Scope{
+		Resource: &types.OpaqueEntry{
+			Decoder: "json",
+			Value:   val,
+		},
+		Role: role,
+	}
+	return scopes, nil
+}
+
+// FromScopes reads the restrictions from a token scope, nil is returned if the scope has no restrictions
+func FromScopes(scopes map[string]*authpb.Scope) (*Restrictions, error) {
+	s, ok := scopes[ScopeKey]
+	if !ok {
+		return nil, nil
+	}
+
+	r := &Restrictions{}
+	if err := json.Unmarshal(s.GetResource().GetValue(), r); err != nil {
+		return nil, fmt.Errorf("invalid app token restrictions: %w", err)
+	}
+	return r, nil
+}
+
+// FromToken reads the restrictions from the scope of a reva access token.
+// The signature of the token is not verified, the token must come from a trusted source like the gateway.
+func FromToken(token string) (*Restrictions, error) {
+	claims := &struct {
+		jwt.RegisteredClaims
+		Scope map[string]*authpb.Scope `json:"scope"`
+	}{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return nil, err
+	}
+	return FromScopes(claims.Scope)
+}
+
+// role returns the reva role of the access
+func (r *Restrictions) role() authpb.Role {
+	switch r.Access {
+	case AccessRead:
+		return authpb.Role_ROLE_VIEWER
+	case AccessUpload:
+		return authpb.Role_ROLE_UPLOADER
+	default:
+		return authpb.Role_ROLE_EDITOR
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
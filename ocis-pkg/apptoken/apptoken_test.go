@@ -0,0 +1,150 @@
+package apptoken_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
+	"github.com/owncloud/reva/v2/pkg/auth/scope"
+	"github.com/test-go/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		restrictions apptoken.Restrictions
+		wantErr      bool
+		wantNetworks []string
+	}{
+		{name: "empty", restrictions: apptoken.Restrictions{}},
+		{name: "space and folder", restrictions: apptoken.Restrictions{Resources: []string{"storage$space", "storage$space!folder"}, Access: apptoken.AccessRead}},
+		{name: "networks", restrictions: apptoken.Restrictions{Networks: []string{"10.0.0.7", "192.168.1.20/24", "2001:db8::1"}}, wantNetworks: []string{"10.0.0.7/32", "192.168.1.0/24", "2001:db8::1/128"}},
+		{name: "invalid access", restrictions: apptoken.Restrictions{Access: "delete"}, wantErr: true},
+		{name: "invalid resource", restrictions: apptoken.Restrictions{Resources: []string{""}}, wantErr: true},
+		{name: "invalid network", restrictions: apptoken.Restrictions{Networks: []string{"10.0.0.0/33"}}, wantErr: true},
+		{name: "invalid ip", restrictions: apptoken.Restrictions{Networks: []string{"localhost"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.restrictions.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantNetworks != nil {
+				require.Equal(t, tt.wantNetworks, tt.restrictions.Networks)
+			}
+		})
+	}
+}
+
+func TestAllowsMethod(t *testing.T) {
+	read := &apptoken.Restrictions{Access: apptoken.AccessRead}
+	upload := &apptoken.Restrictions{Access: apptoken.AccessUpload}
+	full := &apptoken.Restrictions{Resources: []string{"storage$space"}}
+
+	require.True(t, read.AllowsMethod("PROPFIND"))
+	require.True(t, read.AllowsMethod("GET"))
+	require.False(t, read.AllowsMethod("PUT"))
+	require.False(t, read.AllowsMethod("DELETE"))
+
+	require.True(t, upload.AllowsMethod("PUT"))
+	require.True(t, upload.AllowsMethod("MKCOL"))
+	require.False(t, upload.AllowsMethod("GET"))
+	require.False(t, upload.AllowsMethod("DELETE"))
+
+	require.True(t, full.AllowsMethod("DELETE"))
+
+	var none *apptoken.Restrictions
+	require.True(t, none.AllowsMethod("DELETE"))
+}
+
+func TestAllowsAddress(t *testing.T) {
+	r := &apptoken.Restrictions{Networks: []string{"10.0.0.0/8", "2001:db8::/32"}}
+	require.NoError(t, r.Validate())
+
+	require.True(t, r.AllowsAddress("10.1.2.3:43210"))
+	require.True(t, r.AllowsAddress("10.1.2.3"))
+	require.True(t, r.AllowsAddress("[2001:db8::5]:443"))
+	require.False(t, r.AllowsAddress("192.168.1.1:43210"))
+	require.False(t, r.AllowsAddress("invalid"))
+
+	require.True(t, (&apptoken.Restrictions{}).AllowsAddress("192.168.1.1:43210"))
+}
+
+func TestScopes(t *testing.T) {
+	scopes, err := scope.AddOwnerScope(nil)
+	require.NoError(t, err)
+
+	scopes, err = apptoken.AddScope(&apptoken.Restrictions{}, scopes)
+	require.NoError(t, err)
+	require.Len(t, scopes, 1, "empty restrictions must not be added")
+
+	r, err := apptoken.FromScopes(scopes)
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	want := &apptoken.Restrictions{Resources: []string{"storage$space"}, Access: apptoken.AccessUpload, Networks: []string{"10.0.0.0/8"}}
+	scopes, err = apptoken.AddScope(want, scopes)
+	require.NoError(t, err)
+	require.Equal(t, authpb.Role_ROLE_UPLOADER, scopes[apptoken.ScopeKey].GetRole())
+
+	r, err = apptoken.FromScopes(scopes)
+	require.NoError(t, err)
+	require.Equal(t, want, r)
+
+	// the restrictions survive the encoding in a reva token
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, struct {
+		jwt.RegisteredClaims
+		Scope map[string]*authpb.Scope `json:"scope"`
+	}{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+		Scope:            scopes,
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	r, err = apptoken.FromToken(token)
+	require.NoError(t, err)
+	require.Equal(t, want, r)
+
+	_, err = apptoken.FromToken("not a token")
+	require.Error(t, err)
+}
+
+func TestResourceScopes(t *testing.T) {
+	scopes, err := apptoken.Scopes(&apptoken.Restrictions{Access: apptoken.AccessRead})
+	require.NoError(t, err)
+	require.Contains(t, scopes, "user", "tokens without resources get the owner scope")
+
+	want := &apptoken.Restrictions{Resources: []string{"storage$space", "storage$space!folder"}, Access: apptoken.AccessRead}
+	scopes, err = apptoken.Scopes(want)
+	require.NoError(t, err)
+	require.Len(t, scopes, 3)
+	for k, s := range scopes {
+		require.NotEqual(t, "user", k, "tokens restricted to resources must not get the owner scope")
+		require.Equal(t, authpb.Role_ROLE_VIEWER, s.GetRole())
+	}
+
+	// the share scopes only allow to access the resources
+	for _, tt := range []struct {
+		ref  *provider.Reference
+		want bool
+	}{
+		{ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "space"}}, want: true},
+		{ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "folder"}}, want: true},
+		{ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "other", OpaqueId: "other"}}, want: false},
+	} {
+		ok, err := scope.VerifyScope(context.Background(), scopes, &provider.StatRequest{Ref: tt.ref})
+		require.NoError(t, err)
+		require.Equal(t, tt.want, ok, tt.ref.String())
+	}
+
+	r, err := apptoken.FromScopes(scopes)
+	require.NoError(t, err)
+	require.Equal(t, want, r)
+}
@@ -0,0 +1,229 @@
+// Package casstore writes the records of a go-micro store conditionally, a record is only updated if it was not
+// changed since it was read. Services use it for records which are updated by all of their instances.
+package casstore
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	revastore "github.com/owncloud/reva/v2/pkg/store"
+	microstore "go-micro.dev/v4/store"
+)
+
+// TypeNatsJSKV is the store type which supports conditional writes across instances
+const TypeNatsJSKV = "nats-js-kv"
+
+var (
+	// ErrNotFound is returned when a record does not exist
+	ErrNotFound = microstore.ErrNotFound
+	// ErrConflict is returned when a record was created, changed or removed since it was read
+	ErrConflict = errors.New("record was changed concurrently")
+)
+
+// Store reads records with their revision and only writes them if the revision did not change
+type Store interface {
+	// Read returns the value of a record and its revision
+	Read(key string) ([]byte, uint64, error)
+	// Create writes a record which must not exist yet
+	Create(key string, value []byte) error
+	// Update writes a record which must still have the given revision
+	Update(key string, value []byte, revision uint64) error
+	// Put writes a record regardless of its revision
+	Put(key string, value []byte) error
+}
+
+// Options configure the store, they have to match the options of the go-micro store of the records
+type Options struct {
+	// Store is the type of the store, only the 'nats-js-kv' store checks the revisions on the server
+	Store                string
+	Nodes                []string
+	Database             string
+	Table                string
+	TTL                  time.Duration
+	AuthUsername         string
+	AuthPassword         string
+	EnableTLS            bool
+	TLSInsecure          bool
+	TLSRootCACertificate string
+	// ConnectionName is the name of the connection to the nats server
+	ConnectionName string
+}
+
+// New returns a store for the records of the given go-micro store. The records of 'nats-js-kv' stores are written
+// directly to the nats key value bucket, the revisions are checked by the nats server. The records of other stores
+// are written through the go-micro store, their revisions are only checked in this process, they can't be updated
+// by multiple instances.
+func New(st microstore.Store, o Options) (Store, error) {
+	if o.Store != TypeNatsJSKV {
+		return &microStore{store: st, ttl: o.TTL}, nil
+	}
+
+	natsOptions := nats.GetDefaultOptions()
+	natsOptions.Name = o.ConnectionName
+	natsOptions.Servers = o.Nodes
+	natsOptions.User, natsOptions.Password = o.AuthUsername, o.AuthPassword
+	if o.EnableTLS {
+		natsOptions.Secure = true
+		natsOptions.TLSConfig = revastore.BuildNatsTLSConfig(o.TLSInsecure, o.TLSRootCACertificate)
+	}
+	conn, err := natsOptions.Connect()
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to nats: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.KeyValue(o.Database)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: o.Database, TTL: o.TTL})
+		if err != nil {
+			// the go-micro store might have created it in the meantime
+			kv, err = js.KeyValue(o.Database)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not open bucket '%s': %w", o.Database, err)
+	}
+	return &natsStore{kv: kv, table: o.Table}, nil
+}
+
+// envelope is the format the go-micro nats-js-kv store keeps the records in
+type envelope struct {
+	Key      string                 `json:"key"`
+	Data     []byte                 `json:"data"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// natsStore writes the records of a go-micro nats-js-kv store with the revisions of the nats key value bucket
+type natsStore struct {
+	kv    nats.KeyValue
+	table string
+}
+
+// key returns the key of a record in the bucket, the go-micro store prefixes it with the table and encodes it
+func (s *natsStore) key(key string) string {
+	if s.table != "" {
+		key = s.table + "_" + key
+	}
+	return base32.StdEncoding.EncodeToString([]byte(key))
+}
+
+func (s *natsStore) Read(key string) ([]byte, uint64, error) {
+	e, err := s.kv.Get(s.key(key))
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound):
+		return nil, 0, ErrNotFound
+	case err != nil:
+		return nil, 0, err
+	}
+	var env envelope
+	if err := json.Unmarshal(e.Value(), &env); err != nil {
+		return nil, 0, err
+	}
+	return env.Data, e.Revision(), nil
+}
+
+func (s *natsStore) Create(key string, value []byte) error {
+	b, err := json.Marshal(envelope{Key: key, Data: value})
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Create(s.key(key), b)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return fmt.Errorf("%w: %s", ErrConflict, err)
+	}
+	return err
+}
+
+func (s *natsStore) Update(key string, value []byte, revision uint64) error {
+	b, err := json.Marshal(envelope{Key: key, Data: value})
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Update(s.key(key), b, revision)
+	if errors.Is(err, nats.ErrKeyRevisionMismatch) {
+		return fmt.Errorf("%w: %s", ErrConflict, err)
+	}
+	return err
+}
+
+func (s *natsStore) Put(key string, value []byte) error {
+	b, err := json.Marshal(envelope{Key: key, Data: value})
+	if err != nil {
+		return err
+	}
+	_, err = s.kv.Put(s.key(key), b)
+	return err
+}
+
+// _revisionKey is the metadata of a go-micro record the revision is kept in
+const _revisionKey = "revision"
+
+// microStore keeps the revisions in the metadata of the go-micro records, the writes are serialized in the process
+type microStore struct {
+	store microstore.Store
+	ttl   time.Duration
+	lock  sync.Mutex
+}
+
+func (s *microStore) Read(key string) ([]byte, uint64, error) {
+	records, err := s.store.Read(key)
+	switch {
+	case err != nil:
+		return nil, 0, err
+	case len(records) == 0:
+		return nil, 0, ErrNotFound
+	}
+	return records[0].Value, revision(records[0]), nil
+}
+
+func (s *microStore) Create(key string, value []byte) error {
+	return s.write(key, value, func(current uint64) bool { return current == 0 })
+}
+
+func (s *microStore) Update(key string, value []byte, rev uint64) error {
+	return s.write(key, value, func(current uint64) bool { return current == rev })
+}
+
+func (s *microStore) Put(key string, value []byte) error {
+	return s.write(key, value, func(uint64) bool { return true })
+}
+
+// write writes a record if the check accepts its current revision, which is 0 if the record does not exist
+func (s *microStore) write(key string, value []byte, check func(current uint64) bool) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, current, err := s.Read(key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if !check(current) {
+		return ErrConflict
+	}
+	return s.store.Write(&microstore.Record{
+		Key:      key,
+		Value:    value,
+		Metadata: map[string]interface{}{_revisionKey: current + 1},
+		Expiry:   s.ttl,
+	})
+}
+
+// revision returns the revision of a record, stores which don't keep the metadata of the records only
+// know the revision 1
+func revision(r *microstore.Record) uint64 {
+	switch v := r.Metadata[_revisionKey].(type) {
+	case uint64:
+		return v
+	case float64:
+		return uint64(v)
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,71 @@
+package casstore
+
+import (
+	"testing"
+	"time"
+
+	natsjskv "github.com/go-micro/plugins/v4/store/nats-js-kv"
+	nserver "github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+)
+
+func testStore(t *testing.T, s Store) {
+	_, _, err := s.Read("a")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, s.Create("a", []byte("1")))
+	require.ErrorIs(t, s.Create("a", []byte("2")), ErrConflict)
+
+	value, rev, err := s.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+
+	require.NoError(t, s.Update("a", []byte("2"), rev))
+	require.ErrorIs(t, s.Update("a", []byte("3"), rev), ErrConflict, "the record was updated since it was read")
+
+	value, newRev, err := s.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(value))
+	assert.Greater(t, newRev, rev)
+
+	require.NoError(t, s.Put("a", []byte("4")))
+	value, _, err = s.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(value))
+}
+
+func TestMicroStore(t *testing.T) {
+	st := microstore.NewMemoryStore()
+	s, err := New(st, Options{Store: "memory"})
+	require.NoError(t, err)
+	testStore(t, s)
+
+	records, err := st.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(records[0].Value), "the records are kept in the go-micro store")
+}
+
+func TestNatsStore(t *testing.T) {
+	srv, err := nserver.NewServer(&nserver.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err)
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(10*time.Second))
+
+	s, err := New(nil, Options{Store: TypeNatsJSKV, Nodes: []string{srv.ClientURL()}, Database: "test", Table: "table"})
+	require.NoError(t, err)
+	testStore(t, s)
+
+	st := natsjskv.NewStore(microstore.Nodes(srv.ClientURL()), microstore.Database("test"), microstore.Table("table"), natsjskv.EncodeKeys())
+	records, err := st.Read("a")
+	require.NoError(t, err)
+	assert.Equal(t, "4", string(records[0].Value), "the records can be read by the go-micro store")
+
+	require.NoError(t, st.Write(&microstore.Record{Key: "b", Value: []byte("1")}))
+	value, rev, err := s.Read("b")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(value))
+	require.NoError(t, s.Update("b", []byte("2"), rev))
+}
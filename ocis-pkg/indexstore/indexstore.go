@@ -0,0 +1,88 @@
+// Package indexstore keeps entities in a go-micro store together with index records under key prefixes,
+// which allow listing the entities of e.g. a user or a space without reading all of them.
+package indexstore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	microstore "go-micro.dev/v4/store"
+)
+
+// ErrNotFound is returned when a key does not exist
+var ErrNotFound = errors.New("key not found")
+
+// ValidationError is returned for entities with invalid properties
+type ValidationError struct {
+	msg string
+}
+
+// NewValidationError returns a ValidationError with a formatted message
+func NewValidationError(format string, a ...any) ValidationError {
+	return ValidationError{msg: fmt.Sprintf(format, a...)}
+}
+
+func (e ValidationError) Error() string {
+	return e.msg
+}
+
+// Store reads and writes entities and their index records
+type Store struct {
+	store microstore.Store
+}
+
+// New returns a Store which keeps the records in the given store
+func New(store microstore.Store) *Store {
+	return &Store{store: store}
+}
+
+// Read returns the value of a key
+func (s *Store) Read(key string) ([]byte, error) {
+	records, err := s.store.Read(key)
+	switch {
+	case errors.Is(err, microstore.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	case len(records) == 0:
+		return nil, ErrNotFound
+	}
+	return records[0].Value, nil
+}
+
+// Write stores a value under a key. Records with a ttl expire in stores which support it, the others
+// have to be purged by the caller.
+func (s *Store) Write(key string, value []byte, ttl time.Duration) error {
+	return s.store.Write(&microstore.Record{Key: key, Value: value, Expiry: ttl})
+}
+
+// Delete removes keys, missing keys are ignored
+func (s *Store) Delete(keys ...string) error {
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil && !errors.Is(err, microstore.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Keys returns the keys with the given prefix
+func (s *Store) Keys(prefix string) ([]string, error) {
+	return s.store.List(microstore.ListPrefix(prefix))
+}
+
+// DeletePrefix removes all keys with the given prefix
+func (s *Store) DeletePrefix(prefix string) error {
+	keys, err := s.Keys(prefix)
+	if err != nil {
+		return err
+	}
+	return s.Delete(keys...)
+}
+
+// ID returns the id of the entity an index key points to, which is the last segment of the key
+func ID(key string) string {
+	return key[strings.LastIndex(key, "/")+1:]
+}
@@ -0,0 +1,39 @@
+package indexstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+)
+
+func TestStore(t *testing.T) {
+	s := New(microstore.NewMemoryStore())
+	require.NoError(t, s.Write("entity/1", []byte("one"), 0))
+	require.NoError(t, s.Write("user/alice/1", []byte("1"), 0))
+	require.NoError(t, s.Write("user/alice/2", []byte("2"), 0))
+
+	value, err := s.Read("entity/1")
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(value))
+	_, err = s.Read("entity/2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	keys, err := s.Keys("user/alice/")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user/alice/1", "user/alice/2"}, keys)
+	assert.Equal(t, "2", ID("user/alice/2"))
+
+	// missing keys are ignored
+	require.NoError(t, s.Delete("entity/1", "entity/2"))
+	require.NoError(t, s.DeletePrefix("user/"))
+	keys, err = s.Keys("")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestValidationError(t *testing.T) {
+	var err error = NewValidationError("name must not be longer than %d characters", 255)
+	assert.EqualError(t, err, "name must not be longer than 255 characters")
+}
@@ -44,6 +44,16 @@ func (b Builder) Build(q string) (*ast.Ast, error) {
 	return f.(*ast.Ast), nil
 }
 
+// TimeRange returns the beginning and the end of a natural language date range like
+// "today" or "last 7 days", as it is used by queries like `mtime:"last week"`
+func TimeRange(value string) (time.Time, time.Time, error) {
+	from, to, err := toTimeRange(value)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return *from, *to, nil
+}
+
 // timeNow mirrors time.Now by default, the only reason why this exists
 // is to monkey patch it from the tests. See PatchTimeNow
 var timeNow = time.Now
@@ -2,6 +2,7 @@ package kql_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/ast"
 	"github.com/owncloud/ocis/v2/ocis-pkg/kql"
@@ -52,3 +53,22 @@ func TestNewAST(t *testing.T) {
 		})
 	}
 }
+
+func TestTimeRange(t *testing.T) {
+	kql.PatchTimeNow(func() time.Time {
+		return time.Date(2023, 9, 13, 12, 30, 0, 0, time.UTC)
+	})
+	t.Cleanup(func() {
+		kql.PatchTimeNow(time.Now)
+	})
+
+	assert := tAssert.New(t)
+
+	from, to, err := kql.TimeRange("last week")
+	assert.Nil(err)
+	assert.Equal(time.Date(2023, 9, 4, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(time.Date(2023, 9, 10, 23, 59, 59, 999999999, time.UTC), to)
+
+	_, _, err = kql.TimeRange("next week")
+	assert.NotNil(err)
+}
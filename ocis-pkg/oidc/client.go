@@ -63,7 +63,7 @@ type oidcClient struct {
 	remoteKeySet               KeySet
 	algorithms                 []string
 
-	JWKSOptions config.JWKS
+	JWKSOptions JWKSOptions
 	JWKS        *keyfunc.JWKS
 	jwksLock    *sync.Mutex
 
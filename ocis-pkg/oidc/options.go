@@ -5,7 +5,6 @@ import (
 
 	"github.com/MicahParks/keyfunc/v2"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
-	"github.com/owncloud/ocis/v2/services/proxy/pkg/config"
 
 	goidc "github.com/coreos/go-oidc/v3/oidc"
 )
@@ -22,7 +21,7 @@ type Options struct {
 	// The OpenID Connect Issuer URL
 	OIDCIssuer string
 	// JWKSOptions to use when retrieving keys
-	JWKSOptions config.JWKS
+	JWKSOptions JWKSOptions
 	// the JWKS keyset to use for verifying signatures of Access- and
 	// Logout-Tokens
 	// this option is mostly needed for unit test. To avoid fetching the keys
@@ -94,8 +93,17 @@ func WithHTTPClient(val *http.Client) Option {
 	}
 }
 
+// JWKSOptions configure the refreshing of the keys published by the IDP, the intervals and timeouts
+// are given in minutes and seconds like in the proxy configuration
+type JWKSOptions struct {
+	RefreshInterval   uint64
+	RefreshTimeout    uint64
+	RefreshRateLimit  uint64
+	RefreshUnknownKID bool
+}
+
 // WithJWKSOptions provides a function to set the jwksOptions option.
-func WithJWKSOptions(val config.JWKS) Option {
+func WithJWKSOptions(val JWKSOptions) Option {
 	return func(o *Options) {
 		o.JWKSOptions = val
 	}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+)
+
+// SignatureHeader is the header containing the HMAC-SHA256 signature of the webhook payload
+const SignatureHeader = "X-OCIS-Signature"
+
+// Sender posts JSON payloads to webhook URLs. Requests failing because of network errors, rate limiting or
+// server errors are retried, the time between the retries doubles with every retry.
+type Sender struct {
+	client     *http.Client
+	maxRetries int
+	backoff    time.Duration
+	logger     log.Logger
+}
+
+// NewSender returns a Sender posting with the given client. Use a client of NewClient refusing internal
+// addresses for URLs chosen by users.
+func NewSender(client *http.Client, maxRetries int, backoff time.Duration, logger log.Logger) Sender {
+	return Sender{client: client, maxRetries: maxRetries, backoff: backoff, logger: logger}
+}
+
+// Send posts the body to the URL, the body is signed with the secret unless it is empty
+func (s Sender) Send(ctx context.Context, target, secret string, body []byte) error {
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, target, secret, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.maxRetries {
+			return err
+		}
+
+		s.logger.Debug().Err(err).Int("attempt", attempt+1).Msg("webhook request failed, retrying")
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends the body to the URL and reports if a failed request should be retried
+func (s Sender) post(ctx context.Context, target, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(SignatureHeader, Sign(secret, body))
+	}
+
+	res, err := s.client.Do(req)
+	switch {
+	case errors.Is(err, ErrAddressNotAllowed):
+		return false, ErrAddressNotAllowed
+	case err != nil:
+		return true, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned status %d", res.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned status %d", res.StatusCode)
+	}
+}
+
+// Sign returns the value of the signature header for a webhook payload
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
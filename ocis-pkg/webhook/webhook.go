@@ -0,0 +1,77 @@
+// Package webhook contains the helpers to deliver webhooks to URLs chosen by users
+package webhook
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrAddressNotAllowed is returned when connecting to an internal address is refused
+var ErrAddressNotAllowed = errors.New("connections to internal addresses are not allowed")
+
+// _internalPrefixes are the reserved ranges which are not covered by the netip checks
+var _internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Control is a net.Dialer control function which refuses connections to loopback, private, link-local and
+// other internal addresses. It runs after the host name was resolved, so it also covers host names
+// resolving to internal addresses and redirects to them.
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if Internal(ip) {
+		return ErrAddressNotAllowed
+	}
+	return nil
+}
+
+// Internal returns true if the address is not reachable from the internet
+func Internal(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, p := range _internalPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns a http client for webhooks. The client of URLs chosen by users refuses connections to
+// internal addresses, it doesn't use a proxy because the proxy would connect to the address instead.
+func NewClient(timeout time.Duration, insecureSkipVerify bool, allowInternal bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy:       http.ProxyFromEnvironment,
+		DialContext: dialer.DialContext,
+		TLSClientConfig: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		},
+	}
+	if !allowInternal {
+		dialer.Control = Control
+		transport.Proxy = nil
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
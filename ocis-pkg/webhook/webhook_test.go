@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+)
+
+func TestInternal(t *testing.T) {
+	for addr, internal := range map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00::1":          true,
+		"fe80::1":          true,
+		"::ffff:127.0.0.1": true,
+		"93.184.215.14":    false,
+		"2001:db8::1":      false,
+	} {
+		assert.Equal(t, internal, Internal(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestControl(t *testing.T) {
+	assert.ErrorIs(t, Control("tcp", "127.0.0.1:80", nil), ErrAddressNotAllowed)
+	assert.ErrorIs(t, Control("tcp", "[::1]:80", nil), ErrAddressNotAllowed)
+	assert.NoError(t, Control("tcp", "93.184.215.14:443", nil))
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second, false, false).Get(srv.URL)
+	assert.ErrorIs(t, err, ErrAddressNotAllowed)
+
+	res, err := NewClient(time.Second, false, true).Get(srv.URL)
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestSender(t *testing.T) {
+	for name, tt := range map[string]struct {
+		statuses     []int
+		wantErr      bool
+		wantRequests int32
+	}{
+		"retries server errors":        {statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK}, wantRequests: 3},
+		"gives up after max retries":   {statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}, wantErr: true, wantRequests: 3},
+		"does not retry client errors": {statuses: []int{http.StatusNotFound, http.StatusOK}, wantErr: true, wantRequests: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			err := NewSender(NewClient(time.Second, false, true), 2, time.Millisecond, log.NopLogger()).Send(context.Background(), srv.URL, "", []byte(`{}`))
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantRequests, requests.Load())
+		})
+	}
+}
+
+func TestSenderSignature(t *testing.T) {
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(SignatureHeader)
+	}))
+	defer srv.Close()
+
+	sender := NewSender(NewClient(time.Second, false, true), 0, 0, log.NopLogger())
+	require.NoError(t, sender.Send(context.Background(), srv.URL, "secret", []byte(`{}`)))
+	assert.Equal(t, Sign("secret", []byte(`{}`)), signature)
+
+	require.NoError(t, sender.Send(context.Background(), srv.URL, "", []byte(`{}`)))
+	assert.Empty(t, signature, "payloads are not signed without secret")
+}
+
+func TestSenderInternalAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	err := NewSender(NewClient(time.Second, false, false), 2, time.Millisecond, log.NopLogger()).Send(context.Background(), srv.URL, "", []byte(`{}`))
+	assert.ErrorIs(t, err, ErrAddressNotAllowed)
+}
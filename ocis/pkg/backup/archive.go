@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/lookup"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/metadata/prefixes"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/node"
+	"github.com/pkg/xattr"
+	"github.com/shamaton/msgpack/v2"
+)
+
+// ArchiveVersion is the version of the archive format written by CreateBackup
+const ArchiveVersion = 1
+
+const (
+	// an archive starts with the manifest, followed by the entries of the decomposedfs and
+	// the blobs. The blob of a node directly follows the node. Incremental backups end with
+	// the list of live entries.
+	_manifestName = "manifest.json"
+	_storageDir   = "storage"
+	_blobsDir     = "blobs"
+	_liveName     = "live.json"
+
+	// extended attributes are stored as pax records like GNU tar does
+	_paxXattr = "SCHILY.xattr."
+)
+
+var (
+	// ErrNoManifest is returned for archives which don't start with a manifest
+	ErrNoManifest = errors.New("archive has no manifest")
+	// ErrUnsupportedVersion is returned for archives written by a newer version
+	ErrUnsupportedVersion = errors.New("archive version not supported")
+)
+
+// Blobstore is the blobstore blobs are backed up from and restored to
+type Blobstore interface {
+	UploadFromReader(node *node.Node, r io.Reader, size int64) error
+	Download(node *node.Node) (io.ReadCloser, error)
+}
+
+// Manifest describes the content of a backup archive
+type Manifest struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	// Since is set for incremental backups, which only contain the nodes modified after it
+	// and list the live entries of the storage to remove the deleted ones on restore
+	Since *time.Time `json:"since,omitempty"`
+	// SpaceID is set if the archive only contains a single space
+	SpaceID string `json:"space_id,omitempty"`
+	// Blobs is false if the blobs were not backed up
+	Blobs bool `json:"blobs"`
+}
+
+// Incremental returns if the archive is an incremental backup
+func (m Manifest) Incremental() bool {
+	return m.Since != nil
+}
+
+// Summary sums up the entries of a backup or a restore
+type Summary struct {
+	Entries int
+	Blobs   int
+	// BlobBytes is the size of all blobs
+	BlobBytes int64
+	// Removed is the number of entries an incremental restore removed
+	Removed int
+}
+
+// ReadManifest reads the manifest of a backup archive
+func ReadManifest(r io.Reader) (Manifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer gr.Close()
+	return readManifest(tar.NewReader(gr))
+}
+
+func readManifest(tr *tar.Reader) (Manifest, error) {
+	var m Manifest
+	hdr, err := tr.Next()
+	switch {
+	case errors.Is(err, io.EOF):
+		return m, ErrNoManifest
+	case err != nil:
+		return m, err
+	case hdr.Name != _manifestName:
+		return m, ErrNoManifest
+	}
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return m, fmt.Errorf("could not read manifest: %w", err)
+	}
+	if m.Version > ArchiveVersion {
+		return m, fmt.Errorf("%w: %d", ErrUnsupportedVersion, m.Version)
+	}
+	return m, nil
+}
+
+// spacePath returns the path of a space relative to the decomposedfs root
+func spacePath(spaceID string) string {
+	return path.Join("spaces", lookup.Pathify(spaceID, 1, 2))
+}
+
+// nodePath returns the path of a node relative to the decomposedfs root
+func nodePath(spaceID, nodeID string) string {
+	return path.Join(spacePath(spaceID), "nodes", lookup.Pathify(nodeID, 4, 2))
+}
+
+// blobName returns the name of a blob in the archive
+func blobName(spaceID, blobID string) string {
+	return path.Join(_blobsDir, spaceID, blobID)
+}
+
+// readXattrs returns the ocis extended attributes of a file. File systems without
+// extended attribute support have no attributes.
+func readXattrs(p string) (map[string][]byte, error) {
+	names, err := xattr.LList(p)
+	switch {
+	case errors.Is(err, syscall.ENOTSUP):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	attrs := map[string][]byte{}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefixes.OcisPrefix) {
+			continue
+		}
+		v, err := xattr.LGet(p, name)
+		if err != nil {
+			return nil, err
+		}
+		attrs[name] = v
+	}
+	return attrs, nil
+}
+
+// readMetadata returns the metadata of a node from its .mpk file or, if the
+// xattrs backend is used, from its extended attributes
+func readMetadata(p string) (map[string][]byte, error) {
+	b, err := os.ReadFile(p + ".mpk")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return readXattrs(p)
+	case err != nil:
+		return nil, err
+	}
+	m := map[string][]byte{}
+	if err := msgpack.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// blobOf returns the blob of a node, nil is returned for nodes without blob
+func blobOf(p, spaceID string) (*node.Node, error) {
+	m, err := readMetadata(p)
+	if err != nil {
+		return nil, err
+	}
+	bid := string(m[prefixes.BlobIDAttr])
+	if bid == "" {
+		return nil, nil
+	}
+	n := &node.Node{SpaceID: spaceID, BlobID: bid}
+	if s := string(m[prefixes.BlobsizeAttr]); s != "" {
+		if n.Blobsize, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed blobsize of node '%s': %w", p, err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,323 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis/pkg/backup"
+	ocisbs "github.com/owncloud/reva/v2/pkg/storage/fs/ocis/blobstore"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/lookup"
+	decomposedfs "github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/node"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/spaceidindex"
+	"github.com/pkg/xattr"
+	"github.com/shamaton/msgpack/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	_space      = "5a1ad2b1-6c3b-4bd1-8d6a-6d1c1e2e8f1a"
+	_otherSpace = "9c2d7a45-3f1e-4c8a-b2d0-1e5f6a7b8c9d"
+	_file       = "0f6c8e2a-4b1d-4e7f-9a3c-5d2b1e0f8a7c"
+	_version    = "2024-05-22T07:32:53.89969726Z"
+)
+
+var _old = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+func nodePath(root, spaceID, nodeID string) string {
+	return filepath.Join(root, "spaces", lookup.Pathify(spaceID, 1, 2), "nodes", lookup.Pathify(nodeID, 4, 2))
+}
+
+// writeNode writes a file node with its metadata and blob, nodes without content are folders
+func writeNode(t *testing.T, root, spaceID, nodeID, content string) {
+	p := nodePath(root, spaceID, nodeID)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0700))
+	m := map[string][]byte{"user.ocis.name": []byte(filepath.Base(nodeID))}
+	if content == "" {
+		require.NoError(t, os.Mkdir(p, 0700))
+	} else {
+		require.NoError(t, os.WriteFile(p, nil, 0600))
+		bs, err := ocisbs.New(root)
+		require.NoError(t, err)
+		n := &decomposedfs.Node{SpaceID: spaceID, BlobID: "blob-" + filepath.Base(p), Blobsize: int64(len(content))}
+		require.NoError(t, bs.UploadFromReader(n, bytes.NewBufferString(content), n.Blobsize))
+		m["user.ocis.blobid"] = []byte(n.BlobID)
+		m["user.ocis.blobsize"] = []byte(strconv.Itoa(len(content)))
+	}
+	b, err := msgpack.Marshal(m)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(p+".mpk", b, 0600))
+}
+
+// newStorage creates a decomposedfs with two spaces, a file with a version in the first one
+// and a space index. All files are modified at _old.
+func newStorage(t *testing.T) string {
+	root := t.TempDir()
+	i := spaceidindex.New(filepath.Join(root, "indexes"), "by-type")
+	require.NoError(t, i.Init())
+	for _, s := range []string{_space, _otherSpace} {
+		writeNode(t, root, s, s, "")
+		require.NoError(t, i.Add("project", s, "../../../spaces/"+lookup.Pathify(s, 1, 2)+"/nodes/"+lookup.Pathify(s, 4, 2)))
+	}
+	writeNode(t, root, _space, _file, "current")
+	writeNode(t, root, _space, _file+".REV."+_version, "old")
+	require.NoError(t, os.Symlink("../../../../../"+lookup.Pathify(_file, 4, 2), filepath.Join(nodePath(root, _space, _space), "file.txt")))
+
+	require.NoError(t, filepath.Walk(root, func(p string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(p, _old, _old)
+	}))
+	return root
+}
+
+func newBlobstore(t *testing.T, root string) *ocisbs.Blobstore {
+	bs, err := ocisbs.New(root)
+	require.NoError(t, err)
+	return bs
+}
+
+func create(t *testing.T, root string, opts backup.CreateOptions) (*bytes.Buffer, backup.Summary) {
+	var buf bytes.Buffer
+	_, s, err := backup.CreateBackup(root, newBlobstore(t, root), &buf, opts)
+	require.NoError(t, err)
+	return &buf, s
+}
+
+func restore(t *testing.T, root string, archive *bytes.Buffer, opts backup.RestoreOptions) backup.Summary {
+	_, s, err := backup.RestoreBackup(root, newBlobstore(t, root), bytes.NewReader(archive.Bytes()), opts)
+	require.NoError(t, err)
+	return s
+}
+
+func blobContent(t *testing.T, root, spaceID, nodeID string) string {
+	b, err := os.ReadFile(nodePath(root, spaceID, nodeID) + ".mpk")
+	require.NoError(t, err)
+	m := map[string][]byte{}
+	require.NoError(t, msgpack.Unmarshal(b, &m))
+	r, err := newBlobstore(t, root).Download(&decomposedfs.Node{SpaceID: spaceID, BlobID: string(m["user.ocis.blobid"])})
+	require.NoError(t, err)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func index(t *testing.T, root string) map[string]string {
+	links, err := spaceidindex.New(filepath.Join(root, "indexes"), "by-type").Load("project")
+	require.NoError(t, err)
+	return links
+}
+
+func TestBackupRestore(t *testing.T) {
+	src := newStorage(t)
+	archive, s := create(t, src, backup.CreateOptions{})
+	require.Equal(t, 2, s.Blobs)
+	require.Equal(t, int64(10), s.BlobBytes)
+
+	m, err := backup.ReadManifest(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, backup.ArchiveVersion, m.Version)
+	require.False(t, m.Incremental())
+	require.True(t, m.Blobs)
+
+	dst := t.TempDir()
+	s = restore(t, dst, archive, backup.RestoreOptions{})
+	require.Equal(t, 2, s.Blobs)
+
+	require.Equal(t, "current", blobContent(t, dst, _space, _file))
+	require.Equal(t, "old", blobContent(t, dst, _space, _file+".REV."+_version))
+	link, err := os.Readlink(filepath.Join(nodePath(dst, _space, _space), "file.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "../../../../../"+lookup.Pathify(_file, 4, 2), link)
+	fi, err := os.Stat(nodePath(dst, _space, _file))
+	require.NoError(t, err)
+	require.True(t, fi.ModTime().Equal(_old))
+	require.Len(t, index(t, dst), 2)
+	require.DirExists(t, nodePath(dst, _otherSpace, _otherSpace))
+}
+
+func TestBackupRestoreXattrs(t *testing.T) {
+	src := newStorage(t)
+	// the xattrs backend stores the metadata in extended attributes
+	p := nodePath(src, _space, _file)
+	b, err := os.ReadFile(p + ".mpk")
+	require.NoError(t, err)
+	m := map[string][]byte{}
+	require.NoError(t, msgpack.Unmarshal(b, &m))
+	for k, v := range m {
+		if err := xattr.Set(p, k, v); err != nil {
+			t.Skip("extended attributes are not supported:", err)
+		}
+	}
+	require.NoError(t, os.Remove(p+".mpk"))
+
+	archive, s := create(t, src, backup.CreateOptions{})
+	require.Equal(t, 2, s.Blobs)
+
+	dst := t.TempDir()
+	restore(t, dst, archive, backup.RestoreOptions{})
+	blobID, err := xattr.Get(nodePath(dst, _space, _file), "user.ocis.blobid")
+	require.NoError(t, err)
+	require.Equal(t, m["user.ocis.blobid"], blobID)
+}
+
+func TestIncrementalBackup(t *testing.T) {
+	src := newStorage(t)
+	full, _ := create(t, src, backup.CreateOptions{})
+
+	// the file was overwritten after the full backup
+	since := _old.Add(time.Hour)
+	writeNode(t, src, _space, _file, "new")
+	incremental, s := create(t, src, backup.CreateOptions{Since: since})
+	require.Equal(t, 1, s.Blobs)
+	require.Equal(t, int64(3), s.BlobBytes)
+
+	m, err := backup.ReadManifest(bytes.NewReader(incremental.Bytes()))
+	require.NoError(t, err)
+	require.True(t, m.Incremental())
+	require.True(t, m.Since.Equal(since))
+
+	dst := t.TempDir()
+	restore(t, dst, full, backup.RestoreOptions{})
+	require.Equal(t, "current", blobContent(t, dst, _space, _file))
+	restore(t, dst, incremental, backup.RestoreOptions{})
+	require.Equal(t, "new", blobContent(t, dst, _space, _file))
+	require.Equal(t, "old", blobContent(t, dst, _space, _file+".REV."+_version))
+}
+
+func TestIncrementalBackupDeletions(t *testing.T) {
+	src := newStorage(t)
+	full, _ := create(t, src, backup.CreateOptions{})
+
+	// the version and the other space were deleted after the full backup
+	since := _old.Add(time.Hour)
+	version := nodePath(src, _space, _file+".REV."+_version)
+	require.NoError(t, os.Remove(version))
+	require.NoError(t, os.Remove(version+".mpk"))
+	require.NoError(t, os.RemoveAll(filepath.Join(src, "spaces", lookup.Pathify(_otherSpace, 1, 2))))
+	i := spaceidindex.New(filepath.Join(src, "indexes"), "by-type")
+	require.NoError(t, i.Remove("project", _otherSpace))
+	incremental, s := create(t, src, backup.CreateOptions{Since: since})
+	require.Zero(t, s.Blobs)
+
+	dst := t.TempDir()
+	restore(t, dst, full, backup.RestoreOptions{})
+	s = restore(t, dst, incremental, backup.RestoreOptions{})
+	require.Equal(t, 3, s.Removed)
+	require.NoFileExists(t, nodePath(dst, _space, _file+".REV."+_version))
+	require.NoFileExists(t, nodePath(dst, _space, _file+".REV."+_version)+".mpk")
+	require.NoDirExists(t, nodePath(dst, _otherSpace, _otherSpace))
+	require.Equal(t, "current", blobContent(t, dst, _space, _file))
+	require.Equal(t, []string{_space}, keys(index(t, dst)))
+}
+
+// archive writes an archive with the given storage entries
+func archive(t *testing.T, hdrs ...*tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	m := []byte(`{"version":1}`)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "manifest.json", Mode: 0600, Size: int64(len(m))}))
+	_, err := tw.Write(m)
+	require.NoError(t, err)
+	for _, hdr := range hdrs {
+		hdr.Name = "storage/" + hdr.Name
+		require.NoError(t, tw.WriteHeader(hdr))
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return &buf
+}
+
+func TestRestoreSymlinks(t *testing.T) {
+	space := "spaces/" + lookup.Pathify(_space, 1, 2)
+	for name, hdrs := range map[string][]*tar.Header{
+		"absolute link": {
+			{Name: space + "/nodes/file", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		},
+		"link leaving the space": {
+			{Name: space + "/nodes/file", Typeflag: tar.TypeSymlink, Linkname: "../../" + lookup.Pathify(_otherSpace, 1, 2)[3:]},
+		},
+		"link leaving the storage": {
+			{Name: "indexes/by-type", Typeflag: tar.TypeSymlink, Linkname: "../.."},
+		},
+		"entry below a link": {
+			{Name: space + "/nodes/link", Typeflag: tar.TypeSymlink, Linkname: "."},
+			{Name: space + "/nodes/link/file", Typeflag: tar.TypeReg, Mode: 0600},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := backup.RestoreBackup(t.TempDir(), nil, archive(t, hdrs...), backup.RestoreOptions{})
+			require.Error(t, err)
+		})
+	}
+
+	// the parent of the storage may be a symlink
+	dst := filepath.Join(t.TempDir(), "storage")
+	require.NoError(t, os.Symlink(t.TempDir(), dst))
+	_, _, err := backup.RestoreBackup(dst, nil, archive(t,
+		&tar.Header{Name: space + "/nodes/link", Typeflag: tar.TypeSymlink, Linkname: "../nodes"},
+	), backup.RestoreOptions{})
+	require.NoError(t, err)
+}
+
+func TestRestoreSpace(t *testing.T) {
+	src := newStorage(t)
+	for name, archive := range map[string]*bytes.Buffer{
+		"provider backup": func() *bytes.Buffer { b, _ := create(t, src, backup.CreateOptions{}); return b }(),
+		"space backup":    func() *bytes.Buffer { b, _ := create(t, src, backup.CreateOptions{SpaceID: _otherSpace}); return b }(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dst := t.TempDir()
+			s := restore(t, dst, archive, backup.RestoreOptions{SpaceID: _otherSpace})
+			require.Zero(t, s.Blobs)
+			require.DirExists(t, nodePath(dst, _otherSpace, _otherSpace))
+			require.NoDirExists(t, nodePath(dst, _space, _space))
+			require.Equal(t, []string{_otherSpace}, keys(index(t, dst)))
+		})
+	}
+
+	archive, _ := create(t, src, backup.CreateOptions{SpaceID: _otherSpace})
+	_, _, err := backup.RestoreBackup(t.TempDir(), nil, archive, backup.RestoreOptions{SpaceID: _space})
+	require.Error(t, err, "the archive doesn't contain the space")
+}
+
+func TestRestoreVersion(t *testing.T) {
+	src := newStorage(t)
+	archive, _ := create(t, src, backup.CreateOptions{})
+
+	dst := t.TempDir()
+	s := restore(t, dst, archive, backup.RestoreOptions{SpaceID: _space, NodeID: _file, Version: _version})
+	require.Equal(t, backup.Summary{Entries: 2, Blobs: 1, BlobBytes: 3}, s)
+	require.Equal(t, "old", blobContent(t, dst, _space, _file+".REV."+_version))
+	require.NoFileExists(t, nodePath(dst, _space, _file))
+
+	for _, opts := range []backup.RestoreOptions{
+		{SpaceID: _space, NodeID: _file},
+		{NodeID: _file, Version: _version},
+	} {
+		_, _, err := backup.RestoreBackup(dst, nil, bytes.NewReader(archive.Bytes()), opts)
+		require.Error(t, err)
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	_, err := backup.ReadManifest(bytes.NewBufferString("no archive"))
+	require.Error(t, err)
+}
+
+func keys(m map[string]string) []string {
+	k := make([]string, 0, len(m))
+	for key := range m {
+		k = append(k, key)
+	}
+	return k
+}
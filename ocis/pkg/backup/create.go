@@ -0,0 +1,341 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shamaton/msgpack/v2"
+)
+
+// CreateOptions configure a backup
+type CreateOptions struct {
+	// SpaceID limits the backup to a single space
+	SpaceID string
+	// Since creates an incremental backup of the nodes modified after it
+	Since time.Time
+}
+
+// CreateBackup writes a backup archive of the decomposedfs at basePath to w. The blobs are
+// downloaded from the blobstore, they are not backed up if the blobstore is nil.
+func CreateBackup(basePath string, bs Blobstore, w io.Writer, opts CreateOptions) (Manifest, Summary, error) {
+	m := Manifest{
+		Version: ArchiveVersion,
+		// changes during the backup are contained in the next incremental backup
+		Created: time.Now().UTC(),
+		SpaceID: opts.SpaceID,
+		Blobs:   bs != nil,
+	}
+	if !opts.Since.IsZero() {
+		since := opts.Since.UTC()
+		m.Since = &since
+	}
+
+	root := "spaces"
+	if opts.SpaceID != "" {
+		root = spacePath(opts.SpaceID)
+	}
+	if _, err := os.Stat(filepath.Join(basePath, root)); err != nil {
+		return m, Summary{}, fmt.Errorf("could not read storage: %w", err)
+	}
+
+	gw := gzip.NewWriter(w)
+	bw := &backupWriter{
+		tw:       tar.NewWriter(gw),
+		basePath: basePath,
+		bs:       bs,
+		opts:     opts,
+		blobs:    map[string]struct{}{},
+	}
+	if m.Incremental() {
+		bw.live = []string{}
+	}
+	if err := bw.writeManifest(m); err != nil {
+		return m, bw.summary, err
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "indexes")); err == nil {
+		if err := bw.walk("indexes"); err != nil {
+			return m, bw.summary, err
+		}
+	}
+	if err := bw.walk(root); err != nil {
+		return m, bw.summary, err
+	}
+	if err := bw.writeLive(m); err != nil {
+		return m, bw.summary, err
+	}
+	if err := bw.tw.Close(); err != nil {
+		return m, bw.summary, err
+	}
+	return m, bw.summary, gw.Close()
+}
+
+type backupWriter struct {
+	tw       *tar.Writer
+	basePath string
+	bs       Blobstore
+	opts     CreateOptions
+	// blobs holds the blobs which were written already
+	blobs map[string]struct{}
+	// live holds the storage entries of an incremental backup, the unmodified ones included
+	live    []string
+	summary Summary
+}
+
+func (bw *backupWriter) writeManifest(m Manifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := bw.tw.WriteHeader(&tar.Header{
+		Name:    _manifestName,
+		Mode:    0600,
+		Size:    int64(len(b)),
+		ModTime: m.Created,
+	}); err != nil {
+		return err
+	}
+	_, err = bw.tw.Write(b)
+	return err
+}
+
+// writeLive writes the entries of the storage at the time of an incremental backup, entries
+// which are not listed were deleted since the last backup and are removed on restore
+func (bw *backupWriter) writeLive(m Manifest) error {
+	if bw.live == nil {
+		return nil
+	}
+	b, err := json.Marshal(bw.live)
+	if err != nil {
+		return err
+	}
+	if err := bw.tw.WriteHeader(&tar.Header{
+		Name:    _liveName,
+		Mode:    0600,
+		Size:    int64(len(b)),
+		ModTime: m.Created,
+	}); err != nil {
+		return err
+	}
+	_, err = bw.tw.Write(b)
+	return err
+}
+
+func (bw *backupWriter) walk(root string) error {
+	return filepath.WalkDir(filepath.Join(bw.basePath, root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(bw.basePath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		parts := strings.Split(rel, "/")
+		if bw.live != nil && !isLockFile(rel) && !isBlobsDir(parts) {
+			bw.live = append(bw.live, rel)
+		}
+
+		switch {
+		case d.IsDir() && isBlobsDir(parts):
+			// the blobs of the ocis blobstore are backed up with their nodes
+			return filepath.SkipDir
+		case isLockFile(rel):
+			return nil
+		case parts[0] == "indexes" && strings.HasSuffix(rel, ".mpk"):
+			return bw.addIndex(p, rel)
+		case isNodeFile(parts) && strings.HasSuffix(rel, ".mpk"):
+			// the metadata is written with its node
+			return nil
+		case isNodeFile(parts):
+			return bw.addNode(p, rel, parts)
+		default:
+			_, err := bw.addEntry(p, rel, false)
+			return err
+		}
+	})
+}
+
+// isNodeFile returns if the path is a file or folder of a node, like
+// spaces/a8/e5d981-41e4-4468-b532-258d5fb457d3/nodes/2d/08/8d/24/46-a7e4-4f9a-b8e4-8a2eb8c8e6a1
+func isNodeFile(parts []string) bool {
+	return len(parts) == 9 && parts[0] == "spaces" && parts[3] == "nodes"
+}
+
+// isBlobsDir returns if the path is the blobs folder of the ocis blobstore in a space
+func isBlobsDir(parts []string) bool {
+	return len(parts) == 4 && parts[0] == "spaces" && parts[3] == "blobs"
+}
+
+// isLockFile returns if the path is a lock file, locks are not backed up
+func isLockFile(rel string) bool {
+	return strings.HasSuffix(rel, ".mlock") || strings.HasSuffix(rel, ".flock")
+}
+
+// addEntry writes a file, folder or symlink to the archive. Folders are always written, other
+// entries only if they were modified since the last backup or force is set.
+func (bw *backupWriter) addEntry(p, rel string, force bool) (os.FileInfo, error) {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() && !force && !fi.ModTime().After(bw.opts.Since) {
+		return fi, nil
+	}
+
+	var link string
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if link, err = os.Readlink(p); err != nil {
+			return nil, err
+		}
+	}
+	hdr, err := tar.FileInfoHeader(fi, link)
+	if err != nil {
+		return nil, err
+	}
+	hdr.Name = path.Join(_storageDir, rel)
+	hdr.Format = tar.FormatPAX
+	// the owner is not portable
+	hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+	if link == "" {
+		attrs, err := readXattrs(p)
+		if err != nil {
+			return nil, fmt.Errorf("could not read extended attributes of '%s': %w", p, err)
+		}
+		for k, v := range attrs {
+			if hdr.PAXRecords == nil {
+				hdr.PAXRecords = map[string]string{}
+			}
+			hdr.PAXRecords[_paxXattr+k] = string(v)
+		}
+	}
+	if err := bw.tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	bw.summary.Entries++
+	if !fi.Mode().IsRegular() {
+		return fi, nil
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.Copy(bw.tw, f); err != nil {
+		return nil, fmt.Errorf("could not write '%s' to archive: %w", p, err)
+	}
+	return fi, nil
+}
+
+// addNode writes a node with its metadata and blob if the node or its metadata were modified
+// since the last backup. Without a .mpk file the metadata is stored in extended attributes,
+// changing them only updates the change time of the node.
+func (bw *backupWriter) addNode(p, rel string, parts []string) error {
+	fi, err := os.Lstat(p)
+	if err != nil {
+		return err
+	}
+	modified := fi.ModTime().After(bw.opts.Since)
+	mfi, err := os.Lstat(p + ".mpk")
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		modified = modified || changeTime(fi).After(bw.opts.Since)
+	case err != nil:
+		return err
+	default:
+		modified = modified || mfi.ModTime().After(bw.opts.Since)
+	}
+
+	if _, err := bw.addEntry(p, rel, modified); err != nil || !modified {
+		return err
+	}
+	if mfi != nil {
+		if _, err := bw.addEntry(p+".mpk", rel+".mpk", true); err != nil {
+			return err
+		}
+	}
+	if bw.bs == nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	return bw.addBlob(p, parts[1]+parts[2])
+}
+
+func (bw *backupWriter) addBlob(p, spaceID string) error {
+	n, err := blobOf(p, spaceID)
+	if err != nil {
+		return fmt.Errorf("could not read metadata of '%s': %w", p, err)
+	}
+	if n == nil {
+		return nil
+	}
+	name := blobName(n.SpaceID, n.BlobID)
+	if _, ok := bw.blobs[name]; ok {
+		return nil
+	}
+
+	r, err := bw.bs.Download(n)
+	if err != nil {
+		return fmt.Errorf("could not download blob of '%s': %w", p, err)
+	}
+	defer r.Close()
+	if err := bw.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    n.Blobsize,
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(bw.tw, r, n.Blobsize); err != nil {
+		return fmt.Errorf("could not write blob of '%s' to archive: %w", p, err)
+	}
+	bw.blobs[name] = struct{}{}
+	bw.summary.Blobs++
+	bw.summary.BlobBytes += n.Blobsize
+	return nil
+}
+
+// addIndex writes a space index, a backup of a single space only contains the entries of the space
+func (bw *backupWriter) addIndex(p, rel string) error {
+	if bw.opts.SpaceID == "" {
+		_, err := bw.addEntry(p, rel, true)
+		return err
+	}
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	links := map[string]string{}
+	if err := msgpack.Unmarshal(b, &links); err != nil {
+		return fmt.Errorf("could not read index '%s': %w", p, err)
+	}
+	target, ok := links[bw.opts.SpaceID]
+	if !ok {
+		return nil
+	}
+	if b, err = msgpack.Marshal(map[string]string{bw.opts.SpaceID: target}); err != nil {
+		return err
+	}
+	if err := bw.tw.WriteHeader(&tar.Header{
+		Name:    path.Join(_storageDir, rel),
+		Mode:    0600,
+		Size:    int64(len(b)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	bw.summary.Entries++
+	_, err = bw.tw.Write(b)
+	return err
+}
@@ -0,0 +1,15 @@
+package backup
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// changeTime returns the time the file or its extended attributes were last changed
+func changeTime(fi os.FileInfo) time.Time {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix())
+	}
+	return fi.ModTime()
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package backup
+
+import (
+	"os"
+	"time"
+)
+
+// changeTime returns the modification time, the change time is only read on linux
+func changeTime(fi os.FileInfo) time.Time {
+	return fi.ModTime()
+}
@@ -0,0 +1,406 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/node"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/spaceidindex"
+	"github.com/pkg/xattr"
+	"github.com/shamaton/msgpack/v2"
+)
+
+// RestoreOptions configure a restore
+type RestoreOptions struct {
+	// SpaceID only restores a single space
+	SpaceID string
+	// NodeID and Version only restore a single version of a file, the SpaceID is required
+	NodeID  string
+	Version string
+}
+
+// RestoreBackup restores a backup archive into the decomposedfs at basePath and uploads the blobs
+// to the blobstore, the blobs are skipped if the blobstore is nil. Existing nodes are overwritten.
+// Incremental backups are restored after the backup they are based on, entries deleted since that
+// backup are removed. The storage must not be in use, the storage provider caches the metadata of the
+// nodes and would neither notice the restored metadata nor propagate the changes to the parents.
+func RestoreBackup(basePath string, bs Blobstore, r io.Reader, opts RestoreOptions) (Manifest, Summary, error) {
+	switch {
+	case (opts.NodeID == "") != (opts.Version == ""):
+		return Manifest{}, Summary{}, errors.New("node id and version are required to restore a version")
+	case opts.NodeID != "" && opts.SpaceID == "":
+		return Manifest{}, Summary{}, errors.New("space id is required to restore a version")
+	}
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, Summary{}, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	m, err := readManifest(tr)
+	if err != nil {
+		return m, Summary{}, err
+	}
+	if m.SpaceID != "" && opts.SpaceID != "" && m.SpaceID != opts.SpaceID {
+		return m, Summary{}, fmt.Errorf("archive only contains space '%s'", m.SpaceID)
+	}
+
+	br := &backupReader{
+		basePath: basePath,
+		bs:       bs,
+		opts:     opts,
+		manifest: m,
+		dirs:     map[string]time.Time{},
+	}
+	if opts.NodeID != "" {
+		br.version = nodePath(opts.SpaceID, opts.NodeID) + ".REV." + opts.Version
+	}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return m, br.summary, err
+		}
+		if hdr.Name == _liveName {
+			if err := json.NewDecoder(tr).Decode(&br.live); err != nil {
+				return m, br.summary, fmt.Errorf("could not read live entries: %w", err)
+			}
+			continue
+		}
+		if err := br.restore(hdr, tr); err != nil {
+			return m, br.summary, fmt.Errorf("could not restore '%s': %w", hdr.Name, err)
+		}
+	}
+
+	if m.Incremental() && br.version == "" {
+		if br.live == nil {
+			return m, br.summary, errors.New("incremental backup has no live entries")
+		}
+		if err := br.prune(); err != nil {
+			return m, br.summary, fmt.Errorf("could not remove deleted entries: %w", err)
+		}
+	}
+
+	// the modification times of folders change while their content is restored
+	for p, mtime := range br.dirs {
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			return m, br.summary, err
+		}
+	}
+	return m, br.summary, nil
+}
+
+type backupReader struct {
+	basePath string
+	bs       Blobstore
+	opts     RestoreOptions
+	manifest Manifest
+	// version is the path of the version to restore
+	version string
+	// live holds the storage entries listed by an incremental backup
+	live    []string
+	dirs    map[string]time.Time
+	summary Summary
+}
+
+// spaceID returns the space which is restored, it is empty if all spaces are restored
+func (br *backupReader) spaceID() string {
+	if br.opts.SpaceID != "" {
+		return br.opts.SpaceID
+	}
+	return br.manifest.SpaceID
+}
+
+func (br *backupReader) restore(hdr *tar.Header, r io.Reader) error {
+	dir, rel, _ := strings.Cut(hdr.Name, "/")
+	if !filepath.IsLocal(rel) {
+		return errors.New("invalid path")
+	}
+	switch dir {
+	case _storageDir:
+		parts := strings.Split(rel, "/")
+		switch {
+		case br.version != "":
+			if rel != br.version && rel != br.version+".mpk" {
+				return nil
+			}
+		case parts[0] == "indexes" && hdr.Typeflag == tar.TypeReg && strings.HasSuffix(rel, ".mpk"):
+			return br.restoreIndex(rel, r)
+		case br.opts.SpaceID != "" && parts[0] == "spaces":
+			if rel != spacePath(br.opts.SpaceID) && !strings.HasPrefix(rel, spacePath(br.opts.SpaceID)+"/") {
+				return nil
+			}
+		}
+		return br.restoreEntry(hdr, filepath.Join(br.basePath, filepath.FromSlash(rel)), r)
+	case _blobsDir:
+		spaceID, blobID, ok := strings.Cut(rel, "/")
+		if !ok {
+			return errors.New("invalid blob")
+		}
+		return br.restoreBlob(hdr, spaceID, blobID, r)
+	default:
+		return errors.New("unexpected entry")
+	}
+}
+
+func (br *backupReader) restoreEntry(hdr *tar.Header, p string, r io.Reader) error {
+	// the archive must not write outside of the storage through an existing or a restored symlink
+	dir := filepath.Dir(p)
+	if hdr.Typeflag == tar.TypeDir {
+		dir = p
+	}
+	if err := br.checkParents(dir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(p, 0700); err != nil {
+			return err
+		}
+		br.dirs[p] = hdr.ModTime
+	case tar.TypeSymlink:
+		if err := br.checkLink(p, hdr.Linkname); err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err := os.Symlink(hdr.Linkname, p); err != nil {
+			return err
+		}
+		br.summary.Entries++
+		return nil
+	case tar.TypeReg:
+		// nodes are replaced atomically, the storage may be in use
+		f, err := os.CreateTemp(filepath.Dir(p), ".restore-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Chmod(hdr.FileInfo().Mode().Perm()); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), p); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported type %c", hdr.Typeflag)
+	}
+
+	for k, v := range hdr.PAXRecords {
+		if name, ok := strings.CutPrefix(k, _paxXattr); ok {
+			if err := xattr.LSet(p, name, []byte(v)); err != nil {
+				return err
+			}
+		}
+	}
+	br.summary.Entries++
+	return os.Chtimes(p, hdr.ModTime, hdr.ModTime)
+}
+
+// checkParents returns an error if a folder between the storage root and dir is a symlink
+func (br *backupReader) checkParents(dir string) error {
+	rel, err := filepath.Rel(br.basePath, dir)
+	if err != nil || rel == "." {
+		return err
+	}
+	p := br.basePath
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		p = filepath.Join(p, part)
+		fi, err := os.Lstat(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		case fi.Mode()&os.ModeSymlink != 0:
+			return fmt.Errorf("path contains the symlink '%s'", p)
+		}
+	}
+	return nil
+}
+
+// checkLink returns an error if a symlink at p to target leaves the space it is in, or the
+// storage for symlinks outside of the spaces
+func (br *backupReader) checkLink(p, target string) error {
+	if filepath.IsAbs(target) {
+		return errors.New("absolute symlink")
+	}
+	rel, err := filepath.Rel(br.basePath, p)
+	if err != nil {
+		return err
+	}
+	root := br.basePath
+	// spaces/<xx>/<rest of the space id>/...
+	if parts := strings.SplitN(rel, string(filepath.Separator), 4); len(parts) == 4 && parts[0] == "spaces" {
+		root = filepath.Join(br.basePath, parts[0], parts[1], parts[2])
+	}
+	resolved := filepath.Join(filepath.Dir(p), target)
+	if resolved != root && !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return fmt.Errorf("symlink to '%s' leaves '%s'", target, root)
+	}
+	return nil
+}
+
+// prune removes the entries of the restored spaces which are not listed as live by an
+// incremental backup, they were deleted after the backup it is based on
+func (br *backupReader) prune() error {
+	live := make(map[string]struct{}, len(br.live))
+	for _, rel := range br.live {
+		live[rel] = struct{}{}
+	}
+	root := "spaces"
+	if spaceID := br.spaceID(); spaceID != "" {
+		root = spacePath(spaceID)
+	}
+	return filepath.WalkDir(filepath.Join(br.basePath, root), func(p string, d fs.DirEntry, err error) error {
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			return nil
+		case err != nil:
+			return err
+		}
+		rel, err := filepath.Rel(br.basePath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		parts := strings.Split(rel, "/")
+		switch {
+		case d.IsDir() && isBlobsDir(parts):
+			return filepath.SkipDir
+		case isLockFile(rel):
+			return nil
+		}
+		if _, ok := live[rel]; ok {
+			return nil
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		br.summary.Removed++
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// restoreIndex adds the entries of a space index to the index of the storage. Incremental
+// backups contain complete indexes, entries of spaces deleted since the last backup are removed.
+func (br *backupReader) restoreIndex(rel string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	links := map[string]string{}
+	if err := msgpack.Unmarshal(b, &links); err != nil {
+		return err
+	}
+	if spaceID := br.spaceID(); spaceID != "" {
+		target, ok := links[spaceID]
+		links = map[string]string{}
+		if ok {
+			links[spaceID] = target
+		}
+	}
+
+	// indexes/<name>/<index>.mpk
+	name, index := path.Split(strings.TrimPrefix(rel, "indexes/"))
+	index = strings.TrimSuffix(index, ".mpk")
+	i := spaceidindex.New(filepath.Join(br.basePath, "indexes"), path.Clean(name))
+	if err := i.Init(); err != nil {
+		return err
+	}
+	if br.manifest.Incremental() {
+		stale, err := br.staleIndexEntries(filepath.Join(br.basePath, filepath.FromSlash(rel)), links)
+		if err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if err := i.Remove(index, key); err != nil {
+				return err
+			}
+		}
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	if err := i.AddAll(index, links); err != nil {
+		return err
+	}
+	br.summary.Entries++
+	return nil
+}
+
+// staleIndexEntries returns the entries of the restored spaces in the index at p which are not in links
+func (br *backupReader) staleIndexEntries(p string, links map[string]string) ([]string, error) {
+	b, err := os.ReadFile(p)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	current := map[string]string{}
+	if err := msgpack.Unmarshal(b, &current); err != nil {
+		return nil, fmt.Errorf("could not read index '%s': %w", p, err)
+	}
+	var stale []string
+	for key := range current {
+		if spaceID := br.spaceID(); spaceID != "" && key != spaceID {
+			continue
+		}
+		if _, ok := links[key]; !ok {
+			stale = append(stale, key)
+		}
+	}
+	return stale, nil
+}
+
+func (br *backupReader) restoreBlob(hdr *tar.Header, spaceID, blobID string, r io.Reader) error {
+	switch {
+	case br.bs == nil:
+		return nil
+	case br.version != "":
+		// the blob follows the restored version
+		n, err := blobOf(filepath.Join(br.basePath, filepath.FromSlash(br.version)), spaceID)
+		if err != nil || n == nil || n.BlobID != blobID {
+			return nil
+		}
+	case br.opts.SpaceID != "" && spaceID != br.opts.SpaceID:
+		return nil
+	}
+
+	n := &node.Node{SpaceID: spaceID, BlobID: blobID, Blobsize: hdr.Size}
+	if err := br.bs.UploadFromReader(n, r, hdr.Size); err != nil {
+		return err
+	}
+	br.summary.Blobs++
+	br.summary.BlobBytes += hdr.Size
+	return nil
+}
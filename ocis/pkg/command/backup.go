@@ -3,6 +3,8 @@ package command
 import (
 	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
@@ -21,6 +23,8 @@ func BackupCommand(cfg *config.Config) *cli.Command {
 		Usage: "ocis backup functionality",
 		Subcommands: []*cli.Command{
 			ConsistencyCommand(cfg),
+			CreateCommand(cfg),
+			RestoreCommand(cfg),
 		},
 		Before: func(c *cli.Context) error {
 			return configlog.ReturnError(parser.ParseConfig(cfg, true))
@@ -38,18 +42,8 @@ func ConsistencyCommand(cfg *config.Config) *cli.Command {
 		Name:  "consistency",
 		Usage: "check backup consistency",
 		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "basepath",
-				Aliases:  []string{"p"},
-				Usage:    "the basepath of the decomposedfs (e.g. /var/tmp/ocis/storage/users)",
-				Required: true,
-			},
-			&cli.StringFlag{
-				Name:    "blobstore",
-				Aliases: []string{"b"},
-				Usage:   "the blobstore type. Can be (none, ocis, s3ng). Default ocis",
-				Value:   "ocis",
-			},
+			basePathFlag(),
+			blobstoreFlag(),
 			&cli.BoolFlag{
 				Name:  "fail",
 				Usage: "exit with non-zero status if consistency check fails",
@@ -62,27 +56,7 @@ func ConsistencyCommand(cfg *config.Config) *cli.Command {
 				return cli.ShowCommandHelp(c, "consistency")
 			}
 
-			var (
-				bs  backup.ListBlobstore
-				err error
-			)
-			switch c.String("blobstore") {
-			case "s3ng":
-				bs, err = s3bs.New(
-					cfg.StorageUsers.Drivers.S3NG.Endpoint,
-					cfg.StorageUsers.Drivers.S3NG.Region,
-					cfg.StorageUsers.Drivers.S3NG.Bucket,
-					cfg.StorageUsers.Drivers.S3NG.AccessKey,
-					cfg.StorageUsers.Drivers.S3NG.SecretKey,
-					s3bs.Options{},
-				)
-			case "ocis":
-				bs, err = ocisbs.New(basePath)
-			case "none":
-				bs = nil
-			default:
-				err = errors.New("blobstore type not supported")
-			}
+			bs, err := newBlobstore(cfg, c.String("blobstore"), basePath)
 			if err != nil {
 				fmt.Println(err)
 				return err
@@ -97,6 +71,201 @@ func ConsistencyCommand(cfg *config.Config) *cli.Command {
 	}
 }
 
+// CreateCommand is the entrypoint for the create command
+func CreateCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "create",
+		Usage: "create a backup archive of a decomposedfs or a single space",
+		Flags: []cli.Flag{
+			basePathFlag(),
+			blobstoreFlag(),
+			&cli.StringFlag{
+				Name:     "output",
+				Aliases:  []string{"o"},
+				Usage:    "the path of the backup archive",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "space",
+				Usage: "only backup the space with this id",
+			},
+			&cli.TimestampFlag{
+				Name:   "since",
+				Usage:  "create an incremental backup of the nodes modified after this time (e.g. 2024-05-22T07:32:53Z)",
+				Layout: time.RFC3339,
+			},
+			&cli.StringFlag{
+				Name:  "incremental",
+				Usage: "create an incremental backup of the nodes modified after the given backup archive was created",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			opts := backup.CreateOptions{SpaceID: c.String("space")}
+			if t := c.Timestamp("since"); t != nil {
+				opts.Since = *t
+			}
+			if prev := c.String("incremental"); prev != "" {
+				if !opts.Since.IsZero() {
+					return errors.New("since and incremental can't be combined")
+				}
+				m, err := readManifest(prev)
+				if err != nil {
+					return err
+				}
+				opts.Since = m.Created
+			}
+
+			basePath := c.String("basepath")
+			bs, err := newBlobstore(cfg, c.String("blobstore"), basePath)
+			if err != nil {
+				return err
+			}
+
+			output := c.String("output")
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("could not create backup archive: %w", err)
+			}
+			m, s, err := backup.CreateBackup(basePath, bs, f, opts)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(output)
+				return fmt.Errorf("could not create backup: %w", err)
+			}
+
+			kind := "full"
+			if m.Incremental() {
+				kind = "incremental"
+			}
+			fmt.Printf("Created %s backup '%s' with %d entries and %d blobs (%d bytes)\n", kind, output, s.Entries, s.Blobs, s.BlobBytes)
+			return nil
+		},
+	}
+}
+
+// RestoreCommand is the entrypoint for the restore command
+func RestoreCommand(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "restore",
+		Usage: "restore backup archives, a space or a single file version into a decomposedfs",
+		Description: "The storage-users service must be stopped during the restore. It caches the node metadata, " +
+			"persistent metadata caches have to be purged before starting it again.",
+		ArgsUsage: "['backup archive' required] ['incremental backup archive'...]",
+		Flags: []cli.Flag{
+			basePathFlag(),
+			blobstoreFlag(),
+			&cli.StringFlag{
+				Name:  "space",
+				Usage: "only restore the space with this id",
+			},
+			&cli.StringFlag{
+				Name:  "node",
+				Usage: "the id of the file to restore a version of, requires space and version",
+			},
+			&cli.StringFlag{
+				Name:  "version",
+				Usage: "the version of the file to restore (e.g. 2024-05-22T07:32:53.89969726Z)",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() == 0 {
+				_ = cli.ShowSubcommandHelp(c)
+				return errors.New("backup archive is required")
+			}
+			opts := backup.RestoreOptions{
+				SpaceID: c.String("space"),
+				NodeID:  c.String("node"),
+				Version: c.String("version"),
+			}
+
+			basePath := c.String("basepath")
+			bs, err := newBlobstore(cfg, c.String("blobstore"), basePath)
+			if err != nil {
+				return err
+			}
+
+			var total backup.Summary
+			for _, archive := range c.Args().Slice() {
+				f, err := os.Open(archive)
+				if err != nil {
+					return fmt.Errorf("could not open backup archive: %w", err)
+				}
+				_, s, err := backup.RestoreBackup(basePath, bs, f, opts)
+				f.Close()
+				total.Entries += s.Entries
+				total.Blobs += s.Blobs
+				total.BlobBytes += s.BlobBytes
+				total.Removed += s.Removed
+				if err != nil {
+					return fmt.Errorf("could not restore '%s': %w", archive, err)
+				}
+			}
+			if opts.Version != "" && total.Entries == 0 {
+				return fmt.Errorf("version '%s' of node '%s' not found", opts.Version, opts.NodeID)
+			}
+
+			fmt.Printf("Restored %d entries and %d blobs (%d bytes), removed %d entries\n", total.Entries, total.Blobs, total.BlobBytes, total.Removed)
+			return nil
+		},
+	}
+}
+
+func basePathFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:     "basepath",
+		Aliases:  []string{"p"},
+		Usage:    "the basepath of the decomposedfs (e.g. /var/tmp/ocis/storage/users)",
+		Required: true,
+	}
+}
+
+func blobstoreFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:    "blobstore",
+		Aliases: []string{"b"},
+		Usage:   "the blobstore type. Can be (none, ocis, s3ng). Default ocis",
+		Value:   "ocis",
+	}
+}
+
+// backupBlobstore can be listed for the consistency check and is used to backup and restore blobs
+type backupBlobstore interface {
+	backup.ListBlobstore
+	backup.Blobstore
+}
+
+// newBlobstore returns the blobstore of the given type, nil is returned for the type none
+func newBlobstore(cfg *config.Config, typ, basePath string) (backupBlobstore, error) {
+	switch typ {
+	case "s3ng":
+		return s3bs.New(
+			cfg.StorageUsers.Drivers.S3NG.Endpoint,
+			cfg.StorageUsers.Drivers.S3NG.Region,
+			cfg.StorageUsers.Drivers.S3NG.Bucket,
+			cfg.StorageUsers.Drivers.S3NG.AccessKey,
+			cfg.StorageUsers.Drivers.S3NG.SecretKey,
+			s3bs.Options{},
+		)
+	case "ocis":
+		return ocisbs.New(basePath)
+	case "none":
+		return nil, nil
+	default:
+		return nil, errors.New("blobstore type not supported")
+	}
+}
+
+func readManifest(archive string) (backup.Manifest, error) {
+	f, err := os.Open(archive)
+	if err != nil {
+		return backup.Manifest{}, fmt.Errorf("could not open backup archive: %w", err)
+	}
+	defer f.Close()
+	return backup.ReadManifest(f)
+}
+
 func init() {
 	register.AddCommand(BackupCommand)
 }
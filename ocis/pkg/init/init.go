@@ -219,6 +219,7 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 			Thumbnail: ThumbnailSettings{
 				TransferSecret: thumbnailsTransferSecret,
 			},
+			ServiceAccount: serviceAccount,
 		},
 		Gateway: Gateway{
 			StorageRegistry: StorageRegistry{
@@ -259,6 +260,9 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 		Activitylog: Activitylog{
 			ServiceAccount: serviceAccount,
 		},
+		Antivirus: Antivirus{
+			ServiceAccount: serviceAccount,
+		},
 	}
 
 	if insecure {
@@ -285,6 +289,7 @@ func CreateConfig(insecure, forceOverwrite, diff bool, configPath, adminPassword
 			ServiceAccount: serviceAccount,
 		}
 
+		cfg.Thumbnails.Events = _insecureEvents
 		cfg.Thumbnails.Thumbnail.WebdavAllowInsecure = true
 		cfg.Thumbnails.Thumbnail.Cs3AllowInsecure = true
 	}
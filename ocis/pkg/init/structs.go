@@ -46,6 +46,7 @@ type OcisConfig struct {
 	AuthService       AuthService           `yaml:"auth_service"`
 	Clientlog         Clientlog             `yaml:"clientlog"`
 	Activitylog       Activitylog           `yaml:"activitylog"`
+	Antivirus         Antivirus             `yaml:"antivirus"`
 }
 
 // Activitylog is the configuration for the activitylog service
@@ -53,6 +54,11 @@ type Activitylog struct {
 	ServiceAccount ServiceAccount `yaml:"service_account"`
 }
 
+// Antivirus is the configuration for the antivirus service
+type Antivirus struct {
+	ServiceAccount ServiceAccount `yaml:"service_account"`
+}
+
 // App is the configuration for the collaboration service
 type App struct {
 	Insecure bool `yaml:"insecure"`
@@ -227,7 +233,9 @@ type ThumbnailSettings struct {
 
 // ThumbnailService is the configuration for the thumbnail service
 type ThumbnailService struct {
-	Thumbnail ThumbnailSettings
+	Thumbnail      ThumbnailSettings
+	Events         Events
+	ServiceAccount ServiceAccount `yaml:"service_account"`
 }
 
 // TokenManager is the configuration for the token manager
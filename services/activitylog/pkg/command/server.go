@@ -119,6 +119,7 @@ func Server(cfg *config.Config) *cli.Command {
 
 			hClient := ehsvc.NewEventHistoryService("com.owncloud.api.eventhistory", grpcClient)
 			vClient := settingssvc.NewValueService("com.owncloud.api.settings", grpcClient)
+			pClient := settingssvc.NewPermissionService("com.owncloud.api.settings", grpcClient)
 
 			{
 				svc, err := http.Server(
@@ -131,6 +132,7 @@ func Server(cfg *config.Config) *cli.Command {
 					http.GatewaySelector(gatewaySelector),
 					http.HistoryClient(hClient),
 					http.ValueClient(vClient),
+					http.PermissionClient(pClient),
 					http.RegisteredEvents(_registeredEvents),
 				)
 
@@ -33,6 +33,7 @@ type Options struct {
 	TraceProvider    trace.TracerProvider
 	HistoryClient    ehsvc.EventHistoryService
 	ValueClient      settingssvc.ValueService
+	PermissionClient settingssvc.PermissionService
 	RegisteredEvents []events.Unmarshaller
 }
 
@@ -137,3 +138,10 @@ func ValueClient(val settingssvc.ValueService) Option {
 		o.ValueClient = val
 	}
 }
+
+// PermissionClient provides a function to set the PermissionClient options
+func PermissionClient(val settingssvc.PermissionService) Option {
+	return func(o *Options) {
+		o.PermissionClient = val
+	}
+}
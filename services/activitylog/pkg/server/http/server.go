@@ -77,6 +77,7 @@ func Server(opts ...Option) (http.Service, error) {
 		svc.TraceProvider(options.TraceProvider),
 		svc.HistoryClient(options.HistoryClient),
 		svc.ValueClient(options.ValueClient),
+		svc.PermissionClient(options.PermissionClient),
 		svc.RegisteredEvents(options.RegisteredEvents),
 	)
 	if err != nil {
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+)
+
+// the feeds are bigger than the activities of a resource, keep them well below the nats payload limit
+var _maxFeedActivities = 3000
+
+// Actions of the activities, they can be used to filter activities
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+	ActionMove   = "move"
+	ActionRename = "rename"
+	ActionShare  = "share"
+	ActionLink   = "link"
+	ActionMember = "member"
+)
+
+// feedActivity returns the feed entry of an event, it is false for events which don't show up in feeds
+func feedActivity(ev interface{}, eventID string) (RawActivity, bool) {
+	var (
+		rid       *provider.ResourceId
+		executant *user.UserId
+		action    string
+		ts        time.Time
+	)
+
+	switch ev := ev.(type) {
+	case events.UploadReady:
+		rid, executant, action, ts = ev.FileRef.GetResourceId(), ev.ExecutingUser.GetId(), ActionCreate, utils.TSToTime(ev.Timestamp)
+		if ev.IsVersion {
+			action = ActionUpdate
+		}
+	case events.FileTouched:
+		rid, executant, action, ts = ev.Ref.GetResourceId(), ev.Executant, ActionCreate, utils.TSToTime(ev.Timestamp)
+	case events.ContainerCreated:
+		rid, executant, action, ts = ev.Ref.GetResourceId(), ev.Executant, ActionCreate, utils.TSToTime(ev.Timestamp)
+	case events.ItemTrashed:
+		rid, executant, action, ts = ev.ID, ev.Executant, ActionDelete, utils.TSToTime(ev.Timestamp)
+	case events.ItemMoved:
+		rid, executant, action, ts = ev.Ref.GetResourceId(), ev.Executant, ActionMove, utils.TSToTime(ev.Timestamp)
+		if isRename(ev.OldReference, ev.Ref) {
+			action = ActionRename
+		}
+	case events.ShareCreated:
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionShare, utils.TSToTime(ev.CTime)
+	case events.ShareUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return RawActivity{}, false
+		}
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionShare, utils.TSToTime(ev.MTime)
+	case events.ShareRemoved:
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionShare, ev.Timestamp
+	case events.LinkCreated:
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionLink, utils.TSToTime(ev.CTime)
+	case events.LinkUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return RawActivity{}, false
+		}
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionLink, utils.TSToTime(ev.MTime)
+	case events.LinkRemoved:
+		rid, executant, action, ts = ev.ItemID, ev.Executant, ActionLink, utils.TSToTime(ev.Timestamp)
+	case events.SpaceShared:
+		rid, executant, action, ts = spaceRootID(ev.ID), ev.Executant, ActionMember, ev.Timestamp
+	case events.SpaceUnshared:
+		rid, executant, action, ts = spaceRootID(ev.ID), ev.Executant, ActionMember, ev.Timestamp
+	default:
+		return RawActivity{}, false
+	}
+
+	if rid == nil {
+		return RawActivity{}, false
+	}
+	return RawActivity{
+		EventID:    eventID,
+		Timestamp:  ts,
+		Action:     action,
+		ResourceID: storagespace.FormatResourceID(rid),
+		SpaceID:    storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId()),
+		UserID:     executant.GetOpaqueId(),
+	}, true
+}
+
+// AddFeedActivity adds the activity to the feed of its space and the feed of the user who performed it
+func (a *ActivitylogService) AddFeedActivity(act RawActivity) error {
+	if err := a.appendActivity(spaceFeedKey(act.SpaceID), act, _maxFeedActivities); err != nil {
+		return fmt.Errorf("could not store space activity: %w", err)
+	}
+	if act.UserID == "" {
+		return nil
+	}
+	if err := a.appendActivity(userFeedKey(act.UserID), act, _maxFeedActivities); err != nil {
+		return fmt.Errorf("could not store user activity: %w", err)
+	}
+	return nil
+}
+
+// SpaceActivities returns all activities in the given space, the space id has the format <providerid>$<spaceid>
+func (a *ActivitylogService) SpaceActivities(spaceID string) ([]RawActivity, error) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.read(spaceFeedKey(spaceID))
+}
+
+// UserActivities returns all activities performed by the given user
+func (a *ActivitylogService) UserActivities(userID string) ([]RawActivity, error) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	return a.read(userFeedKey(userID))
+}
+
+// SharedWithMeActivities returns the activities on the resources shared with the user of the context which
+// happened after the resources were shared, an activity below more than one share is only returned once
+func (a *ActivitylogService) SharedWithMeActivities(ctx context.Context) ([]RawActivity, error) {
+	gwc, err := a.gws.Next()
+	if err != nil {
+		return nil, fmt.Errorf("cant get gateway client: %w", err)
+	}
+
+	res, err := gwc.ListReceivedShares(ctx, &collaboration.ListReceivedSharesRequest{})
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("could not list received shares: %w", err)
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return nil, errors.New(res.GetStatus().GetMessage())
+	}
+
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	seen := make(map[string]struct{})
+	var activities []RawActivity
+	for _, rs := range res.GetShares() {
+		if rs.GetState() != collaboration.ShareState_SHARE_STATE_ACCEPTED {
+			continue
+		}
+
+		acts, err := a.read(storagespace.FormatResourceID(rs.GetShare().GetResourceId()))
+		if err != nil {
+			return nil, err
+		}
+		shared := utils.TSToTime(rs.GetShare().GetCtime())
+		for _, act := range acts {
+			if _, ok := seen[act.EventID]; ok || act.Timestamp.Before(shared) {
+				continue
+			}
+			seen[act.EventID] = struct{}{}
+			activities = append(activities, act)
+		}
+	}
+	return activities, nil
+}
+
+// accessCheck checks if the user of the context currently has access to the resources of the activities
+// in a feed, every resource is only looked up once
+type accessCheck struct {
+	ctx context.Context
+	gwc gateway.GatewayAPIClient
+	// permitted returns true if the permissions on a resource allow to see its activities
+	permitted func(*provider.ResourcePermissions) bool
+	results   map[string]accessResult
+}
+
+type accessResult struct {
+	allowed bool
+	gone    bool
+}
+
+func newAccessCheck(ctx context.Context, gwc gateway.GatewayAPIClient, permitted func(*provider.ResourcePermissions) bool) *accessCheck {
+	return &accessCheck{ctx: ctx, gwc: gwc, permitted: permitted, results: make(map[string]accessResult)}
+}
+
+// allowed returns if the user may see the activities of a resource and if the resource does not exist
+// anymore, the resource id has the format <providerid>$<spaceid>!<opaqueid>
+func (c *accessCheck) allowed(resourceID string) (bool, bool) {
+	if r, ok := c.results[resourceID]; ok {
+		return r.allowed, r.gone
+	}
+
+	var r accessResult
+	if rid, err := storagespace.ParseID(resourceID); err == nil {
+		res, err := c.gwc.Stat(c.ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: &rid}})
+		switch {
+		case err != nil:
+		case res.GetStatus().GetCode() == rpc.Code_CODE_OK:
+			r.allowed = c.permitted(res.GetInfo().GetPermissionSet())
+		case res.GetStatus().GetCode() == rpc.Code_CODE_NOT_FOUND:
+			r.gone = true
+		}
+	}
+	c.results[resourceID] = r
+	return r.allowed, r.gone
+}
+
+func spaceFeedKey(spaceID string) string {
+	return "space/" + spaceID
+}
+
+func userFeedKey(userID string) string {
+	return "user/" + userID
+}
+
+// spaceRootID returns the id of the root of a space, the space id has the format <providerid>$<spaceid>
+func spaceRootID(spaceID *provider.StorageSpaceId) *provider.ResourceId {
+	rid, err := storagespace.ParseID(spaceID.GetOpaqueId())
+	if err != nil {
+		return nil
+	}
+	rid.OpaqueId = rid.GetSpaceId()
+	return &rid
+}
+
+// activityCursor points to the last activity of a page, the next page starts after it
+type activityCursor struct {
+	Timestamp int64  `json:"t"`
+	EventID   string `json:"e"`
+}
+
+func (c activityCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseActivityCursor(s string) (activityCursor, error) {
+	var c activityCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	if c.EventID == "" {
+		return c, errors.New("invalid skiptoken")
+	}
+	return c, nil
+}
+
+func cursorOf(act RawActivity) activityCursor {
+	return activityCursor{Timestamp: act.Timestamp.UnixNano(), EventID: act.EventID}
+}
+
+// compareActivities orders activities by time, activities of the same time by their event id
+func compareActivities(a, b RawActivity) int {
+	return compareCursors(cursorOf(a), cursorOf(b))
+}
+
+func compareCursors(a, b activityCursor) int {
+	switch {
+	case a.Timestamp < b.Timestamp:
+		return -1
+	case a.Timestamp > b.Timestamp:
+		return 1
+	case a.EventID < b.EventID:
+		return -1
+	case a.EventID > b.EventID:
+		return 1
+	}
+	return 0
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"embed"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"slices"
 	"strconv"
@@ -24,6 +26,8 @@ import (
 	"github.com/owncloud/ocis/v2/ocis-pkg/l10n"
 	ehmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/eventhistory/v0"
 	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
 )
 
 var (
@@ -35,6 +39,9 @@ var (
 
 	// domain of the activitylog service (transifex)
 	_domain = "activitylog"
+
+	// number of events requested from the eventhistory at once
+	_eventBatchSize = 200
 )
 
 // ServeHTTP implements the http.Handler interface.
@@ -42,7 +49,8 @@ func (s *ActivitylogService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
 }
 
-// HandleGetItemActivities handles the request to get the activities of an item.
+// HandleGetItemActivities handles the request to get the activities of an item, a space, a user or of the
+// items shared with the user.
 func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	ctx = metadata.AppendToOutgoingContext(ctx, revactx.TokenHeader, r.Header.Get("X-Access-Token"))
@@ -59,184 +67,196 @@ func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *h
 		return
 	}
 
-	rid, limit, rawActivityAccepted, activityAccepted, sort, err := s.getFilters(r.URL.Query().Get("kql"))
+	f, err := s.getFilters(r.URL.Query().Get("kql"))
 	if err != nil {
 		s.log.Info().Str("query", r.URL.Query().Get("kql")).Err(err).Msg("error getting filters")
-		_, _ = w.Write([]byte(err.Error()))
 		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
 		return
 	}
 
-	info, err := utils.GetResourceByID(ctx, rid, gwc)
-	if err != nil {
-		w.WriteHeader(http.StatusForbidden)
-		return
+	var cursor *activityCursor
+	if token := r.URL.Query().Get("$skiptoken"); token != "" {
+		c, err := parseActivityCursor(token)
+		if err != nil {
+			s.log.Info().Str("skiptoken", token).Err(err).Msg("invalid skiptoken")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		cursor = &c
 	}
 
-	// you need ListGrants to see activities
-	if !info.GetPermissionSet().GetListGrants() {
-		w.WriteHeader(http.StatusForbidden)
+	var (
+		raw []RawActivity
+		// key of the record the activities are read from, activities of expired events are removed from it
+		key string
+		// visible checks the current access to the resources of the feeds spanning several resources
+		visible func(ev interface{}) bool
+	)
+	switch {
+	case f.itemID != nil, f.spaceID != "":
+		rid := f.itemID
+		if rid == nil {
+			rid = spaceRootID(&provider.StorageSpaceId{OpaqueId: f.spaceID})
+			if rid == nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		info, err := utils.GetResourceByID(ctx, rid, gwc)
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// you need ListGrants to see activities
+		if !info.GetPermissionSet().GetListGrants() {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if f.itemID != nil {
+			key = storagespace.FormatResourceID(f.itemID)
+			raw, err = s.Activities(f.itemID)
+		} else {
+			key = spaceFeedKey(f.spaceID)
+			raw, err = s.SpaceActivities(f.spaceID)
+		}
+	case f.userID != "":
+		// only admins can see what other users did
+		if f.userID != activeUser.GetId().GetOpaqueId() && !s.canListAllActivities(ctx) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// the activities of resources the user can't see the activities of anymore are skipped, deleted
+		// resources are checked by their space
+		access := newAccessCheck(ctx, gwc, func(p *provider.ResourcePermissions) bool { return p.GetListGrants() })
+		visible = func(ev interface{}) bool {
+			act, ok := feedActivity(ev, "")
+			if !ok {
+				return false
+			}
+			allowed, gone := access.allowed(act.ResourceID)
+			if gone {
+				allowed, _ = access.allowed(storagespace.FormatResourceID(spaceRootID(&provider.StorageSpaceId{OpaqueId: act.SpaceID})))
+			}
+			return allowed
+		}
+
+		key = userFeedKey(f.userID)
+		raw, err = s.UserActivities(f.userID)
+	case f.sharedWithMe:
+		// the activities of resources which were moved out of the shares are skipped, deleted resources
+		// were below the share when they were deleted
+		access := newAccessCheck(ctx, gwc, func(p *provider.ResourcePermissions) bool { return p.GetStat() })
+		visible = func(ev interface{}) bool {
+			act, ok := feedActivity(ev, "")
+			if !ok {
+				return false
+			}
+			allowed, gone := access.allowed(act.ResourceID)
+			return allowed || (gone && act.Action == ActionDelete)
+		}
+
+		raw, err = s.SharedWithMeActivities(ctx)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("one of itemid, spaceid, userid or sharedwithme is required"))
 		return
 	}
-
-	raw, err := s.Activities(rid)
 	if err != nil {
 		s.log.Error().Err(err).Msg("error getting activities")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	ids := make([]string, 0, len(raw))
-	toDelete := make(map[string]struct{}, len(raw))
+	slices.SortStableFunc(raw, compareActivities)
+	if f.desc {
+		slices.Reverse(raw)
+	}
+
+	accepted := make([]RawActivity, 0, len(raw))
 	for _, a := range raw {
-		if !rawActivityAccepted(a) {
+		if !f.rawActivityAccepted(a) {
 			continue
 		}
-		ids = append(ids, a.EventID)
-		toDelete[a.EventID] = struct{}{}
-	}
-
-	evRes, err := s.evHistory.GetEvents(r.Context(), &ehsvc.GetEventsRequest{Ids: ids})
-	if err != nil {
-		s.log.Error().Err(err).Msg("error getting events")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		if cursor != nil {
+			// skip the activities up to the last one of the previous page
+			if c := compareCursors(cursorOf(a), *cursor); (c <= 0 && !f.desc) || (c >= 0 && f.desc) {
+				continue
+			}
+		}
+		accepted = append(accepted, a)
 	}
 
-	evs := evRes.GetEvents()
-	sort(evs)
+	loc := l10n.MustGetUserLocale(r.Context(), activeUser.GetId().GetOpaqueId(), r.Header.Get(l10n.HeaderAcceptLanguage), s.valService)
+	t := l10n.NewTranslatorFromCommonConfig(s.cfg.DefaultLanguage, _domain, s.cfg.TranslationPath, _localeFS, _localeSubPath)
 
-	resp := GetActivitiesResponse{Activities: make([]libregraph.Activity, 0, len(evRes.GetEvents()))}
-	for _, e := range evs {
-		delete(toDelete, e.GetId())
+	var (
+		resp     = GetActivitiesResponse{Activities: make([]libregraph.Activity, 0)}
+		toDelete = make(map[string]struct{})
+		last     RawActivity
+		more     bool
+	)
+	// the events are fetched in batches, so a page does not need all events of a feed
+	for start := 0; start < len(accepted) && !more; start += _eventBatchSize {
+		batch := accepted[start:min(start+_eventBatchSize, len(accepted))]
 
-		if limit > 0 && limit <= len(resp.Activities) {
-			continue
+		ids := make([]string, 0, len(batch))
+		for _, a := range batch {
+			ids = append(ids, a.EventID)
 		}
 
-		if !activityAccepted(e) {
-			continue
+		evRes, err := s.evHistory.GetEvents(r.Context(), &ehsvc.GetEventsRequest{Ids: ids})
+		if err != nil {
+			s.log.Error().Err(err).Msg("error getting events")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
 		}
 
-		var (
-			message string
-			ts      time.Time
-			vars    map[string]interface{}
-		)
-
-		loc := l10n.MustGetUserLocale(r.Context(), activeUser.GetId().GetOpaqueId(), r.Header.Get(l10n.HeaderAcceptLanguage), s.valService)
-		t := l10n.NewTranslatorFromCommonConfig(s.cfg.DefaultLanguage, _domain, s.cfg.TranslationPath, _localeFS, _localeSubPath)
+		evs := make(map[string]*ehmsg.Event, len(evRes.GetEvents()))
+		for _, e := range evRes.GetEvents() {
+			evs[e.GetId()] = e
+		}
 
-		switch ev := s.unwrapEvent(e).(type) {
-		case nil:
-			// error already logged in unwrapEvent
-			continue
-		case events.UploadReady:
-			message = MessageResourceCreated
-			if ev.IsVersion {
-				message = MessageResourceUpdated
+		for _, a := range batch {
+			e, ok := evs[a.EventID]
+			if !ok {
+				toDelete[a.EventID] = struct{}{}
+				continue
 			}
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.FileRef, false, "", &t, loc),
-				WithUser(nil, ev.ExecutingUser, ev.ImpersonatingUser))
-		case events.FileTouched:
-			message = MessageResourceCreated
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", &t, loc),
-				WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.FileDownloaded:
-			message = MessageResourceDownloaded
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", &t, loc),
-				WithUser(ev.Executant, nil, ev.ImpersonatingUser), WithVar("token", "", ev.ImpersonatingUser.GetId().GetOpaqueId()))
-		case events.ContainerCreated:
-			message = MessageResourceCreated
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", &t, loc),
-				WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.ItemTrashed:
-			message = MessageResourceTrashed
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithTrashedResource(ev.Ref, ev.ID), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-		case events.ItemMoved:
-			switch isRename(ev.OldReference, ev.Ref) {
-			case true:
-				message = MessageResourceRenamed
-				vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", &t, loc),
-					WithOldResource(ev.OldReference), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
-			case false:
-				message = MessageResourceMoved
-				vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", &t, loc),
-					WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+
+			if f.limit > 0 && f.limit <= len(resp.Activities) {
+				more = true
+				break
 			}
-			ts = utils.TSToTime(ev.Timestamp)
-		case events.ShareCreated:
-			message = MessageShareCreated
-			ts = utils.TSToTime(ev.CTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName, &t, loc),
-				WithUser(ev.Executant, nil, nil),
-				WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.ShareUpdated:
-			if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+
+			ev := s.unwrapEvent(e)
+			if ev == nil || !f.activityAccepted(ev) || (visible != nil && !visible(ev)) {
 				continue
 			}
-			message = MessageShareUpdated
-			ts = utils.TSToTime(ev.MTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName, &t, loc),
-				WithUser(ev.Executant, nil, nil),
-				WithTranslation(&t, loc, "field", ev.UpdateMask))
-		case events.ShareRemoved:
-			message = MessageShareDeleted
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName, &t, loc),
-				WithUser(ev.Executant, nil, nil),
-				WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.LinkCreated:
-			message = MessageLinkCreated
-			ts = utils.TSToTime(ev.CTime)
-			vars, err = s.GetVars(ctx,
-				WithResource(toRef(ev.ItemID), false, ev.ResourceName, &t, loc),
-				WithUser(ev.Executant, nil, nil))
-		case events.LinkUpdated:
-			if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+
+			activity, ok := s.activity(ctx, e.GetId(), ev, &t, loc)
+			if !ok {
 				continue
 			}
-			message = MessageLinkUpdated
-			ts = utils.TSToTime(ev.MTime)
-			vars, err = s.GetVars(ctx,
-				WithVar("resource", storagespace.FormatResourceID(ev.ItemID), ev.ResourceName),
-				WithUser(ev.Executant, nil, nil),
-				WithTranslation(&t, loc, "field", []string{ev.FieldUpdated}),
-				WithVar("token", ev.ItemID.GetOpaqueId(), ev.DisplayName))
-		case events.LinkRemoved:
-			message = MessageLinkDeleted
-			ts = utils.TSToTime(ev.Timestamp)
-			vars, err = s.GetVars(ctx, WithResource(toRef(ev.ItemID), false, "", &t, loc), WithUser(ev.Executant, nil, nil))
-		case events.SpaceShared:
-			message = MessageSpaceShared
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		case events.SpaceUnshared:
-			message = MessageSpaceUnshared
-			ts = ev.Timestamp
-			vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
-		}
-
-		if err != nil {
-			s.log.Error().Err(err).Msg("error getting response data")
-			continue
+			resp.Activities = append(resp.Activities, activity)
+			last = a
 		}
+	}
 
-		resp.Activities = append(resp.Activities, NewActivity(t.Translate(message, loc), ts, e.GetId(), vars))
+	if more {
+		q := r.URL.Query()
+		q.Set("$skiptoken", cursorOf(last).encode())
+		resp.NextLink = (&url.URL{Path: r.URL.Path, RawQuery: q.Encode()}).String()
 	}
 
 	// delete activities in separate go routine
-	if len(toDelete) > 0 {
+	if len(toDelete) > 0 && key != "" {
 		go func() {
-			err := s.RemoveActivities(rid, toDelete)
+			err := s.removeActivities(key, toDelete)
 			if err != nil {
 				s.log.Error().Err(err).Msg("error removing activities")
 			}
@@ -258,6 +278,134 @@ func (s *ActivitylogService) HandleGetItemActivities(w http.ResponseWriter, r *h
 	w.WriteHeader(http.StatusOK)
 }
 
+// activity composes the activity of an event, it is false for events which are not shown
+func (s *ActivitylogService) activity(ctx context.Context, eventID string, ev interface{}, t *l10n.Translator, loc string) (libregraph.Activity, bool) {
+	var (
+		message string
+		ts      time.Time
+		vars    map[string]interface{}
+		err     error
+	)
+
+	switch ev := ev.(type) {
+	case events.UploadReady:
+		message = MessageResourceCreated
+		if ev.IsVersion {
+			message = MessageResourceUpdated
+		}
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.FileRef, false, "", t, loc),
+			WithUser(nil, ev.ExecutingUser, ev.ImpersonatingUser))
+	case events.FileTouched:
+		message = MessageResourceCreated
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", t, loc),
+			WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.FileDownloaded:
+		message = MessageResourceDownloaded
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", t, loc),
+			WithUser(ev.Executant, nil, ev.ImpersonatingUser), WithVar("token", "", ev.ImpersonatingUser.GetId().GetOpaqueId()))
+	case events.ContainerCreated:
+		message = MessageResourceCreated
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", t, loc),
+			WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.ItemTrashed:
+		message = MessageResourceTrashed
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithTrashedResource(ev.Ref, ev.ID), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+	case events.ItemMoved:
+		switch isRename(ev.OldReference, ev.Ref) {
+		case true:
+			message = MessageResourceRenamed
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", t, loc),
+				WithOldResource(ev.OldReference), WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+		case false:
+			message = MessageResourceMoved
+			vars, err = s.GetVars(ctx, WithResource(ev.Ref, false, "", t, loc),
+				WithUser(ev.Executant, nil, ev.ImpersonatingUser))
+		}
+		ts = utils.TSToTime(ev.Timestamp)
+	case events.ShareCreated:
+		message = MessageShareCreated
+		ts = utils.TSToTime(ev.CTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName, t, loc),
+			WithUser(ev.Executant, nil, nil),
+			WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.ShareUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return libregraph.Activity{}, false
+		}
+		message = MessageShareUpdated
+		ts = utils.TSToTime(ev.MTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName, t, loc),
+			WithUser(ev.Executant, nil, nil),
+			WithTranslation(t, loc, "field", ev.UpdateMask))
+	case events.ShareRemoved:
+		message = MessageShareDeleted
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName, t, loc),
+			WithUser(ev.Executant, nil, nil),
+			WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.LinkCreated:
+		message = MessageLinkCreated
+		ts = utils.TSToTime(ev.CTime)
+		vars, err = s.GetVars(ctx,
+			WithResource(toRef(ev.ItemID), false, ev.ResourceName, t, loc),
+			WithUser(ev.Executant, nil, nil))
+	case events.LinkUpdated:
+		if ev.Sharer != nil && ev.ItemID != nil && ev.Sharer.GetOpaqueId() == ev.ItemID.GetSpaceId() {
+			return libregraph.Activity{}, false
+		}
+		message = MessageLinkUpdated
+		ts = utils.TSToTime(ev.MTime)
+		vars, err = s.GetVars(ctx,
+			WithVar("resource", storagespace.FormatResourceID(ev.ItemID), ev.ResourceName),
+			WithUser(ev.Executant, nil, nil),
+			WithTranslation(t, loc, "field", []string{ev.FieldUpdated}),
+			WithVar("token", ev.ItemID.GetOpaqueId(), ev.DisplayName))
+	case events.LinkRemoved:
+		message = MessageLinkDeleted
+		ts = utils.TSToTime(ev.Timestamp)
+		vars, err = s.GetVars(ctx, WithResource(toRef(ev.ItemID), false, "", t, loc), WithUser(ev.Executant, nil, nil))
+	case events.SpaceShared:
+		message = MessageSpaceShared
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	case events.SpaceUnshared:
+		message = MessageSpaceUnshared
+		ts = ev.Timestamp
+		vars, err = s.GetVars(ctx, WithSpace(ev.ID), WithUser(ev.Executant, nil, nil), WithSharee(ev.GranteeUserID, ev.GranteeGroupID))
+	}
+
+	if err != nil {
+		s.log.Error().Err(err).Msg("error getting response data")
+		return libregraph.Activity{}, false
+	}
+
+	return NewActivity(t.Translate(message, loc), ts, eventID, vars), true
+}
+
+// canListAllActivities checks if the user of the context can list the activities of all users
+func (s *ActivitylogService) canListAllActivities(ctx context.Context) bool {
+	if s.permissionService == nil {
+		return false
+	}
+
+	pr, err := s.permissionService.GetPermissionByID(ctx, &settingssvc.GetPermissionByIDRequest{
+		PermissionId: defaults.AccountManagementPermission(0).Id,
+	})
+	if err != nil || pr.GetPermission() == nil {
+		return false
+	}
+
+	return pr.GetPermission().GetConstraint() == defaults.All
+}
+
 func (s *ActivitylogService) unwrapEvent(e *ehmsg.Event) interface{} {
 	etype, ok := s.registeredEvents[e.GetType()]
 	if !ok {
@@ -274,32 +422,78 @@ func (s *ActivitylogService) unwrapEvent(e *ehmsg.Event) interface{} {
 	return einterface
 }
 
-func (s *ActivitylogService) getFilters(query string) (*provider.ResourceId, int, func(RawActivity) bool, func(*ehmsg.Event) bool, func([]*ehmsg.Event), error) {
+// activityFilters are the filters of an activities request
+type activityFilters struct {
+	// exactly one of these selects the activities
+	itemID       *provider.ResourceId
+	spaceID      string
+	userID       string
+	sharedWithMe bool
+
+	limit               int
+	desc                bool
+	rawActivityAccepted func(RawActivity) bool
+	activityAccepted    func(interface{}) bool
+}
+
+func (s *ActivitylogService) getFilters(query string) (activityFilters, error) {
+	var f activityFilters
+
 	qast, err := kql.Builder{}.Build(query)
 	if err != nil {
-		return nil, 0, nil, nil, nil, err
+		return f, err
 	}
 
 	prefilters := make([]func(RawActivity) bool, 0)
-	postfilters := make([]func(*ehmsg.Event) bool, 0)
-
-	sortby := func(_ []*ehmsg.Event) {}
-
-	var (
-		itemID string
-		limit  int
-	)
+	postfilters := make([]func(interface{}) bool, 0)
 
 	for _, n := range qast.Nodes {
 		switch v := n.(type) {
 		case *ast.StringNode:
 			switch strings.ToLower(v.Key) {
 			case "itemid":
-				itemID = v.Value
+				rid, err := storagespace.ParseID(v.Value)
+				if err != nil {
+					return f, err
+				}
+				if rid.GetOpaqueId() == "" {
+					// space root requested - fix format
+					rid.OpaqueId = rid.GetSpaceId()
+				}
+				f.itemID = &rid
+			case "spaceid":
+				rid, err := storagespace.ParseID(v.Value)
+				if err != nil {
+					return f, err
+				}
+				f.spaceID = storagespace.FormatStorageID(rid.GetStorageId(), rid.GetSpaceId())
+			case "userid":
+				f.userID = v.Value
+			case "sharedwithme":
+				f.sharedWithMe = strings.EqualFold(v.Value, "true")
+			case "action":
+				actions := make(map[string]struct{})
+				for _, a := range strings.Split(v.Value, ",") {
+					actions[strings.ToLower(strings.TrimSpace(a))] = struct{}{}
+				}
+
+				// only the activities of the feeds know their action, the others are filtered by their event
+				prefilters = append(prefilters, func(a RawActivity) bool {
+					_, ok := actions[a.Action]
+					return a.Action == "" || ok
+				})
+				postfilters = append(postfilters, func(ev interface{}) bool {
+					act, ok := feedActivity(ev, "")
+					if !ok {
+						return false
+					}
+					_, ok = actions[act.Action]
+					return ok
+				})
 			case "depth":
 				depth, err := strconv.Atoi(v.Value)
 				if err != nil {
-					return nil, limit, nil, nil, sortby, err
+					return f, err
 				}
 				if depth == -1 {
 					break
@@ -311,20 +505,22 @@ func (s *ActivitylogService) getFilters(query string) (*provider.ResourceId, int
 			case "limit":
 				l, err := strconv.Atoi(v.Value)
 				if err != nil {
-					return nil, limit, nil, nil, sortby, err
+					return f, err
 				}
 
-				limit = l
+				f.limit = l
 			case "sort":
 				switch v.Value {
 				case "asc":
 					// nothing to do - already ascending
 				case "desc":
-					sortby = func(activities []*ehmsg.Event) {
-						slices.Reverse(activities)
-					}
+					f.desc = true
 				}
 			}
+		case *ast.BooleanNode:
+			if strings.ToLower(v.Key) == "sharedwithme" {
+				f.sharedWithMe = v.Value
+			}
 		case *ast.DateTimeNode:
 			switch v.Operator.Value {
 			case "<", "<=":
@@ -338,20 +534,12 @@ func (s *ActivitylogService) getFilters(query string) (*provider.ResourceId, int
 			}
 		case *ast.OperatorNode:
 			if v.Value != "AND" {
-				return nil, limit, nil, nil, sortby, errors.New("only AND operator is supported")
+				return f, errors.New("only AND operator is supported")
 			}
 		}
 	}
 
-	rid, err := storagespace.ParseID(itemID)
-	if err != nil {
-		return nil, limit, nil, nil, sortby, err
-	}
-	if rid.GetOpaqueId() == "" {
-		// space root requested - fix format
-		rid.OpaqueId = rid.GetSpaceId()
-	}
-	pref := func(a RawActivity) bool {
+	f.rawActivityAccepted = func(a RawActivity) bool {
 		for _, f := range prefilters {
 			if !f(a) {
 				return false
@@ -359,15 +547,15 @@ func (s *ActivitylogService) getFilters(query string) (*provider.ResourceId, int
 		}
 		return true
 	}
-	postf := func(e *ehmsg.Event) bool {
+	f.activityAccepted = func(ev interface{}) bool {
 		for _, f := range postfilters {
-			if !f(e) {
+			if !f(ev) {
 				return false
 			}
 		}
 		return true
 	}
-	return &rid, limit, pref, postf, sortby, nil
+	return f, nil
 }
 
 // returns true if this is just a rename
@@ -28,6 +28,7 @@ type Options struct {
 	Mux              *chi.Mux
 	HistoryClient    ehsvc.EventHistoryService
 	ValueClient      settingssvc.ValueService
+	PermissionClient settingssvc.PermissionService
 }
 
 // Logger configures a logger for the activitylog service
@@ -99,3 +100,10 @@ func ValueClient(vs settingssvc.ValueService) Option {
 		o.ValueClient = vs
 	}
 }
+
+// PermissionClient adds a grpc client for the permission service
+func PermissionClient(ps settingssvc.PermissionService) Option {
+	return func(o *Options) {
+		o.PermissionClient = ps
+	}
+}
@@ -48,6 +48,7 @@ var (
 // GetActivitiesResponse is the response on GET activities requests
 type GetActivitiesResponse struct {
 	Activities []libregraph.Activity `json:"value"`
+	NextLink   string                `json:"@odata.nextLink,omitempty"`
 }
 
 // Resource represents an item such as a file or folder
@@ -30,11 +30,16 @@ import (
 // Nats runs into max payload exceeded errors at around 7k activities. Let's keep a buffer.
 var _maxActivities = 6000
 
-// RawActivity represents an activity as it is stored in the activitylog store
+// RawActivity represents an activity as it is stored in the activitylog store. The activities of the space
+// and user feeds also carry the action, the resource, the space and the user who performed it.
 type RawActivity struct {
-	EventID   string    `json:"event_id"`
-	Depth     int       `json:"depth"`
-	Timestamp time.Time `json:"timestamp"`
+	EventID    string    `json:"event_id"`
+	Depth      int       `json:"depth"`
+	Timestamp  time.Time `json:"timestamp"`
+	Action     string    `json:"action,omitempty"`
+	ResourceID string    `json:"resource_id,omitempty"`
+	SpaceID    string    `json:"space_id,omitempty"`
+	UserID     string    `json:"user_id,omitempty"`
 }
 
 // ActivitylogService logs events per resource
@@ -47,7 +52,9 @@ type ActivitylogService struct {
 	mux        *chi.Mux
 	evHistory  ehsvc.EventHistoryService
 	valService settingssvc.ValueService
-	lock       sync.RWMutex
+	// permissionService is used to check if a user may see the activities of other users
+	permissionService settingssvc.PermissionService
+	lock              sync.RWMutex
 
 	registeredEvents map[string]events.Unmarshaller
 }
@@ -73,16 +80,17 @@ func New(opts ...Option) (*ActivitylogService, error) {
 	}
 
 	s := &ActivitylogService{
-		log:              o.Logger,
-		cfg:              o.Config,
-		events:           ch,
-		store:            o.Store,
-		gws:              o.GatewaySelector,
-		mux:              o.Mux,
-		evHistory:        o.HistoryClient,
-		valService:       o.ValueClient,
-		lock:             sync.RWMutex{},
-		registeredEvents: make(map[string]events.Unmarshaller),
+		log:               o.Logger,
+		cfg:               o.Config,
+		events:            ch,
+		store:             o.Store,
+		gws:               o.GatewaySelector,
+		mux:               o.Mux,
+		evHistory:         o.HistoryClient,
+		valService:        o.ValueClient,
+		permissionService: o.PermissionClient,
+		lock:              sync.RWMutex{},
+		registeredEvents:  make(map[string]events.Unmarshaller),
 	}
 
 	s.mux.Get("/graph/v1beta1/extensions/org.libregraph/activities", s.HandleGetItemActivities)
@@ -150,6 +158,10 @@ func (a *ActivitylogService) Run() {
 			err = a.AddSpaceActivity(ev.ID, e.ID, ev.Timestamp) // no ctx needed at the moment
 		}
 
+		if act, ok := feedActivity(e.Event, e.ID); ok && err == nil {
+			err = a.AddFeedActivity(act)
+		}
+
 		if err != nil {
 			a.log.Error().Err(err).Interface("event", e).Msg("could not process event")
 		}
@@ -227,10 +239,14 @@ func (a *ActivitylogService) Activities(rid *provider.ResourceId) ([]RawActivity
 
 // RemoveActivities removes the activities from the given resource
 func (a *ActivitylogService) RemoveActivities(rid *provider.ResourceId, toDelete map[string]struct{}) error {
+	return a.removeActivities(storagespace.FormatResourceID(rid), toDelete)
+}
+
+func (a *ActivitylogService) removeActivities(key string, toDelete map[string]struct{}) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	curActivities, err := a.activities(rid)
+	curActivities, err := a.read(key)
 	if err != nil {
 		return err
 	}
@@ -248,7 +264,7 @@ func (a *ActivitylogService) RemoveActivities(rid *provider.ResourceId, toDelete
 	}
 
 	return a.store.Write(&microstore.Record{
-		Key:   storagespace.FormatResourceID(rid),
+		Key:   key,
 		Value: b,
 	})
 }
@@ -266,9 +282,11 @@ func (a *ActivitylogService) RemoveResource(rid *provider.ResourceId) error {
 }
 
 func (a *ActivitylogService) activities(rid *provider.ResourceId) ([]RawActivity, error) {
-	resourceID := storagespace.FormatResourceID(rid)
+	return a.read(storagespace.FormatResourceID(rid))
+}
 
-	records, err := a.store.Read(resourceID)
+func (a *ActivitylogService) read(key string) ([]RawActivity, error) {
+	records, err := a.store.Read(key)
 	if err != nil && err != microstore.ErrNotFound {
 		return nil, fmt.Errorf("could not read activities: %w", err)
 	}
@@ -313,10 +331,20 @@ func (a *ActivitylogService) addActivity(initRef *provider.Reference, eventID st
 }
 
 func (a *ActivitylogService) storeActivity(resourceID string, eventID string, depth int, timestamp time.Time) error {
+	return a.appendActivity(resourceID, RawActivity{
+		EventID:   eventID,
+		Depth:     depth,
+		Timestamp: timestamp,
+	}, _maxActivities)
+}
+
+// appendActivity appends an activity to the activities stored under the key, the oldest activities are
+// dropped when there are more than limit
+func (a *ActivitylogService) appendActivity(key string, act RawActivity, limit int) error {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 
-	records, err := a.store.Read(resourceID)
+	records, err := a.store.Read(key)
 	if err != nil && err != microstore.ErrNotFound {
 		return err
 	}
@@ -328,15 +356,11 @@ func (a *ActivitylogService) storeActivity(resourceID string, eventID string, de
 		}
 	}
 
-	if l := len(activities); l >= _maxActivities {
-		activities = activities[l-_maxActivities+1:]
+	if l := len(activities); l >= limit {
+		activities = activities[l-limit+1:]
 	}
 
-	activities = append(activities, RawActivity{
-		EventID:   eventID,
-		Depth:     depth,
-		Timestamp: timestamp,
-	})
+	activities = append(activities, act)
 
 	b, err := json.Marshal(activities)
 	if err != nil {
@@ -344,7 +368,7 @@ func (a *ActivitylogService) storeActivity(resourceID string, eventID string, de
 	}
 
 	return a.store.Write(&microstore.Record{
-		Key:   resourceID,
+		Key:   key,
 		Value: b,
 	})
 }
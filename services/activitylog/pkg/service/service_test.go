@@ -1,12 +1,25 @@
 package service
 
 import (
+	"context"
+	"slices"
 	"testing"
 	"time"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
 	"github.com/owncloud/reva/v2/pkg/store"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 )
 
 func TestAddActivity(t *testing.T) {
@@ -143,6 +156,118 @@ func TestAddActivity(t *testing.T) {
 	}
 }
 
+func TestFeedActivities(t *testing.T) {
+	alog := &ActivitylogService{
+		store: store.Create(),
+	}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	evs := map[string]interface{}{
+		"upload": events.UploadReady{
+			FileRef:       reference("base"),
+			ExecutingUser: &user.User{Id: &user.UserId{OpaqueId: "alice"}},
+			Timestamp:     utils.TimeToTS(ts),
+		},
+		"version": events.UploadReady{
+			FileRef:       reference("base"),
+			ExecutingUser: &user.User{Id: &user.UserId{OpaqueId: "bob"}},
+			Timestamp:     utils.TimeToTS(ts.Add(time.Minute)),
+			IsVersion:     true,
+		},
+		"rename": events.ItemMoved{
+			Ref:          &provider.Reference{ResourceId: resourceID("base"), Path: "./new"},
+			OldReference: &provider.Reference{ResourceId: resourceID("base"), Path: "./old"},
+			Executant:    &user.UserId{OpaqueId: "alice"},
+			Timestamp:    utils.TimeToTS(ts.Add(2 * time.Minute)),
+		},
+		"purge": events.ItemPurged{
+			ID:        resourceID("base"),
+			Executant: &user.UserId{OpaqueId: "alice"},
+		},
+	}
+
+	for id, ev := range evs {
+		act, ok := feedActivity(ev, id)
+		if id == "purge" {
+			require.False(t, ok)
+			continue
+		}
+		require.True(t, ok, id)
+		require.NoError(t, alog.AddFeedActivity(act))
+	}
+
+	acts, err := alog.SpaceActivities("storageid$spaceid")
+	require.NoError(t, err)
+	slices.SortFunc(acts, compareActivities)
+	require.Equal(t, []RawActivity{
+		{EventID: "upload", Timestamp: ts, Action: ActionCreate, ResourceID: "storageid$spaceid!base", SpaceID: "storageid$spaceid", UserID: "alice"},
+		{EventID: "version", Timestamp: ts.Add(time.Minute), Action: ActionUpdate, ResourceID: "storageid$spaceid!base", SpaceID: "storageid$spaceid", UserID: "bob"},
+		{EventID: "rename", Timestamp: ts.Add(2 * time.Minute), Action: ActionRename, ResourceID: "storageid$spaceid!base", SpaceID: "storageid$spaceid", UserID: "alice"},
+	}, acts)
+
+	acts, err = alog.UserActivities("alice")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"upload", "rename"}, eventIDs(acts))
+
+	acts, err = alog.UserActivities("carol")
+	require.NoError(t, err)
+	require.Empty(t, acts)
+}
+
+func TestGetFilters(t *testing.T) {
+	alog := &ActivitylogService{}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	f, err := alog.getFilters(`spaceid:"storageid$spaceid" AND action:create,delete AND date>2023-12-31 AND sort:desc AND limit:10`)
+	require.NoError(t, err)
+	require.Nil(t, f.itemID)
+	require.Equal(t, "storageid$spaceid", f.spaceID)
+	require.True(t, f.desc)
+	require.Equal(t, 10, f.limit)
+	require.True(t, f.rawActivityAccepted(RawActivity{Action: ActionCreate, Timestamp: ts}))
+	require.False(t, f.rawActivityAccepted(RawActivity{Action: ActionRename, Timestamp: ts}))
+	require.False(t, f.rawActivityAccepted(RawActivity{Action: ActionCreate, Timestamp: ts.AddDate(-1, 0, 0)}))
+	require.True(t, f.activityAccepted(events.ContainerCreated{Ref: reference("base")}))
+	require.False(t, f.activityAccepted(events.LinkCreated{ItemID: resourceID("base")}))
+
+	f, err = alog.getFilters(`itemid:"storageid$spaceid"`)
+	require.NoError(t, err)
+	require.Equal(t, resourceID("spaceid"), f.itemID)
+
+	f, err = alog.getFilters(`userid:alice`)
+	require.NoError(t, err)
+	require.Equal(t, "alice", f.userID)
+
+	f, err = alog.getFilters(`sharedwithme:true`)
+	require.NoError(t, err)
+	require.True(t, f.sharedWithMe)
+
+	_, err = alog.getFilters(`userid:alice OR userid:bob`)
+	require.Error(t, err)
+}
+
+func TestActivityCursor(t *testing.T) {
+	c := activityCursor{Timestamp: 10, EventID: "b"}
+	parsed, err := parseActivityCursor(c.encode())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	_, err = parseActivityCursor("invalid")
+	require.Error(t, err)
+
+	require.Equal(t, -1, compareCursors(activityCursor{Timestamp: 10, EventID: "a"}, c))
+	require.Equal(t, 1, compareCursors(activityCursor{Timestamp: 11, EventID: "a"}, c))
+	require.Equal(t, 0, compareCursors(c, c))
+}
+
+func eventIDs(acts []RawActivity) []string {
+	ids := make([]string, 0, len(acts))
+	for _, a := range acts {
+		ids = append(ids, a.EventID)
+	}
+	return ids
+}
+
 func activitites(acts ...interface{}) []RawActivity {
 	var activities []RawActivity
 	act := RawActivity{}
@@ -179,3 +304,70 @@ func resourceInfo(id, parentID string) *provider.ResourceInfo {
 		},
 	}
 }
+
+func TestSharedWithMeActivities(t *testing.T) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gwc := &cs3mocks.GatewayAPIClient{}
+	alog := &ActivitylogService{
+		store: store.Create(),
+		gws: pool.GetSelector[gateway.GatewayAPIClient]("GatewaySelector", "com.owncloud.api.gateway", func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+			return gwc
+		}),
+	}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for id, acts := range map[string][]RawActivity{
+		"shared":  {{EventID: "before", Timestamp: ts.Add(-time.Minute)}, {EventID: "after", Timestamp: ts.Add(time.Minute)}},
+		"pending": {{EventID: "pending", Timestamp: ts.Add(time.Minute)}},
+	} {
+		for _, act := range acts {
+			require.NoError(t, alog.appendActivity(storagespace.FormatResourceID(resourceID(id)), act, _maxActivities))
+		}
+	}
+
+	gwc.On("ListReceivedShares", mock.Anything, mock.Anything).Return(&collaboration.ListReceivedSharesResponse{
+		Status: status.NewOK(context.Background()),
+		Shares: []*collaboration.ReceivedShare{
+			{State: collaboration.ShareState_SHARE_STATE_ACCEPTED, Share: &collaboration.Share{ResourceId: resourceID("shared"), Ctime: utils.TimeToTS(ts)}},
+			{State: collaboration.ShareState_SHARE_STATE_PENDING, Share: &collaboration.Share{ResourceId: resourceID("pending"), Ctime: utils.TimeToTS(ts)}},
+		},
+	}, nil)
+
+	acts, err := alog.SharedWithMeActivities(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"after"}, eventIDs(acts))
+}
+
+func TestAccessCheck(t *testing.T) {
+	gwc := &cs3mocks.GatewayAPIClient{}
+	stat := func(id string, res *provider.StatResponse) {
+		gwc.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == id
+		})).Return(res, nil).Once()
+	}
+	ctx := context.Background()
+	stat("manager", &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{PermissionSet: &provider.ResourcePermissions{Stat: true, ListGrants: true}}})
+	stat("viewer", &provider.StatResponse{Status: status.NewOK(ctx), Info: &provider.ResourceInfo{PermissionSet: &provider.ResourcePermissions{Stat: true}}})
+	stat("gone", &provider.StatResponse{Status: status.NewNotFound(ctx, "not found")})
+	stat("denied", &provider.StatResponse{Status: status.NewPermissionDenied(ctx, nil, "denied")})
+
+	access := newAccessCheck(ctx, gwc, func(p *provider.ResourcePermissions) bool { return p.GetListGrants() })
+	for _, tc := range []struct {
+		id      string
+		allowed bool
+		gone    bool
+	}{
+		{id: "manager", allowed: true},
+		{id: "viewer"},
+		{id: "gone", gone: true},
+		{id: "denied"},
+		// the results are cached, the resources are only looked up once
+		{id: "manager", allowed: true},
+		{id: "gone", gone: true},
+	} {
+		allowed, gone := access.allowed(storagespace.FormatResourceID(resourceID(tc.id)))
+		require.Equal(t, tc.allowed, allowed, tc.id)
+		require.Equal(t, tc.gone, gone, tc.id)
+	}
+	gwc.AssertExpectations(t)
+}
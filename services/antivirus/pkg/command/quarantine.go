@@ -0,0 +1,167 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+)
+
+// Quarantine wraps the quarantine related sub-commands.
+func Quarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "quarantine",
+		Usage: "manage quarantined files",
+		Subcommands: []*cli.Command{
+			listQuarantine(cfg),
+			releaseQuarantine(cfg),
+			purgeQuarantine(cfg),
+		},
+	}
+}
+
+func listQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "Print a list of all quarantined files",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "output as json",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			q, err := newQuarantine(cfg)
+			if err != nil {
+				return err
+			}
+
+			items, err := q.List(c.Context)
+			if err != nil {
+				return fmt.Errorf("could not list quarantined files: %w", err)
+			}
+
+			if c.Bool("json") {
+				j, err := json.Marshal(items)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(j))
+				return nil
+			}
+
+			table := tablewriter.NewTable(os.Stdout)
+			table.Header("Id", "Name", "Space", "Path", "Size", "Owner", "Executant", "Scan Date", "Scan Result")
+			for _, item := range items {
+				table.Append([]string{
+					item.ID,
+					item.Name,
+					item.SpaceID,
+					item.Path,
+					strconv.FormatUint(item.Size, 10),
+					item.Owner,
+					item.ExecutingUser,
+					item.ScanTime.Format(time.RFC3339),
+					item.Description,
+				})
+			}
+			return table.Render()
+		},
+	}
+}
+
+func releaseQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "release",
+		Usage:     "Restore a quarantined file to its original location. The same content will not be quarantined again.",
+		ArgsUsage: "['id' required]",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			id := c.Args().First()
+			if id == "" {
+				_ = cli.ShowSubcommandHelp(c)
+				return fmt.Errorf("id is required")
+			}
+
+			q, err := newQuarantine(cfg)
+			if err != nil {
+				return err
+			}
+
+			if err := q.Release(c.Context, id); err != nil {
+				return fmt.Errorf("could not release '%s': %w", id, err)
+			}
+
+			fmt.Printf("Released '%s'\n", id)
+			return nil
+		},
+	}
+}
+
+func purgeQuarantine(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "purge",
+		Usage:     "Irrevocably delete a quarantined file",
+		ArgsUsage: "['id' required]",
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			id := c.Args().First()
+			if id == "" {
+				_ = cli.ShowSubcommandHelp(c)
+				return fmt.Errorf("id is required")
+			}
+
+			q, err := newQuarantine(cfg)
+			if err != nil {
+				return err
+			}
+
+			if err := q.Purge(c.Context, id); err != nil {
+				return fmt.Errorf("could not purge '%s': %w", id, err)
+			}
+
+			fmt.Printf("Purged '%s'\n", id)
+			return nil
+		},
+	}
+}
+
+func newQuarantine(cfg *config.Config) (*quarantine.Quarantine, error) {
+	if cfg.ServiceAccount.ServiceAccountID == "" || cfg.ServiceAccount.ServiceAccountSecret == "" {
+		return nil, fmt.Errorf("a service account is required to manage the quarantine")
+	}
+
+	tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+	if err != nil {
+		return nil, err
+	}
+	gatewaySelector, err := pool.GatewaySelector(
+		cfg.RevaGateway,
+		pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+		pool.WithTLSMode(tm),
+		pool.WithRegistry(registry.GetRegistry()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get reva client selector: %s", err)
+	}
+
+	return quarantine.New(gatewaySelector, cfg.ServiceAccount.ServiceAccountID, cfg.ServiceAccount.ServiceAccountSecret, cfg.Quarantine.SpaceID, cfg.Quarantine.SpaceName), nil
+}
@@ -14,6 +14,7 @@ func GetCommands(cfg *config.Config) cli.Commands {
 		Server(cfg),
 		Health(cfg),
 		Version(cfg),
+		Quarantine(cfg),
 	}
 }
 
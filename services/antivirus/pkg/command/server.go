@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os/signal"
 
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/urfave/cli/v2"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/runner"
 	"github.com/owncloud/ocis/v2/ocis-pkg/tracing"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
@@ -47,9 +49,24 @@ func Server(cfg *config.Config) *cli.Command {
 				return err
 			}
 
+			tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+			if err != nil {
+				return err
+			}
+			gatewaySelector, err := pool.GatewaySelector(
+				cfg.RevaGateway,
+				pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+				pool.WithTLSMode(tm),
+				pool.WithRegistry(registry.GetRegistry()),
+				pool.WithTracerProvider(traceProvider),
+			)
+			if err != nil {
+				return fmt.Errorf("could not get reva client selector: %s", err)
+			}
+
 			gr := runner.NewGroup()
 			{
-				svc, err := service.NewAntivirus(cfg, logger, traceProvider)
+				svc, err := service.NewAntivirus(cfg, logger, traceProvider, gatewaySelector)
 				if err != nil {
 					return err
 				}
@@ -3,10 +3,14 @@ package config
 import (
 	"context"
 	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 )
 
 // Config combines all available configuration parts.
 type Config struct {
+	Commons *shared.Commons `yaml:"-"` // don't use this directly as configuration for a service
+
 	File string
 	Log  *Log
 
@@ -16,10 +20,16 @@ type Config struct {
 
 	Tracing *Tracing `yaml:"tracing"`
 
-	InfectedFileHandling string `yaml:"infected-file-handling" env:"ANTIVIRUS_INFECTED_FILE_HANDLING" desc:"Defines the behaviour when a virus has been found. Supported options are: 'delete', 'continue' and 'abort '. Delete will delete the file. Continue will mark the file as infected but continues further processing. Abort will keep the file in the uploads folder for further admin inspection and will not move it to its final destination." introductionVersion:"pre5.0"`
+	InfectedFileHandling string `yaml:"infected-file-handling" env:"ANTIVIRUS_INFECTED_FILE_HANDLING" desc:"Defines the behaviour when a virus has been found. Supported options are: 'delete', 'continue', 'abort' and 'quarantine'. Delete will delete the file. Continue will mark the file as infected but continues further processing. Abort will keep the file in the uploads folder for further admin inspection and will not move it to its final destination. Quarantine will move the file into the quarantine space and delete the upload." introductionVersion:"pre5.0"`
 	Events               Events
 	Workers              int `yaml:"workers" env:"ANTIVIRUS_WORKERS" desc:"The number of concurrent go routines that fetch events from the event queue." introductionVersion:"7.0.0"`
 
+	Quarantine Quarantine `yaml:"quarantine"`
+
+	GRPCClientTLS  *shared.GRPCClientTLS `yaml:"grpc_client_tls"`
+	RevaGateway    string                `yaml:"reva_gateway" env:"OCIS_REVA_GATEWAY" desc:"CS3 gateway used to manage the quarantine space." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ServiceAccount ServiceAccount        `yaml:"service_account" mask:"struct"`
+
 	Scanner     Scanner
 	MaxScanSize string `yaml:"max-scan-size" env:"ANTIVIRUS_MAX_SCAN_SIZE" desc:"The maximum scan size the virus scanner can handle. Only this many bytes of a file will be scanned. 0 means unlimited and is the default. Usable common abbreviations: [KB, KiB, MB, MiB, GB, GiB, TB, TiB, PB, PiB, EB, EiB], example: 2GB." introductionVersion:"pre5.0"`
 
@@ -62,10 +72,12 @@ type Events struct {
 
 // Scanner provides configuration options for the virus scanner
 type Scanner struct {
-	Type string `yaml:"type" env:"ANTIVIRUS_SCANNER_TYPE" desc:"The antivirus scanner to use. Supported values are 'clamav' and 'icap'." introductionVersion:"pre5.0"`
+	Type string `yaml:"type" env:"ANTIVIRUS_SCANNER_TYPE" desc:"The antivirus scanner to use. Supported values are 'clamav', 'icap', 'signature' and 'chain'." introductionVersion:"pre5.0"`
 
-	ClamAV ClamAV // only if Type == clamav
-	ICAP   ICAP   // only if Type == icap
+	ClamAV    ClamAV    // only if Type == clamav
+	ICAP      ICAP      // only if Type == icap
+	Signature Signature // only if Type == signature
+	Chain     Chain     // only if Type == chain
 }
 
 // ClamAV provides configuration option for clamav
@@ -79,3 +91,29 @@ type ICAP struct {
 	URL     string        `yaml:"url" env:"ANTIVIRUS_ICAP_URL" desc:"URL of the ICAP server." introductionVersion:"pre5.0"`
 	Service string        `yaml:"service" env:"ANTIVIRUS_ICAP_SERVICE" desc:"The name of the ICAP service." introductionVersion:"pre5.0"`
 }
+
+// Signature provides configuration options for the in-process signature scanner
+type Signature struct {
+	RulesDir       string        `yaml:"rules_dir" env:"ANTIVIRUS_SIGNATURE_RULES_DIR" desc:"Path to the directory containing the signature rules. Supported are hash blocklists ('*.sha256', '*.md5'), file magic rules ('*.magic') and yara style rules ('*.yar', '*.yara'). If not set, only the EICAR test file is detected." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"ANTIVIRUS_SIGNATURE_RELOAD_INTERVAL" desc:"The interval in which the rules directory is checked for changes. Changed rules are reloaded without a restart. Set to '0' to disable reloading. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	DetectEICAR    bool          `yaml:"detect_eicar" env:"ANTIVIRUS_SIGNATURE_DETECT_EICAR" desc:"Detect the EICAR anti-malware test file." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// Chain provides configuration options for running multiple scanners on the same file
+type Chain struct {
+	Scanners    []string      `yaml:"scanners" env:"ANTIVIRUS_CHAIN_SCANNERS" desc:"A comma-separated list of scanners to run on each file. Supported values are 'clamav', 'icap' and 'signature'. Each scanner is configured by its own environment variables. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Aggregation string        `yaml:"aggregation" env:"ANTIVIRUS_CHAIN_AGGREGATION" desc:"Defines how the results of the chained scanners are combined. Supported values are 'any-infected', 'majority' and 'all-must-succeed'. See the documentation for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Timeout     time.Duration `yaml:"scanner_timeout" env:"ANTIVIRUS_CHAIN_SCANNER_TIMEOUT" desc:"The time each chained scanner has to finish a scan. A scanner not finishing in time is treated as failed. Set to '0' to wait indefinitely. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// Quarantine provides configuration options for the quarantine space
+type Quarantine struct {
+	SpaceID   string `yaml:"space_id" env:"ANTIVIRUS_QUARANTINE_SPACE_ID" desc:"The ID of the space infected files are moved to when ANTIVIRUS_INFECTED_FILE_HANDLING is set to 'quarantine'. The space is created if it does not exist." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	SpaceName string `yaml:"space_name" env:"ANTIVIRUS_QUARANTINE_SPACE_NAME" desc:"The name of the quarantine space, used when the space is created." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// ServiceAccount is the configuration for the used service account
+type ServiceAccount struct {
+	ServiceAccountID     string `yaml:"service_account_id" env:"OCIS_SERVICE_ACCOUNT_ID;ANTIVIRUS_SERVICE_ACCOUNT_ID" desc:"The ID of the service account the service should use. See the 'auth-service' service description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ServiceAccountSecret string `yaml:"service_account_secret" env:"OCIS_SERVICE_ACCOUNT_SECRET;ANTIVIRUS_SERVICE_ACCOUNT_SECRET" desc:"The service account secret." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%" mask:"password"`
+}
@@ -3,6 +3,8 @@ package defaults
 import (
 	"time"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/ocis-pkg/structs"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
 )
 
@@ -30,6 +32,11 @@ func DefaultConfig() *config.Config {
 		},
 		Workers:              10,
 		InfectedFileHandling: "delete",
+		Quarantine: config.Quarantine{
+			SpaceID:   "b2d1c5a8-6f3e-4d0a-9c7b-2e8f4a1d6c93",
+			SpaceName: "Quarantine",
+		},
+		RevaGateway: shared.DefaultRevaConfig().Address,
 		Scanner: config.Scanner{
 			Type: "clamav",
 			ClamAV: config.ClamAV{
@@ -40,6 +47,15 @@ func DefaultConfig() *config.Config {
 				Service: "avscan",
 				Timeout: 5 * time.Minute,
 			},
+			Signature: config.Signature{
+				ReloadInterval: time.Minute,
+				DetectEICAR:    true,
+			},
+			Chain: config.Chain{
+				Scanners:    []string{"clamav"},
+				Aggregation: "any-infected",
+				Timeout:     5 * time.Minute,
+			},
 		},
 	}
 }
@@ -53,6 +69,12 @@ func EnsureDefaults(cfg *config.Config) {
 	if cfg.Tracing == nil {
 		cfg.Tracing = &config.Tracing{}
 	}
+
+	if cfg.GRPCClientTLS == nil && cfg.Commons != nil {
+		cfg.GRPCClientTLS = structs.CopyOrZeroValue(cfg.Commons.GRPCClientTLS)
+	} else if cfg.GRPCClientTLS == nil {
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+	}
 }
 
 // Sanitize sanitizes the configuration
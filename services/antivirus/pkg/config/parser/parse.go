@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config/defaults"
 
@@ -34,5 +35,14 @@ func ParseConfig(cfg *config.Config) error {
 
 // Validate validates our little config
 func Validate(cfg *config.Config) error {
+	if cfg.InfectedFileHandling == "quarantine" {
+		if cfg.ServiceAccount.ServiceAccountID == "" {
+			return shared.MissingServiceAccountID(cfg.Service.Name)
+		}
+		if cfg.ServiceAccount.ServiceAccountSecret == "" {
+			return shared.MissingServiceAccountSecret(cfg.Service.Name)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,549 @@
+// Package quarantine keeps infected files in a dedicated storage space instead of deleting them.
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/errtypes"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/rhttp"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// _metadataPrefix is the prefix of all arbitrary metadata keys written by the quarantine
+	_metadataPrefix = "ocis.quarantine."
+	// _releasedFolder holds markers for the released files which are not scanned yet
+	_releasedFolder = ".released"
+	// _spaceType is the type of the quarantine space. Spaces of this type can't be shared,
+	// so their content is only accessible to the service account.
+	_spaceType = "quarantine"
+	// _transferHeader holds the header key for the reva transfer token
+	_transferHeader = "X-Reva-Transfer"
+)
+
+var (
+	// ErrNotFound is returned when a quarantined item does not exist.
+	ErrNotFound = errors.New("quarantined item not found")
+
+	_metadataKeys = []string{
+		_metadataPrefix + "name",
+		_metadataPrefix + "spaceid",
+		_metadataPrefix + "parentid",
+		_metadataPrefix + "path",
+		_metadataPrefix + "owner",
+		_metadataPrefix + "executinguser",
+		_metadataPrefix + "description",
+		_metadataPrefix + "checksum",
+		_metadataPrefix + "scantime",
+	}
+)
+
+// Item is an infected file kept in the quarantine space
+type Item struct {
+	// ID is the id of the upload which was found to be infected
+	ID string
+	// Name is the original file name
+	Name string
+	// SpaceID is the id of the space the file was uploaded to
+	SpaceID string
+	// ParentID is the resource id of the folder the file was uploaded to
+	ParentID string
+	// Path is the original path of the file relative to its space
+	Path string
+	// Owner is the id of the owner of the original file
+	Owner string
+	// ExecutingUser is the id of the user who uploaded the file
+	ExecutingUser string
+	// Description is the verdict of the virus scanner
+	Description string
+	// Checksum is the hex encoded sha256 checksum of the file content
+	Checksum string
+	// ScanTime is the time the infection was detected
+	ScanTime time.Time
+	// QuarantineTime is the time the file was moved into the quarantine
+	QuarantineTime time.Time
+	// Size is the size of the file in bytes
+	Size uint64
+}
+
+// Quarantine manages the quarantine space
+type Quarantine struct {
+	gatewaySelector      pool.Selectable[gateway.GatewayAPIClient]
+	serviceAccountID     string
+	serviceAccountSecret string
+	spaceID              string
+	spaceName            string
+	client               *http.Client
+
+	mu   sync.Mutex
+	root *provider.ResourceId
+}
+
+// New returns a Quarantine storing its items in the space with the given id
+func New(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], serviceAccountID, serviceAccountSecret, spaceID, spaceName string) *Quarantine {
+	return &Quarantine{
+		gatewaySelector:      gatewaySelector,
+		serviceAccountID:     serviceAccountID,
+		serviceAccountSecret: serviceAccountSecret,
+		spaceID:              spaceID,
+		spaceName:            spaceName,
+		client:               rhttp.GetHTTPClient(rhttp.Insecure(true)),
+	}
+}
+
+// SpaceID returns the id of the quarantine space
+func (q *Quarantine) SpaceID() string {
+	return q.spaceID
+}
+
+// Add stores the given content in the quarantine space.
+// The original location of the item is read from the still existing resource of the upload.
+func (q *Quarantine) Add(ctx context.Context, item Item, resourceID *provider.ResourceId, content io.Reader) error {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return err
+	}
+
+	sRes, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: resourceID, Path: "."}})
+	if err := checkStatus(sRes.GetStatus(), err); err != nil {
+		return fmt.Errorf("could not stat original location: %w", err)
+	}
+
+	info := sRes.GetInfo()
+	item.SpaceID = info.GetSpace().GetId().GetOpaqueId()
+	if item.SpaceID == "" {
+		item.SpaceID = resourceID.GetSpaceId()
+	}
+	item.ParentID = storagespace.FormatResourceID(info.GetParentId())
+	item.Path = info.GetPath()
+	item.Owner = info.GetOwner().GetOpaqueId()
+	if item.Name == "" {
+		item.Name = info.GetName()
+	}
+
+	ref := &provider.Reference{ResourceId: root, Path: utils.MakeRelativePath(item.ID)}
+	if err := q.upload(ctx, gwc, ref, content, int64(item.Size)); err != nil {
+		return err
+	}
+
+	md := map[string]string{
+		_metadataPrefix + "name":          item.Name,
+		_metadataPrefix + "spaceid":       item.SpaceID,
+		_metadataPrefix + "parentid":      item.ParentID,
+		_metadataPrefix + "path":          item.Path,
+		_metadataPrefix + "owner":         item.Owner,
+		_metadataPrefix + "executinguser": item.ExecutingUser,
+		_metadataPrefix + "description":   item.Description,
+		_metadataPrefix + "checksum":      item.Checksum,
+		_metadataPrefix + "scantime":      strconv.FormatInt(item.ScanTime.Unix(), 10),
+	}
+	res, err := gwc.SetArbitraryMetadata(ctx, &provider.SetArbitraryMetadataRequest{
+		Ref:               ref,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: md},
+	})
+	return checkStatus(res.GetStatus(), err)
+}
+
+// List returns all quarantined items
+func (q *Quarantine) List(ctx context.Context) ([]Item, error) {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gwc.ListContainer(ctx, &provider.ListContainerRequest{
+		Ref:                   &provider.Reference{ResourceId: root, Path: "."},
+		ArbitraryMetadataKeys: _metadataKeys,
+	})
+	if err := checkStatus(res.GetStatus(), err); err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(res.GetInfos()))
+	for _, info := range res.GetInfos() {
+		if info.GetType() != provider.ResourceType_RESOURCE_TYPE_FILE {
+			continue
+		}
+
+		items = append(items, itemFromInfo(info))
+	}
+
+	return items, nil
+}
+
+// Get returns the quarantined item with the given id
+func (q *Quarantine) Get(ctx context.Context, id string) (Item, error) {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return Item{}, err
+	}
+
+	info, err := q.stat(ctx, gwc, id)
+	if err != nil {
+		return Item{}, err
+	}
+
+	return itemFromInfo(info), nil
+}
+
+// Release restores the quarantined item to its original location and removes it from the quarantine.
+// A release marker is kept until the restored file is scanned, see ConsumeRelease.
+func (q *Quarantine) Release(ctx context.Context, id string) error {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return err
+	}
+
+	info, err := q.stat(ctx, gwc, id)
+	if err != nil {
+		return err
+	}
+
+	item := itemFromInfo(info)
+	parent, err := storagespace.ParseID(item.ParentID)
+	if err != nil {
+		return fmt.Errorf("invalid original location of %s: %w", id, err)
+	}
+
+	content, err := q.download(ctx, gwc, &provider.Reference{ResourceId: info.GetId(), Path: "."})
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	// the restored file may be scanned before the upload request returns, so the marker
+	// has to exist before the upload and is removed again if the upload fails
+	var marker *provider.Reference
+	if item.Checksum != "" {
+		if marker, err = q.addReleaseMarker(ctx, gwc, root, parent.GetSpaceId(), item.Checksum); err != nil {
+			return err
+		}
+	}
+
+	target := &provider.Reference{ResourceId: &parent, Path: utils.MakeRelativePath(item.Name)}
+	if err := q.upload(ctx, gwc, target, content, int64(item.Size)); err != nil {
+		if marker != nil {
+			dRes, dErr := gwc.Delete(ctx, &provider.DeleteRequest{Ref: marker})
+			if dErr := checkStatus(dRes.GetStatus(), dErr); dErr != nil {
+				return fmt.Errorf("%w, could not remove release marker: %s", err, dErr)
+			}
+		}
+		return err
+	}
+
+	return q.purge(ctx, gwc, root, info)
+}
+
+// Purge irrevocably deletes the quarantined item
+func (q *Quarantine) Purge(ctx context.Context, id string) error {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return err
+	}
+
+	info, err := q.stat(ctx, gwc, id)
+	if err != nil {
+		return err
+	}
+
+	return q.purge(ctx, gwc, root, info)
+}
+
+// ConsumeRelease reports whether content with the given sha256 checksum was released into the space
+// and is not scanned yet. The release marker is removed, so it only passes the restored file once.
+// Callers must make sure the scanned upload was executed by the service account of the quarantine.
+func (q *Quarantine) ConsumeRelease(ctx context.Context, spaceID, checksum string) (bool, error) {
+	ctx, gwc, err := q.authenticate(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := gwc.Stat(ctx, &provider.StatRequest{
+		Ref: &provider.Reference{ResourceId: root, Path: releaseMarkerPath(spaceID, checksum)},
+	})
+	switch err := checkStatus(res.GetStatus(), err); {
+	case errors.As(err, new(errtypes.NotFound)):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+
+	if err := q.purge(ctx, gwc, root, res.GetInfo()); err != nil {
+		return false, fmt.Errorf("could not remove release marker: %w", err)
+	}
+
+	return true, nil
+}
+
+// ServiceAccountID returns the id of the service account which restores released files
+func (q *Quarantine) ServiceAccountID() string {
+	return q.serviceAccountID
+}
+
+func (q *Quarantine) authenticate(ctx context.Context) (context.Context, gateway.GatewayAPIClient, error) {
+	gwc, err := q.gatewaySelector.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, err = utils.GetServiceUserContextWithContext(ctx, gwc, q.serviceAccountID, q.serviceAccountSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ctx, gwc, nil
+}
+
+// ensureSpace returns the root of the quarantine space and creates the space if needed
+func (q *Quarantine) ensureSpace(ctx context.Context, gwc gateway.GatewayAPIClient) (*provider.ResourceId, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.root != nil {
+		return q.root, nil
+	}
+
+	space, err := utils.GetSpace(ctx, q.spaceID, gwc)
+	if err == nil {
+		q.root = space.GetRoot()
+		return q.root, nil
+	}
+
+	res, err := gwc.CreateStorageSpace(ctx, &provider.CreateStorageSpaceRequest{
+		Type:   _spaceType,
+		Name:   q.spaceName,
+		Opaque: utils.AppendPlainToOpaque(nil, "spaceid", q.spaceID),
+	})
+	if err := checkStatus(res.GetStatus(), err); err != nil {
+		return nil, fmt.Errorf("could not create quarantine space: %w", err)
+	}
+
+	q.root = res.GetStorageSpace().GetRoot()
+	return q.root, nil
+}
+
+// addReleaseMarker creates the marker for content released into the space with the given id
+func (q *Quarantine) addReleaseMarker(ctx context.Context, gwc gateway.GatewayAPIClient, root *provider.ResourceId, spaceID, checksum string) (*provider.Reference, error) {
+	ref := &provider.Reference{ResourceId: root, Path: utils.MakeRelativePath(_releasedFolder)}
+	cRes, err := gwc.CreateContainer(ctx, &provider.CreateContainerRequest{Ref: ref})
+	if err := checkStatus(cRes.GetStatus(), err); err != nil && !errors.As(err, new(errtypes.AlreadyExists)) {
+		return nil, err
+	}
+
+	ref = &provider.Reference{ResourceId: root, Path: releaseMarkerPath(spaceID, checksum)}
+	tRes, err := gwc.TouchFile(ctx, &provider.TouchFileRequest{Ref: ref})
+	if err := checkStatus(tRes.GetStatus(), err); err != nil && !errors.As(err, new(errtypes.AlreadyExists)) {
+		return nil, err
+	}
+
+	return ref, nil
+}
+
+func (q *Quarantine) stat(ctx context.Context, gwc gateway.GatewayAPIClient, id string) (*provider.ResourceInfo, error) {
+	root, err := q.ensureSpace(ctx, gwc)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gwc.Stat(ctx, &provider.StatRequest{
+		Ref:                   &provider.Reference{ResourceId: root, Path: utils.MakeRelativePath(id)},
+		ArbitraryMetadataKeys: _metadataKeys,
+	})
+	switch err := checkStatus(res.GetStatus(), err); {
+	case errors.As(err, new(errtypes.NotFound)):
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	case err != nil:
+		return nil, err
+	case res.GetInfo().GetType() != provider.ResourceType_RESOURCE_TYPE_FILE:
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+
+	return res.GetInfo(), nil
+}
+
+// purge deletes the item and removes it from the trash-bin of the quarantine space
+func (q *Quarantine) purge(ctx context.Context, gwc gateway.GatewayAPIClient, root *provider.ResourceId, info *provider.ResourceInfo) error {
+	dRes, err := gwc.Delete(ctx, &provider.DeleteRequest{Ref: &provider.Reference{ResourceId: info.GetId(), Path: "."}})
+	if err := checkStatus(dRes.GetStatus(), err); err != nil {
+		return err
+	}
+
+	pRes, err := gwc.PurgeRecycle(ctx, &provider.PurgeRecycleRequest{
+		Ref: &provider.Reference{ResourceId: root},
+		Key: info.GetId().GetOpaqueId(),
+	})
+	return checkStatus(pRes.GetStatus(), err)
+}
+
+func (q *Quarantine) upload(ctx context.Context, gwc gateway.GatewayAPIClient, ref *provider.Reference, content io.Reader, size int64) error {
+	res, err := gwc.InitiateFileUpload(ctx, &provider.InitiateFileUploadRequest{
+		Ref: ref,
+		Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+			"Upload-Length": {Decoder: "plain", Value: []byte(strconv.FormatInt(size, 10))},
+		}},
+	})
+	if err := checkStatus(res.GetStatus(), err); err != nil {
+		return err
+	}
+
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "simple" || p.GetProtocol() == "spaces" {
+			endpoint, token = p.GetUploadEndpoint(), p.GetToken()
+			break
+		}
+	}
+	if endpoint == "" {
+		return errors.New("no upload endpoint available")
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodPut, endpoint, content)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	q.setAuthHeaders(ctx, req, token)
+
+	hRes, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer hRes.Body.Close()
+
+	switch hRes.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return nil
+	default:
+		return fmt.Errorf("unexpected status code from upload %v", hRes.StatusCode)
+	}
+}
+
+func (q *Quarantine) download(ctx context.Context, gwc gateway.GatewayAPIClient, ref *provider.Reference) (io.ReadCloser, error) {
+	res, err := gwc.InitiateFileDownload(ctx, &provider.InitiateFileDownloadRequest{Ref: ref})
+	if err := checkStatus(res.GetStatus(), err); err != nil {
+		return nil, err
+	}
+
+	var endpoint, token string
+	for _, p := range res.GetProtocols() {
+		if p.GetProtocol() == "spaces" {
+			endpoint, token = p.GetDownloadEndpoint(), p.GetToken()
+			break
+		}
+	}
+	if endpoint == "" && len(res.GetProtocols()) > 0 {
+		endpoint, token = res.GetProtocols()[0].GetDownloadEndpoint(), res.GetProtocols()[0].GetToken()
+	}
+
+	req, err := rhttp.NewRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	q.setAuthHeaders(ctx, req, token)
+
+	hRes, err := q.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if hRes.StatusCode != http.StatusOK {
+		hRes.Body.Close()
+		return nil, fmt.Errorf("unexpected status code from download %v", hRes.StatusCode)
+	}
+
+	return hRes.Body, nil
+}
+
+func (q *Quarantine) setAuthHeaders(ctx context.Context, req *http.Request, transferToken string) {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if t := md.Get(revactx.TokenHeader); len(t) > 0 {
+			req.Header.Set(revactx.TokenHeader, t[0])
+		}
+	}
+
+	if transferToken != "" {
+		req.Header.Set(_transferHeader, transferToken)
+	}
+}
+
+// releaseMarkerPath returns the path of the release marker of content released into a space
+func releaseMarkerPath(spaceID, checksum string) string {
+	return utils.MakeRelativePath(path.Join(_releasedFolder, spaceID+"-"+checksum))
+}
+
+func itemFromInfo(info *provider.ResourceInfo) Item {
+	md := info.GetArbitraryMetadata().GetMetadata()
+	item := Item{
+		ID:             info.GetName(),
+		Name:           md[_metadataPrefix+"name"],
+		SpaceID:        md[_metadataPrefix+"spaceid"],
+		ParentID:       md[_metadataPrefix+"parentid"],
+		Path:           md[_metadataPrefix+"path"],
+		Owner:          md[_metadataPrefix+"owner"],
+		ExecutingUser:  md[_metadataPrefix+"executinguser"],
+		Description:    md[_metadataPrefix+"description"],
+		Checksum:       md[_metadataPrefix+"checksum"],
+		QuarantineTime: utils.TSToTime(info.GetMtime()),
+		Size:           info.GetSize(),
+	}
+
+	if ts, err := strconv.ParseInt(md[_metadataPrefix+"scantime"], 10, 64); err == nil {
+		item.ScanTime = time.Unix(ts, 0)
+	}
+
+	return item
+}
+
+func checkStatus(status *rpc.Status, err error) error {
+	switch {
+	case err != nil:
+		return err
+	case status.GetCode() == rpc.Code_CODE_OK:
+		return nil
+	case status == nil:
+		return errors.New("missing status in response")
+	default:
+		return errtypes.NewErrtypeFromStatus(status)
+	}
+}
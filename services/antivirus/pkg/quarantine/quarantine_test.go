@@ -0,0 +1,270 @@
+package quarantine_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+)
+
+func newQuarantine(t *testing.T) (*quarantine.Quarantine, *cs3mocks.GatewayAPIClient) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Token: "token"}, nil)
+	gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		StorageSpaces: []*provider.StorageSpace{{
+			Root: &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "quarantine"},
+		}},
+	}, nil)
+
+	return quarantine.New(gatewaySelector, "service-account", "secret", "quarantine", "Quarantine"), gatewayClient
+}
+
+func TestQuarantine_List(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	gatewayClient.On("ListContainer", mock.Anything, mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+		return req.GetRef().GetResourceId().GetSpaceId() == "quarantine" && len(req.GetArbitraryMetadataKeys()) > 0
+	})).Return(&provider.ListContainerResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		Infos: []*provider.ResourceInfo{
+			{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Name: ".released"},
+			{
+				Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+				Name: "upload-id",
+				Size: 68,
+				ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{
+					"ocis.quarantine.name":        "eicar.com",
+					"ocis.quarantine.spaceid":     "space-id",
+					"ocis.quarantine.parentid":    "storage$space-id!parent-id",
+					"ocis.quarantine.path":        "./folder/eicar.com",
+					"ocis.quarantine.owner":       "owner",
+					"ocis.quarantine.description": "Eicar-Signature",
+					"ocis.quarantine.scantime":    "1700000000",
+				}},
+			},
+		},
+	}, nil)
+
+	items, err := q.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, quarantine.Item{
+		ID:          "upload-id",
+		Name:        "eicar.com",
+		SpaceID:     "space-id",
+		ParentID:    "storage$space-id!parent-id",
+		Path:        "./folder/eicar.com",
+		Owner:       "owner",
+		Description: "Eicar-Signature",
+		ScanTime:    time.Unix(1700000000, 0),
+		Size:        68,
+	}, items[0])
+}
+
+func TestQuarantine_ConsumeRelease(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+		return req.GetRef().GetPath() == "./.released/space-id-known"
+	})).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: &provider.ResourceInfo{
+		Id: &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "marker"},
+	}}, nil)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil)
+	gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+		return req.GetRef().GetResourceId().GetOpaqueId() == "marker"
+	})).Return(&provider.DeleteResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+	gatewayClient.On("PurgeRecycle", mock.Anything, mock.MatchedBy(func(req *provider.PurgeRecycleRequest) bool {
+		return req.GetKey() == "marker"
+	})).Return(&provider.PurgeRecycleResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+
+	released, err := q.ConsumeRelease(context.Background(), "space-id", "known")
+	require.NoError(t, err)
+	assert.True(t, released)
+
+	released, err = q.ConsumeRelease(context.Background(), "other-space-id", "known")
+	require.NoError(t, err)
+	assert.False(t, released)
+
+	released, err = q.ConsumeRelease(context.Background(), "space-id", "unknown")
+	require.NoError(t, err)
+	assert.False(t, released)
+	gatewayClient.AssertExpectations(t)
+}
+
+func TestQuarantine_Get(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil)
+
+	_, err := q.Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, quarantine.ErrNotFound)
+}
+
+// newDataServer returns a server receiving uploads and serving the given content for downloads
+func newDataServer(t *testing.T, content string, status int) (*httptest.Server, *bytes.Buffer) {
+	var uploaded bytes.Buffer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			_, _ = io.Copy(&uploaded, r.Body)
+			w.WriteHeader(status)
+		case http.MethodGet:
+			_, _ = io.WriteString(w, content)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &uploaded
+}
+
+func expectUpload(gatewayClient *cs3mocks.GatewayAPIClient, srv *httptest.Server, path string) {
+	gatewayClient.On("InitiateFileUpload", mock.Anything, mock.MatchedBy(func(req *provider.InitiateFileUploadRequest) bool {
+		return req.GetRef().GetPath() == path
+	})).Return(&gateway.InitiateFileUploadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: srv.URL, Token: "transfer"}},
+	}, nil)
+}
+
+func quarantinedItem() *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+		Id:   &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "item"},
+		Name: "upload-id",
+		Size: 7,
+		ArbitraryMetadata: &provider.ArbitraryMetadata{Metadata: map[string]string{
+			"ocis.quarantine.name":     "eicar.com",
+			"ocis.quarantine.parentid": "storage$space-id!parent-id",
+			"ocis.quarantine.checksum": "checksum",
+		}},
+	}
+}
+
+func TestQuarantine_Add(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	srv, uploaded := newDataServer(t, "", http.StatusCreated)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: &provider.ResourceInfo{
+		Name:     "eicar.com",
+		Path:     "./folder/eicar.com",
+		ParentId: &provider.ResourceId{StorageId: "storage", SpaceId: "space-id", OpaqueId: "parent-id"},
+		Owner:    &userpb.UserId{OpaqueId: "owner"},
+	}}, nil)
+	expectUpload(gatewayClient, srv, "./upload-id")
+	gatewayClient.On("SetArbitraryMetadata", mock.Anything, mock.MatchedBy(func(req *provider.SetArbitraryMetadataRequest) bool {
+		md := req.GetArbitraryMetadata().GetMetadata()
+		return req.GetRef().GetPath() == "./upload-id" &&
+			md["ocis.quarantine.parentid"] == "storage$space-id!parent-id" &&
+			md["ocis.quarantine.path"] == "./folder/eicar.com" &&
+			md["ocis.quarantine.owner"] == "owner" &&
+			md["ocis.quarantine.spaceid"] == "space-id" &&
+			md["ocis.quarantine.checksum"] == "checksum"
+	})).Return(&provider.SetArbitraryMetadataResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+
+	err := q.Add(context.Background(), quarantine.Item{ID: "upload-id", Checksum: "checksum", Size: 7}, &provider.ResourceId{SpaceId: "space-id", OpaqueId: "file-id"}, strings.NewReader("content"))
+	require.NoError(t, err)
+	assert.Equal(t, "content", uploaded.String())
+	gatewayClient.AssertExpectations(t)
+}
+
+func TestQuarantine_Release(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	srv, uploaded := newDataServer(t, "content", http.StatusCreated)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: quarantinedItem()}, nil)
+	gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileDownloadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileDownloadProtocol{{Protocol: "spaces", DownloadEndpoint: srv.URL}},
+	}, nil)
+	gatewayClient.On("CreateContainer", mock.Anything, mock.Anything).Return(&provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_ALREADY_EXISTS}}, nil)
+	gatewayClient.On("TouchFile", mock.Anything, mock.MatchedBy(func(req *provider.TouchFileRequest) bool {
+		return req.GetRef().GetPath() == "./.released/space-id-checksum"
+	})).Return(&provider.TouchFileResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+	expectUpload(gatewayClient, srv, "./eicar.com")
+	gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+		return req.GetRef().GetResourceId().GetOpaqueId() == "item"
+	})).Return(&provider.DeleteResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+	gatewayClient.On("PurgeRecycle", mock.Anything, mock.Anything).Return(&provider.PurgeRecycleResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+
+	require.NoError(t, q.Release(context.Background(), "upload-id"))
+	assert.Equal(t, "content", uploaded.String())
+	gatewayClient.AssertExpectations(t)
+}
+
+func TestQuarantine_ReleaseFailed(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	srv, _ := newDataServer(t, "content", http.StatusInsufficientStorage)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: quarantinedItem()}, nil)
+	gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileDownloadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileDownloadProtocol{{Protocol: "spaces", DownloadEndpoint: srv.URL}},
+	}, nil)
+	gatewayClient.On("CreateContainer", mock.Anything, mock.Anything).Return(&provider.CreateContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+	gatewayClient.On("TouchFile", mock.Anything, mock.Anything).Return(&provider.TouchFileResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+	expectUpload(gatewayClient, srv, "./eicar.com")
+	// the release marker is removed, the item is kept
+	gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+		return req.GetRef().GetPath() == "./.released/space-id-checksum"
+	})).Return(&provider.DeleteResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+
+	require.Error(t, q.Release(context.Background(), "upload-id"))
+	gatewayClient.AssertExpectations(t)
+	gatewayClient.AssertNotCalled(t, "PurgeRecycle", mock.Anything, mock.Anything)
+}
+
+func TestQuarantine_Purge(t *testing.T) {
+	q, gatewayClient := newQuarantine(t)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: quarantinedItem()}, nil)
+	gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *provider.DeleteRequest) bool {
+		return req.GetRef().GetResourceId().GetOpaqueId() == "item"
+	})).Return(&provider.DeleteResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+	gatewayClient.On("PurgeRecycle", mock.Anything, mock.MatchedBy(func(req *provider.PurgeRecycleRequest) bool {
+		return req.GetKey() == "item" && req.GetRef().GetResourceId().GetSpaceId() == "quarantine"
+	})).Return(&provider.PurgeRecycleResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+
+	require.NoError(t, q.Purge(context.Background(), "upload-id"))
+	gatewayClient.AssertExpectations(t)
+}
+
+func TestQuarantine_CreatesSpace(t *testing.T) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient]("GatewaySelector", "com.owncloud.api.gateway", func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+		return gatewayClient
+	})
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Token: "token"}, nil)
+	gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+	gatewayClient.On("CreateStorageSpace", mock.Anything, mock.MatchedBy(func(req *provider.CreateStorageSpaceRequest) bool {
+		// the space must not be a project space, which could be shared with users
+		return req.GetType() == "quarantine" && req.GetName() == "Quarantine"
+	})).Return(&provider.CreateStorageSpaceResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, StorageSpace: &provider.StorageSpace{
+		Root: &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "quarantine"},
+	}}, nil).Once()
+	gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(&provider.ListContainerResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+
+	q := quarantine.New(gatewaySelector, "service-account", "secret", "quarantine", "Quarantine")
+	_, err := q.List(context.Background())
+	require.NoError(t, err)
+	gatewayClient.AssertExpectations(t)
+}
@@ -0,0 +1,181 @@
+package scanners
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Aggregation defines how the results of a Chain are combined into one verdict
+type Aggregation string
+
+const (
+	// AggregationAnyInfected reports a file as infected as soon as one scanner flags it.
+	// If no scanner flags it, the scan fails if any of the scanners fails.
+	AggregationAnyInfected Aggregation = "any-infected"
+	// AggregationMajority reports a file as infected if more than half of the scanners flag it.
+	AggregationMajority Aggregation = "majority"
+	// AggregationAllMustSucceed reports a file as infected as soon as one scanner flags it,
+	// but fails if any of the scanners fails.
+	AggregationAllMustSucceed Aggregation = "all-must-succeed"
+)
+
+var (
+	// ErrScanTimeout is returned when a scanner of a Chain did not finish in time.
+	ErrScanTimeout = errors.New("scan timeout")
+)
+
+// Engine is the interface implemented by all scanners which can be part of a Chain
+type Engine interface {
+	Scan(in Input) (Result, error)
+}
+
+// ChainLink is a named scanner taking part in a Chain
+type ChainLink struct {
+	Name    string
+	Engine  Engine
+	Timeout time.Duration
+}
+
+// NewChain returns a Scanner running all links on the same input and combining their results
+func NewChain(aggregation Aggregation, links ...ChainLink) (Chain, error) {
+	switch aggregation {
+	case AggregationAnyInfected, AggregationMajority, AggregationAllMustSucceed:
+	default:
+		return Chain{}, fmt.Errorf("unknown chain aggregation '%s'", aggregation)
+	}
+
+	if len(links) == 0 {
+		return Chain{}, errors.New("chain needs at least one scanner")
+	}
+
+	return Chain{aggregation: aggregation, links: links}, nil
+}
+
+// Chain is a Scanner combining the verdicts of multiple scanners
+type Chain struct {
+	aggregation Aggregation
+	links       []ChainLink
+}
+
+type chainResult struct {
+	link   ChainLink
+	result Result
+	err    error
+}
+
+// Scan to fulfill Scanner interface
+func (s Chain) Scan(in Input) (Result, error) {
+	// every scanner consumes the body, so it is buffered once in a temporary file
+	f, err := os.CreateTemp("", "antivirus-chain-*")
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	size, err := io.Copy(f, in.body())
+	if err != nil {
+		return Result{}, err
+	}
+
+	results := make([]chainResult, len(s.links))
+	wg := sync.WaitGroup{}
+	for i, link := range s.links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			linkIn := in
+			linkIn.Body = io.NewSectionReader(f, 0, size)
+			result, err := scanWithTimeout(link, linkIn)
+			results[i] = chainResult{link: link, result: result, err: err}
+		}()
+	}
+	wg.Wait()
+
+	var (
+		infected     []string
+		failed       []string
+		errs         []error
+		lastScanTime time.Time
+	)
+	// results are evaluated in chain order to get a stable description
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			failed = append(failed, r.link.Name)
+			errs = append(errs, fmt.Errorf("%s: %w", r.link.Name, r.err))
+			continue
+		case r.result.Infected:
+			infected = append(infected, fmt.Sprintf("%s: %s", r.link.Name, r.result.Description))
+		}
+
+		if r.result.ScanTime.After(lastScanTime) {
+			lastScanTime = r.result.ScanTime
+		}
+	}
+
+	result := Result{
+		ScanTime:    lastScanTime,
+		Description: strings.Join(infected, "; "),
+	}
+
+	switch s.aggregation {
+	case AggregationAnyInfected:
+		if len(infected) > 0 {
+			result.Infected = true
+			return result, nil
+		}
+
+		// a failed scanner could have flagged the file, so the file must not be reported as clean
+		if len(failed) > 0 {
+			return Result{}, errors.Join(errs...)
+		}
+	case AggregationMajority:
+		majority := len(s.links)/2 + 1
+		switch {
+		case len(infected) >= majority:
+			result.Infected = true
+		case len(infected)+len(failed) >= majority:
+			// the failed scanners could still tip the balance
+			return Result{}, errors.Join(errs...)
+		}
+	case AggregationAllMustSucceed:
+		if len(failed) > 0 {
+			return Result{}, errors.Join(errs...)
+		}
+
+		result.Infected = len(infected) > 0
+	}
+
+	if !result.Infected {
+		result.Description = ""
+	}
+
+	return result, nil
+}
+
+// scanWithTimeout cancels the context of the scan when the timeout of the link is exceeded
+func scanWithTimeout(link ChainLink, in Input) (Result, error) {
+	if link.Timeout <= 0 {
+		return link.Engine.Scan(in)
+	}
+
+	ctx, cancel := context.WithTimeout(in.context(), link.Timeout)
+	defer cancel()
+	in.Context = ctx
+	in.Body = contextReader{ctx: ctx, r: in.Body}
+
+	result, err := link.Engine.Scan(in)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return Result{}, fmt.Errorf("%w after %s", ErrScanTimeout, link.Timeout)
+	}
+	return result, err
+}
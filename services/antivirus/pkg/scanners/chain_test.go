@@ -0,0 +1,118 @@
+package scanners_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+)
+
+type fakeEngine struct {
+	infected    bool
+	description string
+	err         error
+	delay       time.Duration
+}
+
+func (e fakeEngine) Scan(in scanners.Input) (scanners.Result, error) {
+	if _, err := io.ReadAll(in.Body); err != nil {
+		return scanners.Result{}, err
+	}
+
+	if e.delay > 0 {
+		select {
+		case <-time.After(e.delay):
+		case <-in.Context.Done():
+			return scanners.Result{}, in.Context.Err()
+		}
+	}
+
+	return scanners.Result{Infected: e.infected, Description: e.description, ScanTime: time.Now()}, e.err
+}
+
+func TestChain_Scan(t *testing.T) {
+	var (
+		clean    = scanners.ChainLink{Name: "clean", Engine: fakeEngine{}}
+		infected = scanners.ChainLink{Name: "infected", Engine: fakeEngine{infected: true, description: "bad"}}
+		broken   = scanners.ChainLink{Name: "broken", Engine: fakeEngine{err: errors.New("offline")}}
+		scan     = func(chain scanners.Chain) (scanners.Result, error) {
+			return chain.Scan(scanners.Input{Body: strings.NewReader("content")})
+		}
+	)
+
+	t.Run("it rejects invalid configurations", func(t *testing.T) {
+		_, err := scanners.NewChain("unknown", clean)
+		assert.Error(t, err)
+
+		_, err = scanners.NewChain(scanners.AggregationAnyInfected)
+		assert.Error(t, err)
+	})
+
+	t.Run("any-infected", func(t *testing.T) {
+		chain, err := scanners.NewChain(scanners.AggregationAnyInfected, clean, infected, broken)
+		require.NoError(t, err)
+
+		result, err := scan(chain)
+		assert.NoError(t, err)
+		assert.True(t, result.Infected)
+		assert.Equal(t, "infected: bad", result.Description)
+
+		chain, _ = scanners.NewChain(scanners.AggregationAnyInfected, clean, clean)
+		result, err = scan(chain)
+		assert.NoError(t, err)
+		assert.False(t, result.Infected)
+
+		chain, _ = scanners.NewChain(scanners.AggregationAnyInfected, clean, broken)
+		_, err = scan(chain)
+		assert.Error(t, err)
+
+		chain, _ = scanners.NewChain(scanners.AggregationAnyInfected, broken)
+		_, err = scan(chain)
+		assert.Error(t, err)
+	})
+
+	t.Run("majority", func(t *testing.T) {
+		chain, _ := scanners.NewChain(scanners.AggregationMajority, clean, infected, infected)
+		result, err := scan(chain)
+		assert.NoError(t, err)
+		assert.True(t, result.Infected)
+		assert.Equal(t, "infected: bad; infected: bad", result.Description)
+
+		chain, _ = scanners.NewChain(scanners.AggregationMajority, clean, clean, infected)
+		result, err = scan(chain)
+		assert.NoError(t, err)
+		assert.False(t, result.Infected)
+		assert.Empty(t, result.Description)
+
+		chain, _ = scanners.NewChain(scanners.AggregationMajority, clean, infected, broken)
+		_, err = scan(chain)
+		assert.Error(t, err)
+	})
+
+	t.Run("all-must-succeed", func(t *testing.T) {
+		chain, _ := scanners.NewChain(scanners.AggregationAllMustSucceed, clean, infected)
+		result, err := scan(chain)
+		assert.NoError(t, err)
+		assert.True(t, result.Infected)
+
+		chain, _ = scanners.NewChain(scanners.AggregationAllMustSucceed, infected, broken)
+		_, err = scan(chain)
+		assert.Error(t, err)
+	})
+
+	t.Run("it enforces the scanner timeout", func(t *testing.T) {
+		slow := scanners.ChainLink{Name: "slow", Engine: fakeEngine{delay: time.Second}, Timeout: 10 * time.Millisecond}
+		chain, _ := scanners.NewChain(scanners.AggregationAllMustSucceed, clean, slow)
+		start := time.Now()
+		_, err := scan(chain)
+		assert.ErrorIs(t, err, scanners.ErrScanTimeout)
+		// the slow scanner was cancelled instead of running in the background
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+}
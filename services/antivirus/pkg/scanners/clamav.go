@@ -1,6 +1,8 @@
 package scanners
 
 import (
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/dutchcoders/go-clamd"
@@ -20,12 +22,36 @@ type ClamAV struct {
 
 // Scan to fulfill Scanner interface
 func (s ClamAV) Scan(in Input) (Result, error) {
-	ch, err := s.clamd.ScanStream(in.Body, make(chan bool))
+	// closing abort closes the connection to clamd
+	abort := make(chan bool)
+	var once sync.Once
+	stop := func() { once.Do(func() { close(abort) }) }
+	defer stop()
+
+	ctx := in.context()
+	go func() {
+		select {
+		case <-ctx.Done():
+			stop()
+		case <-abort:
+		}
+	}()
+
+	ch, err := s.clamd.ScanStream(in.body(), abort)
 	if err != nil {
 		return Result{}, err
 	}
 
-	r := <-ch
+	var r *clamd.ScanResult
+	select {
+	case r = <-ch:
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+	if r == nil {
+		return Result{}, errors.New("clamd closed the connection without result")
+	}
+
 	return Result{
 		Infected:    r.Status == clamd.RES_FOUND,
 		Description: r.Description,
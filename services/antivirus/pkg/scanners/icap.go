@@ -1,7 +1,6 @@
 package scanners
 
 import (
-	"context"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -47,7 +46,7 @@ type ICAP struct {
 
 // Scan scans a file using the ICAP server
 func (s ICAP) Scan(in Input) (Result, error) {
-	ctx := context.TODO()
+	ctx := in.context()
 	result := Result{}
 
 	optReq, err := ic.NewRequest(ctx, ic.MethodOPTIONS, s.URL, nil, nil)
@@ -60,7 +59,7 @@ func (s ICAP) Scan(in Input) (Result, error) {
 		return result, err
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPost, in.Url, in.Body)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, in.Url, in.body())
 	if err != nil {
 		return result, err
 	}
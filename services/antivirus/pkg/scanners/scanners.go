@@ -1,6 +1,7 @@
 package scanners
 
 import (
+	"context"
 	"io"
 	"time"
 )
@@ -18,4 +19,31 @@ type Input struct {
 	Size int64
 	Url  string
 	Name string
+	// Context is cancelled when the result is no longer needed, scanners stop reading the body then
+	Context context.Context
+}
+
+// context returns the context of the input, it defaults to the background context
+func (in Input) context() context.Context {
+	if in.Context == nil {
+		return context.Background()
+	}
+	return in.Context
+}
+
+// body returns the body of the input which fails once the context is cancelled
+func (in Input) body() io.Reader {
+	return contextReader{ctx: in.context(), r: in.Body}
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
 }
@@ -0,0 +1,666 @@
+package scanners
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eicar is the EICAR anti-malware test file signature
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+var (
+	// ErrInvalidRule is returned when a rule file cannot be parsed.
+	ErrInvalidRule = errors.New("invalid rule")
+)
+
+// NewSignature returns a Scanner evaluating the hash blocklists, file magic and yara style rules found in rulesDir.
+// The directory is checked for changes at most once per reloadInterval and reloaded if needed.
+func NewSignature(rulesDir string, reloadInterval time.Duration, detectEICAR bool) (*Signature, error) {
+	s := &Signature{
+		dir:      rulesDir,
+		interval: reloadInterval,
+		eicar:    detectEICAR,
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Signature is an in-process Scanner based on local signature rules
+type Signature struct {
+	dir      string
+	interval time.Duration
+	eicar    bool
+
+	mu          sync.RWMutex
+	rules       *ruleSet
+	fingerprint string
+	checked     time.Time
+}
+
+// Scan to fulfill Scanner interface
+func (s *Signature) Scan(in Input) (Result, error) {
+	if err := s.reloadIfChanged(); err != nil {
+		return Result{}, err
+	}
+
+	s.mu.RLock()
+	rules := s.rules
+	s.mu.RUnlock()
+
+	// the body is streamed through the matcher, only a window of the longest pattern is kept in memory
+	m := newMatcher(rules)
+	if _, err := io.Copy(m, in.body()); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{ScanTime: time.Now()}
+	if s.eicar && bytes.HasPrefix(m.head, []byte(eicar)) {
+		result.Infected = true
+		result.Description = "EICAR-Test-File"
+		return result, nil
+	}
+
+	if description, found := m.result(); found {
+		result.Infected = true
+		result.Description = description
+	}
+
+	return result, nil
+}
+
+// Reload reads all rules from the rules directory and replaces the active rule set.
+// The active rule set is kept untouched if any of the rule files is invalid.
+func (s *Signature) Reload() error {
+	fingerprint, err := s.dirFingerprint()
+	if err != nil {
+		return err
+	}
+
+	rules, err := loadRules(s.dir)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = rules
+	s.fingerprint = fingerprint
+	s.checked = time.Now()
+
+	return nil
+}
+
+// reloadIfChanged reloads the rules if the rule directory changed since the last check.
+// A broken rule file makes the scan fail until it is fixed, this way uploads are retried instead of passing unscanned.
+func (s *Signature) reloadIfChanged() error {
+	s.mu.RLock()
+	due := s.interval > 0 && time.Since(s.checked) >= s.interval
+	known := s.fingerprint
+	s.mu.RUnlock()
+
+	if !due {
+		return nil
+	}
+
+	fingerprint, err := s.dirFingerprint()
+	if err != nil {
+		return err
+	}
+
+	if fingerprint == known {
+		s.mu.Lock()
+		s.checked = time.Now()
+		s.mu.Unlock()
+		return nil
+	}
+
+	return s.Reload()
+}
+
+// dirFingerprint summarizes names, sizes and modification times of all rule files
+func (s *Signature) dirFingerprint() (string, error) {
+	if s.dir == "" {
+		return "", nil
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, entry := range entries {
+		if entry.IsDir() || ruleKind(entry.Name()) == "" {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ruleKind returns the kind of rules contained in a file based on its extension
+func ruleKind(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".sha256":
+		return "sha256"
+	case ".md5":
+		return "md5"
+	case ".magic":
+		return "magic"
+	case ".yar", ".yara":
+		return "yara"
+	default:
+		return ""
+	}
+}
+
+type ruleSet struct {
+	sha256   map[string]string
+	md5      map[string]string
+	magic    []magicRule
+	patterns []patternRule
+}
+
+type magicRule struct {
+	name   string
+	offset int
+	value  []byte
+}
+
+type patternRule struct {
+	name      string
+	strings   []pattern
+	condition condition
+}
+
+type pattern struct {
+	id     string
+	value  []byte
+	mask   []bool // only set for hex strings, false marks a wildcard byte
+	nocase bool
+}
+
+type condition struct {
+	op  string // "any", "all", "count", "and" or "or"
+	n   int
+	ids []string
+}
+
+func loadRules(dir string) (*ruleSet, error) {
+	rules := &ruleSet{
+		sha256: map[string]string{},
+		md5:    map[string]string{},
+	}
+
+	if dir == "" {
+		return rules, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		kind := ruleKind(entry.Name())
+		if entry.IsDir() || kind == "" {
+			continue
+		}
+
+		p := filepath.Join(dir, entry.Name())
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+
+		switch kind {
+		case "sha256":
+			err = parseHashList(b, sha256.Size, rules.sha256)
+		case "md5":
+			err = parseHashList(b, md5.Size, rules.md5)
+		case "magic":
+			err = parseMagic(b, rules)
+		case "yara":
+			err = parseYara(b, rules)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s", ErrInvalidRule, p, err)
+		}
+	}
+
+	return rules, nil
+}
+
+// parseHashList parses lines in the form of '<hex digest> [description]'
+func parseHashList(b []byte, size int, into map[string]string) error {
+	return eachLine(b, func(n int, line string) error {
+		fields := strings.Fields(line)
+		digest := strings.ToLower(fields[0])
+		if d, err := hex.DecodeString(digest); err != nil || len(d) != size {
+			return fmt.Errorf("line %d: invalid digest '%s'", n, fields[0])
+		}
+
+		description := strings.Join(fields[1:], " ")
+		if description == "" {
+			description = "blocklisted hash " + digest
+		}
+		into[digest] = description
+		return nil
+	})
+}
+
+// parseMagic parses lines in the form of '<offset> <hex bytes> <name>'
+func parseMagic(b []byte, rules *ruleSet) error {
+	return eachLine(b, func(n int, line string) error {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return fmt.Errorf("line %d: expected '<offset> <hex bytes> <name>'", n)
+		}
+
+		offset, err := strconv.Atoi(fields[0])
+		if err != nil || offset < 0 {
+			return fmt.Errorf("line %d: invalid offset '%s'", n, fields[0])
+		}
+
+		value, err := hex.DecodeString(fields[1])
+		if err != nil || len(value) == 0 {
+			return fmt.Errorf("line %d: invalid magic bytes '%s'", n, fields[1])
+		}
+
+		rules.magic = append(rules.magic, magicRule{name: strings.Join(fields[2:], " "), offset: offset, value: value})
+		return nil
+	})
+}
+
+// parseYara parses a subset of the yara rule language: text and hex strings and
+// conditions in the form of 'any of them', 'all of them', '<n> of them' or
+// string identifiers combined by either 'and' or 'or'.
+func parseYara(b []byte, rules *ruleSet) error {
+	var (
+		current *patternRule
+		section string
+		expr    []string
+	)
+
+	finish := func(n int) error {
+		c, err := parseCondition(strings.Join(expr, " "), current.strings)
+		if err != nil {
+			return fmt.Errorf("line %d: rule %s: %s", n, current.name, err)
+		}
+
+		current.condition = c
+		rules.patterns = append(rules.patterns, *current)
+		current, section, expr = nil, "", nil
+		return nil
+	}
+
+	err := eachLine(b, func(n int, line string) error {
+		if strings.HasPrefix(line, "//") {
+			return nil
+		}
+
+		switch {
+		case current == nil:
+			fields := strings.Fields(strings.TrimSuffix(line, "{"))
+			if len(fields) < 2 || fields[0] != "rule" {
+				return fmt.Errorf("line %d: expected rule declaration", n)
+			}
+
+			current = &patternRule{name: strings.TrimSuffix(fields[1], ":")}
+			return nil
+		case line == "{":
+			return nil
+		case line == "}":
+			return finish(n)
+		case line == "meta:" || line == "strings:" || line == "condition:":
+			section = strings.TrimSuffix(line, ":")
+			return nil
+		}
+
+		switch section {
+		case "strings":
+			p, err := parsePattern(line)
+			if err != nil {
+				return fmt.Errorf("line %d: %s", n, err)
+			}
+
+			current.strings = append(current.strings, p)
+		case "condition":
+			if strings.HasSuffix(line, "}") {
+				expr = append(expr, strings.TrimSpace(strings.TrimSuffix(line, "}")))
+				return finish(n)
+			}
+
+			expr = append(expr, line)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if current != nil {
+		return fmt.Errorf("rule %s is not closed", current.name)
+	}
+
+	return nil
+}
+
+func parsePattern(line string) (pattern, error) {
+	id, value, ok := strings.Cut(line, "=")
+	id = strings.TrimSpace(id)
+	value = strings.TrimSpace(value)
+	if !ok || !strings.HasPrefix(id, "$") {
+		return pattern{}, fmt.Errorf("expected '$identifier = value'")
+	}
+
+	p := pattern{id: id}
+	switch {
+	case strings.HasPrefix(value, "{"):
+		end := strings.Index(value, "}")
+		if end < 0 {
+			return p, fmt.Errorf("unterminated hex string %s", id)
+		}
+
+		for _, token := range strings.Fields(value[1:end]) {
+			if token == "??" {
+				p.value = append(p.value, 0)
+				p.mask = append(p.mask, false)
+				continue
+			}
+
+			c, err := hex.DecodeString(token)
+			if err != nil || len(c) != 1 {
+				return p, fmt.Errorf("invalid hex byte '%s' in %s", token, id)
+			}
+
+			p.value = append(p.value, c[0])
+			p.mask = append(p.mask, true)
+		}
+	case strings.HasPrefix(value, `"`):
+		end := strings.LastIndex(value, `"`)
+		if end == 0 {
+			return p, fmt.Errorf("unterminated text string %s", id)
+		}
+
+		text, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return p, fmt.Errorf("invalid text string %s: %s", id, err)
+		}
+
+		p.value = []byte(text)
+		p.nocase = strings.Contains(value[end+1:], "nocase")
+		if p.nocase {
+			p.value = bytes.ToLower(p.value)
+		}
+	default:
+		return p, fmt.Errorf("unsupported string type for %s", id)
+	}
+
+	if len(p.value) == 0 {
+		return p, fmt.Errorf("empty string %s", id)
+	}
+
+	return p, nil
+}
+
+func parseCondition(expr string, patterns []pattern) (condition, error) {
+	fields := strings.Fields(expr)
+	known := make(map[string]bool, len(patterns))
+	for _, p := range patterns {
+		known[p.id] = true
+	}
+
+	if len(fields) == 3 && fields[1] == "of" && fields[2] == "them" {
+		switch fields[0] {
+		case "any":
+			return condition{op: "any"}, nil
+		case "all":
+			return condition{op: "all"}, nil
+		}
+
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			return condition{}, fmt.Errorf("invalid condition '%s'", expr)
+		}
+
+		return condition{op: "count", n: n}, nil
+	}
+
+	c := condition{op: "and"}
+	for i, field := range fields {
+		if i%2 == 1 {
+			if (field != "and" && field != "or") || (i > 1 && field != c.op) {
+				return condition{}, fmt.Errorf("invalid condition '%s'", expr)
+			}
+
+			c.op = field
+			continue
+		}
+
+		if !known[field] {
+			return condition{}, fmt.Errorf("unknown string %s in condition", field)
+		}
+
+		c.ids = append(c.ids, field)
+	}
+
+	if len(c.ids) == 0 || len(fields)%2 == 0 {
+		return condition{}, fmt.Errorf("invalid condition '%s'", expr)
+	}
+
+	return c, nil
+}
+
+func eachLine(b []byte, fn func(n int, line string) error) error {
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := fn(n, line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// matcher evaluates a rule set on a stream. Patterns are searched in a sliding window which
+// overlaps the previous chunk by the length of the longest pattern, so matches spanning chunks are found.
+type matcher struct {
+	rules  *ruleSet
+	sha256 hash.Hash
+	md5    hash.Hash
+
+	// head holds the first bytes of the stream for the magic rules and the EICAR check
+	head     []byte
+	headSize int
+
+	overlap int
+	tail    []byte
+	window  []byte
+	lowered []byte
+	// nocase is set if any pattern is case insensitive
+	nocase  bool
+	matched []map[string]bool
+}
+
+func newMatcher(rs *ruleSet) *matcher {
+	m := &matcher{
+		rules:    rs,
+		sha256:   sha256.New(),
+		md5:      md5.New(),
+		headSize: len(eicar),
+		matched:  make([]map[string]bool, len(rs.patterns)),
+	}
+
+	for _, r := range rs.magic {
+		m.headSize = max(m.headSize, r.offset+len(r.value))
+	}
+
+	for i, r := range rs.patterns {
+		m.matched[i] = make(map[string]bool, len(r.strings))
+		for _, p := range r.strings {
+			m.overlap = max(m.overlap, len(p.value)-1)
+			m.nocase = m.nocase || p.nocase
+		}
+	}
+
+	return m
+}
+
+// Write feeds the next chunk of the stream to the matcher
+func (m *matcher) Write(b []byte) (int, error) {
+	if len(m.rules.sha256) > 0 {
+		m.sha256.Write(b)
+	}
+
+	if len(m.rules.md5) > 0 {
+		m.md5.Write(b)
+	}
+
+	if missing := m.headSize - len(m.head); missing > 0 {
+		m.head = append(m.head, b[:min(missing, len(b))]...)
+	}
+
+	if len(m.rules.patterns) == 0 {
+		return len(b), nil
+	}
+
+	m.window = append(append(m.window[:0], m.tail...), b...)
+	if m.nocase {
+		m.lowered = bytes.ToLower(m.window)
+	}
+
+	for i, r := range m.rules.patterns {
+		for _, p := range r.strings {
+			if m.matched[i][p.id] {
+				continue
+			}
+
+			haystack := m.window
+			if p.nocase {
+				haystack = m.lowered
+			}
+
+			m.matched[i][p.id] = p.find(haystack)
+		}
+	}
+
+	keep := min(m.overlap, len(m.window))
+	m.tail = append(m.tail[:0], m.window[len(m.window)-keep:]...)
+
+	return len(b), nil
+}
+
+// result returns the description of the first matching rule
+func (m *matcher) result() (string, bool) {
+	rs := m.rules
+	if len(rs.sha256) > 0 {
+		if description, ok := rs.sha256[hex.EncodeToString(m.sha256.Sum(nil))]; ok {
+			return description, true
+		}
+	}
+
+	if len(rs.md5) > 0 {
+		if description, ok := rs.md5[hex.EncodeToString(m.md5.Sum(nil))]; ok {
+			return description, true
+		}
+	}
+
+	for _, r := range rs.magic {
+		if len(m.head) >= r.offset+len(r.value) && bytes.Equal(m.head[r.offset:r.offset+len(r.value)], r.value) {
+			return r.name, true
+		}
+	}
+
+	for i, r := range rs.patterns {
+		if r.condition.eval(r.strings, m.matched[i]) {
+			return r.name, true
+		}
+	}
+
+	return "", false
+}
+
+func (p pattern) find(data []byte) bool {
+	if p.mask == nil {
+		return bytes.Contains(data, p.value)
+	}
+
+Outer:
+	for i := 0; i+len(p.value) <= len(data); i++ {
+		for j, c := range p.value {
+			if p.mask[j] && data[i+j] != c {
+				continue Outer
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+func (c condition) eval(patterns []pattern, matched map[string]bool) bool {
+	count := 0
+	for _, p := range patterns {
+		if matched[p.id] {
+			count++
+		}
+	}
+
+	switch c.op {
+	case "any":
+		return count > 0
+	case "all":
+		return count == len(patterns)
+	case "count":
+		return count >= c.n
+	case "or":
+		return anyOf(c.ids, matched)
+	default:
+		for _, id := range c.ids {
+			if !matched[id] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func anyOf(ids []string, matched map[string]bool) bool {
+	for _, id := range ids {
+		if matched[id] {
+			return true
+		}
+	}
+	return false
+}
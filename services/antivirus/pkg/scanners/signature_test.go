@@ -0,0 +1,143 @@
+package scanners_test
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+)
+
+func TestSignature_Scan(t *testing.T) {
+	scan := func(t *testing.T, s *scanners.Signature, body string) scanners.Result {
+		result, err := s.Scan(scanners.Input{Body: strings.NewReader(body), Size: int64(len(body))})
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("it detects the EICAR test file", func(t *testing.T) {
+		s, err := scanners.NewSignature("", 0, true)
+		require.NoError(t, err)
+
+		result := scan(t, s, `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+		assert.True(t, result.Infected)
+		assert.Equal(t, "EICAR-Test-File", result.Description)
+
+		s, err = scanners.NewSignature("", 0, false)
+		require.NoError(t, err)
+		assert.False(t, scan(t, s, `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`).Infected)
+	})
+
+	t.Run("it detects blocklisted hashes", func(t *testing.T) {
+		dir := t.TempDir()
+		sha := sha256.Sum256([]byte("bad sha"))
+		md := md5.Sum([]byte("bad md5"))
+		writeRule(t, dir, "list.sha256", "# comment\n"+hex.EncodeToString(sha[:])+" Bad.Sha256\n")
+		writeRule(t, dir, "list.md5", hex.EncodeToString(md[:])+"\n")
+
+		s, err := scanners.NewSignature(dir, 0, true)
+		require.NoError(t, err)
+
+		result := scan(t, s, "bad sha")
+		assert.True(t, result.Infected)
+		assert.Equal(t, "Bad.Sha256", result.Description)
+
+		result = scan(t, s, "bad md5")
+		assert.True(t, result.Infected)
+		assert.Equal(t, "blocklisted hash "+hex.EncodeToString(md[:]), result.Description)
+
+		assert.False(t, scan(t, s, "good").Infected)
+	})
+
+	t.Run("it detects file magic", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRule(t, dir, "exe.magic", "0 4d5a Windows.Executable\n")
+
+		s, err := scanners.NewSignature(dir, 0, true)
+		require.NoError(t, err)
+
+		assert.True(t, scan(t, s, "MZ\x90\x00").Infected)
+		assert.False(t, scan(t, s, " MZ").Infected)
+	})
+
+	t.Run("it evaluates yara style rules", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRule(t, dir, "rules.yar", `
+// test rules
+rule Any_Of_Them
+{
+    meta:
+        author = "test"
+    strings:
+        $a = "evil" nocase
+        $b = { 65 ?? 69 6C }
+    condition:
+        any of them
+}
+
+rule Both : tag {
+    strings:
+        $a = "first"
+        $b = "second"
+    condition:
+        $a and $b
+}
+`)
+
+		s, err := scanners.NewSignature(dir, 0, true)
+		require.NoError(t, err)
+
+		result := scan(t, s, "something EVIL")
+		assert.True(t, result.Infected)
+		assert.Equal(t, "Any_Of_Them", result.Description)
+
+		assert.True(t, scan(t, s, "exil").Infected)
+
+		result = scan(t, s, "first and second")
+		assert.True(t, result.Infected)
+		assert.Equal(t, "Both", result.Description)
+
+		assert.False(t, scan(t, s, "first only").Infected)
+
+		// the body is streamed, patterns spanning chunks are found
+		body := strings.Repeat("x", 100) + "sec" + "ond" + strings.Repeat("y", 100) + "first"
+		result, err = s.Scan(scanners.Input{Body: iotest.OneByteReader(strings.NewReader(body)), Size: int64(len(body))})
+		require.NoError(t, err)
+		assert.Equal(t, "Both", result.Description)
+	})
+
+	t.Run("it rejects invalid rules", func(t *testing.T) {
+		dir := t.TempDir()
+		writeRule(t, dir, "rules.yar", "rule Broken {\n strings:\n $a = \"x\"\n condition:\n $b\n}\n")
+
+		_, err := scanners.NewSignature(dir, 0, true)
+		assert.ErrorIs(t, err, scanners.ErrInvalidRule)
+	})
+
+	t.Run("it reloads changed rules", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := scanners.NewSignature(dir, time.Nanosecond, true)
+		require.NoError(t, err)
+		assert.False(t, scan(t, s, "MZ").Infected)
+
+		writeRule(t, dir, "exe.magic", "0 4d5a Windows.Executable\n")
+		assert.True(t, scan(t, s, "MZ").Infected)
+
+		writeRule(t, dir, "broken.md5", "nohash\n")
+		_, err = s.Scan(scanners.Input{Body: strings.NewReader("MZ")})
+		assert.ErrorIs(t, err, scanners.ErrInvalidRule)
+	})
+}
+
+func writeRule(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+}
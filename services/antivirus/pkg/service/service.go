@@ -3,7 +3,9 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -18,15 +20,23 @@ import (
 	ctxpkg "github.com/owncloud/reva/v2/pkg/ctx"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/events/stream"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/owncloud/reva/v2/pkg/rhttp"
 	"go.opentelemetry.io/otel/trace"
 
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+
 	"github.com/owncloud/ocis/v2/ocis-pkg/generators"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/config"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
 	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
 )
 
+// OutcomeQuarantine moves infected files into the quarantine space.
+// It is not a postprocessing outcome on its own, the upload is deleted once the file is quarantined.
+const OutcomeQuarantine events.PostprocessingOutcome = "quarantine"
+
 var (
 	// ErrFatal is returned when a fatal error occurs, and we want to exit.
 	ErrFatal = errors.New("fatal error")
@@ -40,17 +50,29 @@ type Scanner interface {
 }
 
 // NewAntivirus returns a service implementation for Service.
-func NewAntivirus(c *config.Config, l log.Logger, tp trace.TracerProvider) (Antivirus, error) {
+func NewAntivirus(c *config.Config, l log.Logger, tp trace.TracerProvider, gatewaySelector pool.Selectable[gateway.GatewayAPIClient]) (Antivirus, error) {
 
 	var scanner Scanner
 	var err error
 	switch c.Scanner.Type {
+	case "chain":
+		links := make([]scanners.ChainLink, 0, len(c.Scanner.Chain.Scanners))
+		for _, t := range c.Scanner.Chain.Scanners {
+			if t == "chain" {
+				return Antivirus{}, errors.New("av scanner chains can not be nested")
+			}
+
+			s, err := newScanner(t, c.Scanner)
+			if err != nil {
+				return Antivirus{}, err
+			}
+
+			links = append(links, scanners.ChainLink{Name: t, Engine: s, Timeout: c.Scanner.Chain.Timeout})
+		}
+
+		scanner, err = scanners.NewChain(scanners.Aggregation(c.Scanner.Chain.Aggregation), links...)
 	default:
-		return Antivirus{}, fmt.Errorf("unknown av scanner: '%s'", c.Scanner.Type)
-	case "clamav":
-		scanner = scanners.NewClamAV(c.Scanner.ClamAV.Socket)
-	case "icap":
-		scanner, err = scanners.NewICAP(c.Scanner.ICAP.URL, c.Scanner.ICAP.Service, c.Scanner.ICAP.Timeout)
+		scanner, err = newScanner(c.Scanner.Type, c.Scanner)
 	}
 	if err != nil {
 		return Antivirus{}, err
@@ -69,6 +91,9 @@ func NewAntivirus(c *config.Config, l log.Logger, tp trace.TracerProvider) (Anti
 	switch o := events.PostprocessingOutcome(c.InfectedFileHandling); o {
 	case events.PPOutcomeContinue, events.PPOutcomeAbort, events.PPOutcomeDelete:
 		av.o = o
+	case OutcomeQuarantine:
+		av.o = o
+		av.q = quarantine.New(gatewaySelector, c.ServiceAccount.ServiceAccountID, c.ServiceAccount.ServiceAccountSecret, c.Quarantine.SpaceID, c.Quarantine.SpaceName)
 	default:
 		return av, fmt.Errorf("unknown infected file handling '%s'", o)
 	}
@@ -85,12 +110,27 @@ func NewAntivirus(c *config.Config, l log.Logger, tp trace.TracerProvider) (Anti
 	return av, nil
 }
 
+// newScanner returns the single scanner of the given type
+func newScanner(t string, c config.Scanner) (Scanner, error) {
+	switch t {
+	case "clamav":
+		return scanners.NewClamAV(c.ClamAV.Socket), nil
+	case "icap":
+		return scanners.NewICAP(c.ICAP.URL, c.ICAP.Service, c.ICAP.Timeout)
+	case "signature":
+		return scanners.NewSignature(c.Signature.RulesDir, c.Signature.ReloadInterval, c.Signature.DetectEICAR)
+	default:
+		return nil, fmt.Errorf("unknown av scanner: '%s'", t)
+	}
+}
+
 // Antivirus defines implements the business logic for Service.
 type Antivirus struct {
 	c  *config.Config
 	l  log.Logger
 	s  Scanner
 	o  events.PostprocessingOutcome
+	q  *quarantine.Quarantine
 	m  uint64
 	tp trace.TracerProvider
 
@@ -214,7 +254,7 @@ func (av Antivirus) processEvent(e events.Event, s events.Publisher) error {
 	av.l.Debug().Str("uploadid", ev.UploadID).Str("filename", ev.Filename).Msg("Starting virus scan.")
 	var errmsg string
 	start := time.Now()
-	res, err := av.process(ev)
+	res, checksum, err := av.process(ev)
 	if err != nil {
 		errmsg = err.Error()
 	}
@@ -222,6 +262,8 @@ func (av Antivirus) processEvent(e events.Event, s events.Publisher) error {
 
 	var outcome events.PostprocessingOutcome
 	switch {
+	case res.Infected && av.o == OutcomeQuarantine:
+		outcome = av.quarantine(ctx, ev, &res, checksum)
 	case res.Infected:
 		outcome = av.o
 	case !res.Infected && err == nil:
@@ -254,37 +296,111 @@ func (av Antivirus) processEvent(e events.Event, s events.Publisher) error {
 	return nil
 }
 
-// process the scan
-func (av Antivirus) process(ev events.StartPostprocessingStep) (scanners.Result, error) {
+// process the scan, the returned checksum is the hex encoded sha256 of the scanned content
+func (av Antivirus) process(ev events.StartPostprocessingStep) (scanners.Result, string, error) {
 	if ev.Filesize == 0 || (0 < av.m && av.m < ev.Filesize) {
 		av.l.Info().Str("uploadid", ev.UploadID).Uint64("limit", av.m).Uint64("filesize", ev.Filesize).Msg("Skipping file to be virus scanned because its file size is higher than the defined limit.")
 		return scanners.Result{
 			ScanTime: time.Now(),
-		}, nil
+		}, "", nil
 	}
 
-	var err error
-	var rrc io.ReadCloser
-
-	switch ev.UploadID {
-	default:
-		rrc, err = av.downloadViaToken(ev.URL)
-	case "":
-		rrc, err = av.downloadViaReva(ev.URL, ev.Token, ev.RevaToken)
+	if av.q != nil && ev.ResourceID.GetSpaceId() == av.q.SpaceID() {
+		av.l.Info().Str("uploadid", ev.UploadID).Msg("Skipping file to be virus scanned because it is stored in the quarantine space.")
+		return scanners.Result{
+			ScanTime: time.Now(),
+		}, "", nil
 	}
+
+	rrc, err := av.download(ev)
 	if err != nil {
 		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("error downloading file")
-		return scanners.Result{}, err
+		return scanners.Result{}, "", err
 	}
 	defer rrc.Close()
 	av.l.Debug().Str("uploadid", ev.UploadID).Msg("Downloaded file successfully, starting virusscan")
 
-	res, err := av.s.Scan(scanners.Input{Body: rrc, Size: int64(ev.Filesize), Url: ev.URL, Name: ev.Filename})
+	if av.q == nil {
+		res, err := av.s.Scan(scanners.Input{Body: rrc, Size: int64(ev.Filesize), Url: ev.URL, Name: ev.Filename})
+		if err != nil {
+			av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("error scanning file")
+		}
+		return res, "", err
+	}
+
+	// the quarantine needs the checksum of the content
+	h := sha256.New()
+	body := io.TeeReader(rrc, h)
+	res, err := av.s.Scan(scanners.Input{Body: body, Size: int64(ev.Filesize), Url: ev.URL, Name: ev.Filename})
 	if err != nil {
 		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("error scanning file")
+		return res, "", err
+	}
+
+	// scanners may stop reading before the end of the content, the rest is hashed here
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		av.l.Error().Err(err).Str("uploadid", ev.UploadID).Msg("error hashing file")
+		return res, "", err
+	}
+
+	return res, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// quarantine moves the infected file into the quarantine space and returns the outcome for the upload.
+// Files which are restored by a release from the quarantine are passed through.
+func (av Antivirus) quarantine(ctx context.Context, ev events.StartPostprocessingStep, res *scanners.Result, checksum string) events.PostprocessingOutcome {
+	sublog := av.l.With().Str("uploadid", ev.UploadID).Interface("resourceID", ev.ResourceID).Logger()
+
+	var released bool
+	if ev.ExecutingUser.GetId().GetOpaqueId() == av.q.ServiceAccountID() {
+		var err error
+		released, err = av.q.ConsumeRelease(ctx, ev.ResourceID.GetSpaceId(), checksum)
+		if err != nil {
+			sublog.Error().Err(err).Msg("could not check quarantine releases, aborting")
+			return events.PPOutcomeAbort
+		}
+	}
+
+	if released {
+		sublog.Info().Str("virus", res.Description).Msg("file was released from quarantine, continuing")
+		res.Infected = false
+		res.Description = "released from quarantine: " + res.Description
+		return events.PPOutcomeContinue
 	}
 
-	return res, err
+	rrc, err := av.download(ev)
+	if err != nil {
+		sublog.Error().Err(err).Msg("could not download file for quarantine, aborting")
+		return events.PPOutcomeAbort
+	}
+	defer rrc.Close()
+
+	item := quarantine.Item{
+		ID:            ev.UploadID,
+		Name:          ev.Filename,
+		ExecutingUser: ev.ExecutingUser.GetId().GetOpaqueId(),
+		Description:   res.Description,
+		Checksum:      checksum,
+		ScanTime:      res.ScanTime,
+		Size:          ev.Filesize,
+	}
+	if err := av.q.Add(ctx, item, ev.ResourceID, rrc); err != nil {
+		sublog.Error().Err(err).Msg("could not move file to quarantine, aborting")
+		return events.PPOutcomeAbort
+	}
+
+	sublog.Info().Msg("moved infected file to quarantine")
+	return events.PPOutcomeDelete
+}
+
+// download the file to scan
+func (av Antivirus) download(ev events.StartPostprocessingStep) (io.ReadCloser, error) {
+	switch ev.UploadID {
+	default:
+		return av.downloadViaToken(ev.URL)
+	case "":
+		return av.downloadViaReva(ev.URL, ev.Token, ev.RevaToken)
+	}
 }
 
 // download will download the file
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/quarantine"
+	"github.com/owncloud/ocis/v2/services/antivirus/pkg/scanners"
+)
+
+const _content = "infected content"
+
+// partialScanner only reads the first bytes of the content
+type partialScanner struct{}
+
+func (partialScanner) Scan(in scanners.Input) (scanners.Result, error) {
+	_, err := io.ReadFull(in.Body, make([]byte, 4))
+	return scanners.Result{Infected: true, Description: "virus"}, err
+}
+
+func newQuarantineAntivirus(t *testing.T) (Antivirus, *cs3mocks.GatewayAPIClient, events.StartPostprocessingStep) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient]("GatewaySelector", "com.owncloud.api.gateway", func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+		return gatewayClient
+	})
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Token: "token"}, nil)
+	gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+		Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+		StorageSpaces: []*provider.StorageSpace{{
+			Root: &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "quarantine"},
+		}},
+	}, nil)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = io.WriteString(w, _content)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+
+	av := Antivirus{
+		l:      log.NopLogger(),
+		s:      partialScanner{},
+		o:      OutcomeQuarantine,
+		q:      quarantine.New(gatewaySelector, "service-account", "secret", "quarantine", "Quarantine"),
+		client: srv.Client(),
+	}
+	ev := events.StartPostprocessingStep{
+		UploadID:      "upload-id",
+		URL:           srv.URL,
+		Filename:      "eicar.com",
+		Filesize:      uint64(len(_content)),
+		ResourceID:    &provider.ResourceId{StorageId: "storage", SpaceId: "space-id", OpaqueId: "file-id"},
+		ExecutingUser: &userpb.User{Id: &userpb.UserId{OpaqueId: "user"}},
+	}
+	return av, gatewayClient, ev
+}
+
+func checksum() string {
+	sum := sha256.Sum256([]byte(_content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestProcessHashesTheWholeContent(t *testing.T) {
+	av, _, ev := newQuarantineAntivirus(t)
+
+	res, sum, err := av.process(ev)
+	require.NoError(t, err)
+	assert.True(t, res.Infected)
+	assert.Equal(t, checksum(), sum)
+}
+
+func TestQuarantineOutcome(t *testing.T) {
+	av, gatewayClient, ev := newQuarantineAntivirus(t)
+	srvURL := ev.URL
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: &provider.ResourceInfo{
+		Name:     "eicar.com",
+		ParentId: &provider.ResourceId{StorageId: "storage", SpaceId: "space-id", OpaqueId: "parent-id"},
+	}}, nil)
+	gatewayClient.On("InitiateFileUpload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileUploadResponse{
+		Status:    &rpc.Status{Code: rpc.Code_CODE_OK},
+		Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: srvURL}},
+	}, nil)
+	gatewayClient.On("SetArbitraryMetadata", mock.Anything, mock.MatchedBy(func(req *provider.SetArbitraryMetadataRequest) bool {
+		return req.GetArbitraryMetadata().GetMetadata()["ocis.quarantine.checksum"] == checksum()
+	})).Return(&provider.SetArbitraryMetadataResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil).Once()
+
+	res := scanners.Result{Infected: true, Description: "virus"}
+	assert.Equal(t, events.PPOutcomeDelete, av.quarantine(context.Background(), ev, &res, checksum()))
+	assert.True(t, res.Infected)
+	gatewayClient.AssertExpectations(t)
+}
+
+func TestQuarantineOutcomeFailed(t *testing.T) {
+	av, gatewayClient, ev := newQuarantineAntivirus(t)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil)
+
+	res := scanners.Result{Infected: true, Description: "virus"}
+	assert.Equal(t, events.PPOutcomeAbort, av.quarantine(context.Background(), ev, &res, checksum()))
+}
+
+func TestQuarantineOutcomeReleased(t *testing.T) {
+	marker := "./.released/space-id-" + checksum()
+	for name, tc := range map[string]struct {
+		executingUser string
+		outcome       events.PostprocessingOutcome
+	}{
+		"restored by the release": {executingUser: "service-account", outcome: events.PPOutcomeContinue},
+		// the release marker doesn't apply to uploads of users
+		"uploaded by a user": {executingUser: "user", outcome: events.PPOutcomeAbort},
+	} {
+		t.Run(name, func(t *testing.T) {
+			av, gatewayClient, ev := newQuarantineAntivirus(t)
+			ev.ExecutingUser.Id.OpaqueId = tc.executingUser
+			gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+				return req.GetRef().GetPath() == marker
+			})).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: &provider.ResourceInfo{
+				Id: &provider.ResourceId{StorageId: "storage", SpaceId: "quarantine", OpaqueId: "marker"},
+			}}, nil)
+			// quarantining the upload of the user fails
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil)
+			gatewayClient.On("Delete", mock.Anything, mock.Anything).Return(&provider.DeleteResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+			gatewayClient.On("PurgeRecycle", mock.Anything, mock.Anything).Return(&provider.PurgeRecycleResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}}, nil)
+
+			res := scanners.Result{Infected: true, Description: "virus"}
+			assert.Equal(t, tc.outcome, av.quarantine(context.Background(), ev, &res, checksum()))
+			assert.Equal(t, tc.outcome != events.PPOutcomeContinue, res.Infected)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/services/audit/pkg/config"
+	"github.com/owncloud/ocis/v2/services/audit/pkg/config/parser"
+	svc "github.com/owncloud/ocis/v2/services/audit/pkg/service"
+)
+
+// Verify is the entrypoint for the verify command.
+func Verify(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "verify",
+		Usage:     "Verify the hash chain of an audit log file. Missing, reordered and modified records are reported.",
+		ArgsUsage: "['file' defaults to the configured audit log file]",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "hmac-key",
+				Usage: "The key to verify the signatures of the records with. Defaults to the configured key, the signatures are not verified without a key.",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			path := c.Args().First()
+			if path == "" {
+				path = cfg.Auditlog.FilePath
+			}
+			if path == "" {
+				_ = cli.ShowSubcommandHelp(c)
+				return fmt.Errorf("file is required")
+			}
+
+			key := cfg.Auditlog.HMACKey
+			if c.IsSet("hmac-key") {
+				key = c.String("hmac-key")
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("could not open '%s': %w", path, err)
+			}
+			defer f.Close()
+
+			report, err := svc.VerifyChain(f, []byte(key))
+			if err != nil {
+				return fmt.Errorf("could not read '%s': %w", path, err)
+			}
+
+			for _, p := range report.Problems {
+				fmt.Printf("line %d: %s\n", p.Line, p.Message)
+			}
+			fmt.Printf("%d records, %d checkpoints", report.Records, report.Checkpoints)
+			if report.Checkpoints > 0 {
+				fmt.Printf(", last checkpoint at %s", report.LastCheckpoint.Format(time.RFC3339))
+			}
+			fmt.Println()
+			if key == "" {
+				fmt.Println("The signatures were not verified, no key is configured.")
+			}
+
+			if len(report.Problems) > 0 {
+				return fmt.Errorf("the hash chain of '%s' is broken, %d problems found", path, len(report.Problems))
+			}
+			fmt.Printf("The hash chain of '%s' is intact.\n", path)
+			return nil
+		},
+	}
+}
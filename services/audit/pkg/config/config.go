@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 )
@@ -39,6 +40,10 @@ type Auditlog struct {
 	LogToFile    bool   `yaml:"log_to_file" env:"AUDIT_LOG_TO_FILE" desc:"Logs to file if set to 'true'. Independent of the LOG_TO_CONSOLE option." introductionVersion:"pre5.0"`
 	FilePath     string `yaml:"filepath" env:"AUDIT_FILEPATH" desc:"Filepath of the logfile. Mandatory if LOG_TO_FILE is set to 'true'." introductionVersion:"pre5.0"`
 	Format       string `yaml:"format" env:"AUDIT_FORMAT" desc:"Log format. Supported values are '' (empty) and 'json'. Using 'json' is advised, '' (empty) renders the 'minimal' format. See the text description for more details." introductionVersion:"pre5.0"`
+
+	HashChain          bool          `yaml:"hash_chain" env:"AUDIT_HASH_CHAIN" desc:"Adds a sequence number and the hash of the previous record to every record, so missing, reordered or modified records can be detected with 'ocis audit verify'. Requires the 'json' format. See the text description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	HMACKey            string        `yaml:"hmac_key" env:"AUDIT_HMAC_KEY" desc:"Key to sign the records of the hash chain with HMAC-SHA256. Without a key, the records are only hashed and can be recomputed by anyone who can write the audit log." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%" mask:"password"`
+	CheckpointInterval time.Duration `yaml:"checkpoint_interval" env:"AUDIT_CHECKPOINT_INTERVAL" desc:"Interval in which a checkpoint record is added to the hash chain. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
 }
 
 // Tracing defines the available tracing configuration.
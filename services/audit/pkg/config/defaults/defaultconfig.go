@@ -1,6 +1,8 @@
 package defaults
 
 import (
+	"time"
+
 	"github.com/owncloud/ocis/v2/services/audit/pkg/config"
 )
 
@@ -29,8 +31,9 @@ func DefaultConfig() *config.Config {
 			EnableTLS: false,
 		},
 		Auditlog: config.Auditlog{
-			LogToConsole: true,
-			Format:       "json",
+			LogToConsole:       true,
+			Format:             "json",
+			CheckpointInterval: 24 * time.Hour,
 		},
 	}
 }
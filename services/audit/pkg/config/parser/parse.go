@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
 	"github.com/owncloud/ocis/v2/services/audit/pkg/config"
@@ -34,5 +35,13 @@ func ParseConfig(cfg *config.Config) error {
 
 // Validate validates the configuration
 func Validate(cfg *config.Config) error {
+	if cfg.Auditlog.HashChain && cfg.Auditlog.Format != "json" {
+		return fmt.Errorf("The hash chain of %s requires the 'json' format, but the format is '%s'.",
+			cfg.Service.Name, cfg.Auditlog.Format)
+	}
+	if cfg.Auditlog.HashChain && cfg.Auditlog.CheckpointInterval <= 0 {
+		return fmt.Errorf("The checkpoint interval of %s must be positive when the hash chain is enabled.",
+			cfg.Service.Name)
+	}
 	return nil
 }
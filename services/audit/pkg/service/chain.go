@@ -0,0 +1,326 @@
+package svc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/audit/pkg/types"
+)
+
+// _hashField starts the fields appended to a record after its hash was computed. json.Marshal escapes
+// quotes in values, so the last occurrence is always the field added by the chain.
+var _hashField = []byte(`,"Hash":"`)
+
+// chainFields are the fields of a record which are needed to verify the hash chain
+type chainFields struct {
+	Seq      uint64
+	PrevHash string
+	Hash     string
+	HMAC     string
+	Action   string
+	Time     string
+}
+
+// Chain links the audit records into a hash chain. Every record gets a sequence number and the hash of
+// the previous record, the hash covers the record including these fields. If a key is configured, the
+// hash is signed with HMAC-SHA256 as well.
+type Chain struct {
+	mu    sync.Mutex
+	key   []byte
+	seq   uint64
+	prev  string
+	log   log.Logger
+	logto []Log
+}
+
+// NewChain returns a Chain writing the linked records to logto
+func NewChain(key []byte, log log.Logger, logto ...Log) *Chain {
+	return &Chain{
+		key:   key,
+		log:   log,
+		logto: logto,
+	}
+}
+
+// Resume continues the chain after the last record of the audit log file at path. A missing or empty file
+// starts a new chain.
+func (c *Chain) Resume(path string) error {
+	line, err := lastLine(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case len(line) == 0:
+		return nil
+	}
+
+	f, problem := checkRecord(line, c.key)
+	if problem != "" {
+		return fmt.Errorf("the last record of '%s' can't be used to continue the hash chain: %s", path, problem)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq, c.prev = f.Seq, f.Hash
+	return nil
+}
+
+// Log links the record to the chain and writes it to the outputs of the chain
+func (c *Chain) Log(content []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.write(content)
+}
+
+// Checkpoint adds a checkpoint record to the chain. It states the head of the chain at the given time.
+func (c *Chain) Checkpoint(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := json.Marshal(types.AuditCheckpoint(c.seq, t))
+	if err != nil {
+		c.log.Error().Err(err).Msg("error marshaling the audit checkpoint")
+		return
+	}
+	c.write(b)
+}
+
+// checkpoints adds a checkpoint record to the chain in the given interval until stop is closed
+func (c *Chain) checkpoints(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case t := <-ticker.C:
+			c.Checkpoint(t)
+		}
+	}
+}
+
+// write links the record to the chain, the chain must be locked
+func (c *Chain) write(content []byte) {
+	record, hash, err := seal(content, c.seq+1, c.prev, c.key)
+	if err != nil {
+		c.log.Error().Err(err).Msg("error linking the record to the hash chain")
+		return
+	}
+	c.seq++
+	c.prev = hash
+
+	for _, l := range c.logto {
+		l(record)
+	}
+}
+
+// seal adds the chain fields to a json object, it returns the record and its hash
+func seal(content []byte, seq uint64, prev string, key []byte) ([]byte, string, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) < 2 || content[0] != '{' || content[len(content)-1] != '}' {
+		return nil, "", errors.New("record is not a json object")
+	}
+	if bytes.ContainsAny(content, "\r\n") {
+		return nil, "", errors.New("record spans multiple lines")
+	}
+
+	record := make([]byte, 0, len(content)+256)
+	record = append(record, content[:len(content)-1]...)
+	if len(bytes.TrimSpace(content[1:len(content)-1])) > 0 {
+		record = append(record, ',')
+	}
+	record = append(record, `"Seq":`...)
+	record = strconv.AppendUint(record, seq, 10)
+	record = append(record, `,"PrevHash":"`...)
+	record = append(record, prev...)
+	record = append(record, '"')
+
+	hash := recordHash(record)
+	record = append(record, tail(hash, recordHMAC(record, key))...)
+	return record, hash, nil
+}
+
+// tail renders the fields following the hashed part of a record
+func tail(hash, mac string) []byte {
+	t := append([]byte{}, _hashField...)
+	t = append(t, hash...)
+	t = append(t, '"')
+	if mac != "" {
+		t = append(t, `,"HMAC":"`...)
+		t = append(t, mac...)
+		t = append(t, '"')
+	}
+	return append(t, '}')
+}
+
+func recordHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func recordHMAC(b []byte, key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkRecord checks the integrity of a single record, a record which fails the check is described by
+// the returned problem. Without a key, the signatures of the records are not checked.
+func checkRecord(line []byte, key []byte) (chainFields, string) {
+	var f chainFields
+	i := bytes.LastIndex(line, _hashField)
+	if i < 0 {
+		return f, "record is not part of a hash chain"
+	}
+	if err := json.Unmarshal(line, &f); err != nil || f.Seq == 0 {
+		return f, "invalid record"
+	}
+
+	hashed := line[:i]
+	if !bytes.Equal(line[i:], tail(f.Hash, f.HMAC)) || recordHash(hashed) != f.Hash {
+		return f, "record was modified"
+	}
+	if len(key) != 0 {
+		if f.HMAC == "" {
+			return f, "record is not signed"
+		}
+		if !hmac.Equal([]byte(f.HMAC), []byte(recordHMAC(hashed, key))) {
+			return f, "invalid signature"
+		}
+	}
+	return f, ""
+}
+
+// ChainProblem is an inconsistency found in a hash chained audit log
+type ChainProblem struct {
+	Line    int
+	Message string
+}
+
+// ChainReport is the result of the verification of a hash chained audit log
+type ChainReport struct {
+	Records        uint64
+	Checkpoints    uint64
+	LastCheckpoint time.Time
+	Problems       []ChainProblem
+}
+
+// VerifyChain verifies the hash chain of an audit log. It reports modified records, gaps in the sequence,
+// records which are out of order and, if a key is given, records with invalid signatures.
+func VerifyChain(r io.Reader, key []byte) (ChainReport, error) {
+	var (
+		report   ChainReport
+		prevSeq  uint64
+		prevHash string
+	)
+	problem := func(line int, format string, args ...interface{}) {
+		report.Problems = append(report.Problems, ChainProblem{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := br.ReadBytes('\n')
+		switch {
+		case errors.Is(err, io.EOF) && len(line) == 0:
+			return report, nil
+		case err != nil && !errors.Is(err, io.EOF):
+			return report, err
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+
+		f, msg := checkRecord(line, key)
+		if f.Seq == 0 {
+			problem(n, "%s", msg)
+			continue
+		}
+		report.Records++
+		if msg != "" {
+			problem(n, "%s", msg)
+		}
+
+		switch {
+		case prevSeq == 0 && f.Seq != 1:
+			problem(n, "the chain starts at record %d, the records before are missing", f.Seq)
+		case prevSeq == 0:
+			// the first record has no predecessor
+		case f.Seq == 1:
+			problem(n, "the chain was restarted after record %d", prevSeq)
+		case f.Seq <= prevSeq:
+			// the chain continues after the latest record
+			problem(n, "record %d is out of order, it follows record %d", f.Seq, prevSeq)
+			continue
+		case f.Seq == prevSeq+1 && f.PrevHash != prevHash:
+			problem(n, "record %d does not follow the previous record", f.Seq)
+		case f.Seq == prevSeq+2:
+			problem(n, "record %d is missing", prevSeq+1)
+		case f.Seq > prevSeq+2:
+			problem(n, "records %d to %d are missing", prevSeq+1, f.Seq-1)
+		}
+		prevSeq, prevHash = f.Seq, f.Hash
+
+		if f.Action == types.ActionAuditCheckpoint {
+			report.Checkpoints++
+			t, err := time.Parse(time.RFC3339, f.Time)
+			switch {
+			case err != nil:
+				problem(n, "checkpoint has an invalid time")
+			case t.Before(report.LastCheckpoint):
+				problem(n, "checkpoint is older than the previous checkpoint")
+			default:
+				report.LastCheckpoint = t
+			}
+		}
+	}
+}
+
+// lastLine returns the last line of a file without reading all of it
+func lastLine(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	buf := make([]byte, 4096)
+	for pos := info.Size(); pos > 0; {
+		n := int64(len(buf))
+		if pos < n {
+			n = pos
+		}
+		pos -= n
+		if _, err := f.ReadAt(buf[:n], pos); err != nil {
+			return nil, err
+		}
+		data = append(append([]byte{}, buf[:n]...), data...)
+
+		trimmed := bytes.TrimRight(data, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+	}
+	return bytes.TrimRight(data, "\n"), nil
+}
@@ -0,0 +1,114 @@
+package svc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/audit/pkg/types"
+)
+
+func TestChain(t *testing.T) {
+	var lines []string
+	chain := NewChain([]byte("secret"), log.NopLogger(), func(b []byte) {
+		lines = append(lines, string(b))
+	})
+	chain.Log([]byte(`{"Action":"file_create","Message":"user 'a' created file 'b'"}`))
+	chain.Log([]byte(`{"Action":"file_delete"}`))
+	chain.Checkpoint(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	chain.Log([]byte(`not json`))
+	chain.Log([]byte(`{}`))
+
+	require.Len(t, lines, 4)
+	require.True(t, strings.HasPrefix(lines[0], `{"Action":"file_create","Message":"user 'a' created file 'b'","Seq":1,"PrevHash":"","Hash":"`))
+	require.Contains(t, lines[1], `"Seq":2`)
+	require.Contains(t, lines[2], `"Action":"audit_checkpoint"`)
+	require.Contains(t, lines[2], `"Message":"audit log checkpoint after record '2'"`)
+	require.True(t, strings.HasPrefix(lines[3], `{"Seq":4,"PrevHash":"`))
+
+	report, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")+"\n"), []byte("secret"))
+	require.NoError(t, err)
+	require.Empty(t, report.Problems)
+	require.Equal(t, uint64(4), report.Records)
+	require.Equal(t, uint64(1), report.Checkpoints)
+	require.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), report.LastCheckpoint)
+
+	report, err = VerifyChain(strings.NewReader(strings.Join(lines, "\n")), []byte("other"))
+	require.NoError(t, err)
+	require.Len(t, report.Problems, 4)
+	require.Equal(t, "invalid signature", report.Problems[0].Message)
+}
+
+func TestVerifyChain(t *testing.T) {
+	var lines []string
+	chain := NewChain(nil, log.NopLogger(), func(b []byte) {
+		lines = append(lines, string(b))
+	})
+	for i := 0; i < 5; i++ {
+		chain.Log([]byte(`{"Action":"file_create","Message":"user 'a' created file 'b'"}`))
+	}
+
+	verify := func(lines ...string) []ChainProblem {
+		report, err := VerifyChain(strings.NewReader(strings.Join(lines, "\n")), nil)
+		require.NoError(t, err)
+		return report.Problems
+	}
+
+	require.Empty(t, verify(lines...))
+	require.Equal(t, []ChainProblem{{Line: 2, Message: "record 2 is missing"}}, verify(lines[0], lines[2], lines[3], lines[4]))
+	require.Equal(t, []ChainProblem{{Line: 3, Message: "records 3 to 4 are missing"}}, verify(lines[0], lines[1], lines[4]))
+	require.Equal(t, []ChainProblem{{Line: 1, Message: "the chain starts at record 2, the records before are missing"}}, verify(lines[1:]...))
+	require.Equal(t, []ChainProblem{
+		{Line: 3, Message: "record 3 is missing"},
+		{Line: 4, Message: "record 3 is out of order, it follows record 4"},
+	}, verify(lines[0], lines[1], lines[3], lines[2], lines[4]))
+	require.Equal(t, []ChainProblem{{Line: 2, Message: "the chain was restarted after record 5"}}, verify(lines[4], lines[0])[1:])
+
+	edited := strings.Replace(lines[2], "user 'a'", "user 'c'", 1)
+	require.Equal(t, []ChainProblem{{Line: 3, Message: "record was modified"}}, verify(lines[0], lines[1], edited, lines[3], lines[4]))
+
+	// an edited record with a recomputed hash breaks the link to the next record
+	i := strings.LastIndex(edited, `,"Hash":"`)
+	rehashed := edited[:i] + string(tail(recordHash([]byte(edited[:i])), ""))
+	require.Equal(t, []ChainProblem{{Line: 4, Message: "record 4 does not follow the previous record"}}, verify(lines[0], lines[1], rehashed, lines[3], lines[4]))
+
+	appended := strings.TrimSuffix(lines[2], "}") + `,"Extra":true}`
+	require.Equal(t, []ChainProblem{{Line: 3, Message: "record was modified"}}, verify(lines[0], lines[1], appended, lines[3], lines[4]))
+
+	require.Equal(t, []ChainProblem{{Line: 2, Message: "record is not part of a hash chain"}}, verify(lines[0], `{"Action":"file_create"}`, lines[1]))
+}
+
+func TestChainResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	chain := NewChain(nil, log.NopLogger(), WriteToFile(path, log.NopLogger()))
+	require.NoError(t, chain.Resume(path))
+	chain.Log([]byte(`{"Action":"file_create"}`))
+	chain.Log([]byte(`{"Action":"file_delete","Message":"` + strings.Repeat("x", 5000) + `"}`))
+
+	chain = NewChain(nil, log.NopLogger(), WriteToFile(path, log.NopLogger()))
+	require.NoError(t, chain.Resume(path))
+	chain.Checkpoint(time.Now())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	report, err := VerifyChain(bytes.NewReader(b), nil)
+	require.NoError(t, err)
+	require.Empty(t, report.Problems)
+	require.Equal(t, uint64(3), report.Records)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"Action":"` + types.ActionFileCreated + `"}` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	chain = NewChain(nil, log.NopLogger(), WriteToFile(path, log.NopLogger()))
+	require.Error(t, chain.Resume(path))
+}
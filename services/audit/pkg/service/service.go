@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/audit/pkg/config"
@@ -19,7 +20,7 @@ type Log func([]byte)
 type Marshaller func(interface{}) ([]byte, error)
 
 // AuditLoggerFromConfig will start a new AuditLogger generated from the config
-func AuditLoggerFromConfig(ctx context.Context, cfg config.Auditlog, ch <-chan events.Event, log log.Logger) {
+func AuditLoggerFromConfig(ctx context.Context, cfg config.Auditlog, ch <-chan events.Event, log log.Logger) error {
 	var logs []Log
 
 	if cfg.LogToConsole {
@@ -30,8 +31,27 @@ func AuditLoggerFromConfig(ctx context.Context, cfg config.Auditlog, ch <-chan e
 		logs = append(logs, WriteToFile(cfg.FilePath, log))
 	}
 
-	StartAuditLogger(ctx, ch, log, Marshal(cfg.Format, log), logs...)
+	if cfg.HashChain {
+		chain := NewChain([]byte(cfg.HMACKey), log, logs...)
+		if cfg.LogToFile {
+			if err := chain.Resume(cfg.FilePath); err != nil {
+				return err
+			}
+		}
+		logs = []Log{chain.Log}
+
+		// the checkpoints mark the start and the end of the service as well
+		chain.Checkpoint(time.Now())
+		stop := make(chan struct{})
+		go chain.checkpoints(cfg.CheckpointInterval, stop)
+		defer func() {
+			close(stop)
+			chain.Checkpoint(time.Now())
+		}()
+	}
 
+	StartAuditLogger(ctx, ch, log, Marshal(cfg.Format, log), logs...)
+	return nil
 }
 
 // StartAuditLogger will block. run in separate go routine
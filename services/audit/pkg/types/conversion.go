@@ -526,6 +526,11 @@ func ScienceMeshInviteTokenGenerated(ev events.ScienceMeshInviteTokenGenerated)
 	}
 }
 
+// AuditCheckpoint creates the checkpoint of a hash chained audit log, seq is the sequence number of the last record before it
+func AuditCheckpoint(seq uint64, t time.Time) AuditEvent {
+	return BasicAuditEvent("", t.UTC().Format(time.RFC3339), MessageAuditCheckpoint(seq), ActionAuditCheckpoint)
+}
+
 func extractGrantee(uid *user.UserId, gid *group.GroupId) (string, string) {
 	switch {
 	case uid != nil && uid.OpaqueId != "":
@@ -56,6 +56,9 @@ const (
 
 	// ScienceMesh
 	ActionScienceMeshInviteTokenGenerated = "science_mesh_invite_token_generated"
+
+	// Audit log
+	ActionAuditCheckpoint = "audit_checkpoint"
 )
 
 // MessageShareCreated returns the human-readable string that describes the action
@@ -250,3 +253,8 @@ func MessageGroupMemberRemoved(executant, userID, groupID string) string {
 func MessageScienceMeshInviteTokenGenerated(user, token string) string {
 	return fmt.Sprintf("user '%s' generated a ScienceMesh invite with token '%s'", user, token)
 }
+
+// MessageAuditCheckpoint returns the human-readable string that describes the action
+func MessageAuditCheckpoint(seq uint64) string {
+	return fmt.Sprintf("audit log checkpoint after record '%d'", seq)
+}
@@ -3,10 +3,6 @@ package command
 import (
 	"context"
 	"fmt"
-
-	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
-	"github.com/owncloud/reva/v2/pkg/auth/scope"
-
 	"time"
 
 	applicationsv1beta1 "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
@@ -14,11 +10,13 @@ import (
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
 	typesv1beta1 "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
 	"github.com/owncloud/ocis/v2/ocis-pkg/tracing"
 	"github.com/owncloud/ocis/v2/services/auth-app/pkg/config"
 	"github.com/owncloud/ocis/v2/services/auth-app/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/auth-app/pkg/service"
 	ctxpkg "github.com/owncloud/reva/v2/pkg/ctx"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/urfave/cli/v2"
@@ -42,6 +40,19 @@ func Create(cfg *config.Config) *cli.Command {
 				Value: "72h",
 				Usage: "expiration of the app password, e.g. 72h, 1h, 1m, 1s. Default is 72h.",
 			},
+			&cli.StringSliceFlag{
+				Name:  "resource",
+				Usage: "restrict the app-token to the space or folder with this id, can be used multiple times",
+			},
+			&cli.StringFlag{
+				Name:  "access",
+				Value: string(apptoken.AccessFull),
+				Usage: "restrict the operations of the app-token, one of 'full', 'read' or 'upload'",
+			},
+			&cli.StringSliceFlag{
+				Name:  "ip",
+				Usage: "restrict the app-token to requests from this IP address or range in CIDR notation, can be used multiple times",
+			},
 		},
 		Before: func(_ *cli.Context) error {
 			return configlog.ReturnError(parser.ParseConfig(cfg))
@@ -92,7 +103,16 @@ func Create(cfg *config.Config) *cli.Command {
 			granteeCtx := ctxpkg.ContextSetUser(context.Background(), &userpb.User{Id: authRes.GetUser().GetId()})
 			granteeCtx = metadata.AppendToOutgoingContext(granteeCtx, ctxpkg.TokenHeader, authRes.GetToken())
 
-			scopes, err := scope.AddOwnerScope(map[string]*authpb.Scope{})
+			restrictions := &apptoken.Restrictions{
+				Resources: c.StringSlice("resource"),
+				Access:    apptoken.Access(c.String("access")),
+				Networks:  c.StringSlice("ip"),
+			}
+			if err := service.ResolveRestrictions(granteeCtx, next, restrictions); err != nil {
+				return err
+			}
+
+			scopes, err := apptoken.Scopes(restrictions)
 			if err != nil {
 				return err
 			}
@@ -6,22 +6,24 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	applications "github.com/cs3org/go-cs3apis/cs3/auth/applications/v1beta1"
-	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/go-chi/chi/v5"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/roles"
 	"github.com/owncloud/ocis/v2/services/auth-app/pkg/config"
 	settings "github.com/owncloud/ocis/v2/services/settings/pkg/service/v0"
 	"github.com/owncloud/reva/v2/pkg/appctx"
-	"github.com/owncloud/reva/v2/pkg/auth/scope"
 	ctxpkg "github.com/owncloud/reva/v2/pkg/ctx"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
 	"github.com/owncloud/reva/v2/pkg/utils"
 	"google.golang.org/grpc/metadata"
 )
@@ -37,6 +39,8 @@ type AuthAppToken struct {
 	ExpirationDate time.Time `json:"expiration_date"`
 	CreatedDate    time.Time `json:"created_date"`
 	Label          string    `json:"label"`
+	// Restrictions are the restrictions of the token, nil if the token grants full access
+	Restrictions *apptoken.Restrictions `json:"restrictions,omitempty"`
 }
 
 // AuthAppService defines the service interface.
@@ -147,9 +151,24 @@ func (a *AuthAppService) HandleCreate(w http.ResponseWriter, r *http.Request) {
 		label = customLabel
 	}
 
-	scopes, err := scope.AddOwnerScope(map[string]*authpb.Scope{})
+	restrictions := &apptoken.Restrictions{
+		Resources: splitParams(q["resource"]),
+		Access:    apptoken.Access(q.Get("access")),
+		Networks:  splitParams(q["ip"]),
+	}
+	if err := ResolveRestrictions(ctx, gwc, restrictions); err != nil {
+		sublog.Info().Err(err).Msg("invalid restrictions")
+		if errors.Is(err, ErrBadRequest) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	scopes, err := apptoken.Scopes(restrictions)
 	if err != nil {
-		sublog.Error().Err(err).Msg("error adding owner scope")
+		sublog.Error().Err(err).Msg("error adding token scope")
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -263,6 +282,45 @@ func (a *AuthAppService) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// ResolveRestrictions validates the restrictions of an app token. The resources must be spaces or folders
+// the user in the context has access to, their ids are replaced by the ids of the spaces or folders.
+func ResolveRestrictions(ctx context.Context, gwc gateway.GatewayAPIClient, r *apptoken.Restrictions) error {
+	if err := r.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", err.Error(), ErrBadRequest)
+	}
+
+	for i, res := range r.Resources {
+		id, _ := storagespace.ParseID(res)
+		if id.GetOpaqueId() == "" {
+			id.OpaqueId = id.GetSpaceId()
+		}
+
+		statRes, err := gwc.Stat(ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: &id}})
+		if err != nil {
+			return err
+		}
+		switch statRes.GetStatus().GetCode() {
+		case rpc.Code_CODE_OK:
+		case rpc.Code_CODE_NOT_FOUND, rpc.Code_CODE_PERMISSION_DENIED:
+			return fmt.Errorf("resource '%s' not found: %w", res, ErrBadRequest)
+		default:
+			return errors.New("error stating resource: " + statRes.GetStatus().GetMessage())
+		}
+
+		info := statRes.GetInfo()
+		if info.GetType() != provider.ResourceType_RESOURCE_TYPE_CONTAINER {
+			return fmt.Errorf("resource '%s' is not a space or folder: %w", res, ErrBadRequest)
+		}
+		if info.GetId().GetOpaqueId() == info.GetId().GetSpaceId() {
+			r.Resources[i] = storagespace.FormatStorageID(info.GetId().GetStorageId(), info.GetId().GetSpaceId())
+			continue
+		}
+		r.Resources[i] = storagespace.FormatResourceID(info.GetId())
+	}
+
+	return nil
+}
+
 func (a *AuthAppService) authenticateUser(userID, userName string, gwc gateway.GatewayAPIClient) (context.Context, error) {
 	ctx := context.Background()
 	authRes, err := gwc.Authenticate(ctx, &gateway.AuthenticateRequest{
@@ -338,10 +396,26 @@ func isAdmin(ctx context.Context, rm *roles.Manager) (bool, error) {
 }
 
 func convert(ap *applications.AppPassword) AuthAppToken {
+	// the proxy rejects tokens with unreadable restrictions, there is nothing more to show for them
+	restrictions, _ := apptoken.FromScopes(ap.GetTokenScope())
 	return AuthAppToken{
 		Token:          ap.GetPassword(),
 		ExpirationDate: utils.TSToTime(ap.GetExpiration()),
 		CreatedDate:    utils.TSToTime(ap.GetCtime()),
 		Label:          ap.GetLabel(),
+		Restrictions:   restrictions,
+	}
+}
+
+// splitParams splits comma separated query parameter values
+func splitParams(values []string) []string {
+	var params []string
+	for _, v := range values {
+		for _, p := range strings.Split(v, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				params = append(params, p)
+			}
+		}
 	}
+	return params
 }
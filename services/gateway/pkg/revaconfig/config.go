@@ -153,6 +153,11 @@ func spacesProviders(cfg *config.Config, logger log.Logger) map[string]map[strin
 					"mount_point":   "/projects",
 					"path_template": "/projects/{{.Space.Name}}",
 				},
+				// the quarantine space of the antivirus service
+				"quarantine": map[string]interface{}{
+					"mount_point":   "/quarantine",
+					"path_template": "/quarantine/{{.Space.Name}}",
+				},
 			},
 		},
 		cfg.StorageSharesEndpoint: {
@@ -29,6 +29,69 @@ func (_m *DrivesDriveItemProvider) EXPECT() *DrivesDriveItemProvider_Expecter {
 	return &DrivesDriveItemProvider_Expecter{mock: &_m.Mock}
 }
 
+// CopyItem provides a mock function with given fields: ctx, itemID, parentID, name, conflictBehavior, progress
+func (_m *DrivesDriveItemProvider) CopyItem(ctx context.Context, itemID *providerv1beta1.ResourceId, parentID *providerv1beta1.ResourceId, name string, conflictBehavior svc.ConflictBehavior, progress svc.CopyProgressFunc) (*providerv1beta1.ResourceInfo, error) {
+	ret := _m.Called(ctx, itemID, parentID, name, conflictBehavior, progress)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CopyItem")
+	}
+
+	var r0 *providerv1beta1.ResourceInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior, svc.CopyProgressFunc) (*providerv1beta1.ResourceInfo, error)); ok {
+		return rf(ctx, itemID, parentID, name, conflictBehavior, progress)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior, svc.CopyProgressFunc) *providerv1beta1.ResourceInfo); ok {
+		r0 = rf(ctx, itemID, parentID, name, conflictBehavior, progress)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*providerv1beta1.ResourceInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior, svc.CopyProgressFunc) error); ok {
+		r1 = rf(ctx, itemID, parentID, name, conflictBehavior, progress)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DrivesDriveItemProvider_CopyItem_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CopyItem'
+type DrivesDriveItemProvider_CopyItem_Call struct {
+	*mock.Call
+}
+
+// CopyItem is a helper method to define mock.On call
+//   - ctx context.Context
+//   - itemID *providerv1beta1.ResourceId
+//   - parentID *providerv1beta1.ResourceId
+//   - name string
+//   - conflictBehavior svc.ConflictBehavior
+//   - progress svc.CopyProgressFunc
+func (_e *DrivesDriveItemProvider_Expecter) CopyItem(ctx interface{}, itemID interface{}, parentID interface{}, name interface{}, conflictBehavior interface{}, progress interface{}) *DrivesDriveItemProvider_CopyItem_Call {
+	return &DrivesDriveItemProvider_CopyItem_Call{Call: _e.mock.On("CopyItem", ctx, itemID, parentID, name, conflictBehavior, progress)}
+}
+
+func (_c *DrivesDriveItemProvider_CopyItem_Call) Run(run func(ctx context.Context, itemID *providerv1beta1.ResourceId, parentID *providerv1beta1.ResourceId, name string, conflictBehavior svc.ConflictBehavior, progress svc.CopyProgressFunc)) *DrivesDriveItemProvider_CopyItem_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*providerv1beta1.ResourceId), args[2].(*providerv1beta1.ResourceId), args[3].(string), args[4].(svc.ConflictBehavior), args[5].(svc.CopyProgressFunc))
+	})
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_CopyItem_Call) Return(_a0 *providerv1beta1.ResourceInfo, _a1 error) *DrivesDriveItemProvider_CopyItem_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_CopyItem_Call) RunAndReturn(run func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior, svc.CopyProgressFunc) (*providerv1beta1.ResourceInfo, error)) *DrivesDriveItemProvider_CopyItem_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetShare provides a mock function with given fields: ctx, shareID
 func (_m *DrivesDriveItemProvider) GetShare(ctx context.Context, shareID *collaborationv1beta1.ShareId) (*collaborationv1beta1.ReceivedShare, error) {
 	ret := _m.Called(ctx, shareID)
@@ -148,6 +211,65 @@ func (_c *DrivesDriveItemProvider_GetSharesForResource_Call) RunAndReturn(run fu
 	return _c
 }
 
+// ListVersions provides a mock function with given fields: ctx, itemID
+func (_m *DrivesDriveItemProvider) ListVersions(ctx context.Context, itemID *providerv1beta1.ResourceId) ([]*providerv1beta1.FileVersion, error) {
+	ret := _m.Called(ctx, itemID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListVersions")
+	}
+
+	var r0 []*providerv1beta1.FileVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId) ([]*providerv1beta1.FileVersion, error)); ok {
+		return rf(ctx, itemID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId) []*providerv1beta1.FileVersion); ok {
+		r0 = rf(ctx, itemID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*providerv1beta1.FileVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *providerv1beta1.ResourceId) error); ok {
+		r1 = rf(ctx, itemID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DrivesDriveItemProvider_ListVersions_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListVersions'
+type DrivesDriveItemProvider_ListVersions_Call struct {
+	*mock.Call
+}
+
+// ListVersions is a helper method to define mock.On call
+//   - ctx context.Context
+//   - itemID *providerv1beta1.ResourceId
+func (_e *DrivesDriveItemProvider_Expecter) ListVersions(ctx interface{}, itemID interface{}) *DrivesDriveItemProvider_ListVersions_Call {
+	return &DrivesDriveItemProvider_ListVersions_Call{Call: _e.mock.On("ListVersions", ctx, itemID)}
+}
+
+func (_c *DrivesDriveItemProvider_ListVersions_Call) Run(run func(ctx context.Context, itemID *providerv1beta1.ResourceId)) *DrivesDriveItemProvider_ListVersions_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*providerv1beta1.ResourceId))
+	})
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_ListVersions_Call) Return(_a0 []*providerv1beta1.FileVersion, _a1 error) *DrivesDriveItemProvider_ListVersions_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_ListVersions_Call) RunAndReturn(run func(context.Context, *providerv1beta1.ResourceId) ([]*providerv1beta1.FileVersion, error)) *DrivesDriveItemProvider_ListVersions_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // MountOCMShare provides a mock function with given fields: ctx, resourceID
 func (_m *DrivesDriveItemProvider) MountOCMShare(ctx context.Context, resourceID *providerv1beta1.ResourceId) ([]*ocmv1beta1.ReceivedShare, error) {
 	ret := _m.Called(ctx, resourceID)
@@ -267,6 +389,116 @@ func (_c *DrivesDriveItemProvider_MountShare_Call) RunAndReturn(run func(context
 	return _c
 }
 
+// MoveItem provides a mock function with given fields: ctx, itemID, parentID, name, conflictBehavior
+func (_m *DrivesDriveItemProvider) MoveItem(ctx context.Context, itemID *providerv1beta1.ResourceId, parentID *providerv1beta1.ResourceId, name string, conflictBehavior svc.ConflictBehavior) (*providerv1beta1.ResourceInfo, error) {
+	ret := _m.Called(ctx, itemID, parentID, name, conflictBehavior)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MoveItem")
+	}
+
+	var r0 *providerv1beta1.ResourceInfo
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior) (*providerv1beta1.ResourceInfo, error)); ok {
+		return rf(ctx, itemID, parentID, name, conflictBehavior)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior) *providerv1beta1.ResourceInfo); ok {
+		r0 = rf(ctx, itemID, parentID, name, conflictBehavior)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*providerv1beta1.ResourceInfo)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior) error); ok {
+		r1 = rf(ctx, itemID, parentID, name, conflictBehavior)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DrivesDriveItemProvider_MoveItem_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MoveItem'
+type DrivesDriveItemProvider_MoveItem_Call struct {
+	*mock.Call
+}
+
+// MoveItem is a helper method to define mock.On call
+//   - ctx context.Context
+//   - itemID *providerv1beta1.ResourceId
+//   - parentID *providerv1beta1.ResourceId
+//   - name string
+//   - conflictBehavior svc.ConflictBehavior
+func (_e *DrivesDriveItemProvider_Expecter) MoveItem(ctx interface{}, itemID interface{}, parentID interface{}, name interface{}, conflictBehavior interface{}) *DrivesDriveItemProvider_MoveItem_Call {
+	return &DrivesDriveItemProvider_MoveItem_Call{Call: _e.mock.On("MoveItem", ctx, itemID, parentID, name, conflictBehavior)}
+}
+
+func (_c *DrivesDriveItemProvider_MoveItem_Call) Run(run func(ctx context.Context, itemID *providerv1beta1.ResourceId, parentID *providerv1beta1.ResourceId, name string, conflictBehavior svc.ConflictBehavior)) *DrivesDriveItemProvider_MoveItem_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*providerv1beta1.ResourceId), args[2].(*providerv1beta1.ResourceId), args[3].(string), args[4].(svc.ConflictBehavior))
+	})
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_MoveItem_Call) Return(_a0 *providerv1beta1.ResourceInfo, _a1 error) *DrivesDriveItemProvider_MoveItem_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_MoveItem_Call) RunAndReturn(run func(context.Context, *providerv1beta1.ResourceId, *providerv1beta1.ResourceId, string, svc.ConflictBehavior) (*providerv1beta1.ResourceInfo, error)) *DrivesDriveItemProvider_MoveItem_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestoreVersion provides a mock function with given fields: ctx, itemID, key
+func (_m *DrivesDriveItemProvider) RestoreVersion(ctx context.Context, itemID *providerv1beta1.ResourceId, key string) error {
+	ret := _m.Called(ctx, itemID, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreVersion")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *providerv1beta1.ResourceId, string) error); ok {
+		r0 = rf(ctx, itemID, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DrivesDriveItemProvider_RestoreVersion_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreVersion'
+type DrivesDriveItemProvider_RestoreVersion_Call struct {
+	*mock.Call
+}
+
+// RestoreVersion is a helper method to define mock.On call
+//   - ctx context.Context
+//   - itemID *providerv1beta1.ResourceId
+//   - key string
+func (_e *DrivesDriveItemProvider_Expecter) RestoreVersion(ctx interface{}, itemID interface{}, key interface{}) *DrivesDriveItemProvider_RestoreVersion_Call {
+	return &DrivesDriveItemProvider_RestoreVersion_Call{Call: _e.mock.On("RestoreVersion", ctx, itemID, key)}
+}
+
+func (_c *DrivesDriveItemProvider_RestoreVersion_Call) Run(run func(ctx context.Context, itemID *providerv1beta1.ResourceId, key string)) *DrivesDriveItemProvider_RestoreVersion_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(*providerv1beta1.ResourceId), args[2].(string))
+	})
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_RestoreVersion_Call) Return(_a0 error) *DrivesDriveItemProvider_RestoreVersion_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *DrivesDriveItemProvider_RestoreVersion_Call) RunAndReturn(run func(context.Context, *providerv1beta1.ResourceId, string) error) *DrivesDriveItemProvider_RestoreVersion_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // UnmountShare provides a mock function with given fields: ctx, shareID
 func (_m *DrivesDriveItemProvider) UnmountShare(ctx context.Context, shareID *collaborationv1beta1.ShareId) error {
 	ret := _m.Called(ctx, shareID)
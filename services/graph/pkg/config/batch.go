@@ -0,0 +1,7 @@
+package config
+
+// Batch configures the JSON batching of graph requests.
+type Batch struct {
+	MaxRequests    int `yaml:"max_requests" env:"GRAPH_BATCH_MAX_REQUESTS" desc:"The maximum number of requests a client can combine in one '$batch' request." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxConcurrency int `yaml:"max_concurrency" env:"GRAPH_BATCH_MAX_CONCURRENCY" desc:"The maximum number of requests of one '$batch' request that are executed at the same time." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
@@ -142,6 +142,30 @@ func DefaultConfig() *config.Config {
 			MaxTagLength:     100,
 			MaxImageFileSize: "50MB",
 		},
+		Subscriptions: config.Subscriptions{
+			MaxExpiration:     72 * time.Hour,
+			ValidationTimeout: 10 * time.Second,
+			Timeout:           10 * time.Second,
+			MaxRetries:        3,
+			RetryBackoff:      time.Second,
+			Store:             "nats-js-kv",
+			Nodes:             []string{"127.0.0.1:9233"},
+			Database:          "graph",
+			Table:             "subscriptions",
+		},
+		Delta: config.Delta{
+			MaxPageSize:       200,
+			TokenMaxAge:       336 * time.Hour,
+			MaxActivities:     1000,
+			ActivitiesTimeout: 30 * time.Second,
+		},
+		Batch: config.Batch{
+			MaxRequests:    20,
+			MaxConcurrency: 5,
+		},
+		Search: config.Search{
+			MaxResults: 1000,
+		},
 	}
 }
 
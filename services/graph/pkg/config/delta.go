@@ -0,0 +1,12 @@
+package config
+
+import "time"
+
+// Delta configures the delta queries of drives and drive items.
+type Delta struct {
+	MaxPageSize        int           `yaml:"max_page_size" env:"GRAPH_DELTA_MAX_PAGE_SIZE" desc:"The maximum number of changed items returned in one page of a delta query. Clients can request smaller pages with the '$top' query parameter." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TokenMaxAge        time.Duration `yaml:"token_max_age" env:"GRAPH_DELTA_TOKEN_MAX_AGE" desc:"The maximum age of a delta token. Clients with an older token have to start a new sync. Moved and deleted items are taken from the recorded activities, so this must not exceed the time the eventhistory service keeps events, see EVENTHISTORY_STORE_TTL. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxActivities      int           `yaml:"max_activities" env:"GRAPH_DELTA_MAX_ACTIVITIES" desc:"The maximum number of activities read for a delta query. Clients have to start a new sync if more activities were recorded for the folder since the last sync. This must be lower than the number of activities the activitylog service keeps per item." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ActivitiesTimeout  time.Duration `yaml:"activities_timeout" env:"GRAPH_DELTA_ACTIVITIES_TIMEOUT" desc:"The timeout for reading the activities of a folder from the activitylog service. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ActivitiesInsecure bool          `yaml:"activities_insecure" env:"OCIS_INSECURE;GRAPH_DELTA_ACTIVITIES_INSECURE" desc:"Skip the TLS certificate verification when reading the activities from the activitylog service." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
@@ -0,0 +1,6 @@
+package config
+
+// Search configures the graph search endpoint.
+type Search struct {
+	MaxResults int `yaml:"max_results" env:"GRAPH_SEARCH_MAX_RESULTS" desc:"The maximum number of matches the search service is asked for per query. Paging, sorting and aggregations of the '/search/query' endpoint operate on these matches." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+// Subscriptions configures the change notification subscriptions of the graph API.
+type Subscriptions struct {
+	Enabled              bool          `yaml:"enabled" env:"GRAPH_SUBSCRIPTIONS_ENABLED" desc:"Enables the '/subscriptions' endpoint which allows users to register webhooks for change notifications of drives and drive items. Note that the graph service will then send requests to any URL a user enters." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxExpiration        time.Duration `yaml:"max_expiration" env:"GRAPH_SUBSCRIPTIONS_MAX_EXPIRATION" desc:"The maximum lifetime of a subscription. Clients need to renew subscriptions before they expire. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ValidationTimeout    time.Duration `yaml:"validation_timeout" env:"GRAPH_SUBSCRIPTIONS_VALIDATION_TIMEOUT" desc:"The time the notification URL has to answer the validation request when a subscription is created. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Timeout              time.Duration `yaml:"timeout" env:"GRAPH_SUBSCRIPTIONS_TIMEOUT" desc:"Timeout for a single change notification request. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxRetries           int           `yaml:"max_retries" env:"GRAPH_SUBSCRIPTIONS_MAX_RETRIES" desc:"Number of times a failed change notification request is retried. Requests are only retried on network errors, rate limiting and server errors." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	RetryBackoff         time.Duration `yaml:"retry_backoff" env:"GRAPH_SUBSCRIPTIONS_RETRY_BACKOFF" desc:"Time to wait before the first retry of a failed change notification request. The time doubles with every further retry. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	InsecureSkipVerify   bool          `yaml:"insecure" env:"OCIS_INSECURE;GRAPH_SUBSCRIPTIONS_INSECURE" desc:"Skip the TLS certificate verification of notification URLs." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Store                string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;GRAPH_SUBSCRIPTIONS_STORE" desc:"The type of the store. Supported values are: 'memory', 'nats-js-kv', 'redis-sentinel', 'noop'. See the text description for details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Nodes                []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;GRAPH_SUBSCRIPTIONS_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Database             string        `yaml:"database" env:"GRAPH_SUBSCRIPTIONS_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Table                string        `yaml:"table" env:"GRAPH_SUBSCRIPTIONS_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthUsername         string        `yaml:"username" env:"OCIS_PERSISTENT_STORE_AUTH_USERNAME;GRAPH_SUBSCRIPTIONS_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthPassword         string        `yaml:"password" env:"OCIS_PERSISTENT_STORE_AUTH_PASSWORD;GRAPH_SUBSCRIPTIONS_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	EnableTLS            bool          `yaml:"enable_tls" env:"OCIS_PERSISTENT_STORE_ENABLE_TLS;GRAPH_SUBSCRIPTIONS_STORE_ENABLE_TLS" desc:"Activate TLS for the connection to the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSInsecure          bool          `yaml:"tls_insecure" env:"OCIS_PERSISTENT_STORE_TLS_INSECURE;GRAPH_SUBSCRIPTIONS_STORE_TLS_INSECURE" desc:"Disable TLS certificate verification for the store connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSRootCACertificate string        `yaml:"tls_root_ca_certificate" env:"OCIS_PERSISTENT_STORE_TLS_ROOT_CA_CERTIFICATE;GRAPH_SUBSCRIPTIONS_STORE_TLS_ROOT_CA_CERTIFICATE" desc:"Path to the PEM-encoded root CA certificate for the store TLS connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
@@ -8,9 +8,12 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/owncloud/reva/v2/pkg/events/stream"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/store"
 	"github.com/pkg/errors"
 	"go-micro.dev/v4"
 	"go-micro.dev/v4/events"
+	"go-micro.dev/v4/selector"
+	microstore "go-micro.dev/v4/store"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/account"
 	"github.com/owncloud/ocis/v2/ocis-pkg/cors"
@@ -26,6 +29,7 @@ import (
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
 	graphMiddleware "github.com/owncloud/ocis/v2/services/graph/pkg/middleware"
 	svc "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
 )
 
 // Server initializes the http service and server.
@@ -130,6 +134,28 @@ func Server(opts ...Option) (http.Service, error) {
 
 	hClient := ehsvc.NewEventHistoryService("com.owncloud.api.eventhistory", grpcClient)
 
+	var subscriptionStore *subscriptions.Store
+	var subscriptionNotifier *subscriptions.Notifier
+	if cfg := options.Config.Subscriptions; cfg.Enabled {
+		subscriptionStore = subscriptions.NewStore(store.Create(
+			store.Store(cfg.Store),
+			microstore.Nodes(cfg.Nodes...),
+			microstore.Database(cfg.Database),
+			microstore.Table(cfg.Table),
+			store.Authentication(cfg.AuthUsername, cfg.AuthPassword),
+			store.TLS(cfg.EnableTLS, cfg.TLSInsecure, cfg.TLSRootCACertificate),
+		))
+		subscriptionNotifier = subscriptions.NewNotifier(cfg, options.Logger)
+
+		// change notifications need the events of the storage and the gateway to look up the changed items
+		if eventsStream != nil && gatewaySelector != nil {
+			listener := subscriptions.NewListener(subscriptionStore, subscriptionNotifier, gatewaySelector, options.Config.ServiceAccount, options.Logger)
+			if err := listener.Start(options.Context, eventsStream); err != nil {
+				return http.Service{}, errors.Wrap(err, "could not consume events for subscriptions")
+			}
+		}
+	}
+
 	var handle svc.Service
 	handle, err = svc.NewService(
 		svc.Context(options.Context),
@@ -146,6 +172,9 @@ func Server(opts ...Option) (http.Service, error) {
 		svc.KeycloakClient(keyCloakClient),
 		svc.EventHistoryClient(hClient),
 		svc.TraceProvider(options.TraceProvider),
+		svc.WithSubscriptionStore(subscriptionStore),
+		svc.WithSubscriptionNotifier(subscriptionNotifier),
+		svc.WithServiceSelector(selector.NewSelector(selector.Registry(registry.GetRegistry()))),
 	)
 
 	if err != nil {
@@ -12,6 +12,7 @@ import (
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/go-chi/render"
 	libregraph "github.com/owncloud/libre-graph-api-go"
+	microstore "go-micro.dev/v4/store"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
@@ -97,6 +98,18 @@ type (
 
 		// GetSharesForResource returns all shares for a given resourceID
 		GetSharesForResource(ctx context.Context, resourceID *storageprovider.ResourceId, filters []*collaboration.Filter) ([]*collaboration.ReceivedShare, error)
+
+		// MoveItem moves and/or renames an item within its drive
+		MoveItem(ctx context.Context, itemID *storageprovider.ResourceId, parentID *storageprovider.ResourceId, name string, conflictBehavior ConflictBehavior) (*storageprovider.ResourceInfo, error)
+
+		// CopyItem copies an item and all its children to a folder
+		CopyItem(ctx context.Context, itemID *storageprovider.ResourceId, parentID *storageprovider.ResourceId, name string, conflictBehavior ConflictBehavior, progress CopyProgressFunc) (*storageprovider.ResourceInfo, error)
+
+		// ListVersions lists the previous versions of a file
+		ListVersions(ctx context.Context, itemID *storageprovider.ResourceId) ([]*storageprovider.FileVersion, error)
+
+		// RestoreVersion restores a previous version of a file
+		RestoreVersion(ctx context.Context, itemID *storageprovider.ResourceId, key string) error
 	}
 )
 
@@ -336,14 +349,16 @@ type DrivesDriveItemApi struct {
 	logger                 log.Logger
 	drivesDriveItemService DrivesDriveItemProvider
 	baseGraphService       BaseGraphProvider
+	operations             *driveItemOperations
 }
 
-// NewDrivesDriveItemApi creates a new DrivesDriveItemApi
-func NewDrivesDriveItemApi(drivesDriveItemService DrivesDriveItemProvider, baseGraphService BaseGraphProvider, logger log.Logger) (DrivesDriveItemApi, error) {
+// NewDrivesDriveItemApi creates a new DrivesDriveItemApi, the status of long-running operations is kept in the operationStore
+func NewDrivesDriveItemApi(drivesDriveItemService DrivesDriveItemProvider, baseGraphService BaseGraphProvider, operationStore microstore.Store, logger log.Logger) (DrivesDriveItemApi, error) {
 	return DrivesDriveItemApi{
 		logger:                 log.Logger{Logger: logger.With().Str("graph api", "DrivesDriveItemApi").Logger()},
 		drivesDriveItemService: drivesDriveItemService,
 		baseGraphService:       baseGraphService,
+		operations:             newDriveItemOperations(operationStore),
 	}, nil
 }
 
@@ -421,7 +436,8 @@ func (api DrivesDriveItemApi) GetDriveItem(w http.ResponseWriter, r *http.Reques
 	render.JSON(w, r, driveItems[0])
 }
 
-// UpdateDriveItem updates a drive item, currently only the visibility of the share is updated
+// UpdateDriveItem updates a drive item. In the share jail only the visibility of the share is updated,
+// in other drives the item is moved and/or renamed.
 func (api DrivesDriveItemApi) UpdateDriveItem(w http.ResponseWriter, r *http.Request) {
 	driveID, itemID, err := GetDriveAndItemIDParam(r, &api.logger)
 	if err != nil {
@@ -431,8 +447,7 @@ func (api DrivesDriveItemApi) UpdateDriveItem(w http.ResponseWriter, r *http.Req
 	}
 
 	if !IsShareJail(driveID) {
-		api.logger.Debug().Interface("driveID", driveID).Msg(ErrNotAShareJail.Error())
-		ErrNotAShareJail.Render(w, r)
+		api.moveDriveItem(w, r, driveID, itemID)
 		return
 	}
 
@@ -0,0 +1,725 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/rhttp"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+)
+
+// ConflictBehavior defines what happens if the target of a copy or move already exists
+type ConflictBehavior string
+
+// CopyProgressFunc is called with the number of bytes copied so far and the total number of bytes of a copy
+type CopyProgressFunc func(copied, total uint64)
+
+const (
+	// ConflictBehaviorFail fails the operation if the target exists
+	ConflictBehaviorFail ConflictBehavior = "fail"
+	// ConflictBehaviorReplace deletes the existing target before the operation
+	ConflictBehaviorReplace ConflictBehavior = "replace"
+	// ConflictBehaviorRename picks a new name for the target, e.g. "file (1).txt"
+	ConflictBehaviorRename ConflictBehavior = "rename"
+
+	// OperationStatusNotStarted is the status of an operation that has not started yet
+	OperationStatusNotStarted = "notStarted"
+	// OperationStatusInProgress is the status of a running operation
+	OperationStatusInProgress = "inProgress"
+	// OperationStatusCompleted is the status of a successful operation
+	OperationStatusCompleted = "completed"
+	// OperationStatusFailed is the status of a failed operation
+	OperationStatusFailed = "failed"
+
+	_conflictBehaviorParam     = "@microsoft.graph.conflictBehavior"
+	_maxRenameAttempts         = 100
+	_operationPrefix           = "drive-item-operation/"
+	_operationRetention        = time.Hour
+	_operationTTL              = 24 * time.Hour
+	_operationProgressInterval = time.Second
+	_maxPendingCopies          = 64
+	_maxRunningCopies          = 8
+	_replacePrefix             = ".~copy-"
+)
+
+var (
+	// ErrInvalidConflictBehavior is returned when the conflict behavior is unknown
+	ErrInvalidConflictBehavior = errorcode.New(errorcode.InvalidRequest, "invalid conflict behavior, must be one of fail, replace or rename")
+
+	// ErrInvalidName is returned when the name of an item is not a valid file name
+	ErrInvalidName = errorcode.New(errorcode.InvalidRequest, "invalid name")
+
+	// ErrCrossDriveMove is returned when an item should be moved to another drive
+	ErrCrossDriveMove = errorcode.New(errorcode.InvalidRequest, "items can only be moved within a drive, copy them instead")
+
+	// ErrParentNotAFolder is returned when the parent of a copy is not a folder
+	ErrParentNotAFolder = errorcode.New(errorcode.InvalidRequest, "the parent is not a folder")
+
+	// ErrCopyIntoItself is returned when a folder should be copied into itself
+	ErrCopyIntoItself = errorcode.New(errorcode.InvalidRequest, "a folder cannot be copied into itself")
+
+	// ErrShareJailNotSupported is returned when an operation is not supported on the share jail
+	ErrShareJailNotSupported = errorcode.New(errorcode.InvalidRequest, "the operation is not supported on the shares drive, use the drive of the shared item")
+
+	// ErrOperationNotFound is returned when an operation does not exist or belongs to another user
+	ErrOperationNotFound = errorcode.New(errorcode.ItemNotFound, "operation not found")
+)
+
+// ParseConflictBehavior parses the conflict behavior of a request, it defaults to fail
+func ParseConflictBehavior(s string) (ConflictBehavior, error) {
+	switch cb := ConflictBehavior(strings.ToLower(s)); cb {
+	case "":
+		return ConflictBehaviorFail, nil
+	case ConflictBehaviorFail, ConflictBehaviorReplace, ConflictBehaviorRename:
+		return cb, nil
+	default:
+		return "", ErrInvalidConflictBehavior
+	}
+}
+
+// MoveItem moves and/or renames an item within its drive and returns the moved item.
+// An empty name keeps the current name, a nil parentID keeps the current parent.
+func (s DrivesDriveItemService) MoveItem(ctx context.Context, itemID *storageprovider.ResourceId, parentID *storageprovider.ResourceId, name string, conflictBehavior ConflictBehavior) (*storageprovider.ResourceInfo, error) {
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := statResourceID(ctx, gatewayClient, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID == nil {
+		parentID = item.GetParentId()
+	}
+	if name == "" {
+		name = item.GetName()
+	}
+	if !isValidItemName(name) {
+		return nil, ErrInvalidName
+	}
+	if parentID.GetStorageId() != itemID.GetStorageId() || parentID.GetSpaceId() != itemID.GetSpaceId() {
+		return nil, ErrCrossDriveMove
+	}
+
+	if utils.ResourceIDEqual(parentID, item.GetParentId()) && name == item.GetName() {
+		// the item is already where it should be
+		return item, nil
+	}
+
+	target, existing, err := resolveConflict(ctx, gatewayClient, parentID, name, conflictBehavior, item.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		// the replaced item goes to the trash bin right before the move
+		if err := deleteReference(ctx, gatewayClient, target); err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := gatewayClient.Move(ctx, &storageprovider.MoveRequest{
+		Source:      &storageprovider.Reference{ResourceId: itemID},
+		Destination: target,
+	})
+	if err := errorcode.FromCS3Status(res.GetStatus(), err); err != nil {
+		return nil, err
+	}
+
+	return statResourceID(ctx, gatewayClient, itemID)
+}
+
+// CopyItem copies an item and all its children to a folder and returns the copy.
+// An empty name keeps the name of the item, a nil parentID copies the item into its own parent.
+// Copies may take long, the progress function is called after every chunk of copied data.
+func (s DrivesDriveItemService) CopyItem(ctx context.Context, itemID *storageprovider.ResourceId, parentID *storageprovider.ResourceId, name string, conflictBehavior ConflictBehavior, progress CopyProgressFunc) (*storageprovider.ResourceInfo, error) {
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := statResourceID(ctx, gatewayClient, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID == nil {
+		parentID = item.GetParentId()
+	}
+	if name == "" {
+		name = item.GetName()
+	}
+	if !isValidItemName(name) {
+		return nil, ErrInvalidName
+	}
+
+	parent, err := statResourceID(ctx, gatewayClient, parentID)
+	switch {
+	case err != nil:
+		return nil, err
+	case parent.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		return nil, ErrParentNotAFolder
+	case item.GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		inside, err := isWithin(ctx, gatewayClient, parent.GetId(), item.GetId())
+		if err != nil {
+			return nil, err
+		}
+		if inside {
+			return nil, ErrCopyIntoItself
+		}
+	}
+
+	target, existing, err := resolveConflict(ctx, gatewayClient, parent.GetId(), name, conflictBehavior, item.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	c := &itemCopier{
+		gatewayClient: gatewayClient,
+		client:        rhttp.GetHTTPClient(rhttp.Insecure(true)),
+		total:         item.GetSize(),
+		progress:      progress,
+	}
+	switch {
+	case existing == nil:
+		if err := c.copy(ctx, item, target); err != nil {
+			return nil, err
+		}
+	case existing.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER && item.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		// a replaced file keeps its versions, the copy is uploaded as a new version
+		if err := c.copyFile(ctx, item, target); err != nil {
+			return nil, err
+		}
+	default:
+		// the existing item is only replaced after the copy succeeded
+		if err := c.replace(ctx, item, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return statReference(ctx, gatewayClient, target)
+}
+
+// DriveItemOperation is the status of a long-running drive item operation, it is returned by the monitor URL of the operation
+type DriveItemOperation struct {
+	ID                 string                     `json:"id"`
+	Operation          string                     `json:"operation"`
+	Status             string                     `json:"status"`
+	PercentageComplete float64                    `json:"percentageComplete"`
+	ResourceID         string                     `json:"resourceId,omitempty"`
+	Error              *libregraph.OdataErrorMain `json:"error,omitempty"`
+}
+
+// operationRecord is an operation as it is kept in the store
+type operationRecord struct {
+	DriveItemOperation
+	UserID   string    `json:"userId"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// driveItemOperations keeps the status of the long-running operations in the store of the service,
+// the monitor URL works on every graph instance. The number of running and waiting copies is limited.
+type driveItemOperations struct {
+	store microstore.Store
+	// pending limits the copies which were accepted but have not finished
+	pending chan struct{}
+	// running limits the copies which transfer data at the same time
+	running chan struct{}
+}
+
+func newDriveItemOperations(store microstore.Store) *driveItemOperations {
+	return &driveItemOperations{
+		store:   store,
+		pending: make(chan struct{}, _maxPendingCopies),
+		running: make(chan struct{}, _maxRunningCopies),
+	}
+}
+
+// add registers a new operation of a user
+func (o *driveItemOperations) add(userID, operation string) (*operationRecord, error) {
+	op := &operationRecord{
+		DriveItemOperation: DriveItemOperation{
+			ID:        uuid.New().String(),
+			Operation: operation,
+			Status:    OperationStatusNotStarted,
+		},
+		UserID: userID,
+	}
+	return op, o.write(op)
+}
+
+// get returns an operation of a user, finished operations are forgotten after _operationRetention
+func (o *driveItemOperations) get(userID, id string) (DriveItemOperation, bool) {
+	records, err := o.store.Read(_operationPrefix + id)
+	if err != nil || len(records) == 0 {
+		return DriveItemOperation{}, false
+	}
+	op := &operationRecord{}
+	if err := json.Unmarshal(records[0].Value, op); err != nil {
+		return DriveItemOperation{}, false
+	}
+	if op.UserID != userID || (!op.Finished.IsZero() && time.Since(op.Finished) > _operationRetention) {
+		return DriveItemOperation{}, false
+	}
+	return op.DriveItemOperation, true
+}
+
+// progress records the progress of a running operation, the store is updated at most every
+// _operationProgressInterval
+func (o *driveItemOperations) progress(op *operationRecord, lastWrite *time.Time, copied, total uint64) error {
+	op.Status = OperationStatusInProgress
+	if total > 0 {
+		op.PercentageComplete = min(100, float64(copied)*100/float64(total))
+	}
+	if time.Since(*lastWrite) < _operationProgressInterval {
+		return nil
+	}
+	*lastWrite = time.Now()
+	return o.write(op)
+}
+
+func (o *driveItemOperations) finish(ctx context.Context, op *operationRecord, info *storageprovider.ResourceInfo, err error) error {
+	op.Finished = time.Now()
+	if err != nil {
+		code := errorcode.GeneralException
+		if e, ok := errorcode.ToError(err); ok {
+			code = e.GetCode()
+		}
+		op.Status = OperationStatusFailed
+		op.Error = &code.CreateOdataError(ctx, err.Error()).Error
+		return o.write(op)
+	}
+	op.Status = OperationStatusCompleted
+	op.PercentageComplete = 100
+	op.ResourceID = storagespace.FormatResourceID(info.GetId())
+	return o.write(op)
+}
+
+func (o *driveItemOperations) write(op *operationRecord) error {
+	value, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	return o.store.Write(&microstore.Record{
+		Key:    _operationPrefix + op.ID,
+		Value:  value,
+		Expiry: _operationTTL,
+	})
+}
+
+// CopyDriveItem starts copying a drive item into a folder, which may be in another drive.
+// It returns 202 with the monitor URL of the copy operation in the Location header.
+func (api DrivesDriveItemApi) CopyDriveItem(w http.ResponseWriter, r *http.Request) {
+	driveID, itemID, err := GetDriveAndItemIDParam(r, &api.logger)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidDriveIDOrItemID.Error())
+		ErrInvalidDriveIDOrItemID.Render(w, r)
+		return
+	}
+
+	if IsShareJail(driveID) {
+		api.logger.Debug().Interface("driveID", driveID).Msg(ErrShareJailNotSupported.Error())
+		ErrShareJailNotSupported.Render(w, r)
+		return
+	}
+
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not get user from context")
+		return
+	}
+
+	requestDriveItem := libregraph.DriveItem{}
+	if err := StrictJSONUnmarshal(r.Body, &requestDriveItem); err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidRequestBody.Error())
+		ErrInvalidRequestBody.Render(w, r)
+		return
+	}
+
+	parentID, err := parseParentReference(requestDriveItem.ParentReference)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidID.Error())
+		ErrInvalidID.Render(w, r)
+		return
+	}
+
+	conflictBehavior, err := ParseConflictBehavior(r.URL.Query().Get(_conflictBehaviorParam))
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidConflictBehavior.Error())
+		ErrInvalidConflictBehavior.Render(w, r)
+		return
+	}
+
+	select {
+	case api.operations.pending <- struct{}{}:
+	default:
+		errorcode.ActivityLimitReached.Render(w, r, http.StatusTooManyRequests, "too many copy operations, try again later")
+		return
+	}
+
+	op, err := api.operations.add(u.GetId().GetOpaqueId(), "itemCopy")
+	if err != nil {
+		<-api.operations.pending
+		api.logger.Error().Err(err).Msg("could not store the copy operation")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not store the copy operation")
+		return
+	}
+	// the copy outlives the request, it must not be canceled when the response has been sent
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		defer func() { <-api.operations.pending }()
+		api.operations.running <- struct{}{}
+		defer func() { <-api.operations.running }()
+
+		var lastWrite time.Time
+		info, err := api.drivesDriveItemService.CopyItem(ctx, itemID, parentID, requestDriveItem.GetName(), conflictBehavior, func(copied, total uint64) {
+			if err := api.operations.progress(op, &lastWrite, copied, total); err != nil {
+				api.logger.Error().Err(err).Str("operation", op.ID).Msg("could not store the progress of the copy operation")
+			}
+		})
+		if err != nil {
+			api.logger.Debug().Err(err).Str("operation", op.ID).Msg("copying drive item failed")
+		}
+		if err := api.operations.finish(ctx, op, info, err); err != nil {
+			api.logger.Error().Err(err).Str("operation", op.ID).Msg("could not store the copy operation")
+		}
+	}()
+
+	base, _, _ := strings.Cut(r.URL.Path, "/drives/")
+	w.Header().Set("Location", base+"/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// GetOperation returns the status of a long-running operation of the current user
+func (api DrivesDriveItemApi) GetOperation(w http.ResponseWriter, r *http.Request) {
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not get user from context")
+		return
+	}
+
+	op, ok := api.operations.get(u.GetId().GetOpaqueId(), chi.URLParam(r, "operationID"))
+	if !ok {
+		ErrOperationNotFound.Render(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, op)
+}
+
+// moveDriveItem moves and/or renames a drive item outside of the share jail
+func (api DrivesDriveItemApi) moveDriveItem(w http.ResponseWriter, r *http.Request, driveID, itemID *storageprovider.ResourceId) {
+	requestDriveItem := libregraph.DriveItem{}
+	if err := StrictJSONUnmarshal(r.Body, &requestDriveItem); err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidRequestBody.Error())
+		ErrInvalidRequestBody.Render(w, r)
+		return
+	}
+
+	if requestDriveItem.ParentReference == nil && requestDriveItem.GetName() == "" {
+		api.logger.Debug().Msg(ErrNoUpdates.Error())
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "neither a name nor a parentReference was given")
+		return
+	}
+
+	parentID, err := parseParentReference(requestDriveItem.ParentReference)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidID.Error())
+		ErrInvalidID.Render(w, r)
+		return
+	}
+	if driveRef := requestDriveItem.GetParentReference().DriveId; driveRef != nil {
+		parentDriveID, err := storagespace.ParseID(*driveRef)
+		if err != nil || parentDriveID.GetStorageId() != driveID.GetStorageId() || parentDriveID.GetSpaceId() != driveID.GetSpaceId() {
+			ErrCrossDriveMove.Render(w, r)
+			return
+		}
+	}
+
+	conflictBehavior, err := ParseConflictBehavior(r.URL.Query().Get(_conflictBehaviorParam))
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidConflictBehavior.Error())
+		ErrInvalidConflictBehavior.Render(w, r)
+		return
+	}
+
+	info, err := api.drivesDriveItemService.MoveItem(r.Context(), itemID, parentID, requestDriveItem.GetName(), conflictBehavior)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg("moving drive item failed")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	driveItem, err := cs3ResourceToDriveItem(&api.logger, info)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrDriveItemConversion.Error())
+		ErrDriveItemConversion.Render(w, r)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, driveItem)
+}
+
+// parseParentReference returns the id of the parent reference, nil if it has none
+func parseParentReference(ref *libregraph.ItemReference) (*storageprovider.ResourceId, error) {
+	if ref.GetId() == "" {
+		return nil, nil
+	}
+	id, err := storagespace.ParseID(ref.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if id.GetOpaqueId() == "" {
+		id.OpaqueId = id.GetSpaceId()
+	}
+	return &id, nil
+}
+
+// resolveConflict returns the reference the item named name in the folder parentID should get
+// according to the conflict behavior and the item which exists there and has to be replaced.
+// Existing items are only replaced if they don't contain the source.
+func resolveConflict(ctx context.Context, gatewayClient gateway.GatewayAPIClient, parentID *storageprovider.ResourceId, name string, conflictBehavior ConflictBehavior, source *storageprovider.ResourceId) (*storageprovider.Reference, *storageprovider.ResourceInfo, error) {
+	for i := 0; i <= _maxRenameAttempts; i++ {
+		ref := &storageprovider.Reference{ResourceId: parentID, Path: utils.MakeRelativePath(numberedName(name, i))}
+		res, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{Ref: ref})
+		switch {
+		case err != nil:
+			return nil, nil, errorcode.FromCS3Status(nil, err)
+		case res.GetStatus().GetCode() == rpc.Code_CODE_NOT_FOUND:
+			return ref, nil, nil
+		case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+			return nil, nil, errorcode.FromCS3Status(res.GetStatus(), nil)
+		}
+
+		switch conflictBehavior {
+		case ConflictBehaviorReplace:
+			if res.GetInfo().GetType() == storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER || utils.ResourceIDEqual(res.GetInfo().GetId(), source) {
+				inside, err := isWithin(ctx, gatewayClient, source, res.GetInfo().GetId())
+				if err != nil {
+					return nil, nil, err
+				}
+				if inside {
+					return nil, nil, errorcode.New(errorcode.NameAlreadyExists, fmt.Sprintf("'%s' cannot be replaced, it contains the source", name))
+				}
+			}
+			return ref, res.GetInfo(), nil
+		case ConflictBehaviorRename:
+			continue
+		default:
+			return nil, nil, errorcode.New(errorcode.NameAlreadyExists, fmt.Sprintf("an item named '%s' already exists", name))
+		}
+	}
+	return nil, nil, errorcode.New(errorcode.NameAlreadyExists, fmt.Sprintf("could not find a free name for '%s'", name))
+}
+
+func deleteReference(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference) error {
+	res, err := gatewayClient.Delete(ctx, &storageprovider.DeleteRequest{Ref: ref})
+	return errorcode.FromCS3Status(res.GetStatus(), err)
+}
+
+// numberedName returns the n-th alternative of a name, "file.txt" becomes "file (n).txt"
+func numberedName(name string, n int) string {
+	if n == 0 {
+		return name
+	}
+	ext := path.Ext(name)
+	if ext == name {
+		ext = ""
+	}
+	return strings.TrimSuffix(name, ext) + " (" + strconv.Itoa(n) + ")" + ext
+}
+
+func isValidItemName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
+// isWithin checks if the item id is the folder or one of its descendants
+func isWithin(ctx context.Context, gatewayClient gateway.GatewayAPIClient, id, folder *storageprovider.ResourceId) (bool, error) {
+	if id.GetStorageId() != folder.GetStorageId() || id.GetSpaceId() != folder.GetSpaceId() {
+		return false, nil
+	}
+	for id != nil && id.GetOpaqueId() != "" {
+		if utils.ResourceIDEqual(id, folder) {
+			return true, nil
+		}
+		if id.GetOpaqueId() == id.GetSpaceId() {
+			return false, nil
+		}
+		info, err := statResourceID(ctx, gatewayClient, id)
+		if err != nil {
+			return false, err
+		}
+		id = info.GetParentId()
+	}
+	return false, nil
+}
+
+func statResourceID(ctx context.Context, gatewayClient gateway.GatewayAPIClient, id *storageprovider.ResourceId) (*storageprovider.ResourceInfo, error) {
+	return statReference(ctx, gatewayClient, &storageprovider.Reference{ResourceId: id})
+}
+
+func statReference(ctx context.Context, gatewayClient gateway.GatewayAPIClient, ref *storageprovider.Reference) (*storageprovider.ResourceInfo, error) {
+	res, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{Ref: ref})
+	if err := errorcode.FromStat(res, err); err != nil {
+		return nil, err
+	}
+	return res.GetInfo(), nil
+}
+
+// itemCopier copies a tree of items through the data gateway
+type itemCopier struct {
+	gatewayClient gateway.GatewayAPIClient
+	client        *http.Client
+	copied        atomic.Uint64
+	total         uint64
+	progress      CopyProgressFunc
+}
+
+func (c *itemCopier) copy(ctx context.Context, item *storageprovider.ResourceInfo, target *storageprovider.Reference) error {
+	if item.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER {
+		return c.copyFile(ctx, item, target)
+	}
+
+	cRes, err := c.gatewayClient.CreateContainer(ctx, &storageprovider.CreateContainerRequest{Ref: target})
+	if err := errorcode.FromCS3Status(cRes.GetStatus(), err); err != nil {
+		return err
+	}
+
+	lRes, err := c.gatewayClient.ListContainer(ctx, &storageprovider.ListContainerRequest{Ref: &storageprovider.Reference{ResourceId: item.GetId()}})
+	if err := errorcode.FromCS3Status(lRes.GetStatus(), err); err != nil {
+		return err
+	}
+	for _, child := range lRes.GetInfos() {
+		childTarget := &storageprovider.Reference{
+			ResourceId: target.GetResourceId(),
+			Path:       utils.MakeRelativePath(path.Join(target.GetPath(), child.GetName())),
+		}
+		if err := c.copy(ctx, child, childTarget); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replace copies the item next to the target under a temporary name and moves it over the
+// target when the copy is complete, the target is kept if the copy fails
+func (c *itemCopier) replace(ctx context.Context, item *storageprovider.ResourceInfo, target *storageprovider.Reference) error {
+	tmp := &storageprovider.Reference{
+		ResourceId: target.GetResourceId(),
+		Path:       utils.MakeRelativePath(path.Join(path.Dir(target.GetPath()), _replacePrefix+uuid.New().String())),
+	}
+	if err := c.copy(ctx, item, tmp); err != nil {
+		// remove what was copied so far
+		if dErr := deleteReference(ctx, c.gatewayClient, tmp); dErr != nil {
+			if e, ok := errorcode.ToError(dErr); !ok || e.GetCode() != errorcode.ItemNotFound {
+				return errors.Join(err, dErr)
+			}
+		}
+		return err
+	}
+	if err := deleteReference(ctx, c.gatewayClient, target); err != nil {
+		return err
+	}
+	res, err := c.gatewayClient.Move(ctx, &storageprovider.MoveRequest{Source: tmp, Destination: target})
+	return errorcode.FromCS3Status(res.GetStatus(), err)
+}
+
+func (c *itemCopier) copyFile(ctx context.Context, item *storageprovider.ResourceInfo, target *storageprovider.Reference) error {
+	dRes, err := c.gatewayClient.InitiateFileDownload(ctx, &storageprovider.InitiateFileDownloadRequest{Ref: &storageprovider.Reference{ResourceId: item.GetId()}})
+	if err := errorcode.FromCS3Status(dRes.GetStatus(), err); err != nil {
+		return err
+	}
+	var downloadEP, downloadToken string
+	for _, p := range dRes.GetProtocols() {
+		if p.GetProtocol() == "spaces" {
+			downloadEP, downloadToken = p.GetDownloadEndpoint(), p.GetToken()
+		}
+	}
+
+	uRes, err := c.gatewayClient.InitiateFileUpload(ctx, &storageprovider.InitiateFileUploadRequest{
+		Ref:    target,
+		Opaque: utils.AppendPlainToOpaque(nil, "Upload-Length", strconv.FormatUint(item.GetSize(), 10)),
+	})
+	if err := errorcode.FromCS3Status(uRes.GetStatus(), err); err != nil {
+		return err
+	}
+	var uploadEP, uploadToken string
+	for _, p := range uRes.GetProtocols() {
+		if p.GetProtocol() == "simple" {
+			uploadEP, uploadToken = p.GetUploadEndpoint(), p.GetToken()
+		}
+	}
+
+	httpDownloadReq, err := rhttp.NewRequest(ctx, http.MethodGet, downloadEP, nil)
+	if err != nil {
+		return err
+	}
+	if downloadToken != "" {
+		httpDownloadReq.Header.Set(TokenTransportHeader, downloadToken)
+	}
+	httpDownloadRes, err := c.client.Do(httpDownloadReq)
+	if err != nil {
+		return err
+	}
+	defer httpDownloadRes.Body.Close()
+	if httpDownloadRes.StatusCode != http.StatusOK {
+		return fmt.Errorf("wrong status downloading file: %d", httpDownloadRes.StatusCode)
+	}
+
+	httpUploadReq, err := rhttp.NewRequest(ctx, http.MethodPut, uploadEP, &progressReader{r: httpDownloadRes.Body, c: c})
+	if err != nil {
+		return err
+	}
+	httpUploadReq.ContentLength = int64(item.GetSize())
+	httpUploadReq.Header.Set(TokenTransportHeader, uploadToken)
+	httpUploadRes, err := c.client.Do(httpUploadReq)
+	if err != nil {
+		return err
+	}
+	defer httpUploadRes.Body.Close()
+	if httpUploadRes.StatusCode != http.StatusOK && httpUploadRes.StatusCode != http.StatusCreated && httpUploadRes.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("wrong status uploading file: %d", httpUploadRes.StatusCode)
+	}
+	return nil
+}
+
+// progressReader reports the bytes read from r to the copier
+type progressReader struct {
+	r io.Reader
+	c *itemCopier
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		copied := p.c.copied.Add(uint64(n))
+		if p.c.progress != nil {
+			p.c.progress(copied, p.c.total)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,511 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userprovider "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/tidwall/gjson"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+	svc "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("DrivesDriveItemService operations", func() {
+	var (
+		drivesDriveItemService svc.DrivesDriveItemService
+		gatewayClient          *cs3mocks.GatewayAPIClient
+
+		rootID   = &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "2"}
+		folderID = &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "folder"}
+		fileID   = &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "file"}
+		file     = &storageprovider.ResourceInfo{Id: fileID, ParentId: rootID, Name: "a.txt", Type: storageprovider.ResourceType_RESOURCE_TYPE_FILE, Size: 11}
+		folder   = &storageprovider.ResourceInfo{Id: folderID, ParentId: rootID, Name: "folder", Type: storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER}
+	)
+
+	BeforeEach(func() {
+		gatewayClient = cs3mocks.NewGatewayAPIClient(GinkgoT())
+		gatewaySelector := mocks.NewSelectable[gateway.GatewayAPIClient](GinkgoT())
+		gatewaySelector.EXPECT().Next().Return(gatewayClient, nil)
+
+		service, err := svc.NewDrivesDriveItemService(log.NewLogger(), gatewaySelector)
+		Expect(err).ToNot(HaveOccurred())
+		drivesDriveItemService = service
+	})
+
+	statID := func(info *storageprovider.ResourceInfo) {
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *storageprovider.StatRequest) bool {
+			return req.GetRef().GetPath() == "" && req.GetRef().GetResourceId().GetOpaqueId() == info.GetId().GetOpaqueId()
+		})).Return(&storageprovider.StatResponse{Status: status.NewOK(context.Background()), Info: info}, nil)
+	}
+	statPath := func(parentID *storageprovider.ResourceId, path string, info *storageprovider.ResourceInfo) {
+		res := &storageprovider.StatResponse{Status: status.NewNotFound(context.Background(), "not found")}
+		if info != nil {
+			res = &storageprovider.StatResponse{Status: status.NewOK(context.Background()), Info: info}
+		}
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *storageprovider.StatRequest) bool {
+			return req.GetRef().GetPath() == path && req.GetRef().GetResourceId().GetOpaqueId() == parentID.GetOpaqueId()
+		})).Return(res, nil)
+	}
+
+	Describe("MoveItem", func() {
+		It("moves an item into another folder", func() {
+			statID(file)
+			statPath(folderID, "./a.txt", nil)
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *storageprovider.MoveRequest) bool {
+				return req.GetSource().GetResourceId().GetOpaqueId() == "file" &&
+					req.GetDestination().GetResourceId().GetOpaqueId() == "folder" &&
+					req.GetDestination().GetPath() == "./a.txt"
+			})).Return(&storageprovider.MoveResponse{Status: status.NewOK(context.Background())}, nil).Once()
+
+			info, err := drivesDriveItemService.MoveItem(context.Background(), fileID, folderID, "", svc.ConflictBehaviorFail)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.GetId()).To(Equal(fileID))
+		})
+
+		It("fails if the target exists", func() {
+			statID(file)
+			statPath(rootID, "./b.txt", &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "b"}})
+
+			_, err := drivesDriveItemService.MoveItem(context.Background(), fileID, nil, "b.txt", svc.ConflictBehaviorFail)
+			e, ok := errorcode.ToError(err)
+			Expect(ok).To(BeTrue())
+			Expect(e.GetCode()).To(Equal(errorcode.NameAlreadyExists))
+		})
+
+		It("picks a free name if the target exists and the conflict behavior is rename", func() {
+			statID(file)
+			statPath(rootID, "./b.txt", &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "b"}})
+			statPath(rootID, "./b (1).txt", nil)
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *storageprovider.MoveRequest) bool {
+				return req.GetDestination().GetPath() == "./b (1).txt"
+			})).Return(&storageprovider.MoveResponse{Status: status.NewOK(context.Background())}, nil).Once()
+
+			_, err := drivesDriveItemService.MoveItem(context.Background(), fileID, nil, "b.txt", svc.ConflictBehaviorRename)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("does not replace folders containing the item", func() {
+			nested := &storageprovider.ResourceInfo{Id: fileID, ParentId: folderID, Name: "a.txt", Type: storageprovider.ResourceType_RESOURCE_TYPE_FILE}
+			statID(nested)
+			statPath(rootID, "./folder", folder)
+
+			_, err := drivesDriveItemService.MoveItem(context.Background(), fileID, rootID, "folder", svc.ConflictBehaviorReplace)
+			e, ok := errorcode.ToError(err)
+			Expect(ok).To(BeTrue())
+			Expect(e.GetCode()).To(Equal(errorcode.NameAlreadyExists))
+			gatewayClient.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.Anything)
+		})
+
+		It("rejects moves to other drives", func() {
+			statID(file)
+
+			_, err := drivesDriveItemService.MoveItem(context.Background(), fileID, &storageprovider.ResourceId{StorageId: "1", SpaceId: "3", OpaqueId: "3"}, "", svc.ConflictBehaviorFail)
+			Expect(err).To(MatchError(svc.ErrCrossDriveMove))
+		})
+	})
+
+	Describe("CopyItem", func() {
+		It("copies a file through the data gateway and reports the progress", func() {
+			var uploaded []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/download":
+					Expect(r.Header.Get(svc.TokenTransportHeader)).To(Equal("download-token"))
+					_, _ = w.Write([]byte("hello world"))
+				case "/upload":
+					Expect(r.Header.Get(svc.TokenTransportHeader)).To(Equal("upload-token"))
+					uploaded, _ = io.ReadAll(r.Body)
+					w.WriteHeader(http.StatusOK)
+				}
+			}))
+			defer server.Close()
+
+			statID(file)
+			statID(folder)
+			copyID := &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "copy"}
+			statTarget := mock.MatchedBy(func(req *storageprovider.StatRequest) bool {
+				return req.GetRef().GetPath() == "./a.txt"
+			})
+			gatewayClient.On("Stat", mock.Anything, statTarget).Return(&storageprovider.StatResponse{Status: status.NewNotFound(context.Background(), "not found")}, nil).Once()
+			gatewayClient.On("Stat", mock.Anything, statTarget).Return(&storageprovider.StatResponse{Status: status.NewOK(context.Background()), Info: &storageprovider.ResourceInfo{Id: copyID}}, nil).Once()
+			gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileDownloadResponse{
+				Status:    status.NewOK(context.Background()),
+				Protocols: []*gateway.FileDownloadProtocol{{Protocol: "spaces", DownloadEndpoint: server.URL + "/download", Token: "download-token"}},
+			}, nil)
+			gatewayClient.On("InitiateFileUpload", mock.Anything, mock.MatchedBy(func(req *storageprovider.InitiateFileUploadRequest) bool {
+				return req.GetRef().GetResourceId().GetOpaqueId() == "folder" && req.GetRef().GetPath() == "./a.txt"
+			})).Return(&gateway.InitiateFileUploadResponse{
+				Status:    status.NewOK(context.Background()),
+				Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: server.URL + "/upload", Token: "upload-token"}},
+			}, nil)
+
+			var copied, total uint64
+			info, err := drivesDriveItemService.CopyItem(context.Background(), fileID, folderID, "", svc.ConflictBehaviorFail, func(c, t uint64) {
+				copied, total = c, t
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.GetId()).To(Equal(copyID))
+			Expect(string(uploaded)).To(Equal("hello world"))
+			Expect(copied).To(Equal(uint64(11)))
+			Expect(total).To(Equal(uint64(11)))
+		})
+
+		It("uploads a new version of a replaced file", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/download" {
+					_, _ = w.Write([]byte("hello world"))
+				}
+			}))
+			defer server.Close()
+
+			existing := &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "existing"}, Name: "a.txt", Type: storageprovider.ResourceType_RESOURCE_TYPE_FILE}
+			statID(file)
+			statID(folder)
+			statPath(folderID, "./a.txt", existing)
+			gatewayClient.On("InitiateFileDownload", mock.Anything, mock.Anything).Return(&gateway.InitiateFileDownloadResponse{
+				Status:    status.NewOK(context.Background()),
+				Protocols: []*gateway.FileDownloadProtocol{{Protocol: "spaces", DownloadEndpoint: server.URL + "/download"}},
+			}, nil)
+			gatewayClient.On("InitiateFileUpload", mock.Anything, mock.MatchedBy(func(req *storageprovider.InitiateFileUploadRequest) bool {
+				return req.GetRef().GetPath() == "./a.txt"
+			})).Return(&gateway.InitiateFileUploadResponse{
+				Status:    status.NewOK(context.Background()),
+				Protocols: []*gateway.FileUploadProtocol{{Protocol: "simple", UploadEndpoint: server.URL + "/upload"}},
+			}, nil).Once()
+
+			info, err := drivesDriveItemService.CopyItem(context.Background(), fileID, folderID, "", svc.ConflictBehaviorReplace, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.GetId()).To(Equal(existing.GetId()))
+			gatewayClient.AssertNotCalled(GinkgoT(), "Delete", mock.Anything, mock.Anything)
+		})
+
+		It("replaces a folder after the copy", func() {
+			existing := &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "existing"}, Name: "folder", Type: storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER}
+			target := &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "target"}, Type: storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER}
+			statID(folder)
+			statID(target)
+			statPath(target.GetId(), "./folder", existing)
+			var calls []string
+			gatewayClient.On("CreateContainer", mock.Anything, mock.MatchedBy(func(req *storageprovider.CreateContainerRequest) bool {
+				return strings.HasPrefix(req.GetRef().GetPath(), "./.~copy-")
+			})).Run(func(mock.Arguments) { calls = append(calls, "create") }).
+				Return(&storageprovider.CreateContainerResponse{Status: status.NewOK(context.Background())}, nil).Once()
+			gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(&storageprovider.ListContainerResponse{Status: status.NewOK(context.Background())}, nil).Once()
+			gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *storageprovider.DeleteRequest) bool {
+				return req.GetRef().GetPath() == "./folder"
+			})).Run(func(mock.Arguments) { calls = append(calls, "delete") }).
+				Return(&storageprovider.DeleteResponse{Status: status.NewOK(context.Background())}, nil).Once()
+			gatewayClient.On("Move", mock.Anything, mock.MatchedBy(func(req *storageprovider.MoveRequest) bool {
+				return strings.HasPrefix(req.GetSource().GetPath(), "./.~copy-") && req.GetDestination().GetPath() == "./folder"
+			})).Run(func(mock.Arguments) { calls = append(calls, "move") }).
+				Return(&storageprovider.MoveResponse{Status: status.NewOK(context.Background())}, nil).Once()
+
+			_, err := drivesDriveItemService.CopyItem(context.Background(), folderID, target.GetId(), "", svc.ConflictBehaviorReplace, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(calls).To(Equal([]string{"create", "delete", "move"}))
+		})
+
+		It("keeps the replaced item if the copy fails", func() {
+			existing := &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "existing"}, Name: "folder", Type: storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER}
+			target := &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "target"}, Type: storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER}
+			statID(folder)
+			statID(target)
+			statPath(target.GetId(), "./folder", existing)
+			gatewayClient.On("CreateContainer", mock.Anything, mock.Anything).Return(&storageprovider.CreateContainerResponse{Status: status.NewInsufficientStorage(context.Background(), nil, "quota")}, nil).Once()
+			gatewayClient.On("Delete", mock.Anything, mock.MatchedBy(func(req *storageprovider.DeleteRequest) bool {
+				return strings.HasPrefix(req.GetRef().GetPath(), "./.~copy-")
+			})).Return(&storageprovider.DeleteResponse{Status: status.NewNotFound(context.Background(), "not found")}, nil).Once()
+
+			_, err := drivesDriveItemService.CopyItem(context.Background(), folderID, target.GetId(), "", svc.ConflictBehaviorReplace, nil)
+			Expect(err).To(HaveOccurred())
+			gatewayClient.AssertNotCalled(GinkgoT(), "Move", mock.Anything, mock.Anything)
+		})
+
+		It("does not copy a folder into itself", func() {
+			subfolder := &storageprovider.ResourceInfo{
+				Id:       &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "sub"},
+				ParentId: folderID,
+				Type:     storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER,
+			}
+			statID(folder)
+			statID(subfolder)
+
+			_, err := drivesDriveItemService.CopyItem(context.Background(), folderID, subfolder.GetId(), "", svc.ConflictBehaviorFail, nil)
+			Expect(err).To(MatchError(svc.ErrCopyIntoItself))
+		})
+
+		It("fails if the parent is not a folder", func() {
+			statID(folder)
+			statID(file)
+
+			_, err := drivesDriveItemService.CopyItem(context.Background(), folderID, fileID, "", svc.ConflictBehaviorFail, nil)
+			Expect(err).To(MatchError(svc.ErrParentNotAFolder))
+		})
+	})
+
+	Describe("ListVersions", func() {
+		It("lists the versions of the file", func() {
+			gatewayClient.On("ListFileVersions", mock.Anything, mock.Anything).Return(&storageprovider.ListFileVersionsResponse{
+				Status:   status.NewOK(context.Background()),
+				Versions: []*storageprovider.FileVersion{{Key: "v1"}},
+			}, nil)
+
+			versions, err := drivesDriveItemService.ListVersions(context.Background(), fileID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(versions).To(HaveLen(1))
+		})
+	})
+
+	Describe("RestoreVersion", func() {
+		It("maps the status of the gateway", func() {
+			gatewayClient.On("RestoreFileVersion", mock.Anything, mock.MatchedBy(func(req *storageprovider.RestoreFileVersionRequest) bool {
+				return req.GetKey() == "v1"
+			})).Return(&storageprovider.RestoreFileVersionResponse{Status: status.NewNotFound(context.Background(), "not found")}, nil)
+
+			err := drivesDriveItemService.RestoreVersion(context.Background(), fileID, "v1")
+			e, ok := errorcode.ToError(err)
+			Expect(ok).To(BeTrue())
+			Expect(e.GetCode()).To(Equal(errorcode.ItemNotFound))
+		})
+	})
+})
+
+var _ = Describe("DrivesDriveItemApi operations", func() {
+	var (
+		drivesDriveItemProvider *mocks.DrivesDriveItemProvider
+		drivesDriveItemApi      svc.DrivesDriveItemApi
+		operationStore          microstore.Store
+		rCTX                    *chi.Context
+		ctx                     context.Context
+	)
+
+	BeforeEach(func() {
+		drivesDriveItemProvider = mocks.NewDrivesDriveItemProvider(GinkgoT())
+		operationStore = microstore.NewMemoryStore()
+		api, err := svc.NewDrivesDriveItemApi(drivesDriveItemProvider, mocks.NewBaseGraphProvider(GinkgoT()), operationStore, log.NewLogger())
+		Expect(err).ToNot(HaveOccurred())
+		drivesDriveItemApi = api
+
+		rCTX = chi.NewRouteContext()
+		rCTX.URLParams.Add("driveID", "1$2")
+		rCTX.URLParams.Add("itemID", "1$2!3")
+		ctx = revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}})
+	})
+
+	request := func(method, target string, body interface{}) *http.Request {
+		var reader io.Reader
+		if body != nil {
+			b, err := json.Marshal(body)
+			Expect(err).ToNot(HaveOccurred())
+			reader = bytes.NewReader(b)
+		}
+		return httptest.NewRequest(method, target, reader).WithContext(context.WithValue(ctx, chi.RouteCtxKey, rCTX))
+	}
+	errorOf := func(w *httptest.ResponseRecorder) string {
+		jsonData := gjson.Get(w.Body.String(), "error")
+		return jsonData.Get("code").String() + ": " + jsonData.Get("message").String()
+	}
+
+	Describe("UpdateDriveItem", func() {
+		It("moves items outside of the share jail", func() {
+			drivesDriveItemProvider.EXPECT().
+				MoveItem(mock.Anything, &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "3"}, &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "4"}, "renamed.txt", svc.ConflictBehaviorRename).
+				Return(&storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "3"}, Name: "renamed.txt"}, nil).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.UpdateDriveItem(w, request(http.MethodPatch, "/?@microsoft.graph.conflictBehavior=rename", libregraph.DriveItem{
+				Name:            libregraph.PtrString("renamed.txt"),
+				ParentReference: &libregraph.ItemReference{DriveId: libregraph.PtrString("1$2"), Id: libregraph.PtrString("1$2!4")},
+			}))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(gjson.Get(w.Body.String(), "name").String()).To(Equal("renamed.txt"))
+		})
+
+		It("renders the errors of the move", func() {
+			drivesDriveItemProvider.EXPECT().
+				MoveItem(mock.Anything, mock.Anything, mock.Anything, mock.Anything, svc.ConflictBehaviorFail).
+				Return(nil, errorcode.New(errorcode.NameAlreadyExists, "exists")).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.UpdateDriveItem(w, request(http.MethodPatch, "/", libregraph.DriveItem{Name: libregraph.PtrString("b.txt")}))
+			Expect(w.Code).To(Equal(http.StatusConflict))
+		})
+
+		It("rejects moves to other drives", func() {
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.UpdateDriveItem(w, request(http.MethodPatch, "/", libregraph.DriveItem{
+				ParentReference: &libregraph.ItemReference{DriveId: libregraph.PtrString("1$5"), Id: libregraph.PtrString("1$5!6")},
+			}))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(errorOf(w)).To(Equal(svc.ErrCrossDriveMove.Error()))
+		})
+
+		It("rejects unknown conflict behaviors", func() {
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.UpdateDriveItem(w, request(http.MethodPatch, "/?@microsoft.graph.conflictBehavior=merge", libregraph.DriveItem{Name: libregraph.PtrString("b.txt")}))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(errorOf(w)).To(Equal(svc.ErrInvalidConflictBehavior.Error()))
+		})
+	})
+
+	Describe("CopyDriveItem", func() {
+		monitor := func(location string, c context.Context) (int, map[string]interface{}) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("operationID", location[len("/graph/v1beta1/operations/"):])
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.GetOperation(w, httptest.NewRequest(http.MethodGet, location, nil).WithContext(context.WithValue(c, chi.RouteCtxKey, rctx)))
+			res := map[string]interface{}{}
+			if w.Code == http.StatusOK {
+				Expect(json.Unmarshal(w.Body.Bytes(), &res)).To(Succeed())
+			}
+			return w.Code, res
+		}
+
+		It("copies in the background and returns a monitor url", func() {
+			var wg sync.WaitGroup
+			wg.Add(1)
+			drivesDriveItemProvider.EXPECT().
+				CopyItem(mock.Anything, &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "3"}, &storageprovider.ResourceId{StorageId: "1", SpaceId: "5", OpaqueId: "5"}, "", svc.ConflictBehaviorFail, mock.Anything).
+				RunAndReturn(func(_ context.Context, _, _ *storageprovider.ResourceId, _ string, _ svc.ConflictBehavior, progress svc.CopyProgressFunc) (*storageprovider.ResourceInfo, error) {
+					defer wg.Done()
+					progress(5, 10)
+					return &storageprovider.ResourceInfo{Id: &storageprovider.ResourceId{StorageId: "1", SpaceId: "5", OpaqueId: "copy"}}, nil
+				}).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.CopyDriveItem(w, request(http.MethodPost, "/graph/v1beta1/drives/1$2/items/1$2!3/copy", libregraph.DriveItem{
+				ParentReference: &libregraph.ItemReference{DriveId: libregraph.PtrString("1$5"), Id: libregraph.PtrString("1$5")},
+			}))
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+			location := w.Header().Get("Location")
+			Expect(location).To(HavePrefix("/graph/v1beta1/operations/"))
+
+			wg.Wait()
+			Eventually(func() interface{} {
+				_, res := monitor(location, ctx)
+				return res["status"]
+			}).Should(Equal(svc.OperationStatusCompleted))
+			_, res := monitor(location, ctx)
+			Expect(res["resourceId"]).To(Equal("1$5!copy"))
+			Expect(res["percentageComplete"]).To(Equal(float64(100)))
+
+			code, _ := monitor(location, revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "bob"}}))
+			Expect(code).To(Equal(http.StatusNotFound))
+
+			// the status is kept in the store, other graph instances report it as well
+			drivesDriveItemApi, _ = svc.NewDrivesDriveItemApi(drivesDriveItemProvider, mocks.NewBaseGraphProvider(GinkgoT()), operationStore, log.NewLogger())
+			_, res = monitor(location, ctx)
+			Expect(res["status"]).To(Equal(svc.OperationStatusCompleted))
+		})
+
+		It("limits the number of pending copies", func() {
+			release := make(chan struct{})
+			defer close(release)
+			drivesDriveItemProvider.EXPECT().
+				CopyItem(mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				RunAndReturn(func(context.Context, *storageprovider.ResourceId, *storageprovider.ResourceId, string, svc.ConflictBehavior, svc.CopyProgressFunc) (*storageprovider.ResourceInfo, error) {
+					<-release
+					return nil, errorcode.New(errorcode.GeneralException, "canceled")
+				}).
+				Maybe()
+
+			codes := map[int]int{}
+			for i := 0; i < 65; i++ {
+				w := httptest.NewRecorder()
+				drivesDriveItemApi.CopyDriveItem(w, request(http.MethodPost, "/graph/v1beta1/drives/1$2/items/1$2!3/copy", libregraph.DriveItem{}))
+				codes[w.Code]++
+			}
+			Expect(codes).To(Equal(map[int]int{http.StatusAccepted: 64, http.StatusTooManyRequests: 1}))
+		})
+
+		It("reports failed copies in the monitor", func() {
+			drivesDriveItemProvider.EXPECT().
+				CopyItem(mock.Anything, mock.Anything, mock.Anything, "b.txt", svc.ConflictBehaviorFail, mock.Anything).
+				Return(nil, errorcode.New(errorcode.NameAlreadyExists, "exists")).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.CopyDriveItem(w, request(http.MethodPost, "/graph/v1beta1/drives/1$2/items/1$2!3/copy", libregraph.DriveItem{Name: libregraph.PtrString("b.txt")}))
+			Expect(w.Code).To(Equal(http.StatusAccepted))
+
+			Eventually(func() interface{} {
+				_, res := monitor(w.Header().Get("Location"), ctx)
+				return res["status"]
+			}).Should(Equal(svc.OperationStatusFailed))
+			_, res := monitor(w.Header().Get("Location"), ctx)
+			Expect(gjson.Get(mustMarshal(res), "error.code").String()).To(Equal("nameAlreadyExists"))
+		})
+
+		It("does not copy from the share jail", func() {
+			rCTX.URLParams = chi.RouteParams{}
+			rCTX.URLParams.Add("driveID", "a0ca6a90-a365-4782-871e-d44447bbc668$a0ca6a90-a365-4782-871e-d44447bbc668")
+			rCTX.URLParams.Add("itemID", "a0ca6a90-a365-4782-871e-d44447bbc668$a0ca6a90-a365-4782-871e-d44447bbc668!1")
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.CopyDriveItem(w, request(http.MethodPost, "/", libregraph.DriveItem{}))
+			Expect(w.Code).To(Equal(http.StatusBadRequest))
+			Expect(errorOf(w)).To(Equal(svc.ErrShareJailNotSupported.Error()))
+		})
+	})
+
+	Describe("ListDriveItemVersions", func() {
+		It("lists the versions newest first", func() {
+			drivesDriveItemProvider.EXPECT().
+				ListVersions(mock.Anything, &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "3"}).
+				Return([]*storageprovider.FileVersion{
+					{Key: "old", Mtime: 1000, Size: 1},
+					{Key: "new", Mtime: 2000, Size: 2},
+				}, nil).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.ListDriveItemVersions(w, request(http.MethodGet, "/", nil))
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(gjson.Get(w.Body.String(), "value.#.id").String()).To(Equal(`["new","old"]`))
+			Expect(gjson.Get(w.Body.String(), "value.0.lastModifiedDateTime").String()).To(Equal("1970-01-01T00:33:20Z"))
+		})
+	})
+
+	Describe("RestoreDriveItemVersion", func() {
+		It("restores the version", func() {
+			rCTX.URLParams.Add("versionID", "v1")
+			drivesDriveItemProvider.EXPECT().
+				RestoreVersion(mock.Anything, &storageprovider.ResourceId{StorageId: "1", SpaceId: "2", OpaqueId: "3"}, "v1").
+				Return(nil).
+				Once()
+
+			w := httptest.NewRecorder()
+			drivesDriveItemApi.RestoreDriveItemVersion(w, request(http.MethodPost, "/", nil))
+			Expect(w.Code).To(Equal(http.StatusNoContent))
+		})
+	})
+})
+
+func mustMarshal(v interface{}) string {
+	b, err := json.Marshal(v)
+	Expect(err).ToNot(HaveOccurred())
+	return string(b)
+}
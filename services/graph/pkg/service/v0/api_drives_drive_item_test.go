@@ -17,6 +17,7 @@ import (
 	libregraph "github.com/owncloud/libre-graph-api-go"
 	"github.com/stretchr/testify/mock"
 	"github.com/tidwall/gjson"
+	microstore "go-micro.dev/v4/store"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
 
@@ -494,7 +495,7 @@ var _ = Describe("DrivesDriveItemApi", func() {
 		baseGraphProvider = mocks.NewBaseGraphProvider(GinkgoT())
 
 		drivesDriveItemProvider = mocks.NewDrivesDriveItemProvider(GinkgoT())
-		api, err := svc.NewDrivesDriveItemApi(drivesDriveItemProvider, baseGraphProvider, logger)
+		api, err := svc.NewDrivesDriveItemApi(drivesDriveItemProvider, baseGraphProvider, microstore.NewMemoryStore(), logger)
 		Expect(err).ToNot(HaveOccurred())
 
 		drivesDriveItemApi = api
@@ -604,8 +605,6 @@ var _ = Describe("DrivesDriveItemApi", func() {
 	Describe("UpdateDriveItem", func() {
 		failOnInvalidDriveIDOrItemID(drivesDriveItemApi.UpdateDriveItem)
 
-		failOnNonShareJailDriveID(drivesDriveItemApi.UpdateDriveItem)
-
 		failOninvalidDriveItemBody(drivesDriveItemApi.UpdateDriveItem)
 
 		It("fails if retrieving the share fails", func() {
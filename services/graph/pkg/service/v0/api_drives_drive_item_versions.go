@@ -0,0 +1,127 @@
+package svc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+)
+
+// ErrInvalidVersionID is returned when the version id is missing or invalid
+var ErrInvalidVersionID = errorcode.New(errorcode.InvalidRequest, "invalid version id")
+
+// DriveItemVersion is a previous version of a file
+type DriveItemVersion struct {
+	ID                   string    `json:"id"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	Size                 int64     `json:"size"`
+	ETag                 string    `json:"eTag,omitempty"`
+}
+
+// ListVersions lists the previous versions of a file
+func (s DrivesDriveItemService) ListVersions(ctx context.Context, itemID *storageprovider.ResourceId) ([]*storageprovider.FileVersion, error) {
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := gatewayClient.ListFileVersions(ctx, &storageprovider.ListFileVersionsRequest{
+		Ref: &storageprovider.Reference{ResourceId: itemID},
+	})
+	if err := errorcode.FromCS3Status(res.GetStatus(), err); err != nil {
+		return nil, err
+	}
+	return res.GetVersions(), nil
+}
+
+// RestoreVersion makes a previous version the current version of a file, the current version becomes a previous version
+func (s DrivesDriveItemService) RestoreVersion(ctx context.Context, itemID *storageprovider.ResourceId, key string) error {
+	gatewayClient, err := s.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+
+	res, err := gatewayClient.RestoreFileVersion(ctx, &storageprovider.RestoreFileVersionRequest{
+		Ref: &storageprovider.Reference{ResourceId: itemID},
+		Key: key,
+	})
+	return errorcode.FromCS3Status(res.GetStatus(), err)
+}
+
+// ListDriveItemVersions lists the previous versions of a drive item, the newest version first
+func (api DrivesDriveItemApi) ListDriveItemVersions(w http.ResponseWriter, r *http.Request) {
+	driveID, itemID, err := GetDriveAndItemIDParam(r, &api.logger)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidDriveIDOrItemID.Error())
+		ErrInvalidDriveIDOrItemID.Render(w, r)
+		return
+	}
+
+	if IsShareJail(driveID) {
+		api.logger.Debug().Interface("driveID", driveID).Msg(ErrShareJailNotSupported.Error())
+		ErrShareJailNotSupported.Render(w, r)
+		return
+	}
+
+	versions, err := api.drivesDriveItemService.ListVersions(r.Context(), itemID)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg("could not list versions")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	value := make([]DriveItemVersion, 0, len(versions))
+	for _, v := range versions {
+		value = append(value, DriveItemVersion{
+			ID:                   v.GetKey(),
+			LastModifiedDateTime: time.Unix(int64(v.GetMtime()), 0).UTC(),
+			Size:                 int64(v.GetSize()),
+			ETag:                 v.GetEtag(),
+		})
+	}
+	slices.SortStableFunc(value, func(a, b DriveItemVersion) int {
+		return b.LastModifiedDateTime.Compare(a.LastModifiedDateTime)
+	})
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &ListResponse{Value: value})
+}
+
+// RestoreDriveItemVersion restores a previous version of a drive item
+func (api DrivesDriveItemApi) RestoreDriveItemVersion(w http.ResponseWriter, r *http.Request) {
+	driveID, itemID, err := GetDriveAndItemIDParam(r, &api.logger)
+	if err != nil {
+		api.logger.Debug().Err(err).Msg(ErrInvalidDriveIDOrItemID.Error())
+		ErrInvalidDriveIDOrItemID.Render(w, r)
+		return
+	}
+
+	if IsShareJail(driveID) {
+		api.logger.Debug().Interface("driveID", driveID).Msg(ErrShareJailNotSupported.Error())
+		ErrShareJailNotSupported.Render(w, r)
+		return
+	}
+
+	versionID, err := url.PathUnescape(chi.URLParam(r, "versionID"))
+	if err != nil || versionID == "" {
+		api.logger.Debug().Err(err).Msg(ErrInvalidVersionID.Error())
+		ErrInvalidVersionID.Render(w, r)
+		return
+	}
+
+	if err := api.drivesDriveItemService.RestoreVersion(r.Context(), itemID, versionID); err != nil {
+		api.logger.Debug().Err(err).Msg("could not restore version")
+		errorcode.RenderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusNoContent)
+	render.NoContent(w, r)
+}
@@ -0,0 +1,241 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+)
+
+var _batchMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+const (
+	// _batchMaxRequests and _batchMaxConcurrency apply if the configured limits are not positive
+	_batchMaxRequests    = 20
+	_batchMaxConcurrency = 5
+)
+
+// BatchRequest combines several graph requests into one
+type BatchRequest struct {
+	Requests []BatchRequestItem `json:"requests"`
+}
+
+// BatchRequestItem is a single request of a batch, the URL is relative to the version of the batch endpoint
+type BatchRequestItem struct {
+	ID        string            `json:"id"`
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body,omitempty"`
+	DependsOn []string          `json:"dependsOn,omitempty"`
+}
+
+// BatchResponse contains the responses to the requests of a batch in the order of the requests
+type BatchResponse struct {
+	Responses []BatchResponseItem `json:"responses"`
+}
+
+// BatchResponseItem is the response to a single request of a batch. JSON bodies are embedded,
+// other bodies are base64 encoded.
+type BatchResponseItem struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Batch executes the requests of a batch with the identity of the caller. Requests run concurrently
+// unless they depend on other requests, which have to succeed before the depending request is executed.
+// At most the configured number of requests are executed at the same time.
+func (g Graph) Batch(w http.ResponseWriter, r *http.Request) {
+	batch := &BatchRequest{}
+	if err := StrictJSONUnmarshal(r.Body, batch); err != nil {
+		g.logger.Debug().Err(err).Msg("could not decode batch request")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid body schema definition")
+		return
+	}
+	if err := g.validateBatch(batch); err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	base := strings.TrimSuffix(r.URL.Path, "/$batch")
+	responses := make([]BatchResponseItem, len(batch.Requests))
+	done := make([]chan struct{}, len(batch.Requests))
+	index := make(map[string]int, len(batch.Requests))
+	for i, item := range batch.Requests {
+		index[item.ID] = i
+		done[i] = make(chan struct{})
+	}
+
+	maxConcurrency := g.config.Batch.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = _batchMaxConcurrency
+	}
+	sem := make(chan struct{}, maxConcurrency)
+
+	for i := range batch.Requests {
+		go func(i int) {
+			defer close(done[i])
+			item := batch.Requests[i]
+			for _, dep := range item.DependsOn {
+				j := index[dep]
+				<-done[j]
+				if responses[j].Status >= http.StatusBadRequest {
+					responses[i] = batchErrorResponse(r.Context(), item.ID, http.StatusFailedDependency, errorcode.PreconditionFailed, "the request '"+dep+"' this request depends on failed")
+					return
+				}
+			}
+			// acquire a slot only after the dependencies are done, so waiting requests don't block others
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			responses[i] = g.executeBatchRequest(r, base, item)
+		}(i)
+	}
+	for _, d := range done {
+		<-d
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &BatchResponse{Responses: responses})
+}
+
+func (g Graph) validateBatch(batch *BatchRequest) error {
+	maxRequests := g.config.Batch.MaxRequests
+	if maxRequests <= 0 {
+		maxRequests = _batchMaxRequests
+	}
+	switch {
+	case len(batch.Requests) == 0:
+		return errors.New("the batch does not contain any requests")
+	case len(batch.Requests) > maxRequests:
+		return fmt.Errorf("a batch must not contain more than %d requests", maxRequests)
+	}
+
+	ids := make(map[string]struct{}, len(batch.Requests))
+	for _, item := range batch.Requests {
+		if item.ID == "" {
+			return errors.New("every request of the batch needs an id")
+		}
+		if _, ok := ids[item.ID]; ok {
+			return fmt.Errorf("duplicate request id '%s'", item.ID)
+		}
+		for _, dep := range item.DependsOn {
+			if _, ok := ids[dep]; !ok {
+				return fmt.Errorf("request '%s' depends on '%s', which must be listed before it", item.ID, dep)
+			}
+		}
+		if !slices.Contains(_batchMethods, strings.ToUpper(item.Method)) {
+			return fmt.Errorf("invalid method '%s' in request '%s'", item.Method, item.ID)
+		}
+		u, err := url.Parse(item.URL)
+		if err != nil || item.URL == "" || u.IsAbs() || u.Host != "" {
+			return fmt.Errorf("the url of request '%s' must be relative to the batch endpoint", item.ID)
+		}
+		if path.Base(u.Path) == "$batch" {
+			return fmt.Errorf("request '%s' is a nested batch, which is not supported", item.ID)
+		}
+		ids[item.ID] = struct{}{}
+	}
+	return nil
+}
+
+// executeBatchRequest passes a request of a batch to the router, it gets the headers of the batch request
+// so the request is authenticated like the batch request itself
+func (g Graph) executeBatchRequest(r *http.Request, base string, item BatchRequestItem) BatchResponseItem {
+	var body io.Reader = http.NoBody
+	if len(item.Body) > 0 {
+		body = bytes.NewReader(item.Body)
+	}
+
+	// the router must not reuse the routing context of the batch request
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, nil)
+	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(item.Method), base+"/"+strings.TrimPrefix(item.URL, "/"), body)
+	if err != nil {
+		return batchErrorResponse(r.Context(), item.ID, http.StatusBadRequest, errorcode.InvalidRequest, err.Error())
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Type")
+	if len(item.Body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range item.Headers {
+		req.Header.Set(k, v)
+	}
+	req.RemoteAddr = r.RemoteAddr
+
+	rw := &batchResponseWriter{header: http.Header{}}
+	g.ServeHTTP(rw, req)
+
+	res := BatchResponseItem{ID: item.ID, Status: rw.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	for k := range rw.header {
+		if k != "Content-Length" {
+			if res.Headers == nil {
+				res.Headers = map[string]string{}
+			}
+			res.Headers[k] = rw.header.Get(k)
+		}
+	}
+
+	if b := rw.body.Bytes(); len(b) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(rw.header.Get("Content-Type"))
+		if mediaType == "application/json" && json.Valid(b) {
+			res.Body = b
+		} else {
+			// encoding a byte slice encodes it in base64
+			res.Body, _ = json.Marshal(b)
+		}
+	}
+	return res
+}
+
+func batchErrorResponse(ctx context.Context, id string, status int, code errorcode.ErrorCode, msg string) BatchResponseItem {
+	body, _ := json.Marshal(code.CreateOdataError(ctx, msg))
+	return BatchResponseItem{
+		ID:      id,
+		Status:  status,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	}
+}
+
+// batchResponseWriter records the response to a request of a batch
+type batchResponseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *batchResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *batchResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
@@ -0,0 +1,169 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	userprovider "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/services/graph/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config/defaults"
+	identitymocks "github.com/owncloud/ocis/v2/services/graph/pkg/identity/mocks"
+	service "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/unifiedrole"
+)
+
+var _ = Describe("Batch", func() {
+	var (
+		svc service.Service
+		cfg *config.Config
+		ctx context.Context
+	)
+
+	BeforeEach(func() {
+		cfg = defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.Batch.MaxRequests = 4
+
+		ctx = revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}})
+	})
+
+	JustBeforeEach(func() {
+		svc, _ = service.NewService(
+			service.Config(cfg),
+			service.EventsPublisher(&mocks.Publisher{}),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+		)
+	})
+
+	batch := func(requests ...map[string]interface{}) (*httptest.ResponseRecorder, *service.BatchResponse) {
+		b, err := json.Marshal(map[string]interface{}{"requests": requests})
+		Expect(err).ToNot(HaveOccurred())
+		r := httptest.NewRequest(http.MethodPost, "/graph/v1beta1/$batch", bytes.NewReader(b)).WithContext(ctx)
+		r.Header.Set("Accept-Language", "en")
+
+		rr := httptest.NewRecorder()
+		svc.ServeHTTP(rr, r)
+		res := &service.BatchResponse{}
+		if rr.Code == http.StatusOK {
+			Expect(json.Unmarshal(rr.Body.Bytes(), res)).To(Succeed())
+		}
+		return rr, res
+	}
+
+	It("executes the requests and returns their responses in order", func() {
+		rr, res := batch(
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions"},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "roleManagement/permissions/roleDefinitions/" + unifiedrole.UnifiedRoleViewerID},
+			map[string]interface{}{"id": "3", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions/unknown"},
+		)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(res.Responses).To(HaveLen(3))
+
+		Expect(res.Responses[0].ID).To(Equal("1"))
+		Expect(res.Responses[0].Status).To(Equal(http.StatusOK))
+		var roles []map[string]interface{}
+		Expect(json.Unmarshal(res.Responses[0].Body, &roles)).To(Succeed())
+		Expect(roles).ToNot(BeEmpty())
+
+		Expect(res.Responses[1].ID).To(Equal("2"))
+		Expect(res.Responses[1].Status).To(Equal(http.StatusOK))
+		Expect(res.Responses[1].Headers).To(HaveKeyWithValue("Content-Type", "application/json"))
+		var role map[string]interface{}
+		Expect(json.Unmarshal(res.Responses[1].Body, &role)).To(Succeed())
+		Expect(role["id"]).To(Equal(unifiedrole.UnifiedRoleViewerID))
+
+		Expect(res.Responses[2].ID).To(Equal("3"))
+		Expect(res.Responses[2].Status).To(Equal(http.StatusNotFound))
+	})
+
+	It("executes depending requests only if their dependencies succeeded", func() {
+		rr, res := batch(
+			map[string]interface{}{"id": "ok", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions"},
+			map[string]interface{}{"id": "failed", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions/unknown"},
+			map[string]interface{}{"id": "after-ok", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions", "dependsOn": []string{"ok"}},
+			map[string]interface{}{"id": "after-failed", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions", "dependsOn": []string{"ok", "failed"}},
+		)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(res.Responses[2].Status).To(Equal(http.StatusOK))
+		Expect(res.Responses[3].Status).To(Equal(http.StatusFailedDependency))
+	})
+
+	When("the requests are executed one at a time", func() {
+		BeforeEach(func() {
+			cfg.Batch.MaxConcurrency = 1
+		})
+
+		It("still executes depending requests", func() {
+			rr, res := batch(
+				map[string]interface{}{"id": "1", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions"},
+				map[string]interface{}{"id": "2", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions", "dependsOn": []string{"1"}},
+				map[string]interface{}{"id": "3", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions", "dependsOn": []string{"1", "2"}},
+				map[string]interface{}{"id": "4", "method": "GET", "url": "/roleManagement/permissions/roleDefinitions"},
+			)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(res.Responses).To(HaveLen(4))
+			for _, item := range res.Responses {
+				Expect(item.Status).To(Equal(http.StatusOK))
+			}
+		})
+	})
+
+	When("no maximum number of requests is configured", func() {
+		BeforeEach(func() {
+			cfg.Batch.MaxRequests = 0
+		})
+
+		It("rejects batches with more than 20 requests", func() {
+			requests := make([]map[string]interface{}, 21)
+			for i := range requests {
+				requests[i] = map[string]interface{}{"id": strconv.Itoa(i), "method": "GET", "url": "/roleManagement/permissions/roleDefinitions"}
+			}
+			rr, _ := batch(requests...)
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+
+			rr, res := batch(requests[:20]...)
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(res.Responses).To(HaveLen(20))
+		})
+	})
+
+	DescribeTable("rejects invalid batches",
+		func(requests ...map[string]interface{}) {
+			rr, _ := batch(requests...)
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		},
+		Entry("without requests"),
+		Entry("with too many requests",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/me"},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "/me"},
+			map[string]interface{}{"id": "3", "method": "GET", "url": "/me"},
+			map[string]interface{}{"id": "4", "method": "GET", "url": "/me"},
+			map[string]interface{}{"id": "5", "method": "GET", "url": "/me"},
+		),
+		Entry("without id", map[string]interface{}{"method": "GET", "url": "/me"}),
+		Entry("with duplicate ids",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/me"},
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/me"},
+		),
+		Entry("with dependencies on later requests",
+			map[string]interface{}{"id": "1", "method": "GET", "url": "/me", "dependsOn": []string{"2"}},
+			map[string]interface{}{"id": "2", "method": "GET", "url": "/me"},
+		),
+		Entry("with invalid methods", map[string]interface{}{"id": "1", "method": "OPTIONS", "url": "/me"}),
+		Entry("with absolute urls", map[string]interface{}{"id": "1", "method": "GET", "url": "https://example.com/graph/v1.0/me"}),
+		Entry("with nested batches", map[string]interface{}{"id": "1", "method": "POST", "url": "/$batch"}),
+	)
+})
@@ -0,0 +1,579 @@
+package svc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/render"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"go-micro.dev/v4/selector"
+
+	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+)
+
+const (
+	// _deltaTokenLatest requests a delta link for the current state without enumerating the items
+	_deltaTokenLatest = "latest"
+
+	// _activitylogService is the name the activitylog service registers its http api with
+	_activitylogService = "com.owncloud.web.activitylog"
+	// _activitiesEndpoint is the path of the activities api of the activitylog service
+	_activitiesEndpoint = "/graph/v1beta1/extensions/org.libregraph/activities"
+)
+
+var (
+	// errResyncRequired is returned if the changes since the last sync can't be determined completely
+	errResyncRequired = errors.New("resync required")
+
+	_itemTrashedEvent = reflect.TypeOf(events.ItemTrashed{}).String()
+	_itemMovedEvent   = reflect.TypeOf(events.ItemMoved{}).String()
+)
+
+// DeltaResponse is a page of the changes returned by a delta query. All pages but the last one
+// have a next link, the last page has the delta link for the next sync.
+type DeltaResponse struct {
+	Value     []*libregraph.DriveItem `json:"value"`
+	NextLink  string                  `json:"@odata.nextLink,omitempty"`
+	DeltaLink string                  `json:"@odata.deltaLink,omitempty"`
+}
+
+// deltaToken is the state of a sync, it is handed to clients as an opaque token
+type deltaToken struct {
+	// Since is the time of the previous sync in unix nanoseconds, only changes after it are returned
+	Since int64 `json:"s,omitempty"`
+	// Until is the time the current sync started in unix nanoseconds, it is set for the next pages of a sync
+	Until int64 `json:"u,omitempty"`
+	// Cursor is the key of the last item of the previous page
+	Cursor string `json:"c,omitempty"`
+}
+
+func (t deltaToken) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseDeltaToken(s string) (deltaToken, error) {
+	var t deltaToken
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(b, &t); err != nil {
+		return t, err
+	}
+	if t.Since < 0 || t.Until < 0 || (t.Until != 0 && t.Until < t.Since) {
+		return t, errors.New("invalid delta token")
+	}
+	return t, nil
+}
+
+// deltaEntry is a changed or deleted item, the entries of a sync are paged in the order of their keys.
+// Deleted items come first, the changed items follow in the order the folders are walked in.
+type deltaEntry struct {
+	key  string
+	item *libregraph.DriveItem
+}
+
+// GetDriveDelta returns the items of a drive which changed since the sync the delta token was issued for
+func (g Graph) GetDriveDelta(w http.ResponseWriter, r *http.Request) {
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	driveID.OpaqueId = driveID.GetSpaceId()
+
+	g.delta(w, r, &driveID)
+}
+
+// GetDriveItemDelta returns the items in a folder which changed since the sync the delta token was issued for
+func (g Graph) GetDriveItemDelta(w http.ResponseWriter, r *http.Request) {
+	driveID, err := parseIDParam(r, "driveID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	itemID, err := parseIDParam(r, "itemID")
+	if err != nil {
+		errorcode.RenderError(w, r, err)
+		return
+	}
+	if driveID.GetStorageId() != itemID.GetStorageId() || driveID.GetSpaceId() != itemID.GetSpaceId() {
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "item does not exist")
+		return
+	}
+
+	g.delta(w, r, &itemID)
+}
+
+// delta pages through the changes below a folder. The changed items are found by walking the folders
+// with a tree mtime newer than the last sync, moved and deleted items are found in the activities
+// the activitylog service recorded for the folder.
+func (g Graph) delta(w http.ResponseWriter, r *http.Request, rootID *storageprovider.ResourceId) {
+	ctx := r.Context()
+	if IsShareJail(rootID) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "delta queries on the shares drive are not supported, use the drive of the shared item")
+		return
+	}
+
+	now := time.Now()
+	var token deltaToken
+	switch t := r.URL.Query().Get("token"); t {
+	case "":
+	case _deltaTokenLatest:
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, &DeltaResponse{
+			Value:     []*libregraph.DriveItem{},
+			DeltaLink: g.deltaLink(r, deltaToken{Since: now.UnixNano()}),
+		})
+		return
+	default:
+		var err error
+		if token, err = parseDeltaToken(t); err != nil {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid delta token")
+			return
+		}
+	}
+	if maxAge := g.config.Delta.TokenMaxAge; token.Since != 0 && maxAge > 0 && now.Sub(time.Unix(0, token.Since)) > maxAge {
+		// the activities of the moved and deleted items might have expired already
+		errorcode.ResyncRequired.Render(w, r, http.StatusGone, "the delta token expired, start a new sync")
+		return
+	}
+
+	pageSize := g.config.Delta.MaxPageSize
+	if pageSize < 1 {
+		pageSize = math.MaxInt
+	}
+	if top := r.URL.Query().Get("$top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 1 {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid $top parameter")
+			return
+		}
+		pageSize = min(n, pageSize)
+	}
+
+	var since time.Time
+	if token.Since != 0 {
+		since = time.Unix(0, token.Since)
+	}
+	until := now
+	if token.Until != 0 {
+		until = time.Unix(0, token.Until)
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("could not select next gateway client")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not select next gateway client")
+		return
+	}
+
+	res, err := gatewayClient.Stat(ctx, &storageprovider.StatRequest{Ref: &storageprovider.Reference{ResourceId: rootID}})
+	switch {
+	case err != nil:
+		g.logger.Error().Err(err).Msg("could not stat delta root")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not stat item")
+		return
+	case res.GetStatus().GetCode() == cs3rpc.Code_CODE_NOT_FOUND || res.GetStatus().GetCode() == cs3rpc.Code_CODE_PERMISSION_DENIED:
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "item not found")
+		return
+	case res.GetStatus().GetCode() != cs3rpc.Code_CODE_OK:
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, res.GetStatus().GetMessage())
+		return
+	case res.GetInfo().GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER:
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "delta queries are only supported on folders")
+		return
+	}
+
+	d := &deltaCollector{
+		g:       g,
+		ctx:     ctx,
+		gwc:     gatewayClient,
+		token:   r.Header.Get(revactx.TokenHeader),
+		root:    res.GetInfo(),
+		since:   since,
+		until:   until,
+		cursor:  token.Cursor,
+		limit:   pageSize,
+		entries: map[string]deltaEntry{},
+	}
+	if err := d.walk(); err != nil {
+		g.logger.Error().Err(err).Msg("could not collect changed items")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not collect changed items")
+		return
+	}
+	switch err := d.activities(); {
+	case errors.Is(err, errResyncRequired):
+		errorcode.ResyncRequired.Render(w, r, http.StatusGone, "the changes since the last sync are not available, start a new sync")
+		return
+	case err != nil:
+		g.logger.Error().Err(err).Msg("could not collect moved and deleted items")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not collect moved and deleted items")
+		return
+	}
+
+	entries := make([]deltaEntry, 0, len(d.entries))
+	for _, e := range d.entries {
+		if e.key > token.Cursor {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	resp := &DeltaResponse{Value: make([]*libregraph.DriveItem, 0, min(len(entries), pageSize))}
+	for _, e := range entries[:min(len(entries), pageSize)] {
+		resp.Value = append(resp.Value, e.item)
+	}
+	if len(entries) > pageSize {
+		resp.NextLink = g.deltaLink(r, deltaToken{Since: token.Since, Until: until.UnixNano(), Cursor: entries[pageSize-1].key})
+	} else {
+		resp.DeltaLink = g.deltaLink(r, deltaToken{Since: until.UnixNano()})
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, resp)
+}
+
+// deltaLink returns the public url of the delta function with the given token
+func (g Graph) deltaLink(r *http.Request, token deltaToken) string {
+	u, err := url.Parse(g.config.Spaces.WebDavBase)
+	if err != nil {
+		u = &url.URL{}
+	}
+	u.Path = r.URL.Path
+	q := url.Values{}
+	q.Set("token", token.encode())
+	if top := r.URL.Query().Get("$top"); top != "" {
+		q.Set("$top", top)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// deltaCollector collects the changes below the root of a delta query, the entries are mapped by item id
+type deltaCollector struct {
+	g       Graph
+	ctx     context.Context
+	gwc     gateway.GatewayAPIClient
+	token   string
+	root    *storageprovider.ResourceInfo
+	since   time.Time
+	until   time.Time
+	entries map[string]deltaEntry
+
+	// cursor is the key of the last item of the previous page, limit is the size of a page
+	cursor string
+	limit  int
+	// found is the number of changed items after the cursor the walk added
+	found int
+
+	rootPath string
+}
+
+// deltaKey returns the key of an item by its path relative to the root. The separators are replaced by
+// a character sorting before all others, so the keys of the items in a folder sort after the folder
+// and before its next sibling.
+func deltaKey(p string) string {
+	return strings.ReplaceAll(p, "/", "\x01")
+}
+
+// changed returns true if the mtime of an item is in the time frame of the sync
+func (d *deltaCollector) changed(info *storageprovider.ResourceInfo) bool {
+	mtime := cs3TimestampToTime(info.GetMtime())
+	return mtime.After(d.since) && !mtime.After(d.until)
+}
+
+// paged returns true if all items below the folder with the given key were on previous pages
+func (d *deltaCollector) paged(key string) bool {
+	prefix := strings.TrimSuffix(key, "\x01") + "\x01"
+	return d.cursor > prefix && !strings.HasPrefix(d.cursor, prefix)
+}
+
+// add adds a changed item by its path relative to the root
+func (d *deltaCollector) add(p string, info *storageprovider.ResourceInfo) error {
+	item, err := cs3ResourceToDriveItem(d.g.logger, info)
+	if err != nil {
+		return err
+	}
+	d.entries[storagespace.FormatResourceID(info.GetId())] = deltaEntry{key: deltaKey(p), item: item}
+	return nil
+}
+
+// walk adds the changed items after the cursor. The folders are walked in the order of the keys, the
+// walk stops once it found more items than fit on the page, so a page doesn't list the folders of the
+// previous or the next pages. The storage propagates the mtime to the parents of changed items, so only
+// folders with a newer mtime than the last sync need to be listed.
+func (d *deltaCollector) walk() error {
+	_, err := d.walkItem("/", d.root)
+	return err
+}
+
+// walkItem adds an item if it changed and walks its children, it returns false once the page is full
+func (d *deltaCollector) walkItem(p string, info *storageprovider.ResourceInfo) (bool, error) {
+	key := deltaKey(p)
+	if key > d.cursor && d.changed(info) {
+		if err := d.add(p, info); err != nil {
+			return false, err
+		}
+		d.found++
+		if d.found > d.limit {
+			return false, nil
+		}
+	}
+	if info.GetType() != storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER || !cs3TimestampToTime(info.GetMtime()).After(d.since) || d.paged(key) {
+		return true, nil
+	}
+
+	res, err := d.gwc.ListContainer(d.ctx, &storageprovider.ListContainerRequest{Ref: &storageprovider.Reference{ResourceId: info.GetId()}})
+	switch {
+	case err != nil:
+		return false, err
+	case res.GetStatus().GetCode() == cs3rpc.Code_CODE_NOT_FOUND:
+		// the folder was deleted or moved while walking
+		return true, nil
+	case res.GetStatus().GetCode() != cs3rpc.Code_CODE_OK:
+		return false, errors.New("could not list folder: " + res.GetStatus().GetMessage())
+	}
+
+	children := res.GetInfos()
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].GetName() < children[j].GetName()
+	})
+	for _, child := range children {
+		more, err := d.walkItem(path.Join(p, child.GetName()), child)
+		if err != nil || !more {
+			return more, err
+		}
+	}
+	return true, nil
+}
+
+// activities adds the moved and deleted items. Moving an item doesn't change its mtime and deleted
+// items are gone from the tree, both are taken from the activities the activitylog service recorded
+// for the root of the query.
+func (d *deltaCollector) activities() error {
+	if d.since.IsZero() || d.g.serviceSelector == nil || d.g.historyClient == nil {
+		// the first sync returns all items anyway
+		return nil
+	}
+
+	ids, err := d.listActivities()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	res, err := d.g.historyClient.GetEvents(d.ctx, &ehsvc.GetEventsRequest{Ids: ids})
+	if err != nil {
+		return err
+	}
+	for _, e := range res.GetEvents() {
+		switch e.GetType() {
+		case _itemTrashedEvent:
+			var ev events.ItemTrashed
+			if err := json.Unmarshal(e.GetEvent(), &ev); err != nil {
+				d.g.logger.Error().Err(err).Str("eventid", e.GetId()).Msg("could not unmarshal event")
+				continue
+			}
+			if err := d.trashed(ev); err != nil {
+				return err
+			}
+		case _itemMovedEvent:
+			var ev events.ItemMoved
+			if err := json.Unmarshal(e.GetEvent(), &ev); err != nil {
+				d.g.logger.Error().Err(err).Str("eventid", e.GetId()).Msg("could not unmarshal event")
+				continue
+			}
+			if _, err := d.current(ev.Ref); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listActivities returns the event ids of the activities below the root in the time frame of the sync.
+// It asks the activitylog service on behalf of the user, who only gets the activities of items they can
+// list the grants of. The changes can't be determined completely if the user can't list the activities
+// or if there are more activities than the activitylog service keeps, errResyncRequired is returned then.
+func (d *deltaCollector) listActivities() ([]string, error) {
+	next, err := d.g.serviceSelector.Select(_activitylogService)
+	switch {
+	case errors.Is(err, selector.ErrNotFound), errors.Is(err, selector.ErrNoneAvailable):
+		d.g.logger.Debug().Msg("the activitylog service is not running, the moved and deleted items are unknown")
+		return nil, errResyncRequired
+	case err != nil:
+		return nil, err
+	}
+	node, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	u := url.URL{Scheme: node.Metadata["protocol"], Host: node.Address, Path: _activitiesEndpoint}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+	}
+	if node.Metadata["use_tls"] == "true" {
+		u.Scheme = "https"
+	}
+	kql := fmt.Sprintf(`itemid:"%s" AND date>"%s" AND date<"%s"`,
+		storagespace.FormatResourceID(d.root.GetId()), d.since.Format(time.RFC3339Nano), d.until.Format(time.RFC3339Nano))
+	maxActivities := d.g.config.Delta.MaxActivities
+	if maxActivities > 0 {
+		kql += fmt.Sprintf(" AND limit:%d", maxActivities+1)
+	}
+	u.RawQuery = url.Values{"kql": []string{kql}}.Encode()
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(revactx.TokenHeader, d.token)
+
+	res, err := d.g.activitiesClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		d.g.logger.Debug().Str("item", storagespace.FormatResourceID(d.root.GetId())).Msg("not allowed to list the activities, the moved and deleted items are unknown")
+		return nil, errResyncRequired
+	default:
+		return nil, fmt.Errorf("could not list activities: unexpected status %d", res.StatusCode)
+	}
+
+	var activities struct {
+		Value []libregraph.Activity `json:"value"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&activities); err != nil {
+		return nil, err
+	}
+	if maxActivities > 0 && len(activities.Value) > maxActivities {
+		// the oldest activities of the folder might have been dropped already
+		return nil, errResyncRequired
+	}
+	ids := make([]string, 0, len(activities.Value))
+	for _, a := range activities.Value {
+		ids = append(ids, a.GetId())
+	}
+	return ids, nil
+}
+
+// trashed adds a deleted item unless it has been restored since
+func (d *deltaCollector) trashed(ev events.ItemTrashed) error {
+	if ev.ID == nil {
+		return nil
+	}
+	id := storagespace.FormatResourceID(ev.ID)
+	if _, ok := d.entries[id]; ok {
+		return nil
+	}
+	found, err := d.current(&storageprovider.Reference{ResourceId: ev.ID})
+	if err != nil || found {
+		return err
+	}
+
+	d.deleted(ev.ID, path.Base(ev.Ref.GetPath()))
+	return nil
+}
+
+// deleted adds an item which was deleted or moved out of the root
+func (d *deltaCollector) deleted(id *storageprovider.ResourceId, name string) {
+	rid := storagespace.FormatResourceID(id)
+	d.entries[rid] = deltaEntry{
+		// deleted items are returned before the changed items
+		key: "\x00" + rid,
+		item: &libregraph.DriveItem{
+			Id:      libregraph.PtrString(rid),
+			Name:    libregraph.PtrString(name),
+			Deleted: &libregraph.Deleted{State: libregraph.PtrString("deleted")},
+		},
+	}
+}
+
+// current adds an item with its current location if it still is below the root or as deleted if it
+// was moved out of the root, it returns false if the item does not exist anymore or can't be accessed
+func (d *deltaCollector) current(ref *storageprovider.Reference) (bool, error) {
+	res, err := d.gwc.Stat(d.ctx, &storageprovider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return false, err
+	case res.GetStatus().GetCode() == cs3rpc.Code_CODE_NOT_FOUND || res.GetStatus().GetCode() == cs3rpc.Code_CODE_PERMISSION_DENIED:
+		return false, nil
+	case res.GetStatus().GetCode() != cs3rpc.Code_CODE_OK:
+		return false, errors.New("could not stat item: " + res.GetStatus().GetMessage())
+	}
+	info := res.GetInfo()
+	if _, ok := d.entries[storagespace.FormatResourceID(info.GetId())]; ok {
+		return true, nil
+	}
+
+	rootPath, err := d.path(d.root.GetId())
+	if err != nil {
+		return false, err
+	}
+	itemPath, err := d.path(info.GetId())
+	if err != nil {
+		return false, err
+	}
+	if rootPath == "" || itemPath == "" || (rootPath != "/" && itemPath != rootPath && !strings.HasPrefix(itemPath, rootPath+"/")) {
+		// the item was moved out of the root of the query
+		d.deleted(info.GetId(), info.GetName())
+		return true, nil
+	}
+
+	return true, d.add(path.Join("/", strings.TrimPrefix(itemPath, rootPath)), info)
+}
+
+// path returns the path of a resource in its space or an empty string if the resource does not exist
+func (d *deltaCollector) path(id *storageprovider.ResourceId) (string, error) {
+	isRoot := id.GetOpaqueId() == d.root.GetId().GetOpaqueId()
+	switch {
+	case id.GetOpaqueId() == id.GetSpaceId():
+		return "/", nil
+	case isRoot && d.rootPath != "":
+		return d.rootPath, nil
+	}
+
+	res, err := d.gwc.GetPath(d.ctx, &storageprovider.GetPathRequest{ResourceId: id})
+	if err != nil {
+		return "", err
+	}
+	switch res.GetStatus().GetCode() {
+	case cs3rpc.Code_CODE_OK:
+	case cs3rpc.Code_CODE_NOT_FOUND, cs3rpc.Code_CODE_PERMISSION_DENIED:
+		return "", nil
+	default:
+		return "", errors.New("could not get path: " + res.GetStatus().GetMessage())
+	}
+
+	p := path.Join("/", res.GetPath())
+	if isRoot {
+		d.rootPath = p
+	}
+	return p, nil
+}
@@ -0,0 +1,360 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userprovider "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	"go-micro.dev/v4/registry"
+	"go-micro.dev/v4/selector"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	ehmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/eventhistory/v0"
+	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
+	ehmocks "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config/defaults"
+	identitymocks "github.com/owncloud/ocis/v2/services/graph/pkg/identity/mocks"
+	service "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("Delta", func() {
+	var (
+		svc           service.Service
+		ctx           context.Context
+		gatewayClient *cs3mocks.GatewayAPIClient
+		historyClient *ehmocks.EventHistoryService
+		activities     []libregraph.Activity
+		activityStatus int
+		activityQuery  url.Values
+		activityToken  string
+
+		rootID   = &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "space"}
+		folderID = &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "folder"}
+	)
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"com.owncloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		historyClient = &ehmocks.EventHistoryService{}
+
+		activities, activityStatus, activityQuery, activityToken = nil, http.StatusOK, nil, ""
+		activitylog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/graph/v1beta1/extensions/org.libregraph/activities"))
+			activityQuery, activityToken = r.URL.Query(), r.Header.Get(revactx.TokenHeader)
+			if activityStatus != http.StatusOK {
+				w.WriteHeader(activityStatus)
+				return
+			}
+			Expect(json.NewEncoder(w).Encode(map[string]interface{}{"value": activities})).To(Succeed())
+		}))
+		DeferCleanup(activitylog.Close)
+		reg := registry.NewMemoryRegistry()
+		Expect(reg.Register(&registry.Service{
+			Name:  "com.owncloud.web.activitylog",
+			Nodes: []*registry.Node{{Id: "activitylog", Address: strings.TrimPrefix(activitylog.URL, "http://"), Metadata: map[string]string{"protocol": "http"}}},
+		})).To(Succeed())
+
+		cfg := defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.Spaces.WebDavBase = "https://localhost:9200"
+		cfg.Delta.MaxActivities = 2
+
+		svc, _ = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.EventsPublisher(&mocks.Publisher{}),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.EventHistoryClient(historyClient),
+			service.WithServiceSelector(selector.NewSelector(selector.Registry(reg))),
+		)
+
+		ctx = revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}})
+	})
+
+	statItem := func(info *provider.ResourceInfo) {
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == info.GetId().GetOpaqueId()
+		})).Return(&provider.StatResponse{Status: status.NewOK(ctx), Info: info}, nil)
+	}
+	listFolder := func(id *provider.ResourceId, infos ...*provider.ResourceInfo) {
+		gatewayClient.On("ListContainer", mock.Anything, mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == id.GetOpaqueId()
+		})).Return(&provider.ListContainerResponse{Status: status.NewOK(ctx), Infos: infos}, nil)
+	}
+	item := func(id, name string, typ provider.ResourceType, mtime time.Time) *provider.ResourceInfo {
+		return &provider.ResourceInfo{
+			Id:    &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: id},
+			Name:  name,
+			Type:  typ,
+			Mtime: utils.TimeToTS(mtime),
+		}
+	}
+
+	itemDelta := func(itemID, query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		target := "/graph/v1beta1/drives/storage$space/root/delta"
+		if itemID != "" {
+			target = "/graph/v1beta1/drives/storage$space/items/" + itemID + "/delta"
+		}
+		r := httptest.NewRequest(http.MethodGet, target+query, nil)
+		r.Header.Set(revactx.TokenHeader, "token")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("driveID", "storage$space")
+		r = r.WithContext(context.WithValue(revactx.ContextSetUser(ctx, &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}}), chi.RouteCtxKey, rctx))
+
+		rr := httptest.NewRecorder()
+		if itemID != "" {
+			rctx.URLParams.Add("itemID", itemID)
+			svc.GetDriveItemDelta(rr, r)
+		} else {
+			svc.GetDriveDelta(rr, r)
+		}
+		res := map[string]interface{}{}
+		if rr.Code == http.StatusOK {
+			Expect(json.Unmarshal(rr.Body.Bytes(), &res)).To(Succeed())
+		}
+		return rr, res
+	}
+	delta := func(query string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		return itemDelta("", query)
+	}
+	names := func(res map[string]interface{}) []string {
+		var n []string
+		for _, v := range res["value"].([]interface{}) {
+			n = append(n, v.(map[string]interface{})["name"].(string))
+		}
+		return n
+	}
+	tokenOf := func(link interface{}) string {
+		u, err := url.Parse(link.(string))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(u.Host).To(Equal("localhost:9200"))
+		Expect(u.Path).To(Equal("/graph/v1beta1/drives/storage$space/root/delta"))
+		return u.Query().Get("token")
+	}
+
+	It("returns all items on the first sync and pages them", func() {
+		old := time.Now().Add(-time.Hour)
+		root := item("space", "space", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old)
+		root.Id = rootID
+		statItem(root)
+		listFolder(rootID,
+			item("file", "a.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old),
+			item("folder", "b", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old),
+		)
+		listFolder(folderID, item("nested", "c.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old))
+
+		rr, res := delta("?$top=2")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"space", "a.txt"}))
+		Expect(res).ToNot(HaveKey("@odata.deltaLink"))
+		Expect(res).To(HaveKey("@odata.nextLink"))
+
+		rr, res = delta("?$top=2&token=" + tokenOf(res["@odata.nextLink"]))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"b", "c.txt"}))
+		Expect(res).ToNot(HaveKey("@odata.nextLink"))
+		Expect(res).To(HaveKey("@odata.deltaLink"))
+	})
+
+	It("only lists the folders of the requested page", func() {
+		old := time.Now().Add(-time.Hour)
+		root := item("space", "space", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old)
+		root.Id = rootID
+		statItem(root)
+
+		listed := map[string]int{}
+		tree := map[string][]*provider.ResourceInfo{
+			"space": {
+				item("c", "c", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old),
+				item("a", "a", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old),
+				item("b", "b", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old),
+			},
+			"a": {item("x", "x.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old)},
+			"b": {item("y", "y.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old)},
+			"c": {item("z", "z.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old)},
+		}
+		gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.ListContainerRequest, _ ...grpc.CallOption) (*provider.ListContainerResponse, error) {
+			id := req.GetRef().GetResourceId().GetOpaqueId()
+			listed[id]++
+			return &provider.ListContainerResponse{Status: status.NewOK(ctx), Infos: tree[id]}, nil
+		})
+
+		rr, res := delta("?$top=2")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"space", "a"}))
+		Expect(listed).To(Equal(map[string]int{"space": 1, "a": 1}))
+
+		rr, res = delta("?$top=2&token=" + tokenOf(res["@odata.nextLink"]))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"x.txt", "b"}))
+		Expect(listed).To(Equal(map[string]int{"space": 2, "a": 2, "b": 1}))
+
+		rr, res = delta("?$top=2&token=" + tokenOf(res["@odata.nextLink"]))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"y.txt", "c"}))
+		Expect(listed).To(Equal(map[string]int{"space": 3, "a": 2, "b": 2, "c": 1}))
+
+		rr, res = delta("?$top=2&token=" + tokenOf(res["@odata.nextLink"]))
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"z.txt"}))
+		Expect(res).To(HaveKey("@odata.deltaLink"))
+		Expect(listed).To(Equal(map[string]int{"space": 4, "a": 2, "b": 2, "c": 2}))
+	})
+
+	It("returns a delta link without items for the latest token", func() {
+		rr, res := delta("?token=latest")
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(res["value"]).To(BeEmpty())
+		Expect(tokenOf(res["@odata.deltaLink"])).ToNot(BeEmpty())
+	})
+
+	It("returns the changed and deleted items since the last sync", func() {
+		_, res := delta("?token=latest")
+		token := tokenOf(res["@odata.deltaLink"])
+
+		old := time.Now().Add(-time.Hour)
+		time.Sleep(time.Millisecond)
+		changed := time.Now()
+		time.Sleep(time.Millisecond)
+
+		root := item("space", "space", provider.ResourceType_RESOURCE_TYPE_CONTAINER, changed)
+		root.Id = rootID
+		statItem(root)
+		listFolder(rootID,
+			item("file", "a.txt", provider.ResourceType_RESOURCE_TYPE_FILE, old),
+			item("folder", "b", provider.ResourceType_RESOURCE_TYPE_CONTAINER, changed),
+			item("unchanged", "d", provider.ResourceType_RESOURCE_TYPE_CONTAINER, old),
+		)
+		listFolder(folderID, item("nested", "c.txt", provider.ResourceType_RESOURCE_TYPE_FILE, changed))
+
+		trashed := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "trashed"}
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == "trashed"
+		})).Return(&provider.StatResponse{Status: status.NewNotFound(ctx, "not found")}, nil)
+
+		activities = []libregraph.Activity{{Id: "trashed"}}
+
+		ev, err := json.Marshal(events.ItemTrashed{ID: trashed, Ref: &provider.Reference{ResourceId: rootID, Path: "./gone.txt"}})
+		Expect(err).ToNot(HaveOccurred())
+		historyClient.EXPECT().GetEvents(mock.Anything, &ehsvc.GetEventsRequest{Ids: []string{"trashed"}}).Return(&ehsvc.GetEventsResponse{
+			Events: []*ehmsg.Event{{Id: "trashed", Type: "events.ItemTrashed", Event: ev}},
+		}, nil)
+
+		rr, res := delta("?token=" + token)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"gone.txt", "space", "b", "c.txt"}))
+		Expect(activityToken).To(Equal("token"))
+		Expect(activityQuery.Get("kql")).To(HavePrefix(`itemid:"storage$space!space" AND date>`))
+		Expect(activityQuery.Get("kql")).To(HaveSuffix(` AND limit:3`))
+		deleted := res["value"].([]interface{})[0].(map[string]interface{})
+		Expect(deleted["id"]).To(Equal("storage$space!trashed"))
+		Expect(deleted["deleted"]).To(Equal(map[string]interface{}{"state": "deleted"}))
+		gatewayClient.AssertNotCalled(GinkgoT(), "ListContainer", mock.Anything, mock.MatchedBy(func(req *provider.ListContainerRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == "unchanged"
+		}))
+	})
+
+	It("returns the items moved out of the folder as deleted", func() {
+		_, res := delta("?token=latest")
+		token := tokenOf(res["@odata.deltaLink"])
+
+		folder := item("folder", "b", provider.ResourceType_RESOURCE_TYPE_CONTAINER, time.Now().Add(-time.Hour))
+		statItem(folder)
+		statItem(item("moved", "e.txt", provider.ResourceType_RESOURCE_TYPE_FILE, time.Now().Add(-time.Hour)))
+		gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.GetPathRequest, _ ...grpc.CallOption) (*provider.GetPathResponse, error) {
+			p := map[string]string{"folder": "/b", "moved": "/elsewhere/e.txt"}[req.GetResourceId().GetOpaqueId()]
+			return &provider.GetPathResponse{Status: status.NewOK(ctx), Path: p}, nil
+		})
+
+		activities = []libregraph.Activity{{Id: "moved"}}
+		movedID := &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "moved"}
+		ev, err := json.Marshal(events.ItemMoved{
+			Ref:          &provider.Reference{ResourceId: movedID},
+			OldReference: &provider.Reference{ResourceId: folderID, Path: "./e.txt"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		historyClient.EXPECT().GetEvents(mock.Anything, &ehsvc.GetEventsRequest{Ids: []string{"moved"}}).Return(&ehsvc.GetEventsResponse{
+			Events: []*ehmsg.Event{{Id: "moved", Type: "events.ItemMoved", Event: ev}},
+		}, nil)
+
+		rr, res := itemDelta("storage$space!folder", "?token="+token)
+		Expect(rr.Code).To(Equal(http.StatusOK))
+		Expect(names(res)).To(Equal([]string{"e.txt"}))
+		deleted := res["value"].([]interface{})[0].(map[string]interface{})
+		Expect(deleted["id"]).To(Equal("storage$space!moved"))
+		Expect(deleted["deleted"]).To(Equal(map[string]interface{}{"state": "deleted"}))
+	})
+
+	It("requires a resync if the changes can't be determined completely", func() {
+		_, res := delta("?token=latest")
+		token := tokenOf(res["@odata.deltaLink"])
+
+		root := item("space", "space", provider.ResourceType_RESOURCE_TYPE_CONTAINER, time.Now().Add(-time.Hour))
+		root.Id = rootID
+		statItem(root)
+
+		activityStatus = http.StatusForbidden
+		rr, _ := delta("?token=" + token)
+		Expect(rr.Code).To(Equal(http.StatusGone))
+		Expect(rr.Body.String()).To(ContainSubstring("resyncRequired"))
+
+		activityStatus = http.StatusOK
+		activities = []libregraph.Activity{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+		rr, _ = delta("?token=" + token)
+		Expect(rr.Code).To(Equal(http.StatusGone))
+		historyClient.AssertNotCalled(GinkgoT(), "GetEvents", mock.Anything, mock.Anything)
+	})
+
+	It("requires a resync for expired tokens", func() {
+		expired := time.Now().Add(-defaults.FullDefaultConfig().Delta.TokenMaxAge - time.Hour).UnixNano()
+		token := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"s":%d}`, expired)))
+
+		rr, _ := delta("?token=" + token)
+		Expect(rr.Code).To(Equal(http.StatusGone))
+		Expect(rr.Body.String()).To(ContainSubstring("resyncRequired"))
+	})
+
+	It("rejects invalid tokens", func() {
+		rr, _ := delta("?token=invalid")
+		Expect(rr.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("returns 404 for drives the user can't access", func() {
+		gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: status.NewPermissionDenied(ctx, nil, "denied")}, nil)
+
+		rr, _ := delta("")
+		Expect(rr.Code).To(Equal(http.StatusNotFound))
+	})
+})
@@ -42,6 +42,7 @@ const (
 	_spaceTypeProject    = "project"
 	_spaceTypeVirtual    = "virtual"
 	_spaceTypeMountpoint = "mountpoint"
+	_spaceTypeQuarantine = "quarantine"
 	_spaceStateTrashed   = "trashed"
 
 	_sortDescending = "desc"
@@ -249,13 +250,33 @@ func (g Graph) getDrives(r *http.Request, unrestricted bool, apiVersion APIVersi
 		return nil, errorcode.New(errorcode.GeneralException, res.Status.Message)
 	}
 
+	storageSpaces := res.GetStorageSpaces()
+	// the storage providers only list the quarantine space of the antivirus service if it is asked for,
+	// it is added to the unrestricted listing so admins can see it
+	isTypeFilter := func(f *storageprovider.ListStorageSpacesRequest_Filter) bool {
+		return f.GetType() == storageprovider.ListStorageSpacesRequest_Filter_TYPE_SPACE_TYPE
+	}
+	if unrestricted && !slices.ContainsFunc(filters, isTypeFilter) {
+		qRes, err := g.ListStorageSpacesWithFilters(ctx, append(filters, listStorageSpacesTypeFilter(_spaceTypeQuarantine)), unrestricted)
+		switch {
+		case err != nil:
+			logger.Error().Err(err).Msg("could not get quarantine drives: transport error")
+			return nil, errorcode.New(errorcode.GeneralException, err.Error())
+		case qRes.GetStatus().GetCode() == cs3rpc.Code_CODE_OK:
+			storageSpaces = append(storageSpaces, qRes.GetStorageSpaces()...)
+		case qRes.GetStatus().GetCode() != cs3rpc.Code_CODE_NOT_FOUND:
+			logger.Debug().Str("message", qRes.GetStatus().GetMessage()).Msg("could not get quarantine drives: grpc error")
+			return nil, errorcode.New(errorcode.GeneralException, qRes.GetStatus().GetMessage())
+		}
+	}
+
 	webDavBaseURL, err := g.getWebDavBaseURL()
 	if err != nil {
 		logger.Error().Err(err).Str("url", webDavBaseURL.String()).Msg("could not get drives: error parsing url")
 		return nil, errorcode.New(errorcode.GeneralException, err.Error())
 	}
 
-	spaces, err := g.formatDrives(ctx, webDavBaseURL, res.StorageSpaces, apiVersion)
+	spaces, err := g.formatDrives(ctx, webDavBaseURL, storageSpaces, apiVersion)
 	if err != nil {
 		logger.Debug().Err(err).Msg("could not get drives: error parsing grpc response")
 		return nil, errorcode.New(errorcode.GeneralException, err.Error())
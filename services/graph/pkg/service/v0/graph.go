@@ -14,6 +14,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jellydator/ttlcache/v3"
 	"go-micro.dev/v4/client"
+	"go-micro.dev/v4/selector"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -26,6 +27,7 @@ import (
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
 	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
 	"github.com/owncloud/ocis/v2/services/graph/pkg/identity"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
 )
 
 // Permissions is the interface used to access the permissions service
@@ -69,6 +71,10 @@ type Graph struct {
 	historyClient            ehsvc.EventHistoryService
 	traceProvider            trace.TracerProvider
 	maxImageFileSize         uint64
+	subscriptionStore        *subscriptions.Store
+	subscriptionNotifier     *subscriptions.Notifier
+	serviceSelector          selector.Selector
+	activitiesClient         HTTPClient
 }
 
 // ServeHTTP implements the Service interface.
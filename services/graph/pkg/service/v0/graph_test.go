@@ -127,6 +127,10 @@ var _ = Describe("Graph", func() {
 			})
 
 			It("can list an empty list of all spaces when having 2fa", func() {
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(isQuarantineListing)).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{},
+				}, nil)
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Times(1).Return(&provider.ListStorageSpacesResponse{
 					Status:        status.NewOK(ctx),
 					StorageSpaces: []*provider.StorageSpace{},
@@ -1474,6 +1478,10 @@ var _ = Describe("Graph", func() {
 
 			It("hides other users' personal drives from a non-admin", func() {
 				mockSpaceFormatting()
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(isQuarantineListing)).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{},
+				}, nil)
 				mockAccountPerms(v0.Permission_CONSTRAINT_OWN)
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
 					Status:        status.NewOK(ctx),
@@ -1488,6 +1496,10 @@ var _ = Describe("Graph", func() {
 
 			It("shows all personal drives to a full-account admin", func() {
 				mockSpaceFormatting()
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(isQuarantineListing)).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{},
+				}, nil)
 				mockAccountPerms(v0.Permission_CONSTRAINT_ALL)
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
 					Status:        status.NewOK(ctx),
@@ -1502,6 +1514,10 @@ var _ = Describe("Graph", func() {
 
 			It("keeps the caller's own personal drive", func() {
 				mockSpaceFormatting()
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(isQuarantineListing)).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{},
+				}, nil)
 				mockAccountPerms(v0.Permission_CONSTRAINT_OWN)
 				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
 					Status:        status.NewOK(ctx),
@@ -1513,6 +1529,48 @@ var _ = Describe("Graph", func() {
 				Expect(rr.Code).To(Equal(http.StatusOK))
 				Expect(listedDriveIDs()).To(ConsistOf("pro-1$targetspace"))
 			})
+
+			It("lists the quarantine space of the antivirus service", func() {
+				mockSpaceFormatting()
+				mockAccountPerms(v0.Permission_CONSTRAINT_ALL)
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.MatchedBy(isQuarantineListing)).Return(&provider.ListStorageSpacesResponse{
+					Status: status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{{
+						Id:        &provider.StorageSpaceId{OpaqueId: "quarantinespace"},
+						SpaceType: "quarantine",
+						Root: &provider.ResourceId{
+							StorageId: "pro-1",
+							SpaceId:   "quarantinespace",
+							OpaqueId:  "quarantinespace",
+						},
+						Name: "Quarantine",
+					}},
+				}, nil)
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{projectSpace("targetuser")},
+				}, nil)
+
+				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/drives", nil).WithContext(callerCtx("admin"))
+				svc.GetAllDrivesV1(rr, r)
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				Expect(listedDriveIDs()).To(ConsistOf("pro-1$projectspace", "pro-1$quarantinespace"))
+			})
+
+			It("only lists the requested drive type", func() {
+				mockSpaceFormatting()
+				mockAccountPerms(v0.Permission_CONSTRAINT_ALL)
+				gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Times(1).Return(&provider.ListStorageSpacesResponse{
+					Status:        status.NewOK(ctx),
+					StorageSpaces: []*provider.StorageSpace{projectSpace("targetuser")},
+				}, nil)
+
+				r := httptest.NewRequest(http.MethodGet, "/graph/v1.0/drives?$filter=driveType+eq+'project'", nil).WithContext(callerCtx("admin"))
+				svc.GetAllDrivesV1(rr, r)
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				Expect(listedDriveIDs()).To(ConsistOf("pro-1$projectspace"))
+				gatewayClient.AssertNumberOfCalls(GinkgoT(), "ListStorageSpaces", 1)
+			})
 		})
 	})
 
@@ -1566,3 +1624,13 @@ var _ = Describe("Graph", func() {
 		})
 	})
 })
+
+// isQuarantineListing matches the listing of the quarantine spaces which is added to unrestricted listings
+func isQuarantineListing(req *provider.ListStorageSpacesRequest) bool {
+	for _, f := range req.GetFilters() {
+		if f.GetSpaceType() == "quarantine" {
+			return true
+		}
+	}
+	return false
+}
@@ -13,8 +13,10 @@ import (
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
 	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
 	"github.com/owncloud/ocis/v2/services/graph/pkg/identity"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"go-micro.dev/v4/selector"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -41,6 +43,9 @@ type Options struct {
 	KeycloakClient           keycloak.Client
 	EventHistoryClient       ehsvc.EventHistoryService
 	TraceProvider            trace.TracerProvider
+	SubscriptionStore        *subscriptions.Store
+	SubscriptionNotifier     *subscriptions.Notifier
+	ServiceSelector          selector.Selector
 }
 
 // newOptions initializes the available default options.
@@ -179,3 +184,24 @@ func TraceProvider(val trace.TracerProvider) Option {
 		o.TraceProvider = val
 	}
 }
+
+// WithSubscriptionStore provides a function to set the SubscriptionStore option.
+func WithSubscriptionStore(val *subscriptions.Store) Option {
+	return func(o *Options) {
+		o.SubscriptionStore = val
+	}
+}
+
+// WithSubscriptionNotifier provides a function to set the SubscriptionNotifier option.
+func WithSubscriptionNotifier(val *subscriptions.Notifier) Option {
+	return func(o *Options) {
+		o.SubscriptionNotifier = val
+	}
+}
+
+// WithServiceSelector provides a function to set the ServiceSelector option.
+func WithServiceSelector(val selector.Selector) Option {
+	return func(o *Options) {
+		o.ServiceSelector = val
+	}
+}
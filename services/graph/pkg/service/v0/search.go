@@ -0,0 +1,616 @@
+package svc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/render"
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	merrors "go-micro.dev/v4/errors"
+	"go-micro.dev/v4/metadata"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/ast"
+	"github.com/owncloud/ocis/v2/ocis-pkg/kql"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+)
+
+const (
+	// SearchEntityTypeDriveItem searches files and folders
+	SearchEntityTypeDriveItem = "driveItem"
+	// SearchEntityTypeDrive searches drives by their name and description
+	SearchEntityTypeDrive = "drive"
+
+	_searchDefaultSize            = 25
+	_searchDefaultAggregationSize = 10
+)
+
+// _searchMediaTypes maps the mediatype categories of the KQL `mediatype` property to the mime types they contain,
+// a trailing slash matches all mime types with that prefix
+var _searchMediaTypes = []struct {
+	category  string
+	mimeTypes []string
+}{
+	{"folder", []string{"httpd/unix-directory"}},
+	{"document", []string{
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.form",
+		"application/vnd.oasis.opendocument.text",
+		"text/plain",
+		"text/markdown",
+		"application/rtf",
+		"application/vnd.apple.pages",
+	}},
+	{"spreadsheet", []string{
+		"application/vnd.ms-excel",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"text/csv",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.apple.numbers",
+	}},
+	{"presentation", []string{
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.presentation",
+		"application/vnd.ms-powerpoint",
+		"application/vnd.apple.keynote",
+	}},
+	{"pdf", []string{"application/pdf"}},
+	{"image", []string{"image/"}},
+	{"video", []string{"video/"}},
+	{"audio", []string{"audio/"}},
+	{"archive", []string{
+		"application/zip",
+		"application/gzip",
+		"application/x-gzip",
+		"application/x-7z-compressed",
+		"application/x-rar-compressed",
+		"application/x-tar",
+		"application/x-bzip2",
+		"application/x-bzip",
+		"application/x-tgz",
+	}},
+}
+
+// _searchTimeRanges are the natural language date ranges of the KQL `mtime` property, in the order of their buckets
+var _searchTimeRanges = []string{
+	"today",
+	"yesterday",
+	"this week",
+	"last week",
+	"last 7 days",
+	"this month",
+	"last month",
+	"last 30 days",
+	"this year",
+	"last year",
+}
+
+// SearchQueryRequest contains one or more search requests
+type SearchQueryRequest struct {
+	Requests []SearchRequestItem `json:"requests"`
+}
+
+// SearchRequestItem is a single search request
+type SearchRequestItem struct {
+	EntityTypes    []string                 `json:"entityTypes"`
+	Query          SearchQuery              `json:"query"`
+	From           int                      `json:"from,omitempty"`
+	Size           int                      `json:"size,omitempty"`
+	SortProperties []SearchSortProperty     `json:"sortProperties,omitempty"`
+	Aggregations   []SearchAggregationInput `json:"aggregations,omitempty"`
+}
+
+// SearchQuery holds the KQL query string of a search request
+type SearchQuery struct {
+	QueryString string `json:"queryString"`
+}
+
+// SearchSortProperty defines a property the hits are sorted by, instead of by their rank
+type SearchSortProperty struct {
+	Name         string `json:"name"`
+	IsDescending bool   `json:"isDescending,omitempty"`
+}
+
+// SearchAggregationInput requests the aggregation of the matches by a field
+type SearchAggregationInput struct {
+	Field string `json:"field"`
+	Size  int    `json:"size,omitempty"`
+}
+
+// SearchQueryResponse contains the results of the search requests in the order of the requests
+type SearchQueryResponse struct {
+	Value []SearchResult `json:"value"`
+}
+
+// SearchResult is the result of a single search request, it contains one hits container per entity type
+type SearchResult struct {
+	HitsContainers []SearchHitsContainer `json:"hitsContainers"`
+}
+
+// SearchHitsContainer contains a page of the hits of an entity type
+type SearchHitsContainer struct {
+	Hits                 []SearchHit         `json:"hits"`
+	Total                int                 `json:"total"`
+	MoreResultsAvailable bool                `json:"moreResultsAvailable"`
+	Aggregations         []SearchAggregation `json:"aggregations,omitempty"`
+}
+
+// SearchHit is a single search hit, the summary contains the highlighted snippets of the matched content
+type SearchHit struct {
+	HitID    string      `json:"hitId"`
+	Rank     int         `json:"rank"`
+	Summary  string      `json:"summary,omitempty"`
+	Resource interface{} `json:"resource"`
+}
+
+// SearchAggregation contains the buckets of an aggregated field
+type SearchAggregation struct {
+	Field   string         `json:"field"`
+	Buckets []SearchBucket `json:"buckets"`
+}
+
+// SearchBucket counts the matches with a value of an aggregated field. The filter token can be
+// added to the query string to narrow down the search to the matches of the bucket.
+type SearchBucket struct {
+	Key                    string `json:"key"`
+	Count                  int    `json:"count"`
+	AggregationFilterToken string `json:"aggregationFilterToken,omitempty"`
+}
+
+// searchHit is a hit before paging, the sort values are used for sorting by properties
+type searchHit struct {
+	hit          SearchHit
+	name         string
+	lastModified time.Time
+	size         int64
+}
+
+// SearchQuery searches drive items and drives with KQL queries
+func (g Graph) SearchQuery(w http.ResponseWriter, r *http.Request) {
+	logger := g.logger.SubloggerWithRequestID(r.Context())
+
+	req := &SearchQueryRequest{}
+	if err := StrictJSONUnmarshal(r.Body, req); err != nil {
+		logger.Debug().Err(err).Msg("could not decode search request")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid body schema definition")
+		return
+	}
+	if len(req.Requests) == 0 {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "the search does not contain any requests")
+		return
+	}
+	for _, item := range req.Requests {
+		if err := validateSearchRequest(item, g.config.Search.MaxResults); err != nil {
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	th := r.Header.Get(revactx.TokenHeader)
+	ctx := revactx.ContextSetToken(r.Context(), th)
+	ctx = metadata.Set(ctx, revactx.TokenHeader, th)
+
+	res := &SearchQueryResponse{Value: make([]SearchResult, 0, len(req.Requests))}
+	for _, item := range req.Requests {
+		result := SearchResult{HitsContainers: make([]SearchHitsContainer, 0, len(item.EntityTypes))}
+		for _, entityType := range item.EntityTypes {
+			var (
+				container *SearchHitsContainer
+				err       error
+			)
+			switch entityType {
+			case SearchEntityTypeDriveItem:
+				container, err = g.searchDriveItems(ctx, item)
+			case SearchEntityTypeDrive:
+				container, err = g.searchDrives(ctx, item)
+			}
+			if err != nil {
+				logger.Debug().Err(err).Str("entityType", entityType).Str("query", item.Query.QueryString).Msg("could not search")
+				errorcode.RenderError(w, r, err)
+				return
+			}
+			result.HitsContainers = append(result.HitsContainers, *container)
+		}
+		res.Value = append(res.Value, result)
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, res)
+}
+
+// validateSearchRequest checks a request item, maxResults is the number of driveItems the search service is asked for
+func validateSearchRequest(item SearchRequestItem, maxResults int) error {
+	switch {
+	case strings.TrimSpace(item.Query.QueryString) == "":
+		return errors.New("the query string must not be empty")
+	case len(item.EntityTypes) == 0:
+		return errors.New("at least one entity type is required")
+	case item.From < 0 || item.Size < 0:
+		return errors.New("from and size must not be negative")
+	case maxResults > 0 && item.From >= maxResults && slices.Contains(item.EntityTypes, SearchEntityTypeDriveItem):
+		return fmt.Errorf("from must be lower than %d, refine the query to find more items", maxResults)
+	}
+
+	for _, entityType := range item.EntityTypes {
+		if entityType != SearchEntityTypeDriveItem && entityType != SearchEntityTypeDrive {
+			return fmt.Errorf("unsupported entity type '%s'", entityType)
+		}
+	}
+	for _, p := range item.SortProperties {
+		switch p.Name {
+		case "name", "lastModifiedDateTime", "size":
+		default:
+			return fmt.Errorf("unsupported sort property '%s'", p.Name)
+		}
+	}
+	for _, a := range item.Aggregations {
+		if searchAggregationField(a.Field) == "" {
+			return fmt.Errorf("unsupported aggregation field '%s'", a.Field)
+		}
+		if !slices.Equal(item.EntityTypes, []string{SearchEntityTypeDriveItem}) {
+			return errors.New("aggregations are only supported when searching driveItems only")
+		}
+	}
+	return nil
+}
+
+// searchAggregationField returns the canonical name of an aggregation field, the KQL property names are accepted as well
+func searchAggregationField(field string) string {
+	switch strings.ToLower(field) {
+	case "mediatype":
+		return "mediaType"
+	case "mtime", "lastmodifieddatetime":
+		return "lastModifiedDateTime"
+	case "tag", "tags":
+		return "tags"
+	default:
+		return ""
+	}
+}
+
+func (g Graph) searchDriveItems(ctx context.Context, item SearchRequestItem) (*SearchHitsContainer, error) {
+	res, err := g.searchService.Search(ctx, &searchsvc.SearchRequest{
+		Query:    item.Query.QueryString,
+		PageSize: int32(g.config.Search.MaxResults),
+	})
+	if err != nil {
+		e := merrors.Parse(err.Error())
+		if e.Code == http.StatusBadRequest {
+			return nil, errorcode.New(errorcode.InvalidRequest, e.Detail)
+		}
+		return nil, errorcode.New(errorcode.GeneralException, e.Detail)
+	}
+
+	hits := make([]searchHit, 0, len(res.GetMatches()))
+	for i, match := range res.GetMatches() {
+		driveItem := searchEntityToDriveItem(match.GetEntity())
+		hits = append(hits, searchHit{
+			hit: SearchHit{
+				HitID:    driveItem.GetId(),
+				Rank:     i + 1,
+				Summary:  match.GetEntity().GetHighlights(),
+				Resource: driveItem,
+			},
+			name:         driveItem.GetName(),
+			lastModified: driveItem.GetLastModifiedDateTime(),
+			size:         driveItem.GetSize(),
+		})
+	}
+
+	// paging, sorting and aggregations only cover the fetched matches, so the total is limited to them as well
+	container := pageSearchHits(hits, item)
+	for _, a := range item.Aggregations {
+		container.Aggregations = append(container.Aggregations, aggregateSearchMatches(res.GetMatches(), a))
+	}
+	return container, nil
+}
+
+func (g Graph) searchDrives(ctx context.Context, item SearchRequestItem) (*SearchHitsContainer, error) {
+	terms, err := searchDriveTerms(item.Query.QueryString)
+	if err != nil {
+		return nil, errorcode.New(errorcode.InvalidRequest, err.Error())
+	}
+
+	user, ok := revactx.ContextGetUser(ctx)
+	if !ok {
+		return nil, errorcode.New(errorcode.AccessDenied, "invalid user")
+	}
+	res, err := g.ListStorageSpacesWithFilters(ctx, []*storageprovider.ListStorageSpacesRequest_Filter{
+		listStorageSpacesUserFilter(user.GetId().GetOpaqueId()),
+	}, false)
+	// no spaces is not an error, the search has no hits then
+	if err := errorcode.FromCS3Status(res.GetStatus(), err, cs3rpc.Code_CODE_NOT_FOUND); err != nil {
+		return nil, err
+	}
+
+	webDavBaseURL, err := g.getWebDavBaseURL()
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]searchHit, 0, len(res.GetStorageSpaces()))
+	for _, space := range res.GetStorageSpaces() {
+		if space.GetSpaceType() == _spaceTypeMountpoint || space.GetSpaceType() == _spaceTypeVirtual ||
+			space.GetRoot().GetStorageId() == utils.OCMStorageProviderID ||
+			utils.ReadPlainFromOpaque(space.GetOpaque(), _spaceStateTrashed) == _spaceStateTrashed {
+			continue
+		}
+		description := utils.ReadPlainFromOpaque(space.GetOpaque(), "description")
+		if !searchTermsMatch(terms, space.GetName(), description) {
+			continue
+		}
+
+		drive, err := g.cs3StorageSpaceToDrive(ctx, webDavBaseURL, space, APIVersion_1_Beta_1)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, searchHit{
+			hit: SearchHit{
+				HitID:    drive.GetId(),
+				Resource: drive,
+			},
+			name:         drive.GetName(),
+			lastModified: drive.GetLastModifiedDateTime(),
+		})
+	}
+
+	// drives have no relevance score, rank them by name
+	slices.SortStableFunc(hits, func(a, b searchHit) int {
+		return cmp.Compare(strings.ToLower(a.name), strings.ToLower(b.name))
+	})
+	for i := range hits {
+		hits[i].hit.Rank = i + 1
+	}
+	return pageSearchHits(hits, item), nil
+}
+
+// searchDriveTerms extracts the terms drives are matched with from a KQL query,
+// these are the free text terms and the values of the name property
+func searchDriveTerms(queryString string) ([]string, error) {
+	q, err := kql.Builder{}.Build(queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []string
+	for _, node := range q.Nodes {
+		n, ok := node.(*ast.StringNode)
+		if !ok || (n.Key != "" && !strings.EqualFold(n.Key, "name")) {
+			continue
+		}
+		if term := strings.Trim(n.Value, "*?"); term != "" {
+			terms = append(terms, strings.ToLower(term))
+		}
+	}
+	return terms, nil
+}
+
+// searchTermsMatch checks if all terms are contained in one of the values, ignoring the case
+func searchTermsMatch(terms []string, values ...string) bool {
+	for _, term := range terms {
+		if !slices.ContainsFunc(values, func(v string) bool {
+			return strings.Contains(strings.ToLower(v), term)
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// pageSearchHits sorts the hits by the sort properties of the request and returns the requested page
+func pageSearchHits(hits []searchHit, item SearchRequestItem) *SearchHitsContainer {
+	if len(item.SortProperties) > 0 {
+		slices.SortStableFunc(hits, func(a, b searchHit) int {
+			for _, p := range item.SortProperties {
+				var c int
+				switch p.Name {
+				case "name":
+					c = cmp.Compare(strings.ToLower(a.name), strings.ToLower(b.name))
+				case "lastModifiedDateTime":
+					c = a.lastModified.Compare(b.lastModified)
+				case "size":
+					c = cmp.Compare(a.size, b.size)
+				}
+				if p.IsDescending {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	size := item.Size
+	if size == 0 {
+		size = _searchDefaultSize
+	}
+	from := min(item.From, len(hits))
+	to := min(from+size, len(hits))
+
+	container := &SearchHitsContainer{
+		Hits:                 make([]SearchHit, 0, to-from),
+		Total:                len(hits),
+		MoreResultsAvailable: to < len(hits),
+	}
+	for _, h := range hits[from:to] {
+		container.Hits = append(container.Hits, h.hit)
+	}
+	return container
+}
+
+// aggregateSearchMatches counts the matches per value of the aggregated field, the buckets are
+// limited to the requested size
+func aggregateSearchMatches(matches []*searchmsg.Match, input SearchAggregationInput) SearchAggregation {
+	field := searchAggregationField(input.Field)
+	counts := map[string]int{}
+	var buckets []SearchBucket
+
+	switch field {
+	case "mediaType":
+		for _, match := range matches {
+			counts[searchMediaType(match.GetEntity().GetMimeType())]++
+		}
+		for key, count := range counts {
+			bucket := SearchBucket{Key: key, Count: count}
+			if key != "other" {
+				bucket.AggregationFilterToken = "mediatype:" + key
+			}
+			buckets = append(buckets, bucket)
+		}
+	case "tags":
+		for _, match := range matches {
+			for _, tag := range match.GetEntity().GetTags() {
+				counts[tag]++
+			}
+		}
+		for key, count := range counts {
+			buckets = append(buckets, SearchBucket{Key: key, Count: count, AggregationFilterToken: "tag:" + strconv.Quote(key)})
+		}
+	case "lastModifiedDateTime":
+		// the time ranges overlap, a match is counted in every range it falls into.
+		// The buckets keep the order of the time ranges.
+		for _, key := range _searchTimeRanges {
+			from, to, err := kql.TimeRange(key)
+			if err != nil {
+				continue
+			}
+			count := 0
+			for _, match := range matches {
+				mtime := match.GetEntity().GetLastModifiedTime().AsTime()
+				if !mtime.Before(from) && !mtime.After(to) {
+					count++
+				}
+			}
+			if count > 0 {
+				buckets = append(buckets, SearchBucket{Key: key, Count: count, AggregationFilterToken: "mtime:" + strconv.Quote(key)})
+			}
+		}
+	}
+
+	if field != "lastModifiedDateTime" {
+		slices.SortFunc(buckets, func(a, b SearchBucket) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Key, b.Key)
+		})
+	}
+
+	size := input.Size
+	if size <= 0 {
+		size = _searchDefaultAggregationSize
+	}
+	if len(buckets) > size {
+		buckets = buckets[:size]
+	}
+	if buckets == nil {
+		buckets = []SearchBucket{}
+	}
+	return SearchAggregation{Field: field, Buckets: buckets}
+}
+
+// searchMediaType returns the KQL mediatype category of a mime type
+func searchMediaType(mimeType string) string {
+	for _, mt := range _searchMediaTypes {
+		for _, m := range mt.mimeTypes {
+			if m == mimeType || (strings.HasSuffix(m, "/") && strings.HasPrefix(mimeType, m)) {
+				return mt.category
+			}
+		}
+	}
+	return "other"
+}
+
+func searchEntityToDriveItem(e *searchmsg.Entity) *libregraph.DriveItem {
+	id := &storageprovider.ResourceId{
+		StorageId: e.GetId().GetStorageId(),
+		SpaceId:   e.GetId().GetSpaceId(),
+		OpaqueId:  e.GetId().GetOpaqueId(),
+	}
+
+	driveItem := libregraph.NewDriveItem()
+	driveItem.SetId(storagespace.FormatResourceID(id))
+	driveItem.SetName(e.GetName())
+	driveItem.SetETag(e.GetEtag())
+	driveItem.SetSize(int64(e.GetSize()))
+	if e.GetLastModifiedTime() != nil {
+		driveItem.SetLastModifiedDateTime(e.GetLastModifiedTime().AsTime())
+	}
+
+	if e.GetType() == uint64(storageprovider.ResourceType_RESOURCE_TYPE_CONTAINER) {
+		driveItem.SetFolder(libregraph.Folder{})
+	} else {
+		driveItem.SetFile(libregraph.OpenGraphFile{MimeType: libregraph.PtrString(e.GetMimeType())})
+	}
+
+	parentReference := libregraph.NewItemReference()
+	parentReference.SetDriveId(storagespace.FormatStorageID(id.GetStorageId(), id.GetSpaceId()))
+	if p := e.GetParentId(); p != nil {
+		parentReference.SetId(storagespace.FormatResourceID(&storageprovider.ResourceId{
+			StorageId: p.GetStorageId(),
+			SpaceId:   p.GetSpaceId(),
+			OpaqueId:  p.GetOpaqueId(),
+		}))
+	}
+	driveItem.SetParentReference(*parentReference)
+
+	if img := e.GetImage(); img != nil {
+		driveItem.SetImage(libregraph.Image{
+			Width:  libregraph.PtrInt32(img.GetWidth()),
+			Height: libregraph.PtrInt32(img.GetHeight()),
+		})
+	}
+	if loc := e.GetLocation(); loc != nil {
+		driveItem.SetLocation(libregraph.GeoCoordinates{
+			Altitude:  libregraph.PtrFloat64(loc.GetAltitude()),
+			Latitude:  libregraph.PtrFloat64(loc.GetLatitude()),
+			Longitude: libregraph.PtrFloat64(loc.GetLongitude()),
+		})
+	}
+	if photo := e.GetPhoto(); photo != nil {
+		p := libregraph.Photo{
+			CameraMake:          libregraph.PtrString(photo.GetCameraMake()),
+			CameraModel:         libregraph.PtrString(photo.GetCameraModel()),
+			ExposureDenominator: libregraph.PtrFloat64(float64(photo.GetExposureDenominator())),
+			ExposureNumerator:   libregraph.PtrFloat64(float64(photo.GetExposureNumerator())),
+			FNumber:             libregraph.PtrFloat64(float64(photo.GetFNumber())),
+			FocalLength:         libregraph.PtrFloat64(float64(photo.GetFocalLength())),
+			Iso:                 libregraph.PtrInt32(photo.GetIso()),
+			Orientation:         libregraph.PtrInt32(photo.GetOrientation()),
+		}
+		if photo.GetTakenDateTime() != nil {
+			p.SetTakenDateTime(photo.GetTakenDateTime().AsTime())
+		}
+		driveItem.SetPhoto(p)
+	}
+	if audio := e.GetAudio(); audio != nil {
+		driveItem.SetAudio(libregraph.Audio{
+			Album:       libregraph.PtrString(audio.GetAlbum()),
+			AlbumArtist: libregraph.PtrString(audio.GetAlbumArtist()),
+			Artist:      libregraph.PtrString(audio.GetArtist()),
+			Bitrate:     libregraph.PtrInt64(audio.GetBitrate()),
+			Duration:    libregraph.PtrInt64(audio.GetDuration()),
+			Genre:       libregraph.PtrString(audio.GetGenre()),
+			Title:       libregraph.PtrString(audio.GetTitle()),
+			Track:       libregraph.PtrInt32(audio.GetTrack()),
+			Year:        libregraph.PtrInt32(audio.GetYear()),
+		})
+	}
+	return driveItem
+}
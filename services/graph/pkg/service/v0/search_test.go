@@ -0,0 +1,301 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userprovider "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	merrors "go-micro.dev/v4/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	searchmocks "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0/mocks"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/graph/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config/defaults"
+	identitymocks "github.com/owncloud/ocis/v2/services/graph/pkg/identity/mocks"
+	service "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+)
+
+var _ = Describe("Search", func() {
+	var (
+		svc               service.Service
+		ctx               context.Context
+		gatewayClient     *cs3mocks.GatewayAPIClient
+		searchClient      *searchmocks.SearchProviderService
+		permissionService *mocks.Permissions
+	)
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"com.owncloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+		searchClient = &searchmocks.SearchProviderService{}
+		permissionService = &mocks.Permissions{}
+		permissionService.On("GetPermissionByID", mock.Anything, mock.Anything).Return(&settingssvc.GetPermissionByIDResponse{}, nil)
+
+		cfg := defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.Spaces.WebDavBase = "https://localhost:9200"
+
+		svc, _ = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.EventsPublisher(&mocks.Publisher{}),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.WithSearchService(searchClient),
+			service.PermissionService(permissionService),
+		)
+
+		ctx = revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}})
+	})
+
+	search := func(requests ...map[string]interface{}) (*httptest.ResponseRecorder, *service.SearchQueryResponse) {
+		b, err := json.Marshal(map[string]interface{}{"requests": requests})
+		Expect(err).ToNot(HaveOccurred())
+		r := httptest.NewRequest(http.MethodPost, "/graph/v1beta1/search/query", bytes.NewReader(b)).WithContext(ctx)
+
+		rr := httptest.NewRecorder()
+		svc.ServeHTTP(rr, r)
+		res := &service.SearchQueryResponse{}
+		if rr.Code == http.StatusOK {
+			Expect(json.Unmarshal(rr.Body.Bytes(), res)).To(Succeed())
+		}
+		return rr, res
+	}
+	entity := func(id, name, mimeType string, size uint64, mtime time.Time, tags ...string) *searchmsg.Match {
+		return &searchmsg.Match{Entity: &searchmsg.Entity{
+			Id:               &searchmsg.ResourceID{StorageId: "storage", SpaceId: "space", OpaqueId: id},
+			ParentId:         &searchmsg.ResourceID{StorageId: "storage", SpaceId: "space", OpaqueId: "space"},
+			Name:             name,
+			MimeType:         mimeType,
+			Type:             uint64(provider.ResourceType_RESOURCE_TYPE_FILE),
+			Size:             size,
+			LastModifiedTime: timestamppb.New(mtime),
+			Tags:             tags,
+			Highlights:       "the <mark>" + name + "</mark>",
+		}}
+	}
+	hitIDs := func(container service.SearchHitsContainer) []string {
+		ids := make([]string, 0, len(container.Hits))
+		for _, hit := range container.Hits {
+			ids = append(ids, hit.HitID)
+		}
+		return ids
+	}
+
+	Describe("driveItems", func() {
+		var matches []*searchmsg.Match
+
+		BeforeEach(func() {
+			now := time.Now()
+			matches = []*searchmsg.Match{
+				entity("a", "b.pdf", "application/pdf", 30, now, "work"),
+				entity("b", "c.png", "image/png", 10, now.AddDate(-2, 0, 0), "work", "private"),
+				entity("c", "a.txt", "text/plain", 20, now.AddDate(-2, 0, 0)),
+			}
+		})
+
+		It("passes the query to the search service and returns the matches as drive items", func() {
+			searchClient.On("Search", mock.Anything, mock.MatchedBy(func(req *searchsvc.SearchRequest) bool {
+				return req.GetQuery() == "name:*" && req.GetPageSize() == 1000
+			})).Return(&searchsvc.SearchResponse{Matches: matches, TotalMatches: 3}, nil)
+
+			rr, res := search(map[string]interface{}{
+				"entityTypes": []string{"driveItem"},
+				"query":       map[string]string{"queryString": "name:*"},
+			})
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			Expect(res.Value).To(HaveLen(1))
+			Expect(res.Value[0].HitsContainers).To(HaveLen(1))
+
+			container := res.Value[0].HitsContainers[0]
+			Expect(container.Total).To(Equal(3))
+			Expect(container.MoreResultsAvailable).To(BeFalse())
+			Expect(hitIDs(container)).To(Equal([]string{"storage$space!a", "storage$space!b", "storage$space!c"}))
+			Expect(container.Hits[0].Rank).To(Equal(1))
+			Expect(container.Hits[0].Summary).To(Equal("the <mark>b.pdf</mark>"))
+
+			resource := container.Hits[0].Resource.(map[string]interface{})
+			Expect(resource["name"]).To(Equal("b.pdf"))
+			Expect(resource["file"]).To(HaveKeyWithValue("mimeType", "application/pdf"))
+			Expect(resource["parentReference"]).To(HaveKeyWithValue("driveId", "storage$space"))
+			Expect(resource["parentReference"]).To(HaveKeyWithValue("id", "storage$space!space"))
+		})
+
+		It("sorts and pages the hits", func() {
+			searchClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchResponse{Matches: matches, TotalMatches: 3}, nil)
+
+			rr, res := search(map[string]interface{}{
+				"entityTypes":    []string{"driveItem"},
+				"query":          map[string]string{"queryString": "name:*"},
+				"from":           1,
+				"size":           1,
+				"sortProperties": []map[string]interface{}{{"name": "size", "isDescending": true}},
+			})
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			container := res.Value[0].HitsContainers[0]
+			Expect(hitIDs(container)).To(Equal([]string{"storage$space!c"}))
+			Expect(container.Hits[0].Rank).To(Equal(3))
+			Expect(container.MoreResultsAvailable).To(BeTrue())
+		})
+
+		It("aggregates the matches", func() {
+			searchClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchResponse{Matches: matches, TotalMatches: 3}, nil)
+
+			rr, res := search(map[string]interface{}{
+				"entityTypes": []string{"driveItem"},
+				"query":       map[string]string{"queryString": "name:*"},
+				"aggregations": []map[string]interface{}{
+					{"field": "mediatype"},
+					{"field": "tags", "size": 1},
+					{"field": "mtime"},
+				},
+			})
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			aggregations := res.Value[0].HitsContainers[0].Aggregations
+			Expect(aggregations).To(HaveLen(3))
+
+			Expect(aggregations[0].Field).To(Equal("mediaType"))
+			Expect(aggregations[0].Buckets).To(ConsistOf(
+				service.SearchBucket{Key: "pdf", Count: 1, AggregationFilterToken: "mediatype:pdf"},
+				service.SearchBucket{Key: "image", Count: 1, AggregationFilterToken: "mediatype:image"},
+				service.SearchBucket{Key: "document", Count: 1, AggregationFilterToken: "mediatype:document"},
+			))
+
+			Expect(aggregations[1].Field).To(Equal("tags"))
+			Expect(aggregations[1].Buckets).To(Equal([]service.SearchBucket{
+				{Key: "work", Count: 2, AggregationFilterToken: `tag:"work"`},
+			}))
+
+			Expect(aggregations[2].Field).To(Equal("lastModifiedDateTime"))
+			Expect(aggregations[2].Buckets[0]).To(Equal(service.SearchBucket{Key: "today", Count: 1, AggregationFilterToken: `mtime:"today"`}))
+		})
+
+		It("limits the total to the fetched matches", func() {
+			searchClient.On("Search", mock.Anything, mock.Anything).Return(&searchsvc.SearchResponse{Matches: matches, TotalMatches: 5000}, nil)
+
+			rr, res := search(map[string]interface{}{
+				"entityTypes": []string{"driveItem"},
+				"query":       map[string]string{"queryString": "name:*"},
+				"from":        2,
+			})
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			container := res.Value[0].HitsContainers[0]
+			Expect(container.Total).To(Equal(3))
+			Expect(hitIDs(container)).To(Equal([]string{"storage$space!c"}))
+			Expect(container.MoreResultsAvailable).To(BeFalse())
+		})
+
+		It("rejects pages beyond the fetched matches", func() {
+			rr, _ := search(map[string]interface{}{
+				"entityTypes": []string{"driveItem"},
+				"query":       map[string]string{"queryString": "name:*"},
+				"from":        1000,
+			})
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			searchClient.AssertNotCalled(GinkgoT(), "Search", mock.Anything, mock.Anything)
+		})
+
+		It("returns bad request for invalid queries", func() {
+			searchClient.On("Search", mock.Anything, mock.Anything).Return(nil, merrors.BadRequest("com.owncloud.api.search", "invalid query"))
+
+			rr, _ := search(map[string]interface{}{
+				"entityTypes": []string{"driveItem"},
+				"query":       map[string]string{"queryString": "name:("},
+			})
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("drives", func() {
+		It("matches the drives of the user by name and description", func() {
+			gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(&provider.ListStorageSpacesResponse{
+				Status: status.NewOK(ctx),
+				StorageSpaces: []*provider.StorageSpace{
+					{
+						Id:        &provider.StorageSpaceId{OpaqueId: "storage$marketing"},
+						Root:      &provider.ResourceId{StorageId: "storage", SpaceId: "marketing", OpaqueId: "marketing"},
+						Name:      "Marketing",
+						SpaceType: "project",
+					},
+					{
+						Id:        &provider.StorageSpaceId{OpaqueId: "storage$sales"},
+						Root:      &provider.ResourceId{StorageId: "storage", SpaceId: "sales", OpaqueId: "sales"},
+						Name:      "Sales",
+						SpaceType: "project",
+						Opaque: &types.Opaque{Map: map[string]*types.OpaqueEntry{
+							"description": {Decoder: "plain", Value: []byte("the marketing budget")},
+						}},
+					},
+					{
+						Id:        &provider.StorageSpaceId{OpaqueId: "storage$personal"},
+						Root:      &provider.ResourceId{StorageId: "storage", SpaceId: "personal", OpaqueId: "personal"},
+						Name:      "Alice",
+						SpaceType: "personal",
+					},
+				},
+			}, nil)
+
+			rr, res := search(map[string]interface{}{
+				"entityTypes": []string{"drive"},
+				"query":       map[string]string{"queryString": "market*"},
+			})
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			container := res.Value[0].HitsContainers[0]
+			Expect(container.Total).To(Equal(2))
+			Expect(hitIDs(container)).To(Equal([]string{"storage$marketing", "storage$sales"}))
+		})
+	})
+
+	DescribeTable("rejects invalid requests",
+		func(request map[string]interface{}) {
+			rr, _ := search(request)
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		},
+		Entry("without query", map[string]interface{}{"entityTypes": []string{"driveItem"}}),
+		Entry("without entity types", map[string]interface{}{"query": map[string]string{"queryString": "a"}}),
+		Entry("with unknown entity types", map[string]interface{}{"entityTypes": []string{"message"}, "query": map[string]string{"queryString": "a"}}),
+		Entry("with unknown sort properties", map[string]interface{}{
+			"entityTypes":    []string{"driveItem"},
+			"query":          map[string]string{"queryString": "a"},
+			"sortProperties": []map[string]interface{}{{"name": "owner"}},
+		}),
+		Entry("with unknown aggregations", map[string]interface{}{
+			"entityTypes":  []string{"driveItem"},
+			"query":        map[string]string{"queryString": "a"},
+			"aggregations": []map[string]interface{}{{"field": "owner"}},
+		}),
+		Entry("with aggregations of drives", map[string]interface{}{
+			"entityTypes":  []string{"drive"},
+			"query":        map[string]string{"queryString": "a"},
+			"aggregations": []map[string]interface{}{{"field": "tags"}},
+		}),
+	)
+})
@@ -18,6 +18,7 @@ import (
 	"github.com/owncloud/reva/v2/pkg/bytesize"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/rhttp"
 	"github.com/owncloud/reva/v2/pkg/store"
 	"github.com/owncloud/reva/v2/pkg/utils"
 
@@ -124,6 +125,18 @@ type Service interface { //nolint:interfacebloat
 	GetTags(w http.ResponseWriter, r *http.Request)
 	AssignTags(w http.ResponseWriter, r *http.Request)
 	UnassignTags(w http.ResponseWriter, r *http.Request)
+
+	ListSubscriptions(w http.ResponseWriter, r *http.Request)
+	CreateSubscription(w http.ResponseWriter, r *http.Request)
+	GetSubscription(w http.ResponseWriter, r *http.Request)
+	UpdateSubscription(w http.ResponseWriter, r *http.Request)
+	DeleteSubscription(w http.ResponseWriter, r *http.Request)
+
+	GetDriveDelta(w http.ResponseWriter, r *http.Request)
+	GetDriveItemDelta(w http.ResponseWriter, r *http.Request)
+
+	Batch(w http.ResponseWriter, r *http.Request)
+	SearchQuery(w http.ResponseWriter, r *http.Request)
 }
 
 // NewService returns a service implementation for Service.
@@ -164,6 +177,13 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 		historyClient:            options.EventHistoryClient,
 		traceProvider:            options.TraceProvider,
 		valueService:             options.ValueService,
+		subscriptionStore:        options.SubscriptionStore,
+		subscriptionNotifier:     options.SubscriptionNotifier,
+		serviceSelector:          options.ServiceSelector,
+		activitiesClient: rhttp.GetHTTPClient(
+			rhttp.Timeout(options.Config.Delta.ActivitiesTimeout),
+			rhttp.Insecure(options.Config.Delta.ActivitiesInsecure),
+		),
 	}
 
 	if raw := options.Config.Validation.MaxImageFileSize; raw != "" {
@@ -190,18 +210,19 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 
 	svc.roleService = options.RoleService
 
+	storeOptions := []microstore.Option{
+		store.Store(options.Config.Cache.Store),
+		store.TTL(options.Config.Cache.TTL),
+		microstore.Nodes(options.Config.Cache.Nodes...),
+		microstore.Database(options.Config.Cache.Database),
+		microstore.Table(options.Config.Cache.Table),
+		store.DisablePersistence(options.Config.Cache.DisablePersistence),
+		store.Authentication(options.Config.Cache.AuthUsername, options.Config.Cache.AuthPassword),
+		store.TLS(options.Config.Cache.EnableTLS, options.Config.Cache.TLSInsecure, options.Config.Cache.TLSRootCACertificate),
+	}
+
 	roleManager := options.RoleManager
 	if roleManager == nil {
-		storeOptions := []microstore.Option{
-			store.Store(options.Config.Cache.Store),
-			store.TTL(options.Config.Cache.TTL),
-			microstore.Nodes(options.Config.Cache.Nodes...),
-			microstore.Database(options.Config.Cache.Database),
-			microstore.Table(options.Config.Cache.Table),
-			store.DisablePersistence(options.Config.Cache.DisablePersistence),
-			store.Authentication(options.Config.Cache.AuthUsername, options.Config.Cache.AuthPassword),
-			store.TLS(options.Config.Cache.EnableTLS, options.Config.Cache.TLSInsecure, options.Config.Cache.TLSRootCACertificate),
-		}
 		m := roles.NewManager(
 			roles.StoreOptions(storeOptions),
 			roles.Logger(options.Logger),
@@ -222,7 +243,7 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 		return svc, err
 	}
 
-	drivesDriveItemApi, err := NewDrivesDriveItemApi(drivesDriveItemService, svc.BaseGraphService, options.Logger)
+	drivesDriveItemApi, err := NewDrivesDriveItemApi(drivesDriveItemService, svc.BaseGraphService, store.Create(storeOptions...), options.Logger)
 	if err != nil {
 		return svc, err
 	}
@@ -240,6 +261,7 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 	graphRoutes := func(r chi.Router, drivesRequireMFA func(http.Handler) http.Handler) {
 		r.Use(middleware.StripSlashes)
 		r.Route("/v1beta1", func(r chi.Router) {
+			r.Post("/$batch", svc.Batch)
 			r.Route("/me", func(r chi.Router) {
 				r.Get("/drives", svc.GetDrives(APIVersion_1_Beta_1))
 				r.Route("/drive", func(r chi.Router) {
@@ -255,6 +277,7 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 					r.Patch("/", svc.UpdateDriveV1Beta1)
 					r.Delete("/", svc.DeleteDrive)
 					r.Route("/root", func(r chi.Router) {
+						r.Get("/delta", svc.GetDriveDelta)
 						r.Post("/children", drivesDriveItemApi.CreateDriveItem)
 						r.Post("/invite", driveItemPermissionsApi.SpaceRootInvite)
 						r.Post("/createLink", driveItemPermissionsApi.CreateSpaceRootLink)
@@ -272,6 +295,12 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 						r.Get("/", drivesDriveItemApi.GetDriveItem)
 						r.Patch("/", drivesDriveItemApi.UpdateDriveItem)
 						r.Delete("/", drivesDriveItemApi.DeleteDriveItem)
+						r.Get("/delta", svc.GetDriveItemDelta)
+						r.Post("/copy", drivesDriveItemApi.CopyDriveItem)
+						r.Route("/versions", func(r chi.Router) {
+							r.Get("/", drivesDriveItemApi.ListDriveItemVersions)
+							r.Post("/{versionID}/restoreVersion", drivesDriveItemApi.RestoreDriveItemVersion)
+						})
 						r.Post("/invite", driveItemPermissionsApi.Invite)
 						r.Post("/createLink", driveItemPermissionsApi.CreateLink)
 						r.Route("/permissions", func(r chi.Router) {
@@ -286,17 +315,31 @@ func NewService(opts ...Option) (Graph, error) { //nolint:maintidx
 					})
 				})
 			})
+			r.Get("/operations/{operationID}", drivesDriveItemApi.GetOperation)
+			r.Post("/search/query", svc.SearchQuery)
 			r.Route("/roleManagement/permissions/roleDefinitions", func(r chi.Router) {
 				r.Get("/", svc.GetRoleDefinitions)
 				r.Get("/{roleID}", svc.GetRoleDefinition)
 			})
 		})
 		r.Route("/v1.0", func(r chi.Router) {
+			r.Post("/$batch", svc.Batch)
 			r.Route("/extensions/org.libregraph", func(r chi.Router) {
 				r.Get("/tags", svc.GetTags)
 				r.Put("/tags", svc.AssignTags)
 				r.Delete("/tags", svc.UnassignTags)
 			})
+			if svc.subscriptionStore != nil {
+				r.Route("/subscriptions", func(r chi.Router) {
+					r.Get("/", svc.ListSubscriptions)
+					r.Post("/", svc.CreateSubscription)
+					r.Route("/{subscriptionID}", func(r chi.Router) {
+						r.Get("/", svc.GetSubscription)
+						r.Patch("/", svc.UpdateSubscription)
+						r.Delete("/", svc.DeleteSubscription)
+					})
+				})
+			}
 			r.Route("/applications", func(r chi.Router) {
 				r.Get("/", svc.ListApplications)
 				r.Get("/{applicationID}", svc.GetApplication)
@@ -0,0 +1,253 @@
+package svc
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
+)
+
+// ListSubscriptions lists the subscriptions of the current user
+func (g Graph) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not get user from context")
+		return
+	}
+
+	subs, err := g.subscriptionStore.ListByUser(u.GetId().GetOpaqueId())
+	if err != nil {
+		g.logger.Error().Err(err).Msg("could not list subscriptions")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not list subscriptions")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &ListResponse{Value: subs})
+}
+
+// CreateSubscription creates a subscription for changes of a drive or a drive item.
+// The notification URL has to answer a validation request before the subscription is created.
+func (g Graph) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	u, ok := revactx.ContextGetUser(ctx)
+	if !ok {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not get user from context")
+		return
+	}
+
+	sub := &subscriptions.Subscription{}
+	if err := StrictJSONUnmarshal(r.Body, sub); err != nil {
+		g.logger.Debug().Err(err).Msg("could not decode subscription")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid body schema definition")
+		return
+	}
+	sub.ID = ""
+	sub.CreatorID = u.GetId().GetOpaqueId()
+
+	if err := sub.Validate(); err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return
+	}
+
+	expiration, err := subscriptions.Expiration(sub.ExpirationDateTime, g.config.Subscriptions.MaxExpiration)
+	if err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return
+	}
+	sub.ExpirationDateTime = expiration
+
+	if !g.checkSubscriptionResource(w, r, sub) {
+		return
+	}
+
+	if err := g.subscriptionNotifier.Validate(ctx, sub.NotificationURL); err != nil {
+		g.logger.Debug().Err(err).Str("url", sub.NotificationURL).Msg("notification url validation failed")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "notification url validation failed")
+		return
+	}
+
+	if err := g.subscriptionStore.Create(sub); err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, sub)
+}
+
+// GetSubscription returns a subscription of the current user
+func (g Graph) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := g.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, sub)
+}
+
+// UpdateSubscription renews a subscription or changes its notification URL
+func (g Graph) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := g.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var update struct {
+		ExpirationDateTime *time.Time `json:"expirationDateTime"`
+		NotificationURL    *string    `json:"notificationUrl"`
+	}
+	if err := StrictJSONUnmarshal(r.Body, &update); err != nil {
+		g.logger.Debug().Err(err).Msg("could not decode subscription update")
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid body schema definition")
+		return
+	}
+
+	if update.ExpirationDateTime != nil {
+		expiration, err := subscriptions.Expiration(*update.ExpirationDateTime, g.config.Subscriptions.MaxExpiration)
+		if err != nil {
+			g.renderSubscriptionError(w, r, err)
+			return
+		}
+		sub.ExpirationDateTime = expiration
+	}
+
+	if update.NotificationURL != nil && *update.NotificationURL != sub.NotificationURL {
+		sub.NotificationURL = *update.NotificationURL
+		if err := sub.Validate(); err != nil {
+			g.renderSubscriptionError(w, r, err)
+			return
+		}
+		if err := g.subscriptionNotifier.Validate(r.Context(), sub.NotificationURL); err != nil {
+			g.logger.Debug().Err(err).Str("url", sub.NotificationURL).Msg("notification url validation failed")
+			errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "notification url validation failed")
+			return
+		}
+	}
+
+	if err := g.subscriptionStore.Update(sub); err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, sub)
+}
+
+// DeleteSubscription deletes a subscription of the current user
+func (g Graph) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, ok := g.getSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	if err := g.subscriptionStore.Delete(sub); err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return
+	}
+
+	render.NoContent(w, r)
+}
+
+// getSubscription returns the subscription of the request if it belongs to the current user
+func (g Graph) getSubscription(w http.ResponseWriter, r *http.Request) (*subscriptions.Subscription, bool) {
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok {
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not get user from context")
+		return nil, false
+	}
+
+	sub, err := g.subscriptionStore.Get(chi.URLParam(r, "subscriptionID"))
+	if err == nil && sub.CreatorID != u.GetId().GetOpaqueId() {
+		err = subscriptions.ErrNotFound
+	}
+	if err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return nil, false
+	}
+	return sub, true
+}
+
+// checkSubscriptionResource makes sure the current user can access the resource of the subscription
+// and normalizes the ids in it
+func (g Graph) checkSubscriptionResource(w http.ResponseWriter, r *http.Request, sub *subscriptions.Subscription) bool {
+	driveID, itemID, err := subscriptions.ParseResource(sub.Resource)
+	if err != nil {
+		g.renderSubscriptionError(w, r, err)
+		return false
+	}
+
+	drive, err := storagespace.ParseID(driveID)
+	if err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid driveID in resource")
+		return false
+	}
+	ref := driveID
+	if itemID != "" {
+		ref = itemID
+	}
+	rid, err := storagespace.ParseID(ref)
+	if err != nil || rid.GetSpaceId() != drive.GetSpaceId() {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "item is not part of the drive")
+		return false
+	}
+	if itemID == "" {
+		rid.OpaqueId = rid.GetSpaceId()
+	}
+	if IsShareJail(&rid) {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "subscriptions on the shares drive are not supported, use the drive of the shared item")
+		return false
+	}
+
+	gatewayClient, err := g.gatewaySelector.Next()
+	if err != nil {
+		g.logger.Error().Err(err).Msg("could not select next gateway client")
+		errorcode.ServiceNotAvailable.Render(w, r, http.StatusInternalServerError, "could not select next gateway client")
+		return false
+	}
+	res, err := gatewayClient.Stat(r.Context(), &provider.StatRequest{Ref: &provider.Reference{ResourceId: &rid}})
+	switch {
+	case err != nil:
+		g.logger.Error().Err(err).Msg("could not stat subscription resource")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not stat resource")
+		return false
+	case res.GetStatus().GetCode() == rpc.Code_CODE_NOT_FOUND || res.GetStatus().GetCode() == rpc.Code_CODE_PERMISSION_DENIED:
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "resource not found")
+		return false
+	case res.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, res.GetStatus().GetMessage())
+		return false
+	}
+
+	id := res.GetInfo().GetId()
+	sub.Resource = "/drives/" + storagespace.FormatStorageID(id.GetStorageId(), id.GetSpaceId())
+	if itemID == "" {
+		sub.Resource += "/root"
+	} else {
+		sub.Resource += "/items/" + storagespace.FormatResourceID(id)
+	}
+	return true
+}
+
+func (g Graph) renderSubscriptionError(w http.ResponseWriter, r *http.Request, err error) {
+	var verr subscriptions.ValidationError
+	switch {
+	case errors.Is(err, subscriptions.ErrNotFound):
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, err.Error())
+	case errors.As(err, &verr):
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+	default:
+		g.logger.Error().Err(err).Msg("subscription error")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,226 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userprovider "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	microstore "go-micro.dev/v4/store"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/services/graph/mocks"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config/defaults"
+	identitymocks "github.com/owncloud/ocis/v2/services/graph/pkg/identity/mocks"
+	service "github.com/owncloud/ocis/v2/services/graph/pkg/service/v0"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
+)
+
+var _ = Describe("Subscriptions", func() {
+	var (
+		svc           service.Service
+		ctx           context.Context
+		gatewayClient *cs3mocks.GatewayAPIClient
+		store         *subscriptions.Store
+		hooks         *httptest.Server
+		rr            *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+		gatewayClient = &cs3mocks.GatewayAPIClient{}
+		gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+			"GatewaySelector",
+			"com.owncloud.api.gateway",
+			func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+				return gatewayClient
+			},
+		)
+
+		hooks = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/reject" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(r.URL.Query().Get("validationToken")))
+		}))
+
+		cfg := defaults.FullDefaultConfig()
+		cfg.Identity.LDAP.CACert = "" // skip the startup checks, we don't use LDAP at all in this tests
+		cfg.TokenManager.JWTSecret = "loremipsum"
+		cfg.Commons = &shared.Commons{}
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
+		cfg.Subscriptions.Enabled = true
+
+		store = subscriptions.NewStore(microstore.NewMemoryStore())
+		svc, _ = service.NewService(
+			service.Config(cfg),
+			service.WithGatewaySelector(gatewaySelector),
+			service.EventsPublisher(&mocks.Publisher{}),
+			service.WithIdentityBackend(&identitymocks.Backend{}),
+			service.WithSubscriptionStore(store),
+			service.WithSubscriptionNotifier(subscriptions.NewNotifier(cfg.Subscriptions, log.NopLogger(), subscriptions.WithHTTPClient(hooks.Client()))),
+		)
+
+		rr = httptest.NewRecorder()
+		ctx = revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: "alice"}})
+	})
+
+	AfterEach(func() {
+		hooks.Close()
+	})
+
+	create := func(body map[string]interface{}) {
+		b, err := json.Marshal(body)
+		Expect(err).ToNot(HaveOccurred())
+		r := httptest.NewRequest(http.MethodPost, "/graph/v1.0/subscriptions", bytes.NewReader(b)).WithContext(ctx)
+		svc.CreateSubscription(rr, r)
+	}
+
+	Describe("CreateSubscription", func() {
+		It("creates a subscription for an accessible item", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info:   &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "folder"}},
+			}, nil)
+
+			create(map[string]interface{}{
+				"resource":        "/drives/storage$space/items/storage$space!folder",
+				"changeType":      "created,deleted",
+				"notificationUrl": hooks.URL,
+				"clientState":     "secret",
+			})
+
+			Expect(rr.Code).To(Equal(http.StatusCreated))
+			sub := subscriptions.Subscription{}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &sub)).To(Succeed())
+			Expect(sub.ID).ToNot(BeEmpty())
+			Expect(sub.CreatorID).To(Equal("alice"))
+			Expect(sub.ExpirationDateTime).To(BeTemporally("~", time.Now().Add(72*time.Hour), time.Minute))
+
+			subs, err := store.ListBySpace("space")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(HaveLen(1))
+		})
+
+		It("rejects inaccessible resources", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewNotFound(ctx, "not found"),
+			}, nil)
+
+			create(map[string]interface{}{
+				"resource":        "/drives/storage$space/root",
+				"changeType":      "created",
+				"notificationUrl": hooks.URL,
+			})
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("rejects notification urls which fail the validation", func() {
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{
+				Status: status.NewOK(ctx),
+				Info:   &provider.ResourceInfo{Id: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "space"}},
+			}, nil)
+
+			create(map[string]interface{}{
+				"resource":        "/drives/storage$space/root",
+				"changeType":      "created",
+				"notificationUrl": hooks.URL + "/reject",
+			})
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+
+			subs, err := store.ListByUser("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(BeEmpty())
+		})
+
+		It("rejects invalid subscriptions", func() {
+			create(map[string]interface{}{
+				"resource":        "/drives/storage$space/root",
+				"changeType":      "renamed",
+				"notificationUrl": hooks.URL,
+			})
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("managing subscriptions", func() {
+		var sub *subscriptions.Subscription
+
+		BeforeEach(func() {
+			sub = &subscriptions.Subscription{
+				Resource:           "/drives/storage$space/root",
+				ChangeType:         "created",
+				NotificationURL:    hooks.URL,
+				ExpirationDateTime: time.Now().Add(time.Hour),
+				CreatorID:          "alice",
+			}
+			Expect(store.Create(sub)).To(Succeed())
+		})
+
+		request := func(method, id string, body []byte, user string) *http.Request {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("subscriptionID", id)
+			c := revactx.ContextSetUser(context.Background(), &userprovider.User{Id: &userprovider.UserId{OpaqueId: user}})
+			return httptest.NewRequest(method, "/graph/v1.0/subscriptions/"+id, bytes.NewReader(body)).
+				WithContext(context.WithValue(c, chi.RouteCtxKey, rctx))
+		}
+
+		It("renews a subscription", func() {
+			expiration := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+			body, _ := json.Marshal(map[string]interface{}{"expirationDateTime": expiration})
+			svc.UpdateSubscription(rr, request(http.MethodPatch, sub.ID, body, "alice"))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+
+			got, err := store.Get(sub.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got.ExpirationDateTime).To(BeTemporally("==", expiration))
+		})
+
+		It("rejects renewals beyond the maximum lifetime", func() {
+			body, _ := json.Marshal(map[string]interface{}{"expirationDateTime": time.Now().Add(100 * time.Hour)})
+			svc.UpdateSubscription(rr, request(http.MethodPatch, sub.ID, body, "alice"))
+			Expect(rr.Code).To(Equal(http.StatusBadRequest))
+		})
+
+		It("hides the subscriptions of other users", func() {
+			svc.GetSubscription(rr, request(http.MethodGet, sub.ID, nil, "bob"))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+
+			rr = httptest.NewRecorder()
+			svc.DeleteSubscription(rr, request(http.MethodDelete, sub.ID, nil, "bob"))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("lists and deletes subscriptions", func() {
+			svc.ListSubscriptions(rr, request(http.MethodGet, "", nil, "alice"))
+			Expect(rr.Code).To(Equal(http.StatusOK))
+			var list struct {
+				Value []subscriptions.Subscription `json:"value"`
+			}
+			Expect(json.Unmarshal(rr.Body.Bytes(), &list)).To(Succeed())
+			Expect(list.Value).To(ConsistOf(HaveField("ID", sub.ID)))
+
+			rr = httptest.NewRecorder()
+			svc.DeleteSubscription(rr, request(http.MethodDelete, sub.ID, nil, "alice"))
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+			_, err := store.Get(sub.ID)
+			Expect(err).To(MatchError(subscriptions.ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,439 @@
+package subscriptions
+
+import (
+	"context"
+	"errors"
+	"path"
+	"slices"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
+)
+
+// _consumerGroup is the consumer group of the listener, it must differ from the other consumers of the graph service
+const _consumerGroup = "graph-subscriptions"
+
+// _maxConcurrentDeliveries is the number of change notification requests which are sent at the same time
+const _maxConcurrentDeliveries = 16
+
+// Change is a change of a drive item
+type Change struct {
+	Type string
+	// ID is the id of the changed item, it is looked up if it is not known
+	ID *provider.ResourceId
+	// Refs are the references of the item, moved items have the new and the old one
+	Refs []*provider.Reference
+}
+
+// ChangeFromEvent returns the change described by an event, false is returned for events without changes
+func ChangeFromEvent(event interface{}) (Change, bool) {
+	switch ev := event.(type) {
+	case events.UploadReady:
+		if ev.Failed {
+			return Change{}, false
+		}
+		c := Change{Type: ChangeCreated, ID: ev.ResourceID, Refs: []*provider.Reference{ev.FileRef}}
+		if ev.IsVersion {
+			c.Type = ChangeUpdated
+		}
+		return c, true
+	case events.ContainerCreated:
+		return Change{Type: ChangeCreated, Refs: []*provider.Reference{ev.Ref}}, true
+	case events.ItemMoved:
+		return Change{Type: ChangeUpdated, Refs: []*provider.Reference{ev.Ref, ev.OldReference}}, true
+	case events.ItemTrashed:
+		return Change{Type: ChangeDeleted, ID: ev.ID, Refs: []*provider.Reference{ev.Ref}}, true
+	case events.ShareCreated:
+		return Change{Type: ChangeShared, ID: ev.ItemID, Refs: []*provider.Reference{{ResourceId: ev.ItemID}}}, true
+	default:
+		return Change{}, false
+	}
+}
+
+// Listener sends change notifications for the events of the storage to the matching subscriptions
+type Listener struct {
+	store           *Store
+	notifier        *Notifier
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	serviceAccount  config.ServiceAccount
+	logger          log.Logger
+	// deliveries limits the number of concurrent change notification requests
+	deliveries chan struct{}
+}
+
+// NewListener creates a Listener, the service account is used to look up the changed items and their grants
+func NewListener(store *Store, notifier *Notifier, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], serviceAccount config.ServiceAccount, logger log.Logger) *Listener {
+	return &Listener{
+		store:           store,
+		notifier:        notifier,
+		gatewaySelector: gatewaySelector,
+		serviceAccount:  serviceAccount,
+		logger:          logger,
+		deliveries:      make(chan struct{}, _maxConcurrentDeliveries),
+	}
+}
+
+// Start consumes the events of the storage until the context is cancelled
+func (l *Listener) Start(ctx context.Context, consumer events.Consumer) error {
+	evChannel, err := events.Consume(consumer, _consumerGroup,
+		events.UploadReady{},
+		events.ContainerCreated{},
+		events.ItemMoved{},
+		events.ItemTrashed{},
+		events.ShareCreated{},
+	)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			select {
+			case e := <-evChannel:
+				change, ok := ChangeFromEvent(e.Event)
+				if !ok {
+					continue
+				}
+				if err := l.Handle(ctx, change); err != nil {
+					l.logger.Error().Err(err).Str("eventid", e.ID).Msg("could not send change notifications")
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Handle sends change notifications for a change to the subscriptions of its space
+func (l *Listener) Handle(ctx context.Context, c Change) error {
+	spaceID := c.ID.GetSpaceId()
+	if spaceID == "" && len(c.Refs) > 0 {
+		spaceID = c.Refs[0].GetResourceId().GetSpaceId()
+	}
+	if spaceID == "" {
+		return nil
+	}
+
+	subs, err := l.store.ListBySpace(spaceID)
+	if err != nil || len(subs) == 0 {
+		return err
+	}
+	subs = slices.DeleteFunc(subs, func(s *Subscription) bool {
+		return !slices.Contains(s.ChangeTypes(), c.Type)
+	})
+	if len(subs) == 0 {
+		return nil
+	}
+
+	gwc, err := l.gatewaySelector.Next()
+	if err != nil {
+		return err
+	}
+	ctx, err = utils.GetServiceUserContextWithContext(ctx, gwc, l.serviceAccount.ServiceAccountID, l.serviceAccount.ServiceAccountSecret)
+	if err != nil {
+		return err
+	}
+
+	if c.ID == nil && len(c.Refs) > 0 {
+		res, err := gwc.Stat(ctx, &provider.StatRequest{Ref: c.Refs[0]})
+		if err != nil {
+			return err
+		}
+		if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+			// the item is gone already
+			return nil
+		}
+		c.ID = res.GetInfo().GetId()
+	}
+
+	m := &matcher{ctx: ctx, gwc: gwc, change: c, paths: map[string]string{}}
+	a := &accessChecker{ctx: ctx, gwc: gwc, change: c, groups: map[string][]string{}}
+	for _, sub := range subs {
+		ok, err := m.matches(sub)
+		if err != nil {
+			l.logger.Error().Err(err).Str("subscription", sub.ID).Msg("could not match subscription")
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// the subscriber might have lost access since subscribing
+		ok, err = a.allowed(sub.CreatorID)
+		if err != nil {
+			l.logger.Error().Err(err).Str("subscription", sub.ID).Msg("could not check access of subscriber")
+			continue
+		}
+		if !ok {
+			l.logger.Debug().Str("subscription", sub.ID).Str("userid", sub.CreatorID).Msg("subscriber has no access to the item")
+			continue
+		}
+
+		l.deliveries <- struct{}{}
+		go func(sub *Subscription) {
+			defer func() { <-l.deliveries }()
+			l.send(context.WithoutCancel(ctx), sub, c)
+		}(sub)
+	}
+	return nil
+}
+
+func (l *Listener) send(ctx context.Context, sub *Subscription, c Change) {
+	driveID := storagespace.FormatStorageID(c.ID.GetStorageId(), c.ID.GetSpaceId())
+	itemID := storagespace.FormatResourceID(c.ID)
+
+	err := l.notifier.Send(ctx, sub.NotificationURL, Notification{
+		SubscriptionID:                 sub.ID,
+		SubscriptionExpirationDateTime: sub.ExpirationDateTime,
+		ChangeType:                     c.Type,
+		Resource:                       sub.Resource,
+		ClientState:                    sub.ClientState,
+		ResourceData: ResourceData{
+			ODataType: "#microsoft.graph.driveItem",
+			ODataID:   "drives/" + driveID + "/items/" + itemID,
+			ID:        itemID,
+		},
+	})
+	if err != nil {
+		l.logger.Info().Err(err).Str("subscription", sub.ID).Msg("could not deliver change notification")
+	}
+}
+
+// matcher checks if a change is in the resource of subscriptions, it caches the paths it looked up
+type matcher struct {
+	ctx    context.Context
+	gwc    gateway.GatewayAPIClient
+	change Change
+	paths  map[string]string
+}
+
+func (m *matcher) matches(sub *Subscription) (bool, error) {
+	_, itemID, err := ParseResource(sub.Resource)
+	if err != nil {
+		return false, err
+	}
+	if itemID == "" {
+		return true, nil
+	}
+
+	id, err := storagespace.ParseID(itemID)
+	if err != nil {
+		return false, err
+	}
+	if id.GetOpaqueId() == "" || id.GetOpaqueId() == id.GetSpaceId() {
+		return true, nil
+	}
+	if id.GetOpaqueId() == m.change.ID.GetOpaqueId() {
+		return true, nil
+	}
+
+	folderPath, err := m.path(&id)
+	if err != nil || folderPath == "" {
+		return false, err
+	}
+	for _, ref := range m.change.Refs {
+		if ref.GetResourceId() == nil {
+			continue
+		}
+		basePath, err := m.path(ref.GetResourceId())
+		if err != nil {
+			return false, err
+		}
+		if basePath == "" {
+			continue
+		}
+		p := path.Join(basePath, ref.GetPath())
+		if strings.HasPrefix(p, folderPath+"/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// path returns the path of a resource in its space or an empty string if the resource does not exist
+func (m *matcher) path(id *provider.ResourceId) (string, error) {
+	key := storagespace.FormatResourceID(id)
+	if p, ok := m.paths[key]; ok {
+		return p, nil
+	}
+
+	var p string
+	if id.GetOpaqueId() == "" || id.GetOpaqueId() == id.GetSpaceId() {
+		p = "/"
+	} else {
+		res, err := m.gwc.GetPath(m.ctx, &provider.GetPathRequest{ResourceId: id})
+		if err != nil {
+			return "", err
+		}
+		switch res.GetStatus().GetCode() {
+		case rpc.Code_CODE_OK:
+			p = path.Join("/", res.GetPath())
+		case rpc.Code_CODE_NOT_FOUND:
+		default:
+			return "", errors.New("error getting path: " + res.GetStatus().GetMessage())
+		}
+	}
+
+	m.paths[key] = p
+	return p, nil
+}
+
+// accessChecker checks if users may see a changed item, it caches what it looked up.
+// Users have access if they own the space or if the closest grant for them on the item or one of
+// its ancestors allows to stat it. Space memberships are grants on the space root, shares are grants
+// on the shared item.
+type accessChecker struct {
+	ctx    context.Context
+	gwc    gateway.GatewayAPIClient
+	change Change
+
+	loaded bool
+	owner  string
+	// grants are the grants of the item and its ancestors, the item comes first
+	grants []nodeGrants
+	groups map[string][]string
+}
+
+// nodeGrants are the grants of a node keyed by the id of the grantee
+type nodeGrants struct {
+	permissions map[string]*provider.ResourcePermissions
+	groups      map[string]struct{}
+}
+
+func (a *accessChecker) allowed(userID string) (bool, error) {
+	if err := a.load(); err != nil {
+		return false, err
+	}
+
+	for _, g := range a.grants {
+		if perms, ok := g.permissions[userID]; ok {
+			// denials are grants without permissions
+			return perms.GetStat(), nil
+		}
+		if len(g.groups) == 0 {
+			continue
+		}
+
+		groups, ok := a.groups[userID]
+		if !ok {
+			u, err := utils.GetUserWithContext(a.ctx, &userpb.UserId{OpaqueId: userID}, a.gwc)
+			if err != nil && !utils.IsStatusCodeError(err, rpc.Code_CODE_NOT_FOUND) {
+				return false, err
+			}
+			groups = u.GetGroups()
+			a.groups[userID] = groups
+		}
+		for _, group := range groups {
+			if _, ok := g.groups[group]; !ok {
+				continue
+			}
+			if perms, ok := g.permissions[group]; ok {
+				return perms.GetStat(), nil
+			}
+		}
+	}
+	return a.owner != "" && a.owner == userID, nil
+}
+
+// load looks up the grants of the changed item and its ancestors. Deleted items can't be looked up,
+// the references of the change point to their parents.
+func (a *accessChecker) load() error {
+	if a.loaded {
+		return nil
+	}
+
+	ids := []*provider.ResourceId{a.change.ID}
+	for _, ref := range a.change.Refs {
+		if ref.GetResourceId() != nil {
+			ids = append(ids, ref.GetResourceId())
+		}
+	}
+
+	for _, id := range ids {
+		found, err := a.loadAncestors(id)
+		if err != nil {
+			return err
+		}
+		if found {
+			break
+		}
+	}
+	a.loaded = true
+	return nil
+}
+
+// loadAncestors walks from an item up to the space root, false is returned if the item does not exist
+func (a *accessChecker) loadAncestors(id *provider.ResourceId) (bool, error) {
+	for i := 0; id != nil; i++ {
+		res, err := a.gwc.Stat(a.ctx, &provider.StatRequest{Ref: &provider.Reference{ResourceId: id}})
+		if err != nil {
+			return false, err
+		}
+		switch res.GetStatus().GetCode() {
+		case rpc.Code_CODE_OK:
+		case rpc.Code_CODE_NOT_FOUND:
+			// the item was moved or deleted in the meantime, only the grants found so far apply
+			return i > 0, nil
+		default:
+			return false, errors.New("error stating item: " + res.GetStatus().GetMessage())
+		}
+
+		info := res.GetInfo()
+		if err := a.loadGrants(info.GetId()); err != nil {
+			return false, err
+		}
+
+		id = nil
+		if nodeID := info.GetId(); nodeID.GetOpaqueId() != nodeID.GetSpaceId() {
+			id = info.GetParentId()
+		}
+	}
+	return true, nil
+}
+
+// loadGrants reads the grants of a node, the storage lists a node like a space with the grants of the node
+func (a *accessChecker) loadGrants(id *provider.ResourceId) error {
+	res, err := a.gwc.ListStorageSpaces(a.ctx, &provider.ListStorageSpacesRequest{
+		Opaque: utils.AppendPlainToOpaque(nil, "unrestricted", "true"),
+		Filters: []*provider.ListStorageSpacesRequest_Filter{{
+			Type: provider.ListStorageSpacesRequest_Filter_TYPE_ID,
+			Term: &provider.ListStorageSpacesRequest_Filter_Id{
+				Id: &provider.StorageSpaceId{OpaqueId: storagespace.FormatResourceID(id)},
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	if res.GetStatus().GetCode() != rpc.Code_CODE_OK {
+		return errors.New("error listing grants: " + res.GetStatus().GetMessage())
+	}
+
+	g := nodeGrants{permissions: map[string]*provider.ResourcePermissions{}, groups: map[string]struct{}{}}
+	for _, space := range res.GetStorageSpaces() {
+		if a.owner == "" {
+			a.owner = space.GetOwner().GetId().GetOpaqueId()
+		}
+		for key, v := range map[string]interface{}{"grants": &g.permissions, "groups": &g.groups} {
+			if !utils.ExistsInOpaque(space.GetOpaque(), key) {
+				continue
+			}
+			if err := utils.ReadJSONFromOpaque(space.GetOpaque(), key, v); err != nil {
+				return err
+			}
+		}
+	}
+	a.grants = append(a.grants, g)
+	return nil
+}
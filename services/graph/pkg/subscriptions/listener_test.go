@@ -0,0 +1,201 @@
+package subscriptions_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/mock"
+	microstore "go-micro.dev/v4/store"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
+)
+
+var _ = Describe("Listener", func() {
+	DescribeTable("ChangeFromEvent",
+		func(event interface{}, changeType string, ok bool) {
+			c, got := subscriptions.ChangeFromEvent(event)
+			Expect(got).To(Equal(ok))
+			Expect(c.Type).To(Equal(changeType))
+		},
+		Entry("upload", events.UploadReady{}, subscriptions.ChangeCreated, true),
+		Entry("new version", events.UploadReady{IsVersion: true}, subscriptions.ChangeUpdated, true),
+		Entry("failed upload", events.UploadReady{Failed: true}, "", false),
+		Entry("folder", events.ContainerCreated{}, subscriptions.ChangeCreated, true),
+		Entry("move", events.ItemMoved{}, subscriptions.ChangeUpdated, true),
+		Entry("trash", events.ItemTrashed{}, subscriptions.ChangeDeleted, true),
+		Entry("share", events.ShareCreated{}, subscriptions.ChangeShared, true),
+		Entry("other event", events.SpaceCreated{}, "", false),
+	)
+
+	Describe("Handle", func() {
+		var (
+			store         *subscriptions.Store
+			listener      *subscriptions.Listener
+			gatewayClient *cs3mocks.GatewayAPIClient
+			srv           *httptest.Server
+			mu            sync.Mutex
+			delivered     map[string]subscriptions.Notification
+		)
+
+		BeforeEach(func() {
+			delivered = map[string]subscriptions.Notification{}
+			srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Value []subscriptions.Notification `json:"value"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				mu.Lock()
+				defer mu.Unlock()
+				for _, n := range body.Value {
+					delivered[n.ClientState] = n
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+
+			pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+			gatewayClient = &cs3mocks.GatewayAPIClient{}
+			gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+				"GatewaySelector",
+				"com.owncloud.api.gateway",
+				func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+					return gatewayClient
+				},
+			)
+			gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{
+				Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+				Token:  "service-token",
+			}, nil)
+			parents := map[string]string{"file": "folder", "folder": "space", "other": "space"}
+			gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.StatRequest, _ ...grpc.CallOption) (*provider.StatResponse, error) {
+				id := req.GetRef().GetResourceId()
+				info := &provider.ResourceInfo{Id: id}
+				if parent, ok := parents[id.GetOpaqueId()]; ok {
+					info.ParentId = &provider.ResourceId{StorageId: id.GetStorageId(), SpaceId: id.GetSpaceId(), OpaqueId: parent}
+				} else if id.GetOpaqueId() != id.GetSpaceId() {
+					return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+				}
+				return &provider.StatResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Info: info}, nil
+			})
+			// marie and the physics group got the folder shared, frank is a member of the space but was denied the folder
+			grants := map[string]map[string]*provider.ResourcePermissions{
+				"space":  {"frank": {Stat: true}},
+				"folder": {"marie": {Stat: true}, "physics": {Stat: true}, "frank": {}},
+			}
+			gatewayClient.On("ListStorageSpaces", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.ListStorageSpacesRequest, _ ...grpc.CallOption) (*provider.ListStorageSpacesResponse, error) {
+				id, _ := storagespace.ParseID(req.GetFilters()[0].GetId().GetOpaqueId())
+				opaque := utils.AppendJSONToOpaque(nil, "grants", grants[id.GetOpaqueId()])
+				if id.GetOpaqueId() == "folder" {
+					opaque = utils.AppendJSONToOpaque(opaque, "groups", map[string]struct{}{"physics": {}})
+				}
+				return &provider.ListStorageSpacesResponse{
+					Status: &rpc.Status{Code: rpc.Code_CODE_OK},
+					StorageSpaces: []*provider.StorageSpace{{
+						Opaque: opaque,
+						Owner:  &user.User{Id: &user.UserId{OpaqueId: "alice"}},
+					}},
+				}, nil
+			})
+			gatewayClient.On("GetUser", mock.Anything, mock.Anything).Return(func(_ context.Context, req *user.GetUserRequest, _ ...grpc.CallOption) (*user.GetUserResponse, error) {
+				u := &user.User{Id: req.GetUserId()}
+				if req.GetUserId().GetOpaqueId() == "erin" {
+					u.Groups = []string{"physics"}
+				}
+				return &user.GetUserResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, User: u}, nil
+			})
+			gatewayClient.On("GetPath", mock.Anything, mock.Anything).Return(func(_ context.Context, req *provider.GetPathRequest, _ ...grpc.CallOption) (*provider.GetPathResponse, error) {
+				paths := map[string]string{"folder": "./folder", "other": "./other"}
+				p, ok := paths[req.GetResourceId().GetOpaqueId()]
+				if !ok {
+					return &provider.GetPathResponse{Status: &rpc.Status{Code: rpc.Code_CODE_NOT_FOUND}}, nil
+				}
+				return &provider.GetPathResponse{Status: &rpc.Status{Code: rpc.Code_CODE_OK}, Path: p}, nil
+			})
+
+			store = subscriptions.NewStore(microstore.NewMemoryStore())
+			notifier := subscriptions.NewNotifier(config.Subscriptions{Timeout: time.Second}, log.NopLogger(), subscriptions.WithHTTPClient(srv.Client()))
+			listener = subscriptions.NewListener(store, notifier, gatewaySelector, config.ServiceAccount{ServiceAccountID: "service", ServiceAccountSecret: "secret"}, log.NopLogger())
+		})
+
+		AfterEach(func() {
+			srv.Close()
+		})
+
+		subscribe := func(state, creator, resource, changeType string) {
+			sub := subscription(creator, resource)
+			sub.NotificationURL = srv.URL
+			sub.ClientState = state
+			sub.ChangeType = changeType
+			Expect(store.Create(sub)).To(Succeed())
+		}
+
+		It("notifies the matching subscriptions of users with access to the item", func() {
+			subscribe("drive", "alice", "/drives/storage$space/root", "created")
+			subscribe("folder", "alice", "/drives/storage$space/items/storage$space!folder", "created,updated")
+			subscribe("other-folder", "alice", "/drives/storage$space/items/storage$space!other", "created")
+			subscribe("deletes", "alice", "/drives/storage$space/root", "deleted")
+			subscribe("no-member", "bob", "/drives/storage$space/root", "created")
+			subscribe("other-space", "alice", "/drives/storage$other/root", "created")
+			subscribe("share", "marie", "/drives/storage$space/items/storage$space!folder", "created")
+			subscribe("group-share", "erin", "/drives/storage$space/items/storage$space!folder", "created")
+			subscribe("denied", "frank", "/drives/storage$space/root", "created")
+			subscribe("gone", "alice", "/drives/storage$space/items/storage$space!gone", "created")
+
+			change, ok := subscriptions.ChangeFromEvent(events.UploadReady{
+				FileRef:    &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "space"}, Path: "./folder/file.txt"},
+				ResourceID: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+			})
+			Expect(ok).To(BeTrue())
+			Expect(listener.Handle(context.Background(), change)).To(Succeed())
+
+			Eventually(func() map[string]subscriptions.Notification {
+				mu.Lock()
+				defer mu.Unlock()
+				return delivered
+			}).Should(HaveLen(4))
+			Consistently(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(delivered)
+			}, 100*time.Millisecond).Should(Equal(4))
+
+			mu.Lock()
+			defer mu.Unlock()
+			Expect(delivered).To(HaveKey("drive"))
+			Expect(delivered).To(HaveKey("folder"))
+			Expect(delivered).To(HaveKey("share"))
+			Expect(delivered).To(HaveKey("group-share"))
+			n := delivered["folder"]
+			Expect(n.ChangeType).To(Equal(subscriptions.ChangeCreated))
+			Expect(n.Resource).To(Equal("/drives/storage$space/items/storage$space!folder"))
+			Expect(n.ResourceData.ID).To(Equal("storage$space!file"))
+			Expect(n.ResourceData.ODataID).To(Equal("drives/storage$space/items/storage$space!file"))
+		})
+
+		It("skips spaces without subscriptions", func() {
+			change, _ := subscriptions.ChangeFromEvent(events.ItemTrashed{
+				ID:  &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+				Ref: &provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}},
+			})
+			Expect(listener.Handle(context.Background(), change)).To(Succeed())
+			gatewayClient.AssertNotCalled(GinkgoT(), "Authenticate", mock.Anything, mock.Anything)
+		})
+	})
+})
@@ -0,0 +1,121 @@
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/webhook"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
+)
+
+// _maxValidationResponse limits how much of the response to a validation request is read
+const _maxValidationResponse = 1024
+
+// Notification is a change notification posted to the notification URL of a subscription
+type Notification struct {
+	SubscriptionID                 string       `json:"subscriptionId"`
+	SubscriptionExpirationDateTime time.Time    `json:"subscriptionExpirationDateTime"`
+	ChangeType                     string       `json:"changeType"`
+	Resource                       string       `json:"resource"`
+	ClientState                    string       `json:"clientState,omitempty"`
+	ResourceData                   ResourceData `json:"resourceData"`
+}
+
+// ResourceData identifies the drive item which changed
+type ResourceData struct {
+	ODataType string `json:"@odata.type"`
+	ODataID   string `json:"@odata.id"`
+	ID        string `json:"id"`
+}
+
+// Notifier validates notification URLs and posts change notifications to them
+type Notifier struct {
+	client           *http.Client
+	validationClient *http.Client
+	sender           webhook.Sender
+}
+
+// NotifierOption configures a Notifier
+type NotifierOption func(n *Notifier)
+
+// WithHTTPClient replaces the http client of the requests, the client has to refuse internal addresses itself
+func WithHTTPClient(c *http.Client) NotifierOption {
+	return func(n *Notifier) {
+		n.client = c
+		n.validationClient = c
+	}
+}
+
+// NewNotifier creates a Notifier. The notification URLs are chosen by users, so the requests
+// refuse connections to internal addresses.
+func NewNotifier(cfg config.Subscriptions, logger log.Logger, opts ...NotifierOption) *Notifier {
+	n := &Notifier{
+		client:           webhook.NewClient(cfg.Timeout, cfg.InsecureSkipVerify, false),
+		validationClient: webhook.NewClient(cfg.ValidationTimeout, cfg.InsecureSkipVerify, false),
+	}
+	for _, o := range opts {
+		o(n)
+	}
+	n.sender = webhook.NewSender(n.client, cfg.MaxRetries, cfg.RetryBackoff, logger)
+	return n
+}
+
+// Validate makes sure the notification URL is willing to receive change notifications.
+// A validation token is sent in the 'validationToken' query parameter, the URL must answer
+// with status 200 and the token as plain text.
+func (n *Notifier) Validate(ctx context.Context, notificationURL string) error {
+	u, err := url.Parse(notificationURL)
+	if err != nil {
+		return err
+	}
+	token := uuid.New().String()
+	q := u.Query()
+	q.Set("validationToken", token)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), http.NoBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	res, err := n.validationClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("validation request returned status %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, _maxValidationResponse))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return errors.New("validation request did not return the validation token")
+	}
+	return nil
+}
+
+// Send posts change notifications to a notification URL, failed requests are retried
+func (n *Notifier) Send(ctx context.Context, notificationURL string, notifications ...Notification) error {
+	body, err := json.Marshal(struct {
+		Value []Notification `json:"value"`
+	}{Value: notifications})
+	if err != nil {
+		return err
+	}
+
+	return n.sender.Send(ctx, notificationURL, "", body)
+}
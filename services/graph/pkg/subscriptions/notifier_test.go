@@ -0,0 +1,110 @@
+package subscriptions_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/webhook"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/config"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
+)
+
+var _ = Describe("Notifier", func() {
+	var notifier *subscriptions.Notifier
+
+	BeforeEach(func() {
+		notifier = subscriptions.NewNotifier(config.Subscriptions{
+			ValidationTimeout: time.Second,
+			Timeout:           time.Second,
+			MaxRetries:        2,
+			RetryBackoff:      time.Millisecond,
+		}, log.NopLogger(), subscriptions.WithHTTPClient(&http.Client{Timeout: time.Second})) // the test servers listen on the loopback interface
+	})
+
+	It("refuses internal addresses", func() {
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+		}))
+		defer srv.Close()
+
+		n := subscriptions.NewNotifier(config.Subscriptions{ValidationTimeout: time.Second, Timeout: time.Second, MaxRetries: 2}, log.NopLogger())
+		Expect(n.Validate(context.Background(), srv.URL)).To(MatchError(webhook.ErrAddressNotAllowed))
+		Expect(n.Send(context.Background(), srv.URL, subscriptions.Notification{})).To(MatchError(webhook.ErrAddressNotAllowed))
+		Expect(requests.Load()).To(BeZero())
+	})
+
+	Describe("Validate", func() {
+		It("accepts urls echoing the validation token", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte(r.URL.Query().Get("validationToken")))
+			}))
+			defer srv.Close()
+
+			Expect(notifier.Validate(context.Background(), srv.URL+"/hooks?tenant=1")).To(Succeed())
+		})
+
+		It("rejects urls not echoing the validation token", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			}))
+			defer srv.Close()
+
+			Expect(notifier.Validate(context.Background(), srv.URL)).ToNot(Succeed())
+		})
+
+		It("rejects urls not answering with 200", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				_, _ = w.Write([]byte(r.URL.Query().Get("validationToken")))
+			}))
+			defer srv.Close()
+
+			Expect(notifier.Validate(context.Background(), srv.URL)).ToNot(Succeed())
+		})
+	})
+
+	Describe("Send", func() {
+		It("posts the notifications and retries server errors", func() {
+			var attempts atomic.Int32
+			var received struct {
+				Value []subscriptions.Notification `json:"value"`
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if attempts.Add(1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_ = json.NewDecoder(r.Body).Decode(&received)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer srv.Close()
+
+			err := notifier.Send(context.Background(), srv.URL, subscriptions.Notification{SubscriptionID: "sub", ChangeType: "created", ClientState: "secret"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(attempts.Load()).To(Equal(int32(2)))
+			Expect(received.Value).To(ConsistOf(HaveField("ClientState", "secret")))
+		})
+
+		It("does not retry client errors", func() {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				w.WriteHeader(http.StatusGone)
+			}))
+			defer srv.Close()
+
+			Expect(notifier.Send(context.Background(), srv.URL, subscriptions.Notification{})).ToNot(Succeed())
+			Expect(attempts.Load()).To(Equal(int32(1)))
+		})
+	})
+})
@@ -0,0 +1,290 @@
+// Package subscriptions manages the change notification subscriptions of the graph API and
+// delivers the change notifications to the notification URLs of the subscribers.
+package subscriptions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/indexstore"
+)
+
+// The types of changes clients can subscribe to
+const (
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+	ChangeShared  = "shared"
+)
+
+const (
+	_maxClientStateLength = 128
+
+	_subscriptionPrefix = "subscription/"
+	_userPrefix         = "user/"
+	_spacePrefix        = "space/"
+)
+
+var _changeTypes = []string{ChangeCreated, ChangeUpdated, ChangeDeleted, ChangeShared}
+
+// ErrNotFound is returned when a subscription does not exist, has expired or belongs to another user
+var ErrNotFound = errors.New("subscription not found")
+
+// ValidationError is returned for subscriptions with invalid properties
+type ValidationError = indexstore.ValidationError
+
+// Subscription is the registration of a notification URL for changes of a drive or a drive item
+type Subscription struct {
+	ID                 string    `json:"id"`
+	Resource           string    `json:"resource"`
+	ChangeType         string    `json:"changeType"`
+	NotificationURL    string    `json:"notificationUrl"`
+	ExpirationDateTime time.Time `json:"expirationDateTime"`
+	ClientState        string    `json:"clientState,omitempty"`
+	CreatorID          string    `json:"creatorId,omitempty"`
+}
+
+// Validate checks the resource, the change types, the notification URL and the client state
+func (s *Subscription) Validate() error {
+	if _, _, err := ParseResource(s.Resource); err != nil {
+		return err
+	}
+
+	changeTypes := s.ChangeTypes()
+	if len(changeTypes) == 0 {
+		return indexstore.NewValidationError("changeType must not be empty")
+	}
+	for i, ct := range changeTypes {
+		if !slices.Contains(_changeTypes, ct) {
+			return indexstore.NewValidationError("invalid changeType '%s', must be a comma separated list of '%s'", ct, strings.Join(_changeTypes, "', '"))
+		}
+		if slices.Contains(changeTypes[:i], ct) {
+			return indexstore.NewValidationError("duplicate changeType '%s'", ct)
+		}
+	}
+
+	u, err := url.Parse(s.NotificationURL)
+	switch {
+	case err != nil || u.Host == "":
+		return indexstore.NewValidationError("notificationUrl must be an absolute url")
+	case u.Scheme != "http" && u.Scheme != "https":
+		return indexstore.NewValidationError("notificationUrl must use http or https")
+	}
+
+	if len(s.ClientState) > _maxClientStateLength {
+		return indexstore.NewValidationError("clientState must not be longer than %d characters", _maxClientStateLength)
+	}
+
+	return nil
+}
+
+// ChangeTypes returns the change types the subscription is registered for
+func (s *Subscription) ChangeTypes() []string {
+	var changeTypes []string
+	for _, ct := range strings.Split(s.ChangeType, ",") {
+		if ct = strings.TrimSpace(ct); ct != "" {
+			changeTypes = append(changeTypes, ct)
+		}
+	}
+	return changeTypes
+}
+
+// Expired returns true if the subscription expired
+func (s *Subscription) Expired() bool {
+	return !s.ExpirationDateTime.After(time.Now())
+}
+
+// Expiration checks the requested expiration of a subscription, subscriptions without expiration get the maximum lifetime
+func Expiration(requested time.Time, maxLifetime time.Duration) (time.Time, error) {
+	now := time.Now().UTC()
+	maxExpiration := now.Add(maxLifetime)
+	switch {
+	case requested.IsZero():
+		return maxExpiration, nil
+	case !requested.After(now):
+		return time.Time{}, indexstore.NewValidationError("expirationDateTime must be in the future")
+	case requested.After(maxExpiration):
+		return time.Time{}, indexstore.NewValidationError("expirationDateTime must not be later than %s", maxExpiration.Format(time.RFC3339))
+	}
+	return requested.UTC(), nil
+}
+
+// ParseResource returns the drive id and, for drive items, the item id of a subscription resource.
+// Supported resources are '/drives/{driveID}/root' and '/drives/{driveID}/items/{itemID}'.
+func ParseResource(resource string) (string, string, error) {
+	rest, ok := strings.CutPrefix(strings.TrimPrefix(resource, "/"), "drives/")
+	if !ok {
+		return "", "", indexstore.NewValidationError("resource must be '/drives/{driveID}/root' or '/drives/{driveID}/items/{itemID}'")
+	}
+
+	driveID, itemPath, _ := strings.Cut(rest, "/")
+	var itemID string
+	switch {
+	case itemPath == "root":
+	case strings.HasPrefix(itemPath, "items/") && !strings.Contains(strings.TrimPrefix(itemPath, "items/"), "/"):
+		itemID = strings.TrimPrefix(itemPath, "items/")
+	default:
+		return "", "", indexstore.NewValidationError("resource must be '/drives/{driveID}/root' or '/drives/{driveID}/items/{itemID}'")
+	}
+
+	if id, err := storagespace.ParseID(driveID); err != nil || id.GetSpaceId() == "" {
+		return "", "", indexstore.NewValidationError("invalid driveID in resource")
+	}
+	if itemID != "" {
+		if id, err := storagespace.ParseID(itemID); err != nil || id.GetSpaceId() == "" {
+			return "", "", indexstore.NewValidationError("invalid itemID in resource")
+		}
+	}
+
+	return driveID, itemID, nil
+}
+
+// Store persists subscriptions
+type Store struct {
+	store *indexstore.Store
+}
+
+// NewStore creates a Store which keeps the subscriptions in the given store
+func NewStore(store microstore.Store) *Store {
+	return &Store{store: indexstore.New(store)}
+}
+
+// Get returns a subscription, expired subscriptions are removed
+func (s *Store) Get(id string) (*Subscription, error) {
+	value, err := s.store.Read(_subscriptionPrefix + id)
+	switch {
+	case errors.Is(err, indexstore.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	sub := &Subscription{}
+	if err := json.Unmarshal(value, sub); err != nil {
+		return nil, err
+	}
+
+	if sub.Expired() {
+		if err := s.Delete(sub); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	return sub, nil
+}
+
+// ListByUser returns the subscriptions created by a user
+func (s *Store) ListByUser(userID string) ([]*Subscription, error) {
+	return s.list(_userPrefix + userID + "/")
+}
+
+// ListBySpace returns the subscriptions of the drive and the drive items of a space
+func (s *Store) ListBySpace(spaceID string) ([]*Subscription, error) {
+	return s.list(_spacePrefix + spaceID + "/")
+}
+
+// Create validates and stores a new subscription
+func (s *Store) Create(sub *Subscription) error {
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+
+	sub.ID = uuid.New().String()
+	return s.write(sub)
+}
+
+// Update validates and stores an existing subscription
+func (s *Store) Update(sub *Subscription) error {
+	if _, err := s.Get(sub.ID); err != nil {
+		return err
+	}
+
+	if err := sub.Validate(); err != nil {
+		return err
+	}
+
+	return s.write(sub)
+}
+
+// Delete removes a subscription
+func (s *Store) Delete(sub *Subscription) error {
+	keys := []string{userKey(sub), _subscriptionPrefix + sub.ID}
+	if key, err := spaceKey(sub); err == nil {
+		keys = append([]string{key}, keys...)
+	}
+	return s.store.Delete(keys...)
+}
+
+func (s *Store) write(sub *Subscription) error {
+	value, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+
+	key, err := spaceKey(sub)
+	if err != nil {
+		return err
+	}
+
+	// the indexes only contain the id, so updates only need to write the subscription
+	if err := s.store.Write(_subscriptionPrefix+sub.ID, value, 0); err != nil {
+		return err
+	}
+	if err := s.store.Write(userKey(sub), []byte(sub.ID), 0); err != nil {
+		return err
+	}
+	return s.store.Write(key, []byte(sub.ID), 0)
+}
+
+func (s *Store) list(prefix string) ([]*Subscription, error) {
+	keys, err := s.store.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]*Subscription, 0, len(keys))
+	for _, key := range keys {
+		sub, err := s.Get(indexstore.ID(key))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			// the subscription expired or the index was left behind
+			if err := s.store.Delete(key); err != nil {
+				return nil, err
+			}
+			continue
+		case err != nil:
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+
+	sort.Slice(subs, func(i, j int) bool {
+		return subs[i].ExpirationDateTime.Before(subs[j].ExpirationDateTime)
+	})
+	return subs, nil
+}
+
+func userKey(sub *Subscription) string {
+	return _userPrefix + sub.CreatorID + "/" + sub.ID
+}
+
+func spaceKey(sub *Subscription) (string, error) {
+	driveID, _, err := ParseResource(sub.Resource)
+	if err != nil {
+		return "", err
+	}
+	id, err := storagespace.ParseID(driveID)
+	if err != nil {
+		return "", err
+	}
+	return _spacePrefix + id.GetSpaceId() + "/" + sub.ID, nil
+}
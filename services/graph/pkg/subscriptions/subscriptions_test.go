@@ -0,0 +1,139 @@
+package subscriptions_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/services/graph/pkg/subscriptions"
+)
+
+func subscription(creator, resource string) *subscriptions.Subscription {
+	return &subscriptions.Subscription{
+		Resource:           resource,
+		ChangeType:         "created,updated",
+		NotificationURL:    "https://hooks.example.com/ocis",
+		ExpirationDateTime: time.Now().Add(time.Hour),
+		CreatorID:          creator,
+	}
+}
+
+var _ = Describe("Subscriptions", func() {
+	Describe("Validate", func() {
+		DescribeTable("valid subscriptions",
+			func(resource, changeType string) {
+				sub := subscription("alice", resource)
+				sub.ChangeType = changeType
+				Expect(sub.Validate()).To(Succeed())
+			},
+			Entry("drive", "/drives/storage$space/root", "created"),
+			Entry("drive item", "/drives/storage$space/items/storage$space!item", "created, updated,deleted"),
+			Entry("without leading slash", "drives/storage$space/root", "shared"),
+		)
+
+		DescribeTable("invalid subscriptions",
+			func(mutate func(*subscriptions.Subscription)) {
+				sub := subscription("alice", "/drives/storage$space/root")
+				mutate(sub)
+				Expect(sub.Validate()).To(BeAssignableToTypeOf(subscriptions.ValidationError{}))
+			},
+			Entry("other resource", func(s *subscriptions.Subscription) { s.Resource = "/me/drive/root" }),
+			Entry("drive without root", func(s *subscriptions.Subscription) { s.Resource = "/drives/storage$space" }),
+			Entry("item children", func(s *subscriptions.Subscription) {
+				s.Resource = "/drives/storage$space/items/storage$space!item/children"
+			}),
+			Entry("empty change type", func(s *subscriptions.Subscription) { s.ChangeType = " , " }),
+			Entry("unknown change type", func(s *subscriptions.Subscription) { s.ChangeType = "created,renamed" }),
+			Entry("duplicate change type", func(s *subscriptions.Subscription) { s.ChangeType = "created,created" }),
+			Entry("relative url", func(s *subscriptions.Subscription) { s.NotificationURL = "/hooks" }),
+			Entry("other scheme", func(s *subscriptions.Subscription) { s.NotificationURL = "ftp://hooks.example.com" }),
+			Entry("long client state", func(s *subscriptions.Subscription) { s.ClientState = strings.Repeat("a", 129) }),
+		)
+	})
+
+	Describe("Expiration", func() {
+		It("defaults to the maximum lifetime", func() {
+			expiration, err := subscriptions.Expiration(time.Time{}, time.Hour)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(expiration).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+		})
+
+		It("rejects expirations in the past and beyond the maximum lifetime", func() {
+			_, err := subscriptions.Expiration(time.Now().Add(-time.Minute), time.Hour)
+			Expect(err).To(BeAssignableToTypeOf(subscriptions.ValidationError{}))
+
+			_, err = subscriptions.Expiration(time.Now().Add(2*time.Hour), time.Hour)
+			Expect(err).To(BeAssignableToTypeOf(subscriptions.ValidationError{}))
+		})
+	})
+
+	Describe("Store", func() {
+		var store *subscriptions.Store
+
+		BeforeEach(func() {
+			store = subscriptions.NewStore(microstore.NewMemoryStore())
+		})
+
+		It("lists the subscriptions by user and by space", func() {
+			drive := subscription("alice", "/drives/storage$space/root")
+			item := subscription("alice", "/drives/storage$space/items/storage$space!item")
+			other := subscription("bob", "/drives/storage$other/root")
+			for _, s := range []*subscriptions.Subscription{drive, item, other} {
+				Expect(store.Create(s)).To(Succeed())
+				Expect(s.ID).ToNot(BeEmpty())
+			}
+
+			subs, err := store.ListByUser("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(HaveLen(2))
+
+			subs, err = store.ListBySpace("space")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(HaveLen(2))
+
+			subs, err = store.ListBySpace("other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(ConsistOf(HaveField("CreatorID", "bob")))
+		})
+
+		It("renews and deletes subscriptions", func() {
+			sub := subscription("alice", "/drives/storage$space/root")
+			Expect(store.Create(sub)).To(Succeed())
+
+			sub.ExpirationDateTime = time.Now().Add(48 * time.Hour).UTC()
+			Expect(store.Update(sub)).To(Succeed())
+			got, err := store.Get(sub.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(got.ExpirationDateTime).To(BeTemporally("==", sub.ExpirationDateTime))
+
+			Expect(store.Delete(sub)).To(Succeed())
+			_, err = store.Get(sub.ID)
+			Expect(err).To(MatchError(subscriptions.ErrNotFound))
+			subs, err := store.ListBySpace("space")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(BeEmpty())
+		})
+
+		It("removes expired subscriptions", func() {
+			sub := subscription("alice", "/drives/storage$space/root")
+			Expect(store.Create(sub)).To(Succeed())
+			sub.ExpirationDateTime = time.Now().Add(-time.Minute)
+			Expect(store.Update(sub)).To(Succeed())
+
+			_, err := store.Get(sub.ID)
+			Expect(err).To(MatchError(subscriptions.ErrNotFound))
+			subs, err := store.ListByUser("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(subs).To(BeEmpty())
+		})
+
+		It("does not update unknown subscriptions", func() {
+			sub := subscription("alice", "/drives/storage$space/root")
+			sub.ID = "unknown"
+			Expect(store.Update(sub)).To(MatchError(subscriptions.ErrNotFound))
+		})
+	})
+})
@@ -22,12 +22,14 @@ type Channel interface {
 
 // Message represent the already rendered message including the user id opaqueID
 type Message struct {
-	Sender       string
-	Recipient    []string
-	Subject      string
-	TextBody     string
-	HTMLBody     string
-	AttachInline map[string][]byte
+	Sender        string
+	SenderAddress string
+	Recipient     []string
+	RecipientID   string
+	Subject       string
+	TextBody      string
+	HTMLBody      string
+	AttachInline  map[string][]byte
 }
 
 // NewMailChannel instantiates a new mail communication channel.
@@ -115,8 +117,18 @@ func (m Mail) SendMessage(_ context.Context, message *Message) error {
 		return err
 	}
 
+	from := m.smtpAddress
+	if message.SenderAddress != "" {
+		// themes can replace the configured sender address
+		a, err := stdmail.ParseAddress(message.SenderAddress)
+		if err != nil {
+			return err
+		}
+		from = *a
+	}
+
 	email := mail.NewMSG()
-	email.SetFrom(appendSender(message.Sender, m.smtpAddress)).AddTo(message.Recipient...)
+	email.SetFrom(appendSender(message.Sender, from)).AddTo(message.Recipient...)
 	email.SetSubject(message.Subject)
 	email.SetBody(mail.TextPlain, message.TextBody)
 	if message.HTMLBody != "" {
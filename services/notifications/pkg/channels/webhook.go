@@ -0,0 +1,215 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-micro.dev/v4/metadata"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/middleware"
+	"github.com/owncloud/ocis/v2/ocis-pkg/webhook"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/config"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+)
+
+// WebhookPayload is the JSON document posted to webhook URLs.
+// The text field contains subject and body, it is understood by the incoming webhooks of most chat systems.
+type WebhookPayload struct {
+	Text      string           `json:"text"`
+	Subject   string           `json:"subject"`
+	Body      string           `json:"body"`
+	HTMLBody  string           `json:"html,omitempty"`
+	Recipient WebhookRecipient `json:"recipient"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+// WebhookRecipient identifies the user a webhook notification is meant for
+type WebhookRecipient struct {
+	ID   string `json:"id"`
+	Mail string `json:"mail,omitempty"`
+}
+
+// errWebhookQueueFull is returned when a message can't be queued because the workers don't keep up
+var errWebhookQueueFull = errors.New("webhook queue is full")
+
+// NewWebhookChannel instantiates a new webhook communication channel. The messages are posted by a fixed number of
+// workers which run until the context is done, messages still queued then are dropped.
+func NewWebhookChannel(ctx context.Context, cfg config.Config, valueService settingssvc.ValueService, logger log.Logger) Channel {
+	conf := cfg.Notifications.Webhook
+	w := Webhook{
+		conf:         conf,
+		valueService: valueService,
+		logger:       logger,
+		sender:       webhook.NewSender(webhook.NewClient(conf.Timeout, conf.InsecureSkipVerify, true), conf.MaxRetries, conf.RetryBackoff, logger),
+		// users must not be able to reach internal systems through their webhook url
+		userSender: webhook.NewSender(webhook.NewClient(conf.Timeout, conf.InsecureSkipVerify, false), conf.MaxRetries, conf.RetryBackoff, logger),
+		queue:      make(chan delivery, conf.QueueSize),
+	}
+	for range max(conf.Workers, 1) {
+		go w.work(ctx)
+	}
+	return w
+}
+
+// Webhook is the communication channel posting messages as JSON to a URL.
+type Webhook struct {
+	conf         config.Webhook
+	valueService settingssvc.ValueService
+	sender       webhook.Sender
+	userSender   webhook.Sender
+	logger       log.Logger
+	queue        chan delivery
+}
+
+// delivery is a payload waiting to be posted to a webhook URL
+type delivery struct {
+	sender      webhook.Sender
+	target      string
+	secret      string
+	body        []byte
+	recipientID string
+}
+
+// SendMessage queues a message for the webhook URL of the recipient, it is posted by the workers of the channel.
+// Messages which are not addressed to a user are skipped, an error is returned if the queue is full.
+func (w Webhook) SendMessage(ctx context.Context, message *Message) error {
+	d, err := w.newDelivery(ctx, message)
+	if err != nil || d == nil {
+		return err
+	}
+
+	select {
+	case w.queue <- *d:
+		return nil
+	default:
+		return fmt.Errorf("%w: dropping message for %s", errWebhookQueueFull, message.RecipientID)
+	}
+}
+
+// newDelivery returns the delivery of a message, nil if the message is not sent to a webhook
+func (w Webhook) newDelivery(ctx context.Context, message *Message) (*delivery, error) {
+	if message.RecipientID == "" {
+		return nil, nil
+	}
+
+	target, secret, userURL := w.getURL(ctx, message.RecipientID)
+	if target == "" {
+		return nil, nil
+	}
+	sender := w.sender
+	if userURL {
+		sender = w.userSender
+	}
+
+	payload := WebhookPayload{
+		Text:     strings.TrimSpace(message.Subject + "\n\n" + message.TextBody),
+		Subject:  message.Subject,
+		Body:     message.TextBody,
+		HTMLBody: message.HTMLBody,
+		Recipient: WebhookRecipient{
+			ID: message.RecipientID,
+		},
+		Timestamp: time.Now().UTC(),
+	}
+	if len(message.Recipient) > 0 {
+		payload.Recipient.Mail = message.Recipient[0]
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery{sender: sender, target: target, secret: secret, body: body, recipientID: message.RecipientID}, nil
+}
+
+// work posts the queued deliveries until the context is done
+func (w Webhook) work(ctx context.Context) {
+	for {
+		select {
+		case d := <-w.queue:
+			if err := d.sender.Send(ctx, d.target, d.secret, d.body); err != nil {
+				w.logger.Error().Err(err).Str("userId", d.recipientID).Msg("failed to post webhook notification")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// getURL returns the webhook URL of the user and the secret the user got when registering it if users may
+// set their own, the configured URL and secret otherwise. The third return value is true for the URL of the user.
+func (w Webhook) getURL(ctx context.Context, userID string) (string, string, bool) {
+	if !w.conf.AllowUserURLs {
+		return w.conf.URL, w.conf.Secret, false
+	}
+
+	userURL := strings.TrimSpace(w.getValue(ctx, userID, defaults.SettingUUIDProfileWebhookURL))
+	if userURL == "" {
+		return w.conf.URL, w.conf.Secret, false
+	}
+	if err := validateURL(userURL); err != nil {
+		w.logger.Debug().Err(err).Str("userId", userID).Msg("ignoring invalid webhook url")
+		return w.conf.URL, w.conf.Secret, false
+	}
+	// the instance wide secret must not be handed to the urls of users
+	secret := w.getValue(ctx, userID, defaults.SettingUUIDProfileWebhookSecret)
+	if secret == "" {
+		w.logger.Debug().Str("userId", userID).Msg("ignoring webhook url without secret")
+		return w.conf.URL, w.conf.Secret, false
+	}
+	return userURL, secret, true
+}
+
+// getValue returns the value of a string setting of a user or an empty string
+func (w Webhook) getValue(ctx context.Context, userID, settingID string) string {
+	resp, err := w.valueService.GetValueByUniqueIdentifiers(
+		metadata.Set(ctx, middleware.AccountID, userID),
+		&settingssvc.GetValueByUniqueIdentifiersRequest{
+			AccountUuid: userID,
+			SettingId:   settingID,
+		},
+	)
+	if err != nil {
+		return ""
+	}
+	return resp.GetValue().GetValue().GetStringValue()
+}
+
+func validateURL(target string) error {
+	u, err := url.Parse(target)
+	switch {
+	case err != nil:
+		return err
+	case u.Scheme != "http" && u.Scheme != "https":
+		return errors.New("webhook url must use http or https")
+	case u.Host == "":
+		return errors.New("webhook url must contain a host")
+	}
+	return nil
+}
+
+// NewMultiChannel combines channels, messages are sent through all of them.
+func NewMultiChannel(channels ...Channel) Channel {
+	return Multi(channels)
+}
+
+// Multi is a communication channel sending messages through several channels.
+type Multi []Channel
+
+// SendMessage sends the message through all channels, a failing channel does not stop the others.
+func (m Multi) SendMessage(ctx context.Context, message *Message) error {
+	var errs []error
+	for _, c := range m {
+		if err := c.SendMessage(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
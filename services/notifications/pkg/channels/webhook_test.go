@@ -0,0 +1,294 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-micro.dev/v4/client"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/webhook"
+	settingsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/settings/v0"
+	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/config"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+)
+
+func newTestWebhook(t *testing.T, conf config.Webhook, userURL, userSecret string) Webhook {
+	wh := newWebhook(t, conf, userURL, userSecret)
+	// the test servers listen on the loopback interface
+	wh.userSender = wh.sender
+	return wh
+}
+
+func newWebhook(t *testing.T, conf config.Webhook, userURL, userSecret string) Webhook {
+	vs := &settingssvc.MockValueService{
+		GetValueByUniqueIdentifiersFunc: func(ctx context.Context, req *settingssvc.GetValueByUniqueIdentifiersRequest, opts ...client.CallOption) (*settingssvc.GetValueResponse, error) {
+			value := userURL
+			if req.GetSettingId() == defaults.SettingUUIDProfileWebhookSecret {
+				value = userSecret
+			}
+			return &settingssvc.GetValueResponse{
+				Value: &settingsmsg.ValueWithIdentifier{
+					Value: &settingsmsg.Value{
+						Value: &settingsmsg.Value_StringValue{StringValue: value},
+					},
+				},
+			}, nil
+		},
+	}
+	if conf.Timeout == 0 {
+		conf.Timeout = time.Second
+	}
+	cfg := config.Config{Notifications: config.Notifications{Webhook: conf}}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewWebhookChannel(ctx, cfg, vs, log.NopLogger()).(Webhook)
+}
+
+// send posts a message right away instead of queueing it
+func send(wh Webhook, message *Message) error {
+	d, err := wh.newDelivery(context.Background(), message)
+	if err != nil || d == nil {
+		return err
+	}
+	return d.sender.Send(context.Background(), d.target, d.secret, d.body)
+}
+
+func TestWebhook_SendMessage(t *testing.T) {
+	var received WebhookPayload
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(webhook.SignatureHeader)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+		if want := webhook.Sign("secret", body); signature != want {
+			t.Errorf("signature = %v, want %v", signature, want)
+		}
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhook(t, config.Webhook{URL: srv.URL, Secret: "secret"}, "", "")
+	err := send(wh, &Message{
+		Recipient:   []string{"marie@example.com"},
+		RecipientID: "marie",
+		Subject:     "Resource shared",
+		TextBody:    "Einstein shared secrets with you",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Text != "Resource shared\n\nEinstein shared secrets with you" {
+		t.Errorf("text = %v", received.Text)
+	}
+	if received.Recipient.ID != "marie" || received.Recipient.Mail != "marie@example.com" {
+		t.Errorf("recipient = %v", received.Recipient)
+	}
+	if signature == "" {
+		t.Error("missing signature")
+	}
+}
+
+func TestWebhook_Queue(t *testing.T) {
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		started <- struct{}{}
+		<-release
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhook(t, config.Webhook{URL: srv.URL, Workers: 1, QueueSize: 1}, "", "")
+	message := &Message{RecipientID: "marie", Subject: "subject"}
+
+	// the message is sent in the background, the next one waits for the worker
+	if err := wh.SendMessage(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := wh.SendMessage(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	if err := wh.SendMessage(context.Background(), message); !errors.Is(err, errWebhookQueueFull) {
+		t.Errorf("SendMessage() error = %v, want %v", err, errWebhookQueueFull)
+	}
+
+	close(release)
+	<-started
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %v, want 2", got)
+	}
+}
+
+func TestWebhook_Retry(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantRequests int32
+	}{
+		{
+			name:         "retries server errors",
+			statuses:     []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			wantErr:      false,
+			wantRequests: 3,
+		},
+		{
+			name:         "gives up after max retries",
+			statuses:     []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			wantErr:      true,
+			wantRequests: 3,
+		},
+		{
+			name:         "does not retry client errors",
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := requests.Add(1)
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			wh := newTestWebhook(t, config.Webhook{URL: srv.URL, MaxRetries: 2, RetryBackoff: time.Millisecond}, "", "")
+			err := send(wh, &Message{RecipientID: "marie", Subject: "subject"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWebhook_URL(t *testing.T) {
+	var instanceRequests, userRequests atomic.Int32
+	instance := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceRequests.Add(1)
+	}))
+	defer instance.Close()
+	personal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userRequests.Add(1)
+	}))
+	defer personal.Close()
+
+	tests := []struct {
+		name         string
+		conf         config.Webhook
+		userURL      string
+		userSecret   string
+		recipientID  string
+		wantInstance int32
+		wantUser     int32
+	}{
+		{
+			name:         "instance url",
+			conf:         config.Webhook{URL: instance.URL},
+			userURL:      personal.URL,
+			recipientID:  "marie",
+			wantInstance: 1,
+		},
+		{
+			name:        "user url takes precedence",
+			conf:        config.Webhook{URL: instance.URL, AllowUserURLs: true},
+			userURL:     personal.URL,
+			userSecret:  "user-secret",
+			recipientID: "marie",
+			wantUser:    1,
+		},
+		{
+			name:         "user url without secret",
+			conf:         config.Webhook{URL: instance.URL, AllowUserURLs: true},
+			userURL:      personal.URL,
+			recipientID:  "marie",
+			wantInstance: 1,
+		},
+		{
+			name:         "invalid user url",
+			conf:         config.Webhook{URL: instance.URL, AllowUserURLs: true},
+			userURL:      "file:///etc/passwd",
+			recipientID:  "marie",
+			wantInstance: 1,
+		},
+		{
+			name:        "no url",
+			conf:        config.Webhook{AllowUserURLs: true},
+			recipientID: "marie",
+		},
+		{
+			name:    "no recipient",
+			conf:    config.Webhook{URL: instance.URL},
+			userURL: personal.URL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instanceRequests.Store(0)
+			userRequests.Store(0)
+
+			wh := newTestWebhook(t, tt.conf, tt.userURL, tt.userSecret)
+			if err := send(wh, &Message{RecipientID: tt.recipientID}); err != nil {
+				t.Fatal(err)
+			}
+			if got := instanceRequests.Load(); got != tt.wantInstance {
+				t.Errorf("instance requests = %v, want %v", got, tt.wantInstance)
+			}
+			if got := userRequests.Load(); got != tt.wantUser {
+				t.Errorf("user requests = %v, want %v", got, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestWebhook_InternalUserURL(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	}))
+	defer srv.Close()
+
+	wh := newWebhook(t, config.Webhook{AllowUserURLs: true, MaxRetries: 2, RetryBackoff: time.Millisecond}, srv.URL, "user-secret")
+	err := send(wh, &Message{RecipientID: "marie"})
+	if !errors.Is(err, webhook.ErrAddressNotAllowed) {
+		t.Errorf("send() error = %v, want %v", err, webhook.ErrAddressNotAllowed)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("requests = %v, want 0", got)
+	}
+}
+
+func TestWebhook_UserSecret(t *testing.T) {
+	var signature string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhook.SignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	wh := newTestWebhook(t, config.Webhook{URL: "https://instance.example.com", Secret: "secret", AllowUserURLs: true}, srv.URL, "user-secret")
+	if err := send(wh, &Message{RecipientID: "marie", Subject: "subject"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := webhook.Sign("user-secret", body); signature != want {
+		t.Errorf("signature = %v, want %v", signature, want)
+	}
+}
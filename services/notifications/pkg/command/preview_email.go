@@ -0,0 +1,123 @@
+package command
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/config"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/email"
+)
+
+// PreviewEmail renders an email template with sample data.
+func PreviewEmail(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "preview-email",
+		Usage: "Render an email template with sample data to preview custom templates and themes.",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "template",
+				Aliases:  []string{"t"},
+				Usage:    fmt.Sprintf("The template to render, one of '%s'.", strings.Join(email.TemplateNames(), "', '")),
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "theme",
+				Usage: "The name of the theme to render the template with. Defaults to the theme selected by '--space' and '--group'.",
+			},
+			&cli.StringFlag{
+				Name:  "space",
+				Usage: "Select the theme used for emails about this space.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "group",
+				Usage: "Select the theme used for emails sent to members of this group, can be used multiple times.",
+			},
+			&cli.StringFlag{
+				Name:    "locale",
+				Aliases: []string{"l"},
+				Usage:   "The locale to render the template in. Defaults to the default language.",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "text",
+				Usage: "The part of the email to print, one of 'text' or 'html'.",
+			},
+			&cli.StringSliceFlag{
+				Name:  "var",
+				Usage: "Replace a sample value of a placeholder, e.g. '--var SpaceName=Marketing'. Can be used multiple times.",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			format := c.String("format")
+			if format != "text" && format != "html" {
+				return fmt.Errorf("unknown format '%s', must be 'text' or 'html'", format)
+			}
+
+			vars := make(map[string]string)
+			for _, v := range c.StringSlice("var") {
+				key, value, ok := strings.Cut(v, "=")
+				if !ok {
+					return fmt.Errorf("invalid variable '%s', must be in the form 'name=value'", v)
+				}
+				vars[key] = value
+			}
+
+			themes, err := email.LoadThemes(cfg.Notifications.EmailThemesPath)
+			if err != nil {
+				return err
+			}
+			theme := themes.Select(c.String("space"), c.StringSlice("group"))
+			if name := c.String("theme"); name != "" {
+				theme = themes.Get(name)
+				if theme == nil {
+					return errors.Errorf("theme '%s' not found in '%s'", name, cfg.Notifications.EmailThemesPath)
+				}
+			}
+
+			locale := c.String("locale")
+			if locale == "" {
+				locale = cfg.Notifications.DefaultLanguage
+			}
+
+			msg, err := email.RenderPreview(c.String("template"), theme, locale, cfg.Notifications.DefaultLanguage,
+				cfg.Notifications.EmailTemplatePath, cfg.Notifications.TranslationPath, vars)
+			if err != nil {
+				return err
+			}
+
+			sender := msg.SenderAddress
+			if sender == "" {
+				sender = cfg.Notifications.SMTP.Sender
+			}
+			themeName := "none"
+			if theme != nil {
+				themeName = theme.Name
+			}
+
+			fmt.Fprintf(os.Stderr, "Theme: %s\nFrom: %s\nSubject: %s\n", themeName, sender, msg.Subject)
+			if len(msg.AttachInline) > 0 {
+				images := slices.Sorted(maps.Keys(msg.AttachInline))
+				fmt.Fprintf(os.Stderr, "Inline images: %s\n", strings.Join(images, ", "))
+			}
+			fmt.Fprintln(os.Stderr)
+
+			if format == "html" {
+				fmt.Println(msg.HTMLBody)
+				return nil
+			}
+			fmt.Println(msg.TextBody)
+			return nil
+		},
+	}
+}
@@ -16,6 +16,7 @@ func GetCommands(cfg *config.Config) cli.Commands {
 
 		// interaction with this service
 		SendEmail(cfg),
+		PreviewEmail(cfg),
 
 		// infos about this service
 		Health(cfg),
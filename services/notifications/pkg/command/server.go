@@ -26,6 +26,7 @@ import (
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/channels"
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/config"
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/email"
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/logging"
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/server/debug"
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/service"
@@ -125,6 +126,9 @@ func Server(cfg *config.Config) *cli.Command {
 				logger.Fatal().Err(err).Str("addr", cfg.Notifications.RevaGateway).Msg("could not get reva gateway selector")
 			}
 			valueService := settingssvc.NewValueService("com.owncloud.api.settings", grpcClient)
+			if cfg.Notifications.Webhook.URL != "" || cfg.Notifications.Webhook.AllowUserURLs {
+				channel = channels.NewMultiChannel(channel, channels.NewWebhookChannel(ctx, *cfg, valueService, logger))
+			}
 			historyClient := ehsvc.NewEventHistoryService("com.owncloud.api.eventhistory", grpcClient)
 
 			notificationStore := store.Create(
@@ -137,10 +141,15 @@ func Server(cfg *config.Config) *cli.Command {
 				store.TLS(cfg.Store.EnableTLS, cfg.Store.TLSInsecure, cfg.Store.TLSRootCACertificate),
 			)
 
+			themes, err := email.LoadThemes(cfg.Notifications.EmailThemesPath)
+			if err != nil {
+				return err
+			}
+
 			svc := service.NewEventsNotifier(traceProvider, evts, channel, logger, gatewaySelector, valueService,
 				cfg.ServiceAccount.ServiceAccountID, cfg.ServiceAccount.ServiceAccountSecret,
 				cfg.Notifications.EmailTemplatePath, cfg.Notifications.DefaultLanguage, cfg.WebUIURL,
-				cfg.Notifications.TranslationPath, cfg.Notifications.SMTP.Sender, notificationStore, historyClient, registeredEvents)
+				cfg.Notifications.TranslationPath, cfg.Notifications.SMTP.Sender, notificationStore, historyClient, registeredEvents, themes)
 
 			gr.Add(runner.New(cfg.Service.Name+".svc", func() error {
 				return svc.Run()
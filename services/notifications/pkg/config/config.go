@@ -32,8 +32,10 @@ type Config struct {
 // Notifications defines the config options for the notifications service.
 type Notifications struct {
 	SMTP              SMTP                  `yaml:"SMTP"`
+	Webhook           Webhook               `yaml:"webhook"`
 	Events            Events                `yaml:"events"`
 	EmailTemplatePath string                `yaml:"email_template_path" env:"OCIS_EMAIL_TEMPLATE_PATH;NOTIFICATIONS_EMAIL_TEMPLATE_PATH" desc:"Path to Email notification templates overriding embedded ones." introductionVersion:"pre5.0"`
+	EmailThemesPath   string                `yaml:"email_themes_path" env:"NOTIFICATIONS_EMAIL_THEMES_PATH" desc:"Path to a folder containing email themes in subfolders. A theme replaces templates, images, subjects and the sender address for the emails about certain spaces or sent to members of certain groups. See the documentation for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
 	TranslationPath   string                `yaml:"translation_path" env:"OCIS_TRANSLATION_PATH;NOTIFICATIONS_TRANSLATION_PATH" desc:"(optional) Set this to a path with custom translations to overwrite the builtin translations. Note that file and folder naming rules apply, see the documentation for more details." introductionVersion:"pre5.0"`
 	DefaultLanguage   string                `yaml:"default_language" env:"OCIS_DEFAULT_LANGUAGE" desc:"The default language used by services and the WebUI. If not defined, English will be used as default. See the documentation for more details." introductionVersion:"5.0"`
 	RevaGateway       string                `yaml:"reva_gateway" env:"OCIS_REVA_GATEWAY" desc:"CS3 gateway used to look up user metadata" introductionVersion:"pre5.0"`
@@ -52,6 +54,19 @@ type SMTP struct {
 	Encryption     string `yaml:"smtp_encryption" env:"NOTIFICATIONS_SMTP_ENCRYPTION" desc:"Encryption method for the SMTP communication. Possible values are 'starttls', 'ssltls' and 'none'." introductionVersion:"pre5.0"`
 }
 
+// Webhook combines the webhook configuration options.
+type Webhook struct {
+	URL                string        `yaml:"url" env:"NOTIFICATIONS_WEBHOOK_URL" desc:"URL notifications are posted to for all users in addition to emails. Users can set their own URL in their notification settings if NOTIFICATIONS_WEBHOOK_ALLOW_USER_URLS is enabled, which takes precedence. Leave empty to only use the URLs of the users." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AllowUserURLs      bool          `yaml:"allow_user_urls" env:"NOTIFICATIONS_WEBHOOK_ALLOW_USER_URLS" desc:"Allow users to set their own webhook URL in their notification settings. Requests to URLs of users which resolve to internal addresses are refused." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Secret             string        `yaml:"secret" env:"NOTIFICATIONS_WEBHOOK_SECRET" desc:"Secret used to sign the payload of the webhook requests to NOTIFICATIONS_WEBHOOK_URL. The HMAC-SHA256 signature is sent in the 'X-OCIS-Signature' header. If empty, requests are not signed. Requests to the URLs of users are signed with the secret they got when registering the URL." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%" mask:"password"`
+	Timeout            time.Duration `yaml:"timeout" env:"NOTIFICATIONS_WEBHOOK_TIMEOUT" desc:"Timeout for a single webhook request. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxRetries         int           `yaml:"max_retries" env:"NOTIFICATIONS_WEBHOOK_MAX_RETRIES" desc:"Number of times a failed webhook request is retried. Requests are only retried on network errors, rate limiting and server errors." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	RetryBackoff       time.Duration `yaml:"retry_backoff" env:"NOTIFICATIONS_WEBHOOK_RETRY_BACKOFF" desc:"Time to wait before the first retry of a failed webhook request. The time doubles with every further retry. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Workers            int           `yaml:"workers" env:"NOTIFICATIONS_WEBHOOK_WORKERS" desc:"Number of webhook requests which are sent at the same time, including their retries." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	QueueSize          int           `yaml:"queue_size" env:"NOTIFICATIONS_WEBHOOK_QUEUE_SIZE" desc:"Number of webhook notifications which can wait to be sent. Further notifications are dropped until the queue has space again." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	InsecureSkipVerify bool          `yaml:"insecure" env:"OCIS_INSECURE;NOTIFICATIONS_WEBHOOK_INSECURE" desc:"Skip the TLS certificate verification of webhook URLs." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
 // Events combines the configuration options for the event bus.
 type Events struct {
 	Endpoint             string `yaml:"endpoint" env:"OCIS_EVENTS_ENDPOINT;NOTIFICATIONS_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture." introductionVersion:"pre5.0"`
@@ -34,6 +34,13 @@ func DefaultConfig() *config.Config {
 			SMTP: config.SMTP{
 				Encryption: "none",
 			},
+			Webhook: config.Webhook{
+				Timeout:      10 * time.Second,
+				MaxRetries:   3,
+				RetryBackoff: time.Second,
+				Workers:      4,
+				QueueSize:    1000,
+			},
 			Events: config.Events{
 				Endpoint:  "127.0.0.1:9233",
 				Cluster:   "ocis-cluster",
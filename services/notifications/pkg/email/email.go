@@ -27,11 +27,18 @@ var (
 
 // RenderEmailTemplate is responsible to prepare a message which than can be used to notify the user via email.
 func RenderEmailTemplate(mt MessageTemplate, locale, defaultLocale string, emailTemplatePath string, translationPath string, vars map[string]string) (*channels.Message, error) {
+	return RenderThemedEmailTemplate(nil, mt, locale, defaultLocale, emailTemplatePath, translationPath, vars)
+}
+
+// RenderThemedEmailTemplate prepares a message like RenderEmailTemplate using the templates, images, subject and sender of a theme.
+// A nil theme renders the default templates.
+func RenderThemedEmailTemplate(th *Theme, mt MessageTemplate, locale, defaultLocale string, emailTemplatePath string, translationPath string, vars map[string]string) (*channels.Message, error) {
+	mt.Subject = th.subject(mt.name, mt.Subject)
 	textMt, err := NewTextTemplate(mt, locale, defaultLocale, translationPath, vars)
 	if err != nil {
 		return nil, err
 	}
-	tpl, err := parseTemplate(emailTemplatePath, mt.textTemplate)
+	tpl, err := parseTemplate(th.templatePath(emailTemplatePath, mt.textTemplate), mt.textTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +50,7 @@ func RenderEmailTemplate(mt MessageTemplate, locale, defaultLocale string, email
 	if err != nil {
 		return nil, err
 	}
-	htmlTpl, err := parseTemplate(emailTemplatePath, mt.htmlTemplate)
+	htmlTpl, err := parseTemplate(th.templatePath(emailTemplatePath, mt.htmlTemplate), mt.htmlTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -52,28 +59,36 @@ func RenderEmailTemplate(mt MessageTemplate, locale, defaultLocale string, email
 		return nil, err
 	}
 	var data map[string][]byte
-	if emailTemplatePath != "" {
-		data, err = readImages(emailTemplatePath)
+	if imagePath := th.imagePath(emailTemplatePath); imagePath != "" {
+		data, err = readImages(imagePath)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	return &channels.Message{
-		Subject:      textMt.Subject,
-		TextBody:     textBody,
-		HTMLBody:     htmlBody,
-		AttachInline: data,
+		SenderAddress: th.sender(),
+		Subject:       textMt.Subject,
+		TextBody:      textBody,
+		HTMLBody:      htmlBody,
+		AttachInline:  data,
 	}, nil
 }
 
 // RenderGroupedEmailTemplate is responsible to prepare a message which than can be used to notify the user via email.
 func RenderGroupedEmailTemplate(gmt GroupedMessageTemplate, vars map[string]string, locale, defaultLocale string, emailTemplatePath string, translationPath string, mts []MessageTemplate, mtsVars []map[string]string) (*channels.Message, error) {
+	return RenderThemedGroupedEmailTemplate(nil, gmt, vars, locale, defaultLocale, emailTemplatePath, translationPath, mts, mtsVars)
+}
+
+// RenderThemedGroupedEmailTemplate prepares a message like RenderGroupedEmailTemplate using the templates, images, subject and sender of a theme.
+// A nil theme renders the default templates.
+func RenderThemedGroupedEmailTemplate(th *Theme, gmt GroupedMessageTemplate, vars map[string]string, locale, defaultLocale string, emailTemplatePath string, translationPath string, mts []MessageTemplate, mtsVars []map[string]string) (*channels.Message, error) {
+	gmt.Subject = th.subject(gmt.name, gmt.Subject)
 	textMt, err := NewGroupedTextTemplate(gmt, vars, locale, defaultLocale, translationPath, mts, mtsVars)
 	if err != nil {
 		return nil, err
 	}
-	tpl, err := parseTemplate(emailTemplatePath, gmt.textTemplate)
+	tpl, err := parseTemplate(th.templatePath(emailTemplatePath, gmt.textTemplate), gmt.textTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -90,7 +105,7 @@ func RenderGroupedEmailTemplate(gmt GroupedMessageTemplate, vars map[string]stri
 	if err != nil {
 		return nil, err
 	}
-	htmlTpl, err := parseTemplate(emailTemplatePath, gmt.htmlTemplate)
+	htmlTpl, err := parseTemplate(th.templatePath(emailTemplatePath, gmt.htmlTemplate), gmt.htmlTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -99,18 +114,19 @@ func RenderGroupedEmailTemplate(gmt GroupedMessageTemplate, vars map[string]stri
 		return nil, err
 	}
 	var data map[string][]byte
-	if emailTemplatePath != "" {
-		data, err = readImages(emailTemplatePath)
+	if imagePath := th.imagePath(emailTemplatePath); imagePath != "" {
+		data, err = readImages(imagePath)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	return &channels.Message{
-		Subject:      textMt.Subject,
-		TextBody:     textBody,
-		HTMLBody:     htmlBody,
-		AttachInline: data,
+		SenderAddress: th.sender(),
+		Subject:       textMt.Subject,
+		TextBody:      textBody,
+		HTMLBody:      htmlBody,
+		AttachInline:  data,
 	}, nil
 }
 
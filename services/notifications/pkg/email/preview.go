@@ -0,0 +1,67 @@
+package email
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/owncloud/ocis/v2/services/notifications/pkg/channels"
+)
+
+// _templatesByName holds the message templates which can be customized by themes, next to the grouped template
+var _templatesByName = map[string]MessageTemplate{
+	ShareCreated.name:      ShareCreated,
+	ShareExpired.name:      ShareExpired,
+	ShareRemoved.name:      ShareRemoved,
+	SharedSpace.name:       SharedSpace,
+	UnsharedSpace.name:     UnsharedSpace,
+	MembershipExpired.name: MembershipExpired,
+
+	ScienceMeshInviteTokenGenerated.name:                 ScienceMeshInviteTokenGenerated,
+	ScienceMeshInviteTokenGeneratedWithoutShareLink.name: ScienceMeshInviteTokenGeneratedWithoutShareLink,
+}
+
+// _sampleVars are the placeholder values used to preview templates
+var _sampleVars = map[string]string{
+	"ShareSharer":     "Albert Einstein",
+	"ShareFolder":     "Theory of Relativity",
+	"ShareGrantee":    "Marie Curie",
+	"ShareLink":       "https://ocis.example.com/f/8d4f5a2c-39b1-4a6e-9a43-3c3d2e6e0f4b",
+	"SpaceName":       "Physics Department",
+	"SpaceGrantee":    "Marie Curie",
+	"SpaceSharer":     "Albert Einstein",
+	"ExpiredAt":       "2024-01-01 12:00:00",
+	"ShareSharerMail": "einstein@example.com",
+	"ProviderDomain":  "ocis.example.com",
+	"Token":           "7f3b8a9e-1c2d-4e5f-8a9b-0c1d2e3f4a5b",
+	"DisplayName":     "Marie Curie",
+}
+
+// TemplateNames returns the names of the templates which can be previewed and customized by themes
+func TemplateNames() []string {
+	names := append(slices.Collect(maps.Keys(_templatesByName)), Grouped.name)
+	slices.Sort(names)
+	return names
+}
+
+func isTemplateName(name string) bool {
+	_, ok := _templatesByName[name]
+	return ok || name == Grouped.name
+}
+
+// RenderPreview renders a template with sample data, vars replace the sample values.
+// The grouped template is rendered with a share and a space notification.
+func RenderPreview(name string, th *Theme, locale, defaultLocale string, emailTemplatePath string, translationPath string, vars map[string]string) (*channels.Message, error) {
+	if !isTemplateName(name) {
+		return nil, fmt.Errorf("unknown template '%s'", name)
+	}
+
+	sample := maps.Clone(_sampleVars)
+	maps.Copy(sample, vars)
+
+	if name == Grouped.name {
+		return RenderThemedGroupedEmailTemplate(th, Grouped, sample, locale, defaultLocale, emailTemplatePath, translationPath,
+			[]MessageTemplate{ShareCreated, SharedSpace}, []map[string]string{sample, sample})
+	}
+	return RenderThemedEmailTemplate(th, _templatesByName[name], locale, defaultLocale, emailTemplatePath, translationPath, sample)
+}
@@ -11,6 +11,7 @@ const (
 var (
 	// Shares
 	ShareCreated = MessageTemplate{
+		name:         "ShareCreated",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// ShareCreated email template, Subject field (resolves directly)
@@ -24,6 +25,7 @@ var (
 	}
 
 	ShareExpired = MessageTemplate{
+		name:         "ShareExpired",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// ShareExpired email template, Subject field (resolves directly)
@@ -37,6 +39,7 @@ Even though this share has been revoked you still might have access through othe
 	}
 
 	ShareRemoved = MessageTemplate{
+		name:         "ShareRemoved",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// ShareRemoved email template, Subject field (resolves directly)
@@ -51,6 +54,7 @@ Even though this share has been revoked you still might have access through othe
 
 	// Spaces templates
 	SharedSpace = MessageTemplate{
+		name:         "SharedSpace",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// SharedSpace email template, Subject field (resolves directly)
@@ -64,6 +68,7 @@ Even though this share has been revoked you still might have access through othe
 	}
 
 	UnsharedSpace = MessageTemplate{
+		name:         "UnsharedSpace",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// UnsharedSpace email template, Subject field (resolves directly)
@@ -79,6 +84,7 @@ You might still have access through your other groups or direct membership.`),
 	}
 
 	MembershipExpired = MessageTemplate{
+		name:         "MembershipExpired",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// MembershipExpired email template, Subject field (resolves directly)
@@ -92,6 +98,7 @@ Even though this membership has expired you still might have access through othe
 	}
 
 	ScienceMeshInviteTokenGenerated = MessageTemplate{
+		name:         "ScienceMeshInviteTokenGenerated",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// ScienceMeshInviteTokenGenerated email template, Subject field (resolves directly)
@@ -109,6 +116,7 @@ Alternatively, you can visit your federation settings and use the following deta
 	}
 
 	ScienceMeshInviteTokenGeneratedWithoutShareLink = MessageTemplate{
+		name:         "ScienceMeshInviteTokenGeneratedWithoutShareLink",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// ScienceMeshInviteTokenGeneratedWithoutShareLink email template, Subject field (resolves directly)
@@ -123,6 +131,7 @@ Please visit your federation settings and use the following details:
 	}
 
 	Grouped = GroupedMessageTemplate{
+		name:         "Grouped",
 		textTemplate: _textTemplate,
 		htmlTemplate: _htmlTemplate,
 		// Grouped email template, Subject field (resolves directly)
@@ -151,6 +160,8 @@ var _placeholders = map[string]string{
 
 // MessageTemplate is the data structure for the email
 type MessageTemplate struct {
+	// name identifies the template in themes
+	name string
 	// textTemplate represent the path to text plain .tmpl file
 	textTemplate string
 	// htmlTemplate represent the path to html .tmpl file
@@ -164,6 +175,8 @@ type MessageTemplate struct {
 
 // GroupedMessageTemplate is the data structure for the email
 type GroupedMessageTemplate struct {
+	// name identifies the template in themes
+	name string
 	// textTemplate represent the path to text plain .tmpl file
 	textTemplate string
 	// htmlTemplate represent the path to html .tmpl file
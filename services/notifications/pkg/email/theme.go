@@ -0,0 +1,193 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	stdmail "net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+)
+
+const _themeFileName = "theme.json"
+
+// Theme holds the templates, images, subjects and sender address used for the emails of some spaces or groups.
+// The templates and images of a theme are loaded from the same subfolder hierarchy as custom email templates,
+// missing files are taken from the custom email templates or the embedded ones.
+type Theme struct {
+	// Name is the name of the theme folder
+	Name string `json:"-"`
+	// Sender replaces the sender address of the emails
+	Sender string `json:"sender"`
+	// Subjects replaces the subjects of templates, the keys are the template names
+	Subjects map[string]string `json:"subjects"`
+	// Spaces are the ids of the spaces the theme is used for
+	Spaces []string `json:"spaces"`
+	// Groups are the ids of the groups the theme is used for
+	Groups []string `json:"groups"`
+
+	path     string
+	spaceIDs []string
+}
+
+// Themes are the email themes found in a theme folder
+type Themes struct {
+	themes []*Theme
+}
+
+// LoadThemes loads the themes from the subfolders of path which contain a theme.json file
+func LoadThemes(path string) (*Themes, error) {
+	themes := &Themes{}
+	if path == "" {
+		return themes, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		th, err := loadTheme(filepath.Join(path, e.Name()))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("could not load email theme '%s': %w", e.Name(), err)
+		}
+		themes.themes = append(themes.themes, th)
+	}
+
+	return themes, nil
+}
+
+func loadTheme(path string) (*Theme, error) {
+	b, err := os.ReadFile(filepath.Join(path, _themeFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	th := &Theme{}
+	if err := json.Unmarshal(b, th); err != nil {
+		return nil, err
+	}
+	th.Name = filepath.Base(path)
+	th.path = path
+
+	if th.Sender != "" {
+		a, err := stdmail.ParseAddress(th.Sender)
+		if err != nil {
+			return nil, fmt.Errorf("the sender must be a valid single RFC 5322 address: %w", err)
+		}
+		th.Sender = a.String()
+	}
+
+	for name, subject := range th.Subjects {
+		if !isTemplateName(name) {
+			return nil, fmt.Errorf("unknown template '%s'", name)
+		}
+		if _, err := template.New("").Parse(replacePlaceholders(subject)); err != nil {
+			return nil, fmt.Errorf("invalid subject for template '%s': %w", name, err)
+		}
+	}
+
+	for _, s := range th.Spaces {
+		id, err := storagespace.ParseID(s)
+		if err != nil || id.GetSpaceId() == "" {
+			return nil, fmt.Errorf("invalid space id '%s'", s)
+		}
+		th.spaceIDs = append(th.spaceIDs, id.GetSpaceId())
+	}
+
+	return th, nil
+}
+
+// Get returns the theme with the given name or nil
+func (ts *Themes) Get(name string) *Theme {
+	if ts == nil {
+		return nil
+	}
+	for _, th := range ts.themes {
+		if th.Name == name {
+			return th
+		}
+	}
+	return nil
+}
+
+// Select returns the theme for an email about a space sent to a member of the given groups.
+// Themes of the space take precedence over themes of the groups, nil is returned if no theme applies.
+func (ts *Themes) Select(spaceID string, groups []string) *Theme {
+	if ts == nil {
+		return nil
+	}
+
+	if spaceID != "" {
+		if id, err := storagespace.ParseID(spaceID); err == nil && id.GetSpaceId() != "" {
+			for _, th := range ts.themes {
+				if slices.Contains(th.spaceIDs, id.GetSpaceId()) {
+					return th
+				}
+			}
+		}
+	}
+
+	for _, th := range ts.themes {
+		for _, g := range groups {
+			if slices.Contains(th.Groups, g) {
+				return th
+			}
+		}
+	}
+
+	return nil
+}
+
+// subject returns the subject of the theme for a template
+func (th *Theme) subject(name, subject string) string {
+	if th == nil {
+		return subject
+	}
+	if s, ok := th.Subjects[name]; ok {
+		return s
+	}
+	return subject
+}
+
+// sender returns the sender address of the theme
+func (th *Theme) sender() string {
+	if th == nil {
+		return ""
+	}
+	return th.Sender
+}
+
+// templatePath returns the base folder to load a template file from
+func (th *Theme) templatePath(emailTemplatePath string, file string) string {
+	if th == nil {
+		return emailTemplatePath
+	}
+	if _, err := os.Stat(filepath.Join(th.path, file)); err == nil {
+		return th.path
+	}
+	return emailTemplatePath
+}
+
+// imagePath returns the base folder to load the inline images from
+func (th *Theme) imagePath(emailTemplatePath string) string {
+	if th == nil {
+		return emailTemplatePath
+	}
+	if info, err := os.Stat(filepath.Join(th.path, imgDir)); err == nil && info.IsDir() {
+		return th.path
+	}
+	return emailTemplatePath
+}
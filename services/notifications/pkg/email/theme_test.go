@@ -0,0 +1,155 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeThemeFile(t *testing.T, root, name, file, content string) {
+	t.Helper()
+	p := filepath.Join(root, name, file)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadThemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   string
+		wantErr bool
+	}{
+		{name: "valid", theme: `{"sender": "Acme <noreply@acme.example>", "subjects": {"ShareCreated": "{ShareSharer} shared"}, "spaces": ["1$2"], "groups": ["g1"]}`},
+		{name: "invalid json", theme: `{`, wantErr: true},
+		{name: "invalid sender", theme: `{"sender": "not an address"}`, wantErr: true},
+		{name: "unknown template", theme: `{"subjects": {"Unknown": "subject"}}`, wantErr: true},
+		{name: "invalid subject", theme: `{"subjects": {"ShareCreated": "{{ .ShareSharer"}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeThemeFile(t, root, "acme", _themeFileName, tt.theme)
+			// folders without theme file are ignored
+			writeThemeFile(t, root, "other", "templates/text/email.text.tmpl", "")
+
+			themes, err := LoadThemes(root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadThemes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if themes.Get("acme") == nil {
+				t.Error("theme acme not loaded")
+			}
+			if themes.Get("other") != nil {
+				t.Error("folder without theme file loaded")
+			}
+		})
+	}
+}
+
+func TestThemes_Select(t *testing.T) {
+	root := t.TempDir()
+	writeThemeFile(t, root, "groups", _themeFileName, `{"groups": ["g1", "g2"]}`)
+	writeThemeFile(t, root, "space", _themeFileName, `{"spaces": ["storage$space"]}`)
+
+	themes, err := LoadThemes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spaceID string
+		groups  []string
+		want    string
+	}{
+		{name: "space", spaceID: "storage$space", want: "space"},
+		{name: "space resource id", spaceID: "storage$space!node", want: "space"},
+		{name: "space takes precedence", spaceID: "storage$space", groups: []string{"g2"}, want: "space"},
+		{name: "group", spaceID: "storage$other", groups: []string{"g3", "g2"}, want: "groups"},
+		{name: "none", spaceID: "storage$other", groups: []string{"g3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := themes.Select(tt.spaceID, tt.groups)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("Select() = %v, want none", got.Name)
+			case tt.want != "" && (got == nil || got.Name != tt.want):
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var noThemes *Themes
+	if noThemes.Select("storage$space", []string{"g1"}) != nil {
+		t.Error("nil themes selected a theme")
+	}
+}
+
+func TestRenderThemedEmailTemplate(t *testing.T) {
+	root := t.TempDir()
+	writeThemeFile(t, root, "acme", _themeFileName, `{"sender": "Acme <noreply@acme.example>", "subjects": {"ShareCreated": "[Acme] {ShareSharer} shared {ShareFolder}"}}`)
+	writeThemeFile(t, root, "acme", _htmlTemplate, `<p>Acme</p>{{ .MessageBody }}<img src="cid:logo.png">`)
+	writeThemeFile(t, root, "acme", filepath.Join(imgDir, "logo.png"), "\x89PNG\r\n\x1a\n")
+
+	themes, err := LoadThemes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"ShareSharer": "Einstein", "ShareFolder": "secrets", "ShareGrantee": "Marie", "ShareLink": "https://example.test"}
+
+	msg, err := RenderThemedEmailTemplate(themes.Get("acme"), ShareCreated, "en", "en", "", "", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "[Acme] Einstein shared secrets" {
+		t.Errorf("Subject = %v", msg.Subject)
+	}
+	if msg.SenderAddress != `"Acme" <noreply@acme.example>` {
+		t.Errorf("SenderAddress = %v", msg.SenderAddress)
+	}
+	if !strings.HasPrefix(msg.HTMLBody, "<p>Acme</p>") {
+		t.Errorf("HTMLBody not rendered from theme: %v", msg.HTMLBody)
+	}
+	// the theme has no text template, the embedded one is used
+	if !strings.Contains(msg.TextBody, `Einstein has shared "secrets" with you.`) {
+		t.Errorf("TextBody not rendered from embedded template: %v", msg.TextBody)
+	}
+	if _, ok := msg.AttachInline["logo.png"]; !ok {
+		t.Errorf("AttachInline = %v, want logo.png", msg.AttachInline)
+	}
+
+	msg, err = RenderThemedEmailTemplate(nil, ShareCreated, "en", "en", "", "", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "Einstein shared 'secrets' with you" || msg.SenderAddress != "" || len(msg.AttachInline) != 0 {
+		t.Errorf("unexpected message without theme: %v", msg)
+	}
+}
+
+func TestRenderPreview(t *testing.T) {
+	for _, name := range TemplateNames() {
+		t.Run(name, func(t *testing.T) {
+			msg, err := RenderPreview(name, nil, "en", "en", "", "", map[string]string{"DisplayName": "Bob"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.Subject == "" || msg.TextBody == "" || msg.HTMLBody == "" {
+				t.Errorf("incomplete preview: %v", msg)
+			}
+		})
+	}
+
+	if _, err := RenderPreview("Unknown", nil, "en", "en", "", "", nil); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
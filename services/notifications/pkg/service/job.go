@@ -154,7 +154,8 @@ func (s eventsNotifier) createGroupedMail(ctx context.Context, logger zerolog.Lo
 		return
 	}
 
-	rendered, err := email.RenderGroupedEmailTemplate(email.Grouped, map[string]string{
+	theme := s.themes.Select("", userEvents.User.GetGroups())
+	rendered, err := email.RenderThemedGroupedEmailTemplate(theme, email.Grouped, map[string]string{
 		"DisplayName": userEvents.User.GetDisplayName(),
 	}, locale, s.defaultLanguage, s.emailTemplatePath, s.translationPath, mts, mtsVars)
 	if err != nil {
@@ -163,6 +164,7 @@ func (s eventsNotifier) createGroupedMail(ctx context.Context, logger zerolog.Lo
 	}
 	rendered.Sender = s.defaultEmailSender
 	rendered.Recipient = []string{userEvents.User.GetMail()}
+	rendered.RecipientID = userEvents.User.GetId().GetOpaqueId()
 	s.send(ctx, []*channels.Message{rendered})
 }
 
@@ -59,7 +59,8 @@ func NewEventsNotifier(
 	serviceAccountID, serviceAccountSecret, emailTemplatePath, defaultLanguage, ocisURL, translationPath, emailSender string,
 	store store.Store,
 	historyClient ehsvc.EventHistoryService,
-	registeredEvents map[string]events.Unmarshaller) Service {
+	registeredEvents map[string]events.Unmarshaller,
+	themes *email.Themes) Service {
 
 	return eventsNotifier{
 		traceProvider:        traceProvider,
@@ -79,6 +80,7 @@ func NewEventsNotifier(
 		splitter:             newIntervalSplitter(logger, valueService),
 		userEventStore:       newUserEventStore(logger, store, historyClient),
 		registeredEvents:     registeredEvents,
+		themes:               themes,
 		stopCh:               make(chan struct{}, 1),
 		stopped:              new(atomic.Bool),
 	}
@@ -102,6 +104,7 @@ type eventsNotifier struct {
 	splitter             *intervalSplitter
 	userEventStore       *userEventStore
 	registeredEvents     map[string]events.Unmarshaller
+	themes               *email.Themes
 	stopCh               chan struct{}
 	stopped              *atomic.Bool
 }
@@ -168,7 +171,7 @@ func (s eventsNotifier) Close() {
 	}
 }
 
-func (s eventsNotifier) render(ctx context.Context, template email.MessageTemplate,
+func (s eventsNotifier) render(ctx context.Context, template email.MessageTemplate, spaceID string,
 	granteeFieldName string, fields map[string]string, granteeList []*user.User, sender string) ([]*channels.Message, error) {
 	// Render the Email Template for each user
 	messageList := make([]*channels.Message, len(granteeList))
@@ -176,12 +179,14 @@ func (s eventsNotifier) render(ctx context.Context, template email.MessageTempla
 		locale := l10n.MustGetUserLocale(ctx, usr.GetId().GetOpaqueId(), "", s.valueService)
 		fields[granteeFieldName] = usr.GetDisplayName()
 
-		rendered, err := email.RenderEmailTemplate(template, locale, s.defaultLanguage, s.emailTemplatePath, s.translationPath, fields)
+		theme := s.themes.Select(spaceID, usr.GetGroups())
+		rendered, err := email.RenderThemedEmailTemplate(theme, template, locale, s.defaultLanguage, s.emailTemplatePath, s.translationPath, fields)
 		if err != nil {
 			return nil, err
 		}
 		rendered.Sender = sender
 		rendered.Recipient = []string{usr.GetMail()}
+		rendered.RecipientID = usr.GetId().GetOpaqueId()
 		messageList[i] = rendered
 	}
 	return messageList, nil
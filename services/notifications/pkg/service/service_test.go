@@ -107,7 +107,7 @@ var _ = Describe("Notifications", func() {
 			ch := make(chan events.Event)
 			evts := NewEventsNotifier(trace.NewNoopTracerProvider(), ch, tc, log.NewLogger(), gatewaySelector, vs, "",
 				"", "", "", "", "", "",
-				store.Create(), nil, nil)
+				store.Create(), nil, nil, nil)
 			go evts.Run()
 
 			ch <- ev
@@ -344,7 +344,7 @@ var _ = Describe("Notifications X-Site Scripting", func() {
 			ch := make(chan events.Event)
 			evts := NewEventsNotifier(trace.NewNoopTracerProvider(), ch, tc, log.NewLogger(), gatewaySelector, vs, "",
 				"", "", "", "", "", "",
-				store.Create(), nil, nil)
+				store.Create(), nil, nil, nil)
 			go evts.Run()
 
 			ch <- ev
@@ -634,7 +634,7 @@ var _ = Describe("Notifications grouped store", func() {
 			ch := make(chan events.Event)
 			evts := NewEventsNotifier(trace.NewNoopTracerProvider(), ch, tc, log.NewLogger(), gatewaySelector, valueService, "",
 				"", "", "", "", "", "",
-				store, nil, nil)
+				store, nil, nil, nil)
 			go evts.Run()
 
 			// test
@@ -903,7 +903,7 @@ var _ = Describe("Notifications grouped send", func() {
 			ch := make(chan events.Event)
 			evts := NewEventsNotifier(trace.NewNoopTracerProvider(), ch, tc, log.NewLogger(), gatewaySelector, valueService, "",
 				"", "", "", "", "",
-				sender, store, historyClient, registeredEvents)
+				sender, store, historyClient, registeredEvents, nil)
 			go evts.Run()
 
 			// trigger sending
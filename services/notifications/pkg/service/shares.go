@@ -7,6 +7,7 @@ import (
 	"github.com/owncloud/ocis/v2/services/notifications/pkg/email"
 	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
 	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
 	"github.com/owncloud/reva/v2/pkg/utils"
 	"github.com/rs/zerolog"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
@@ -35,7 +36,7 @@ func (s eventsNotifier) handleShareCreated(baseCtx context.Context, e events.Sha
 	}
 
 	sharerDisplayName := owner.GetDisplayName()
-	emails, err := s.render(ctx, email.ShareCreated,
+	emails, err := s.render(ctx, email.ShareCreated, storagespace.FormatStorageID(e.ItemID.GetStorageId(), e.ItemID.GetSpaceId()),
 		"ShareGrantee",
 		map[string]string{
 			"ShareSharer": sharerDisplayName,
@@ -124,7 +125,7 @@ func (s eventsNotifier) handleShareExpired(baseCtx context.Context, e events.Sha
 		return
 	}
 
-	emails, err := s.render(ctx, email.ShareExpired,
+	emails, err := s.render(ctx, email.ShareExpired, storagespace.FormatStorageID(e.ItemID.GetStorageId(), e.ItemID.GetSpaceId()),
 		"ShareGrantee",
 		map[string]string{
 			"ShareFolder": shareFolder,
@@ -186,7 +187,7 @@ func (s eventsNotifier) handleShareRemoved(baseCtx context.Context, e events.Sha
 
 	sharerDisplayName := executant.GetDisplayName()
 
-	emails, err := s.render(ctx, email.ShareRemoved,
+	emails, err := s.render(ctx, email.ShareRemoved, storagespace.FormatStorageID(e.ItemID.GetStorageId(), e.ItemID.GetSpaceId()),
 		"ShareGrantee",
 		map[string]string{
 			"ShareSharer": sharerDisplayName,
@@ -34,7 +34,7 @@ func (s eventsNotifier) handleSpaceShared(baseCtx context.Context, e events.Spac
 	}
 
 	sharerDisplayName := executant.GetDisplayName()
-	emails, err := s.render(ctx, email.SharedSpace,
+	emails, err := s.render(ctx, email.SharedSpace, e.ID.GetOpaqueId(),
 		"SpaceGrantee",
 		map[string]string{
 			"SpaceSharer": sharerDisplayName,
@@ -119,7 +119,7 @@ func (s eventsNotifier) handleSpaceUnshared(baseCtx context.Context, e events.Sp
 	}
 
 	sharerDisplayName := executant.GetDisplayName()
-	emails, err := s.render(ctx, email.UnsharedSpace,
+	emails, err := s.render(ctx, email.UnsharedSpace, e.ID.GetOpaqueId(),
 		"SpaceGrantee",
 		map[string]string{
 			"SpaceSharer": sharerDisplayName,
@@ -220,7 +220,7 @@ func (s eventsNotifier) handleSpaceMembershipExpired(baseCtx context.Context, e
 		return
 	}
 
-	emails, err := s.render(ctx, email.MembershipExpired,
+	emails, err := s.render(ctx, email.MembershipExpired, e.SpaceID.GetOpaqueId(),
 		"SpaceGrantee",
 		map[string]string{
 			"SpaceName": e.SpaceName,
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/owncloud/reva/v2/pkg/store"
+	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+)
+
+// PrintGraph cli command to print the resolved postprocessing step graph
+func PrintGraph(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "graph",
+		Usage: "print the resolved postprocessing step graph, optionally for a given upload",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "upload-id",
+				Aliases: []string{"u"},
+				Usage:   "show the graph and the state of its steps for a running upload. Takes precedence over the other flags.",
+			},
+			&cli.StringFlag{
+				Name:  "filename",
+				Usage: "evaluate the step conditions for a file with this name",
+			},
+			&cli.Uint64Flag{
+				Name:  "size",
+				Usage: "evaluate the step conditions for a file of this size in bytes",
+			},
+			&cli.StringFlag{
+				Name:  "space-id",
+				Usage: "evaluate the step conditions for a file in this space",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			var (
+				graph postprocessing.Graph
+				state func(postprocessing.Step) string
+			)
+
+			if uid := c.String("upload-id"); uid != "" {
+				pp, err := readPostprocessing(cfg, uid)
+				if err != nil {
+					return err
+				}
+				graph = pp.ResolvedGraph()
+				state = func(s postprocessing.Step) string {
+					return string(pp.StepState(s.Name))
+				}
+			} else {
+				var err error
+				graph, err = postprocessing.NewGraph(cfg.Postprocessing)
+				if err != nil {
+					return err
+				}
+				filename, size, spaceID := c.String("filename"), c.Uint64("size"), c.String("space-id")
+				state = func(s postprocessing.Step) string {
+					if !s.Matches(filename, size, spaceID) {
+						return string(postprocessing.StepSkipped)
+					}
+					return "run"
+				}
+			}
+
+			table := tablewriter.NewTable(os.Stdout)
+			table.Header("Step", "Depends On", "Conditions", "Max Retries", "Backoff", "State")
+			for _, s := range graph {
+				deps := make([]string, 0, len(s.DependsOn))
+				for _, d := range s.DependsOn {
+					deps = append(deps, string(d))
+				}
+
+				table.Append([]string{
+					string(s.Name),
+					strings.Join(deps, ", "),
+					formatConditions(s.Conditions),
+					strconv.Itoa(s.MaxRetries),
+					s.RetryBackoffDuration.String(),
+					state(s),
+				})
+			}
+			return table.Render()
+		},
+	}
+}
+
+func readPostprocessing(cfg *config.Config, uploadID string) (*postprocessing.Postprocessing, error) {
+	st := store.Create(
+		store.Store(cfg.Store.Store),
+		store.TTL(cfg.Store.TTL),
+		microstore.Nodes(cfg.Store.Nodes...),
+		microstore.Database(cfg.Store.Database),
+		microstore.Table(cfg.Store.Table),
+		store.Authentication(cfg.Store.AuthUsername, cfg.Store.AuthPassword),
+		store.TLS(cfg.Store.EnableTLS, cfg.Store.TLSInsecure, cfg.Store.TLSRootCACertificate),
+	)
+
+	recs, err := st.Read(uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read upload '%s': %w", uploadID, err)
+	}
+	if len(recs) != 1 {
+		return nil, fmt.Errorf("expected only one result for '%s', got %d", uploadID, len(recs))
+	}
+
+	pp := postprocessing.New(cfg.Postprocessing)
+	if err := json.Unmarshal(recs[0].Value, pp); err != nil {
+		return nil, err
+	}
+	return pp, nil
+}
+
+func formatConditions(c config.StepConditions) string {
+	var conditions []string
+	if len(c.MimeTypes) > 0 {
+		conditions = append(conditions, "mimetypes: "+strings.Join(c.MimeTypes, ", "))
+	}
+	if c.MinSize > 0 {
+		conditions = append(conditions, "min size: "+strconv.FormatUint(c.MinSize, 10))
+	}
+	if c.MaxSize > 0 {
+		conditions = append(conditions, "max size: "+strconv.FormatUint(c.MaxSize, 10))
+	}
+	if len(c.SpaceIDs) > 0 {
+		conditions = append(conditions, "spaces: "+strings.Join(c.SpaceIDs, ", "))
+	}
+	return strings.Join(conditions, "; ")
+}
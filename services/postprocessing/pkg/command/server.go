@@ -11,6 +11,7 @@ import (
 	"github.com/urfave/cli/v2"
 	microstore "go-micro.dev/v4/store"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
 	"github.com/owncloud/ocis/v2/ocis-pkg/generators"
 	"github.com/owncloud/ocis/v2/ocis-pkg/runner"
 	"github.com/owncloud/ocis/v2/ocis-pkg/tracing"
@@ -68,7 +69,24 @@ func Server(cfg *config.Config) *cli.Command {
 					store.TLS(cfg.Store.EnableTLS, cfg.Store.TLSInsecure, cfg.Store.TLSRootCACertificate),
 				)
 
-				svc, err := service.NewPostprocessingService(ctx, bus, logger, st, traceProvider, cfg.Postprocessing)
+				cas, err := casstore.New(st, casstore.Options{
+					Store:                cfg.Store.Store,
+					Nodes:                cfg.Store.Nodes,
+					Database:             cfg.Store.Database,
+					Table:                cfg.Store.Table,
+					TTL:                  cfg.Store.TTL,
+					AuthUsername:         cfg.Store.AuthUsername,
+					AuthPassword:         cfg.Store.AuthPassword,
+					EnableTLS:            cfg.Store.EnableTLS,
+					TLSInsecure:          cfg.Store.TLSInsecure,
+					TLSRootCACertificate: cfg.Store.TLSRootCACertificate,
+					ConnectionName:       generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeKeyValue),
+				})
+				if err != nil {
+					return err
+				}
+
+				svc, err := service.NewPostprocessingService(ctx, bus, logger, st, cas, traceProvider, cfg.Postprocessing)
 				if err != nil {
 					return err
 				}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
@@ -33,6 +34,34 @@ type Postprocessing struct {
 
 	RetryBackoffDuration time.Duration `yaml:"retry_backoff_duration" env:"POSTPROCESSING_RETRY_BACKOFF_DURATION" desc:"The base for the exponential backoff duration before retrying a failed postprocessing step. See the Environment Variable Types description for more details." introductionVersion:"5.0"`
 	MaxRetries           int           `yaml:"max_retries" env:"POSTPROCESSING_MAX_RETRIES" desc:"The maximum number of retries for a failed postprocessing step." introductionVersion:"5.0"`
+
+	StepGraph StepGraph `yaml:"step_graph" env:"POSTPROCESSING_STEP_GRAPH" desc:"The postprocessing steps as a graph of steps with their dependencies and conditions, encoded as JSON list. If set, POSTPROCESSING_STEPS is ignored. See the documentation for the format and more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// StepGraph is the list of steps of the postprocessing step graph.
+type StepGraph []Step
+
+// Decode implements the envdecode.Decoder interface
+func (g *StepGraph) Decode(value string) error {
+	return json.Unmarshal([]byte(value), g)
+}
+
+// Step defines a node of the postprocessing step graph.
+type Step struct {
+	Name       string         `yaml:"name" json:"name" desc:"The name of the step. The same keywords as for POSTPROCESSING_STEPS apply." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	DependsOn  []string       `yaml:"depends_on" json:"depends_on" desc:"The steps which need to be finished before the step is started." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Conditions StepConditions `yaml:"conditions" json:"conditions"`
+
+	MaxRetries           *int          `yaml:"max_retries" json:"max_retries" desc:"The maximum number of retries of the step. If not set, POSTPROCESSING_MAX_RETRIES is used." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	RetryBackoffDuration time.Duration `yaml:"retry_backoff_duration" json:"retry_backoff_duration" desc:"The base for the exponential backoff duration before retrying the step. If not set, POSTPROCESSING_RETRY_BACKOFF_DURATION is used." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// StepConditions restrict the uploads a step is run for. Unset conditions always match.
+type StepConditions struct {
+	MimeTypes []string `yaml:"mimetypes" json:"mimetypes" desc:"Patterns of the mimetypes the step is run for, like 'image/*'." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MinSize   uint64   `yaml:"min_size" json:"min_size" desc:"The minimum size in bytes of the files the step is run for." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxSize   uint64   `yaml:"max_size" json:"max_size" desc:"The maximum size in bytes of the files the step is run for." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	SpaceIDs  []string `yaml:"space_ids" json:"space_ids" desc:"The IDs of the spaces the step is run for." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
 }
 
 // Events combines the configuration options for the event bus.
@@ -57,11 +86,11 @@ type Debug struct {
 
 // Store configures the store to use
 type Store struct {
-	Store        string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;POSTPROCESSING_STORE" desc:"The type of the store. Supported values are: 'memory', 'redis-sentinel', 'nats-js-kv', 'noop'. See the text description for details." introductionVersion:"pre5.0"`
-	Nodes        []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;POSTPROCESSING_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"pre5.0"`
-	Database     string        `yaml:"database" env:"POSTPROCESSING_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"pre5.0"`
-	Table        string        `yaml:"table" env:"POSTPROCESSING_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"pre5.0"`
-	TTL          time.Duration `yaml:"ttl" env:"OCIS_PERSISTENT_STORE_TTL;POSTPROCESSING_STORE_TTL" desc:"Time to live for events in the store. See the Environment Variable Types description for more details." introductionVersion:"pre5.0"`
+	Store                string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;POSTPROCESSING_STORE" desc:"The type of the store. Supported values are: 'memory', 'redis-sentinel', 'nats-js-kv', 'noop'. See the text description for details." introductionVersion:"pre5.0"`
+	Nodes                []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;POSTPROCESSING_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"pre5.0"`
+	Database             string        `yaml:"database" env:"POSTPROCESSING_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"pre5.0"`
+	Table                string        `yaml:"table" env:"POSTPROCESSING_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"pre5.0"`
+	TTL                  time.Duration `yaml:"ttl" env:"OCIS_PERSISTENT_STORE_TTL;POSTPROCESSING_STORE_TTL" desc:"Time to live for events in the store. See the Environment Variable Types description for more details." introductionVersion:"pre5.0"`
 	AuthUsername         string        `yaml:"username" env:"OCIS_PERSISTENT_STORE_AUTH_USERNAME;POSTPROCESSING_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"5.0"`
 	AuthPassword         string        `yaml:"password" env:"OCIS_PERSISTENT_STORE_AUTH_PASSWORD;POSTPROCESSING_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"5.0"`
 	EnableTLS            bool          `yaml:"enable_tls" env:"OCIS_PERSISTENT_STORE_ENABLE_TLS;POSTPROCESSING_STORE_ENABLE_TLS" desc:"Activate TLS for the connection to the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"8.3.0"`
@@ -8,6 +8,7 @@ import (
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config/defaults"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
 	"github.com/owncloud/reva/v2/pkg/events"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/envdecode"
@@ -37,7 +38,18 @@ func ParseConfig(cfg *config.Config) error {
 
 // Validate validates the config
 func Validate(cfg *config.Config) error {
-	if cfg.Postprocessing.Delayprocessing != 0 {
+	switch {
+	case cfg.Postprocessing.Delayprocessing == 0:
+	case len(cfg.Postprocessing.StepGraph) > 0:
+		if !graphContains(cfg.Postprocessing.StepGraph, events.PPStepDelay) {
+			// the delay step runs after all other steps
+			delay := config.Step{Name: string(events.PPStepDelay)}
+			for _, s := range cfg.Postprocessing.StepGraph {
+				delay.DependsOn = append(delay.DependsOn, s.Name)
+			}
+			cfg.Postprocessing.StepGraph = append(cfg.Postprocessing.StepGraph, delay)
+		}
+	default:
 		if !contains(cfg.Postprocessing.Steps, events.PPStepDelay) {
 			if len(cfg.Postprocessing.Steps) > 0 {
 				s := strings.Join(append(cfg.Postprocessing.Steps, string(events.PPStepDelay)), ",")
@@ -47,9 +59,22 @@ func Validate(cfg *config.Config) error {
 			cfg.Postprocessing.Steps = append(cfg.Postprocessing.Steps, string(events.PPStepDelay))
 		}
 	}
+
+	if _, err := postprocessing.NewGraph(cfg.Postprocessing); err != nil {
+		return fmt.Errorf("invalid postprocessing step graph: %w", err)
+	}
 	return nil
 }
 
+func graphContains(steps []config.Step, candidate events.Postprocessingstep) bool {
+	for _, s := range steps {
+		if s.Name == string(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
 func contains(all []string, candidate events.Postprocessingstep) bool {
 	for _, s := range all {
 		if s == string(candidate) {
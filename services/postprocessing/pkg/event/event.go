@@ -0,0 +1,22 @@
+// Package event contains the events of the postprocessing service which are not part of reva.
+package event
+
+import (
+	"encoding/json"
+
+	types "github.com/cs3org/go-cs3apis/cs3/types/v1beta1"
+)
+
+// CancelPostprocessing can be emitted to cancel the postprocessing of an upload. The postprocessing is finished
+// with the abort outcome, the upload is marked as failed and its bytes are kept.
+type CancelPostprocessing struct {
+	UploadID  string
+	Timestamp *types.Timestamp
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (CancelPostprocessing) Unmarshal(v []byte) (interface{}, error) {
+	e := CancelPostprocessing{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
@@ -0,0 +1,159 @@
+package postprocessing
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/mime"
+)
+
+// Step is a node of the postprocessing step graph
+type Step struct {
+	Name                 events.Postprocessingstep
+	DependsOn            []events.Postprocessingstep `json:",omitempty"`
+	Conditions           config.StepConditions
+	MaxRetries           int
+	RetryBackoffDuration time.Duration
+}
+
+// Graph holds the postprocessing steps in an order where every step comes after its dependencies
+type Graph []Step
+
+// NewGraph resolves the step graph from the configuration. If no step graph is configured, the
+// plain list of steps is turned into a chain where every step depends on its predecessor.
+func NewGraph(c config.Postprocessing) (Graph, error) {
+	steps := c.StepGraph
+	if len(steps) == 0 {
+		for i, name := range c.Steps {
+			s := config.Step{Name: name}
+			if i > 0 {
+				s.DependsOn = []string{c.Steps[i-1]}
+			}
+			steps = append(steps, s)
+		}
+	}
+
+	nodes := make(map[events.Postprocessingstep]Step, len(steps))
+	order := make([]events.Postprocessingstep, 0, len(steps))
+	for _, s := range steps {
+		name := events.Postprocessingstep(s.Name)
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("postprocessing step without name")
+		case name == events.PPStepFinished:
+			return nil, fmt.Errorf("'%s' is a reserved postprocessing step name", name)
+		}
+		if _, ok := nodes[name]; ok {
+			return nil, fmt.Errorf("duplicate postprocessing step '%s'", name)
+		}
+
+		step := Step{
+			Name:                 name,
+			Conditions:           s.Conditions,
+			MaxRetries:           c.MaxRetries,
+			RetryBackoffDuration: s.RetryBackoffDuration,
+		}
+		if s.MaxRetries != nil {
+			step.MaxRetries = *s.MaxRetries
+		}
+		if step.RetryBackoffDuration == 0 {
+			step.RetryBackoffDuration = c.RetryBackoffDuration
+		}
+		for _, d := range s.DependsOn {
+			step.DependsOn = append(step.DependsOn, events.Postprocessingstep(d))
+		}
+
+		nodes[name] = step
+		order = append(order, name)
+	}
+
+	for _, name := range order {
+		for _, d := range nodes[name].DependsOn {
+			if _, ok := nodes[d]; !ok {
+				return nil, fmt.Errorf("postprocessing step '%s' depends on unknown step '%s'", name, d)
+			}
+		}
+	}
+
+	// sort topologically, steps which are ready at the same time keep their configured order
+	g := make(Graph, 0, len(order))
+	added := make(map[events.Postprocessingstep]bool, len(order))
+	for len(g) < len(order) {
+		progress := false
+		for _, name := range order {
+			if added[name] || !dependenciesMet(nodes[name], func(d events.Postprocessingstep) bool { return added[d] }) {
+				continue
+			}
+			g = append(g, nodes[name])
+			added[name] = true
+			progress = true
+		}
+
+		if !progress {
+			var cyclic []string
+			for _, name := range order {
+				if !added[name] {
+					cyclic = append(cyclic, string(name))
+				}
+			}
+			return nil, fmt.Errorf("postprocessing steps contain a cycle: %s", strings.Join(cyclic, ", "))
+		}
+	}
+
+	return g, nil
+}
+
+// Names returns the names of all steps of the graph
+func (g Graph) Names() []events.Postprocessingstep {
+	names := make([]events.Postprocessingstep, 0, len(g))
+	for _, s := range g {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// Step returns the step with the given name
+func (g Graph) Step(name events.Postprocessingstep) (Step, bool) {
+	for _, s := range g {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Step{}, false
+}
+
+// Matches checks if the step needs to run for an upload
+func (s Step) Matches(filename string, filesize uint64, spaceID string) bool {
+	c := s.Conditions
+	if c.MinSize > 0 && filesize < c.MinSize {
+		return false
+	}
+	if c.MaxSize > 0 && filesize > c.MaxSize {
+		return false
+	}
+	if len(c.SpaceIDs) > 0 && !slices.Contains(c.SpaceIDs, spaceID) {
+		return false
+	}
+	if len(c.MimeTypes) > 0 {
+		mimetype := mime.Detect(false, filename)
+		return slices.ContainsFunc(c.MimeTypes, func(pattern string) bool {
+			ok, _ := path.Match(pattern, mimetype)
+			return ok
+		})
+	}
+	return true
+}
+
+func dependenciesMet(s Step, done func(events.Postprocessingstep) bool) bool {
+	for _, d := range s.DependsOn {
+		if !done(d) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package postprocessing
 
 import (
 	"math"
+	"slices"
 	"time"
 
 	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
@@ -20,20 +21,42 @@ type Postprocessing struct {
 	Filesize          uint64
 	ResourceID        *provider.ResourceId
 	Steps             []events.Postprocessingstep
+	Graph             Graph `json:",omitempty"`
 	Status            Status
 	Failures          int
+	StepFailures      map[events.Postprocessingstep]int `json:",omitempty"`
 	InitiatorID       string
+	Started           time.Time
 	Finished          bool
+	// Revision is the revision of the stored postprocessing it was read with
+	Revision uint64 `json:"-"`
 
 	config config.Postprocessing
 }
 
 // Status is helper struct to show current postprocessing status
 type Status struct {
-	CurrentStep events.Postprocessingstep
-	Outcome     events.PostprocessingOutcome
+	CurrentStep   events.Postprocessingstep
+	Outcome       events.PostprocessingOutcome
+	RunningSteps  []events.Postprocessingstep `json:",omitempty"`
+	FinishedSteps []events.Postprocessingstep `json:",omitempty"`
+	SkippedSteps  []events.Postprocessingstep `json:",omitempty"`
 }
 
+// StepState describes the progress of a single step of an upload
+type StepState string
+
+const (
+	// StepPending means the step waits for its dependencies
+	StepPending StepState = "pending"
+	// StepRunning means the step has been started
+	StepRunning StepState = "running"
+	// StepDone means the step has finished successfully
+	StepDone StepState = "done"
+	// StepSkipped means the conditions of the step did not match the upload
+	StepSkipped StepState = "skipped"
+)
+
 // New returns a new postprocessing instance
 func New(config config.Postprocessing) *Postprocessing {
 	return &Postprocessing{
@@ -41,65 +64,173 @@ func New(config config.Postprocessing) *Postprocessing {
 	}
 }
 
-// Init is the first step of the postprocessing
-func (pp *Postprocessing) Init(_ events.BytesReceived) interface{} {
-	if len(pp.Steps) == 0 {
-		return pp.finished(events.PPOutcomeContinue)
+// Init is the first step of the postprocessing. It returns the events to publish.
+func (pp *Postprocessing) Init(_ events.BytesReceived) []interface{} {
+	pp.ensureGraph()
+	return pp.advance()
+}
+
+// NextStep returns the events for the steps which can be started after the given step finished
+func (pp *Postprocessing) NextStep(ev events.PostprocessingStepFinished) []interface{} {
+	if pp.Status.CurrentStep == events.PPStepFinished {
+		// a parallel step finished after postprocessing was already decided
+		return nil
 	}
 
-	return pp.step(pp.Steps[0])
-}
+	pp.ensureGraph()
+	if !slices.Contains(pp.Status.RunningSteps, ev.FinishedStep) {
+		// unknown or duplicate event
+		return nil
+	}
 
-// NextStep returns the next postprocessing step
-func (pp *Postprocessing) NextStep(ev events.PostprocessingStepFinished) interface{} {
 	switch ev.Outcome {
 	case events.PPOutcomeContinue:
 		return pp.next(ev.FinishedStep)
 	case events.PPOutcomeRetry:
+		if pp.StepFailures == nil {
+			pp.StepFailures = make(map[events.Postprocessingstep]int)
+		}
 		pp.Failures++
-		if pp.Failures > pp.config.MaxRetries {
-			return pp.finished(events.PPOutcomeAbort)
+		pp.StepFailures[ev.FinishedStep]++
+		step, _ := pp.Graph.Step(ev.FinishedStep)
+		if pp.StepFailures[ev.FinishedStep] > step.MaxRetries {
+			return []interface{}{pp.finished(events.PPOutcomeAbort)}
 		}
-		return pp.retry()
+		return []interface{}{pp.retry(ev.FinishedStep)}
 	default:
-		return pp.finished(ev.Outcome)
+		return []interface{}{pp.finished(ev.Outcome)}
+	}
+}
+
+// Cancel finishes the postprocessing with the abort outcome. Nothing is returned if it is already finished.
+func (pp *Postprocessing) Cancel() []interface{} {
+	if pp.Status.CurrentStep == events.PPStepFinished {
+		return nil
 	}
+	return []interface{}{pp.finished(events.PPOutcomeAbort)}
 }
 
-// CurrentStep returns the current postprocessing step
-func (pp *Postprocessing) CurrentStep() interface{} {
+// CurrentSteps returns the events to restart all currently running steps
+func (pp *Postprocessing) CurrentSteps() []interface{} {
 	if pp.Status.CurrentStep == events.PPStepFinished {
-		return pp.finished(pp.Status.Outcome)
+		return []interface{}{pp.finished(pp.Status.Outcome)}
+	}
+
+	pp.ensureGraph()
+	if len(pp.Status.RunningSteps) == 0 {
+		return pp.advance()
+	}
+
+	next := make([]interface{}, 0, len(pp.Status.RunningSteps))
+	for _, s := range pp.Status.RunningSteps {
+		next = append(next, pp.step(s))
+	}
+	return next
+}
+
+// ResolvedGraph returns the step graph of the upload. Uploads which were started before step graphs
+// were introduced get a chain of their configured steps.
+func (pp *Postprocessing) ResolvedGraph() Graph {
+	pp.ensureGraph()
+	return pp.Graph
+}
+
+// StepState returns the state of the given step
+func (pp *Postprocessing) StepState(step events.Postprocessingstep) StepState {
+	switch {
+	case slices.Contains(pp.Status.FinishedSteps, step):
+		return StepDone
+	case slices.Contains(pp.Status.SkippedSteps, step):
+		return StepSkipped
+	case slices.Contains(pp.Status.RunningSteps, step):
+		return StepRunning
+	default:
+		return StepPending
 	}
-	return pp.step(pp.Status.CurrentStep)
 }
 
-// Delay will sleep the configured time then continue
-func (pp *Postprocessing) Delay(f func(next interface{})) {
-	next := pp.next(events.PPStepDelay)
-	go func() {
-		time.Sleep(pp.config.Delayprocessing)
-		f(next)
-	}()
+// Delay finishes the delay step. The returned events have to be published after the configured delay.
+func (pp *Postprocessing) Delay() []interface{} {
+	return pp.NextStep(events.PostprocessingStepFinished{FinishedStep: events.PPStepDelay, Outcome: events.PPOutcomeContinue})
 }
 
-// BackoffDuration calculates the duration for exponential backoff based on the number of failures.
-func (pp *Postprocessing) BackoffDuration() time.Duration {
-	return pp.config.RetryBackoffDuration * time.Duration(math.Pow(2, float64(pp.Failures-1)))
+// BackoffDuration calculates the duration for exponential backoff based on the number of failures of a step.
+func (pp *Postprocessing) BackoffDuration(step events.Postprocessingstep) time.Duration {
+	backoff := pp.config.RetryBackoffDuration
+	if s, ok := pp.Graph.Step(step); ok {
+		backoff = s.RetryBackoffDuration
+	}
+	return backoff * time.Duration(math.Pow(2, float64(pp.StepFailures[step]-1)))
 }
 
-func (pp *Postprocessing) next(current events.Postprocessingstep) interface{} {
-	l := len(pp.Steps)
-	for i, s := range pp.Steps {
-		if s == current && i+1 < l {
-			return pp.step(pp.Steps[i+1])
+// ensureGraph migrates postprocessings which were stored before the step graph was introduced
+func (pp *Postprocessing) ensureGraph() {
+	if pp.Graph != nil {
+		return
+	}
+
+	pp.Graph, _ = NewGraph(config.Postprocessing{
+		Steps:                stepNames(pp.Steps),
+		MaxRetries:           pp.config.MaxRetries,
+		RetryBackoffDuration: pp.config.RetryBackoffDuration,
+	})
+
+	current := pp.Status.CurrentStep
+	if current == "" || current == events.PPStepFinished || len(pp.Status.RunningSteps) > 0 {
+		return
+	}
+	for _, s := range pp.Steps {
+		if s == current {
+			pp.Status.RunningSteps = []events.Postprocessingstep{s}
+			break
 		}
+		pp.Status.FinishedSteps = append(pp.Status.FinishedSteps, s)
+	}
+	if pp.StepFailures == nil && pp.Failures > 0 {
+		pp.StepFailures = map[events.Postprocessingstep]int{current: pp.Failures}
 	}
-	return pp.finished(events.PPOutcomeContinue)
+}
+
+func (pp *Postprocessing) next(current events.Postprocessingstep) []interface{} {
+	pp.Status.RunningSteps = slices.DeleteFunc(pp.Status.RunningSteps, func(s events.Postprocessingstep) bool { return s == current })
+	pp.Status.FinishedSteps = append(pp.Status.FinishedSteps, current)
+	return pp.advance()
+}
+
+// advance starts all steps whose dependencies are met. As the graph is sorted, steps which are
+// skipped because of their conditions unblock their dependents in the same pass.
+func (pp *Postprocessing) advance() []interface{} {
+	spaceID := pp.ResourceID.GetSpaceId()
+
+	var next []interface{}
+	for _, s := range pp.Graph {
+		if pp.StepState(s.Name) != StepPending || !dependenciesMet(s, pp.completed) {
+			continue
+		}
+
+		if !s.Matches(pp.Filename, pp.Filesize, spaceID) {
+			pp.Status.SkippedSteps = append(pp.Status.SkippedSteps, s.Name)
+			continue
+		}
+
+		pp.Status.RunningSteps = append(pp.Status.RunningSteps, s.Name)
+		next = append(next, pp.step(s.Name))
+	}
+
+	if len(pp.Status.RunningSteps) == 0 {
+		return []interface{}{pp.finished(events.PPOutcomeContinue)}
+	}
+
+	pp.Status.CurrentStep = pp.Status.RunningSteps[0]
+	return next
+}
+
+func (pp *Postprocessing) completed(step events.Postprocessingstep) bool {
+	state := pp.StepState(step)
+	return state == StepDone || state == StepSkipped
 }
 
 func (pp *Postprocessing) step(next events.Postprocessingstep) events.StartPostprocessingStep {
-	pp.Status.CurrentStep = next
 	return events.StartPostprocessingStep{
 		UploadID:          pp.ID,
 		URL:               pp.URL,
@@ -115,6 +246,7 @@ func (pp *Postprocessing) step(next events.Postprocessingstep) events.StartPostp
 func (pp *Postprocessing) finished(outcome events.PostprocessingOutcome) events.PostprocessingFinished {
 	pp.Status.CurrentStep = events.PPStepFinished
 	pp.Status.Outcome = outcome
+	pp.Status.RunningSteps = nil
 	return events.PostprocessingFinished{
 		UploadID:          pp.ID,
 		ExecutingUser:     pp.User,
@@ -125,13 +257,21 @@ func (pp *Postprocessing) finished(outcome events.PostprocessingOutcome) events.
 	}
 }
 
-func (pp *Postprocessing) retry() events.PostprocessingRetry {
+func (pp *Postprocessing) retry(step events.Postprocessingstep) events.PostprocessingRetry {
 	pp.Status.Outcome = events.PPOutcomeRetry
 	return events.PostprocessingRetry{
 		UploadID:        pp.ID,
 		ExecutingUser:   pp.User,
 		Filename:        pp.Filename,
-		Failures:        pp.Failures,
-		BackoffDuration: pp.BackoffDuration(),
+		Failures:        pp.StepFailures[step],
+		BackoffDuration: pp.BackoffDuration(step),
+	}
+}
+
+func stepNames(steps []events.Postprocessingstep) []string {
+	names := make([]string, 0, len(steps))
+	for _, s := range steps {
+		names = append(names, string(s))
 	}
+	return names
 }
@@ -0,0 +1,165 @@
+package postprocessing_test
+
+import (
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+)
+
+func startedSteps(next []interface{}) []events.Postprocessingstep {
+	var steps []events.Postprocessingstep
+	for _, n := range next {
+		if ev, ok := n.(events.StartPostprocessingStep); ok {
+			steps = append(steps, ev.StepToStart)
+		}
+	}
+	return steps
+}
+
+func finish(pp *postprocessing.Postprocessing, step events.Postprocessingstep, outcome events.PostprocessingOutcome) []interface{} {
+	return pp.NextStep(events.PostprocessingStepFinished{UploadID: pp.ID, FinishedStep: step, Outcome: outcome})
+}
+
+func newPostprocessing(t *testing.T, c config.Postprocessing) *postprocessing.Postprocessing {
+	graph, err := postprocessing.NewGraph(c)
+	require.NoError(t, err)
+
+	pp := postprocessing.New(c)
+	pp.ID = "upload"
+	pp.Filename = "image.png"
+	pp.Filesize = 1024
+	pp.ResourceID = &provider.ResourceId{SpaceId: "space"}
+	pp.Graph = graph
+	return pp
+}
+
+func TestNewGraph(t *testing.T) {
+	t.Run("a list of steps becomes a chain", func(t *testing.T) {
+		g, err := postprocessing.NewGraph(config.Postprocessing{Steps: []string{"virusscan", "policies"}, MaxRetries: 3, RetryBackoffDuration: time.Second})
+		require.NoError(t, err)
+		assert.Equal(t, postprocessing.Graph{
+			{Name: "virusscan", MaxRetries: 3, RetryBackoffDuration: time.Second},
+			{Name: "policies", DependsOn: []events.Postprocessingstep{"virusscan"}, MaxRetries: 3, RetryBackoffDuration: time.Second},
+		}, g)
+	})
+
+	t.Run("steps are sorted by their dependencies", func(t *testing.T) {
+		g, err := postprocessing.NewGraph(config.Postprocessing{StepGraph: []config.Step{
+			{Name: "thumbnails", DependsOn: []string{"virusscan"}},
+			{Name: "virusscan"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []events.Postprocessingstep{"virusscan", "thumbnails"}, g.Names())
+	})
+
+	t.Run("invalid graphs are rejected", func(t *testing.T) {
+		for name, steps := range map[string][]config.Step{
+			"cycle":      {{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}},
+			"unknown":    {{Name: "a", DependsOn: []string{"b"}}},
+			"duplicates": {{Name: "a"}, {Name: "a"}},
+			"reserved":   {{Name: "finished"}},
+			"unnamed":    {{}},
+		} {
+			_, err := postprocessing.NewGraph(config.Postprocessing{StepGraph: steps})
+			assert.Error(t, err, name)
+		}
+	})
+}
+
+func TestPostprocessing_LinearSteps(t *testing.T) {
+	pp := newPostprocessing(t, config.Postprocessing{Steps: []string{"virusscan", "policies"}})
+
+	assert.Equal(t, []events.Postprocessingstep{"virusscan"}, startedSteps(pp.Init(events.BytesReceived{})))
+	assert.Equal(t, []events.Postprocessingstep{"policies"}, startedSteps(finish(pp, "virusscan", events.PPOutcomeContinue)))
+
+	next := finish(pp, "policies", events.PPOutcomeContinue)
+	require.Len(t, next, 1)
+	assert.Equal(t, events.PPOutcomeContinue, next[0].(events.PostprocessingFinished).Outcome)
+	assert.Equal(t, events.PPStepFinished, pp.Status.CurrentStep)
+}
+
+func TestPostprocessing_ParallelSteps(t *testing.T) {
+	pp := newPostprocessing(t, config.Postprocessing{StepGraph: []config.Step{
+		{Name: "virusscan"},
+		{Name: "policies"},
+		{Name: "thumbnails", DependsOn: []string{"virusscan", "policies"}},
+	}})
+
+	assert.Equal(t, []events.Postprocessingstep{"virusscan", "policies"}, startedSteps(pp.Init(events.BytesReceived{})))
+	assert.Empty(t, finish(pp, "policies", events.PPOutcomeContinue))
+	assert.Equal(t, []events.Postprocessingstep{"thumbnails"}, startedSteps(finish(pp, "virusscan", events.PPOutcomeContinue)))
+	assert.Equal(t, postprocessing.StepRunning, pp.StepState("thumbnails"))
+
+	t.Run("late results are ignored once postprocessing finished", func(t *testing.T) {
+		pp := newPostprocessing(t, config.Postprocessing{StepGraph: []config.Step{{Name: "virusscan"}, {Name: "policies"}}})
+		pp.Init(events.BytesReceived{})
+
+		next := finish(pp, "virusscan", events.PPOutcomeDelete)
+		require.Len(t, next, 1)
+		assert.Equal(t, events.PPOutcomeDelete, next[0].(events.PostprocessingFinished).Outcome)
+		assert.Empty(t, finish(pp, "policies", events.PPOutcomeContinue))
+	})
+}
+
+func TestPostprocessing_Conditions(t *testing.T) {
+	pp := newPostprocessing(t, config.Postprocessing{StepGraph: []config.Step{
+		{Name: "virusscan", Conditions: config.StepConditions{MaxSize: 512}},
+		{Name: "thumbnails", DependsOn: []string{"virusscan"}, Conditions: config.StepConditions{MimeTypes: []string{"image/*"}}},
+		{Name: "policies", DependsOn: []string{"thumbnails"}, Conditions: config.StepConditions{SpaceIDs: []string{"other"}}},
+	}})
+
+	assert.Equal(t, []events.Postprocessingstep{"thumbnails"}, startedSteps(pp.Init(events.BytesReceived{})))
+	assert.Equal(t, postprocessing.StepSkipped, pp.StepState("virusscan"))
+
+	next := finish(pp, "thumbnails", events.PPOutcomeContinue)
+	require.Len(t, next, 1)
+	assert.IsType(t, events.PostprocessingFinished{}, next[0])
+	assert.Equal(t, postprocessing.StepSkipped, pp.StepState("policies"))
+}
+
+func TestPostprocessing_Retries(t *testing.T) {
+	one, zero := 1, 0
+	pp := newPostprocessing(t, config.Postprocessing{
+		MaxRetries:           5,
+		RetryBackoffDuration: time.Second,
+		StepGraph: []config.Step{
+			{Name: "virusscan", MaxRetries: &one, RetryBackoffDuration: time.Minute},
+		},
+	})
+	pp.Init(events.BytesReceived{})
+
+	next := finish(pp, "virusscan", events.PPOutcomeRetry)
+	require.Len(t, next, 1)
+	assert.Equal(t, time.Minute, next[0].(events.PostprocessingRetry).BackoffDuration)
+
+	next = finish(pp, "virusscan", events.PPOutcomeRetry)
+	require.Len(t, next, 1)
+	assert.Equal(t, events.PPOutcomeAbort, next[0].(events.PostprocessingFinished).Outcome)
+
+	// a step which must not be retried
+	pp = newPostprocessing(t, config.Postprocessing{
+		MaxRetries: 5,
+		StepGraph:  []config.Step{{Name: "policies", MaxRetries: &zero}},
+	})
+	pp.Init(events.BytesReceived{})
+	next = finish(pp, "policies", events.PPOutcomeRetry)
+	require.Len(t, next, 1)
+	assert.Equal(t, events.PPOutcomeAbort, next[0].(events.PostprocessingFinished).Outcome)
+}
+
+func TestPostprocessing_StoredBeforeGraph(t *testing.T) {
+	pp := postprocessing.New(config.Postprocessing{MaxRetries: 3})
+	pp.Steps = []events.Postprocessingstep{"virusscan", "policies", "delay"}
+	pp.Status.CurrentStep = "policies"
+
+	assert.Equal(t, []events.Postprocessingstep{"policies"}, startedSteps(pp.CurrentSteps()))
+	assert.Equal(t, postprocessing.StepDone, pp.StepState("virusscan"))
+	assert.Equal(t, []events.Postprocessingstep{"delay"}, startedSteps(finish(pp, "policies", events.PPOutcomeContinue)))
+}
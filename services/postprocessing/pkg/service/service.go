@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	ocissync "github.com/owncloud/ocis/v2/ocis-pkg/sync"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/event"
 	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
 	"github.com/owncloud/reva/v2/pkg/autoprop"
 	ctxpkg "github.com/owncloud/reva/v2/pkg/ctx"
@@ -26,12 +30,14 @@ type PostprocessingService struct {
 	log     log.Logger
 	events  <-chan events.Event
 	pub     events.Publisher
-	steps   []events.Postprocessingstep
+	graph   postprocessing.Graph
 	store   store.Store
+	cas     casstore.Store
 	c       config.Postprocessing
 	tp      trace.TracerProvider
 	stopCh  chan struct{}
 	stopped atomic.Bool
+	uploads ocissync.NamedRWMutex
 }
 
 var (
@@ -41,31 +47,45 @@ var (
 	ErrEvent = errors.New("event error")
 	// ErrNotFound is returned when a postprocessing is not found in the store.
 	ErrNotFound = errors.New("postprocessing not found")
+
+	// errConflict is returned when a postprocessing was updated concurrently
+	errConflict = errors.New("postprocessing was updated concurrently")
 )
 
+// _maxConflictRetries is the number of times an event is processed again after a concurrent update
+const _maxConflictRetries = 5
+
 // NewPostprocessingService returns a new instance of a postprocessing service
-func NewPostprocessingService(ctx context.Context, stream events.Stream, logger log.Logger, sto store.Store, tp trace.TracerProvider, c config.Postprocessing) (*PostprocessingService, error) {
+func NewPostprocessingService(ctx context.Context, stream events.Stream, logger log.Logger, sto store.Store, cas casstore.Store, tp trace.TracerProvider, c config.Postprocessing) (*PostprocessingService, error) {
+	graph, err := postprocessing.NewGraph(c)
+	if err != nil {
+		return nil, err
+	}
+
 	evs, err := events.Consume(stream, "postprocessing",
 		events.BytesReceived{},
 		events.StartPostprocessingStep{},
 		events.UploadReady{},
 		events.PostprocessingStepFinished{},
 		events.ResumePostprocessing{},
+		event.CancelPostprocessing{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
 	return &PostprocessingService{
-		ctx:    ctx,
-		log:    logger,
-		events: evs,
-		pub:    stream,
-		steps:  getSteps(c),
-		store:  sto,
-		c:      c,
-		tp:     tp,
-		stopCh: make(chan struct{}, 1),
+		ctx:     ctx,
+		log:     logger,
+		events:  evs,
+		pub:     stream,
+		graph:   graph,
+		store:   sto,
+		cas:     cas,
+		c:       c,
+		tp:      tp,
+		stopCh:  make(chan struct{}, 1),
+		uploads: ocissync.NewNamedRWMutex(),
 	}, nil
 }
 
@@ -137,17 +157,47 @@ func (pps *PostprocessingService) Close() {
 func (pps *PostprocessingService) processEvent(e events.Event) error {
 	pps.log.Debug().Str("Type", e.Type).Str("ID", e.ID).Msg("processing event received")
 
-	var (
-		next interface{}
-		pp   *postprocessing.Postprocessing
-		err  error
-	)
-
 	evCtx := context.Background()
 	ctx, span := events.TraceEventConsumer(evCtx, pps.tp, e)
 	ctx = autoprop.SetMetaToContext(ctx, e.ExtraInfo)
 	defer span.End()
 
+	if ev, ok := e.Event.(events.ResumePostprocessing); ok {
+		pps.log.Info().Str("UploadID", ev.UploadID).Str("step", string(ev.Step)).Msg("processing resumed")
+		return pps.handleResumePPEvent(ctx, ev)
+	}
+
+	// the events of an upload are processed one after another, other instances of the service
+	// are detected by the revision of the stored postprocessing
+	if id := uploadID(e.Event); id != "" {
+		pps.uploads.Lock(id)
+		defer pps.uploads.Unlock(id)
+	}
+
+	for i := 0; ; i++ {
+		err := pps.handleEvent(ctx, e)
+		if !errors.Is(err, errConflict) {
+			return err
+		}
+		if i == _maxConflictRetries {
+			return fmt.Errorf("%w: %s", ErrEvent, err)
+		}
+		pps.log.Debug().Str("uploadID", uploadID(e.Event)).Msg("upload was updated concurrently, processing event again")
+	}
+}
+
+// handleEvent processes a single event, errConflict is returned if the upload was updated concurrently
+func (pps *PostprocessingService) handleEvent(ctx context.Context, e events.Event) error {
+	var (
+		next []interface{}
+		pp   *postprocessing.Postprocessing
+		err  error
+		// retry and delay are scheduled once the postprocessing is stored
+		retry   *events.StartPostprocessingStep
+		backoff time.Duration
+		delayed []interface{}
+	)
+
 	switch ev := e.Event.(type) {
 	case events.BytesReceived:
 		pp = &postprocessing.Postprocessing{
@@ -157,8 +207,10 @@ func (pps *PostprocessingService) processEvent(e events.Event) error {
 			Filename:          ev.Filename,
 			Filesize:          ev.Filesize,
 			ResourceID:        ev.ResourceID,
-			Steps:             pps.steps,
+			Steps:             pps.graph.Names(),
+			Graph:             pps.graph,
 			InitiatorID:       e.InitiatorID,
+			Started:           time.Now(),
 			ImpersonatingUser: ev.ImpersonatingUser,
 		}
 		pps.log.Info().Str("UploadID", ev.UploadID).Msg("processing init")
@@ -174,34 +226,26 @@ func (pps *PostprocessingService) processEvent(e events.Event) error {
 			// no current upload - this was an on demand scan
 			return nil
 		}
-		pp, err = pps.getPP(pps.store, ev.UploadID)
+		pp, err = pps.getPP(pps.cas, ev.UploadID)
 		if err != nil {
 			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
 			return fmt.Errorf("%w: cannot get upload", ErrEvent)
 		}
 		next = pp.NextStep(ev)
 
-		if pp.Status.Outcome == events.PPOutcomeRetry {
-			// schedule retry
-			backoff := pp.BackoffDuration()
+		if isRetry(next) {
+			backoff = pp.BackoffDuration(ev.FinishedStep)
 			pps.log.Info().Str("UploadID", ev.UploadID).Str("step", string(ev.FinishedStep)).Err(ev.Error).Msg("retrying step in " + backoff.String())
-			go func() {
-				time.Sleep(backoff)
-				retryEvent := events.StartPostprocessingStep{
-					UploadID:          pp.ID,
-					URL:               pp.URL,
-					ExecutingUser:     pp.User,
-					Filename:          pp.Filename,
-					Filesize:          pp.Filesize,
-					ResourceID:        pp.ResourceID,
-					StepToStart:       pp.Status.CurrentStep,
-					ImpersonatingUser: pp.ImpersonatingUser,
-				}
-				err := events.Publish(ctx, pps.pub, retryEvent)
-				if err != nil {
-					pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot publish RestartPostprocessing event")
-				}
-			}()
+			retry = &events.StartPostprocessingStep{
+				UploadID:          pp.ID,
+				URL:               pp.URL,
+				ExecutingUser:     pp.User,
+				Filename:          pp.Filename,
+				Filesize:          pp.Filesize,
+				ResourceID:        pp.ResourceID,
+				StepToStart:       ev.FinishedStep,
+				ImpersonatingUser: pp.ImpersonatingUser,
+			}
 		}
 
 		if pp.Status.CurrentStep == events.PPStepFinished {
@@ -213,27 +257,31 @@ func (pps *PostprocessingService) processEvent(e events.Event) error {
 		if ev.StepToStart != events.PPStepDelay {
 			return nil
 		}
-		pp, err = pps.getPP(pps.store, ev.UploadID)
+		pp, err = pps.getPP(pps.cas, ev.UploadID)
 		if err != nil {
 			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
 			return fmt.Errorf("%w: cannot get upload", ErrEvent)
 		}
-		pp.Delay(func(next interface{}) {
-			if err := events.Publish(ctx, pps.pub, next); err != nil {
-				pps.log.Error().Err(err).Msg("cannot publish event")
-			}
-		})
+		delayed = pp.Delay()
+	case event.CancelPostprocessing:
+		pp, err = pps.getPP(pps.cas, ev.UploadID)
+		if err != nil {
+			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
+			return fmt.Errorf("%w: cannot get upload", ErrEvent)
+		}
+		pps.log.Info().Str("UploadID", ev.UploadID).Msg("processing cancelled")
+		next = pp.Cancel()
 	case events.UploadReady:
 		pps.log.Debug().Str("UploadID", e.ID).Str("filename", ev.Filename).Msg("processing UploadReady")
 		if ev.Failed {
 			// the upload failed - let's keep it around for a while - but mark it as finished
-			pp, err = pps.getPP(pps.store, ev.UploadID)
+			pp, err = pps.getPP(pps.cas, ev.UploadID)
 			if err != nil {
 				pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot get upload")
 				return fmt.Errorf("%w: cannot get upload", ErrEvent)
 			}
 			pp.Finished = true
-			return storePP(pps.store, pp)
+			return pps.storePP(pps.cas, pp)
 		}
 
 		// the storage provider thinks the upload is done - so no need to keep it any more
@@ -241,22 +289,42 @@ func (pps *PostprocessingService) processEvent(e events.Event) error {
 			pps.log.Error().Str("uploadID", ev.UploadID).Err(err).Msg("cannot delete upload")
 			return fmt.Errorf("%w: cannot delete upload", ErrEvent)
 		}
-	case events.ResumePostprocessing:
-		pps.log.Info().Str("UploadID", ev.UploadID).Str("step", string(ev.Step)).Msg("processing resumed")
-		return pps.handleResumePPEvent(ctx, ev)
 	}
 
 	if pp != nil {
 		ctx = ctxpkg.ContextSetInitiator(ctx, pp.InitiatorID)
 
-		if err := storePP(pps.store, pp); err != nil {
+		if err := pps.storePP(pps.cas, pp); err != nil {
+			if errors.Is(err, errConflict) {
+				return err
+			}
 			pps.log.Error().Str("uploadID", pp.ID).Err(err).Msg("cannot store upload")
 			return fmt.Errorf("%w: cannot store upload", ErrEvent)
 		}
 	}
 
-	if next != nil {
-		if err := events.Publish(ctx, pps.pub, next); err != nil {
+	if retry != nil {
+		go func() {
+			time.Sleep(backoff)
+			if err := events.Publish(ctx, pps.pub, *retry); err != nil {
+				pps.log.Error().Str("uploadID", retry.UploadID).Err(err).Msg("cannot publish RestartPostprocessing event")
+			}
+		}()
+	}
+
+	if len(delayed) > 0 {
+		go func() {
+			time.Sleep(pps.c.Delayprocessing)
+			for _, n := range delayed {
+				if err := events.Publish(ctx, pps.pub, n); err != nil {
+					pps.log.Error().Err(err).Msg("cannot publish event")
+				}
+			}
+		}()
+	}
+
+	for _, n := range next {
+		if err := events.Publish(ctx, pps.pub, n); err != nil {
 			pps.log.Error().Err(err).Msg("unable to publish event")
 			return fmt.Errorf("%w: unable to publish event", ErrFatal) // we can't publish -> we are screwed
 		}
@@ -264,10 +332,37 @@ func (pps *PostprocessingService) processEvent(e events.Event) error {
 	return nil
 }
 
-func (pps *PostprocessingService) getPP(sto store.Store, uploadID string) (*postprocessing.Postprocessing, error) {
-	recs, err := sto.Read(uploadID)
+// uploadID returns the id of the upload an event belongs to
+func uploadID(ev interface{}) string {
+	switch ev := ev.(type) {
+	case events.BytesReceived:
+		return ev.UploadID
+	case events.PostprocessingStepFinished:
+		return ev.UploadID
+	case events.StartPostprocessingStep:
+		return ev.UploadID
+	case events.UploadReady:
+		return ev.UploadID
+	case event.CancelPostprocessing:
+		return ev.UploadID
+	default:
+		return ""
+	}
+}
+
+func isRetry(next []interface{}) bool {
+	for _, n := range next {
+		if _, ok := n.(events.PostprocessingRetry); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (pps *PostprocessingService) getPP(sto casstore.Store, uploadID string) (*postprocessing.Postprocessing, error) {
+	value, rev, err := sto.Read(uploadID)
 	if err != nil {
-		if err == store.ErrNotFound {
+		if errors.Is(err, casstore.ErrNotFound) {
 			pps.log.Error().Str("uploadID", uploadID).Err(err).Msg("reading from store: upload not found in the store")
 			return nil, ErrNotFound
 		}
@@ -275,48 +370,33 @@ func (pps *PostprocessingService) getPP(sto store.Store, uploadID string) (*post
 		return nil, err
 	}
 
-	if len(recs) == 0 {
-		pps.log.Error().Str("uploadID", uploadID).Err(err).Msg("reading from store: empty upload records in the store")
-		return nil, ErrNotFound
-	}
-
-	if len(recs) > 1 {
-		pps.log.Error().Str("uploadID", uploadID).Int("records", len(recs)).Err(err).Msg("reading from store: expected only one result")
-		return nil, fmt.Errorf("expected only one result for '%s', got %d", uploadID, len(recs))
-	}
-
 	pp := postprocessing.New(pps.c)
-	err = json.Unmarshal(recs[0].Value, pp)
+	err = json.Unmarshal(value, pp)
 	if err != nil {
 		pps.log.Error().Str("uploadID", uploadID).Err(err).Msg("reading from store: unmarshaling error")
 		return nil, err
 	}
+	pp.Revision = rev
 
 	return pp, nil
 }
 
-func getSteps(c config.Postprocessing) []events.Postprocessingstep {
-	// NOTE: improved version only allows configuring order of postprocessing steps
-	// But we aim for a system where postprocessing steps can be configured per space, ideally by the spaceadmin itself
-	// We need to iterate over configuring PP service when we see fit
-	steps := make([]events.Postprocessingstep, 0, len(c.Steps))
-	for _, s := range c.Steps {
-		steps = append(steps, events.Postprocessingstep(s))
-	}
-
-	return steps
-}
-
-func storePP(sto store.Store, pp *postprocessing.Postprocessing) error {
+// storePP writes the postprocessing to the store. errConflict is returned if the stored postprocessing
+// was updated or removed since it was read, new postprocessings overwrite existing ones.
+func (pps *PostprocessingService) storePP(sto casstore.Store, pp *postprocessing.Postprocessing) error {
 	b, err := json.Marshal(pp)
 	if err != nil {
 		return err
 	}
 
-	return sto.Write(&store.Record{
-		Key:   pp.ID,
-		Value: b,
-	})
+	if pp.Revision == 0 {
+		return sto.Put(pp.ID, b)
+	}
+	err = sto.Update(pp.ID, b, pp.Revision)
+	if errors.Is(err, casstore.ErrConflict) {
+		return fmt.Errorf("%w: %s", errConflict, err)
+	}
+	return err
 }
 
 func (pps *PostprocessingService) handleResumePPEvent(ctx context.Context, ev events.ResumePostprocessing) error {
@@ -334,7 +414,19 @@ func (pps *PostprocessingService) handleResumePPEvent(ctx context.Context, ev ev
 }
 
 func (pps *PostprocessingService) resumePP(ctx context.Context, uploadID string) error {
-	pp, err := pps.getPP(pps.store, uploadID)
+	pps.uploads.Lock(uploadID)
+	defer pps.uploads.Unlock(uploadID)
+
+	for i := 0; ; i++ {
+		err := pps.resumeUpload(ctx, uploadID)
+		if !errors.Is(err, errConflict) || i == _maxConflictRetries {
+			return err
+		}
+	}
+}
+
+func (pps *PostprocessingService) resumeUpload(ctx context.Context, uploadID string) error {
+	pp, err := pps.getPP(pps.cas, uploadID)
 	if err != nil {
 		if err == ErrNotFound {
 			if err := events.Publish(ctx, pps.pub, events.RestartPostprocessing{
@@ -353,7 +445,17 @@ func (pps *PostprocessingService) resumePP(ctx context.Context, uploadID string)
 		return nil
 	}
 
-	return events.Publish(ctx, pps.pub, pp.CurrentStep())
+	next := pp.CurrentSteps()
+	if err := pps.storePP(pps.cas, pp); err != nil {
+		return fmt.Errorf("cannot store upload: %w", err)
+	}
+
+	for _, n := range next {
+		if err := events.Publish(ctx, pps.pub, n); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (pps *PostprocessingService) findUploadsByStep(step events.Postprocessingstep) []string {
@@ -383,7 +485,7 @@ func (pps *PostprocessingService) findUploadsByStep(step events.Postprocessingst
 			continue
 		}
 
-		if pp.Status.CurrentStep == step {
+		if pp.Status.CurrentStep == step || slices.Contains(pp.Status.RunningSteps, step) {
 			ids = append(ids, pp.ID)
 		}
 	}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	"go-micro.dev/v4/store"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	ocissync "github.com/owncloud/ocis/v2/ocis-pkg/sync"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/config"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/event"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+)
+
+// slowStore delays reads to widen the window for lost updates
+type slowStore struct {
+	store.Store
+}
+
+func (s slowStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	time.Sleep(10 * time.Millisecond)
+	return s.Store.Read(key, opts...)
+}
+
+type publisher struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func (p *publisher) finished() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var n int
+	for _, ev := range p.events {
+		if _, ok := ev.(events.PostprocessingFinished); ok {
+			n++
+		}
+	}
+	return n
+}
+
+func newService(t *testing.T, sto store.Store, c config.Postprocessing) (*PostprocessingService, *publisher) {
+	graph, err := postprocessing.NewGraph(c)
+	require.NoError(t, err)
+	cas, err := casstore.New(sto, casstore.Options{})
+	require.NoError(t, err)
+	pub := &publisher{}
+	return &PostprocessingService{
+		log:     log.NopLogger(),
+		pub:     pub,
+		graph:   graph,
+		store:   sto,
+		cas:     cas,
+		c:       c,
+		tp:      noop.NewTracerProvider(),
+		stopCh:  make(chan struct{}, 1),
+		uploads: ocissync.NewNamedRWMutex(),
+	}, pub
+}
+
+func stepFinished(step events.Postprocessingstep) events.Event {
+	return events.Event{Type: "PostprocessingStepFinished", Event: events.PostprocessingStepFinished{
+		UploadID:     "upload",
+		FinishedStep: step,
+		Outcome:      events.PPOutcomeContinue,
+	}}
+}
+
+func TestParallelStepsFinishConcurrently(t *testing.T) {
+	c := config.Postprocessing{Workers: 2, StepGraph: []config.Step{{Name: "virusscan"}, {Name: "policies"}}}
+	sto := slowStore{store.NewMemoryStore()}
+	pps, pub := newService(t, sto, c)
+	require.NoError(t, pps.processEvent(bytesReceived()))
+
+	var wg sync.WaitGroup
+	for _, step := range []events.Postprocessingstep{"virusscan", "policies"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pps.processEvent(stepFinished(step)))
+		}()
+	}
+	wg.Wait()
+
+	assertFinished(t, pps, sto)
+	assert.Equal(t, 1, pub.finished())
+}
+
+// interceptStore calls intercept once after reading a record
+type interceptStore struct {
+	store.Store
+	intercept func()
+}
+
+func (s *interceptStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	recs, err := s.Store.Read(key, opts...)
+	if f := s.intercept; f != nil {
+		s.intercept = nil
+		f()
+	}
+	return recs, err
+}
+
+func TestStepFinishedByAnotherInstance(t *testing.T) {
+	c := config.Postprocessing{StepGraph: []config.Step{{Name: "virusscan"}, {Name: "policies"}}}
+	sto := &interceptStore{Store: store.NewMemoryStore()}
+	other, otherPub := newService(t, sto, c)
+	pps, pub := newService(t, sto, c)
+	require.NoError(t, pps.processEvent(bytesReceived()))
+
+	// the other instance stores its step after the upload was read for the first step
+	sto.intercept = func() {
+		assert.NoError(t, other.processEvent(stepFinished("policies")))
+	}
+	require.NoError(t, pps.processEvent(stepFinished("virusscan")))
+
+	assertFinished(t, pps, sto)
+	assert.Equal(t, 0, otherPub.finished())
+	assert.Equal(t, 1, pub.finished())
+}
+
+func TestCancelPostprocessing(t *testing.T) {
+	c := config.Postprocessing{StepGraph: []config.Step{{Name: "virusscan"}}}
+	sto := store.NewMemoryStore()
+	pps, pub := newService(t, sto, c)
+	require.NoError(t, pps.processEvent(bytesReceived()))
+
+	cancel := events.Event{Type: "CancelPostprocessing", Event: event.CancelPostprocessing{UploadID: "upload"}}
+	require.NoError(t, pps.processEvent(cancel))
+	require.Equal(t, 1, pub.finished())
+	ev := pub.events[len(pub.events)-1].(events.PostprocessingFinished)
+	assert.Equal(t, events.PPOutcomeAbort, ev.Outcome)
+
+	// the step finishing later and a second cancel don't change the outcome
+	require.NoError(t, pps.processEvent(stepFinished("virusscan")))
+	require.NoError(t, pps.processEvent(cancel))
+	assert.Equal(t, 1, pub.finished())
+	pp, err := pps.getPP(pps.cas, "upload")
+	require.NoError(t, err)
+	assert.Equal(t, events.PPOutcomeAbort, pp.Status.Outcome)
+}
+
+func bytesReceived() events.Event {
+	return events.Event{Type: "BytesReceived", Event: events.BytesReceived{
+		UploadID:   "upload",
+		Filename:   "file.txt",
+		Filesize:   1,
+		ResourceID: &provider.ResourceId{SpaceId: "space"},
+	}}
+}
+
+func assertFinished(t *testing.T, pps *PostprocessingService, sto store.Store) {
+	pp, err := pps.getPP(pps.cas, "upload")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []events.Postprocessingstep{"virusscan", "policies"}, pp.Status.FinishedSteps)
+	assert.Empty(t, pp.Status.RunningSteps)
+	assert.Equal(t, events.PPStepFinished, pp.Status.CurrentStep)
+}
@@ -116,7 +116,7 @@ func Server(cfg *config.Config) *cli.Command {
 				oidc.WithLogger(logger),
 				oidc.WithHTTPClient(oidcHTTPClient),
 				oidc.WithOidcIssuer(cfg.OIDC.Issuer),
-				oidc.WithJWKSOptions(cfg.OIDC.JWKS),
+				oidc.WithJWKSOptions(oidc.JWKSOptions(cfg.OIDC.JWKS)),
 			)
 
 			m := metrics.New()
@@ -295,6 +295,7 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 		authenticators = append(authenticators, middleware.AppAuthAuthenticator{
 			Logger:              logger,
 			RevaGatewaySelector: gatewaySelector,
+			TrustedProxies:      cfg.AuthMiddleware.AppAuthTrustedProxies,
 		})
 	}
 
@@ -310,7 +311,7 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 			oidc.WithLogger(logger),
 			oidc.WithHTTPClient(oidcHTTPClient),
 			oidc.WithOidcIssuer(cfg.OIDC.Issuer),
-			oidc.WithJWKSOptions(cfg.OIDC.JWKS),
+			oidc.WithJWKSOptions(oidc.JWKSOptions(cfg.OIDC.JWKS)),
 		)),
 		middleware.SkipUserInfo(cfg.OIDC.SkipUserInfo),
 	))
@@ -336,6 +337,8 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 		// first make sure we log all requests and redirect to https if necessary
 		pkgmiddleware.GetOtelhttpMiddleware(cfg.Service.Name, traceProvider),
 		middleware.Instrumenter(metrics),
+		// keep the address of the peer before it gets replaced with the forwarded address
+		middleware.PeerAddress,
 		chimiddleware.RealIP,
 		chimiddleware.RequestID,
 		middleware.AccessLog(logger),
@@ -364,6 +367,10 @@ func loadMiddlewares(logger log.Logger, cfg *config.Config,
 			middleware.EventsPublisher(publisher),
 			middleware.MultiInstance(cfg.MultiInstance.Enabled, cfg.MultiInstance.InstanceID, cfg.MultiInstance.MasterID, cfg.MultiInstance.MemberClaim, cfg.MultiInstance.GuestClaim, cfg.MultiInstance.GuestRole),
 		),
+		// enforce the restrictions of app tokens
+		middleware.AppAuthRestrictions(
+			middleware.Logger(logger),
+		),
 		middleware.SelectorCookie(
 			middleware.Logger(logger),
 			middleware.PolicySelectorConfig(*cfg.PolicySelector),
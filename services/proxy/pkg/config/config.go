@@ -96,6 +96,7 @@ var (
 type AuthMiddleware struct {
 	CredentialsByUserAgent map[string]string `yaml:"credentials_by_user_agent"`
 	AllowAppAuth           bool              `yaml:"allow_app_auth" env:"PROXY_ENABLE_APP_AUTH" desc:"Allow app authentication. This can be used to authenticate 3rd party applications. Note that auth-app service must be running for this feature to work." introductionVersion:"7.0.0"`
+	AppAuthTrustedProxies  []string          `yaml:"app_auth_trusted_proxies" env:"PROXY_APP_AUTH_TRUSTED_PROXIES" desc:"A list of IP addresses or ranges in CIDR notation of reverse proxies in front of the proxy service. The client address in the 'X-Forwarded-For' header of requests from these proxies is used to check the IP restrictions of app tokens. Requests from other peers are checked with the address of the peer. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
 }
 
 // PoliciesMiddleware configures the proxy's policies middleware.
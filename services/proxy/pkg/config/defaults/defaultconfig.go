@@ -156,6 +156,10 @@ func DefaultPolicies() []config.Policy {
 					Endpoint: "/ocs/v2.php/apps/notifications/api/v1/notifications/sse",
 					Service:  "com.owncloud.sse.sse",
 				},
+				{
+					Endpoint: "/ocs/v2.php/apps/notifications/api/v1/notifications/ws",
+					Service:  "com.owncloud.sse.sse",
+				},
 				{
 					// reroute oc10 notifications endpoint to userlog service
 					Endpoint: "/ocs/v2.php/apps/notifications/api/v1/notifications",
@@ -270,6 +274,14 @@ func DefaultPolicies() []config.Policy {
 					Endpoint: "/graph/v1.0/invitations",
 					Service:  "com.owncloud.web.invitations",
 				},
+				{
+					Endpoint: "/graph/v1beta1/uploadSessions",
+					Service:  "com.owncloud.web.storage-users",
+				},
+				{
+					Endpoint: "/graph/v1beta1/extensions/org.libregraph/savedSearches",
+					Service:  "com.owncloud.web.search",
+				},
 				{
 					Endpoint: "/graph/",
 					Service:  "com.owncloud.web.graph",
@@ -1,20 +1,37 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"net/netip"
+	"strings"
 
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	cs3rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
 	revactx "github.com/owncloud/reva/v2/pkg/ctx"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 )
 
+type peerAddressKey struct{}
+
 // AppAuthAuthenticator defines the app auth authenticator
 type AppAuthAuthenticator struct {
 	Logger              log.Logger
 	RevaGatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	// TrustedProxies are the addresses and ranges of the reverse proxies whose X-Forwarded-For header is used
+	// to check the network restrictions of app tokens
+	TrustedProxies []string
+}
+
+// PeerAddress stores the address of the peer of the connection in the context. It must be added before middlewares
+// like chi's RealIP, which replace the remote address with the client controlled X-Forwarded-For and X-Real-IP headers.
+func PeerAddress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), peerAddressKey{}, r.RemoteAddr)))
+	})
 }
 
 // Authenticate implements the authenticator interface to authenticate requests via app auth.
@@ -48,6 +65,16 @@ func (m AppAuthAuthenticator) Authenticate(r *http.Request) (*http.Request, bool
 		return nil, false
 	}
 
+	restrictions, err := apptoken.FromToken(authenticateResponse.GetToken())
+	if err != nil {
+		m.Logger.Error().Err(err).Msg("could not read app token restrictions")
+		return nil, false
+	}
+	if addr := m.clientAddress(r); !restrictions.AllowsAddress(addr) {
+		m.Logger.Debug().Str("remote-addr", addr).Msg("app token not allowed from remote address")
+		return nil, false
+	}
+
 	r.Header.Set(revactx.TokenHeader, authenticateResponse.GetToken())
 
 	user := authenticateResponse.GetUser()
@@ -58,7 +85,59 @@ func (m AppAuthAuthenticator) Authenticate(r *http.Request) (*http.Request, bool
 		oidc.Email:             user.GetMail(),
 		oidc.OwncloudUUID:      user.GetId().GetOpaqueId(),
 	}
-	r = r.WithContext(oidc.NewContext(r.Context(), claims))
+	ctx := oidc.NewContext(r.Context(), claims)
+	if restrictions != nil {
+		// the account resolver replaces the token, keep the restrictions and the token for the app auth restrictions middleware
+		ctx = contextWithAppAuthRestrictions(ctx, restrictions, authenticateResponse.GetToken())
+	}
+	r = r.WithContext(ctx)
 
 	return r, true
 }
+
+// clientAddress returns the address of the client. The X-Forwarded-For header is only used if the peer is a trusted
+// proxy, the client is the last address in the header which is not a trusted proxy.
+func (m AppAuthAuthenticator) clientAddress(r *http.Request) string {
+	peer, ok := r.Context().Value(peerAddressKey{}).(string)
+	if !ok {
+		peer = r.RemoteAddr
+	}
+	if len(m.TrustedProxies) == 0 || !m.trustedProxy(peer) {
+		return peer
+	}
+
+	var forwarded []string
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(h, ",")...)
+	}
+	addr := peer
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr = strings.TrimSpace(forwarded[i])
+		if !m.trustedProxy(addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// trustedProxy returns true if the address, which may contain a port, is one of the trusted proxies
+func (m AppAuthAuthenticator) trustedProxy(addr string) bool {
+	ap, err := netip.ParseAddrPort(addr)
+	ip := ap.Addr()
+	if err != nil {
+		if ip, err = netip.ParseAddr(addr); err != nil {
+			return false
+		}
+	}
+	ip = ip.Unmap()
+
+	for _, p := range m.TrustedProxies {
+		if prefix, err := netip.ParsePrefix(p); err == nil && prefix.Contains(ip) {
+			return true
+		}
+		if trusted, err := netip.ParseAddr(p); err == nil && trusted.Unmap() == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+	"github.com/owncloud/ocis/v2/services/proxy/pkg/webdav"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+)
+
+type appAuthRestrictionsKey struct{}
+
+// appAuthRestrictionsValue is stored in the context of requests authenticated with restricted app tokens
+type appAuthRestrictionsValue struct {
+	restrictions *apptoken.Restrictions
+	token        string
+}
+
+var (
+	// _graphDrivePath matches the graph endpoints of a drive or of an item in a drive
+	_graphDrivePath = regexp.MustCompile(`^/graph/v1(?:\.0|beta1)/drives/[^/]+`)
+
+	// _appAuthRestrictedPaths are the paths which can be used by app tokens restricted to spaces or folders
+	// next to the WebDAV and graph endpoints of the spaces and folders. Clients need them to get the user and the capabilities.
+	_appAuthRestrictedPaths = []string{
+		"/ocs/v1.php/cloud/capabilities",
+		"/ocs/v2.php/cloud/capabilities",
+		"/ocs/v1.php/cloud/user",
+		"/ocs/v2.php/cloud/user",
+		"/graph/v1.0/me",
+	}
+)
+
+// contextWithAppAuthRestrictions adds the restrictions and the reva token of an app token to the context
+func contextWithAppAuthRestrictions(ctx context.Context, r *apptoken.Restrictions, token string) context.Context {
+	return context.WithValue(ctx, appAuthRestrictionsKey{}, appAuthRestrictionsValue{restrictions: r, token: token})
+}
+
+// appAuthRestrictionsFromContext returns the restrictions of the app token the request was authenticated with
+func appAuthRestrictionsFromContext(ctx context.Context) *apptoken.Restrictions {
+	v, _ := ctx.Value(appAuthRestrictionsKey{}).(appAuthRestrictionsValue)
+	return v.restrictions
+}
+
+// AppAuthRestrictions provides a middleware which enforces the restrictions of app tokens on the WebDAV and graph requests
+// authenticated with them. It must be added after the account resolver.
+//
+// The access restrictions are checked here. The resource restrictions are share scopes of the reva token of the app token,
+// the services accessing the storage make sure the requests stay within the resources. The account resolver replaces the
+// reva token with a token of the user, the middleware puts back the reva token of the app token.
+func AppAuthRestrictions(optionSetters ...Option) func(next http.Handler) http.Handler {
+	options := newOptions(optionSetters...)
+
+	return func(next http.Handler) http.Handler {
+		return &appAuthRestrictions{
+			next:   next,
+			logger: options.Logger,
+		}
+	}
+}
+
+type appAuthRestrictions struct {
+	next   http.Handler
+	logger log.Logger
+}
+
+func (m appAuthRestrictions) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	v, _ := req.Context().Value(appAuthRestrictionsKey{}).(appAuthRestrictionsValue)
+	if v.restrictions.IsZero() {
+		m.next.ServeHTTP(w, req)
+		return
+	}
+
+	if !allowedByAppAuthRestrictions(req, v.restrictions) {
+		m.logger.Debug().Str("method", req.Method).Str("path", req.URL.Path).Msg("request not allowed by app token restrictions")
+		renderAppAuthDenied(w, req)
+		return
+	}
+
+	if len(v.restrictions.Resources) > 0 {
+		req.Header.Set(revactx.TokenHeader, v.token)
+	}
+	m.next.ServeHTTP(w, req)
+}
+
+// allowedByAppAuthRestrictions checks the access restrictions. Requests of tokens restricted to resources must use
+// the endpoints which address spaces and items by id, the storage checks if they are within the resources.
+func allowedByAppAuthRestrictions(req *http.Request, restrictions *apptoken.Restrictions) bool {
+	if !restrictions.AllowsMethod(req.Method) {
+		return false
+	}
+
+	p := req.URL.Path
+	isData := strings.HasPrefix(p, "/data/")
+	if restrictions.Access == apptoken.AccessUpload && !isData && !isWebdavPath(p) {
+		// upload tokens can't be used to create anything else than files and folders
+		return false
+	}
+	if len(restrictions.Resources) == 0 || isData || slices.Contains(_appAuthRestrictedPaths, p) {
+		return true
+	}
+
+	return isAppAuthTargetPath(p)
+}
+
+// isAppAuthTargetPath returns true for the WebDAV spaces and the graph drive endpoints
+func isAppAuthTargetPath(p string) bool {
+	if rest, ok := strings.CutPrefix(strings.TrimPrefix(p, "/remote.php"), "/dav/spaces/"); ok {
+		ref, _, _ := strings.Cut(rest, "/")
+		_, err := storagespace.ParseID(ref)
+		return err == nil
+	}
+	return _graphDrivePath.MatchString(p)
+}
+
+func isWebdavPath(p string) bool {
+	p = strings.TrimPrefix(p, "/remote.php")
+	return strings.HasPrefix(p, "/dav/") || strings.HasPrefix(p, "/webdav")
+}
+
+func renderAppAuthDenied(w http.ResponseWriter, req *http.Request) {
+	const msg = "request not allowed for this app token"
+	switch {
+	case webdav.IsWebdavRequest(req) || isWebdavPath(req.URL.Path):
+		w.WriteHeader(http.StatusForbidden)
+		b, err := webdav.Marshal(webdav.Exception{
+			Code:    webdav.SabredavPermissionDenied,
+			Message: msg,
+		})
+		webdav.HandleWebdavError(w, b, err)
+	case strings.HasPrefix(req.URL.Path, "/graph/"):
+		errorcode.AccessDenied.Render(w, req, http.StatusForbidden, msg)
+	default:
+		http.Error(w, msg, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+)
+
+var _ = Describe("Restricting app token requests", Label("AppAuthRestrictions"), func() {
+	var (
+		handler http.Handler
+		token   string
+	)
+	BeforeEach(func() {
+		token = ""
+		handler = AppAuthRestrictions(
+			Logger(log.NopLogger()),
+		)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token = r.Header.Get(revactx.TokenHeader)
+			w.WriteHeader(http.StatusOK)
+		}))
+	})
+
+	request := func(method, target string, restrictions *apptoken.Restrictions) *http.Request {
+		req := httptest.NewRequest(method, target, http.NoBody)
+		// the account resolver replaced the token of the app token
+		req.Header.Set(revactx.TokenHeader, "user-token")
+		if restrictions != nil {
+			req = req.WithContext(contextWithAppAuthRestrictions(req.Context(), restrictions, "app-token"))
+		}
+		return req
+	}
+
+	DescribeTable("requests",
+		func(method, target string, restrictions *apptoken.Restrictions, status int) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, request(method, target, restrictions))
+			Expect(rec.Code).To(Equal(status))
+		},
+		Entry("unrestricted token", "DELETE", "/remote.php/dav/spaces/storage$other/file.txt", nil, http.StatusOK),
+		Entry("read token reading", "PROPFIND", "/dav/files/alice", &apptoken.Restrictions{Access: apptoken.AccessRead}, http.StatusOK),
+		Entry("read token writing", "PUT", "/dav/files/alice/file.txt", &apptoken.Restrictions{Access: apptoken.AccessRead}, http.StatusForbidden),
+		Entry("upload token uploading", "PUT", "/dav/files/alice/file.txt", &apptoken.Restrictions{Access: apptoken.AccessUpload}, http.StatusOK),
+		Entry("upload token downloading", "GET", "/dav/files/alice/file.txt", &apptoken.Restrictions{Access: apptoken.AccessUpload}, http.StatusForbidden),
+		Entry("upload token using graph", "POST", "/graph/v1.0/drives", &apptoken.Restrictions{Access: apptoken.AccessUpload}, http.StatusForbidden),
+		Entry("upload token using data endpoint", "PATCH", "/data/upload-token", &apptoken.Restrictions{Access: apptoken.AccessUpload}, http.StatusOK),
+
+		Entry("space token on webdav spaces", "PUT", "/remote.php/dav/spaces/storage$space/docs/file.txt", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusOK),
+		Entry("space token on graph drive", "GET", "/graph/v1.0/drives/storage$space/root/children", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusOK),
+		Entry("space token on graph item", "GET", "/graph/v1beta1/drives/storage$space/items/storage$space!other", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusOK),
+		Entry("space token listing drives", "GET", "/graph/v1.0/me/drives", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusForbidden),
+		Entry("space token using batch requests", "POST", "/graph/v1.0/$batch", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusForbidden),
+		Entry("space token getting the user", "GET", "/graph/v1.0/me", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusOK),
+		Entry("space token on path based webdav", "GET", "/remote.php/webdav/file.txt", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusForbidden),
+		Entry("space token on invalid space", "GET", "/dav/spaces/", &apptoken.Restrictions{Resources: []string{"storage$space"}}, http.StatusForbidden),
+		Entry("read folder token writing", "PUT", "/dav/spaces/storage$space!folder/file.txt", &apptoken.Restrictions{Resources: []string{"storage$space!folder"}, Access: apptoken.AccessRead}, http.StatusForbidden),
+	)
+
+	It("passes on the token of the app token for tokens restricted to resources", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request("GET", "/dav/spaces/storage$space!folder/file.txt", &apptoken.Restrictions{Resources: []string{"storage$space!folder"}}))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(token).To(Equal("app-token"))
+	})
+
+	It("keeps the token of the user for other tokens", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request("GET", "/dav/files/alice/file.txt", &apptoken.Restrictions{Access: apptoken.AccessRead}))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(token).To(Equal("user-token"))
+	})
+})
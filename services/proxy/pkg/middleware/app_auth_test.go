@@ -4,24 +4,51 @@ import (
 	"net/http"
 	"net/http/httptest"
 
+	authpb "github.com/cs3org/go-cs3apis/cs3/auth/provider/v1beta1"
 	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
 	userv1beta1 "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	rpcv1beta1 "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/owncloud/reva/v2/pkg/auth/scope"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 	"google.golang.org/grpc"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/owncloud/ocis/v2/ocis-pkg/apptoken"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
 )
 
+// newRevaToken mints a reva token with the owner scope and the app token restrictions
+func newRevaToken(restrictions *apptoken.Restrictions) string {
+	scopes, err := scope.AddOwnerScope(nil)
+	Expect(err).ToNot(HaveOccurred())
+	scopes, err = apptoken.AddScope(restrictions, scopes)
+	Expect(err).ToNot(HaveOccurred())
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, struct {
+		jwt.RegisteredClaims
+		Scope map[string]*authpb.Scope `json:"scope"`
+	}{Scope: scopes}).SignedString([]byte("secret"))
+	Expect(err).ToNot(HaveOccurred())
+	return token
+}
+
 var _ = Describe("Authenticating requests", Label("AppAuthAuthenticator"), func() {
-	var authenticator Authenticator
+	var (
+		authenticator   Authenticator
+		token           string
+		restrictedToken string
+	)
 	BeforeEach(func() {
+		token = newRevaToken(nil)
+		restrictedToken = newRevaToken(&apptoken.Restrictions{Access: apptoken.AccessRead, Networks: []string{"10.0.0.0/8"}})
 		pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
 		authenticator = AppAuthAuthenticator{
-			Logger: log.NewLogger(),
+			Logger:         log.NewLogger(),
+			TrustedProxies: []string{"172.16.0.0/12", "192.168.1.10"},
 			RevaGatewaySelector: pool.GetSelector[gateway.GatewayAPIClient](
 				"GatewaySelector",
 				"com.owncloud.api.gateway",
@@ -34,10 +61,14 @@ var _ = Describe("Authenticating requests", Label("AppAuthAuthenticator"), func(
 								}
 							}
 
-							if clientID == "test-user" && clientSecret == "AppPassword" {
+							if clientID == "test-user" && (clientSecret == "AppPassword" || clientSecret == "RestrictedAppPassword") {
+								t := token
+								if clientSecret == "RestrictedAppPassword" {
+									t = restrictedToken
+								}
 								return &gateway.AuthenticateResponse{
 									Status: &rpcv1beta1.Status{Code: rpcv1beta1.Code_CODE_OK},
-									Token:  "reva-token",
+									Token:  t,
 									User: &userv1beta1.User{
 										Id:          &userv1beta1.UserId{Idp: "testIDP", OpaqueId: "abcd-1234", Type: userv1beta1.UserType_USER_TYPE_PRIMARY},
 										Username:    "alice",
@@ -66,7 +97,7 @@ var _ = Describe("Authenticating requests", Label("AppAuthAuthenticator"), func(
 
 			Expect(valid).To(Equal(true))
 			Expect(req2).ToNot(BeNil())
-			Expect(req2.Header.Get("x-access-token")).To(Equal("reva-token"))
+			Expect(req2.Header.Get("x-access-token")).To(Equal(token))
 
 			claims := oidc.FromContext(req2.Context())
 			Expect(claims).ToNot(BeNil())
@@ -74,6 +105,57 @@ var _ = Describe("Authenticating requests", Label("AppAuthAuthenticator"), func(
 			Expect(claims[oidc.PreferredUsername]).To(Equal("alice"))
 			Expect(claims[oidc.Email]).To(Equal("alice@example.prv"))
 			Expect(claims[oidc.OwncloudUUID]).To(Equal("abcd-1234"))
+			Expect(appAuthRestrictionsFromContext(req2.Context())).To(BeNil())
+		})
+	})
+
+	When("the app token is restricted", func() {
+		It("should add the restrictions to the context", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = "10.1.2.3:43210"
+			req.SetBasicAuth("test-user", "RestrictedAppPassword")
+
+			req2, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(true))
+			restrictions := appAuthRestrictionsFromContext(req2.Context())
+			Expect(restrictions).ToNot(BeNil())
+			Expect(restrictions.Access).To(Equal(apptoken.AccessRead))
+		})
+
+		It("should not authenticate requests from other networks", func() {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = "192.168.1.2:43210"
+			req.SetBasicAuth("test-user", "RestrictedAppPassword")
+
+			req2, valid := authenticator.Authenticate(req)
+
+			Expect(valid).To(Equal(false))
+			Expect(req2).To(BeNil())
+		})
+
+		// authenticate sends the request through the peer address and the real ip middlewares
+		authenticate := func(peer, forwardedFor string) bool {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/example/path", http.NoBody)
+			req.RemoteAddr = peer
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			req.SetBasicAuth("test-user", "RestrictedAppPassword")
+
+			var valid bool
+			PeerAddress(chimiddleware.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, valid = authenticator.Authenticate(r)
+			}))).ServeHTTP(httptest.NewRecorder(), req)
+			return valid
+		}
+
+		It("should ignore forwarded addresses of untrusted peers", func() {
+			Expect(authenticate("192.168.1.2:43210", "10.1.2.3")).To(BeFalse())
+		})
+
+		It("should use forwarded addresses of trusted proxies", func() {
+			Expect(authenticate("192.168.1.10:43210", "10.1.2.3")).To(BeTrue())
+			Expect(authenticate("172.16.0.5:43210", "192.168.5.5, 10.1.2.3, 172.16.0.4")).To(BeTrue())
+			Expect(authenticate("172.16.0.5:43210", "10.1.2.3, 192.168.5.5")).To(BeFalse())
 		})
 	})
 
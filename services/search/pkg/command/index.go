@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -15,6 +17,9 @@ import (
 	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/bleve"
 )
 
 // Index is the entrypoint for the server command.
@@ -34,11 +39,28 @@ func Index(cfg *config.Config) *cli.Command {
 				Name:  "all-spaces",
 				Usage: "index all spaces instead. This or --space is required.",
 			},
+			&cli.BoolFlag{
+				Name:  "migrate-from-bleve",
+				Usage: "copy all resources of an existing bleve index into the configured search engine instead of indexing spaces. The search service does not need to run.",
+			},
+			&cli.StringFlag{
+				Name:  "bleve-data-path",
+				Usage: "the data path of the bleve index to migrate. Defaults to SEARCH_ENGINE_BLEVE_DATA_PATH.",
+			},
+			&cli.IntFlag{
+				Name:  "batch-size",
+				Value: 500,
+				Usage: "the number of resources to migrate per request.",
+			},
 		},
 		Before: func(_ *cli.Context) error {
 			return configlog.ReturnFatal(parser.ParseConfig(cfg))
 		},
 		Action: func(ctx *cli.Context) error {
+			if ctx.Bool("migrate-from-bleve") {
+				return migrateFromBleve(cfg, ctx.String("bleve-data-path"), ctx.Int("batch-size"))
+			}
+
 			if ctx.String("space") == "" && !ctx.Bool("all-spaces") {
 				return errors.New("either --space or --all-spaces is required")
 			}
@@ -68,3 +90,38 @@ func Index(cfg *config.Config) *cli.Command {
 		},
 	}
 }
+
+// migrateFromBleve copies the resources of a bleve index into the configured search engine
+func migrateFromBleve(cfg *config.Config, dataPath string, batchSize int) error {
+	if cfg.Engine.Type == "bleve" {
+		return errors.New("the configured search engine is bleve already, set SEARCH_ENGINE_TYPE to the engine to migrate to")
+	}
+	if dataPath == "" {
+		dataPath = cfg.Engine.Bleve.Datapath
+	}
+	if _, err := os.Stat(filepath.Join(dataPath, "bleve")); err != nil {
+		return fmt.Errorf("no bleve index found in '%s': %w", dataPath, err)
+	}
+
+	bleveMapping, err := engine.BuildBleveMapping()
+	if err != nil {
+		return err
+	}
+	src := engine.NewBleveEngine(bleveEngine.NewIndexGetterPersistentScale(dataPath, bleveMapping), bleve.DefaultCreator)
+
+	dst, closer, err := engine.NewEngineFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	fmt.Printf("migrating bleve index from '%s' to the %s engine...\n", dataPath, cfg.Engine.Type)
+	migrated, err := engine.Migrate(context.Background(), src, dst, batchSize, func(migrated uint64) {
+		fmt.Printf("migrated %d resources\n", migrated)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate index: %w", err)
+	}
+	fmt.Printf("migration complete, %d resources migrated\n", migrated)
+	return nil
+}
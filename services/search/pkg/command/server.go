@@ -14,9 +14,13 @@ import (
 	"github.com/owncloud/ocis/v2/services/search/pkg/config/parser"
 	"github.com/owncloud/ocis/v2/services/search/pkg/logging"
 	"github.com/owncloud/ocis/v2/services/search/pkg/metrics"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
 	"github.com/owncloud/ocis/v2/services/search/pkg/server/debug"
 	"github.com/owncloud/ocis/v2/services/search/pkg/server/grpc"
+	"github.com/owncloud/ocis/v2/services/search/pkg/server/http"
+	"github.com/owncloud/reva/v2/pkg/store"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 )
 
 // Server is the entrypoint for the server command.
@@ -54,6 +58,15 @@ func Server(cfg *config.Config) *cli.Command {
 
 			gr := runner.NewGroup()
 
+			savedSearches := savedsearch.NewStore(store.Create(
+				store.Store(cfg.SavedSearches.Store),
+				microstore.Nodes(cfg.SavedSearches.Nodes...),
+				microstore.Database(cfg.SavedSearches.Database),
+				microstore.Table(cfg.SavedSearches.Table),
+				store.Authentication(cfg.SavedSearches.AuthUsername, cfg.SavedSearches.AuthPassword),
+				store.TLS(cfg.SavedSearches.EnableTLS, cfg.SavedSearches.TLSInsecure, cfg.SavedSearches.TLSRootCACertificate),
+			), cfg.SavedSearches.NotifiedTTL)
+
 			grpcServer, teardown, err := grpc.Server(
 				grpc.Config(cfg),
 				grpc.Logger(logger),
@@ -62,6 +75,7 @@ func Server(cfg *config.Config) *cli.Command {
 				grpc.Metrics(mtrcs),
 				grpc.JWTSecret(cfg.TokenManager.JWTSecret),
 				grpc.TraceProvider(traceProvider),
+				grpc.SavedSearches(savedSearches),
 			)
 			defer teardown()
 			if err != nil {
@@ -71,6 +85,20 @@ func Server(cfg *config.Config) *cli.Command {
 
 			gr.Add(runner.NewGoMicroGrpcServerRunner(cfg.Service.Name+".grpc", grpcServer))
 
+			httpServer, err := http.Server(
+				http.Logger(logger),
+				http.Context(ctx),
+				http.Config(cfg),
+				http.SavedSearches(savedSearches),
+				http.TraceProvider(traceProvider),
+			)
+			if err != nil {
+				logger.Info().Err(err).Str("transport", "http").Msg("Failed to initialize server")
+				return err
+			}
+
+			gr.Add(runner.NewGoMicroHttpServerRunner(cfg.Service.Name+".http", httpServer))
+
 			debugServer, err := debug.Server(
 				debug.Logger(logger),
 				debug.Context(ctx),
@@ -19,6 +19,7 @@ type Config struct {
 
 	GRPC       GRPCConfig    `yaml:"grpc"`
 	GrpcClient client.Client `yaml:"-"`
+	HTTP       HTTPConfig    `yaml:"http"`
 
 	TokenManager *TokenManager `yaml:"token_manager"`
 
@@ -28,6 +29,7 @@ type Config struct {
 	Engine                     Engine                `yaml:"engine"`
 	Extractor                  Extractor             `yaml:"extractor"`
 	ContentExtractionSizeLimit uint64                `yaml:"content_extraction_size_limit" env:"SEARCH_CONTENT_EXTRACTION_SIZE_LIMIT" desc:"Maximum file size in bytes that is allowed for content extraction." introductionVersion:"pre5.0"`
+	SavedSearches              SavedSearches         `yaml:"saved_searches"`
 
 	ServiceAccount ServiceAccount `yaml:"service_account" mask:"struct"`
 
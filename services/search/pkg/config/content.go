@@ -2,7 +2,7 @@ package config
 
 // Extractor defines which extractor to use
 type Extractor struct {
-	Type             string        `yaml:"type" env:"SEARCH_EXTRACTOR_TYPE" desc:"Defines the content extraction engine. Defaults to 'basic'. Supported values are: 'basic' and 'tika'." introductionVersion:"pre5.0"`
+	Type             string        `yaml:"type" env:"SEARCH_EXTRACTOR_TYPE" desc:"Defines the content extraction engine. Defaults to 'basic'. Supported values are: 'basic', 'native' and 'tika'. The 'native' extractor reads the text of office documents, PDF files, Markdown, HTML and emails without an external service." introductionVersion:"pre5.0"`
 	CS3AllowInsecure bool          `yaml:"cs3_allow_insecure" env:"OCIS_INSECURE;SEARCH_EXTRACTOR_CS3SOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the CS3 source." introductionVersion:"pre5.0"`
 	Tika             ExtractorTika `yaml:"tika"`
 }
@@ -2,6 +2,7 @@ package defaults
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/defaults"
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
@@ -29,6 +30,10 @@ func DefaultConfig() *config.Config {
 			Addr:      "127.0.0.1:9220",
 			Namespace: "com.owncloud.api",
 		},
+		HTTP: config.HTTPConfig{
+			Addr:      "127.0.0.1:9222",
+			Namespace: "com.owncloud.web",
+		},
 		Service: config.Service{
 			Name: "search",
 		},
@@ -38,6 +43,10 @@ func DefaultConfig() *config.Config {
 			Bleve: config.EngineBleve{
 				Datapath: filepath.Join(defaults.BaseDataPath(), "search"),
 			},
+			OpenSearch: config.EngineOpenSearch{
+				Addresses: []string{"http://127.0.0.1:9200"},
+				Index:     "ocis-resources",
+			},
 		},
 		Extractor: config.Extractor{
 			Type:             "basic",
@@ -55,6 +64,14 @@ func DefaultConfig() *config.Config {
 			EnableTLS:        false,
 		},
 		ContentExtractionSizeLimit: 20 * 1024 * 1024, // Limit content extraction to <20MB files by default
+		SavedSearches: config.SavedSearches{
+			Store:    "nats-js-kv",
+			Nodes:    []string{"127.0.0.1:9233"},
+			Database: "search",
+			Table:    "saved-searches",
+			// a month
+			NotifiedTTL: 30 * 24 * time.Hour,
+		},
 	}
 }
 
@@ -100,6 +117,10 @@ func EnsureDefaults(cfg *config.Config) {
 	if cfg.GRPC.TLS == nil && cfg.Commons != nil {
 		cfg.GRPC.TLS = structs.CopyOrZeroValue(cfg.Commons.GRPCServiceTLS)
 	}
+
+	if cfg.Commons != nil {
+		cfg.HTTP.TLS = cfg.Commons.HTTPServiceTLS
+	}
 }
 
 // Sanitize sanitizes the configuration
@@ -2,8 +2,9 @@ package config
 
 // Engine defines which search engine to use
 type Engine struct {
-	Type  string      `yaml:"type" env:"SEARCH_ENGINE_TYPE" desc:"Defines which search engine to use. Defaults to 'bleve'. Supported values are: 'bleve'." introductionVersion:"pre5.0"`
-	Bleve EngineBleve `yaml:"bleve"`
+	Type       string           `yaml:"type" env:"SEARCH_ENGINE_TYPE" desc:"Defines which search engine to use. Defaults to 'bleve'. Supported values are: 'bleve', 'open-search'." introductionVersion:"pre5.0"`
+	Bleve      EngineBleve      `yaml:"bleve"`
+	OpenSearch EngineOpenSearch `yaml:"open_search"`
 }
 
 // EngineBleve configures the bleve engine
@@ -11,3 +12,13 @@ type EngineBleve struct {
 	Datapath string `yaml:"data_path" env:"SEARCH_ENGINE_BLEVE_DATA_PATH" desc:"The directory where the filesystem will store search data. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH/search." introductionVersion:"pre5.0"`
 	Scale    bool   `yaml:"scale" env:"SEARCH_ENGINE_BLEVE_SCALE" desc:"Enable scaling of the search index (bleve). If set to 'true', the instance of the search service will no longer have exclusive write access to the index. Note when scaling search, all instances of the search service must be set to true! For 'false', which is the default, the running search service has exclusive access to the index as long it is running. This locks out other search processes tying to access the index." introductionVersion:"7.2.0"`
 }
+
+// EngineOpenSearch configures the OpenSearch engine
+type EngineOpenSearch struct {
+	Addresses            []string `yaml:"addresses" env:"SEARCH_ENGINE_OPEN_SEARCH_ADDRESSES" desc:"A list of URLs of the OpenSearch or Elasticsearch compatible servers, for example 'https://opensearch:9200'. The servers are tried in the given order. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Index                string   `yaml:"index" env:"SEARCH_ENGINE_OPEN_SEARCH_INDEX" desc:"The name of the index the resources are stored in. The index is created with the required mapping if it does not exist." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Username             string   `yaml:"username" env:"SEARCH_ENGINE_OPEN_SEARCH_USERNAME" desc:"The username for the basic authentication against the search servers." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Password             string   `yaml:"password" env:"SEARCH_ENGINE_OPEN_SEARCH_PASSWORD" desc:"The password for the basic authentication against the search servers." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%" mask:"password"`
+	TLSInsecure          bool     `yaml:"tls_insecure" env:"OCIS_INSECURE;SEARCH_ENGINE_OPEN_SEARCH_TLS_INSECURE" desc:"Whether to skip the verification of the TLS certificates of the search servers." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSRootCACertificate string   `yaml:"tls_root_ca_certificate" env:"SEARCH_ENGINE_OPEN_SEARCH_TLS_ROOT_CA_CERTIFICATE" desc:"The root CA certificate used to validate the TLS certificates of the search servers." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
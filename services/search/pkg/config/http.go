@@ -0,0 +1,10 @@
+package config
+
+import "github.com/owncloud/ocis/v2/ocis-pkg/shared"
+
+// HTTPConfig defines the available http configuration.
+type HTTPConfig struct {
+	Addr      string                `yaml:"addr" env:"SEARCH_HTTP_ADDR" desc:"The bind address of the HTTP service providing the saved searches API." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Namespace string                `yaml:"-"`
+	TLS       shared.HTTPServiceTLS `yaml:"tls"`
+}
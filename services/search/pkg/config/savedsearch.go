@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// SavedSearches configures the store of the saved searches of the users.
+type SavedSearches struct {
+	Store                string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;SEARCH_SAVED_SEARCHES_STORE" desc:"The type of the store. Supported values are: 'memory', 'nats-js-kv', 'redis-sentinel', 'noop'. See the text description for details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Nodes                []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;SEARCH_SAVED_SEARCHES_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Database             string        `yaml:"database" env:"SEARCH_SAVED_SEARCHES_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Table                string        `yaml:"table" env:"SEARCH_SAVED_SEARCHES_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthUsername         string        `yaml:"username" env:"OCIS_PERSISTENT_STORE_AUTH_USERNAME;SEARCH_SAVED_SEARCHES_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthPassword         string        `yaml:"password" env:"OCIS_PERSISTENT_STORE_AUTH_PASSWORD;SEARCH_SAVED_SEARCHES_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	EnableTLS            bool          `yaml:"enable_tls" env:"OCIS_PERSISTENT_STORE_ENABLE_TLS;SEARCH_SAVED_SEARCHES_STORE_ENABLE_TLS" desc:"Activate TLS for the connection to the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSInsecure          bool          `yaml:"tls_insecure" env:"OCIS_PERSISTENT_STORE_TLS_INSECURE;SEARCH_SAVED_SEARCHES_STORE_TLS_INSECURE" desc:"Disable TLS certificate verification for the store connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSRootCACertificate string        `yaml:"tls_root_ca_certificate" env:"OCIS_PERSISTENT_STORE_TLS_ROOT_CA_CERTIFICATE;SEARCH_SAVED_SEARCHES_STORE_TLS_ROOT_CA_CERTIFICATE" desc:"Path to the PEM-encoded root CA certificate for the store TLS connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	NotifiedTTL          time.Duration `yaml:"notified_ttl" env:"SEARCH_SAVED_SEARCHES_NOTIFIED_TTL" desc:"The time after which the record that a user was notified about a resource matching a saved search expires. Users can be notified about the resource again afterwards. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	DisableAlerts        bool          `yaml:"disable_alerts" env:"SEARCH_SAVED_SEARCHES_DISABLE_ALERTS" desc:"Disables checking newly indexed resources against the saved searches users subscribed to. Saved searches can still be managed and run." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
@@ -14,17 +14,19 @@ func init() {
 // Document wraps all resource meta fields,
 // it is used as a content extraction result.
 type Document struct {
-	Title    string
-	Name     string
-	Content  string
-	Size     uint64
-	Mtime    string
-	MimeType string
-	Tags     []string
-	Audio    *libregraph.Audio          `json:"audio,omitempty"`
-	Image    *libregraph.Image          `json:"image,omitempty"`
-	Location *libregraph.GeoCoordinates `json:"location,omitempty"`
-	Photo    *libregraph.Photo          `json:"photo,omitempty"`
+	Title     string
+	Name      string
+	Content   string
+	Size      uint64
+	Mtime     string
+	MimeType  string
+	Tags      []string
+	Authors   []string
+	PageCount uint64
+	Audio     *libregraph.Audio          `json:"audio,omitempty"`
+	Image     *libregraph.Image          `json:"image,omitempty"`
+	Location  *libregraph.GeoCoordinates `json:"location,omitempty"`
+	Photo     *libregraph.Photo          `json:"photo,omitempty"`
 }
 
 func CleanString(content, langCode string) string {
@@ -0,0 +1,138 @@
+package content
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// maxMailPartDepth limits the nesting of multipart messages
+const maxMailPartDepth = 8
+
+var mailHeaderDecoder = &mime.WordDecoder{
+	CharsetReader: charset.NewReaderLabel,
+}
+
+// extractEML reads the subject as title, the senders as authors and prefers plain text bodies over html
+func extractEML(data []byte) (nativeExtraction, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	if subject, err := mailHeaderDecoder.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		res.Title = subject
+	}
+
+	if from, err := (&mail.AddressParser{WordDecoder: mailHeaderDecoder}).ParseList(msg.Header.Get("From")); err == nil {
+		for _, addr := range from {
+			author := addr.Name
+			if author == "" {
+				author = addr.Address
+			}
+			res.Authors = appendAuthor(res.Authors, author)
+		}
+	}
+
+	plain, rich, err := mailBody(msg.Header, msg.Body, 0)
+	if err != nil {
+		return res, err
+	}
+
+	switch {
+	case plain != "":
+		res.Content = plain
+	case rich != "":
+		if r, err := extractHTML([]byte(rich)); err == nil {
+			res.Content = r.Content
+		}
+	}
+
+	return res, nil
+}
+
+// mailHeader provides access to the headers of a message or of a multipart part
+type mailHeader interface {
+	Get(key string) string
+}
+
+// mailBody returns the plain text and the html content of a message body
+func mailBody(header mailHeader, body io.Reader, depth int) (string, string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMailPartDepth {
+			return "", "", nil
+		}
+
+		var plain, rich strings.Builder
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return plain.String(), rich.String(), err
+			}
+
+			// attachments are not part of the message content
+			if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+				continue
+			}
+
+			p, r, err := mailBody(part.Header, part, depth+1)
+			if err != nil {
+				return plain.String(), rich.String(), err
+			}
+			plain.WriteString(p)
+			rich.WriteString(r)
+
+			// alternatives contain the same content in different formats
+			if mediaType == "multipart/alternative" && plain.Len() > 0 {
+				break
+			}
+		}
+
+		return plain.String(), rich.String(), nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+
+	if cs := params["charset"]; cs != "" {
+		if r, err := charset.NewReaderLabel(cs, body); err == nil {
+			body = r
+		}
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", "", err
+	}
+
+	if mediaType == "text/html" {
+		return "", string(b), nil
+	}
+
+	return string(bytes.ToValidUTF8(b, nil)), "", nil
+}
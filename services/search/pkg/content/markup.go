@@ -0,0 +1,184 @@
+package content
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+func extractHTML(data []byte) (nativeExtraction, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), "text/html")
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			sb.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template:
+				return
+			case atom.Title:
+				if n.FirstChild != nil && res.Title == "" {
+					res.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+				return
+			case atom.Meta:
+				var name, content string
+				for _, attr := range n.Attr {
+					switch strings.ToLower(attr.Key) {
+					case "name":
+						name = strings.ToLower(attr.Val)
+					case "content":
+						content = attr.Val
+					}
+				}
+				if name == "author" {
+					res.Authors = appendAuthor(res.Authors, content)
+				}
+				return
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if n.Type == html.ElementNode && isHTMLBlock(n.DataAtom) {
+			sb.WriteString("\n")
+		}
+	}
+	walk(doc)
+
+	res.Content = collapseBlankLines(sb.String())
+	return res, nil
+}
+
+func isHTMLBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Br, atom.Li, atom.Tr, atom.Td, atom.Th, atom.Table,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6,
+		atom.Section, atom.Article, atom.Header, atom.Footer, atom.Blockquote, atom.Pre:
+		return true
+	}
+	return false
+}
+
+var (
+	markdownHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+	markdownListMarker = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?`)
+	markdownImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink       = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownEmphasis   = []*regexp.Regexp{
+		regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`),
+		regexp.MustCompile(`\b__(\S(?:.*?\S)?)__\b`),
+		regexp.MustCompile(`\*(\S(?:.*?\S)?)\*`),
+		regexp.MustCompile(`\b_(\S(?:.*?\S)?)_\b`),
+		regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`),
+		regexp.MustCompile("`([^`]*)`"),
+	}
+	markdownHTMLTag     = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	markdownRule        = regexp.MustCompile(`^\s{0,3}(?:[-*_]\s*){3,}$`)
+	markdownTableBorder = regexp.MustCompile(`^\s*\|?\s*:?-{3,}:?\s*(?:\|\s*:?-{3,}:?\s*)*\|?\s*$`)
+)
+
+// extractMarkdown strips the markdown syntax, the title is taken from the front matter or the first heading
+func extractMarkdown(data []byte) (nativeExtraction, error) {
+	res := nativeExtraction{}
+	var sb strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.ToValidUTF8(data, nil)))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+
+	first, frontMatter := true, false
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// yaml front matter
+		if first && strings.TrimSpace(line) == "---" {
+			first, frontMatter = false, true
+			continue
+		}
+		first = false
+		if frontMatter {
+			if strings.TrimSpace(line) == "---" {
+				frontMatter = false
+				continue
+			}
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "title":
+				res.Title = value
+			case "author":
+				res.Authors = appendAuthor(res.Authors, value)
+			}
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") || markdownRule.MatchString(line) || markdownTableBorder.MatchString(line) {
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+			if res.Title == "" {
+				res.Title = stripMarkdownInline(line)
+			}
+		}
+
+		line = strings.TrimLeft(line, " \t>")
+		line = markdownListMarker.ReplaceAllString(line, "")
+		line = strings.ReplaceAll(strings.Trim(line, "|"), "|", " ")
+		sb.WriteString(stripMarkdownInline(line))
+		sb.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return res, err
+	}
+
+	res.Content = collapseBlankLines(sb.String())
+	return res, nil
+}
+
+func stripMarkdownInline(s string) string {
+	s = markdownImage.ReplaceAllString(s, "$1")
+	s = markdownLink.ReplaceAllString(s, "$1")
+	s = markdownHTMLTag.ReplaceAllString(s, "")
+	for _, emphasis := range markdownEmphasis {
+		s = emphasis.ReplaceAllString(s, "$1")
+	}
+	return strings.TrimSpace(s)
+}
+
+// collapseBlankLines trims all lines and removes empty ones
+func collapseBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
@@ -0,0 +1,156 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+)
+
+// nativeExtraction is the result of a native content extraction
+type nativeExtraction struct {
+	Title     string
+	Content   string
+	Authors   []string
+	PageCount uint64
+}
+
+// nativeFormat extracts the content of a file format from its data
+type nativeFormat func(data []byte) (nativeExtraction, error)
+
+var (
+	nativeMimeTypes = map[string]nativeFormat{
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   extractDocx,
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         extractXlsx,
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation": extractPptx,
+		"application/vnd.oasis.opendocument.text":                                   extractODF,
+		"application/vnd.oasis.opendocument.spreadsheet":                            extractODF,
+		"application/vnd.oasis.opendocument.presentation":                           extractODF,
+		"application/pdf":       extractPDF,
+		"text/markdown":         extractMarkdown,
+		"text/x-markdown":       extractMarkdown,
+		"text/html":             extractHTML,
+		"application/xhtml+xml": extractHTML,
+		"message/rfc822":        extractEML,
+		"text/plain":            extractPlain,
+	}
+
+	nativeExtensions = map[string]nativeFormat{
+		".docx":     extractDocx,
+		".xlsx":     extractXlsx,
+		".pptx":     extractPptx,
+		".odt":      extractODF,
+		".ods":      extractODF,
+		".odp":      extractODF,
+		".pdf":      extractPDF,
+		".md":       extractMarkdown,
+		".markdown": extractMarkdown,
+		".html":     extractHTML,
+		".htm":      extractHTML,
+		".eml":      extractEML,
+		".txt":      extractPlain,
+	}
+)
+
+// Native is used to extract content from a resource without an external service,
+// it supports office documents, PDF text layers, Markdown, HTML and emails.
+type Native struct {
+	*Basic
+	Retriever
+	ContentExtractionSizeLimit uint64
+}
+
+// NewNativeExtractor creates a new Native instance.
+func NewNativeExtractor(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], logger log.Logger, cfg *config.Config) (*Native, error) {
+	basic, err := NewBasicExtractor(logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Native{
+		Basic:                      basic,
+		Retriever:                  newCS3Retriever(gatewaySelector, logger, cfg.Extractor.CS3AllowInsecure),
+		ContentExtractionSizeLimit: cfg.ContentExtractionSizeLimit,
+	}, nil
+}
+
+// Extract loads a resource from its underlying storage and extracts its content if the format is supported.
+// Files larger than the content extraction size limit are only indexed with their metadata,
+// most formats can not be read partially.
+func (n Native) Extract(ctx context.Context, ri *provider.ResourceInfo) (Document, error) {
+	doc, err := n.Basic.Extract(ctx, ri)
+	if err != nil {
+		return doc, err
+	}
+
+	if ri.Size == 0 || ri.Size > n.ContentExtractionSizeLimit {
+		return doc, nil
+	}
+
+	if ri.Type != provider.ResourceType_RESOURCE_TYPE_FILE {
+		return doc, nil
+	}
+
+	extract := nativeFormatOf(ri)
+	if extract == nil {
+		return doc, nil
+	}
+
+	data, err := n.Retrieve(ctx, ri.Id)
+	if err != nil {
+		return doc, err
+	}
+	defer data.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(data, int64(ri.Size))) //nolint:gosec // bound by the content extraction size limit
+	if err != nil {
+		return doc, err
+	}
+
+	res, err := extract(buf)
+	if err != nil {
+		return doc, fmt.Errorf("could not extract content of %q: %w", ri.Name, err)
+	}
+
+	doc.Title = strings.TrimSpace(res.Title)
+	doc.Content = strings.TrimSpace(res.Content)
+	doc.Authors = res.Authors
+	doc.PageCount = res.PageCount
+
+	return doc, nil
+}
+
+func nativeFormatOf(ri *provider.ResourceInfo) nativeFormat {
+	mimeType, _, _ := strings.Cut(ri.MimeType, ";")
+	if f, ok := nativeMimeTypes[strings.TrimSpace(mimeType)]; ok {
+		return f
+	}
+	return nativeExtensions[strings.ToLower(path.Ext(ri.Name))]
+}
+
+func extractPlain(data []byte) (nativeExtraction, error) {
+	return nativeExtraction{Content: string(bytes.ToValidUTF8(data, nil))}, nil
+}
+
+// appendAuthor adds an author if it is not empty and not known yet
+func appendAuthor(authors []string, author string) []string {
+	author = strings.TrimSpace(author)
+	if author == "" {
+		return authors
+	}
+	for _, a := range authors {
+		if a == author {
+			return authors
+		}
+	}
+	return append(authors, author)
+}
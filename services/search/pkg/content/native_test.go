@@ -0,0 +1,312 @@
+package content_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	conf "github.com/owncloud/ocis/v2/services/search/pkg/config/defaults"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	contentMocks "github.com/owncloud/ocis/v2/services/search/pkg/content/mocks"
+)
+
+func zipFile(parts map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	w := zip.NewWriter(buf)
+	for name, data := range parts {
+		f, err := w.Create(name)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Write([]byte(data))
+		Expect(err).ToNot(HaveOccurred())
+	}
+	Expect(w.Close()).To(Succeed())
+	return buf.Bytes()
+}
+
+func deflate(data string) string {
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	_, err := w.Write([]byte(data))
+	Expect(err).ToNot(HaveOccurred())
+	Expect(w.Close()).To(Succeed())
+	return buf.String()
+}
+
+// pdfFile builds a pdf file with a cross-reference table from the given object bodies
+func pdfFile(objects ...string) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, 0, len(objects))
+	for i, obj := range objects {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R /Info 2 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func pdfStream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+var _ = Describe("Native", func() {
+	Describe("extract", func() {
+		var (
+			native    *content.Native
+			retriever *contentMocks.Retriever
+		)
+
+		extract := func(name, mimeType string, data []byte) content.Document {
+			retriever.On("Retrieve", mock.Anything, mock.Anything).Return(io.NopCloser(bytes.NewReader(data)), nil).Once()
+
+			doc, err := native.Extract(context.TODO(), &provider.ResourceInfo{
+				Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+				Name:     name,
+				MimeType: mimeType,
+				Size:     uint64(len(data)),
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return doc
+		}
+
+		BeforeEach(func() {
+			var err error
+			native, err = content.NewNativeExtractor(nil, log.NewLogger(), conf.DefaultConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			retriever = &contentMocks.Retriever{}
+			native.Retriever = retriever
+		})
+
+		It("skips non file resources", func() {
+			doc, err := native.Extract(context.TODO(), &provider.ResourceInfo{Type: provider.ResourceType_RESOURCE_TYPE_CONTAINER, Size: 10})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Content).To(BeEmpty())
+			retriever.AssertNotCalled(GinkgoT(), "Retrieve", mock.Anything, mock.Anything)
+		})
+
+		It("skips unsupported formats", func() {
+			doc, err := native.Extract(context.TODO(), &provider.ResourceInfo{
+				Type:     provider.ResourceType_RESOURCE_TYPE_FILE,
+				Name:     "image.png",
+				MimeType: "image/png",
+				Size:     10,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Content).To(BeEmpty())
+			retriever.AssertNotCalled(GinkgoT(), "Retrieve", mock.Anything, mock.Anything)
+		})
+
+		It("skips files above the size limit", func() {
+			native.ContentExtractionSizeLimit = 5
+			doc, err := native.Extract(context.TODO(), &provider.ResourceInfo{
+				Type: provider.ResourceType_RESOURCE_TYPE_FILE,
+				Name: "large.txt",
+				Size: 10,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(doc.Name).To(Equal("large.txt"))
+			Expect(doc.Content).To(BeEmpty())
+		})
+
+		It("extracts docx documents", func() {
+			doc := extract("letter.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", zipFile(map[string]string{
+				"word/document.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Dear</w:t></w:r><w:r><w:t xml:space="preserve"> reader,</w:t></w:r></w:p>
+<w:p><w:r><w:t>the</w:t><w:tab/><w:t>content</w:t></w:r></w:p>
+</w:body></w:document>`,
+				"docProps/core.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title>A Letter</dc:title><dc:creator>Jane Doe</dc:creator></cp:coreProperties>`,
+				"docProps/app.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Pages>2</Pages></Properties>`,
+			}))
+
+			Expect(doc.Title).To(Equal("A Letter"))
+			Expect(doc.Content).To(Equal("Dear reader,\nthe\tcontent"))
+			Expect(doc.Authors).To(Equal([]string{"Jane Doe"}))
+			Expect(doc.PageCount).To(Equal(uint64(2)))
+		})
+
+		It("extracts xlsx documents", func() {
+			doc := extract("table.xlsx", "", zipFile(map[string]string{
+				"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Revenue</t></si></sst>`,
+				"xl/worksheets/sheet1.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row>
+<c r="A1" t="s"><v>0</v></c><c r="B1"><v>4711</v></c><c r="C1" t="inlineStr"><is><t>inline</t></is></c>
+</row></sheetData></worksheet>`,
+				"xl/worksheets/sheet2.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`,
+			}))
+
+			Expect(doc.Content).To(ContainSubstring("Revenue"))
+			Expect(doc.Content).To(ContainSubstring("4711"))
+			Expect(doc.Content).To(ContainSubstring("inline"))
+			Expect(doc.Content).ToNot(ContainSubstring("0\n"))
+			Expect(doc.PageCount).To(Equal(uint64(2)))
+		})
+
+		It("extracts pptx documents in slide order", func() {
+			slide := `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:txBody><a:p><a:r><a:t>%s</a:t></a:r></a:p></p:txBody></p:sld>`
+			doc := extract("talk.pptx", "application/vnd.openxmlformats-officedocument.presentationml.presentation", zipFile(map[string]string{
+				"ppt/slides/slide10.xml": fmt.Sprintf(slide, "last"),
+				"ppt/slides/slide2.xml":  fmt.Sprintf(slide, "second"),
+				"ppt/slides/slide1.xml":  fmt.Sprintf(slide, "first"),
+			}))
+
+			Expect(doc.Content).To(Equal("first\n\nsecond\n\nlast"))
+			Expect(doc.PageCount).To(Equal(uint64(3)))
+		})
+
+		It("extracts odf documents", func() {
+			doc := extract("notes.odt", "application/vnd.oasis.opendocument.text", zipFile(map[string]string{
+				"content.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:automatic-styles><style>ignored</style></office:automatic-styles>
+<office:body><office:text><text:h>Heading</text:h><text:p>some<text:s/>text</text:p></office:text></office:body>
+</office:document-content>`,
+				"meta.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<office:meta><dc:title>Notes</dc:title><meta:initial-creator>Jane Doe</meta:initial-creator><dc:creator>John Doe</dc:creator>
+<meta:document-statistic meta:page-count="3"/></office:meta></office:document-meta>`,
+			}))
+
+			Expect(doc.Title).To(Equal("Notes"))
+			Expect(doc.Content).To(Equal("Heading\nsome text"))
+			Expect(doc.Authors).To(Equal([]string{"Jane Doe", "John Doe"}))
+			Expect(doc.PageCount).To(Equal(uint64(3)))
+		})
+
+		It("counts the sheets of odf spreadsheets", func() {
+			doc := extract("table.ods", "application/vnd.oasis.opendocument.spreadsheet", zipFile(map[string]string{
+				"content.xml": `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet><table:table><table:table-row><table:table-cell><text:p>cell</text:p></table:table-cell></table:table-row></table:table><table:table/></office:spreadsheet></office:body>
+</office:document-content>`,
+			}))
+
+			Expect(doc.Content).To(Equal("cell"))
+			Expect(doc.PageCount).To(Equal(uint64(2)))
+		})
+
+		It("extracts the text layer of pdf documents", func() {
+			doc := extract("report.pdf", "application/pdf", pdfFile(
+				"<< /Type /Catalog /Pages 3 0 R >>",
+				"<< /Title (The Report) /Author <FEFF004A0061006E006500200044006F0065> >>",
+				"<< /Type /Pages /Kids [4 0 R 5 0 R] /Count 2 /Resources << /Font << /F1 6 0 R >> >> >>",
+				"<< /Type /Page /Parent 3 0 R /Contents 7 0 R >>",
+				"<< /Type /Page /Parent 3 0 R /Contents [8 0 R] >>",
+				"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+				pdfStream("/Filter /FlateDecode", deflate("BT /F1 12 Tf 72 712 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Wor) 20 (ld) -300 (again)] TJ ET")),
+				pdfStream("", "BT /F1 12 Tf (second page) Tj ET"),
+			))
+
+			Expect(doc.Title).To(Equal("The Report"))
+			Expect(doc.Authors).To(Equal([]string{"Jane Doe"}))
+			Expect(doc.Content).To(Equal("Hello (PDF)\nWorld again\nsecond page"))
+			Expect(doc.PageCount).To(Equal(uint64(2)))
+		})
+
+		It("maps pdf text with to unicode cmaps", func() {
+			cmap := `/CIDInit /ProcSet findresource begin 12 dict begin begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0001> <00DF> endbfchar
+1 beginbfrange <0010> <0012> <0061> endbfrange
+endcmap end end`
+			doc := extract("cmap.pdf", "application/pdf", pdfFile(
+				"<< /Type /Catalog /Pages 3 0 R >>",
+				"<< >>",
+				"<< /Type /Pages /Kids [4 0 R] /Count 1 >>",
+				"<< /Type /Page /Parent 3 0 R /Contents 5 0 R /Resources << /Font << /F1 6 0 R >> >> >>",
+				pdfStream("", "BT /F1 12 Tf <0010001100120001> Tj ET"),
+				"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /ToUnicode 7 0 R >>",
+				pdfStream("/Filter /FlateDecode", deflate(cmap)),
+			))
+
+			Expect(doc.Content).To(Equal("abcß"))
+		})
+
+		It("extracts markdown documents", func() {
+			doc := extract("README.md", "text/markdown", []byte(`---
+title: "Front Matter Title"
+author: Jane Doe
+---
+# Heading with **bold** text
+
+- [x] a [link](https://owncloud.com) and `+"`code`"+`
+1. ![image](image.png)
+
+| a | b |
+| --- | --- |
+`))
+
+			Expect(doc.Title).To(Equal("Front Matter Title"))
+			Expect(doc.Authors).To(Equal([]string{"Jane Doe"}))
+			Expect(doc.Content).To(Equal("Heading with bold text\na link and code\nimage\na   b"))
+		})
+
+		It("uses the first markdown heading as title", func() {
+			doc := extract("notes.markdown", "", []byte("intro\n\n## The _Title_ ##\ntext"))
+			Expect(doc.Title).To(Equal("The Title"))
+		})
+
+		It("extracts html documents", func() {
+			doc := extract("page.html", "text/html", []byte(`<!DOCTYPE html><html><head>
+<title>Page Title</title><meta name="author" content="Jane Doe"><style>body { color: red; }</style>
+</head><body><h1>Welcome</h1><p>to <b>the</b> page</p><script>alert("hidden")</script></body></html>`))
+
+			Expect(doc.Title).To(Equal("Page Title"))
+			Expect(doc.Authors).To(Equal([]string{"Jane Doe"}))
+			Expect(doc.Content).To(Equal("Welcome\nto the page"))
+		})
+
+		It("extracts emails", func() {
+			doc := extract("mail.eml", "message/rfc822", []byte("From: =?utf-8?q?J=C3=B6rg?= <joerg@example.com>\r\n"+
+				"Subject: =?utf-8?b?w5xiZXIgdGhlIHJlcG9ydA==?=\r\n"+
+				"MIME-Version: 1.0\r\n"+
+				"Content-Type: multipart/mixed; boundary=outer\r\n\r\n"+
+				"--outer\r\n"+
+				"Content-Type: multipart/alternative; boundary=inner\r\n\r\n"+
+				"--inner\r\n"+
+				"Content-Type: text/plain; charset=iso-8859-1\r\n"+
+				"Content-Transfer-Encoding: quoted-printable\r\n\r\n"+
+				"Gr=FC=DFe from the =\r\nplain part\r\n"+
+				"--inner\r\n"+
+				"Content-Type: text/html\r\n\r\n"+
+				"<p>html part</p>\r\n"+
+				"--inner--\r\n"+
+				"--outer\r\n"+
+				"Content-Type: text/plain\r\n"+
+				"Content-Disposition: attachment; filename=notes.txt\r\n\r\n"+
+				"attachment\r\n"+
+				"--outer--\r\n"))
+
+			Expect(doc.Title).To(Equal("Über the report"))
+			Expect(doc.Authors).To(Equal([]string{"Jörg"}))
+			Expect(doc.Content).To(Equal("Grüße from the plain part"))
+		})
+
+		It("falls back to the html part of emails", func() {
+			doc := extract("mail.eml", "", []byte("From: joerg@example.com\r\n"+
+				"Subject: html only\r\n"+
+				"Content-Type: text/html\r\n"+
+				"Content-Transfer-Encoding: base64\r\n\r\n"+
+				"PHA+aHRtbCBib2R5PC9w\r\nPg==\r\n"))
+
+			Expect(doc.Authors).To(Equal([]string{"joerg@example.com"}))
+			Expect(doc.Content).To(Equal("html body"))
+		})
+	})
+})
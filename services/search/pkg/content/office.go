@@ -0,0 +1,362 @@
+package content
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxArchiveEntrySize limits the uncompressed size of a single office document part
+const maxArchiveEntrySize = 128 << 20
+
+// xmlTextWalker collects the character data of selected xml elements
+type xmlTextWalker struct {
+	// text contains the local names of the elements whose character data is collected,
+	// an empty set collects all character data
+	text map[string]bool
+	// breaks contains the local names of the elements which end a line
+	breaks map[string]bool
+	// spaces maps the local names of empty elements to the text they represent
+	spaces map[string]string
+	// onStart is called for every start element
+	onStart func(xml.StartElement)
+	// skip reports if the character data of a text element should not be collected
+	skip func(local string) bool
+}
+
+func (w xmlTextWalker) walk(r io.Reader) (string, error) {
+	var (
+		sb    strings.Builder
+		depth int
+		// collected tracks for every open text element if its character data is collected
+		collected []bool
+	)
+
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		switch {
+		case errors.Is(err, io.EOF):
+			return sb.String(), nil
+		case err != nil:
+			return sb.String(), err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if w.onStart != nil {
+				w.onStart(t)
+			}
+			if w.text[t.Name.Local] {
+				collect := w.skip == nil || !w.skip(t.Name.Local)
+				collected = append(collected, collect)
+				if collect {
+					depth++
+				}
+			}
+			if s, ok := w.spaces[t.Name.Local]; ok {
+				sb.WriteString(s)
+			}
+		case xml.EndElement:
+			if w.text[t.Name.Local] && len(collected) > 0 {
+				if collected[len(collected)-1] {
+					depth--
+				}
+				collected = collected[:len(collected)-1]
+			}
+			if w.breaks[t.Name.Local] {
+				sb.WriteString("\n")
+			}
+		case xml.CharData:
+			if depth > 0 || len(w.text) == 0 {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// officeArchive provides access to the parts of a zip based office document
+type officeArchive struct {
+	files map[string]*zip.File
+}
+
+func openOfficeArchive(data []byte) (officeArchive, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return officeArchive{}, err
+	}
+
+	a := officeArchive{files: make(map[string]*zip.File, len(r.File))}
+	for _, f := range r.File {
+		a.files[f.Name] = f
+	}
+
+	return a, nil
+}
+
+func (a officeArchive) open(name string) (io.ReadCloser, error) {
+	f, ok := a.files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing part %q", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(rc, maxArchiveEntrySize), rc}, nil
+}
+
+func (a officeArchive) text(name string, w xmlTextWalker) (string, error) {
+	rc, err := a.open(name)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	return w.walk(rc)
+}
+
+// numbered returns the parts in dir whose names are prefix followed by a number, in numeric order
+func (a officeArchive) numbered(dir, prefix string) []string {
+	type part struct {
+		name string
+		n    int
+	}
+
+	var parts []part
+	for name := range a.files {
+		if path.Dir(name) != dir {
+			continue
+		}
+
+		base := strings.TrimSuffix(path.Base(name), ".xml")
+		if !strings.HasPrefix(base, prefix) || base == path.Base(name) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimPrefix(base, prefix))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, part{name: name, n: n})
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].n < parts[j].n })
+
+	names := make([]string, 0, len(parts))
+	for _, p := range parts {
+		names = append(names, p.name)
+	}
+
+	return names
+}
+
+// ooxmlProperties reads the title, the authors and the page count from the document properties of an OOXML document
+func (a officeArchive) ooxmlProperties(res *nativeExtraction) {
+	if rc, err := a.open("docProps/core.xml"); err == nil {
+		var core struct {
+			Title   string `xml:"title"`
+			Creator string `xml:"creator"`
+		}
+		if xml.NewDecoder(rc).Decode(&core) == nil {
+			res.Title = core.Title
+			res.Authors = appendAuthor(res.Authors, core.Creator)
+		}
+		rc.Close()
+	}
+
+	if rc, err := a.open("docProps/app.xml"); err == nil {
+		var app struct {
+			Pages  uint64 `xml:"Pages"`
+			Slides uint64 `xml:"Slides"`
+		}
+		if xml.NewDecoder(rc).Decode(&app) == nil {
+			res.PageCount = max(app.Pages, app.Slides)
+		}
+		rc.Close()
+	}
+}
+
+func extractDocx(data []byte) (nativeExtraction, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	a.ooxmlProperties(&res)
+
+	w := xmlTextWalker{
+		text:   map[string]bool{"t": true},
+		breaks: map[string]bool{"p": true},
+		spaces: map[string]string{"tab": "\t", "br": "\n", "cr": "\n"},
+	}
+
+	res.Content, err = a.text("word/document.xml", w)
+	if err != nil {
+		return res, err
+	}
+
+	for _, part := range []string{"word/footnotes.xml", "word/endnotes.xml"} {
+		if text, err := a.text(part, w); err == nil {
+			res.Content += "\n" + text
+		}
+	}
+
+	return res, nil
+}
+
+func extractXlsx(data []byte) (nativeExtraction, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	a.ooxmlProperties(&res)
+
+	var sb strings.Builder
+	if text, err := a.text("xl/sharedStrings.xml", xmlTextWalker{
+		text:   map[string]bool{"t": true},
+		breaks: map[string]bool{"si": true},
+	}); err == nil {
+		sb.WriteString(text)
+	}
+
+	// inline strings and values are stored in the sheets, shared strings are only referenced by index
+	sheets := a.numbered("xl/worksheets", "sheet")
+	for _, sheet := range sheets {
+		shared := false
+		text, err := a.text(sheet, xmlTextWalker{
+			text:   map[string]bool{"t": true, "v": true},
+			breaks: map[string]bool{"c": true},
+			onStart: func(e xml.StartElement) {
+				if e.Name.Local != "c" {
+					return
+				}
+				shared = false
+				for _, attr := range e.Attr {
+					if attr.Name.Local == "t" && attr.Value == "s" {
+						shared = true
+					}
+				}
+			},
+			// shared string cells contain the index of the string, not its value
+			skip: func(local string) bool { return local == "v" && shared },
+		})
+		if err != nil {
+			return res, err
+		}
+		sb.WriteString(text)
+	}
+
+	res.Content = sb.String()
+	if res.PageCount == 0 {
+		res.PageCount = uint64(len(sheets))
+	}
+
+	return res, nil
+}
+
+func extractPptx(data []byte) (nativeExtraction, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	a.ooxmlProperties(&res)
+
+	w := xmlTextWalker{
+		text:   map[string]bool{"t": true},
+		breaks: map[string]bool{"p": true},
+		spaces: map[string]string{"br": "\n"},
+	}
+
+	var sb strings.Builder
+	slides := a.numbered("ppt/slides", "slide")
+	for _, slide := range slides {
+		text, err := a.text(slide, w)
+		if err != nil {
+			return res, err
+		}
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	}
+
+	res.Content = sb.String()
+	if res.PageCount == 0 {
+		res.PageCount = uint64(len(slides))
+	}
+
+	return res, nil
+}
+
+func extractODF(data []byte) (nativeExtraction, error) {
+	a, err := openOfficeArchive(data)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	res := nativeExtraction{}
+	if rc, err := a.open("meta.xml"); err == nil {
+		var meta struct {
+			Meta struct {
+				Title          string   `xml:"title"`
+				Creators       []string `xml:"creator"`
+				InitialCreator string   `xml:"initial-creator"`
+				Statistic      struct {
+					PageCount  uint64 `xml:"page-count,attr"`
+					TableCount uint64 `xml:"table-count,attr"`
+				} `xml:"document-statistic"`
+			} `xml:"meta"`
+		}
+		if xml.NewDecoder(rc).Decode(&meta) == nil {
+			res.Title = meta.Meta.Title
+			res.Authors = appendAuthor(res.Authors, meta.Meta.InitialCreator)
+			for _, creator := range meta.Meta.Creators {
+				res.Authors = appendAuthor(res.Authors, creator)
+			}
+			res.PageCount = meta.Meta.Statistic.PageCount
+		}
+		rc.Close()
+	}
+
+	var pages, tables uint64
+	res.Content, err = a.text("content.xml", xmlTextWalker{
+		text:   map[string]bool{"body": true},
+		breaks: map[string]bool{"p": true, "h": true},
+		spaces: map[string]string{"s": " ", "tab": "\t", "line-break": "\n"},
+		onStart: func(e xml.StartElement) {
+			switch {
+			case e.Name.Local == "page" && strings.HasSuffix(e.Name.Space, ":drawing:1.0"):
+				pages++
+			case e.Name.Local == "table" && strings.HasSuffix(e.Name.Space, ":table:1.0"):
+				tables++
+			}
+		},
+	})
+	if err != nil {
+		return res, err
+	}
+
+	// presentations and spreadsheets have no page statistic, count their slides or sheets instead
+	if res.PageCount == 0 {
+		res.PageCount = max(pages, tables)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,1015 @@
+package content
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// maxDecodedStreamSize limits the size of a single decoded pdf stream
+	maxDecodedStreamSize = 128 << 20
+	// maxPDFNesting limits the depth of page trees, form xobjects and references
+	maxPDFNesting = 32
+	// maxCMapRange limits the number of codes a single cmap range can define
+	maxCMapRange = 1 << 16
+)
+
+var (
+	errNotPDF = errors.New("not a pdf document")
+
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+)
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+// pdfLexer reads the tokens and objects of pdf files and content streams
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the next token, delimiters and operators are returned as keywords
+func (l *pdfLexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		var name []byte
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			if l.data[l.pos] == '#' && l.pos+2 < len(l.data) {
+				if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+					name = append(name, b[0])
+					l.pos += 3
+					continue
+				}
+			}
+			name = append(name, l.data[l.pos])
+			l.pos++
+		}
+		return pdfName(name), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), nil
+	case c == '<':
+		return l.hexString()
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(c), nil
+	case c == ')' || c == '>':
+		// stray delimiters of broken files are skipped
+		l.pos++
+		return l.token()
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil {
+		return n, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) literalString() (pdfString, error) {
+	l.pos++ // (
+	var (
+		s     []byte
+		depth = 1
+	)
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s, nil
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(e - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					v = v*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(v) //nolint:gosec // octal escapes are at most three digits
+			default:
+				c = e
+			}
+		}
+		s = append(s, c)
+	}
+	return s, nil
+}
+
+func (l *pdfLexer) hexString() (pdfString, error) {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, hex.DecodedLen(len(digits)))
+	_, err := hex.Decode(s, digits)
+	return s, err
+}
+
+// object reads a complete object, references and streams are only resolved if file is set
+func (l *pdfLexer) object(file bool, depth int) (any, error) {
+	if depth > maxPDFNesting {
+		return nil, errors.New("pdf objects are nested too deep")
+	}
+
+	token, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case pdfKeyword("<<"):
+		dict := pdfDict{}
+		for {
+			key, err := l.object(file, depth+1)
+			if err != nil {
+				return dict, err
+			}
+			if key == pdfKeyword(">>") {
+				break
+			}
+			name, ok := key.(pdfName)
+			if !ok {
+				continue
+			}
+			value, err := l.object(file, depth+1)
+			if err != nil {
+				return dict, err
+			}
+			dict[name] = value
+		}
+		if file {
+			return l.stream(dict), nil
+		}
+		return dict, nil
+	case pdfKeyword("["):
+		var array pdfArray
+		for {
+			value, err := l.object(file, depth+1)
+			if err != nil {
+				return array, err
+			}
+			if value == pdfKeyword("]") {
+				return array, nil
+			}
+			array = append(array, value)
+		}
+	}
+
+	if n, ok := token.(float64); ok && file {
+		pos := l.pos
+		if gen, err := l.token(); err == nil {
+			if _, ok := gen.(float64); ok {
+				if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+					return pdfRef{num: int(n), gen: int(gen.(float64))}, nil
+				}
+			}
+		}
+		l.pos = pos
+	}
+
+	return token, nil
+}
+
+// stream reads the stream data following a dictionary if there is any
+func (l *pdfLexer) stream(dict pdfDict) any {
+	pos := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = pos
+		return dict
+	}
+
+	l.pos += len("stream")
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end >= start && end <= len(l.data) {
+			rest := bytes.TrimLeft(l.data[end:], "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				l.pos = len(l.data) - len(rest) + len("endstream")
+				return pdfStream{dict: dict, raw: l.data[start:end]}
+			}
+		}
+	}
+
+	// the length is indirect or wrong, use the stream end marker instead
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return pdfStream{dict: dict, raw: l.data[start:]}
+	}
+	l.pos = start + end + len("endstream")
+	raw := bytes.TrimSuffix(l.data[start:start+end], []byte("\n"))
+	return pdfStream{dict: dict, raw: bytes.TrimSuffix(raw, []byte("\r"))}
+}
+
+// pdfDocument contains all objects of a pdf file
+type pdfDocument struct {
+	objects map[int]any
+	trailer pdfDict
+	cmaps   map[pdfRef]*pdfCMap
+}
+
+func parsePDF(data []byte) (*pdfDocument, error) {
+	if i := bytes.Index(data, []byte("%PDF-")); i < 0 || i > 1024 {
+		return nil, errNotPDF
+	}
+
+	doc := &pdfDocument{
+		objects: map[int]any{},
+		trailer: pdfDict{},
+		cmaps:   map[pdfRef]*pdfCMap{},
+	}
+
+	// the objects are read in file order instead of following the cross-reference tables,
+	// later revisions of an object replace earlier ones and broken tables do not matter
+	var streams []pdfStream
+	l := &pdfLexer{data: data}
+	for l.pos < len(data) {
+		m := pdfObjectHeader.FindSubmatchIndex(data[l.pos:])
+		if m == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[l.pos+m[2] : l.pos+m[3]]))
+		l.pos += m[1]
+
+		obj, err := l.object(true, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			continue
+		}
+		doc.objects[num] = obj
+
+		if s, ok := obj.(pdfStream); ok {
+			switch s.dict["Type"] {
+			case pdfName("ObjStm"):
+				streams = append(streams, s)
+			case pdfName("XRef"):
+				doc.mergeTrailer(s.dict)
+			}
+		}
+	}
+
+	for i := 0; i < len(data); {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		l := &pdfLexer{data: data, pos: i + j + len("trailer")}
+		if dict, err := l.object(true, 0); err == nil {
+			if dict, ok := dict.(pdfDict); ok {
+				doc.mergeTrailer(dict)
+			}
+		}
+		i = l.pos
+	}
+
+	for _, s := range streams {
+		doc.readObjectStream(s)
+	}
+
+	return doc, nil
+}
+
+func (d *pdfDocument) mergeTrailer(dict pdfDict) {
+	for _, key := range []pdfName{"Root", "Info", "Encrypt"} {
+		if v, ok := dict[key]; ok {
+			d.trailer[key] = v
+		}
+	}
+}
+
+// readObjectStream adds the objects of a compressed object stream
+func (d *pdfDocument) readObjectStream(s pdfStream) {
+	data, err := d.decode(s)
+	if err != nil {
+		return
+	}
+
+	n, _ := d.resolve(s.dict["N"]).(float64)
+	first, _ := d.resolve(s.dict["First"]).(float64)
+	if int(first) > len(data) {
+		return
+	}
+
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		num, err := header.token()
+		if err != nil {
+			return
+		}
+		offset, err := header.token()
+		if err != nil {
+			return
+		}
+		numf, ok1 := num.(float64)
+		offsetf, ok2 := offset.(float64)
+		if !ok1 || !ok2 || int(first+offsetf) > len(data) {
+			return
+		}
+
+		if _, ok := d.objects[int(numf)]; ok {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: int(first + offsetf)}
+		if obj, err := l.object(true, 0); err == nil {
+			d.objects[int(numf)] = obj
+		}
+	}
+}
+
+// resolve follows references until it reaches a direct object
+func (d *pdfDocument) resolve(v any) any {
+	for i := 0; i < maxPDFNesting; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDocument) dict(v any) pdfDict {
+	switch v := d.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// decode applies the filters of a stream
+func (d *pdfDocument) decode(s pdfStream) ([]byte, error) {
+	var filters pdfArray
+	switch f := d.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = pdfArray{f}
+	case pdfArray:
+		filters = f
+	}
+
+	var params pdfArray
+	switch p := d.resolve(s.dict["DecodeParms"]).(type) {
+	case pdfDict:
+		params = pdfArray{p}
+	case pdfArray:
+		params = p
+	}
+
+	data := s.raw
+	for i, filter := range filters {
+		if i < len(params) {
+			if predictor, _ := d.dict(params[i])["Predictor"].(float64); predictor > 1 {
+				return nil, fmt.Errorf("unsupported predictor %v", predictor)
+			}
+		}
+
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			decoded, err := io.ReadAll(io.LimitReader(r, maxDecodedStreamSize))
+			// many generators write truncated streams, keep what could be read
+			if err != nil && len(decoded) == 0 {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			l := &pdfLexer{data: append(append([]byte{'<'}, bytes.TrimSuffix(bytes.TrimSpace(data), []byte(">"))...), '>')}
+			decoded, err := l.hexString()
+			if err != nil {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			src := bytes.TrimSuffix(bytes.TrimSpace(data), []byte("~>"))
+			decoded := make([]byte, 4*len(src))
+			n, _, err := ascii85.Decode(decoded, src, true)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded[:n]
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", filter)
+		}
+	}
+
+	return data, nil
+}
+
+// pages returns the page dictionaries in document order with their inherited resources
+func (d *pdfDocument) pages() []pdfPage {
+	var (
+		pages   []pdfPage
+		visited = map[pdfRef]bool{}
+		walk    func(node any, resources pdfDict, depth int)
+	)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+
+		dict := d.dict(node)
+		if dict == nil || depth > maxPDFNesting {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+
+		kids, ok := d.resolve(dict["Kids"]).(pdfArray)
+		if !ok || dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// the page tree is broken, fall back to all page objects in object order
+	nums := make([]int, 0, len(d.objects))
+	for num := range d.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if dict := d.dict(d.objects[num]); dict != nil && dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+
+	return pages
+}
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// text interprets the content streams of a page and returns the text shown on it
+func (d *pdfDocument) text(page pdfPage) string {
+	var content []byte
+	switch c := d.resolve(page.dict["Contents"]).(type) {
+	case pdfStream:
+		content, _ = d.decode(c)
+	case pdfArray:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(pdfStream); ok {
+				if data, err := d.decode(s); err == nil {
+					content = append(append(content, data...), '\n')
+				}
+			}
+		}
+	}
+
+	var sb strings.Builder
+	d.interpret(&sb, content, page.resources, 0)
+	return sb.String()
+}
+
+func (d *pdfDocument) interpret(sb *strings.Builder, content []byte, resources pdfDict, depth int) {
+	if depth > maxPDFNesting {
+		return
+	}
+
+	var (
+		font     *pdfFont
+		operands []any
+		l        = &pdfLexer{data: content}
+	)
+	for {
+		token, err := l.object(false, 0)
+		if err != nil {
+			return
+		}
+
+		op, ok := token.(pdfKeyword)
+		if !ok {
+			operands = append(operands, token)
+			continue
+		}
+
+		switch op {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = d.font(d.dict(resources["Font"])[name])
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				sb.WriteString(font.decode(operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			sb.WriteString("\n")
+			if len(operands) >= 1 {
+				sb.WriteString(font.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				array, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range array {
+					// large negative adjustments separate words
+					if n, ok := item.(float64); ok && n < -200 {
+						sb.WriteString(" ")
+						continue
+					}
+					sb.WriteString(font.decode(item))
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := operands[1].(float64); ty != 0 {
+					sb.WriteString("\n")
+				} else {
+					sb.WriteString(" ")
+				}
+			}
+		case "T*", "Tm", "ET":
+			sb.WriteString("\n")
+		case "Do":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					if form, ok := d.resolve(d.dict(resources["XObject"])[name]).(pdfStream); ok && form.dict["Subtype"] == pdfName("Form") {
+						formResources := d.dict(form.dict["Resources"])
+						if formResources == nil {
+							formResources = resources
+						}
+						if data, err := d.decode(form); err == nil {
+							d.interpret(sb, data, formResources, depth+1)
+						}
+					}
+				}
+			}
+		case "ID":
+			// inline image data is binary and ends with the EI operator
+			end := bytes.Index(content[l.pos:], []byte("EI"))
+			for end >= 0 && l.pos+end+2 < len(content) && !isPDFSpace(content[l.pos+end+2]) {
+				next := bytes.Index(content[l.pos+end+2:], []byte("EI"))
+				if next < 0 {
+					end = -1
+					break
+				}
+				end += 2 + next
+			}
+			if end < 0 {
+				return
+			}
+			l.pos += end + 2
+		}
+		operands = operands[:0]
+	}
+}
+
+// info returns the title and the authors from the document information dictionary
+func (d *pdfDocument) info() (string, []string) {
+	info := d.dict(d.trailer["Info"])
+	if info == nil {
+		return "", nil
+	}
+
+	var title string
+	if s, ok := d.resolve(info["Title"]).(pdfString); ok {
+		title = decodePDFTextString(s)
+	}
+
+	var authors []string
+	if s, ok := d.resolve(info["Author"]).(pdfString); ok {
+		for _, author := range strings.Split(decodePDFTextString(s), ";") {
+			authors = appendAuthor(authors, author)
+		}
+	}
+
+	return title, authors
+}
+
+// decodePDFTextString decodes strings outside of content streams
+func decodePDFTextString(s []byte) string {
+	switch {
+	case bytes.HasPrefix(s, []byte{0xfe, 0xff}):
+		return decodeUTF16BE(s[2:])
+	case bytes.HasPrefix(s, []byte{0xef, 0xbb, 0xbf}):
+		return string(bytes.ToValidUTF8(s[3:], nil))
+	}
+	return decodeLatin1(s)
+}
+
+func decodeUTF16BE(s []byte) string {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiHigh contains the characters of the windows-1252 range 0x80 - 0x9f
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+func decodeLatin1(s []byte) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch {
+		case c >= 0x80 && c < 0xa0:
+			if r := winAnsiHigh[c-0x80]; r != 0 {
+				sb.WriteRune(r)
+			}
+		default:
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
+
+// pdfFont decodes the strings shown with a font
+type pdfFont struct {
+	cmap        *pdfCMap
+	differences map[byte]string
+	composite   bool
+}
+
+func (d *pdfDocument) font(v any) *pdfFont {
+	dict := d.dict(v)
+	if dict == nil {
+		return nil
+	}
+
+	font := &pdfFont{composite: dict["Subtype"] == pdfName("Type0")}
+	if s, ok := d.resolve(dict["ToUnicode"]).(pdfStream); ok {
+		ref, cached := dict["ToUnicode"].(pdfRef)
+		if cmap, ok := d.cmaps[ref]; cached && ok {
+			font.cmap = cmap
+		} else if data, err := d.decode(s); err == nil {
+			font.cmap = parseCMap(data)
+			if cached {
+				d.cmaps[ref] = font.cmap
+			}
+		}
+	}
+
+	if encoding := d.dict(dict["Encoding"]); encoding != nil {
+		if differences, ok := d.resolve(encoding["Differences"]).(pdfArray); ok {
+			font.differences = map[byte]string{}
+			code := 0
+			for _, item := range differences {
+				switch item := d.resolve(item).(type) {
+				case float64:
+					code = int(item)
+				case pdfName:
+					if code >= 0 && code < 256 {
+						if glyph := glyphText(string(item)); glyph != "" {
+							font.differences[byte(code)] = glyph
+						}
+					}
+					code++
+				}
+			}
+		}
+	}
+
+	return font
+}
+
+func (f *pdfFont) decode(v any) string {
+	s, ok := v.(pdfString)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case f == nil:
+		return decodeLatin1(s)
+	case f.cmap != nil:
+		return f.cmap.decode(s)
+	case f.composite:
+		// the codes of composite fonts without a unicode mapping are glyph ids
+		return ""
+	case f.differences != nil:
+		var sb strings.Builder
+		for _, c := range s {
+			if glyph, ok := f.differences[c]; ok {
+				sb.WriteString(glyph)
+			} else {
+				sb.WriteString(decodeLatin1([]byte{c}))
+			}
+		}
+		return sb.String()
+	}
+	return decodeLatin1(s)
+}
+
+var glyphNames = map[string]string{
+	"space": " ", "period": ".", "comma": ",", "colon": ":", "semicolon": ";", "hyphen": "-",
+	"quoteright": "’", "quoteleft": "‘", "quotedblleft": "“", "quotedblright": "”", "quotesingle": "'",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…", "fi": "fi", "fl": "fl", "ff": "ff",
+	"ffi": "ffi", "ffl": "ffl", "parenleft": "(", "parenright": ")", "exclam": "!", "question": "?",
+	"slash": "/", "ampersand": "&", "percent": "%", "zero": "0", "one": "1", "two": "2", "three": "3",
+	"four": "4", "five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+	"adieresis": "ä", "odieresis": "ö", "udieresis": "ü", "Adieresis": "Ä", "Odieresis": "Ö",
+	"Udieresis": "Ü", "germandbls": "ß", "eacute": "é", "egrave": "è", "agrave": "à", "ccedilla": "ç",
+}
+
+// glyphText maps a glyph name to its text
+func glyphText(name string) string {
+	if len(name) == 1 {
+		return name
+	}
+	if t, ok := glyphNames[name]; ok {
+		return t
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if r, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return string(rune(r))
+		}
+	}
+	return ""
+}
+
+// pdfCMap maps character codes to unicode text
+type pdfCMap struct {
+	codeLength int
+	mapping    map[uint32]string
+}
+
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{mapping: map[uint32]string{}}
+
+	var (
+		operands []any
+		section  pdfKeyword
+		l        = &pdfLexer{data: data}
+	)
+	for {
+		token, err := l.object(false, 0)
+		if err != nil {
+			break
+		}
+
+		keyword, ok := token.(pdfKeyword)
+		if !ok {
+			if section != "" {
+				operands = append(operands, token)
+			}
+			continue
+		}
+
+		switch keyword {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = keyword
+			operands = operands[:0]
+			continue
+		case "endcodespacerange":
+			for _, o := range operands {
+				if s, ok := o.(pdfString); ok && len(s) > cmap.codeLength {
+					cmap.codeLength = len(s)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					cmap.set(src, decodeUTF16BE(dst))
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				cmap.setRange(lo, hi, operands[i+2])
+			}
+		}
+		section = ""
+		operands = operands[:0]
+	}
+
+	if cmap.codeLength == 0 {
+		cmap.codeLength = 1
+	}
+
+	return cmap
+}
+
+func cmapCode(s []byte) uint32 {
+	var code uint32
+	for _, b := range s {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func (c *pdfCMap) set(src []byte, dst string) {
+	if len(src) > c.codeLength {
+		c.codeLength = min(len(src), 4)
+	}
+	c.mapping[cmapCode(src)] = dst
+}
+
+func (c *pdfCMap) setRange(lo, hi []byte, dst any) {
+	from, to := cmapCode(lo), cmapCode(hi)
+	if to < from || to-from >= maxCMapRange {
+		return
+	}
+
+	for code := from; code <= to; code++ {
+		offset := code - from
+		src := make([]byte, len(lo))
+		for i, v := len(src)-1, code; i >= 0; i, v = i-1, v>>8 {
+			src[i] = byte(v) //nolint:gosec // truncation is intended
+		}
+
+		switch dst := dst.(type) {
+		case pdfString:
+			runes := []rune(decodeUTF16BE(dst))
+			if len(runes) == 0 {
+				continue
+			}
+			runes[len(runes)-1] += rune(offset) //nolint:gosec // bound by maxCMapRange
+			c.set(src, string(runes))
+		case pdfArray:
+			if int(offset) < len(dst) {
+				if s, ok := dst[offset].(pdfString); ok {
+					c.set(src, decodeUTF16BE(s))
+				}
+			}
+		}
+	}
+}
+
+func (c *pdfCMap) decode(s []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i += c.codeLength {
+		end := min(i+c.codeLength, len(s))
+		if t, ok := c.mapping[cmapCode(s[i:end])]; ok {
+			sb.WriteString(t)
+		}
+	}
+	return sb.String()
+}
+
+// extractPDF reads the text layer of a pdf document, scanned documents without text only provide metadata
+func extractPDF(data []byte) (nativeExtraction, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return nativeExtraction{}, err
+	}
+
+	// the strings of encrypted documents can not be read
+	if doc.trailer["Encrypt"] != nil {
+		return nativeExtraction{}, nil
+	}
+
+	res := nativeExtraction{}
+	res.Title, res.Authors = doc.info()
+
+	pages := doc.pages()
+	res.PageCount = uint64(len(pages))
+
+	var sb strings.Builder
+	for _, page := range pages {
+		sb.WriteString(doc.text(page))
+		sb.WriteString("\n")
+	}
+	res.Content = collapseBlankLines(sb.String())
+
+	return res, nil
+}
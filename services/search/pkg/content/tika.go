@@ -100,6 +100,16 @@ func (t Tika) Extract(ctx context.Context, ri *provider.ResourceInfo) (Document,
 			doc.Content = strings.TrimSpace(fmt.Sprintf("%s %s", doc.Content, content))
 		}
 
+		if creator, err := getFirstValue(meta, "dc:creator"); err == nil {
+			doc.Authors = appendAuthor(doc.Authors, creator)
+		}
+
+		if pages, err := getFirstValue(meta, "xmpTPg:NPages"); err == nil {
+			if n, err := strconv.ParseUint(pages, 10, 64); err == nil && doc.PageCount == 0 {
+				doc.PageCount = n
+			}
+		}
+
 		doc.Location = t.getLocation(meta)
 		doc.Image = t.getImage(meta)
 		doc.Photo = t.getPhoto(meta)
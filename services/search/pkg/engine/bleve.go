@@ -26,11 +26,8 @@ import (
 	"github.com/owncloud/reva/v2/pkg/storagespace"
 	"github.com/owncloud/reva/v2/pkg/utils"
 
-	libregraph "github.com/owncloud/libre-graph-api-go"
-
 	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
 	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
-	"github.com/owncloud/ocis/v2/services/search/pkg/content"
 	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
 	searchQuery "github.com/owncloud/ocis/v2/services/search/pkg/query"
 )
@@ -87,6 +84,7 @@ func BuildBleveMapping() (mapping.IndexMapping, error) {
 	docMapping := bleve.NewDocumentMapping()
 	docMapping.AddFieldMappingsAt("Name", nameMapping)
 	docMapping.AddFieldMappingsAt("Tags", lowercaseMapping)
+	docMapping.AddFieldMappingsAt("Authors", lowercaseMapping)
 	docMapping.AddFieldMappingsAt("Content", fulltextFieldMapping)
 
 	// Add explicit Photo field mappings to ensure fields are stored
@@ -231,44 +229,11 @@ func (b *Bleve) Search(ctx context.Context, sir *searchService.SearchIndexReques
 			}
 		}
 
-		rootID, err := storagespace.ParseID(getFieldValue[string](hit.Fields, "RootID"))
-		if err != nil {
-			return nil, err
-		}
-
-		rID, err := storagespace.ParseID(getFieldValue[string](hit.Fields, "ID"))
+		match, err := newMatch(hit.Fields, float32(hit.Score), getFragmentValue(hit.Fragments, "Content", 0))
 		if err != nil {
 			return nil, err
 		}
 
-		pID, _ := storagespace.ParseID(getFieldValue[string](hit.Fields, "ParentID"))
-		match := &searchMessage.Match{
-			Score: float32(hit.Score),
-			Entity: &searchMessage.Entity{
-				Ref: &searchMessage.Reference{
-					ResourceId: resourceIDtoSearchID(rootID),
-					Path:       getFieldValue[string](hit.Fields, "Path"),
-				},
-				Id:         resourceIDtoSearchID(rID),
-				Name:       getFieldValue[string](hit.Fields, "Name"),
-				ParentId:   resourceIDtoSearchID(pID),
-				Size:       uint64(getFieldValue[float64](hit.Fields, "Size")),
-				Type:       uint64(getFieldValue[float64](hit.Fields, "Type")),
-				MimeType:   getFieldValue[string](hit.Fields, "MimeType"),
-				Deleted:    getFieldValue[bool](hit.Fields, "Deleted"),
-				Tags:       getFieldSliceValue[string](hit.Fields, "Tags"),
-				Highlights: getFragmentValue(hit.Fragments, "Content", 0),
-				Audio:      getAudioValue[searchMessage.Audio](hit.Fields),
-				Image:      getImageValue[searchMessage.Image](hit.Fields),
-				Location:   getLocationValue[searchMessage.GeoCoordinates](hit.Fields),
-				Photo:      getPhotoValue[searchMessage.Photo](hit.Fields),
-			},
-		}
-
-		if mtime, err := time.Parse(time.RFC3339, getFieldValue[string](hit.Fields, "Mtime")); err == nil {
-			match.Entity.LastModifiedTime = &timestamppb.Timestamp{Seconds: mtime.Unix(), Nanos: int32(mtime.Nanosecond())}
-		}
-
 		matches = append(matches, match)
 	}
 
@@ -454,6 +419,45 @@ func (b *Bleve) DocCount() (uint64, error) {
 	return bleveIndex.DocCount()
 }
 
+// Walk calls fn with all resources of the index in batches of the given size, including the
+// resources marked as deleted. The resources are ordered by their id.
+func (b *Bleve) Walk(ctx context.Context, batchSize int, fn func([]*Resource) error) error {
+	bleveIndex, closeFn, err := b.indexGetter.GetIndex(bleveEngine.ReadOnly(true))
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	var searchAfter []string
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		req := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), batchSize, 0, false)
+		req.Fields = []string{"*"}
+		req.SortBy([]string{"_id"})
+		req.SearchAfter = searchAfter
+		res, err := bleveIndex.Search(req)
+		if err != nil {
+			return err
+		}
+		if len(res.Hits) == 0 {
+			return nil
+		}
+
+		resources := make([]*Resource, 0, len(res.Hits))
+		for _, hit := range res.Hits {
+			resources = append(resources, newResource(hit.Fields))
+		}
+		if err := fn(resources); err != nil {
+			return err
+		}
+
+		searchAfter = []string{res.Hits[len(res.Hits)-1].ID}
+	}
+}
+
 func (b *Bleve) getResource(bleveIndex bleve.Index, id string) (*Resource, error) {
 	req := bleve.NewSearchRequest(bleve.NewDocIDQuery([]string{id}))
 	req.Fields = []string{"*"}
@@ -465,30 +469,7 @@ func (b *Bleve) getResource(bleveIndex bleve.Index, id string) (*Resource, error
 		return nil, ErrResourceNotFound
 	}
 
-	fields := res.Hits[0].Fields
-
-	return &Resource{
-		ID:        getFieldValue[string](fields, "ID"),
-		RootID:    getFieldValue[string](fields, "RootID"),
-		Path:      getFieldValue[string](fields, "Path"),
-		ParentID:  getFieldValue[string](fields, "ParentID"),
-		Type:      uint64(getFieldValue[float64](fields, "Type")),
-		Deleted:   getFieldValue[bool](fields, "Deleted"),
-		Extracted: getFieldValue[bool](fields, "Extracted"),
-		Document: content.Document{
-			Name:     getFieldValue[string](fields, "Name"),
-			Title:    getFieldValue[string](fields, "Title"),
-			Size:     uint64(getFieldValue[float64](fields, "Size")),
-			Mtime:    getFieldValue[string](fields, "Mtime"),
-			MimeType: getFieldValue[string](fields, "MimeType"),
-			Content:  getFieldValue[string](fields, "Content"),
-			Tags:     getFieldSliceValue[string](fields, "Tags"),
-			Audio:    getAudioValue[libregraph.Audio](fields),
-			Image:    getImageValue[libregraph.Image](fields),
-			Location: getLocationValue[libregraph.GeoCoordinates](fields),
-			Photo:    getPhotoValue[libregraph.Photo](fields),
-		},
-	}, nil
+	return newResource(res.Hits[0].Fields), nil
 }
 
 func newPointerOfType[T any]() *T {
@@ -2,20 +2,32 @@ package engine
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"regexp"
+	"time"
 
 	"github.com/blevesearch/bleve/v2/search"
 	storageProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+
+	libregraph "github.com/owncloud/libre-graph-api-go"
 
 	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
 	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
-	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
+	openSearchEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/opensearch"
 	"github.com/owncloud/ocis/v2/services/search/pkg/query/bleve"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/opensearch"
 )
 
 // ErrResourceNotFound is returned when a resource is not present in the index.
@@ -58,11 +70,53 @@ func NewEngineFromConfig(cfg *config.Config) (Engine, io.Closer, error) {
 		eng := NewBleveEngine(indexGetter, bleve.DefaultCreator)
 		return eng, eng, nil
 
+	case "open-search":
+		httpClient, err := openSearchHTTPClient(cfg.Engine.OpenSearch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client, err := openSearchEngine.NewClient(
+			openSearchEngine.Addresses(cfg.Engine.OpenSearch.Addresses...),
+			openSearchEngine.Index(cfg.Engine.OpenSearch.Index),
+			openSearchEngine.BasicAuth(cfg.Engine.OpenSearch.Username, cfg.Engine.OpenSearch.Password),
+			openSearchEngine.Mapping(BuildOpenSearchMapping()),
+			openSearchEngine.HTTPClient(httpClient),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return NewOpenSearchEngine(client, opensearch.DefaultCreator), client, nil
+
 	default:
 		return nil, nil, fmt.Errorf("unknown search engine: %s", cfg.Engine.Type)
 	}
 }
 
+func openSearchHTTPClient(cfg config.EngineOpenSearch) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure, //nolint:gosec
+	}
+	if cfg.TLSRootCACertificate != "" {
+		rootCrtFile, err := os.ReadFile(cfg.TLSRootCACertificate)
+		if err != nil {
+			return nil, fmt.Errorf("could not read root ca certificate: %w", err)
+		}
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(rootCrtFile) {
+			return nil, fmt.Errorf("could not parse root ca certificate '%s'", cfg.TLSRootCACertificate)
+		}
+		tlsConfig.RootCAs = certPool
+		tlsConfig.InsecureSkipVerify = false
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: time.Minute}, nil
+}
+
 // Resource is the entity that is stored in the index.
 type Resource struct {
 	content.Document
@@ -77,7 +131,80 @@ type Resource struct {
 	Extracted bool
 }
 
-func resourceIDtoSearchID(id storageProvider.ResourceId) *searchMessage.ResourceID {
+// newResource creates a Resource from the flattened fields of an index document,
+// nested fields like 'photo.iso' are separated by dots.
+func newResource(fields map[string]interface{}) *Resource {
+	return &Resource{
+		ID:        getFieldValue[string](fields, "ID"),
+		RootID:    getFieldValue[string](fields, "RootID"),
+		Path:      getFieldValue[string](fields, "Path"),
+		ParentID:  getFieldValue[string](fields, "ParentID"),
+		Type:      uint64(getFieldValue[float64](fields, "Type")),
+		Deleted:   getFieldValue[bool](fields, "Deleted"),
+		Hidden:    getFieldValue[bool](fields, "Hidden"),
+		Extracted: getFieldValue[bool](fields, "Extracted"),
+		Document: content.Document{
+			Name:      getFieldValue[string](fields, "Name"),
+			Title:     getFieldValue[string](fields, "Title"),
+			Size:      uint64(getFieldValue[float64](fields, "Size")),
+			Mtime:     getFieldValue[string](fields, "Mtime"),
+			MimeType:  getFieldValue[string](fields, "MimeType"),
+			Content:   getFieldValue[string](fields, "Content"),
+			Tags:      getFieldSliceValue[string](fields, "Tags"),
+			Authors:   getFieldSliceValue[string](fields, "Authors"),
+			PageCount: uint64(getFieldValue[float64](fields, "PageCount")),
+			Audio:     getAudioValue[libregraph.Audio](fields),
+			Image:     getImageValue[libregraph.Image](fields),
+			Location:  getLocationValue[libregraph.GeoCoordinates](fields),
+			Photo:     getPhotoValue[libregraph.Photo](fields),
+		},
+	}
+}
+
+// newMatch creates a search match from the flattened fields of an index document.
+func newMatch(fields map[string]interface{}, score float32, highlights string) (*searchMessage.Match, error) {
+	rootID, err := storagespace.ParseID(getFieldValue[string](fields, "RootID"))
+	if err != nil {
+		return nil, err
+	}
+
+	rID, err := storagespace.ParseID(getFieldValue[string](fields, "ID"))
+	if err != nil {
+		return nil, err
+	}
+
+	pID, _ := storagespace.ParseID(getFieldValue[string](fields, "ParentID"))
+	match := &searchMessage.Match{
+		Score: score,
+		Entity: &searchMessage.Entity{
+			Ref: &searchMessage.Reference{
+				ResourceId: resourceIDtoSearchID(&rootID),
+				Path:       getFieldValue[string](fields, "Path"),
+			},
+			Id:         resourceIDtoSearchID(&rID),
+			Name:       getFieldValue[string](fields, "Name"),
+			ParentId:   resourceIDtoSearchID(&pID),
+			Size:       uint64(getFieldValue[float64](fields, "Size")),
+			Type:       uint64(getFieldValue[float64](fields, "Type")),
+			MimeType:   getFieldValue[string](fields, "MimeType"),
+			Deleted:    getFieldValue[bool](fields, "Deleted"),
+			Tags:       getFieldSliceValue[string](fields, "Tags"),
+			Highlights: highlights,
+			Audio:      getAudioValue[searchMessage.Audio](fields),
+			Image:      getImageValue[searchMessage.Image](fields),
+			Location:   getLocationValue[searchMessage.GeoCoordinates](fields),
+			Photo:      getPhotoValue[searchMessage.Photo](fields),
+		},
+	}
+
+	if mtime, err := time.Parse(time.RFC3339, getFieldValue[string](fields, "Mtime")); err == nil {
+		match.Entity.LastModifiedTime = &timestamppb.Timestamp{Seconds: mtime.Unix(), Nanos: int32(mtime.Nanosecond())}
+	}
+
+	return match, nil
+}
+
+func resourceIDtoSearchID(id *storageProvider.ResourceId) *searchMessage.ResourceID {
 	return &searchMessage.ResourceID{
 		StorageId: id.GetStorageId(),
 		SpaceId:   id.GetSpaceId(),
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"context"
+)
+
+// batchUpserter is implemented by engines which can store multiple resources at once
+type batchUpserter interface {
+	UpsertBatch(resources []*Resource) error
+}
+
+// Migrate copies all resources of a bleve index into another engine, including the resources
+// marked as deleted. Existing resources in the target engine are replaced. The progress function
+// is called after every batch with the number of resources migrated so far.
+func Migrate(ctx context.Context, src *Bleve, dst Engine, batchSize int, progress func(migrated uint64)) (uint64, error) {
+	var migrated uint64
+	err := src.Walk(ctx, batchSize, func(resources []*Resource) error {
+		if bu, ok := dst.(batchUpserter); ok {
+			if err := bu.UpsertBatch(resources); err != nil {
+				return err
+			}
+		} else {
+			for _, r := range resources {
+				if err := dst.Upsert(r.ID, *r); err != nil {
+					return err
+				}
+			}
+		}
+
+		migrated += uint64(len(resources))
+		if progress != nil {
+			progress(migrated)
+		}
+		return nil
+	})
+	return migrated, err
+}
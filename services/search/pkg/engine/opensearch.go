@@ -0,0 +1,414 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"strings"
+
+	storageProvider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/errtypes"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+
+	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	openSearchEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/opensearch"
+	searchQuery "github.com/owncloud/ocis/v2/services/search/pkg/query"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/opensearch"
+)
+
+// openSearchMaxResults is the default max_result_window of OpenSearch and Elasticsearch,
+// more results can not be returned by a single search request.
+const openSearchMaxResults = 10000
+
+// OpenSearch represents a search engine which utilizes an OpenSearch or Elasticsearch
+// compatible server to search and store resources.
+type OpenSearch struct {
+	client       *openSearchEngine.Client
+	queryCreator searchQuery.Creator[opensearch.Query]
+}
+
+// NewOpenSearchEngine creates a new OpenSearch instance
+func NewOpenSearchEngine(client *openSearchEngine.Client, queryCreator searchQuery.Creator[opensearch.Query]) *OpenSearch {
+	return &OpenSearch{
+		client:       client,
+		queryCreator: queryCreator,
+	}
+}
+
+// BuildOpenSearchMapping builds the settings and mappings of the OpenSearch index.
+// The analysis of the fields matches the bleve mapping, see BuildBleveMapping.
+func BuildOpenSearchMapping() map[string]interface{} {
+	keyword := map[string]interface{}{"type": "keyword"}
+	lowercaseKeyword := map[string]interface{}{"type": "keyword", "normalizer": lowercaseKeywordAnalyzer}
+	date := map[string]interface{}{"type": "date", "ignore_malformed": true}
+	double := map[string]interface{}{"type": "double"}
+
+	return map[string]interface{}{
+		"settings": map[string]interface{}{
+			"analysis": map[string]interface{}{
+				"normalizer": map[string]interface{}{
+					lowercaseKeywordAnalyzer: map[string]interface{}{
+						"type":   "custom",
+						"filter": []string{"lowercase"},
+					},
+				},
+				"analyzer": map[string]interface{}{
+					"fulltext": map[string]interface{}{
+						"type":      "custom",
+						"tokenizer": "standard",
+						"filter":    []string{"lowercase", "porter_stem"},
+					},
+				},
+			},
+		},
+		"mappings": map[string]interface{}{
+			"properties": map[string]interface{}{
+				"ID":        keyword,
+				"RootID":    keyword,
+				"ParentID":  keyword,
+				"Path":      keyword,
+				"Name":      lowercaseKeyword,
+				"Tags":      lowercaseKeyword,
+				"MimeType":  lowercaseKeyword,
+				"Authors":   lowercaseKeyword,
+				"PageCount": map[string]interface{}{"type": "long"},
+				"Title":     map[string]interface{}{"type": "text"},
+				"Content":   map[string]interface{}{"type": "text", "analyzer": "fulltext"},
+				"Size":      map[string]interface{}{"type": "long"},
+				"Type":      map[string]interface{}{"type": "long"},
+				"Mtime":     date,
+				"Deleted":   map[string]interface{}{"type": "boolean"},
+				"Hidden":    map[string]interface{}{"type": "boolean"},
+				"Extracted": map[string]interface{}{"type": "boolean"},
+				"photo": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"cameraMake":          lowercaseKeyword,
+						"cameraModel":         lowercaseKeyword,
+						"exposureDenominator": double,
+						"exposureNumerator":   double,
+						"fNumber":             double,
+						"focalLength":         double,
+						"iso":                 double,
+						"orientation":         double,
+						"takenDateTime":       date,
+					},
+				},
+				"location": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"latitude":  double,
+						"longitude": double,
+						"altitude":  double,
+					},
+				},
+			},
+		},
+	}
+}
+
+// Search executes a search request operation within the index.
+// Returns a SearchIndexResponse object or an error.
+func (o *OpenSearch) Search(ctx context.Context, sir *searchService.SearchIndexRequest) (*searchService.SearchIndexResponse, error) {
+	createdQuery, err := o.queryCreator.Create(sir.Query)
+	if err != nil {
+		if searchQuery.IsValidationError(err) {
+			return nil, errtypes.BadRequest(err.Error())
+		}
+		return nil, err
+	}
+
+	conjuncts := []opensearch.Query{
+		// Skip documents that have been marked as deleted
+		opensearch.Term("Deleted", false),
+		createdQuery,
+	}
+
+	if sir.Ref != nil {
+		rootIDValue := storagespace.FormatResourceID(
+			&storageProvider.ResourceId{
+				StorageId: sir.Ref.GetResourceId().GetStorageId(),
+				SpaceId:   sir.Ref.GetResourceId().GetSpaceId(),
+				OpaqueId:  sir.Ref.GetResourceId().GetOpaqueId(),
+			},
+		)
+		conjuncts = append(conjuncts, opensearch.Term("RootID", rootIDValue))
+
+		if requestedPath := utils.MakeRelativePath(sir.Ref.Path); requestedPath != "." {
+			conjuncts = append(conjuncts, opensearch.Or(
+				opensearch.Term("Path", requestedPath),
+				opensearch.Prefix("Path", requestedPath+"/"),
+			))
+		}
+	}
+
+	size := int(sir.PageSize)
+	switch {
+	case sir.PageSize == -1:
+		size = openSearchMaxResults
+	case sir.PageSize == 0:
+		size = 200
+	}
+
+	res, err := o.client.Search(ctx, map[string]interface{}{
+		"query":            opensearch.And(conjuncts...),
+		"size":             size,
+		"track_total_hits": true,
+		"highlight": map[string]interface{}{
+			"pre_tags":  []string{"<mark>"},
+			"post_tags": []string{"</mark>"},
+			"fields":    map[string]interface{}{"Content": map[string]interface{}{}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*searchMessage.Match, 0, len(res.Hits.Hits))
+	for _, hit := range res.Hits.Hits {
+		fields, err := flattenSource(hit.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		var highlights string
+		if h := hit.Highlight["Content"]; len(h) > 0 {
+			highlights = h[0]
+		}
+
+		match, err := newMatch(fields, float32(hit.Score), highlights)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, match)
+	}
+
+	return &searchService.SearchIndexResponse{
+		Matches:      matches,
+		TotalMatches: int32(res.Hits.Total.Value),
+	}, nil
+}
+
+// Upsert indexes or stores Resource data fields.
+func (o *OpenSearch) Upsert(id string, r Resource) error {
+	return o.client.Index(context.Background(), id, r)
+}
+
+// UpsertBatch indexes or stores multiple resources in a single request.
+func (o *OpenSearch) UpsertBatch(resources []*Resource) error {
+	ops := make([]openSearchEngine.BulkOperation, 0, len(resources))
+	for _, r := range resources {
+		ops = append(ops, openSearchEngine.BulkOperation{ID: r.ID, Document: r})
+	}
+	return o.client.Bulk(context.Background(), ops)
+}
+
+// Update retrieves an existing resource from the index, applies the given
+// mutation function, and writes it back.
+//
+// NOTE: this operation is not atomic, see Bleve.Update.
+//
+// Returns ErrResourceNotFound if the resource is not in the index.
+func (o *OpenSearch) Update(id string, mutateFn func(*Resource)) error {
+	_, err := o.updateEntity(context.Background(), id, mutateFn)
+	return err
+}
+
+// Lookup retrieves a resource by its document ID.
+// Returns ErrResourceNotFound if the resource is not in the index.
+func (o *OpenSearch) Lookup(id string) (*Resource, error) {
+	return o.getResource(context.Background(), id)
+}
+
+// Move updates the resource location and all of its necessary fields.
+func (o *OpenSearch) Move(id string, parentid string, target string) error {
+	ctx := context.Background()
+	r, err := o.getResource(ctx, id)
+	if err != nil {
+		return err
+	}
+	currentPath := r.Path
+	nextPath := utils.MakeRelativePath(target)
+
+	r, err = o.updateEntity(ctx, id, func(r *Resource) {
+		r.Path = nextPath
+		r.Name = path.Base(nextPath)
+		r.ParentID = parentid
+	})
+	if err != nil {
+		return err
+	}
+
+	if r.Type == uint64(storageProvider.ResourceType_RESOURCE_TYPE_CONTAINER) {
+		return o.updateChildren(ctx, r.RootID, currentPath, func(r *Resource) {
+			r.Path = strings.Replace(r.Path, currentPath, nextPath, 1)
+		})
+	}
+
+	return nil
+}
+
+// Delete marks the resource as deleted.
+// The resource object will stay in the index,
+// instead of removing the resource it just marks it as deleted!
+// can be undone
+func (o *OpenSearch) Delete(id string) error {
+	return o.setDeleted(context.Background(), id, true)
+}
+
+// Restore is the counterpart to Delete.
+// It restores the resource which makes it available again.
+func (o *OpenSearch) Restore(id string) error {
+	return o.setDeleted(context.Background(), id, false)
+}
+
+// Purge removes a resource from the index, irreversible operation.
+func (o *OpenSearch) Purge(id string) error {
+	return o.client.Delete(context.Background(), id)
+}
+
+// Optimize triggers a force merge of the index segments into a single
+// segment. This is an expensive I/O operation on the search servers and
+// should be called during low-usage periods (e.g., after bulk indexing).
+func (o *OpenSearch) Optimize(ctx context.Context) error {
+	return o.client.ForceMerge(ctx)
+}
+
+// DocCount returns the number of resources in the index.
+func (o *OpenSearch) DocCount() (uint64, error) {
+	return o.client.Count(context.Background())
+}
+
+func (o *OpenSearch) getResource(ctx context.Context, id string) (*Resource, error) {
+	var source json.RawMessage
+	err := o.client.Get(ctx, id, &source)
+	switch {
+	case errors.Is(err, openSearchEngine.ErrNotFound):
+		return nil, ErrResourceNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	fields, err := flattenSource(source)
+	if err != nil {
+		return nil, err
+	}
+	return newResource(fields), nil
+}
+
+func (o *OpenSearch) updateEntity(ctx context.Context, id string, mutateFunc func(r *Resource)) (*Resource, error) {
+	it, err := o.getResource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mutateFunc(it)
+
+	return it, o.client.Index(ctx, it.ID, it)
+}
+
+func (o *OpenSearch) setDeleted(ctx context.Context, id string, deleted bool) error {
+	it, err := o.updateEntity(ctx, id, func(r *Resource) {
+		r.Deleted = deleted
+	})
+	if err != nil {
+		return err
+	}
+
+	if it.Type == uint64(storageProvider.ResourceType_RESOURCE_TYPE_CONTAINER) {
+		return o.updateChildren(ctx, it.RootID, it.Path, func(r *Resource) {
+			r.Deleted = deleted
+		})
+	}
+
+	return nil
+}
+
+// updateChildren applies the mutation to all resources below the given path. All children are
+// collected before they are written back, the mutation may change the fields the children are found by.
+func (o *OpenSearch) updateChildren(ctx context.Context, rootID, parentPath string, mutateFunc func(r *Resource)) error {
+	// make recently indexed children visible to the search
+	if err := o.client.Refresh(ctx); err != nil {
+		return err
+	}
+
+	q := opensearch.And(
+		opensearch.Term("RootID", rootID),
+		opensearch.Prefix("Path", parentPath+"/"),
+	)
+
+	var (
+		children    []*Resource
+		searchAfter []interface{}
+	)
+	for {
+		req := map[string]interface{}{
+			"query": q,
+			"size":  openSearchMaxResults,
+			"sort":  []map[string]interface{}{{"ID": "asc"}},
+		}
+		if searchAfter != nil {
+			req["search_after"] = searchAfter
+		}
+
+		res, err := o.client.Search(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range res.Hits.Hits {
+			fields, err := flattenSource(hit.Source)
+			if err != nil {
+				return err
+			}
+			r := newResource(fields)
+			mutateFunc(r)
+			children = append(children, r)
+		}
+
+		if len(res.Hits.Hits) < openSearchMaxResults {
+			break
+		}
+		searchAfter = res.Hits.Hits[len(res.Hits.Hits)-1].Sort
+	}
+
+	for len(children) > 0 {
+		n := min(len(children), 1000)
+		ops := make([]openSearchEngine.BulkOperation, 0, n)
+		for _, r := range children[:n] {
+			ops = append(ops, openSearchEngine.BulkOperation{ID: r.ID, Document: r})
+		}
+		if err := o.client.Bulk(ctx, ops); err != nil {
+			return err
+		}
+		children = children[n:]
+	}
+
+	return nil
+}
+
+// flattenSource decodes the source of an index document into the flat field map
+// the bleve engine returns, nested fields like 'photo.iso' are separated by dots.
+func flattenSource(source json.RawMessage) (map[string]interface{}, error) {
+	nested := map[string]interface{}{}
+	if err := json.Unmarshal(source, &nested); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{}, len(nested))
+	var flatten func(prefix string, m map[string]interface{})
+	flatten = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if sub, ok := v.(map[string]interface{}); ok {
+				flatten(prefix+k+".", sub)
+				continue
+			}
+			fields[prefix+k] = v
+		}
+	}
+	flatten("", nested)
+
+	return fields, nil
+}
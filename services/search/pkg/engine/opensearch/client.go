@@ -0,0 +1,283 @@
+// Package opensearch provides a minimal client for the REST API of OpenSearch and Elasticsearch compatible servers.
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned when a document does not exist.
+var ErrNotFound = errors.New("document not found")
+
+// Client talks to the REST API of an OpenSearch or Elasticsearch compatible server. All operations
+// work on a single index, which is created on first use if it does not exist.
+type Client struct {
+	options Options
+
+	mu         sync.Mutex
+	indexReady bool
+}
+
+// Hit is a single document returned by a search.
+type Hit struct {
+	ID        string              `json:"_id"`
+	Score     float64             `json:"_score"`
+	Source    json.RawMessage     `json:"_source"`
+	Sort      []interface{}       `json:"sort,omitempty"`
+	Highlight map[string][]string `json:"highlight,omitempty"`
+}
+
+// SearchResponse is the response of a search.
+type SearchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []Hit `json:"hits"`
+	} `json:"hits"`
+}
+
+// BulkOperation is a single operation of a bulk request. A nil document deletes the document with the given id.
+type BulkOperation struct {
+	ID       string
+	Document interface{}
+}
+
+// ResponseError is returned when the server answers with an unexpected status code.
+type ResponseError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e ResponseError) Error() string {
+	return fmt.Sprintf("unexpected response from search server: %d %s", e.StatusCode, e.Body)
+}
+
+// NewClient creates a new Client.
+func NewClient(opts ...Option) (*Client, error) {
+	o := newOptions(opts...)
+	if len(o.Addresses) == 0 {
+		return nil, errors.New("no search server addresses configured")
+	}
+	if o.Index == "" {
+		return nil, errors.New("no index configured")
+	}
+
+	return &Client{options: o}, nil
+}
+
+// Index stores a document, an existing document with the same id is replaced.
+func (c *Client) Index(ctx context.Context, id string, doc interface{}) error {
+	return c.do(ctx, http.MethodPut, "/_doc/"+url.PathEscape(id), doc, nil)
+}
+
+// Get reads a document into out. It returns ErrNotFound if the document does not exist.
+func (c *Client) Get(ctx context.Context, id string, out interface{}) error {
+	var res struct {
+		Found  bool            `json:"found"`
+		Source json.RawMessage `json:"_source"`
+	}
+	err := c.do(ctx, http.MethodGet, "/_doc/"+url.PathEscape(id), nil, &res)
+	switch {
+	case isStatus(err, http.StatusNotFound):
+		return ErrNotFound
+	case err != nil:
+		return err
+	case !res.Found:
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(res.Source, out)
+}
+
+// Delete removes a document, removing a document which does not exist is not an error.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	err := c.do(ctx, http.MethodDelete, "/_doc/"+url.PathEscape(id), nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Search runs a search request, the request is the body of the search api.
+func (c *Client) Search(ctx context.Context, req map[string]interface{}) (*SearchResponse, error) {
+	res := &SearchResponse{}
+	if err := c.do(ctx, http.MethodPost, "/_search", req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Count returns the number of documents in the index.
+func (c *Client) Count(ctx context.Context) (uint64, error) {
+	var res struct {
+		Count uint64 `json:"count"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_count", nil, &res); err != nil {
+		return 0, err
+	}
+	return res.Count, nil
+}
+
+// Refresh makes all changes visible to searches.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/_refresh", nil, nil)
+}
+
+// ForceMerge merges the segments of the index into a single segment.
+func (c *Client) ForceMerge(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/_forcemerge?max_num_segments=1", nil, nil)
+}
+
+// Bulk runs multiple index and delete operations in a single request.
+func (c *Client) Bulk(ctx context.Context, ops []BulkOperation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+
+	body := &bytes.Buffer{}
+	enc := json.NewEncoder(body)
+	for _, op := range ops {
+		action := "index"
+		if op.Document == nil {
+			action = "delete"
+		}
+		if err := enc.Encode(map[string]interface{}{action: map[string]string{"_id": op.ID}}); err != nil {
+			return err
+		}
+		if op.Document != nil {
+			if err := enc.Encode(op.Document); err != nil {
+				return err
+			}
+		}
+	}
+
+	var res struct {
+		Errors bool                                `json:"errors"`
+		Items  []map[string]map[string]interface{} `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/_bulk", body, &res); err != nil {
+		return err
+	}
+	if !res.Errors {
+		return nil
+	}
+
+	for _, item := range res.Items {
+		for action, r := range item {
+			if r["error"] != nil && !(action == "delete" && r["status"] == float64(http.StatusNotFound)) {
+				return fmt.Errorf("bulk %s of '%v' failed: %v", action, r["_id"], r["error"])
+			}
+		}
+	}
+	return nil
+}
+
+// Close releases the idle connections to the search servers.
+func (c *Client) Close() error {
+	c.options.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// ensureIndex creates the index with the configured mapping if it does not exist yet
+func (c *Client) ensureIndex(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.indexReady {
+		return nil
+	}
+
+	err := c.request(ctx, http.MethodHead, "", nil, nil)
+	switch {
+	case isStatus(err, http.StatusNotFound):
+		err = c.request(ctx, http.MethodPut, "", c.options.Mapping, nil)
+		var rErr ResponseError
+		if errors.As(err, &rErr) && strings.Contains(rErr.Body, "resource_already_exists_exception") {
+			// created concurrently by another instance
+			err = nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not create index '%s': %w", c.options.Index, err)
+		}
+	case err != nil:
+		return err
+	}
+
+	c.indexReady = true
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	if err := c.ensureIndex(ctx); err != nil {
+		return err
+	}
+	return c.request(ctx, method, path, body, out)
+}
+
+// request sends a request to the index. The servers are tried in order until one can be reached.
+func (c *Client) request(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var payload []byte
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case *bytes.Buffer:
+		payload = b.Bytes()
+		contentType = "application/x-ndjson"
+	default:
+		var err error
+		if payload, err = json.Marshal(b); err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+	for _, address := range c.options.Addresses {
+		req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(address, "/")+"/"+url.PathEscape(c.options.Index)+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if c.options.Username != "" {
+			req.SetBasicAuth(c.options.Username, c.options.Password)
+		}
+
+		res, err := c.options.HTTPClient.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		err = handleResponse(res, out)
+		res.Body.Close()
+		return err
+	}
+
+	return fmt.Errorf("no search server reachable: %w", errors.Join(errs...))
+}
+
+func handleResponse(res *http.Response, out interface{}) error {
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return ResponseError{StatusCode: res.StatusCode, Body: string(b)}
+	}
+	if out == nil {
+		_, err := io.Copy(io.Discard, res.Body)
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func isStatus(err error, status int) bool {
+	var rErr ResponseError
+	return errors.As(err, &rErr) && rErr.StatusCode == status
+}
@@ -0,0 +1,556 @@
+// Package opensearchtest provides an in-process stand-in for an OpenSearch server, for use in tests.
+//
+// The stand-in implements the subset of the REST API and of the query DSL which is used by the
+// OpenSearch search engine: documents, search with term, terms, prefix, wildcard, match, range
+// and bool queries, sorting with search_after, counting and bulk requests. Text fields are split
+// into lowercase words, there is no stemming and no scoring, every hit has a score of 1.
+package opensearchtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Server is an in-process stand-in for an OpenSearch server.
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	indices map[string]*index
+}
+
+type index struct {
+	fields map[string]fieldMapping
+	docs   map[string]map[string]interface{}
+}
+
+type fieldMapping struct {
+	Type       string `json:"type"`
+	Normalizer string `json:"normalizer"`
+
+	// lowercase is set if the normalizer of the field lowercases the values
+	lowercase bool
+}
+
+// NewServer starts a new stand-in server. It must be closed by the caller.
+func NewServer() *Server {
+	s := &Server{indices: map[string]*index{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Documents returns the ids of all documents of an index.
+func (s *Server) Documents(name string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	if idx, ok := s.indices[name]; ok {
+		for id := range idx.docs {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	for i := range segments {
+		segments[i], _ = url.PathUnescape(segments[i])
+	}
+
+	name := segments[0]
+	idx, exists := s.indices[name]
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodHead:
+			if !exists {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			if exists {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": map[string]string{"type": "resource_already_exists_exception"}})
+				return
+			}
+			idx, err := newIndex(r)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+				return
+			}
+			s.indices[name] = idx
+			writeJSON(w, http.StatusOK, map[string]interface{}{"acknowledged": true})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": map[string]string{"type": "index_not_found_exception"}})
+		return
+	}
+
+	switch {
+	case segments[1] == "_doc" && len(segments) == 3:
+		idx.serveDocument(w, r, segments[2])
+	case segments[1] == "_search":
+		idx.serveSearch(w, r)
+	case segments[1] == "_count":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"count": len(idx.docs)})
+	case segments[1] == "_bulk":
+		idx.serveBulk(w, r)
+	case segments[1] == "_refresh", segments[1] == "_forcemerge":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_shards": map[string]int{"failed": 0}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newIndex(r *http.Request) (*index, error) {
+	var body struct {
+		Settings struct {
+			Analysis struct {
+				Normalizer map[string]struct {
+					Filter []string `json:"filter"`
+				} `json:"normalizer"`
+			} `json:"analysis"`
+		} `json:"settings"`
+		Mappings struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"mappings"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+	}
+
+	idx := &index{fields: map[string]fieldMapping{}, docs: map[string]map[string]interface{}{}}
+	var add func(prefix string, properties map[string]json.RawMessage) error
+	add = func(prefix string, properties map[string]json.RawMessage) error {
+		for name, raw := range properties {
+			var m struct {
+				fieldMapping
+				Properties map[string]json.RawMessage `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &m); err != nil {
+				return err
+			}
+			if len(m.Properties) > 0 {
+				if err := add(prefix+name+".", m.Properties); err != nil {
+					return err
+				}
+				continue
+			}
+			if n, ok := body.Settings.Analysis.Normalizer[m.Normalizer]; ok {
+				m.lowercase = slices.Contains(n.Filter, "lowercase")
+			} else {
+				m.lowercase = m.Normalizer == "lowercase"
+			}
+			idx.fields[prefix+name] = m.fieldMapping
+		}
+		return nil
+	}
+	return idx, add("", body.Mappings.Properties)
+}
+
+func (idx *index) serveDocument(w http.ResponseWriter, r *http.Request, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		doc, ok := idx.docs[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"_id": id, "found": false})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_id": id, "found": true, "_source": doc})
+	case http.MethodPut, http.MethodPost:
+		doc := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		idx.docs[id] = doc
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_id": id, "result": "updated"})
+	case http.MethodDelete:
+		if _, ok := idx.docs[id]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]interface{}{"_id": id, "result": "not_found"})
+			return
+		}
+		delete(idx.docs, id)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"_id": id, "result": "deleted"})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (idx *index) serveBulk(w http.ResponseWriter, r *http.Request) {
+	var items []map[string]interface{}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var action map[string]struct {
+			ID string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+
+		for name, meta := range action {
+			switch name {
+			case "index":
+				doc := map[string]interface{}{}
+				if !scanner.Scan() || json.Unmarshal(scanner.Bytes(), &doc) != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "invalid bulk document"})
+					return
+				}
+				idx.docs[meta.ID] = doc
+				items = append(items, map[string]interface{}{name: map[string]interface{}{"_id": meta.ID, "status": http.StatusOK}})
+			case "delete":
+				status := http.StatusOK
+				if _, ok := idx.docs[meta.ID]; !ok {
+					status = http.StatusNotFound
+				}
+				delete(idx.docs, meta.ID)
+				items = append(items, map[string]interface{}{name: map[string]interface{}{"_id": meta.ID, "status": status}})
+			default:
+				writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": "unsupported bulk action " + name})
+				return
+			}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"errors": false, "items": items})
+}
+
+func (idx *index) serveSearch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query       map[string]interface{}   `json:"query"`
+		Size        *int                     `json:"size"`
+		From        int                      `json:"from"`
+		Sort        []map[string]interface{} `json:"sort"`
+		SearchAfter []interface{}            `json:"search_after"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	var sortField string
+	for _, s := range req.Sort {
+		for field := range s {
+			sortField = field
+		}
+	}
+	if sortField == "" {
+		sortField = "_id"
+	}
+	sortValue := func(id string) string {
+		if sortField == "_id" {
+			return id
+		}
+		return fmt.Sprint(lookup(idx.docs[id], sortField))
+	}
+
+	var ids []string
+	for id, doc := range idx.docs {
+		ok, err := idx.matches(req.Query, doc, id)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error()})
+			return
+		}
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return sortValue(ids[i]) < sortValue(ids[j]) })
+	total := len(ids)
+
+	if len(req.SearchAfter) > 0 {
+		after := fmt.Sprint(req.SearchAfter[0])
+		i := sort.Search(len(ids), func(i int) bool { return sortValue(ids[i]) > after })
+		ids = ids[i:]
+	}
+	if req.From > 0 {
+		ids = ids[min(req.From, len(ids)):]
+	}
+	size := 10
+	if req.Size != nil {
+		size = *req.Size
+	}
+	ids = ids[:min(size, len(ids))]
+
+	hits := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		hits = append(hits, map[string]interface{}{
+			"_id":     id,
+			"_score":  1.0,
+			"_source": idx.docs[id],
+			"sort":    []interface{}{sortValue(id)},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"hits": map[string]interface{}{
+			"total": map[string]interface{}{"value": total, "relation": "eq"},
+			"hits":  hits,
+		},
+	})
+}
+
+// matches evaluates a query of the query DSL against a document
+func (idx *index) matches(q map[string]interface{}, doc map[string]interface{}, id string) (bool, error) {
+	if len(q) == 0 {
+		return true, nil
+	}
+
+	for kind, body := range q {
+		params, _ := body.(map[string]interface{})
+		switch kind {
+		case "match_all":
+			return true, nil
+		case "ids":
+			for _, v := range asSlice(params["values"]) {
+				if v == id {
+					return true, nil
+				}
+			}
+			return false, nil
+		case "bool":
+			return idx.matchesBool(params, doc, id)
+		case "term", "terms", "prefix", "wildcard", "match", "range":
+			for field, arg := range params {
+				return idx.matchesField(kind, field, arg, lookup(doc, field))
+			}
+			return false, nil
+		default:
+			return false, fmt.Errorf("unsupported query '%s'", kind)
+		}
+	}
+	return false, nil
+}
+
+func (idx *index) matchesBool(params map[string]interface{}, doc map[string]interface{}, id string) (bool, error) {
+	for _, clause := range []string{"must", "filter"} {
+		for _, sub := range asSlice(params[clause]) {
+			ok, err := idx.matches(asMap(sub), doc, id)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	for _, sub := range asSlice(params["must_not"]) {
+		ok, err := idx.matches(asMap(sub), doc, id)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+
+	should := asSlice(params["should"])
+	if len(should) == 0 {
+		return true, nil
+	}
+	for _, sub := range should {
+		ok, err := idx.matches(asMap(sub), doc, id)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+func (idx *index) matchesField(kind, field string, arg interface{}, value interface{}) (bool, error) {
+	mapping := idx.fields[field]
+	normalize := func(v interface{}) string {
+		s := fmt.Sprint(v)
+		if f, ok := v.(float64); ok {
+			s = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		if mapping.lowercase {
+			s = strings.ToLower(s)
+		}
+		return s
+	}
+
+	if params, ok := arg.(map[string]interface{}); ok && kind != "range" {
+		arg = params["value"]
+		if kind == "match" {
+			arg = params["query"]
+		}
+	}
+
+	values := asSlice(value)
+	if value != nil && values == nil {
+		values = []interface{}{value}
+	}
+
+	for _, v := range values {
+		switch kind {
+		case "term":
+			if normalize(v) == normalize(arg) {
+				return true, nil
+			}
+		case "terms":
+			for _, a := range asSlice(arg) {
+				if normalize(v) == normalize(a) {
+					return true, nil
+				}
+			}
+		case "prefix":
+			if strings.HasPrefix(normalize(v), normalize(arg)) {
+				return true, nil
+			}
+		case "wildcard":
+			if wildcard(normalize(arg)).MatchString(normalize(v)) {
+				return true, nil
+			}
+		case "match":
+			words := map[string]bool{}
+			for _, w := range tokenize(fmt.Sprint(v)) {
+				words[w] = true
+			}
+			all := true
+			for _, w := range tokenize(fmt.Sprint(arg)) {
+				all = all && words[w]
+			}
+			if all {
+				return true, nil
+			}
+		case "range":
+			ok, err := inRange(mapping, v, asMap(arg))
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
+
+func inRange(mapping fieldMapping, value interface{}, bounds map[string]interface{}) (bool, error) {
+	compare := func(a, b interface{}) (int, error) {
+		if mapping.Type == "date" {
+			ta, err := time.Parse(time.RFC3339Nano, fmt.Sprint(a))
+			if err != nil {
+				return 0, err
+			}
+			tb, err := time.Parse(time.RFC3339Nano, fmt.Sprint(b))
+			if err != nil {
+				return 0, err
+			}
+			return ta.Compare(tb), nil
+		}
+		fa, err := strconv.ParseFloat(fmt.Sprint(a), 64)
+		if err != nil {
+			return 0, err
+		}
+		fb, err := strconv.ParseFloat(fmt.Sprint(b), 64)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case fa < fb:
+			return -1, nil
+		case fa > fb:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	for op, bound := range bounds {
+		c, err := compare(value, bound)
+		if err != nil {
+			return false, nil
+		}
+		var ok bool
+		switch op {
+		case "gt":
+			ok = c > 0
+		case "gte":
+			ok = c >= 0
+		case "lt":
+			ok = c < 0
+		case "lte":
+			ok = c <= 0
+		default:
+			return false, fmt.Errorf("unsupported range bound '%s'", op)
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func wildcard(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// lookup returns the value of a field, nested fields are separated by dots
+func lookup(doc map[string]interface{}, field string) interface{} {
+	var v interface{} = doc
+	for _, part := range strings.Split(field, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,65 @@
+package opensearch
+
+import (
+	"net/http"
+)
+
+// Option is a function that sets some option for the Client.
+type Option func(o *Options)
+
+// Options contains the options for the Client.
+type Options struct {
+	Addresses  []string
+	Index      string
+	Username   string
+	Password   string
+	Mapping    map[string]interface{}
+	HTTPClient *http.Client
+}
+
+// Addresses sets the URLs of the search servers, they are tried in the given order.
+func Addresses(addresses ...string) Option {
+	return func(o *Options) {
+		o.Addresses = addresses
+	}
+}
+
+// Index sets the name of the index.
+func Index(index string) Option {
+	return func(o *Options) {
+		o.Index = index
+	}
+}
+
+// BasicAuth sets the credentials for the basic authentication against the search servers.
+func BasicAuth(username, password string) Option {
+	return func(o *Options) {
+		o.Username = username
+		o.Password = password
+	}
+}
+
+// Mapping sets the settings and mappings the index is created with if it does not exist.
+func Mapping(mapping map[string]interface{}) Option {
+	return func(o *Options) {
+		o.Mapping = mapping
+	}
+}
+
+// HTTPClient sets the http client used to talk to the search servers.
+func HTTPClient(c *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = c
+	}
+}
+
+// newOptions creates new Options with the given options.
+func newOptions(opts ...Option) Options {
+	o := Options{
+		HTTPClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
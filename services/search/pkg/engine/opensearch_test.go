@@ -0,0 +1,238 @@
+package engine_test
+
+import (
+	"context"
+
+	sprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+
+	libregraph "github.com/owncloud/libre-graph-api-go"
+	searchmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
+	openSearchEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/opensearch"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine/opensearch/opensearchtest"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/bleve"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/opensearch"
+)
+
+var _ = Describe("OpenSearch", func() {
+	var (
+		server *opensearchtest.Server
+		eng    *engine.OpenSearch
+
+		doSearch = func(id string, query, path string) (*searchsvc.SearchIndexResponse, error) {
+			rID, err := storagespace.ParseID(id)
+			if err != nil {
+				return nil, err
+			}
+
+			return eng.Search(context.Background(), &searchsvc.SearchIndexRequest{
+				Query: query,
+				Ref: &searchmsg.Reference{
+					ResourceId: &searchmsg.ResourceID{
+						StorageId: rID.StorageId,
+						SpaceId:   rID.SpaceId,
+						OpaqueId:  rID.OpaqueId,
+					},
+					Path: path,
+				},
+			})
+		}
+
+		assertDocCount = func(id string, query string, expectedCount int) []*searchmsg.Match {
+			res, err := doSearch(id, query, "")
+
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, len(res.Matches)).To(Equal(expectedCount), "query returned unexpected number of results: "+query)
+			return res.Matches
+		}
+
+		rootResource   engine.Resource
+		parentResource engine.Resource
+		childResource  engine.Resource
+	)
+
+	BeforeEach(func() {
+		server = opensearchtest.NewServer()
+		DeferCleanup(server.Close)
+
+		client, err := openSearchEngine.NewClient(
+			openSearchEngine.Addresses(server.URL),
+			openSearchEngine.Index("ocis-resources"),
+			openSearchEngine.Mapping(engine.BuildOpenSearchMapping()),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		eng = engine.NewOpenSearchEngine(client, opensearch.DefaultCreator)
+
+		rootResource = engine.Resource{
+			ID:       "1$2!2",
+			RootID:   "1$2!2",
+			Path:     ".",
+			Document: content.Document{},
+		}
+
+		parentResource = engine.Resource{
+			ID:       "1$2!3",
+			ParentID: rootResource.ID,
+			RootID:   rootResource.ID,
+			Path:     "./parent d!r",
+			Type:     uint64(sprovider.ResourceType_RESOURCE_TYPE_CONTAINER),
+			Document: content.Document{Name: "parent d!r", MimeType: "httpd/unix-directory"},
+		}
+
+		childResource = engine.Resource{
+			ID:       "1$2!4",
+			ParentID: parentResource.ID,
+			RootID:   rootResource.ID,
+			Path:     "./parent d!r/child.pdf",
+			Type:     uint64(sprovider.ResourceType_RESOURCE_TYPE_FILE),
+			Document: content.Document{Name: "child.pdf", Size: 12345, Content: "the quick brown fox"},
+		}
+
+		Expect(eng.Upsert(parentResource.ID, parentResource)).To(Succeed())
+		Expect(eng.Upsert(childResource.ID, childResource)).To(Succeed())
+	})
+
+	Describe("Search", func() {
+		It("finds files by name, prefix or substring match", func() {
+			for _, query := range []string{"child.pdf", "child*", "*ld.*", "Name:Child*"} {
+				assertDocCount(rootResource.ID, query, 1)
+			}
+		})
+
+		It("finds files by other fields than filename", func() {
+			assertDocCount(rootResource.ID, "Size:>1000", 1)
+			assertDocCount(rootResource.ID, "Size:>100000", 0)
+			assertDocCount(rootResource.ID, "content:fox", 1)
+			assertDocCount(rootResource.ID, "content:wolf", 0)
+			assertDocCount(rootResource.ID, "mediatype:folder", 1)
+		})
+
+		It("combines terms with operators", func() {
+			assertDocCount(rootResource.ID, "child* OR parent*", 2)
+			assertDocCount(rootResource.ID, "child* AND parent*", 0)
+			assertDocCount(rootResource.ID, "parent* OR child* AND NOT content:fox", 1)
+		})
+
+		It("scopes the search to the specified space and path", func() {
+			assertDocCount("9$8!7", "child.pdf", 0)
+
+			res, err := doSearch(rootResource.ID, "*d*", "./parent d!r")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.TotalMatches).To(Equal(int32(2)))
+
+			res, err = doSearch(rootResource.ID, "*d*", "./other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(res.Matches).To(BeEmpty())
+		})
+
+		It("returns all desired fields", func() {
+			matches := assertDocCount(rootResource.ID, "child.pdf", 1)
+			Expect(matches[0].Entity.Ref.Path).To(Equal(childResource.Path))
+			Expect(matches[0].Entity.Size).To(Equal(childResource.Size))
+			Expect(matches[0].Entity.ParentId.OpaqueId).To(Equal("3"))
+		})
+
+		It("rejects invalid queries", func() {
+			_, err := doSearch(rootResource.ID, "AND child.pdf", "")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Lookup and Update", func() {
+		It("returns and mutates the stored resource", func() {
+			childResource.Photo = libregraph.NewPhoto()
+			childResource.Photo.SetCameraMake("Canon")
+			Expect(eng.Upsert(childResource.ID, childResource)).To(Succeed())
+
+			Expect(eng.Update(childResource.ID, func(r *engine.Resource) {
+				r.Tags = []string{"foo"}
+			})).To(Succeed())
+
+			r, err := eng.Lookup(childResource.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Tags).To(Equal([]string{"foo"}))
+			Expect(r.Photo.GetCameraMake()).To(Equal("Canon"))
+			Expect(r.Content).To(Equal(childResource.Content))
+
+			assertDocCount(rootResource.ID, "photo.cameraMake:*canon*", 1)
+		})
+
+		It("returns ErrResourceNotFound for unknown resources", func() {
+			_, err := eng.Lookup("1$2!unknown")
+			Expect(err).To(MatchError(engine.ErrResourceNotFound))
+		})
+	})
+
+	Describe("Delete, Restore and Purge", func() {
+		It("marks child resources as deleted and restored", func() {
+			Expect(eng.Delete(parentResource.ID)).To(Succeed())
+			assertDocCount(rootResource.ID, "child.pdf", 0)
+
+			Expect(eng.Restore(parentResource.ID)).To(Succeed())
+			assertDocCount(rootResource.ID, "child.pdf", 1)
+		})
+
+		It("removes resources from the index", func() {
+			Expect(eng.Purge(childResource.ID)).To(Succeed())
+			Expect(eng.Purge(childResource.ID)).To(Succeed())
+
+			count, err := eng.DocCount()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(count).To(Equal(uint64(1)))
+		})
+	})
+
+	Describe("Move", func() {
+		It("moves the parent and its child resources", func() {
+			Expect(eng.Move(parentResource.ID, "1$2!somewhereopaqueid", "./somewhere/else/newname")).To(Succeed())
+
+			matches := assertDocCount(rootResource.ID, "Name:child.pdf", 1)
+			Expect(matches[0].Entity.Ref.Path).To(Equal("./somewhere/else/newname/child.pdf"))
+
+			matches = assertDocCount(rootResource.ID, "newname", 1)
+			Expect(matches[0].Entity.ParentId.OpaqueId).To(Equal("somewhereopaqueid"))
+		})
+	})
+
+	Describe("Optimize", func() {
+		It("force merges the index", func() {
+			Expect(eng.Optimize(context.Background())).To(Succeed())
+		})
+	})
+
+	Describe("Migrate", func() {
+		It("copies all resources of a bleve index", func() {
+			mapping, err := engine.BuildBleveMapping()
+			Expect(err).ToNot(HaveOccurred())
+			src := engine.NewBleveEngine(bleveEngine.NewIndexGetterMemory(mapping), bleve.DefaultCreator)
+
+			deleted := engine.Resource{ID: "1$2!5", RootID: rootResource.ID, ParentID: rootResource.ID, Path: "./deleted.txt", Deleted: true, Document: content.Document{Name: "deleted.txt"}}
+			moved := childResource
+			moved.Path = "./moved.pdf"
+			for _, r := range []engine.Resource{parentResource, moved, deleted} {
+				Expect(src.Upsert(r.ID, r)).To(Succeed())
+			}
+
+			var batches int
+			migrated, err := engine.Migrate(context.Background(), src, eng, 2, func(uint64) { batches++ })
+			Expect(err).ToNot(HaveOccurred())
+			Expect(migrated).To(Equal(uint64(3)))
+			Expect(batches).To(Equal(2))
+			Expect(server.Documents("ocis-resources")).To(ConsistOf("1$2!3", "1$2!4", "1$2!5"))
+
+			r, err := eng.Lookup(deleted.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(r.Deleted).To(BeTrue())
+
+			matches := assertDocCount(rootResource.ID, "child.pdf", 1)
+			Expect(matches[0].Entity.Ref.Path).To(Equal("./moved.pdf"))
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+)
+
+// SavedSearchMatched is emitted when a newly indexed resource matches a saved search the user subscribed to
+type SavedSearchMatched struct {
+	SavedSearchID   string
+	SavedSearchName string
+	UserID          *user.UserId
+	ResourceID      *provider.ResourceId
+	ResourceName    string
+	Timestamp       time.Time
+}
+
+// Unmarshal to fulfill umarshaller interface
+func (SavedSearchMatched) Unmarshal(v []byte) (interface{}, error) {
+	e := SavedSearchMatched{}
+	err := json.Unmarshal(v, &e)
+	return e, err
+}
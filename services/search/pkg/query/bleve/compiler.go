@@ -21,6 +21,9 @@ var _fields = map[string]string{
 	"type":      "Type",
 	"tag":       "Tags",
 	"tags":      "Tags",
+	"author":    "Authors",
+	"authors":   "Authors",
+	"pagecount": "PageCount",
 	"content":   "Content",
 	"hidden":    "Hidden",
 }
@@ -98,7 +101,7 @@ func walk(offset int, nodes []ast.Node) (bleveQuery.Query, int, error) {
 		case *ast.StringNode:
 			k := getField(n.Key)
 			v := n.Value
-			if k != "ID" && k != "Size" {
+			if k != "ID" && k != "Size" && k != "PageCount" {
 				v = bleveEscaper.Replace(n.Value)
 			}
 
@@ -233,8 +233,8 @@ func Test_compile(t *testing.T) {
 				},
 			},
 			want: query.NewConjunctionQuery([]query.Query{
-				query.NewQueryStringQuery(`author:john\ smith`),
-				query.NewQueryStringQuery(`author:jane`),
+				query.NewQueryStringQuery(`Authors:john\ smith`),
+				query.NewQueryStringQuery(`Authors:jane`),
 			}),
 			wantErr: false,
 		},
@@ -255,8 +255,8 @@ func Test_compile(t *testing.T) {
 				},
 			},
 			want: query.NewConjunctionQuery([]query.Query{
-				query.NewQueryStringQuery(`author:john\ smith`),
-				query.NewQueryStringQuery(`author:jane`),
+				query.NewQueryStringQuery(`Authors:john\ smith`),
+				query.NewQueryStringQuery(`Authors:jane`),
 				query.NewQueryStringQuery(`Tags:bestseller`),
 			}),
 			wantErr: false,
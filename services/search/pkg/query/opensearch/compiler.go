@@ -0,0 +1,299 @@
+package opensearch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/ast"
+	"github.com/owncloud/ocis/v2/ocis-pkg/kql"
+)
+
+var _fields = map[string]string{
+	"rootid":    "RootID",
+	"path":      "Path",
+	"id":        "ID",
+	"name":      "Name",
+	"size":      "Size",
+	"mtime":     "Mtime",
+	"mediatype": "MimeType",
+	"type":      "Type",
+	"tag":       "Tags",
+	"tags":      "Tags",
+	"author":    "Authors",
+	"authors":   "Authors",
+	"pagecount": "PageCount",
+	"content":   "Content",
+	"hidden":    "Hidden",
+}
+
+var _photoFields = map[string]string{
+	"cameramake":          "photo.cameraMake",
+	"cameramodel":         "photo.cameraModel",
+	"takendatetime":       "photo.takenDateTime",
+	"fnumber":             "photo.fNumber",
+	"focallength":         "photo.focalLength",
+	"iso":                 "photo.iso",
+	"orientation":         "photo.orientation",
+	"exposurenumerator":   "photo.exposureNumerator",
+	"exposuredenominator": "photo.exposureDenominator",
+}
+
+// _lowercaseFields are indexed with a lowercase normalizer, their values are matched case-insensitive
+var _lowercaseFields = map[string]bool{
+	"Name":              true,
+	"Tags":              true,
+	"MimeType":          true,
+	"Authors":           true,
+	"photo.cameraMake":  true,
+	"photo.cameraModel": true,
+}
+
+// wildcardEscaper escapes the backslash, which is the escape character of wildcard queries.
+// The wildcards * and ? are kept.
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`)
+
+// Compiler represents a KQL query search string to the OpenSearch query formatter.
+type Compiler struct{}
+
+// Compile implements the query formatter which converts the KQL query search string to the OpenSearch query.
+func (c Compiler) Compile(givenAst *ast.Ast) (Query, error) {
+	return compile(givenAst.Nodes)
+}
+
+// compile turns a list of nodes into a query. AND binds stronger than OR, NOT applies to the next node,
+// so 'a OR b AND NOT c' becomes 'a OR (b AND (NOT c))'.
+func compile(nodes []ast.Node) (Query, error) {
+	var (
+		disjuncts []Query
+		conjuncts []Query
+		negate    bool
+	)
+
+	for _, node := range nodes {
+		var q Query
+		switch n := node.(type) {
+		case *ast.OperatorNode:
+			switch n.Value {
+			case kql.BoolOR:
+				if len(conjuncts) > 0 {
+					disjuncts = append(disjuncts, And(conjuncts...))
+					conjuncts = nil
+				}
+			case kql.BoolNOT:
+				negate = !negate
+			}
+			continue
+		case *ast.StringNode:
+			q = stringQuery(n)
+		case *ast.DateTimeNode:
+			q = dateTimeQuery(n)
+		case *ast.NumericNode:
+			q = numericQuery(n)
+		case *ast.BooleanNode:
+			q = Term(getField(n.Key), n.Value)
+		case *ast.GroupNode:
+			if n.Key != "" {
+				n = normalizeGroupingProperty(n)
+			}
+			gq, err := compile(n.Nodes)
+			if err != nil {
+				return nil, err
+			}
+			q = gq
+		}
+		if q == nil {
+			continue
+		}
+
+		if negate {
+			q = Not(q)
+			negate = false
+		}
+		conjuncts = append(conjuncts, q)
+	}
+
+	if len(conjuncts) > 0 {
+		disjuncts = append(disjuncts, And(conjuncts...))
+	}
+	if len(disjuncts) == 0 {
+		return nil, fmt.Errorf("can not compile the query")
+	}
+
+	return Or(disjuncts...), nil
+}
+
+func stringQuery(n *ast.StringNode) Query {
+	k := getField(n.Key)
+	v := n.Value
+	if _lowercaseFields[k] {
+		v = strings.ToLower(v)
+	}
+
+	if q := comparisonQuery(k, v); q != nil {
+		return q
+	}
+
+	switch k {
+	case "MimeType":
+		return mimeType(k, v)
+	case "Content":
+		return Match(k, v)
+	}
+
+	return termOrWildcard(k, v)
+}
+
+// comparisonQuery turns values like '>1000' into a range query, as the bleve query string does
+func comparisonQuery(k, v string) Query {
+	for _, operator := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(v, operator) {
+			continue
+		}
+
+		n, err := strconv.ParseFloat(strings.TrimPrefix(v, operator), 64)
+		if err != nil {
+			return nil
+		}
+		bound, _ := rangeBound(operator)
+		return Range(k, map[string]interface{}{bound: n})
+	}
+	return nil
+}
+
+func termOrWildcard(k, v string) Query {
+	if strings.ContainsAny(v, "*?") {
+		return Wildcard(k, wildcardEscaper.Replace(v))
+	}
+	return Term(k, v)
+}
+
+func getField(name string) string {
+	if name == "" {
+		return "Name"
+	}
+	lower := strings.ToLower(name)
+
+	// Handle nested field prefixes (e.g., "photo.takenDateTime")
+	if parts := strings.SplitN(lower, ".", 2); len(parts) == 2 {
+		switch parts[0] {
+		case "photo":
+			if field, ok := _photoFields[parts[1]]; ok {
+				return field
+			}
+		}
+	}
+
+	if field, ok := _fields[lower]; ok {
+		return field
+	}
+	return name
+}
+
+func normalizeGroupingProperty(group *ast.GroupNode) *ast.GroupNode {
+	for _, n := range group.Nodes {
+		if onode, ok := n.(*ast.StringNode); ok {
+			onode.Key = group.Key
+		}
+	}
+	return group
+}
+
+func mimeType(k, v string) Query {
+	switch v {
+	case "file":
+		return Not(Term(k, "httpd/unix-directory"))
+	case "folder":
+		return Term(k, "httpd/unix-directory")
+	case "document":
+		return Terms(k,
+			"application/msword",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.form",
+			"application/vnd.oasis.opendocument.text",
+			"text/plain",
+			"text/markdown",
+			"application/rtf",
+			"application/vnd.apple.pages",
+		)
+	case "spreadsheet":
+		return Terms(k,
+			"application/vnd.ms-excel",
+			"application/vnd.oasis.opendocument.spreadsheet",
+			"text/csv",
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			"application/vnd.apple.numbers",
+		)
+	case "presentation":
+		return Terms(k,
+			"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+			"application/vnd.oasis.opendocument.presentation",
+			"application/vnd.ms-powerpoint",
+			"application/vnd.apple.keynote",
+		)
+	case "pdf":
+		return Term(k, "application/pdf")
+	case "image":
+		return Prefix(k, "image/")
+	case "video":
+		return Prefix(k, "video/")
+	case "audio":
+		return Prefix(k, "audio/")
+	case "archive":
+		return Terms(k,
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/x-7z-compressed",
+			"application/x-rar-compressed",
+			"application/x-tar",
+			"application/x-bzip2",
+			"application/x-bzip",
+			"application/x-tgz",
+		)
+	default:
+		return termOrWildcard(k, v)
+	}
+}
+
+func dateTimeQuery(n *ast.DateTimeNode) Query {
+	if n.Operator == nil {
+		return nil
+	}
+
+	bound, ok := rangeBound(n.Operator.Value)
+	if !ok {
+		return nil
+	}
+
+	return Range(getField(n.Key), map[string]interface{}{bound: n.Value.Format(time.RFC3339Nano)})
+}
+
+func numericQuery(n *ast.NumericNode) Query {
+	if n.Operator == nil {
+		return nil
+	}
+
+	bound, ok := rangeBound(n.Operator.Value)
+	if !ok {
+		return nil
+	}
+
+	return Range(getField(n.Key), map[string]interface{}{bound: n.Value})
+}
+
+func rangeBound(operator string) (string, bool) {
+	switch operator {
+	case ">":
+		return "gt", true
+	case ">=":
+		return "gte", true
+	case "<":
+		return "lt", true
+	case "<=":
+		return "lte", true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,148 @@
+package opensearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/ast"
+	tAssert "github.com/stretchr/testify/assert"
+)
+
+func Test_compile(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    *ast.Ast
+		want    Query
+		wantErr bool
+	}{
+		{
+			name: `federated`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.StringNode{Value: "Federated"},
+				},
+			},
+			want: Term("Name", "federated"),
+		},
+		{
+			name: `name:"foo o*"`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.StringNode{Key: "name", Value: "Foo o*"},
+				},
+			},
+			want: Wildcard("Name", "foo o*"),
+		},
+		{
+			name: `content:fox size:>1000`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.StringNode{Key: "content", Value: "Fox"},
+					&ast.OperatorNode{Value: "AND"},
+					&ast.StringNode{Key: "size", Value: ">1000"},
+				},
+			},
+			want: And(
+				Match("Content", "Fox"),
+				Range("Size", map[string]interface{}{"gt": float64(1000)}),
+			),
+		},
+		{
+			name: `a OR b AND NOT c`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.StringNode{Value: "a"},
+					&ast.OperatorNode{Value: "OR"},
+					&ast.StringNode{Value: "b"},
+					&ast.OperatorNode{Value: "AND"},
+					&ast.OperatorNode{Value: "NOT"},
+					&ast.StringNode{Value: "c"},
+				},
+			},
+			want: Or(
+				Term("Name", "a"),
+				And(Term("Name", "b"), Not(Term("Name", "c"))),
+			),
+		},
+		{
+			name: `(a OR b) AND c`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.GroupNode{Nodes: []ast.Node{
+						&ast.StringNode{Value: "a"},
+						&ast.OperatorNode{Value: "OR"},
+						&ast.StringNode{Value: "b"},
+					}},
+					&ast.OperatorNode{Value: "AND"},
+					&ast.StringNode{Value: "c"},
+				},
+			},
+			want: And(
+				Or(Term("Name", "a"), Term("Name", "b")),
+				Term("Name", "c"),
+			),
+		},
+		{
+			name: `mediatype:(image OR folder)`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.GroupNode{Key: "mediatype", Nodes: []ast.Node{
+						&ast.StringNode{Value: "image"},
+						&ast.OperatorNode{Value: "OR"},
+						&ast.StringNode{Value: "folder"},
+					}},
+				},
+			},
+			want: Or(
+				Prefix("MimeType", "image/"),
+				Term("MimeType", "httpd/unix-directory"),
+			),
+		},
+		{
+			name: `mtime>=2023-09-05 hidden:true`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.DateTimeNode{Key: "Mtime", Operator: &ast.OperatorNode{Value: ">="}, Value: time.Date(2023, 9, 5, 0, 0, 0, 0, time.UTC)},
+					&ast.OperatorNode{Value: "AND"},
+					&ast.BooleanNode{Key: "hidden", Value: true},
+				},
+			},
+			want: And(
+				Range("Mtime", map[string]interface{}{"gte": "2023-09-05T00:00:00Z"}),
+				Term("Hidden", true),
+			),
+		},
+		{
+			name: `photo.cameraMake:*Canon*`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.StringNode{Key: "photo.cameraMake", Value: "*Canon*"},
+				},
+			},
+			want: Wildcard("photo.cameraMake", "*canon*"),
+		},
+		{
+			name: `no nodes`,
+			args: &ast.Ast{
+				Nodes: []ast.Node{
+					&ast.OperatorNode{Value: "AND"},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	assert := tAssert.New(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compiler{}.Compile(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(tt.want, got)
+		})
+	}
+}
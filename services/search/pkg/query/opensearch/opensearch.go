@@ -0,0 +1,102 @@
+// Package opensearch provides the ability to work with OpenSearch and Elasticsearch queries.
+package opensearch
+
+import (
+	"github.com/owncloud/ocis/v2/ocis-pkg/kql"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query"
+)
+
+// Query is a query of the OpenSearch query DSL, it is sent as json to the server.
+type Query map[string]interface{}
+
+// Creator is combines a Builder and a Compiler which is used to Create the query.
+type Creator[T any] struct {
+	builder  query.Builder
+	compiler query.Compiler[T]
+}
+
+// Create implements the Creator interface
+func (c Creator[T]) Create(qs string) (T, error) {
+	var t T
+	builderAst, err := c.builder.Build(qs)
+	if err != nil {
+		return t, err
+	}
+
+	return c.compiler.Compile(builderAst)
+}
+
+// DefaultCreator exposes a kql to OpenSearch query creator.
+var DefaultCreator = Creator[Query]{kql.Builder{}, Compiler{}}
+
+// Term matches documents which contain the exact value in the field.
+func Term(field string, value interface{}) Query {
+	return Query{"term": map[string]interface{}{field: value}}
+}
+
+// Terms matches documents which contain one of the exact values in the field.
+func Terms(field string, values ...string) Query {
+	return Query{"terms": map[string]interface{}{field: values}}
+}
+
+// Wildcard matches documents whose field matches the pattern, '*' and '?' are supported.
+func Wildcard(field, pattern string) Query {
+	return Query{"wildcard": map[string]interface{}{field: map[string]interface{}{"value": pattern}}}
+}
+
+// Prefix matches documents whose field starts with the value.
+func Prefix(field, value string) Query {
+	return Query{"prefix": map[string]interface{}{field: value}}
+}
+
+// Match matches documents which contain all the terms of the analyzed text in the field.
+func Match(field, text string) Query {
+	return Query{"match": map[string]interface{}{field: map[string]interface{}{"query": text, "operator": "and"}}}
+}
+
+// Range matches documents whose field lies in the given bounds, the keys of the bounds are 'gt', 'gte', 'lt' and 'lte'.
+func Range(field string, bounds map[string]interface{}) Query {
+	return Query{"range": map[string]interface{}{field: bounds}}
+}
+
+// MatchAll matches all documents.
+func MatchAll() Query {
+	return Query{"match_all": map[string]interface{}{}}
+}
+
+// Bool combines queries, all 'must' queries, none of the 'mustNot' queries and at least one of the 'should' queries need to match.
+func Bool(must, should, mustNot []Query) Query {
+	b := map[string]interface{}{}
+	if len(must) > 0 {
+		b["must"] = must
+	}
+	if len(should) > 0 {
+		b["should"] = should
+		b["minimum_should_match"] = 1
+	}
+	if len(mustNot) > 0 {
+		b["must_not"] = mustNot
+	}
+	return Query{"bool": b}
+}
+
+// And matches documents which match all queries.
+func And(queries ...Query) Query {
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return Bool(queries, nil, nil)
+}
+
+// Or matches documents which match at least one of the queries.
+func Or(queries ...Query) Query {
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return Bool(nil, queries, nil)
+}
+
+// Not matches documents which do not match the query.
+func Not(q Query) Query {
+	return Bool(nil, nil, []Query{q})
+}
@@ -0,0 +1,266 @@
+package savedsearch
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	searchMessage "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/search/v0"
+	searchService "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/search/v0"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/search/pkg/event"
+	"github.com/owncloud/ocis/v2/services/search/pkg/search"
+)
+
+const (
+	_queueSize     = 1000
+	_batchSize     = 100
+	_batchInterval = time.Second
+	_purgeInterval = time.Hour
+)
+
+// MembersFunc returns the ids of the users who have access to a space
+type MembersFunc func(ctx context.Context, spaceID string) ([]string, error)
+
+// Alerter notifies users when an indexed resource matches one of the saved searches they subscribed to.
+// The indexed resources are queued and checked in batches, so indexing does not wait for the alerts.
+type Alerter struct {
+	store     *Store
+	engine    engine.Engine
+	publisher events.Publisher
+	members   MembersFunc
+	logger    log.Logger
+	queue     chan indexed
+}
+
+// indexed is a resource waiting to be checked against the alerts with the context it was indexed with
+type indexed struct {
+	ctx      context.Context
+	resource engine.Resource
+}
+
+// NewAlerter creates an Alerter, only members of the space of a resource are notified about it.
+// The queued resources are checked by Run.
+func NewAlerter(store *Store, eng engine.Engine, publisher events.Publisher, members MembersFunc, logger log.Logger) *Alerter {
+	return &Alerter{
+		store:     store,
+		engine:    eng,
+		publisher: publisher,
+		members:   members,
+		logger:    logger,
+		queue:     make(chan indexed, _queueSize),
+	}
+}
+
+// SpaceMembers looks up the members of a space through the gateway
+func SpaceMembers(gatewaySelector pool.Selectable[gateway.GatewayAPIClient]) MembersFunc {
+	return func(ctx context.Context, spaceID string) ([]string, error) {
+		gatewayClient, err := gatewaySelector.Next()
+		if err != nil {
+			return nil, err
+		}
+		return utils.GetSpaceMembers(ctx, spaceID, gatewayClient, utils.ViewerRole)
+	}
+}
+
+// Indexed queues a resource that was just indexed. The context needs to be authenticated to look up the
+// members of the space. Resources are dropped when the queue is full.
+func (a *Alerter) Indexed(ctx context.Context, r engine.Resource) {
+	if r.Deleted {
+		return
+	}
+
+	select {
+	case a.queue <- indexed{ctx: context.WithoutCancel(ctx), resource: r}:
+	default:
+		a.logger.Warn().Str("id", r.ID).Msg("saved search alert queue is full, dropping resource")
+	}
+}
+
+// Run checks the queued resources in batches and purges the expired notification records until the
+// context is done
+func (a *Alerter) Run(ctx context.Context) {
+	purge := time.NewTicker(_purgeInterval)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			if err := a.store.PurgeNotified(); err != nil {
+				a.logger.Error().Err(err).Msg("could not purge saved search notifications")
+			}
+		case item := <-a.queue:
+			a.process(a.collect(item))
+		}
+	}
+}
+
+// collect adds the resources queued within the batch interval to a batch
+func (a *Alerter) collect(item indexed) []indexed {
+	batch := []indexed{item}
+	timeout := time.NewTimer(_batchInterval)
+	defer timeout.Stop()
+	for len(batch) < _batchSize {
+		select {
+		case item := <-a.queue:
+			batch = append(batch, item)
+		case <-timeout.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// process checks a batch of resources against the subscribed saved searches and publishes a
+// SavedSearchMatched event for every user who was not notified about a resource before
+func (a *Alerter) process(batch []indexed) {
+	// the alerts and members are looked up once per space
+	spaces := map[string][]indexed{}
+	for _, item := range batch {
+		rootID, err := storagespace.ParseID(item.resource.RootID)
+		if err != nil {
+			a.logger.Error().Err(err).Str("rootid", item.resource.RootID).Msg("could not parse root id")
+			continue
+		}
+		spaceID := storagespace.FormatStorageID(rootID.GetStorageId(), rootID.GetSpaceId())
+		spaces[spaceID] = append(spaces[spaceID], item)
+	}
+
+	for spaceID, items := range spaces {
+		a.processSpace(spaceID, items)
+	}
+}
+
+func (a *Alerter) processSpace(spaceID string, items []indexed) {
+	rootID, _ := storagespace.ParseID(items[0].resource.RootID)
+	alerts, err := a.store.SpaceAlerts(rootID.GetSpaceId())
+	if err != nil {
+		a.logger.Error().Err(err).Msg("could not list saved search alerts")
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	// the resources are indexed one after another, the latest one has the freshest context
+	ctx := items[len(items)-1].ctx
+	members, err := a.members(ctx, spaceID)
+	if err != nil {
+		a.logger.Debug().Err(err).Str("spaceid", spaceID).Msg("could not get space members")
+		return
+	}
+
+	resources := make(map[string]engine.Resource, len(items))
+	for _, item := range items {
+		resources[item.resource.ID] = item.resource
+	}
+
+	for _, alert := range alerts {
+		if !slices.Contains(members, alert.UserID) {
+			continue
+		}
+
+		matches, err := a.matches(ctx, alert, &rootID, resources)
+		if err != nil {
+			a.logger.Error().Err(err).Str("savedsearch", alert.ID).Msg("could not check saved search")
+			continue
+		}
+
+		for _, r := range matches {
+			a.notify(ctx, alert, r)
+		}
+	}
+}
+
+func (a *Alerter) notify(ctx context.Context, alert *SavedSearch, r engine.Resource) {
+	isNew, err := a.store.MarkNotified(alert.ID, r.ID)
+	if err != nil {
+		a.logger.Error().Err(err).Str("savedsearch", alert.ID).Msg("could not record saved search notification")
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	resourceID, err := storagespace.ParseID(r.ID)
+	if err != nil {
+		a.logger.Error().Err(err).Str("id", r.ID).Msg("could not parse resource id")
+		return
+	}
+
+	if err := events.Publish(ctx, a.publisher, event.SavedSearchMatched{
+		SavedSearchID:   alert.ID,
+		SavedSearchName: alert.Name,
+		UserID:          &user.UserId{OpaqueId: alert.UserID},
+		ResourceID:      &resourceID,
+		ResourceName:    r.Name,
+		Timestamp:       time.Now().UTC(),
+	}); err != nil {
+		a.logger.Error().Err(err).Str("savedsearch", alert.ID).Msg("could not publish saved search match")
+	}
+}
+
+// matches runs the query of the saved search restricted to the resources and their space and returns
+// the matching resources
+func (a *Alerter) matches(ctx context.Context, ss *SavedSearch, rootID *provider.ResourceId, resources map[string]engine.Resource) ([]engine.Resource, error) {
+	query, scope := search.ParseScope(ss.Query)
+	query, _ = search.ParseVaultMode(query)
+
+	// the scope is only compared on space level, resources in other spaces never match
+	if scope != "" {
+		scopeID, err := storagespace.ParseID(scope)
+		if err != nil || scopeID.GetStorageId() != rootID.GetStorageId() || scopeID.GetSpaceId() != rootID.GetSpaceId() {
+			return nil, nil
+		}
+	}
+
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, "id:"+strconv.Quote(id))
+	}
+	sort.Strings(ids)
+
+	res, err := a.engine.Search(ctx, &searchService.SearchIndexRequest{
+		Query: fmt.Sprintf("(%s) AND (%s)", query, strings.Join(ids, " OR ")),
+		Ref: &searchMessage.Reference{
+			ResourceId: &searchMessage.ResourceID{
+				StorageId: rootID.GetStorageId(),
+				SpaceId:   rootID.GetSpaceId(),
+				OpaqueId:  rootID.GetOpaqueId(),
+			},
+		},
+		PageSize: int32(len(ids)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]engine.Resource, 0, len(res.GetMatches()))
+	for _, m := range res.GetMatches() {
+		id := m.GetEntity().GetId()
+		r, ok := resources[storagespace.FormatResourceID(&provider.ResourceId{
+			StorageId: id.GetStorageId(),
+			SpaceId:   id.GetSpaceId(),
+			OpaqueId:  id.GetOpaqueId(),
+		})]
+		if ok {
+			matches = append(matches, r)
+		}
+	}
+	return matches, nil
+}
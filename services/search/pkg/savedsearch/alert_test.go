@@ -0,0 +1,135 @@
+package savedsearch_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go-micro.dev/v4/events"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/search/pkg/content"
+	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	bleveEngine "github.com/owncloud/ocis/v2/services/search/pkg/engine/bleve"
+	"github.com/owncloud/ocis/v2/services/search/pkg/event"
+	"github.com/owncloud/ocis/v2/services/search/pkg/query/bleve"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
+)
+
+type publisher struct {
+	published []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...events.PublishOption) error {
+	p.published = append(p.published, ev)
+	return nil
+}
+
+var _ = Describe("Alerter", func() {
+	var (
+		store   *savedsearch.Store
+		eng     *engine.Bleve
+		pub     *publisher
+		alerter *savedsearch.Alerter
+
+		report = engine.Resource{
+			ID:       "1$2!3",
+			RootID:   "1$2!2",
+			ParentID: "1$2!2",
+			Path:     "./report.pdf",
+			Type:     1,
+			Document: content.Document{Name: "report.pdf"},
+		}
+	)
+
+	BeforeEach(func() {
+		mapping, err := engine.BuildBleveMapping()
+		Expect(err).ToNot(HaveOccurred())
+		eng = engine.NewBleveEngine(bleveEngine.NewIndexGetterMemory(mapping), bleve.DefaultCreator)
+		Expect(eng.Upsert(report.ID, report)).To(Succeed())
+
+		store = savedsearch.NewStore(microstore.NewMemoryStore(), 0)
+		pub = &publisher{}
+		members := func(_ context.Context, spaceID string) ([]string, error) {
+			Expect(spaceID).To(Equal("1$2"))
+			return []string{"alice"}, nil
+		}
+		alerter = savedsearch.NewAlerter(store, eng, pub, members, log.NopLogger())
+	})
+
+	It("notifies members about matching resources once", func() {
+		ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "name:report*", Alert: true}
+		Expect(store.Create(ss)).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+
+		Expect(pub.published).To(HaveLen(1))
+		ev, ok := pub.published[0].(event.SavedSearchMatched)
+		Expect(ok).To(BeTrue())
+		Expect(ev.SavedSearchID).To(Equal(ss.ID))
+		Expect(ev.UserID.GetOpaqueId()).To(Equal("alice"))
+		Expect(ev.ResourceID.GetOpaqueId()).To(Equal("3"))
+		Expect(ev.ResourceName).To(Equal("report.pdf"))
+	})
+
+	It("checks the queued resources in a batch", func() {
+		invoice := report
+		invoice.ID = "1$2!4"
+		invoice.Path = "./invoice.pdf"
+		invoice.Document = content.Document{Name: "invoice.pdf"}
+		Expect(eng.Upsert(invoice.ID, invoice)).To(Succeed())
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "name:report*", Alert: true})).To(Succeed())
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "alice", Name: "pdfs", Query: "name:*.pdf", Alert: true})).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Indexed(context.Background(), invoice)
+		alerter.Flush()
+
+		var names []string
+		for _, p := range pub.published {
+			ev := p.(event.SavedSearchMatched)
+			names = append(names, ev.SavedSearchName+":"+ev.ResourceName)
+		}
+		Expect(names).To(ConsistOf("reports:report.pdf", "pdfs:report.pdf", "pdfs:invoice.pdf"))
+	})
+
+	It("ignores resources which do not match", func() {
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "alice", Name: "invoices", Query: "name:invoice*", Alert: true})).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+
+		Expect(pub.published).To(BeEmpty())
+	})
+
+	It("ignores saved searches without alert", func() {
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "name:report*"})).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+
+		Expect(pub.published).To(BeEmpty())
+	})
+
+	It("does not notify users without access to the space", func() {
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "bob", Name: "reports", Query: "name:report*", Alert: true})).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+
+		Expect(pub.published).To(BeEmpty())
+	})
+
+	It("respects the scope of the saved search", func() {
+		Expect(store.Create(&savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "name:report* scope:1$5!5", Alert: true})).To(Succeed())
+
+		alerter.Indexed(context.Background(), report)
+		alerter.Flush()
+
+		Expect(pub.published).To(BeEmpty())
+	})
+})
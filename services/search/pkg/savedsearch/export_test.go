@@ -0,0 +1,10 @@
+package savedsearch
+
+// Flush checks the queued resources
+func (a *Alerter) Flush() {
+	var batch []indexed
+	for len(a.queue) > 0 {
+		batch = append(batch, <-a.queue)
+	}
+	a.process(batch)
+}
@@ -0,0 +1,298 @@
+// Package savedsearch manages the named search queries of users and the alerts users subscribed to.
+package savedsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/indexstore"
+	"github.com/owncloud/ocis/v2/ocis-pkg/kql"
+	"github.com/owncloud/ocis/v2/services/search/pkg/search"
+)
+
+const (
+	_maxNameLength = 255
+
+	_searchPrefix   = "search/"
+	_alertPrefix    = "alert/"
+	_notifiedPrefix = "notified/"
+
+	// _allSpaces is the space of the alerts without scope
+	_allSpaces = "all"
+)
+
+// ErrNotFound is returned when a saved search does not exist or belongs to another user
+var ErrNotFound = errors.New("saved search not found")
+
+// ValidationError is returned for saved searches with an invalid name or query
+type ValidationError = indexstore.ValidationError
+
+// SavedSearch is a named search query of a user
+type SavedSearch struct {
+	ID                   string    `json:"id"`
+	Name                 string    `json:"name"`
+	Query                string    `json:"query"`
+	Alert                bool      `json:"alert"`
+	CreatedDateTime      time.Time `json:"createdDateTime"`
+	LastModifiedDateTime time.Time `json:"lastModifiedDateTime"`
+	UserID               string    `json:"-"`
+}
+
+// Validate checks the name and that the query is valid KQL
+func (s *SavedSearch) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	switch {
+	case s.Name == "":
+		return indexstore.NewValidationError("name must not be empty")
+	case len(s.Name) > _maxNameLength:
+		return indexstore.NewValidationError("name must not be longer than %d characters", _maxNameLength)
+	}
+
+	query, _ := search.ParseScope(s.Query)
+	query, _ = search.ParseVaultMode(query)
+	if strings.TrimSpace(query) == "" {
+		return indexstore.NewValidationError("query must not be empty")
+	}
+
+	if _, err := (kql.Builder{}).Build(query); err != nil {
+		return indexstore.NewValidationError("invalid query: %s", err)
+	}
+
+	return nil
+}
+
+// record is the stored representation of a saved search, it keeps the owner which is not exposed by the API
+type record struct {
+	SavedSearch
+	UserID string `json:"userId"`
+}
+
+// Store persists saved searches
+type Store struct {
+	store       *indexstore.Store
+	notifiedTTL time.Duration
+}
+
+// NewStore creates a Store which keeps the saved searches in the given store. Users are notified again about
+// resources matching their alerts after the notifiedTTL, a zero notifiedTTL keeps the notifications forever.
+func NewStore(store microstore.Store, notifiedTTL time.Duration) *Store {
+	return &Store{store: indexstore.New(store), notifiedTTL: notifiedTTL}
+}
+
+// List returns the saved searches of a user
+func (s *Store) List(userID string) ([]*SavedSearch, error) {
+	searches, err := s.list(_searchPrefix + userID + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(searches, func(i, j int) bool {
+		return searches[i].CreatedDateTime.Before(searches[j].CreatedDateTime)
+	})
+	return searches, nil
+}
+
+// Get returns a saved search of a user
+func (s *Store) Get(userID, id string) (*SavedSearch, error) {
+	value, err := s.store.Read(searchKey(userID, id))
+	switch {
+	case errors.Is(err, indexstore.ErrNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	return decode(value)
+}
+
+// Create validates and stores a new saved search
+func (s *Store) Create(ss *SavedSearch) error {
+	if err := ss.Validate(); err != nil {
+		return err
+	}
+
+	ss.ID = uuid.New().String()
+	ss.CreatedDateTime = time.Now().UTC()
+	ss.LastModifiedDateTime = ss.CreatedDateTime
+
+	return s.write(ss, nil)
+}
+
+// Update validates and stores an existing saved search
+func (s *Store) Update(ss *SavedSearch) error {
+	old, err := s.Get(ss.UserID, ss.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := ss.Validate(); err != nil {
+		return err
+	}
+
+	ss.LastModifiedDateTime = time.Now().UTC()
+
+	return s.write(ss, old)
+}
+
+// Delete removes a saved search, its alert and the record of the resources the user was notified about
+func (s *Store) Delete(userID, id string) error {
+	ss, err := s.Get(userID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(searchKey(userID, id), alertKey(ss)); err != nil {
+		return err
+	}
+	return s.store.DeletePrefix(_notifiedPrefix + id + "/")
+}
+
+// SpaceAlerts returns the saved searches of all users who subscribed to them, which can match resources of
+// the given space. These are the saved searches scoped to the space and the ones without scope.
+func (s *Store) SpaceAlerts(spaceID string) ([]*SavedSearch, error) {
+	alerts, err := s.list(_alertPrefix + _allSpaces + "/")
+	if err != nil {
+		return nil, err
+	}
+	scoped, err := s.list(_alertPrefix + spaceID + "/")
+	if err != nil {
+		return nil, err
+	}
+	return append(alerts, scoped...), nil
+}
+
+// MarkNotified records that a user was notified about a resource matching a saved search.
+// It returns false if the user was notified before.
+func (s *Store) MarkNotified(id, resourceID string) (bool, error) {
+	key := _notifiedPrefix + id + "/" + resourceID
+	value, err := s.store.Read(key)
+	switch {
+	case err == nil && !s.expired(value):
+		return false, nil
+	case err != nil && !errors.Is(err, indexstore.ErrNotFound):
+		return false, err
+	}
+
+	return true, s.store.Write(key, []byte(time.Now().UTC().Format(time.RFC3339)), s.notifiedTTL)
+}
+
+// PurgeNotified removes the expired records of the resources users were notified about. Not all stores
+// expire records on their own.
+func (s *Store) PurgeNotified() error {
+	if s.notifiedTTL == 0 {
+		return nil
+	}
+
+	keys, err := s.store.Keys(_notifiedPrefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		value, err := s.store.Read(key)
+		switch {
+		case errors.Is(err, indexstore.ErrNotFound):
+			continue
+		case err != nil:
+			return err
+		case !s.expired(value):
+			continue
+		}
+		if err := s.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expired returns true if a notification record is older than the ttl
+func (s *Store) expired(value []byte) bool {
+	if s.notifiedTTL == 0 {
+		return false
+	}
+	notified, err := time.Parse(time.RFC3339, string(value))
+	return err != nil || time.Since(notified) > s.notifiedTTL
+}
+
+func (s *Store) write(ss *SavedSearch, old *SavedSearch) error {
+	value, err := json.Marshal(record{SavedSearch: *ss, UserID: ss.UserID})
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.Write(searchKey(ss.UserID, ss.ID), value, 0); err != nil {
+		return err
+	}
+
+	// a changed scope moves the alert to another space
+	if old != nil && (!ss.Alert || alertKey(old) != alertKey(ss)) {
+		if err := s.store.Delete(alertKey(old)); err != nil {
+			return err
+		}
+	}
+
+	// alerts are kept under the space they are scoped to, so the indexer only needs to read the alerts
+	// which can match a resource
+	if ss.Alert {
+		return s.store.Write(alertKey(ss), value, 0)
+	}
+	return nil
+}
+
+func (s *Store) list(prefix string) ([]*SavedSearch, error) {
+	keys, err := s.store.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	searches := make([]*SavedSearch, 0, len(keys))
+	for _, key := range keys {
+		value, err := s.store.Read(key)
+		switch {
+		case errors.Is(err, indexstore.ErrNotFound):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		ss, err := decode(value)
+		if err != nil {
+			return nil, err
+		}
+		searches = append(searches, ss)
+	}
+
+	return searches, nil
+}
+
+func decode(value []byte) (*SavedSearch, error) {
+	var r record
+	if err := json.Unmarshal(value, &r); err != nil {
+		return nil, err
+	}
+
+	ss := r.SavedSearch
+	ss.UserID = r.UserID
+	return &ss, nil
+}
+
+func searchKey(userID, id string) string {
+	return _searchPrefix + userID + "/" + id
+}
+
+// alertKey returns the key of the alert of a saved search, alerts are indexed by the space they are scoped to
+func alertKey(ss *SavedSearch) string {
+	spaceID := _allSpaces
+	if _, scope := search.ParseScope(ss.Query); scope != "" {
+		if id, err := storagespace.ParseID(scope); err == nil && id.GetSpaceId() != "" {
+			spaceID = id.GetSpaceId()
+		}
+	}
+	return _alertPrefix + spaceID + "/" + ss.UserID + "/" + ss.ID
+}
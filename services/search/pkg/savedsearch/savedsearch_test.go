@@ -0,0 +1,175 @@
+package savedsearch_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
+)
+
+var _ = Describe("SavedSearch", func() {
+	Describe("Validate", func() {
+		DescribeTable("valid saved searches",
+			func(query string) {
+				ss := &savedsearch.SavedSearch{Name: "reports", Query: query}
+				Expect(ss.Validate()).To(Succeed())
+			},
+			Entry("free text", "report"),
+			Entry("properties", `name:"*.pdf" AND mediatype:document`),
+			Entry("scope", "report scope:1$2!3"),
+		)
+
+		DescribeTable("invalid saved searches",
+			func(name, query string) {
+				ss := &savedsearch.SavedSearch{Name: name, Query: query}
+				Expect(ss.Validate()).To(BeAssignableToTypeOf(savedsearch.ValidationError{}))
+			},
+			Entry("empty name", " ", "report"),
+			Entry("long name", strings.Repeat("a", 256), "report"),
+			Entry("empty query", "reports", ""),
+			Entry("scope only", "reports", "scope:1$2!3"),
+			Entry("invalid kql", "reports", "AND report"),
+		)
+	})
+
+	Describe("Store", func() {
+		var (
+			st    microstore.Store
+			store *savedsearch.Store
+		)
+
+		BeforeEach(func() {
+			st = microstore.NewMemoryStore()
+			store = savedsearch.NewStore(st, time.Hour)
+		})
+
+		It("creates and lists the saved searches of a user", func() {
+			first := &savedsearch.SavedSearch{UserID: "alice", Name: "first", Query: "report"}
+			second := &savedsearch.SavedSearch{UserID: "alice", Name: "second", Query: "invoice"}
+			other := &savedsearch.SavedSearch{UserID: "bob", Name: "other", Query: "report"}
+			Expect(store.Create(first)).To(Succeed())
+			Expect(store.Create(second)).To(Succeed())
+			Expect(store.Create(other)).To(Succeed())
+
+			Expect(first.ID).ToNot(BeEmpty())
+			Expect(first.CreatedDateTime).ToNot(BeZero())
+
+			searches, err := store.List("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(searches).To(HaveLen(2))
+			Expect(searches[0].Name).To(Equal("first"))
+			Expect(searches[1].Name).To(Equal("second"))
+			Expect(searches[0].UserID).To(Equal("alice"))
+		})
+
+		It("does not return saved searches of other users", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "report"}
+			Expect(store.Create(ss)).To(Succeed())
+
+			_, err := store.Get("bob", ss.ID)
+			Expect(err).To(MatchError(savedsearch.ErrNotFound))
+			Expect(store.Delete("bob", ss.ID)).To(MatchError(savedsearch.ErrNotFound))
+		})
+
+		It("rejects invalid saved searches", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: ""}
+			Expect(store.Create(ss)).To(BeAssignableToTypeOf(savedsearch.ValidationError{}))
+
+			searches, err := store.List("alice")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(searches).To(BeEmpty())
+		})
+
+		It("keeps the alerts in sync", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "report", Alert: true}
+			Expect(store.Create(ss)).To(Succeed())
+
+			alerts, err := store.SpaceAlerts("2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].ID).To(Equal(ss.ID))
+			Expect(alerts[0].UserID).To(Equal("alice"))
+
+			ss.Alert = false
+			Expect(store.Update(ss)).To(Succeed())
+
+			alerts, err = store.SpaceAlerts("2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(BeEmpty())
+		})
+
+		It("indexes the alerts by the space they are scoped to", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "report scope:1$2!3", Alert: true}
+			Expect(store.Create(ss)).To(Succeed())
+
+			alerts, err := store.SpaceAlerts("2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(HaveLen(1))
+			alerts, err = store.SpaceAlerts("5")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(BeEmpty())
+
+			ss.Query = "report scope:1$5!5"
+			Expect(store.Update(ss)).To(Succeed())
+
+			alerts, err = store.SpaceAlerts("2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(BeEmpty())
+			alerts, err = store.SpaceAlerts("5")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(HaveLen(1))
+		})
+
+		It("does not update unknown saved searches", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", ID: "unknown", Name: "reports", Query: "report"}
+			Expect(store.Update(ss)).To(MatchError(savedsearch.ErrNotFound))
+		})
+
+		It("records notifications once", func() {
+			isNew, err := store.MarkNotified("search", "1$2!3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isNew).To(BeTrue())
+
+			isNew, err = store.MarkNotified("search", "1$2!3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isNew).To(BeFalse())
+		})
+
+		It("expires notifications", func() {
+			old := []byte(time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339))
+			Expect(st.Write(&microstore.Record{Key: "notified/search/1$2!3", Value: old})).To(Succeed())
+			Expect(st.Write(&microstore.Record{Key: "notified/search/1$2!4", Value: old})).To(Succeed())
+
+			isNew, err := store.MarkNotified("search", "1$2!3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isNew).To(BeTrue())
+
+			Expect(store.PurgeNotified()).To(Succeed())
+			keys, err := st.List(microstore.ListPrefix("notified/"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(ConsistOf("notified/search/1$2!3"))
+		})
+
+		It("deletes the alert and notifications with the saved search", func() {
+			ss := &savedsearch.SavedSearch{UserID: "alice", Name: "reports", Query: "report", Alert: true}
+			Expect(store.Create(ss)).To(Succeed())
+			_, err := store.MarkNotified(ss.ID, "1$2!3")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(store.Delete("alice", ss.ID)).To(Succeed())
+
+			_, err = store.Get("alice", ss.ID)
+			Expect(err).To(MatchError(savedsearch.ErrNotFound))
+			alerts, err := store.SpaceAlerts("2")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alerts).To(BeEmpty())
+			isNew, err := store.MarkNotified(ss.ID, "1$2!3")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(isNew).To(BeTrue())
+		})
+	})
+})
@@ -65,6 +65,11 @@ type Searcher interface {
 	MoveItem(ctx context.Context, ref *provider.Reference)
 }
 
+// IndexListener is informed about every resource that was added to or updated in the index
+type IndexListener interface {
+	Indexed(ctx context.Context, r engine.Resource)
+}
+
 // Service is responsible for indexing spaces and pass on a search
 // to it's underlying engine.
 type Service struct {
@@ -72,6 +77,7 @@ type Service struct {
 	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
 	engine          engine.Engine
 	extractor       content.Extractor
+	listeners       []IndexListener
 
 	serviceAccountID     string
 	serviceAccountSecret string
@@ -80,12 +86,13 @@ type Service struct {
 var errSkipSpace error
 
 // NewService creates a new Provider instance.
-func NewService(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], eng engine.Engine, extractor content.Extractor, logger log.Logger, cfg *config.Config) *Service {
+func NewService(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], eng engine.Engine, extractor content.Extractor, logger log.Logger, cfg *config.Config, listeners ...IndexListener) *Service {
 	var s = &Service{
 		gatewaySelector: gatewaySelector,
 		engine:          eng,
 		logger:          logger,
 		extractor:       extractor,
+		listeners:       listeners,
 
 		serviceAccountID:     cfg.ServiceAccount.ServiceAccountID,
 		serviceAccountSecret: cfg.ServiceAccount.ServiceAccountSecret,
@@ -634,6 +641,10 @@ func (s *Service) upsertItem(ctx context.Context, ref *provider.Reference, retri
 
 	logDocCount(s.engine, s.logger)
 	s.storeExtractedMetadata(ctx2, ref, doc)
+
+	for _, l := range s.listeners {
+		l.Indexed(ctx2, r)
+	}
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/metrics"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
 	svc "github.com/owncloud/ocis/v2/services/search/pkg/service/grpc/v0"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -23,6 +24,7 @@ type Options struct {
 	Handler       *svc.Service
 	JWTSecret     string
 	TraceProvider trace.TracerProvider
+	SavedSearches *savedsearch.Store
 }
 
 // newOptions initializes the available default options.
@@ -91,3 +93,10 @@ func TraceProvider(val trace.TracerProvider) Option {
 		o.TraceProvider = val
 	}
 }
+
+// SavedSearches provides a function to set the SavedSearches option
+func SavedSearches(val *savedsearch.Store) Option {
+	return func(o *Options) {
+		o.SavedSearches = val
+	}
+}
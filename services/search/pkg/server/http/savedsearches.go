@@ -0,0 +1,195 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
+)
+
+type savedSearchesHandler struct {
+	log   log.Logger
+	store *savedsearch.Store
+}
+
+// savedSearchRequest is the body of create and update requests, omitted fields are not changed on update
+type savedSearchRequest struct {
+	Name  *string `json:"name"`
+	Query *string `json:"query"`
+	Alert *bool   `json:"alert"`
+}
+
+func (req savedSearchRequest) apply(ss *savedsearch.SavedSearch) {
+	if req.Name != nil {
+		ss.Name = *req.Name
+	}
+	if req.Query != nil {
+		ss.Query = *req.Query
+	}
+	if req.Alert != nil {
+		ss.Alert = *req.Alert
+	}
+}
+
+type listResponse struct {
+	Value []*savedsearch.SavedSearch `json:"value"`
+}
+
+func (h savedSearchesHandler) list(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	searches, err := h.store.List(userID)
+	if err != nil {
+		h.log.Error().Err(err).Str("userid", userID).Msg("could not list saved searches")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not list saved searches")
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &listResponse{Value: searches})
+}
+
+func (h savedSearchesHandler) create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ss := &savedsearch.SavedSearch{UserID: userID}
+	req.apply(ss)
+	if err := h.store.Create(ss); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.JSON(w, r, ss)
+}
+
+func (h savedSearchesHandler) get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := savedSearchIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	ss, err := h.store.Get(userID, id)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, ss)
+}
+
+func (h savedSearchesHandler) update(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := savedSearchIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ss, err := h.store.Get(userID, id)
+	if err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	req.apply(ss)
+	if err := h.store.Update(ss); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, ss)
+}
+
+func (h savedSearchesHandler) delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := savedSearchIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(userID, id); err != nil {
+		h.renderError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h savedSearchesHandler) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr savedsearch.ValidationError
+	switch {
+	case errors.Is(err, savedsearch.ErrNotFound):
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "saved search not found")
+	case errors.As(err, &validationErr):
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, validationErr.Error())
+	default:
+		h.log.Error().Err(err).Msg("could not handle saved search")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not handle saved search")
+	}
+}
+
+func currentUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok || u.GetId().GetOpaqueId() == "" {
+		errorcode.AccessDenied.Render(w, r, http.StatusUnauthorized, "Unauthorized")
+		return "", false
+	}
+	return u.GetId().GetOpaqueId(), true
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request) (savedSearchRequest, bool) {
+	var req savedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid request body")
+		return req, false
+	}
+	return req, true
+}
+
+func savedSearchIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := url.PathUnescape(chi.URLParam(r, "savedSearchID"))
+	if err != nil || id == "" {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid saved search id")
+		return "", false
+	}
+	return id, true
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -15,6 +16,7 @@ type Options struct {
 	Config         *config.Config
 	JWTSecret      string
 	TracerProvider trace.TracerProvider
+	SavedSearches  *savedsearch.Store
 }
 
 func newOptions(opts ...Option) Options {
@@ -54,3 +56,10 @@ func TracerProvider(val trace.TracerProvider) Option {
 		o.TracerProvider = val
 	}
 }
+
+// SavedSearches provides a function to set the SavedSearches option
+func SavedSearches(val *savedsearch.Store) Option {
+	return func(o *Options) {
+		o.SavedSearches = val
+	}
+}
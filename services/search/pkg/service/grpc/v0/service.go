@@ -30,6 +30,7 @@ import (
 	"github.com/owncloud/ocis/v2/services/search/pkg/config"
 	"github.com/owncloud/ocis/v2/services/search/pkg/content"
 	"github.com/owncloud/ocis/v2/services/search/pkg/engine"
+	"github.com/owncloud/ocis/v2/services/search/pkg/savedsearch"
 	"github.com/owncloud/ocis/v2/services/search/pkg/search"
 )
 
@@ -62,6 +63,10 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, func(), error)
 		if extractor, err = content.NewBasicExtractor(logger); err != nil {
 			return nil, teardown, err
 		}
+	case "native":
+		if extractor, err = content.NewNativeExtractor(selector, logger, cfg); err != nil {
+			return nil, teardown, err
+		}
 	case "tika":
 		if extractor, err = content.NewTikaExtractor(selector, logger, cfg); err != nil {
 			return nil, teardown, err
@@ -84,7 +89,21 @@ func NewHandler(opts ...Option) (searchsvc.SearchProviderHandler, func(), error)
 		return nil, teardown, err
 	}
 
-	ss := search.NewService(selector, eng, extractor, logger, cfg)
+	var listeners []search.IndexListener
+	if options.SavedSearches != nil && !cfg.SavedSearches.DisableAlerts {
+		alerter := savedsearch.NewAlerter(options.SavedSearches, eng, bus, savedsearch.SpaceMembers(selector), logger)
+		listeners = append(listeners, alerter)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go alerter.Run(ctx)
+		engTeardown := teardown
+		teardown = func() {
+			cancel()
+			engTeardown()
+		}
+	}
+
+	ss := search.NewService(selector, eng, extractor, logger, cfg, listeners...)
 
 	// setup event handling
 	if err := search.HandleEvents(ss, bus, logger, cfg); err != nil {
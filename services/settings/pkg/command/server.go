@@ -64,7 +64,7 @@ func Server(cfg *config.Config) *cli.Command {
 				http.Context(ctx),
 				http.Config(cfg),
 				http.Metrics(mtrcs),
-				http.ServiceHandler(handle),
+				http.ServiceHandler(svc.NewWebhookSecretFilter(handle)),
 				http.TraceProvider(traceProvider),
 			)
 			if err != nil {
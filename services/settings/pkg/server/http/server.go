@@ -70,6 +70,7 @@ func Server(opts ...Option) (ohttp.Service, error) {
 		settingssvc.RegisterValueServiceWeb(r, handle)
 		settingssvc.RegisterRoleServiceWeb(r, handle)
 		settingssvc.RegisterPermissionServiceWeb(r, handle)
+		r.Post("/api/v0/settings/webhook-register", handle.RegisterWebhook)
 	})
 
 	_ = chi.Walk(mux, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
@@ -253,6 +253,11 @@ func (g Service) SaveValue(ctx context.Context, req *settingssvc.SaveValueReques
 	if validationError := validateSaveValue(req); validationError != nil {
 		return merrors.BadRequest(g.id, "%s", validationError)
 	}
+	switch req.GetValue().GetSettingId() {
+	case defaults.SettingUUIDProfileWebhookURL, defaults.SettingUUIDProfileWebhookSecret:
+		// the webhook url is only set together with a new secret
+		return merrors.BadRequest(g.id, "the webhook url can only be set by registering the webhook")
+	}
 	r, err := g.manager.WriteValue(req.GetValue())
 	if err != nil {
 		return merrors.BadRequest(g.id, "%s", err)
@@ -707,7 +712,8 @@ func translateBundle(bundle *settingsmsg.Bundle, t l10n.OcisLocale) *settingsmsg
 			defaults.SettingUUIDProfileEventSpaceMembershipExpired,
 			defaults.SettingUUIDProfileEventSpaceDisabled,
 			defaults.SettingUUIDProfileEventSpaceDeleted,
-			defaults.SettingUUIDProfileEventPostprocessingStepFinished:
+			defaults.SettingUUIDProfileEventPostprocessingStepFinished,
+			defaults.SettingUUIDProfileWebhookURL:
 			// translate event names ('Share Received', 'Share Removed', ...)
 			set.DisplayName = t.Get(set.GetDisplayName())
 			// translate event descriptions ('Notify me when I receive a share', ...)
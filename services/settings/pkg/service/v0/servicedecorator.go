@@ -2,6 +2,7 @@ package svc
 
 import (
 	"context"
+	"slices"
 	"strings"
 
 	settingsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/settings/v0"
@@ -9,6 +10,7 @@ import (
 	"github.com/owncloud/ocis/v2/services/settings/pkg/config"
 	"github.com/owncloud/ocis/v2/services/settings/pkg/settings"
 	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+	merrors "go-micro.dev/v4/errors"
 )
 
 var _defaultLanguage = "en"
@@ -195,3 +197,45 @@ func getDefaultValueList() map[string]*settingsmsg.ValueWithIdentifier {
 		defaults.SettingUUIDProfileEmailSendingInterval:            nil,
 	}
 }
+
+// NewWebhookSecretFilter returns a decorator for the ServiceHandler of the http api which keeps the webhook
+// secrets from being read. The secret is only returned once, when the webhook is registered. The
+// notifications service reads it through the grpc api.
+func NewWebhookSecretFilter(serviceHandler settings.ServiceHandler) settings.ServiceHandler {
+	return &webhookSecretFilter{ServiceHandler: serviceHandler}
+}
+
+type webhookSecretFilter struct {
+	settings.ServiceHandler
+}
+
+// GetValue implements the ValueServiceHandler interface
+func (s *webhookSecretFilter) GetValue(ctx context.Context, req *settingssvc.GetValueRequest, res *settingssvc.GetValueResponse) error {
+	if err := s.ServiceHandler.GetValue(ctx, req, res); err != nil {
+		return err
+	}
+	if res.GetValue().GetValue().GetSettingId() == defaults.SettingUUIDProfileWebhookSecret {
+		res.Value = nil
+		return merrors.NotFound("ocis-settings", "value %s not found", req.GetId())
+	}
+	return nil
+}
+
+// GetValueByUniqueIdentifiers implements the ValueServiceHandler interface
+func (s *webhookSecretFilter) GetValueByUniqueIdentifiers(ctx context.Context, req *settingssvc.GetValueByUniqueIdentifiersRequest, res *settingssvc.GetValueResponse) error {
+	if req.GetSettingId() == defaults.SettingUUIDProfileWebhookSecret {
+		return merrors.NotFound("ocis-settings", "value of setting %s not found", req.GetSettingId())
+	}
+	return s.ServiceHandler.GetValueByUniqueIdentifiers(ctx, req, res)
+}
+
+// ListValues implements the ValueServiceHandler interface
+func (s *webhookSecretFilter) ListValues(ctx context.Context, req *settingssvc.ListValuesRequest, res *settingssvc.ListValuesResponse) error {
+	if err := s.ServiceHandler.ListValues(ctx, req, res); err != nil {
+		return err
+	}
+	res.Values = slices.DeleteFunc(res.Values, func(v *settingsmsg.ValueWithIdentifier) bool {
+		return v.GetValue().GetSettingId() == defaults.SettingUUIDProfileWebhookSecret
+	})
+	return nil
+}
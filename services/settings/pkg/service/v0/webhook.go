@@ -0,0 +1,106 @@
+package svc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	settingsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/settings/v0"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/settings"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+)
+
+// _webhookSecretSize is the number of random bytes of a webhook secret
+const _webhookSecretSize = 32
+
+// WebhookRegistration is the body of webhook registration requests and responses
+type WebhookRegistration struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// RegisterWebhook sets the notification webhook url of the current user. Every registration generates a
+// new secret, the notifications sent to the url are signed with it. The secret is only returned in the
+// response, it can't be read afterwards. An empty url removes the webhook.
+func (g Service) RegisterWebhook(w http.ResponseWriter, r *http.Request) {
+	accountID := getValidatedAccountUUID(r.Context(), "me")
+	if accountID == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reg WebhookRegistration
+	if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+		http.Error(w, "invalid webhook registration", http.StatusBadRequest)
+		return
+	}
+	reg.URL = strings.TrimSpace(reg.URL)
+	reg.Secret = ""
+	if reg.URL != "" {
+		if err := validateWebhookURL(reg.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		b := make([]byte, _webhookSecretSize)
+		if _, err := rand.Read(b); err != nil {
+			g.logger.Error().Err(err).Msg("could not generate webhook secret")
+			http.Error(w, "could not generate webhook secret", http.StatusInternalServerError)
+			return
+		}
+		reg.Secret = hex.EncodeToString(b)
+	}
+
+	// the secret is written first, the url is never delivered to without a secret
+	for _, v := range []struct{ settingID, value string }{
+		{defaults.SettingUUIDProfileWebhookSecret, reg.Secret},
+		{defaults.SettingUUIDProfileWebhookURL, reg.URL},
+	} {
+		if err := g.writeProfileString(accountID, v.settingID, v.value); err != nil {
+			g.logger.Error().Err(err).Str("settingId", v.settingID).Msg("could not register webhook")
+			http.Error(w, "could not register webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reg)
+}
+
+// writeProfileString sets the value of a string setting of the profile of a user
+func (g Service) writeProfileString(accountID, settingID, value string) error {
+	v, err := g.manager.ReadValueByUniqueIdentifiers(accountID, settingID)
+	switch {
+	case errors.Is(err, settings.ErrNotFound):
+		v = &settingsmsg.Value{
+			BundleId:    defaults.BundleUUIDProfile,
+			SettingId:   settingID,
+			AccountUuid: accountID,
+			Resource:    &settingsmsg.Resource{Type: settingsmsg.Resource_TYPE_USER},
+		}
+	case err != nil:
+		return err
+	}
+	v.Value = &settingsmsg.Value_StringValue{StringValue: value}
+	_, err = g.manager.WriteValue(v)
+	return err
+}
+
+func validateWebhookURL(target string) error {
+	u, err := url.Parse(target)
+	switch {
+	case err != nil:
+		return err
+	case len(target) > 2048:
+		return errors.New("webhook url is too long")
+	case u.Scheme != "http" && u.Scheme != "https":
+		return errors.New("webhook url must use http or https")
+	case u.Host == "":
+		return errors.New("webhook url must contain a host")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package svc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	settingsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/settings/v0"
+	v0 "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/settings"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/settings/mocks"
+	"github.com/owncloud/ocis/v2/services/settings/pkg/store/defaults"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterWebhook(t *testing.T) {
+	accountID := "61445573-4dbe-4d56-88dc-88ab47aceba7"
+	manager := &mocks.Manager{}
+	svc := Service{manager: manager, logger: log.NopLogger()}
+
+	written := map[string]*settingsmsg.Value{}
+	manager.On("ReadValueByUniqueIdentifiers", accountID, defaults.SettingUUIDProfileWebhookURL).Return(&settingsmsg.Value{
+		Id:          "00000000-0000-0000-0000-000000000001",
+		BundleId:    defaults.BundleUUIDProfile,
+		SettingId:   defaults.SettingUUIDProfileWebhookURL,
+		AccountUuid: accountID,
+	}, nil)
+	manager.On("ReadValueByUniqueIdentifiers", accountID, defaults.SettingUUIDProfileWebhookSecret).Return(nil, settings.ErrNotFound)
+	manager.On("WriteValue", mock.Anything).Run(func(args mock.Arguments) {
+		v := args.Get(0).(*settingsmsg.Value)
+		written[v.GetSettingId()] = v
+	}).Return(nil, nil)
+
+	register := func(body string) (*httptest.ResponseRecorder, WebhookRegistration) {
+		r := httptest.NewRequest(http.MethodPost, "/api/v0/settings/webhook-register", strings.NewReader(body)).WithContext(ctxWithUUID)
+		rr := httptest.NewRecorder()
+		svc.RegisterWebhook(rr, r)
+		var reg WebhookRegistration
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &reg))
+		}
+		return rr, reg
+	}
+
+	rr, reg := register(`{"url": "https://chat.example.com/hooks/1", "secret": "chosen"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "https://chat.example.com/hooks/1", reg.URL)
+	assert.Len(t, reg.Secret, 2*_webhookSecretSize)
+	assert.NotEqual(t, "chosen", reg.Secret)
+	assert.Equal(t, "00000000-0000-0000-0000-000000000001", written[defaults.SettingUUIDProfileWebhookURL].GetId())
+	assert.Equal(t, reg.URL, written[defaults.SettingUUIDProfileWebhookURL].GetStringValue())
+	assert.Equal(t, reg.Secret, written[defaults.SettingUUIDProfileWebhookSecret].GetStringValue())
+	assert.Equal(t, defaults.BundleUUIDProfile, written[defaults.SettingUUIDProfileWebhookSecret].GetBundleId())
+
+	_, again := register(`{"url": "https://chat.example.com/hooks/1"}`)
+	assert.NotEqual(t, reg.Secret, again.Secret)
+
+	rr, reg = register(`{"url": ""}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, reg.Secret)
+	assert.Empty(t, written[defaults.SettingUUIDProfileWebhookURL].GetStringValue())
+	assert.Empty(t, written[defaults.SettingUUIDProfileWebhookSecret].GetStringValue())
+
+	rr, _ = register(`{"url": "file:///etc/passwd"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v0/settings/webhook-register", strings.NewReader(`{"url": "https://chat.example.com"}`)).WithContext(emptyCtx)
+	rr = httptest.NewRecorder()
+	svc.RegisterWebhook(rr, r)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestSaveWebhookValue(t *testing.T) {
+	svc := Service{manager: &mocks.Manager{}}
+	for _, settingID := range []string{defaults.SettingUUIDProfileWebhookURL, defaults.SettingUUIDProfileWebhookSecret} {
+		err := svc.SaveValue(ctxWithUUID, &v0.SaveValueRequest{Value: &settingsmsg.Value{
+			BundleId:    defaults.BundleUUIDProfile,
+			SettingId:   settingID,
+			AccountUuid: "me",
+			Resource:    &settingsmsg.Resource{Type: settingsmsg.Resource_TYPE_USER},
+			Value:       &settingsmsg.Value_StringValue{StringValue: "https://chat.example.com"},
+		}}, &v0.SaveValueResponse{})
+		assert.Error(t, err, settingID)
+	}
+}
+
+func TestWebhookSecretFilter(t *testing.T) {
+	accountID := "61445573-4dbe-4d56-88dc-88ab47aceba7"
+	manager := &mocks.Manager{}
+	values := []*settingsmsg.Value{
+		{Id: "00000000-0000-0000-0000-000000000001", BundleId: defaults.BundleUUIDProfile, SettingId: defaults.SettingUUIDProfileWebhookURL, AccountUuid: accountID},
+		{Id: "00000000-0000-0000-0000-000000000002", BundleId: defaults.BundleUUIDProfile, SettingId: defaults.SettingUUIDProfileWebhookSecret, AccountUuid: accountID},
+	}
+	manager.On("ListValues", mock.Anything, accountID).Return(values, nil)
+	manager.On("ReadValue", values[1].GetId()).Return(values[1], nil)
+	manager.On("ReadBundle", defaults.BundleUUIDProfile).Return(&settingsmsg.Bundle{Name: "profile"}, nil)
+	manager.On("ReadSetting", mock.Anything).Return(&settingsmsg.Setting{}, nil)
+	filter := NewWebhookSecretFilter(Service{manager: manager})
+
+	list := &v0.ListValuesResponse{}
+	require.NoError(t, filter.ListValues(ctxWithUUID, &v0.ListValuesRequest{AccountUuid: "me"}, list))
+	require.Len(t, list.GetValues(), 1)
+	assert.Equal(t, defaults.SettingUUIDProfileWebhookURL, list.GetValues()[0].GetValue().GetSettingId())
+
+	assert.Error(t, filter.GetValue(ctxWithUUID, &v0.GetValueRequest{Id: values[1].GetId()}, &v0.GetValueResponse{}))
+	assert.Error(t, filter.GetValueByUniqueIdentifiers(ctxWithUUID, &v0.GetValueByUniqueIdentifiersRequest{
+		AccountUuid: "me",
+		SettingId:   defaults.SettingUUIDProfileWebhookSecret,
+	}, &v0.GetValueResponse{}))
+	manager.AssertNotCalled(t, "ReadValueByUniqueIdentifiers", mock.Anything, mock.Anything)
+}
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"errors"
+	"net/http"
 
 	cs3permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
 
@@ -28,6 +29,9 @@ type ServiceHandler interface {
 	settingssvc.RoleServiceHandler
 	settingssvc.PermissionServiceHandler
 	cs3permissions.PermissionsAPIServer
+
+	// RegisterWebhook sets the notification webhook of the current user and returns its new secret
+	RegisterWebhook(w http.ResponseWriter, r *http.Request)
 }
 
 // Manager combines service interfaces for abstraction of storage implementations
@@ -46,6 +46,10 @@ const (
 	SettingUUIDProfileEventSpaceDeleted = "094ceca9-5a00-40ba-bb1a-bbc7bccd39ee"
 	// SettingUUIDProfileEventPostprocessingStepFinished is the hardcoded setting UUID for the send in mail setting
 	SettingUUIDProfileEventPostprocessingStepFinished = "fe0a3011-d886-49c8-b797-33d02fa426ef"
+	// SettingUUIDProfileWebhookURL is the hardcoded setting UUID for the notification webhook setting
+	SettingUUIDProfileWebhookURL = "c53268b1-62b9-4b43-8c79-faff659a5c74"
+	// SettingUUIDProfileWebhookSecret is the hardcoded setting UUID for the secret the deliveries to the notification webhook are signed with
+	SettingUUIDProfileWebhookSecret = "f954c0c2-417d-48d0-bde6-1518b4abbf62"
 )
 
 // GenerateBundlesDefaultRoles bootstraps the default roles.
@@ -132,6 +136,7 @@ func generateBundleAdminRole() *settingsmsg.Bundle {
 			ProfileEventSpaceDisabledPermission(Own),
 			ProfileEventSpaceDeletedPermission(Own),
 			ProfileEventPostprocessingStepFinishedPermission(Own),
+			ProfileWebhookURLPermission(Own),
 			GroupManagementPermission(All),
 			LanguageManagementPermission(All),
 			ListFavoritesPermission(Own),
@@ -177,6 +182,7 @@ func generateBundleSpaceAdminRole() *settingsmsg.Bundle {
 			ProfileEventSpaceDisabledPermission(Own),
 			ProfileEventSpaceDeletedPermission(Own),
 			ProfileEventPostprocessingStepFinishedPermission(Own),
+			ProfileWebhookURLPermission(Own),
 			LanguageManagementPermission(Own),
 			ListFavoritesPermission(Own),
 			ListSpacesPermission(All),
@@ -216,6 +222,7 @@ func generateBundleUserRole() *settingsmsg.Bundle {
 			ProfileEventSpaceDisabledPermission(Own),
 			ProfileEventSpaceDeletedPermission(Own),
 			ProfileEventPostprocessingStepFinishedPermission(Own),
+			ProfileWebhookURLPermission(Own),
 			LanguageManagementPermission(Own),
 			ListFavoritesPermission(Own),
 			SelfManagementPermission(Own),
@@ -248,6 +255,7 @@ func generateBundleUserLightRole() *settingsmsg.Bundle {
 			ProfileEventSpaceDisabledPermission(Own),
 			ProfileEventSpaceDeletedPermission(Own),
 			ProfileEventPostprocessingStepFinishedPermission(Own),
+			ProfileWebhookURLPermission(Own),
 			LanguageManagementPermission(Own),
 		},
 	}
@@ -457,6 +465,35 @@ func generateBundleProfileRequest() *settingsmsg.Bundle {
 					},
 				},
 			},
+			{
+				Id:          SettingUUIDProfileWebhookURL,
+				Name:        "webhook-url",
+				DisplayName: TemplateWebhookURL,
+				Description: TemplateWebhookURLDescription,
+				Resource: &settingsmsg.Resource{
+					Type: settingsmsg.Resource_TYPE_USER,
+				},
+				Value: &settingsmsg.Setting_StringValue{
+					StringValue: &settingsmsg.String{
+						MaxLength:   2048,
+						Placeholder: "https://chat.example.com/hooks/...",
+					},
+				},
+			},
+			{
+				// the secret is generated when the webhook url is registered, it is never returned to clients
+				Id:          SettingUUIDProfileWebhookSecret,
+				Name:        "webhook-secret",
+				DisplayName: "Webhook Secret",
+				Resource: &settingsmsg.Resource{
+					Type: settingsmsg.Resource_TYPE_USER,
+				},
+				Value: &settingsmsg.Setting_StringValue{
+					StringValue: &settingsmsg.String{
+						MaxLength: 64,
+					},
+				},
+			},
 		},
 	}
 }
@@ -409,6 +409,25 @@ func ProfileEventPostprocessingStepFinishedPermission(c settingsmsg.Permission_C
 	}
 }
 
+// ProfileWebhookURLPermission is the permission to set the notification webhook
+func ProfileWebhookURLPermission(c settingsmsg.Permission_Constraint) *settingsmsg.Setting {
+	return &settingsmsg.Setting{
+		Id:          "dcaa7824-a923-43a9-acde-10dd6b7c2cfc",
+		Name:        "WebhookURL.ReadWrite",
+		DisplayName: "Notification Webhook",
+		Resource: &settingsmsg.Resource{
+			Type: settingsmsg.Resource_TYPE_SETTING,
+			Id:   SettingUUIDProfileWebhookURL,
+		},
+		Value: &settingsmsg.Setting_PermissionValue{
+			PermissionValue: &settingsmsg.Permission{
+				Operation:  settingsmsg.Permission_OPERATION_READWRITE,
+				Constraint: c,
+			},
+		},
+	}
+}
+
 // GroupManagementPermission is the permission to manage groups
 func GroupManagementPermission(c settingsmsg.Permission_Constraint) *settingsmsg.Setting {
 	return &settingsmsg.Setting{
@@ -52,4 +52,8 @@ var (
 	TemplateIntervalWeekly = l10n.Template("Weekly")
 	// translation for the 'never' email interval option
 	TemplateIntervalNever = l10n.Template("Never")
+	// name of the notification option 'Webhook URL'
+	TemplateWebhookURL = l10n.Template("Webhook URL")
+	// description of the notification option 'Webhook URL'
+	TemplateWebhookURLDescription = l10n.Template("Also deliver my notifications to this URL, e.g. an incoming webhook of a chat system")
 )
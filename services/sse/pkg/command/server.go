@@ -2,29 +2,30 @@ package command
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"os/signal"
+	"time"
 
-	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/events/stream"
+	"github.com/owncloud/reva/v2/pkg/store"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/ocis-pkg/generators"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
 	"github.com/owncloud/ocis/v2/ocis-pkg/runner"
 	"github.com/owncloud/ocis/v2/ocis-pkg/tracing"
 	"github.com/owncloud/ocis/v2/services/sse/pkg/config"
 	"github.com/owncloud/ocis/v2/services/sse/pkg/config/parser"
 	"github.com/owncloud/ocis/v2/services/sse/pkg/server/debug"
-	"github.com/owncloud/ocis/v2/services/sse/pkg/server/http"
+	sseHTTP "github.com/owncloud/ocis/v2/services/sse/pkg/server/http"
 )
 
-// all events we care about
-var _registeredEvents = []events.Unmarshaller{
-	events.SendSSE{},
-}
-
 // Server is the entrypoint for the server command.
 func Server(cfg *config.Config) *cli.Command {
 	return &cli.Command{
@@ -63,13 +64,63 @@ func Server(cfg *config.Config) *cli.Command {
 					return err
 				}
 
-				server, err := http.Server(
-					http.Logger(logger),
-					http.Context(ctx),
-					http.Config(cfg),
-					http.Consumer(natsStream),
-					http.RegisteredEvents(_registeredEvents),
-					http.TracerProvider(tracerProvider),
+				st := store.Create(
+					store.Store(cfg.Store.Store),
+					store.TTL(cfg.Store.TTL),
+					microstore.Nodes(cfg.Store.Nodes...),
+					microstore.Database(cfg.Store.Database),
+					microstore.Table(cfg.Store.Table),
+					store.Authentication(cfg.Store.AuthUsername, cfg.Store.AuthPassword),
+					store.TLS(cfg.Store.EnableTLS, cfg.Store.TLSInsecure, cfg.Store.TLSRootCACertificate),
+				)
+				cas, err := casstore.New(st, casstore.Options{
+					Store:                cfg.Store.Store,
+					Nodes:                cfg.Store.Nodes,
+					Database:             cfg.Store.Database,
+					Table:                cfg.Store.Table,
+					TTL:                  cfg.Store.TTL,
+					AuthUsername:         cfg.Store.AuthUsername,
+					AuthPassword:         cfg.Store.AuthPassword,
+					EnableTLS:            cfg.Store.EnableTLS,
+					TLSInsecure:          cfg.Store.TLSInsecure,
+					TLSRootCACertificate: cfg.Store.TLSRootCACertificate,
+					ConnectionName:       generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeKeyValue),
+				})
+				if err != nil {
+					return err
+				}
+
+				oidcClient := oidc.NewOIDCClient(
+					oidc.WithAccessTokenVerifyMethod(config.AccessTokenVerificationJWT),
+					oidc.WithAccessTokenVerifyAudiences(cfg.OIDC.AccessTokenVerifyAud),
+					oidc.WithLogger(logger),
+					oidc.WithHTTPClient(&http.Client{
+						Transport: &http.Transport{
+							Proxy: http.ProxyFromEnvironment,
+							TLSClientConfig: &tls.Config{
+								MinVersion:         tls.VersionTLS12,
+								InsecureSkipVerify: cfg.OIDC.Insecure, //nolint:gosec
+							},
+						},
+						Timeout: time.Second * 10,
+					}),
+					oidc.WithOidcIssuer(cfg.OIDC.Issuer),
+					oidc.WithJWKSOptions(oidc.JWKSOptions{
+						RefreshInterval:   60, // minutes
+						RefreshRateLimit:  60, // seconds
+						RefreshTimeout:    10, // seconds
+						RefreshUnknownKID: true,
+					}),
+				)
+
+				server, err := sseHTTP.Server(
+					sseHTTP.Logger(logger),
+					sseHTTP.Context(ctx),
+					sseHTTP.Config(cfg),
+					sseHTTP.Consumer(natsStream),
+					sseHTTP.Store(cas),
+					sseHTTP.OIDCClient(oidcClient),
+					sseHTTP.TracerProvider(tracerProvider),
 				)
 				if err != nil {
 					return err
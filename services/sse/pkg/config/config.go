@@ -18,6 +18,13 @@ type Config struct {
 	Service           Service       `yaml:"-"`
 	KeepAliveInterval time.Duration `yaml:"keepalive_interval" env:"SSE_KEEPALIVE_INTERVAL" desc:"To prevent intermediate proxies from closing the SSE connection, send periodic SSE comments to keep it open." introductionVersion:"7.0.0"`
 
+	ReplayBufferSize int   `yaml:"replay_buffer_size" env:"SSE_REPLAY_BUFFER_SIZE" desc:"The maximum number of events kept per user to replay them to clients which reconnect with a 'Last-Event-ID' header. Set to 0 to disable the replay." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Store            Store `yaml:"store"`
+
+	OIDC          OIDC   `yaml:"oidc"`
+	UserOIDCClaim string `yaml:"user_oidc_claim" env:"SSE_USER_OIDC_CLAIM" desc:"The name of the OpenID Connect claim which identifies the user when a websocket client refreshes its access token. It must match the 'SSE_USER_CS3_CLAIM' of the user the connection was opened for." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	UserCS3Claim  string `yaml:"user_cs3_claim" env:"SSE_USER_CS3_CLAIM" desc:"The name of the CS3 user attribute (claim) that is compared to the 'SSE_USER_OIDC_CLAIM'. Supported values are 'username', 'mail' and 'userid'." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+
 	Events       Events
 	HTTP         HTTP          `yaml:"http"`
 	TokenManager *TokenManager `yaml:"token_manager"`
@@ -46,6 +53,30 @@ type Debug struct {
 	Zpages bool   `yaml:"zpages" env:"SSE_DEBUG_ZPAGES" desc:"Enables zpages, which can be used for collecting and viewing in-memory traces." introductionVersion:"5.0"`
 }
 
+// Store configures the store for the replay buffer, it must be shared by all instances of the sse service.
+type Store struct {
+	Store                string        `yaml:"store" env:"OCIS_PERSISTENT_STORE;SSE_STORE" desc:"The type of the store. Supported values are: 'memory', 'nats-js-kv', 'redis-sentinel', 'noop'. See the text description for details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Nodes                []string      `yaml:"nodes" env:"OCIS_PERSISTENT_STORE_NODES;SSE_STORE_NODES" desc:"A list of nodes to access the configured store. This has no effect when 'memory' store is configured. Note that the behaviour how nodes are used is dependent on the library of the configured store. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Database             string        `yaml:"database" env:"SSE_STORE_DATABASE" desc:"The database name the configured store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Table                string        `yaml:"table" env:"SSE_STORE_TABLE" desc:"The database table the store should use." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TTL                  time.Duration `yaml:"ttl" env:"SSE_STORE_TTL" desc:"Time to live for events in the replay buffer. Older events are not replayed. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthUsername         string        `yaml:"username" env:"OCIS_PERSISTENT_STORE_AUTH_USERNAME;SSE_STORE_AUTH_USERNAME" desc:"The username to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthPassword         string        `yaml:"password" env:"OCIS_PERSISTENT_STORE_AUTH_PASSWORD;SSE_STORE_AUTH_PASSWORD" desc:"The password to authenticate with the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	EnableTLS            bool          `yaml:"enable_tls" env:"OCIS_PERSISTENT_STORE_ENABLE_TLS;SSE_STORE_ENABLE_TLS" desc:"Activate TLS for the connection to the store. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSInsecure          bool          `yaml:"tls_insecure" env:"OCIS_PERSISTENT_STORE_TLS_INSECURE;SSE_STORE_TLS_INSECURE" desc:"Disable TLS certificate verification for the store connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSRootCACertificate string        `yaml:"tls_root_ca_certificate" env:"OCIS_PERSISTENT_STORE_TLS_ROOT_CA_CERTIFICATE;SSE_STORE_TLS_ROOT_CA_CERTIFICATE" desc:"Path to the PEM-encoded root CA certificate for the store TLS connection. Only applies when store type 'nats-js-kv' is configured." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// AccessTokenVerificationJWT verifies access tokens as jwt with the keys the IDP publishes
+const AccessTokenVerificationJWT = "jwt"
+
+// OIDC configures the verification of the access tokens websocket clients send to refresh their authentication.
+type OIDC struct {
+	Issuer               string   `yaml:"issuer" env:"OCIS_URL;OCIS_OIDC_ISSUER;SSE_OIDC_ISSUER" desc:"URL of the OIDC issuer. It defaults to URL of the builtin IDP." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Insecure             bool     `yaml:"insecure" env:"OCIS_INSECURE;SSE_OIDC_INSECURE" desc:"Disable TLS certificate validation for connections to the IDP. Note that this is not recommended for production environments." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AccessTokenVerifyAud []string `yaml:"access_token_verify_aud" env:"SSE_OIDC_ACCESS_TOKEN_VERIFY_AUD" desc:"A list of accepted audiences for OIDC access tokens, usually the OIDC client IDs used to access ownCloud. When set, an access token is only accepted if one of these values is present in its 'aud' claim or matches its 'azp' claim. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
 // Events combines the configuration options for the event bus.
 type Events struct {
 	Endpoint             string `yaml:"endpoint" env:"OCIS_EVENTS_ENDPOINT;SSE_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture." introductionVersion:"5.0"`
@@ -2,6 +2,7 @@ package defaults
 
 import (
 	"strings"
+	"time"
 
 	"github.com/owncloud/ocis/v2/services/sse/pkg/config"
 )
@@ -24,6 +25,19 @@ func DefaultConfig() *config.Config {
 		Service: config.Service{
 			Name: "sse",
 		},
+		ReplayBufferSize: 100,
+		Store: config.Store{
+			Store:    "nats-js-kv",
+			Nodes:    []string{"127.0.0.1:9233"},
+			Database: "sse",
+			Table:    "",
+			TTL:      time.Hour,
+		},
+		OIDC: config.OIDC{
+			Issuer: "https://localhost:9200",
+		},
+		UserOIDCClaim: "preferred_username",
+		UserCS3Claim:  "username",
 		Events: config.Events{
 			Endpoint: "127.0.0.1:9233",
 			Cluster:  "ocis-cluster",
@@ -3,7 +3,9 @@ package http
 import (
 	"context"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
 	"github.com/owncloud/ocis/v2/services/sse/pkg/config"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"go.opentelemetry.io/otel/trace"
@@ -14,12 +16,13 @@ type Option func(o *Options)
 
 // Options defines the available options for this package.
 type Options struct {
-	Logger           log.Logger
-	Context          context.Context
-	Config           *config.Config
-	Consumer         events.Consumer
-	RegisteredEvents []events.Unmarshaller
-	TracerProvider   trace.TracerProvider
+	Logger         log.Logger
+	Context        context.Context
+	Config         *config.Config
+	Consumer       events.Consumer
+	Store          casstore.Store
+	OIDCClient     oidc.OIDCClient
+	TracerProvider trace.TracerProvider
 }
 
 // newOptions initializes the available default options.
@@ -61,10 +64,17 @@ func Consumer(consumer events.Consumer) Option {
 	}
 }
 
-// RegisteredEvents provides a function to register events
-func RegisteredEvents(evs []events.Unmarshaller) Option {
+// Store provides a function to set the store of the replay buffer
+func Store(store casstore.Store) Option {
 	return func(o *Options) {
-		o.RegisteredEvents = evs
+		o.Store = store
+	}
+}
+
+// OIDCClient provides a function to set the client verifying the access tokens of websocket clients
+func OIDCClient(val oidc.OIDCClient) Option {
+	return func(o *Options) {
+		o.OIDCClient = val
 	}
 }
 
@@ -16,6 +16,7 @@ import (
 	svc "github.com/owncloud/ocis/v2/services/sse/pkg/service"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"go-micro.dev/v4"
+	microevents "go-micro.dev/v4/events"
 )
 
 // Service is the service interface
@@ -68,12 +69,13 @@ func Server(opts ...Option) (http.Service, error) {
 	mux := chi.NewMux()
 	mux.Use(middlewares...)
 
-	ch, err := events.Consume(options.Consumer, "sse-"+uuid.New().String(), options.RegisteredEvents...)
+	// every instance needs to receive all events, so each one consumes with its own group
+	ch, err := options.Consumer.Consume(events.MainQueueName, microevents.WithGroup("sse-"+uuid.New().String()))
 	if err != nil {
 		return http.Service{}, err
 	}
 
-	handle, err := svc.NewSSE(options.Config, options.Logger, ch, mux)
+	handle, err := svc.NewSSE(options.Config, options.Logger, ch, options.Store, options.OIDCClient, mux)
 	if err != nil {
 		return http.Service{}, err
 	}
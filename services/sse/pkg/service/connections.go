@@ -0,0 +1,158 @@
+package service
+
+import (
+	"sync"
+)
+
+// connection is an open connection of a user, the events are passed to send
+type connection struct {
+	mu     sync.Mutex
+	id     string
+	uid    string
+	send   func(ev Event)
+	filter filter
+	// lastID is the id of the last event sent to the connection
+	lastID uint64
+	// ready is set when the missed events have been replayed, events received
+	// until then are pending
+	ready   bool
+	pending []Event
+}
+
+// deliver sends an event to the connection unless it was sent already or does not match the filter.
+// The connection must be locked.
+func (c *connection) deliver(ev Event) {
+	if ev.ID <= c.lastID || !c.filter.match(ev) {
+		return
+	}
+	c.send(ev)
+	c.lastID = ev.ID
+}
+
+// connections keeps track of the open connections of the users
+type connections struct {
+	mu     sync.RWMutex
+	byID   map[string]*connection
+	byUser map[string]map[*connection]struct{}
+}
+
+func newConnections() *connections {
+	return &connections{
+		byID:   map[string]*connection{},
+		byUser: map[string]map[*connection]struct{}{},
+	}
+}
+
+func (cs *connections) add(c *connection) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	cs.byID[c.id] = c
+	if cs.byUser[c.uid] == nil {
+		cs.byUser[c.uid] = map[*connection]struct{}{}
+	}
+	cs.byUser[c.uid][c] = struct{}{}
+}
+
+func (cs *connections) remove(c *connection) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	delete(cs.byID, c.id)
+	delete(cs.byUser[c.uid], c)
+	if len(cs.byUser[c.uid]) == 0 {
+		delete(cs.byUser, c.uid)
+	}
+}
+
+func (cs *connections) get(id string) *connection {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.byID[id]
+}
+
+func (cs *connections) ofUser(uid string) []*connection {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	conns := make([]*connection, 0, len(cs.byUser[uid]))
+	for c := range cs.byUser[uid] {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// publish sends an event to all connections of a user
+func (cs *connections) publish(uid string, ev Event) {
+	for _, c := range cs.ofUser(uid) {
+		c.mu.Lock()
+		if c.ready {
+			c.deliver(ev)
+		} else {
+			c.pending = append(c.pending, ev)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// replay sends the events a connection missed and the events received in the meantime, after that
+// the connection receives the events as they come in
+func (cs *connections) replay(id string, buffer replayBuffer) error {
+	c := cs.get(id)
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer func() {
+		for _, ev := range c.pending {
+			c.deliver(ev)
+		}
+		c.pending = nil
+		c.ready = true
+	}()
+
+	// new connections don't get old events
+	if c.lastID == 0 {
+		return nil
+	}
+	evs, err := buffer.Since(c.uid, c.lastID)
+	if err != nil {
+		return err
+	}
+	for _, ev := range evs {
+		c.deliver(ev)
+	}
+	return nil
+}
+
+// subscribe restricts the connection to events of the given spaces in addition to the spaces it is
+// subscribed to already, it returns the spaces the connection is subscribed to
+func (c *connection) subscribe(spaces []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.filter.spaces == nil {
+		c.filter.spaces = map[string]struct{}{}
+	}
+	for _, s := range spaces {
+		c.filter.spaces[s] = struct{}{}
+	}
+	return c.filter.spaceList()
+}
+
+// unsubscribe removes spaces the connection subscribed to, it returns the spaces the connection is still
+// subscribed to. A connection without subscriptions receives the events of all spaces.
+func (c *connection) unsubscribe(spaces []string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range spaces {
+		delete(c.filter.spaces, s)
+	}
+	if len(c.filter.spaces) == 0 {
+		c.filter.spaces = nil
+	}
+	return c.filter.spaceList()
+}
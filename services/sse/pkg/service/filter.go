@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// filter restricts the events a connection receives to some event types and spaces, nil means
+// no restriction. Events which don't belong to a space, like notifications, are not filtered by space.
+type filter struct {
+	types  map[string]struct{}
+	spaces map[string]struct{}
+}
+
+// parseFilter reads the filter from the 'types' and 'spaces' query parameters, both take
+// comma separated lists and can be repeated
+func parseFilter(q url.Values) filter {
+	return filter{
+		types:  parseList(q["types"]),
+		spaces: parseList(q["spaces"]),
+	}
+}
+
+func parseList(values []string) map[string]struct{} {
+	var m map[string]struct{}
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				if m == nil {
+					m = map[string]struct{}{}
+				}
+				m[item] = struct{}{}
+			}
+		}
+	}
+	return m
+}
+
+func (f filter) match(ev Event) bool {
+	if f.types != nil {
+		if _, ok := f.types[ev.Type]; !ok {
+			return false
+		}
+	}
+	if f.spaces != nil && ev.SpaceID != "" {
+		if _, ok := f.spaces[ev.SpaceID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// spaceList returns the spaces of the filter sorted, nil means all spaces
+func (f filter) spaceList() []string {
+	if f.spaces == nil {
+		return nil
+	}
+	spaces := make([]string, 0, len(f.spaces))
+	for s := range f.spaces {
+		spaces = append(spaces, s)
+	}
+	slices.Sort(spaces)
+	return spaces
+}
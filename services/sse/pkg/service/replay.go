@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
+)
+
+// _maxConflictRetries is the number of times an event is added again after the record was updated by another instance
+const _maxConflictRetries = 10
+
+// Event is an event which is sent to the clients of a user. The ID is the sequence number of the event in
+// the replay buffer of the user, all instances of the sse service agree on it.
+type Event struct {
+	ID      uint64 `json:"id"`
+	Type    string `json:"type"`
+	Data    []byte `json:"data"`
+	SpaceID string `json:"spaceid,omitempty"`
+}
+
+// NewEvent creates an event, the space id is taken from the message if it has one
+func NewEvent(id uint64, typ string, data []byte) Event {
+	ev := Event{ID: id, Type: typ, Data: data}
+
+	var msg struct {
+		SpaceID string `json:"spaceid"`
+	}
+	if json.Unmarshal(data, &msg) == nil {
+		ev.SpaceID = msg.SpaceID
+	}
+	return ev
+}
+
+// replayBuffer keeps the last events of every user in one record of the store, so they can be replayed to
+// clients which reconnect after they missed some events. The events of a user are numbered by a sequence,
+// the record keeps it with the events. All instances of the sse service add the same events in the same
+// order, an event which was added by another instance already keeps its id. The record is only written if
+// it was not updated since it was read, otherwise the event is added again.
+type replayBuffer struct {
+	store casstore.Store
+	size  int
+	ttl   time.Duration
+}
+
+// replayRecord is the record of a user in the store
+type replayRecord struct {
+	// Seq is the id of the last event of the user
+	Seq    uint64        `json:"seq"`
+	Events []replayEntry `json:"events"`
+}
+
+type replayEntry struct {
+	Event
+	// Origin is the id of the event on the bus, it identifies the event on all instances
+	Origin string    `json:"origin"`
+	Time   time.Time `json:"time"`
+}
+
+// Add adds an event to the buffer of a user and returns it with the next id of the user, the oldest
+// events are dropped when the buffer is full. Without buffer the event keeps its id.
+func (b replayBuffer) Add(uid, origin string, ev Event) (Event, error) {
+	if b.size <= 0 {
+		return ev, nil
+	}
+
+	for i := 0; ; i++ {
+		added, err := b.add(uid, origin, ev)
+		if !errors.Is(err, casstore.ErrConflict) {
+			return added, err
+		}
+		if i == _maxConflictRetries {
+			return ev, fmt.Errorf("could not add event for user %s: %w", uid, err)
+		}
+	}
+}
+
+// add adds an event to the record of a user, casstore.ErrConflict is returned if the record was updated since it was read
+func (b replayBuffer) add(uid, origin string, ev Event) (Event, error) {
+	r, rev, err := b.read(uid)
+	if err != nil {
+		return ev, err
+	}
+	for _, e := range r.Events {
+		if e.Origin == origin {
+			return e.Event, nil
+		}
+	}
+
+	now := time.Now()
+	if r.Seq == 0 {
+		// a new sequence starts at the current time, so the ids keep increasing when the record
+		// of an inactive user expired
+		r.Seq = uint64(now.UnixNano())
+	}
+	r.Seq++
+	ev.ID = r.Seq
+	r.Events = append(r.Events, replayEntry{Event: ev, Origin: origin, Time: now})
+	if len(r.Events) > b.size {
+		r.Events = r.Events[len(r.Events)-b.size:]
+	}
+
+	value, err := json.Marshal(r)
+	if err != nil {
+		return ev, err
+	}
+	if rev == 0 {
+		return ev, b.store.Create(uid, value)
+	}
+	return ev, b.store.Update(uid, value, rev)
+}
+
+// Since returns the buffered events of a user which are newer than the given event id, the oldest event first
+func (b replayBuffer) Since(uid string, id uint64) ([]Event, error) {
+	if b.size <= 0 {
+		return nil, nil
+	}
+
+	r, _, err := b.read(uid)
+	if err != nil {
+		return nil, err
+	}
+	evs := make([]Event, 0, len(r.Events))
+	for _, e := range r.Events {
+		if e.ID <= id || (b.ttl > 0 && time.Since(e.Time) > b.ttl) {
+			continue
+		}
+		evs = append(evs, e.Event)
+	}
+	return evs, nil
+}
+
+// read returns the record of a user and its revision, an empty record and the revision 0 if the user has none
+func (b replayBuffer) read(uid string) (replayRecord, uint64, error) {
+	var r replayRecord
+	value, rev, err := b.store.Read(uid)
+	switch {
+	case errors.Is(err, casstore.ErrNotFound):
+		return r, 0, nil
+	case err != nil:
+		return r, 0, err
+	}
+	return r, rev, json.Unmarshal(value, &r)
+}
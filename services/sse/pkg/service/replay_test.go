@@ -0,0 +1,171 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
+)
+
+// newStore returns a store of the replay buffer which keeps the records in the given go-micro store
+func newStore(t *testing.T, st microstore.Store) casstore.Store {
+	cas, err := casstore.New(st, casstore.Options{})
+	require.NoError(t, err)
+	return cas
+}
+
+// interceptStore calls intercept once after reading a record
+type interceptStore struct {
+	casstore.Store
+	intercept func()
+}
+
+func (s *interceptStore) Read(key string) ([]byte, uint64, error) {
+	value, rev, err := s.Store.Read(key)
+	if f := s.intercept; f != nil {
+		s.intercept = nil
+		f()
+	}
+	return value, rev, err
+}
+
+func TestReplayBuffer(t *testing.T) {
+	buffer := replayBuffer{store: newStore(t, microstore.NewMemoryStore()), size: 2}
+
+	add := func(uid, origin string) uint64 {
+		ev, err := buffer.Add(uid, origin, NewEvent(0, origin, []byte(`{}`)))
+		require.NoError(t, err)
+		return ev.ID
+	}
+	a := add("alice", "a")
+	require.Equal(t, a, add("alice", "a"), "events added by another instance keep their id")
+	b := add("alice", "b")
+	c := add("alice", "c")
+	require.Equal(t, []uint64{a + 1, a + 2}, []uint64{b, c})
+	bob := add("bob", "a")
+
+	evs, err := buffer.Since("alice", 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{b, c}, eventIDs(evs))
+
+	evs, err = buffer.Since("alice", b)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{c}, eventIDs(evs))
+
+	evs, err = buffer.Since("bob", 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{bob}, eventIDs(evs))
+}
+
+func TestReplayBufferTTL(t *testing.T) {
+	store := microstore.NewMemoryStore()
+	buffer := replayBuffer{store: newStore(t, store), size: 10, ttl: time.Hour}
+	old, err := buffer.Add("alice", "a", NewEvent(0, "a", []byte(`{}`)))
+	require.NoError(t, err)
+
+	// the record is refreshed by every event, old events are skipped
+	r, _, err := buffer.read("alice")
+	require.NoError(t, err)
+	r.Events[0].Time = time.Now().Add(-2 * time.Hour)
+	value, err := json.Marshal(r)
+	require.NoError(t, err)
+	require.NoError(t, buffer.store.Put("alice", value))
+	ev, err := buffer.Add("alice", "b", NewEvent(0, "b", []byte(`{}`)))
+	require.NoError(t, err)
+
+	evs, err := buffer.Since("alice", 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{ev.ID}, eventIDs(evs))
+
+	// a new sequence continues after the ids of an expired one
+	require.NoError(t, store.Delete("alice"))
+	ev, err = buffer.Add("alice", "c", NewEvent(0, "c", []byte(`{}`)))
+	require.NoError(t, err)
+	require.Greater(t, ev.ID, old.ID)
+}
+
+func TestReplayBufferAddedByAnotherInstance(t *testing.T) {
+	sto := &interceptStore{Store: newStore(t, microstore.NewMemoryStore())}
+	other := replayBuffer{store: sto, size: 10}
+	buffer := replayBuffer{store: sto, size: 10}
+	a, err := buffer.Add("alice", "a", NewEvent(0, "a", []byte(`{}`)))
+	require.NoError(t, err)
+
+	// the other instance adds its event after the record was read for the next one
+	var b Event
+	sto.intercept = func() {
+		b, err = other.Add("alice", "b", NewEvent(0, "b", []byte(`{}`)))
+		require.NoError(t, err)
+	}
+	c, err := buffer.Add("alice", "c", NewEvent(0, "c", []byte(`{}`)))
+	require.NoError(t, err)
+	require.Equal(t, []uint64{a.ID + 1, a.ID + 2}, []uint64{b.ID, c.ID})
+
+	evs, err := buffer.Since("alice", 0)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{a.ID, b.ID, c.ID}, eventIDs(evs))
+}
+
+func TestConnectionsReplay(t *testing.T) {
+	buffer := replayBuffer{store: newStore(t, microstore.NewMemoryStore()), size: 10}
+	addEvents(t, buffer, "alice",
+		NewEvent(0, "a", []byte(`{"spaceid":"s1"}`)),
+		NewEvent(0, "a", []byte(`{"spaceid":"s2"}`)),
+		NewEvent(0, "b", []byte(`{"spaceid":"s1"}`)),
+	)
+
+	var sent []uint64
+	cs := newConnections()
+	cs.add(&connection{
+		id:     "alice/1",
+		uid:    "alice",
+		filter: filter{spaces: map[string]struct{}{"s1": {}}},
+		lastID: 1,
+		send: func(ev Event) {
+			sent = append(sent, ev.ID)
+		},
+	})
+
+	// events received before the replay are pending, the replay sends them after the missed ones
+	cs.publish("alice", NewEvent(3, "b", []byte(`{"spaceid":"s1"}`)))
+	cs.publish("alice", NewEvent(4, "c", []byte(`{"spaceid":"s1"}`)))
+	cs.publish("alice", NewEvent(5, "c", []byte(`{"spaceid":"s2"}`)))
+	require.Empty(t, sent)
+
+	require.NoError(t, cs.replay("alice/1", buffer))
+	require.Equal(t, []uint64{3, 4}, sent)
+
+	cs.publish("alice", NewEvent(6, "notification", []byte(`{}`)))
+	cs.publish("bob", NewEvent(7, "notification", []byte(`{}`)))
+	require.Equal(t, []uint64{3, 4, 6}, sent)
+}
+
+// addEvents adds events to the buffer of a user, the ids of the buffered events are the given ids
+func addEvents(t *testing.T, buffer replayBuffer, uid string, evs ...Event) {
+	for i, ev := range evs {
+		_, err := buffer.Add(uid, strconv.Itoa(i), ev)
+		require.NoError(t, err)
+	}
+	r, _, err := buffer.read(uid)
+	require.NoError(t, err)
+	for i := range r.Events {
+		r.Events[i].ID = uint64(i + 1)
+	}
+	r.Seq = uint64(len(r.Events))
+	value, err := json.Marshal(r)
+	require.NoError(t, err)
+	require.NoError(t, buffer.store.Put(uid, value))
+}
+
+func eventIDs(evs []Event) []uint64 {
+	ids := make([]uint64, 0, len(evs))
+	for _, ev := range evs {
+		ids = append(ids, ev.ID)
+	}
+	return ids
+}
@@ -2,38 +2,58 @@ package service
 
 import (
 	"net/http"
+	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/r3labs/sse/v2"
+	microevents "go-micro.dev/v4/events"
 
 	revactx "github.com/owncloud/reva/v2/pkg/ctx"
 	"github.com/owncloud/reva/v2/pkg/events"
 
+	"github.com/owncloud/ocis/v2/ocis-pkg/casstore"
 	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
 	"github.com/owncloud/ocis/v2/services/sse/pkg/config"
 )
 
+var _sendSSEType = reflect.TypeOf(events.SendSSE{}).String()
+
 // SSE defines implements the business logic for Service.
 type SSE struct {
-	c         *config.Config
-	l         log.Logger
-	m         *chi.Mux
-	sse       *sse.Server
-	evChannel <-chan events.Event
+	c           *config.Config
+	l           log.Logger
+	m           *chi.Mux
+	sse         *sse.Server
+	evChannel   <-chan microevents.Event
+	buffer      replayBuffer
+	connections *connections
+	oidc        oidc.OIDCClient
 }
 
-// NewSSE returns a service implementation for Service.
-func NewSSE(c *config.Config, l log.Logger, ch <-chan events.Event, mux *chi.Mux) (SSE, error) {
+// NewSSE returns a service implementation for Service. The events are consumed directly from the bus,
+// because their ids on the bus identify them in the replay buffer. The oidc client verifies the access tokens websocket
+// clients send to refresh their authentication.
+func NewSSE(c *config.Config, l log.Logger, ch <-chan microevents.Event, store casstore.Store, oidcClient oidc.OIDCClient, mux *chi.Mux) (SSE, error) {
 	s := SSE{
-		c:         c,
-		l:         l,
-		m:         mux,
-		sse:       sse.New(),
-		evChannel: ch,
+		c:           c,
+		l:           l,
+		m:           mux,
+		sse:         sse.New(),
+		evChannel:   ch,
+		buffer:      replayBuffer{store: store, size: c.ReplayBufferSize, ttl: c.Store.TTL},
+		connections: newConnections(),
+		oidc:        oidcClient,
 	}
+	s.sse.AutoReplay = false
+	s.sse.OnSubscribe = s.onSubscribe
+
 	mux.Route("/ocs/v2.php/apps/notifications/api/v1/notifications", func(r chi.Router) {
 		r.Get("/sse", s.HandleSSE)
+		r.Get("/ws", s.HandleWebSocket)
 	})
 
 	go s.ListenForEvents()
@@ -49,21 +69,42 @@ func (s SSE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // ListenForEvents listens for events
 func (s SSE) ListenForEvents() {
 	for e := range s.evChannel {
-		switch ev := e.Event.(type) {
-		default:
-			s.l.Error().Interface("event", ev).Msg("unhandled event")
-		case events.SendSSE:
-			for _, uid := range ev.UserIDs {
-				s.sse.Publish(uid, &sse.Event{
-					Event: []byte(ev.Type),
-					Data:  ev.Message,
-				})
+		if e.Metadata[events.MetadatakeyEventType] != _sendSSEType {
+			continue
+		}
+
+		ev, err := events.SendSSE{}.Unmarshal(e.Payload)
+		if err != nil {
+			s.l.Error().Err(err).Msg("sse: can't unmarshal event")
+			continue
+		}
+		sendSSE := ev.(events.SendSSE)
+
+		// without replay buffer the timestamp is the id of the event
+		ts := e.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		origin := e.ID
+		if origin == "" {
+			origin = strconv.FormatInt(ts.UnixNano(), 10)
+		}
+		event := NewEvent(uint64(ts.UnixNano()), sendSSE.Type, sendSSE.Message)
+
+		for _, uid := range sendSSE.UserIDs {
+			ev, err := s.buffer.Add(uid, origin, event)
+			if err != nil {
+				// the id of the event is unknown, sending it would break the replay of the connections
+				s.l.Error().Err(err).Str("type", event.Type).Str("user", uid).Msg("sse: could not add event to the replay buffer")
+				continue
 			}
+			s.connections.publish(uid, ev)
 		}
 	}
 }
 
-// HandleSSE is the GET handler for events
+// HandleSSE is the GET handler for events. Clients reconnecting with a 'Last-Event-ID' header get the
+// events they missed, the 'types' and 'spaces' query parameters restrict the events they receive.
 func (s SSE) HandleSSE(w http.ResponseWriter, r *http.Request) {
 	u, ok := revactx.ContextGetUser(r.Context())
 	if !ok {
@@ -79,25 +120,65 @@ func (s SSE) HandleSSE(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stream := s.sse.CreateStream(uid)
-	stream.AutoReplay = false
+	var lastID uint64
+	if h := r.Header.Get("Last-Event-ID"); h != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(h, 10, 64); err != nil {
+			s.l.Debug().Err(err).Str("lastEventID", h).Msg("sse: invalid Last-Event-ID")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// every connection gets its own stream, because the connections of a user can use different filters
+	streamID := uid + "/" + uuid.New().String()
+	s.sse.CreateStream(streamID)
+	defer s.sse.RemoveStream(streamID)
+
+	c := &connection{
+		id:     streamID,
+		uid:    uid,
+		filter: parseFilter(r.URL.Query()),
+		lastID: lastID,
+		send: func(ev Event) {
+			s.sse.Publish(streamID, &sse.Event{
+				ID:    []byte(strconv.FormatUint(ev.ID, 10)),
+				Event: []byte(ev.Type),
+				Data:  ev.Data,
+			})
+		},
+	}
+	s.connections.add(c)
+	defer s.connections.remove(c)
 
 	if s.c.KeepAliveInterval != 0 {
 		ticker := time.NewTicker(s.c.KeepAliveInterval)
 		defer ticker.Stop()
 		go func() {
-			for range ticker.C {
-				s.sse.Publish(uid, &sse.Event{
-					Comment: []byte("keepalive"),
-				})
+			for {
+				select {
+				case <-r.Context().Done():
+					return
+				case <-ticker.C:
+					s.sse.Publish(streamID, &sse.Event{
+						Comment: []byte("keepalive"),
+					})
+				}
 			}
 		}()
 	}
 
 	// add stream to URL
 	q := r.URL.Query()
-	q.Set("stream", uid)
+	q.Set("stream", streamID)
 	r.URL.RawQuery = q.Encode()
 
 	s.sse.ServeHTTP(w, r)
 }
+
+// onSubscribe replays the missed events as soon as the client is subscribed to its stream
+func (s SSE) onSubscribe(streamID string, _ *sse.Subscriber) {
+	if err := s.connections.replay(streamID, s.buffer); err != nil {
+		s.l.Error().Err(err).Str("stream", streamID).Msg("sse: could not replay events")
+	}
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"golang.org/x/oauth2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
+)
+
+const (
+	// _wsSendBuffer is the number of messages queued for a websocket client in addition to the replayed
+	// events, clients which fall further behind are disconnected and have to resume
+	_wsSendBuffer = 64
+	// _wsMaxMessageSize limits the size of the messages sent by websocket clients
+	_wsMaxMessageSize = 64 * 1024
+	_wsWriteTimeout   = 10 * time.Second
+)
+
+// the types of the websocket messages
+const (
+	// sent by the server
+	_wsEvent         = "event"
+	_wsSubscribed    = "subscribed"
+	_wsUnsubscribed  = "unsubscribed"
+	_wsAuthenticated = "authenticated"
+	_wsError         = "error"
+
+	// sent by the client
+	_wsSubscribe   = "subscribe"
+	_wsUnsubscribe = "unsubscribe"
+	_wsAuth        = "auth"
+)
+
+// wsMessage is a message exchanged with a websocket client
+type wsMessage struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Spaces  []string        `json:"spaces,omitempty"`
+	Token   string          `json:"token,omitempty"`
+	Expires *time.Time      `json:"expires,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+func eventMessage(ev Event) wsMessage {
+	data := json.RawMessage(ev.Data)
+	if !json.Valid(data) {
+		data, _ = json.Marshal(string(ev.Data))
+	}
+	return wsMessage{
+		Type:  _wsEvent,
+		ID:    strconv.FormatUint(ev.ID, 10),
+		Event: ev.Type,
+		Data:  data,
+	}
+}
+
+// HandleWebSocket is the GET handler for the websocket transport. It carries the same events as HandleSSE,
+// clients resume with the 'lastEventId' query parameter and (un)subscribe spaces or refresh their
+// authentication with messages on the open connection.
+func (s SSE) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	u, ok := revactx.ContextGetUser(r.Context())
+	if !ok {
+		s.l.Error().Msg("sse: no user in context")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	uid := u.GetId().GetOpaqueId()
+	if uid == "" {
+		s.l.Error().Msg("sse: user in context is broken")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var lastID uint64
+	if v := r.URL.Query().Get("lastEventId"); v != "" {
+		var err error
+		if lastID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			s.l.Debug().Err(err).Str("lastEventId", v).Msg("sse: invalid lastEventId")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// browsers send the cookies of the ocis domain with websocket requests of any site
+	if !s.allowedOrigin(r) {
+		s.l.Debug().Str("origin", r.Header.Get("Origin")).Msg("sse: websocket origin not allowed")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	// the request was authenticated already, the connection stays open until its token expires
+	expires := tokenExpiry(r.Header.Get(revactx.TokenHeader))
+
+	conn, rw, _, err := ws.UpgradeHTTP(r, w)
+	if err != nil {
+		// the upgrader responded already
+		s.l.Debug().Err(err).Msg("sse: websocket upgrade failed")
+		return
+	}
+
+	wc := &wsConn{
+		conn: conn,
+		rd: wsutil.Reader{
+			Source:       rw.Reader,
+			State:        ws.StateServerSide,
+			CheckUTF8:    true,
+			MaxFrameSize: _wsMaxMessageSize,
+		},
+		out:  make(chan wsMessage, _wsSendBuffer+s.c.ReplayBufferSize),
+		done: make(chan struct{}),
+	}
+	defer wc.close(ws.StatusGoingAway, "")
+	go wc.writeLoop(s.c.KeepAliveInterval)
+
+	c := &connection{
+		id:     uid + "/" + uuid.New().String(),
+		uid:    uid,
+		filter: parseFilter(r.URL.Query()),
+		lastID: lastID,
+		send: func(ev Event) {
+			wc.enqueue(eventMessage(ev))
+		},
+	}
+	s.connections.add(c)
+	defer s.connections.remove(c)
+
+	if err := s.connections.replay(c.id, s.buffer); err != nil {
+		s.l.Error().Err(err).Str("connection", c.id).Msg("sse: could not replay events")
+	}
+
+	if !expires.IsZero() {
+		wc.expireAt(expires)
+	}
+	defer func() {
+		if wc.expiry != nil {
+			wc.expiry.Stop()
+		}
+	}()
+
+	s.readMessages(r.Context(), u, c, wc)
+}
+
+// allowedOrigin checks the origin of a websocket request against the allowed CORS origins. Requests
+// without origin don't come from a browser, requests from the ocis domain are always allowed.
+func (s SSE) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range s.c.HTTP.CORS.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// readMessages handles the messages of a websocket client until the connection is closed
+func (s SSE) readMessages(ctx context.Context, u *user.User, c *connection, wc *wsConn) {
+	for {
+		data, err := wc.read()
+		if err != nil {
+			var closed wsutil.ClosedError
+			switch {
+			case errors.As(err, &closed):
+				// the close frame was answered already
+				wc.close(0, "")
+			case !errors.Is(err, net.ErrClosed):
+				s.l.Debug().Err(err).Str("connection", c.id).Msg("sse: could not read websocket message")
+			}
+			return
+		}
+
+		var msg wsMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			wc.enqueue(wsMessage{Type: _wsError, Message: "invalid message"})
+			continue
+		}
+
+		switch msg.Type {
+		case _wsSubscribe:
+			wc.enqueue(wsMessage{Type: _wsSubscribed, Spaces: c.subscribe(msg.Spaces)})
+		case _wsUnsubscribe:
+			wc.enqueue(wsMessage{Type: _wsUnsubscribed, Spaces: c.unsubscribe(msg.Spaces)})
+		case _wsAuth:
+			exp, err := s.authenticate(ctx, u, msg.Token)
+			if err != nil {
+				s.l.Debug().Err(err).Str("connection", c.id).Msg("sse: websocket authentication failed")
+				wc.enqueue(wsMessage{Type: _wsError, Message: "authentication failed"})
+				continue
+			}
+			wc.expireAt(exp)
+			wc.enqueue(wsMessage{Type: _wsAuthenticated, Expires: &exp})
+		default:
+			wc.enqueue(wsMessage{Type: _wsError, Message: "unknown message type"})
+		}
+	}
+}
+
+// authenticate verifies an access token sent by a websocket client and returns its expiry. The token must
+// belong to the user the connection was opened for.
+func (s SSE) authenticate(ctx context.Context, u *user.User, token string) (time.Time, error) {
+	if s.oidc == nil {
+		return time.Time{}, errors.New("no oidc client configured")
+	}
+
+	aClaims, claims, err := s.oidc.VerifyAccessToken(ctx, token)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if aClaims.ExpiresAt == nil {
+		return time.Time{}, errors.New("access token has no expiry")
+	}
+
+	value, err := oidc.ReadStringClaim(s.c.UserOIDCClaim, claims)
+	if err != nil {
+		// not every idp adds the claim to the access token
+		userInfo, err := s.oidc.UserInfo(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}))
+		if err != nil {
+			return time.Time{}, err
+		}
+		if err := userInfo.Claims(&claims); err != nil {
+			return time.Time{}, err
+		}
+		if value, err = oidc.ReadStringClaim(s.c.UserOIDCClaim, claims); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	if value == "" || value != cs3Claim(u, s.c.UserCS3Claim) {
+		return time.Time{}, errors.New("access token belongs to another user")
+	}
+	return aClaims.ExpiresAt.Time, nil
+}
+
+func cs3Claim(u *user.User, claim string) string {
+	switch claim {
+	case "username":
+		return u.GetUsername()
+	case "mail":
+		return u.GetMail()
+	case "userid":
+		return u.GetId().GetOpaqueId()
+	}
+	return ""
+}
+
+// tokenExpiry returns the expiry of a reva token, the token has been verified by the middleware already
+func tokenExpiry(token string) time.Time {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return claims.ExpiresAt.Time
+}
+
+// wsConn is a websocket connection, the messages are queued and written by a single goroutine
+type wsConn struct {
+	conn net.Conn
+	rd   wsutil.Reader
+	// mu serializes the frames written to the connection
+	mu   sync.Mutex
+	out  chan wsMessage
+	done chan struct{}
+	once sync.Once
+	// expiry closes the connection when the token it is authenticated with expires
+	expiry *time.Timer
+}
+
+// expireAt sets the time the connection expires
+func (wc *wsConn) expireAt(t time.Time) {
+	if wc.expiry == nil {
+		wc.expiry = time.AfterFunc(time.Until(t), func() {
+			wc.close(ws.StatusPolicyViolation, "token expired")
+		})
+		return
+	}
+	wc.expiry.Reset(time.Until(t))
+}
+
+// enqueue queues a message, a client which does not keep up is disconnected
+func (wc *wsConn) enqueue(msg wsMessage) {
+	select {
+	case <-wc.done:
+	case wc.out <- msg:
+	default:
+		// enqueue is called while events are published, closing must not block it
+		go wc.close(ws.StatusPolicyViolation, "too many pending messages")
+	}
+}
+
+func (wc *wsConn) writeLoop(keepalive time.Duration) {
+	var ping <-chan time.Time
+	if keepalive != 0 {
+		ticker := time.NewTicker(keepalive)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+
+	for {
+		var err error
+		select {
+		case <-wc.done:
+			return
+		case msg := <-wc.out:
+			var data []byte
+			if data, err = json.Marshal(msg); err == nil {
+				err = wc.write(func(w io.Writer) error {
+					return wsutil.WriteServerMessage(w, ws.OpText, data)
+				})
+			}
+		case <-ping:
+			err = wc.write(func(w io.Writer) error {
+				return wsutil.WriteServerMessage(w, ws.OpPing, nil)
+			})
+		}
+		if err != nil {
+			wc.close(ws.StatusInternalServerError, "")
+			return
+		}
+	}
+}
+
+func (wc *wsConn) write(f func(w io.Writer) error) error {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	if err := wc.conn.SetWriteDeadline(time.Now().Add(_wsWriteTimeout)); err != nil {
+		return err
+	}
+	return f(wc.conn)
+}
+
+// read returns the next text message of the client, control frames are answered in between
+func (wc *wsConn) read() ([]byte, error) {
+	for {
+		hdr, err := wc.rd.NextFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case hdr.OpCode.IsControl():
+			// the response is buffered, so it can't interleave with the frames of the writer
+			var resp bytes.Buffer
+			err := wsutil.ControlFrameHandler(&resp, ws.StateServerSide)(hdr, &wc.rd)
+			if resp.Len() > 0 {
+				if werr := wc.write(func(w io.Writer) error {
+					_, err := w.Write(resp.Bytes())
+					return err
+				}); werr != nil && err == nil {
+					err = werr
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		case hdr.OpCode != ws.OpText:
+			if err := wc.rd.Discard(); err != nil {
+				return nil, err
+			}
+		default:
+			return io.ReadAll(io.LimitReader(&wc.rd, _wsMaxMessageSize))
+		}
+	}
+}
+
+// close closes the connection, the close frame is omitted for code 0
+func (wc *wsConn) close(code ws.StatusCode, reason string) {
+	wc.once.Do(func() {
+		close(wc.done)
+
+		wc.mu.Lock()
+		defer wc.mu.Unlock()
+		if code != 0 {
+			_ = wc.conn.SetWriteDeadline(time.Now().Add(_wsWriteTimeout))
+			_ = ws.WriteFrame(wc.conn, ws.NewCloseFrame(ws.NewCloseFrameBody(code, reason)))
+		}
+		_ = wc.conn.Close()
+	})
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	user "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/golang-jwt/jwt/v5"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	microstore "go-micro.dev/v4/store"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/oidc"
+	oidcmocks "github.com/owncloud/ocis/v2/ocis-pkg/oidc/mocks"
+	"github.com/owncloud/ocis/v2/services/sse/pkg/config"
+)
+
+func TestWebSocket(t *testing.T) {
+	oidcClient := oidcmocks.NewOIDCClient(t)
+	s := SSE{
+		c: &config.Config{
+			ReplayBufferSize: 10,
+			UserOIDCClaim:    "preferred_username",
+			UserCS3Claim:     "username",
+		},
+		l:           log.NopLogger(),
+		buffer:      replayBuffer{store: newStore(t, microstore.NewMemoryStore()), size: 10},
+		connections: newConnections(),
+		oidc:        oidcClient,
+	}
+	addEvents(t, s.buffer, "alice",
+		NewEvent(0, "a", []byte(`{"spaceid":"s1"}`)),
+		NewEvent(0, "b", []byte(`{"spaceid":"s1"}`)),
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := &user.User{Id: &user.UserId{OpaqueId: "alice"}, Username: "alice"}
+		s.HandleWebSocket(w, r.WithContext(revactx.ContextSetUser(r.Context(), u)))
+	}))
+	defer srv.Close()
+
+	conn := dial(t, ws.Dialer{}, srv.URL+"?lastEventId=1")
+	defer conn.Close()
+
+	// the missed events are replayed
+	msg := readMessage(t, conn)
+	require.Equal(t, _wsEvent, msg.Type)
+	require.Equal(t, "2", msg.ID)
+	require.Equal(t, "b", msg.Event)
+	require.JSONEq(t, `{"spaceid":"s1"}`, string(msg.Data))
+
+	// subscriptions restrict the events to spaces
+	writeMessage(t, conn, wsMessage{Type: _wsSubscribe, Spaces: []string{"s2", "s3"}})
+	msg = readMessage(t, conn)
+	require.Equal(t, _wsSubscribed, msg.Type)
+	require.Equal(t, []string{"s2", "s3"}, msg.Spaces)
+
+	writeMessage(t, conn, wsMessage{Type: _wsUnsubscribe, Spaces: []string{"s3"}})
+	msg = readMessage(t, conn)
+	require.Equal(t, _wsUnsubscribed, msg.Type)
+	require.Equal(t, []string{"s2"}, msg.Spaces)
+
+	s.connections.publish("alice", NewEvent(3, "c", []byte(`{"spaceid":"s1"}`)))
+	s.connections.publish("alice", NewEvent(4, "c", []byte(`{"spaceid":"s2"}`)))
+	msg = readMessage(t, conn)
+	require.Equal(t, "4", msg.ID)
+
+	// refreshed tokens must belong to the user of the connection
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	oidcClient.EXPECT().VerifyAccessToken(mock.Anything, "alice-token").Return(
+		oidc.RegClaimsWithSID{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)}},
+		jwt.MapClaims{"preferred_username": "alice"}, nil,
+	)
+	oidcClient.EXPECT().VerifyAccessToken(mock.Anything, "bob-token").Return(
+		oidc.RegClaimsWithSID{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)}},
+		jwt.MapClaims{"preferred_username": "bob"}, nil,
+	)
+
+	writeMessage(t, conn, wsMessage{Type: _wsAuth, Token: "alice-token"})
+	msg = readMessage(t, conn)
+	require.Equal(t, _wsAuthenticated, msg.Type)
+	require.True(t, exp.Equal(*msg.Expires))
+
+	writeMessage(t, conn, wsMessage{Type: _wsAuth, Token: "bob-token"})
+	msg = readMessage(t, conn)
+	require.Equal(t, _wsError, msg.Type)
+
+	writeMessage(t, conn, wsMessage{Type: "unknown"})
+	msg = readMessage(t, conn)
+	require.Equal(t, _wsError, msg.Type)
+}
+
+func TestWebSocketExpiry(t *testing.T) {
+	s := SSE{
+		c:           &config.Config{},
+		l:           log.NopLogger(),
+		connections: newConnections(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second)),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := &user.User{Id: &user.UserId{OpaqueId: "alice"}}
+		s.HandleWebSocket(w, r.WithContext(revactx.ContextSetUser(r.Context(), u)))
+	}))
+	defer srv.Close()
+
+	conn := dial(t, ws.Dialer{Header: ws.HandshakeHeaderHTTP(http.Header{revactx.TokenHeader: []string{token}})}, srv.URL)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err = wsutil.ReadServerData(conn)
+	var closed wsutil.ClosedError
+	require.ErrorAs(t, err, &closed)
+	require.Equal(t, ws.StatusPolicyViolation, closed.Code)
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s := SSE{c: &config.Config{}, l: log.NopLogger()}
+	s.c.HTTP.CORS.AllowedOrigins = []string{"https://web.example.com/"}
+
+	for origin, allowed := range map[string]bool{
+		"":                          true,
+		"https://web.example.com":   true,
+		"https://cloud.example.com": true,
+		"https://evil.example.com":  false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "https://cloud.example.com/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		require.Equal(t, allowed, s.allowedOrigin(r), origin)
+	}
+
+	s.c.HTTP.CORS.AllowedOrigins = []string{"*"}
+	r := httptest.NewRequest(http.MethodGet, "https://cloud.example.com/ws", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	require.True(t, s.allowedOrigin(r))
+
+	w := httptest.NewRecorder()
+	s.c.HTTP.CORS.AllowedOrigins = nil
+	s.HandleWebSocket(w, r.WithContext(revactx.ContextSetUser(r.Context(), &user.User{Id: &user.UserId{OpaqueId: "alice"}})))
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// wsClient reads the frames the dialer buffered during the handshake first
+type wsClient struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c wsClient) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func dial(t *testing.T, dialer ws.Dialer, url string) wsClient {
+	t.Helper()
+	conn, br, _, err := dialer.Dial(context.Background(), "ws"+strings.TrimPrefix(url, "http"))
+	require.NoError(t, err)
+	if br != nil {
+		return wsClient{Conn: conn, r: br}
+	}
+	return wsClient{Conn: conn, r: conn}
+}
+
+func readMessage(t *testing.T, conn net.Conn) wsMessage {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	data, err := wsutil.ReadServerText(conn)
+	require.NoError(t, err)
+
+	var msg wsMessage
+	require.NoError(t, json.Unmarshal(data, &msg))
+	return msg
+}
+
+func writeMessage(t *testing.T, conn net.Conn, msg wsMessage) {
+	t.Helper()
+	data, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.NoError(t, wsutil.WriteClientText(conn, data))
+}
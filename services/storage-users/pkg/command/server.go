@@ -16,9 +16,13 @@ import (
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/logging"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/revaconfig"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/server/debug"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/server/http"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/uploads"
 	"github.com/owncloud/reva/v2/cmd/revad/runtime"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/store"
 	"github.com/urfave/cli/v2"
+	microstore "go-micro.dev/v4/store"
 )
 
 // Server is the entry point for the server command.
@@ -106,6 +110,35 @@ func Server(cfg *config.Config) *cli.Command {
 						logger.Fatal().Err(err).Msg("can't run event server")
 					}
 				}()
+
+				ppStore := store.Create(
+					store.Store(cfg.PostprocessingStore.Store),
+					microstore.Nodes(cfg.PostprocessingStore.Nodes...),
+					microstore.Database(cfg.PostprocessingStore.Database),
+					microstore.Table(cfg.PostprocessingStore.Table),
+					store.Authentication(cfg.PostprocessingStore.AuthUsername, cfg.PostprocessingStore.AuthPassword),
+					store.TLS(cfg.PostprocessingStore.EnableTLS, cfg.PostprocessingStore.TLSInsecure, cfg.PostprocessingStore.TLSRootCACertificate),
+				)
+
+				// the admin api lists the upload sessions of the storage the storage provider runs with
+				lister, err := uploads.NewLister(cfg.Driver, revaconfig.StorageProviderDrivers(cfg)[cfg.Driver].(map[string]interface{}), &logger.Logger)
+				if err != nil {
+					logger.Fatal().Err(err).Msg("can't list upload sessions")
+				}
+
+				server, err := http.Server(
+					http.Logger(logger),
+					http.Context(ctx),
+					http.Config(cfg),
+					http.GatewaySelector(selector),
+					http.Uploads(uploads.NewManager(lister, stream, ppStore)),
+					http.TraceProvider(traceProvider),
+				)
+				if err != nil {
+					logger.Fatal().Err(err).Msg("failed to initialize http server")
+				}
+
+				gr.Add(runner.NewGoMicroHttpServerRunner("storage-users_http", server))
 			}
 
 			logger.Warn().Msgf("starting service %s", cfg.Service.Name)
@@ -3,9 +3,11 @@ package command
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -16,16 +18,15 @@ import (
 	"github.com/shamaton/msgpack/v2"
 	"github.com/urfave/cli/v2"
 
-	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
 	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/config"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/config/parser"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/event"
 	"github.com/owncloud/ocis/v2/services/storage-users/pkg/revaconfig"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/uploads"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/storage"
-	"github.com/owncloud/reva/v2/pkg/storage/fs/registry"
 	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/lookup"
 	"github.com/owncloud/reva/v2/pkg/storage/utils/decomposedfs/node"
 	"github.com/owncloud/reva/v2/pkg/utils"
@@ -40,21 +41,6 @@ const (
 	LOG_INDENT_L3 = LOG_INDENT_L2 + LOG_INDENT_L1
 )
 
-// Session contains the information of an upload session
-type Session struct {
-	ID         string         `json:"id"`
-	Space      string         `json:"space"`
-	Filename   string         `json:"filename"`
-	Offset     int64          `json:"offset"`
-	Size       int64          `json:"size"`
-	Executant  userpb.UserId  `json:"executant"`
-	SpaceOwner *userpb.UserId `json:"spaceowner,omitempty"`
-	Expires    time.Time      `json:"expires"`
-	Processing bool           `json:"processing"`
-	ScanDate   time.Time      `json:"virus_scan_date"`
-	ScanResult string         `json:"virus_scan_result"`
-}
-
 // Uploads is the entry point for the uploads command
 func Uploads(cfg *config.Config) *cli.Command {
 	return &cli.Command{
@@ -119,22 +105,15 @@ func ListUploadSessions(cfg *config.Config) *cli.Command {
 			return configlog.ReturnFatal(parser.ParseConfig(cfg))
 		},
 		Action: func(c *cli.Context) error {
-			f, ok := registry.NewFuncs[cfg.Driver]
-			if !ok {
-				fmt.Fprintf(os.Stderr, "Unknown filesystem driver '%s'\n", cfg.Driver)
-				os.Exit(1)
-			}
 			drivers := revaconfig.StorageProviderDrivers(cfg)
-			fs, err := f(drivers[cfg.Driver].(map[string]interface{}), nil, nil)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to initialize filesystem driver '%s'\n", cfg.Driver)
-				return err
-			}
-
-			managingFS, ok := fs.(storage.UploadSessionLister)
-			if !ok {
+			managingFS, err := uploads.NewLister(cfg.Driver, drivers[cfg.Driver].(map[string]interface{}), nil)
+			switch {
+			case errors.Is(err, uploads.ErrNotSupported):
 				fmt.Fprintf(os.Stderr, "'%s' storage does not support listing upload sessions\n", cfg.Driver)
 				os.Exit(1)
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "Failed to initialize filesystem driver '%s'\n", cfg.Driver)
+				return err
 			}
 
 			var stream events.Stream
@@ -147,14 +126,14 @@ func ListUploadSessions(cfg *config.Config) *cli.Command {
 			}
 
 			filter := buildFilter(c)
-			uploads, err := managingFS.ListUploadSessions(c.Context, filter)
+			sessions, err := managingFS.ListUploadSessions(c.Context, filter)
 			if err != nil {
 				return err
 			}
 
 			var (
 				table *tablewriter.Table
-				raw   []*Session
+				raw   []*uploads.Session
 			)
 
 			if !c.Bool("json") {
@@ -164,26 +143,11 @@ func ListUploadSessions(cfg *config.Config) *cli.Command {
 				table.Header("Space", "Upload Id", "Name", "Offset", "Size", "Executant", "Owner", "Expires", "Processing", "Scan Date", "Scan Result")
 			}
 
-			for _, u := range uploads {
-				ref := u.Reference()
-				sr, sd := u.ScanData()
-
-				session := Session{
-					Space:      ref.GetResourceId().GetSpaceId(),
-					ID:         u.ID(),
-					Filename:   u.Filename(),
-					Offset:     u.Offset(),
-					Size:       u.Size(),
-					Executant:  u.Executant(),
-					SpaceOwner: u.SpaceOwner(),
-					Expires:    u.Expires(),
-					Processing: u.IsProcessing(),
-					ScanDate:   sd,
-					ScanResult: sr,
-				}
+			for _, u := range sessions {
+				session := uploads.NewSession(u)
 
 				if c.Bool("json") {
-					raw = append(raw, &session)
+					raw = append(raw, session)
 				} else {
 					table.Append([]string{
 						session.Space,
@@ -202,20 +166,14 @@ func ListUploadSessions(cfg *config.Config) *cli.Command {
 
 				switch {
 				case c.Bool("restart"):
-					if err := events.Publish(context.Background(), stream, events.RestartPostprocessing{
-						UploadID:  u.ID(),
-						Timestamp: utils.TSNow(),
-					}); err != nil {
+					if err := uploads.Restart(context.Background(), stream, u.ID()); err != nil {
 						fmt.Fprintf(os.Stderr, "Failed to send restart event for upload session '%s'\n", u.ID())
 						// if publishing fails there is no need to try publishing other events - they will fail too.
 						os.Exit(1)
 					}
 
 				case c.Bool("resume"):
-					if err := events.Publish(context.Background(), stream, events.ResumePostprocessing{
-						UploadID:  u.ID(),
-						Timestamp: utils.TSNow(),
-					}); err != nil {
+					if err := uploads.Resume(context.Background(), stream, u.ID()); err != nil {
 						fmt.Fprintf(os.Stderr, "Failed to send resume event for upload session '%s'\n", u.ID())
 						// if publishing fails there is no need to try publishing other events - they will fail too.
 						os.Exit(1)
@@ -251,27 +209,18 @@ func ListUploadSessions(cfg *config.Config) *cli.Command {
 }
 
 func buildFilter(c *cli.Context) storage.UploadSessionFilter {
-	filter := storage.UploadSessionFilter{}
-	if c.IsSet("processing") {
-		processingValue := c.Bool("processing")
-		filter.Processing = &processingValue
-	}
-	if c.IsSet("expired") {
-		expiredValue := c.Bool("expired")
-		filter.Expired = &expiredValue
-	}
-	if c.IsSet("has-virus") {
-		infectedValue := c.Bool("has-virus")
-		filter.HasVirus = &infectedValue
-	}
-	if c.IsSet("orphaned") {
-		orphanedValue := c.Bool("orphaned")
-		filter.Orphaned = &orphanedValue
+	values := url.Values{}
+	for _, name := range []string{uploads.FilterProcessing, uploads.FilterExpired, uploads.FilterHasVirus, uploads.FilterOrphaned} {
+		if c.IsSet(name) {
+			values.Set(name, strconv.FormatBool(c.Bool(name)))
+		}
 	}
-	if c.IsSet("id") {
-		idValue := c.String("id")
-		filter.ID = &idValue
+	if c.IsSet(uploads.FilterID) {
+		values.Set(uploads.FilterID, c.String(uploads.FilterID))
 	}
+
+	// the values are formatted above, so parsing can't fail
+	filter, _ := uploads.ParseFilter(values)
 	return filter
 }
 
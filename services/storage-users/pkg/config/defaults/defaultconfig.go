@@ -28,6 +28,10 @@ func DefaultConfig() *config.Config {
 			Pprof:  false,
 			Zpages: false,
 		},
+		AdminAPI: config.AdminAPIConfig{
+			Addr:      "127.0.0.1:9290",
+			Namespace: "com.owncloud.web",
+		},
 		GRPC: config.GRPCConfig{
 			Addr:      "127.0.0.1:9157",
 			Namespace: "com.owncloud.api",
@@ -167,6 +171,11 @@ func DefaultConfig() *config.Config {
 			Database: "ids-storage-users",
 			TTL:      24 * 60 * time.Second,
 		},
+		PostprocessingStore: config.PostprocessingStore{
+			Store:    "nats-js-kv",
+			Nodes:    []string{"127.0.0.1:9233"},
+			Database: "postprocessing",
+		},
 		Tasks: config.Tasks{
 			PurgeTrashBin: config.PurgeTrashBin{
 				ProjectDeleteBefore:  30 * 24 * time.Hour,
@@ -217,6 +226,10 @@ func EnsureDefaults(cfg *config.Config) {
 		cfg.GRPC.TLS = structs.CopyOrZeroValue(cfg.Commons.GRPCServiceTLS)
 	}
 
+	if cfg.Commons != nil {
+		cfg.AdminAPI.TLS = cfg.Commons.HTTPServiceTLS
+	}
+
 	if cfg.Tasks.PurgeTrashBin.UserID == "" && cfg.Commons != nil {
 		cfg.Tasks.PurgeTrashBin.UserID = cfg.Commons.AdminUserID
 	}
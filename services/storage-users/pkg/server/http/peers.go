@@ -0,0 +1,64 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	mregistry "go-micro.dev/v4/registry"
+)
+
+// forwardedHeader marks requests which were forwarded by another instance, they are only answered with the upload
+// sessions of the receiving instance.
+const forwardedHeader = "X-Upload-Sessions-Forwarded"
+
+// peers forwards requests to the admin API of the other instances of the service. Each instance only sees the upload
+// sessions of its own storage, the instance receiving a request asks the others.
+type peers struct {
+	registry mregistry.Registry
+	service  string
+	// self is the id of the node of this instance
+	self   string
+	client *http.Client
+}
+
+// nodes returns the nodes of the other instances
+func (p *peers) nodes() ([]*mregistry.Node, error) {
+	services, err := p.registry.GetService(p.service)
+	if err != nil && err != mregistry.ErrNotFound {
+		return nil, err
+	}
+	var nodes []*mregistry.Node
+	for _, s := range services {
+		for _, n := range s.Nodes {
+			if n.Id != p.self {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// forward sends the request to the node, the caller has to close the body of the response
+func (p *peers) forward(r *http.Request, n *mregistry.Node) (*http.Response, error) {
+	scheme := "http"
+	if n.Metadata["use_tls"] == "true" {
+		scheme = "https"
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, scheme+"://"+n.Address+r.URL.RequestURI(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(revactx.TokenHeader, r.Header.Get(revactx.TokenHeader))
+	req.Header.Set(forwardedHeader, "true")
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not forward request to %s: %w", n.Id, err)
+	}
+	return res, nil
+}
+
+// forwarded returns if the request was forwarded by another instance
+func forwarded(r *http.Request) bool {
+	return r.Header.Get(forwardedHeader) != ""
+}
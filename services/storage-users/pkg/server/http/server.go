@@ -0,0 +1,91 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	stdhttp "net/http"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go-micro.dev/v4"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/account"
+	"github.com/owncloud/ocis/v2/ocis-pkg/middleware"
+	"github.com/owncloud/ocis/v2/ocis-pkg/service/http"
+	"github.com/owncloud/ocis/v2/ocis-pkg/version"
+)
+
+// _peerTimeout is the time after which a request forwarded to another instance is cancelled
+const _peerTimeout = 30 * time.Second
+
+// Server initializes the http service serving the admin API.
+func Server(opts ...Option) (http.Service, error) {
+	options := newOptions(opts...)
+
+	service, err := http.NewService(
+		http.TLSConfig(options.Config.AdminAPI.TLS),
+		http.Logger(options.Logger),
+		http.Namespace(options.Config.AdminAPI.Namespace),
+		http.Name(options.Config.Service.Name),
+		http.Version(version.GetString()),
+		http.Address(options.Config.AdminAPI.Addr),
+		http.Context(options.Context),
+		http.TraceProvider(options.TraceProvider),
+	)
+	if err != nil {
+		options.Logger.Error().
+			Err(err).
+			Msg("Error initializing http service")
+		return http.Service{}, fmt.Errorf("could not initialize http service: %w", err)
+	}
+
+	middlewares := []func(stdhttp.Handler) stdhttp.Handler{
+		middleware.GetOtelhttpMiddleware(options.Config.Service.Name, options.TraceProvider),
+		chimiddleware.RequestID,
+		middleware.Version(
+			options.Config.Service.Name,
+			version.GetString(),
+		),
+		middleware.Logger(
+			options.Logger,
+		),
+		middleware.ExtractAccountUUID(
+			account.Logger(options.Logger),
+			account.JWTSecret(options.Config.TokenManager.JWTSecret),
+		),
+	}
+
+	mux := chi.NewMux()
+	mux.Use(middlewares...)
+
+	o := service.Server().Options()
+	h := uploadSessionsHandler{
+		log:     options.Logger,
+		uploads: options.Uploads,
+		peers: &peers{
+			registry: o.Registry,
+			service:  o.Name,
+			self:     o.Name + "-" + o.Id,
+			client: &stdhttp.Client{
+				Timeout: _peerTimeout,
+				Transport: &stdhttp.Transport{
+					TLSClientConfig: &tls.Config{
+						InsecureSkipVerify: options.Config.AdminAPI.Insecure, //nolint:gosec
+					},
+				},
+			},
+		},
+	}
+	mux.Route("/graph/v1beta1/uploadSessions", func(r chi.Router) {
+		r.Use(requireAdmin(options.GatewaySelector, options.Logger))
+		h.routes(r)
+	})
+
+	if err := micro.RegisterHandler(service.Server(), mux); err != nil {
+		return http.Service{}, err
+	}
+
+	return service, nil
+}
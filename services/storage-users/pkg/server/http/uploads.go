@@ -0,0 +1,250 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/graph/pkg/errorcode"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/uploads"
+)
+
+// accountManagementPermission is the permission of the admin role, the same one the graph service checks
+// for its admin endpoints
+const accountManagementPermission = "Accounts.ReadWrite"
+
+type uploadSessionsHandler struct {
+	log     log.Logger
+	uploads *uploads.Manager
+	// peers is nil if the requests are only answered with the local upload sessions
+	peers *peers
+}
+
+func (h uploadSessionsHandler) routes(r chi.Router) {
+	r.Get("/", h.list)
+	r.Route("/{uploadID}", func(r chi.Router) {
+		r.Get("/", h.get)
+		r.Post("/restart", h.restart)
+		r.Post("/resume", h.resume)
+		r.Post("/abort", h.abort)
+	})
+}
+
+func (h uploadSessionsHandler) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := uploads.ParseFilter(r.URL.Query())
+	if err != nil {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sessions, err := h.uploads.List(r.Context(), filter)
+	if err != nil {
+		h.log.Error().Err(err).Msg("could not list upload sessions")
+		errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not list upload sessions")
+		return
+	}
+
+	if h.peers != nil && !forwarded(r) {
+		if sessions, err = h.listPeers(r, sessions); err != nil {
+			h.log.Error().Err(err).Msg("could not list upload sessions of other instances")
+			errorcode.GeneralException.Render(w, r, http.StatusBadGateway, "could not list upload sessions of all instances")
+			return
+		}
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, &listResponse{Value: sessions})
+}
+
+// listPeers adds the upload sessions of the other instances. Instances which run on the same storage list the
+// same sessions, they are only added once.
+func (h uploadSessionsHandler) listPeers(r *http.Request, sessions []*uploads.Session) ([]*uploads.Session, error) {
+	nodes, err := h.peers.nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(sessions))
+	for _, s := range sessions {
+		seen[s.ID] = struct{}{}
+	}
+	for _, n := range nodes {
+		res, err := h.peers.forward(r, n)
+		if err != nil {
+			return nil, err
+		}
+		var lr listResponse
+		err = json.NewDecoder(res.Body).Decode(&lr)
+		res.Body.Close()
+		switch {
+		case res.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("%s answered with status %d", n.Id, res.StatusCode)
+		case err != nil:
+			return nil, err
+		}
+		for _, s := range lr.Value {
+			if _, ok := seen[s.ID]; !ok {
+				seen[s.ID] = struct{}{}
+				sessions = append(sessions, s)
+			}
+		}
+	}
+	return sessions, nil
+}
+
+func (h uploadSessionsHandler) get(w http.ResponseWriter, r *http.Request) {
+	uploadID, ok := uploadIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	session, err := h.uploads.Get(r.Context(), uploadID)
+	if err != nil {
+		h.renderError(w, r, uploadID, err)
+		return
+	}
+
+	render.Status(r, http.StatusOK)
+	render.JSON(w, r, session)
+}
+
+func (h uploadSessionsHandler) restart(w http.ResponseWriter, r *http.Request) {
+	h.action(w, r, h.uploads.Restart)
+}
+
+func (h uploadSessionsHandler) resume(w http.ResponseWriter, r *http.Request) {
+	h.action(w, r, h.uploads.Resume)
+}
+
+func (h uploadSessionsHandler) abort(w http.ResponseWriter, r *http.Request) {
+	h.action(w, r, h.uploads.Abort)
+}
+
+// action runs an action on an upload session. The actions are carried out asynchronously by publishing events.
+func (h uploadSessionsHandler) action(w http.ResponseWriter, r *http.Request, f func(context.Context, string) error) {
+	uploadID, ok := uploadIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f(r.Context(), uploadID); err != nil {
+		h.renderError(w, r, uploadID, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h uploadSessionsHandler) renderError(w http.ResponseWriter, r *http.Request, uploadID string, err error) {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		if h.peers != nil && !forwarded(r) && h.forwardToPeers(w, r) {
+			return
+		}
+		errorcode.ItemNotFound.Render(w, r, http.StatusNotFound, "upload session not found or not in postprocessing")
+		return
+	case errors.Is(err, uploads.ErrNotSupported):
+		errorcode.NotSupported.Render(w, r, http.StatusNotImplemented, "storage driver does not support listing upload sessions")
+		return
+	}
+
+	h.log.Error().Err(err).Str("uploadid", uploadID).Msg("could not handle upload session")
+	errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not handle upload session")
+}
+
+// forwardToPeers asks the other instances for an upload session which isn't known locally. The first answer of an
+// instance which knows the upload session is passed on, false is returned if none knows it.
+func (h uploadSessionsHandler) forwardToPeers(w http.ResponseWriter, r *http.Request) bool {
+	nodes, err := h.peers.nodes()
+	if err != nil {
+		h.log.Error().Err(err).Msg("could not get the other instances")
+		return false
+	}
+	for _, n := range nodes {
+		res, err := h.peers.forward(r, n)
+		if err != nil {
+			h.log.Error().Err(err).Msg("could not forward upload session request")
+			continue
+		}
+		if res.StatusCode == http.StatusNotFound {
+			res.Body.Close()
+			continue
+		}
+		w.Header().Set("Content-Type", res.Header.Get("Content-Type"))
+		w.WriteHeader(res.StatusCode)
+		if _, err := io.Copy(w, res.Body); err != nil {
+			h.log.Error().Err(err).Msg("could not pass on upload session response")
+		}
+		res.Body.Close()
+		return true
+	}
+	return false
+}
+
+type listResponse struct {
+	Value []*uploads.Session `json:"value"`
+}
+
+func uploadIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	uploadID, err := url.PathUnescape(chi.URLParam(r, "uploadID"))
+	if err != nil || uploadID == "" {
+		errorcode.InvalidRequest.Render(w, r, http.StatusBadRequest, "invalid upload id")
+		return "", false
+	}
+	return uploadID, true
+}
+
+// requireAdmin only lets users pass who have the account management permission
+func requireAdmin(gatewaySelector pool.Selectable[gateway.GatewayAPIClient], logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, ok := revactx.ContextGetUser(r.Context())
+			if !ok {
+				errorcode.AccessDenied.Render(w, r, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			gatewayClient, err := gatewaySelector.Next()
+			if err != nil {
+				logger.Error().Err(err).Msg("could not select next gateway client")
+				errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not check permissions")
+				return
+			}
+
+			ctx := metadata.AppendToOutgoingContext(r.Context(), revactx.TokenHeader, r.Header.Get(revactx.TokenHeader))
+			rsp, err := gatewayClient.CheckPermission(ctx, &permissions.CheckPermissionRequest{
+				Permission: accountManagementPermission,
+				SubjectRef: &permissions.SubjectReference{
+					Spec: &permissions.SubjectReference_UserId{
+						UserId: u.GetId(),
+					},
+				},
+			})
+			switch {
+			case err != nil:
+				logger.Error().Err(err).Str("userid", u.GetId().GetOpaqueId()).Msg("could not check permissions")
+				errorcode.GeneralException.Render(w, r, http.StatusInternalServerError, "could not check permissions")
+				return
+			case rsp.GetStatus().GetCode() != rpc.Code_CODE_OK:
+				errorcode.AccessDenied.Render(w, r, http.StatusForbidden, "Forbidden")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	permissions "github.com/cs3org/go-cs3apis/cs3/permissions/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/go-chi/chi/v5"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storage"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	mregistry "go-micro.dev/v4/registry"
+	microstore "go-micro.dev/v4/store"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	ppevent "github.com/owncloud/ocis/v2/services/postprocessing/pkg/event"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/uploads"
+)
+
+type session struct {
+	id         string
+	processing bool
+}
+
+func (s session) ID() string                    { return s.id }
+func (s session) Filename() string              { return "file.txt" }
+func (s session) Size() int64                   { return 10 }
+func (s session) Offset() int64                 { return 10 }
+func (s session) Executant() userpb.UserId      { return userpb.UserId{OpaqueId: "user"} }
+func (s session) SpaceOwner() *userpb.UserId    { return nil }
+func (s session) Expires() time.Time            { return time.Time{} }
+func (s session) IsProcessing() bool            { return s.processing }
+func (s session) Purge(context.Context)         {}
+func (s session) ScanData() (string, time.Time) { return "", time.Time{} }
+func (s session) Reference() provider.Reference { return provider.Reference{} }
+
+type lister struct {
+	sessions []session
+	err      error
+}
+
+func (l lister) ListUploadSessions(_ context.Context, filter storage.UploadSessionFilter) ([]storage.UploadSession, error) {
+	var sessions []storage.UploadSession
+	for _, s := range l.sessions {
+		if (filter.ID == nil || *filter.ID == s.id) && (filter.Processing == nil || *filter.Processing == s.processing) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, l.err
+}
+
+type publisher struct {
+	events []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func newRouter(t *testing.T, l storage.UploadSessionLister, permission rpc.Code, p *peers) (http.Handler, *publisher) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient]("GatewaySelector", "com.owncloud.api.gateway", func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+		return gatewayClient
+	})
+	gatewayClient.On("CheckPermission", mock.Anything, mock.MatchedBy(func(req *permissions.CheckPermissionRequest) bool {
+		return req.GetPermission() == accountManagementPermission && req.GetSubjectRef().GetUserId().GetOpaqueId() == "admin"
+	})).Return(&permissions.CheckPermissionResponse{Status: &rpc.Status{Code: permission}}, nil)
+
+	pub := &publisher{}
+	h := uploadSessionsHandler{log: log.NopLogger(), uploads: uploads.NewManager(l, pub, microstore.NewMemoryStore()), peers: p}
+	mux := chi.NewMux()
+	mux.Route("/uploadSessions", func(r chi.Router) {
+		r.Use(requireAdmin(gatewaySelector, log.NopLogger()))
+		h.routes(r)
+	})
+	return mux, pub
+}
+
+func request(method, target string) *http.Request {
+	r := httptest.NewRequest(method, target, nil)
+	return r.WithContext(revactx.ContextSetUser(r.Context(), &userpb.User{Id: &userpb.UserId{OpaqueId: "admin"}}))
+}
+
+func TestListUploadSessions(t *testing.T) {
+	router, _ := newRouter(t, lister{sessions: []session{{id: "processing", processing: true}, {id: "finished"}}}, rpc.Code_CODE_OK, nil)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request(http.MethodGet, "/uploadSessions/?processing=true"))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var res struct {
+		Value []uploads.Session `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	require.Len(t, res.Value, 1)
+	assert.Equal(t, "processing", res.Value[0].ID)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, request(http.MethodGet, "/uploadSessions/?processing=maybe"))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestUploadSessionsRequireAdmin(t *testing.T) {
+	router, _ := newRouter(t, lister{}, rpc.Code_CODE_PERMISSION_DENIED, nil)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request(http.MethodGet, "/uploadSessions/"))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/uploadSessions/", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestUploadSessionActions(t *testing.T) {
+	router, pub := newRouter(t, lister{sessions: []session{{id: "processing", processing: true}, {id: "finished"}}}, rpc.Code_CODE_OK, nil)
+
+	for _, tc := range []struct {
+		method, target string
+		status         int
+	}{
+		{http.MethodGet, "/uploadSessions/processing", http.StatusOK},
+		{http.MethodGet, "/uploadSessions/missing", http.StatusNotFound},
+		{http.MethodPost, "/uploadSessions/processing/restart", http.StatusAccepted},
+		{http.MethodPost, "/uploadSessions/finished/restart", http.StatusNotFound},
+		{http.MethodPost, "/uploadSessions/processing/resume", http.StatusAccepted},
+		{http.MethodPost, "/uploadSessions/processing/abort", http.StatusAccepted},
+		{http.MethodPost, "/uploadSessions/finished/abort", http.StatusNotFound},
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, request(tc.method, tc.target))
+		assert.Equal(t, tc.status, rr.Code, tc.target)
+	}
+	require.Len(t, pub.events, 3)
+	assert.IsType(t, events.RestartPostprocessing{}, pub.events[0])
+	assert.IsType(t, events.ResumePostprocessing{}, pub.events[1])
+	assert.IsType(t, ppevent.CancelPostprocessing{}, pub.events[2])
+}
+
+func TestUploadSessionsNotSupported(t *testing.T) {
+	router, _ := newRouter(t, lister{err: uploads.ErrNotSupported}, rpc.Code_CODE_OK, nil)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, request(http.MethodGet, "/uploadSessions/upload"))
+	assert.Equal(t, http.StatusNotImplemented, rr.Code)
+}
+
+func TestUploadSessionsOfOtherInstances(t *testing.T) {
+	peerRouter, peerPub := newRouter(t, lister{sessions: []session{{id: "shared"}, {id: "remote", processing: true}}}, rpc.Code_CODE_OK, nil)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the token of the forwarded request belongs to the admin
+		assert.Equal(t, "token", r.Header.Get(revactx.TokenHeader))
+		assert.True(t, forwarded(r))
+		peerRouter.ServeHTTP(w, r.WithContext(revactx.ContextSetUser(r.Context(), &userpb.User{Id: &userpb.UserId{OpaqueId: "admin"}})))
+	}))
+	defer peer.Close()
+
+	reg := mregistry.NewMemoryRegistry()
+	require.NoError(t, reg.Register(&mregistry.Service{Name: "storage-users", Nodes: []*mregistry.Node{
+		{Id: "storage-users-self", Address: "127.0.0.1:1"},
+		{Id: "storage-users-peer", Address: peer.Listener.Addr().String()},
+	}}))
+	router, pub := newRouter(t, lister{sessions: []session{{id: "shared"}, {id: "local", processing: true}}}, rpc.Code_CODE_OK, &peers{
+		registry: reg,
+		service:  "storage-users",
+		self:     "storage-users-self",
+		client:   peer.Client(),
+	})
+	withToken := func(r *http.Request) *http.Request {
+		r.Header.Set(revactx.TokenHeader, "token")
+		return r
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, withToken(request(http.MethodGet, "/uploadSessions/")))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var res struct {
+		Value []uploads.Session `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+	var ids []string
+	for _, s := range res.Value {
+		ids = append(ids, s.ID)
+	}
+	assert.Equal(t, []string{"shared", "local", "remote"}, ids, "sessions on a shared storage are listed once")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, withToken(request(http.MethodGet, "/uploadSessions/remote")))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"id":"remote"`)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, withToken(request(http.MethodPost, "/uploadSessions/remote/abort")))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Empty(t, pub.events)
+	assert.Len(t, peerPub.events, 1)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, withToken(request(http.MethodGet, "/uploadSessions/missing")))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
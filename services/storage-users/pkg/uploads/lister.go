@@ -0,0 +1,31 @@
+package uploads
+
+import (
+	"fmt"
+
+	"github.com/owncloud/reva/v2/pkg/storage"
+	"github.com/owncloud/reva/v2/pkg/storage/fs/registry"
+	"github.com/rs/zerolog"
+
+	// register the storage drivers
+	_ "github.com/owncloud/reva/v2/pkg/storage/fs/loader"
+)
+
+// NewLister initializes the storage driver with the given name and config to list its upload sessions. The driver
+// works on the same storage as the one of the storage provider. It gets no event stream, so it doesn't consume the
+// events meant for the storage provider.
+func NewLister(driver string, driverConfig map[string]interface{}, logger *zerolog.Logger) (storage.UploadSessionLister, error) {
+	f, ok := registry.NewFuncs[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver '%s'", driver)
+	}
+	fs, err := f(driverConfig, nil, logger)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize storage driver '%s': %w", driver, err)
+	}
+	lister, ok := fs.(storage.UploadSessionLister)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return lister, nil
+}
@@ -0,0 +1,249 @@
+// Package uploads contains the logic to inspect and manage upload sessions, shared by the CLI and the admin API
+package uploads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/storage"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	microstore "go-micro.dev/v4/store"
+
+	ppevent "github.com/owncloud/ocis/v2/services/postprocessing/pkg/event"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+)
+
+// Names of the upload session filters. They are used as CLI flags and as query parameters of the admin API.
+const (
+	FilterID         = "id"
+	FilterProcessing = "processing"
+	FilterExpired    = "expired"
+	FilterHasVirus   = "has-virus"
+	FilterOrphaned   = "orphaned"
+)
+
+var (
+	// ErrNotFound is returned when an upload session does not exist
+	ErrNotFound = errors.New("upload session not found")
+	// ErrNotSupported is returned when the storage driver can't list upload sessions
+	ErrNotSupported = errors.New("storage driver does not support listing upload sessions")
+)
+
+// Session contains the information of an upload session
+type Session struct {
+	ID         string         `json:"id"`
+	Space      string         `json:"space"`
+	Filename   string         `json:"filename"`
+	Offset     int64          `json:"offset"`
+	Size       int64          `json:"size"`
+	Executant  userpb.UserId  `json:"executant"`
+	SpaceOwner *userpb.UserId `json:"spaceowner,omitempty"`
+	Expires    time.Time      `json:"expires"`
+	Processing bool           `json:"processing"`
+	ScanDate   time.Time      `json:"virus_scan_date"`
+	ScanResult string         `json:"virus_scan_result"`
+
+	Postprocessing *Postprocessing `json:"postprocessing,omitempty"`
+}
+
+// Postprocessing contains the state the postprocessing service keeps about an upload session
+type Postprocessing struct {
+	CurrentStep  string     `json:"current_step"`
+	RunningSteps []string   `json:"running_steps,omitempty"`
+	Failures     int        `json:"failures"`
+	Initiator    string     `json:"initiator,omitempty"`
+	Started      *time.Time `json:"started,omitempty"`
+	Age          string     `json:"age,omitempty"`
+}
+
+// NewSession returns the Session for an upload session of the storage
+func NewSession(u storage.UploadSession) *Session {
+	ref := u.Reference()
+	sr, sd := u.ScanData()
+
+	return &Session{
+		Space:      ref.GetResourceId().GetSpaceId(),
+		ID:         u.ID(),
+		Filename:   u.Filename(),
+		Offset:     u.Offset(),
+		Size:       u.Size(),
+		Executant:  u.Executant(),
+		SpaceOwner: u.SpaceOwner(),
+		Expires:    u.Expires(),
+		Processing: u.IsProcessing(),
+		ScanDate:   sd,
+		ScanResult: sr,
+	}
+}
+
+// ParseFilter builds an upload session filter from the given values. Filters which are not set are not applied.
+func ParseFilter(values url.Values) (storage.UploadSessionFilter, error) {
+	filter := storage.UploadSessionFilter{}
+	for name, target := range map[string]**bool{
+		FilterProcessing: &filter.Processing,
+		FilterExpired:    &filter.Expired,
+		FilterHasVirus:   &filter.HasVirus,
+		FilterOrphaned:   &filter.Orphaned,
+	} {
+		if !values.Has(name) {
+			continue
+		}
+		v, err := strconv.ParseBool(values.Get(name))
+		if err != nil {
+			return filter, fmt.Errorf("invalid value for filter '%s': %w", name, err)
+		}
+		*target = &v
+	}
+	if values.Has(FilterID) {
+		id := values.Get(FilterID)
+		filter.ID = &id
+	}
+	return filter, nil
+}
+
+// Restart sends an event to restart the postprocessing of an upload session from the beginning
+func Restart(ctx context.Context, pub events.Publisher, uploadID string) error {
+	return events.Publish(ctx, pub, events.RestartPostprocessing{
+		UploadID:  uploadID,
+		Timestamp: utils.TSNow(),
+	})
+}
+
+// Resume sends an event to resume the postprocessing of an upload session at its current step
+func Resume(ctx context.Context, pub events.Publisher, uploadID string) error {
+	return events.Publish(ctx, pub, events.ResumePostprocessing{
+		UploadID:  uploadID,
+		Timestamp: utils.TSNow(),
+	})
+}
+
+// Cancel sends an event to cancel the postprocessing of an upload session. The postprocessing service finishes the
+// postprocessing with the 'abort' outcome, the upload is marked as failed and its bytes are kept.
+func Cancel(ctx context.Context, pub events.Publisher, uploadID string) error {
+	return events.Publish(ctx, pub, ppevent.CancelPostprocessing{
+		UploadID:  uploadID,
+		Timestamp: utils.TSNow(),
+	})
+}
+
+// PostprocessingState reads the state of an upload session from the store of the postprocessing service.
+// It returns nil if the postprocessing service does not know the upload.
+func PostprocessingState(st microstore.Store, uploadID string) (*Postprocessing, error) {
+	recs, err := st.Read(uploadID)
+	switch {
+	case errors.Is(err, microstore.ErrNotFound):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	case len(recs) == 0:
+		return nil, nil
+	}
+
+	pp := &postprocessing.Postprocessing{}
+	if err := json.Unmarshal(recs[0].Value, pp); err != nil {
+		return nil, err
+	}
+
+	state := &Postprocessing{
+		CurrentStep: string(pp.Status.CurrentStep),
+		Failures:    pp.Failures,
+		Initiator:   pp.InitiatorID,
+	}
+	for _, s := range pp.Status.RunningSteps {
+		state.RunningSteps = append(state.RunningSteps, string(s))
+	}
+	if !pp.Started.IsZero() {
+		state.Started = &pp.Started
+		state.Age = time.Since(pp.Started).Round(time.Second).String()
+	}
+	return state, nil
+}
+
+// Manager lists and manages the upload sessions of a storage
+type Manager struct {
+	lister  storage.UploadSessionLister
+	pub     events.Publisher
+	ppStore microstore.Store
+}
+
+// NewManager returns a new Manager. The store of the postprocessing service is optional, without it the
+// postprocessing state of the sessions is not available.
+func NewManager(lister storage.UploadSessionLister, pub events.Publisher, ppStore microstore.Store) *Manager {
+	return &Manager{lister: lister, pub: pub, ppStore: ppStore}
+}
+
+// List returns all upload sessions matching the filter
+func (m *Manager) List(ctx context.Context, filter storage.UploadSessionFilter) ([]*Session, error) {
+	uploads, err := m.lister.ListUploadSessions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session, 0, len(uploads))
+	for _, u := range uploads {
+		s := NewSession(u)
+		if m.ppStore != nil && s.Processing {
+			if s.Postprocessing, err = PostprocessingState(m.ppStore, s.ID); err != nil {
+				return nil, fmt.Errorf("could not read postprocessing state of '%s': %w", s.ID, err)
+			}
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// Get returns the upload session with the given id
+func (m *Manager) Get(ctx context.Context, uploadID string) (*Session, error) {
+	sessions, err := m.List(ctx, storage.UploadSessionFilter{ID: &uploadID})
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNotFound
+	}
+	return sessions[0], nil
+}
+
+// Restart restarts the postprocessing of an upload session
+func (m *Manager) Restart(ctx context.Context, uploadID string) error {
+	if _, err := m.processing(ctx, uploadID); err != nil {
+		return err
+	}
+	return Restart(ctx, m.pub, uploadID)
+}
+
+// Resume resumes the postprocessing of an upload session
+func (m *Manager) Resume(ctx context.Context, uploadID string) error {
+	if _, err := m.processing(ctx, uploadID); err != nil {
+		return err
+	}
+	return Resume(ctx, m.pub, uploadID)
+}
+
+// Abort cancels the postprocessing of an upload session
+func (m *Manager) Abort(ctx context.Context, uploadID string) error {
+	if _, err := m.processing(ctx, uploadID); err != nil {
+		return err
+	}
+	return Cancel(ctx, m.pub, uploadID)
+}
+
+// processing returns the upload session with the given id if it is in postprocessing
+func (m *Manager) processing(ctx context.Context, uploadID string) (storage.UploadSession, error) {
+	processing := true
+	uploads, err := m.lister.ListUploadSessions(ctx, storage.UploadSessionFilter{ID: &uploadID, Processing: &processing})
+	if err != nil {
+		return nil, err
+	}
+	if len(uploads) == 0 {
+		return nil, ErrNotFound
+	}
+	return uploads[0], nil
+}
@@ -0,0 +1,140 @@
+package uploads_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	userpb "github.com/cs3org/go-cs3apis/cs3/identity/user/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	microevents "go-micro.dev/v4/events"
+	microstore "go-micro.dev/v4/store"
+
+	ppevent "github.com/owncloud/ocis/v2/services/postprocessing/pkg/event"
+	"github.com/owncloud/ocis/v2/services/postprocessing/pkg/postprocessing"
+	"github.com/owncloud/ocis/v2/services/storage-users/pkg/uploads"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := uploads.ParseFilter(url.Values{"processing": {"true"}, "has-virus": {"false"}, "id": {"upload"}})
+	require.NoError(t, err)
+	require.NotNil(t, filter.Processing)
+	require.NotNil(t, filter.HasVirus)
+	require.NotNil(t, filter.ID)
+	assert.True(t, *filter.Processing)
+	assert.False(t, *filter.HasVirus)
+	assert.Equal(t, "upload", *filter.ID)
+	assert.Nil(t, filter.Expired)
+	assert.Nil(t, filter.Orphaned)
+
+	_, err = uploads.ParseFilter(url.Values{"expired": {"maybe"}})
+	assert.Error(t, err)
+}
+
+type session struct {
+	id         string
+	processing bool
+}
+
+func (s session) ID() string                    { return s.id }
+func (s session) Filename() string              { return "file.txt" }
+func (s session) Size() int64                   { return 10 }
+func (s session) Offset() int64                 { return 10 }
+func (s session) Executant() userpb.UserId      { return userpb.UserId{OpaqueId: "user"} }
+func (s session) SpaceOwner() *userpb.UserId    { return nil }
+func (s session) Expires() time.Time            { return time.Time{} }
+func (s session) IsProcessing() bool            { return s.processing }
+func (s session) Purge(context.Context)         {}
+func (s session) ScanData() (string, time.Time) { return "", time.Time{} }
+func (s session) Reference() provider.Reference {
+	return provider.Reference{ResourceId: &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"}}
+}
+
+type lister []session
+
+func (l lister) ListUploadSessions(_ context.Context, filter storage.UploadSessionFilter) ([]storage.UploadSession, error) {
+	var sessions []storage.UploadSession
+	for _, s := range l {
+		if (filter.ID == nil || *filter.ID == s.id) && (filter.Processing == nil || *filter.Processing == s.processing) {
+			sessions = append(sessions, s)
+		}
+	}
+	return sessions, nil
+}
+
+type publisher struct {
+	events []interface{}
+}
+
+func (p *publisher) Publish(_ string, ev interface{}, _ ...microevents.PublishOption) error {
+	p.events = append(p.events, ev)
+	return nil
+}
+
+func newManager(t *testing.T) (*uploads.Manager, *publisher) {
+	pp := postprocessing.Postprocessing{ID: "processing", InitiatorID: "initiator", Started: time.Now()}
+	pp.Status.CurrentStep = events.PPStepAntivirus
+	pp.Status.RunningSteps = []events.Postprocessingstep{events.PPStepAntivirus}
+	b, err := json.Marshal(pp)
+	require.NoError(t, err)
+	st := microstore.NewMemoryStore()
+	require.NoError(t, st.Write(&microstore.Record{Key: "processing", Value: b}))
+
+	pub := &publisher{}
+	l := lister{{id: "processing", processing: true}, {id: "finished"}, {id: "unknown", processing: true}}
+	return uploads.NewManager(l, pub, st), pub
+}
+
+func TestManagerList(t *testing.T) {
+	m, _ := newManager(t)
+
+	sessions, err := m.List(context.Background(), storage.UploadSessionFilter{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+	require.NotNil(t, sessions[0].Postprocessing)
+	assert.Equal(t, "space", sessions[0].Space)
+	assert.Equal(t, []string{"virusscan"}, sessions[0].Postprocessing.RunningSteps)
+	assert.Equal(t, "initiator", sessions[0].Postprocessing.Initiator)
+	assert.Nil(t, sessions[1].Postprocessing)
+	assert.Nil(t, sessions[2].Postprocessing)
+
+	_, err = m.Get(context.Background(), "missing")
+	assert.ErrorIs(t, err, uploads.ErrNotFound)
+}
+
+func TestManagerActions(t *testing.T) {
+	m, pub := newManager(t)
+
+	require.NoError(t, m.Restart(context.Background(), "processing"))
+	require.NoError(t, m.Resume(context.Background(), "processing"))
+	require.Len(t, pub.events, 2)
+	assert.IsType(t, events.RestartPostprocessing{}, pub.events[0])
+	assert.IsType(t, events.ResumePostprocessing{}, pub.events[1])
+
+	// only sessions in postprocessing can be managed
+	assert.ErrorIs(t, m.Restart(context.Background(), "finished"), uploads.ErrNotFound)
+	assert.ErrorIs(t, m.Abort(context.Background(), "finished"), uploads.ErrNotFound)
+	assert.Len(t, pub.events, 2)
+}
+
+func TestManagerAbort(t *testing.T) {
+	m, pub := newManager(t)
+
+	// the postprocessing service cancels the postprocessing
+	require.NoError(t, m.Abort(context.Background(), "processing"))
+	require.Len(t, pub.events, 1)
+	ev, ok := pub.events[0].(ppevent.CancelPostprocessing)
+	require.True(t, ok)
+	assert.Equal(t, "processing", ev.UploadID)
+}
+
+func TestNewLister(t *testing.T) {
+	_, err := uploads.NewLister("unknown", map[string]interface{}{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/owncloud/reva/v2/pkg/bytesize"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/ocis-pkg/registry"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/logging"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/pregeneration"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/imgsource"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+// Backfill generates the thumbnails of the files which already exist in a space.
+func Backfill(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:      "backfill",
+		Usage:     "Generate the thumbnails of all configured resolutions for the files of a space",
+		ArgsUsage: "['space id' required]",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "workers",
+				Usage: "number of files processed concurrently, defaults to the pregeneration workers",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			spaceID := c.Args().First()
+			if spaceID == "" {
+				_ = cli.ShowSubcommandHelp(c)
+				return fmt.Errorf("space id is required")
+			}
+			if cfg.ServiceAccount.ServiceAccountID == "" {
+				return shared.MissingServiceAccountID(cfg.Service.Name)
+			}
+			if cfg.ServiceAccount.ServiceAccountSecret == "" {
+				return shared.MissingServiceAccountSecret(cfg.Service.Name)
+			}
+			if w := c.Int("workers"); w > 0 {
+				cfg.Thumbnail.Pregeneration.Workers = w
+			}
+
+			p, err := newPregenerator(cfg, logging.Configure(cfg.Service.Name, cfg.Log))
+			if err != nil {
+				return err
+			}
+
+			result, err := p.Backfill(c.Context, spaceID)
+			fmt.Printf("Generated %d thumbnails for %d files, %d files failed\n", result.Thumbnails, result.Files, result.Failed)
+			if err != nil {
+				return fmt.Errorf("could not backfill space '%s': %w", spaceID, err)
+			}
+			return nil
+		},
+	}
+}
+
+// newPregenerator creates a Pregenerator which stores the thumbnails in the configured storage
+func newPregenerator(cfg *config.Config, logger log.Logger) (*pregeneration.Pregenerator, error) {
+	tconf := cfg.Thumbnail
+	tm, err := pool.StringToTLSMode(cfg.GRPCClientTLS.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("could not get gateway client tls mode: %w", err)
+	}
+	gatewaySelector, err := pool.GatewaySelector(tconf.RevaGateway,
+		pool.WithTLSCACert(cfg.GRPCClientTLS.CACert),
+		pool.WithTLSMode(tm),
+		pool.WithRegistry(registry.GetRegistry()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not get gateway selector: %w", err)
+	}
+	b, err := bytesize.Parse(tconf.MaxInputImageFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse MaxInputImageFileSize: %w", err)
+	}
+	resolutions, err := thumbnail.ParseResolutions(tconf.Resolutions)
+	if err != nil {
+		return nil, err
+	}
+	thumbnailStorage, err := storage.New(tconf, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	manager := thumbnail.NewSimpleManager(resolutions, thumbnailStorage, logger, tconf.MaxInputWidth, tconf.MaxInputHeight)
+	return pregeneration.New(cfg, manager, imgsource.NewCS3Source(tconf, gatewaySelector, b), gatewaySelector, logger)
+}
@@ -0,0 +1,154 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli/v2"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config/parser"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/logging"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+// Cache wraps the commands to inspect and clean up the thumbnail storage.
+func Cache(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "cache",
+		Usage: "manage the stored thumbnails",
+		Subcommands: []*cli.Command{
+			cacheUsage(cfg),
+			cachePurge(cfg),
+		},
+	}
+}
+
+func cacheUsage(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "usage",
+		Usage: "Print the number and size of the stored thumbnails",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "output as json",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			s, err := storage.New(cfg.Thumbnail, logging.Configure(cfg.Service.Name, cfg.Log))
+			if err != nil {
+				return err
+			}
+
+			usage, err := storage.GetUsage(s)
+			if err != nil {
+				return fmt.Errorf("could not read the thumbnail storage: %w", err)
+			}
+
+			if c.Bool("json") {
+				j, err := json.Marshal(usage)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(j))
+				return nil
+			}
+
+			resolutions := make([]string, 0, len(usage.ByResolution))
+			for r := range usage.ByResolution {
+				resolutions = append(resolutions, r)
+			}
+			sort.Strings(resolutions)
+
+			table := tablewriter.NewTable(os.Stdout)
+			table.Header("Resolution", "Count", "Size")
+			for _, r := range resolutions {
+				table.Append([]string{
+					r,
+					strconv.FormatInt(usage.ByResolution[r].Count, 10),
+					strconv.FormatInt(usage.ByResolution[r].Size, 10),
+				})
+			}
+			table.Append([]string{"total", strconv.FormatInt(usage.Count, 10), strconv.FormatInt(usage.Size, 10)})
+			if err := table.Render(); err != nil {
+				return err
+			}
+			if usage.Count > 0 {
+				fmt.Printf("Oldest thumbnail: %s\nNewest thumbnail: %s\n", usage.Oldest.Format(time.RFC3339), usage.Newest.Format(time.RFC3339))
+			}
+			return nil
+		},
+	}
+}
+
+func cachePurge(cfg *config.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "purge",
+		Usage: "Delete stored thumbnails, they are generated again when requested",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "older-than",
+				Usage: "delete thumbnails stored longer ago than the duration, e.g. 720h",
+			},
+			&cli.StringFlag{
+				Name:  "checksum",
+				Usage: "delete the thumbnails of the source file with this checksum",
+			},
+			&cli.StringFlag{
+				Name:  "resolution",
+				Usage: "delete the thumbnails with this resolution, e.g. 32x32",
+			},
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "delete all thumbnails",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "only print what would be deleted",
+			},
+		},
+		Before: func(c *cli.Context) error {
+			return configlog.ReturnFatal(parser.ParseConfig(cfg))
+		},
+		Action: func(c *cli.Context) error {
+			f := storage.Filter{
+				Checksum:   c.String("checksum"),
+				Resolution: c.String("resolution"),
+			}
+			if d := c.Duration("older-than"); d > 0 {
+				f.OlderThan = time.Now().Add(-d)
+			}
+			if f == (storage.Filter{}) && !c.Bool("all") {
+				_ = cli.ShowSubcommandHelp(c)
+				return fmt.Errorf("one of --older-than, --checksum, --resolution or --all is required")
+			}
+
+			s, err := storage.New(cfg.Thumbnail, logging.Configure(cfg.Service.Name, cfg.Log))
+			if err != nil {
+				return err
+			}
+
+			dryRun := c.Bool("dry-run")
+			removed, err := storage.Purge(s, f, dryRun)
+			if err != nil {
+				return fmt.Errorf("could not purge thumbnails, %d were deleted: %w", removed.Count, err)
+			}
+
+			if dryRun {
+				fmt.Printf("Would delete %d thumbnails (%d bytes)\n", removed.Count, removed.Size)
+				return nil
+			}
+			fmt.Printf("Deleted %d thumbnails (%d bytes)\n", removed.Count, removed.Size)
+			return nil
+		},
+	}
+}
@@ -15,6 +15,8 @@ func GetCommands(cfg *config.Config) cli.Commands {
 		Server(cfg),
 
 		// interaction with this service
+		Cache(cfg),
+		Backfill(cfg),
 
 		// infos about this service
 		Health(cfg),
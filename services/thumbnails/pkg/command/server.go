@@ -6,6 +6,7 @@ import (
 	"os/signal"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/configlog"
+	"github.com/owncloud/ocis/v2/ocis-pkg/generators"
 	"github.com/owncloud/ocis/v2/ocis-pkg/runner"
 	ogrpc "github.com/owncloud/ocis/v2/ocis-pkg/service/grpc"
 	"github.com/owncloud/ocis/v2/ocis-pkg/tracing"
@@ -17,6 +18,8 @@ import (
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/server/debug"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/server/grpc"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/server/http"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+	"github.com/owncloud/reva/v2/pkg/events/stream"
 	"github.com/urfave/cli/v2"
 )
 
@@ -95,6 +98,33 @@ func Server(cfg *config.Config) *cli.Command {
 			}
 			gr.Add(runner.NewGoMicroHttpServerRunner(cfg.Service.Name+".http", httpServer))
 
+			thumbnailStorage, err := storage.New(cfg.Thumbnail, logger)
+			if err != nil {
+				return err
+			}
+			evictor, err := storage.NewEvictor(thumbnailStorage, cfg.Thumbnail.Eviction, logger)
+			if err != nil {
+				return err
+			}
+			if evictor != nil {
+				gr.Add(runner.New(cfg.Service.Name+".eviction", evictor.Run, evictor.Close))
+			}
+
+			if cfg.Thumbnail.Pregeneration.Enabled {
+				connName := generators.GenerateConnectionName(cfg.Service.Name, generators.NTypeBus)
+				bus, err := stream.NatsFromConfig(connName, false, stream.NatsConfig(cfg.Events))
+				if err != nil {
+					return err
+				}
+				pregenerator, err := newPregenerator(cfg, logger)
+				if err != nil {
+					return err
+				}
+				gr.Add(runner.New(cfg.Service.Name+".pregeneration", func() error {
+					return pregenerator.Run(bus)
+				}, pregenerator.Close))
+			}
+
 			logger.Warn().Msgf("starting service %s", cfg.Service.Name)
 			grResults := gr.Run(ctx)
 
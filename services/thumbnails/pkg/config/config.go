@@ -3,6 +3,7 @@ package config
 
 import (
 	"context"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 	"go-micro.dev/v4/client"
@@ -26,18 +27,66 @@ type Config struct {
 
 	Thumbnail Thumbnail `yaml:"thumbnail"`
 
+	Events         Events         `yaml:"events"`
+	ServiceAccount ServiceAccount `yaml:"service_account" mask:"struct"`
+
 	Context context.Context `yaml:"-"`
 }
 
+// Events combines the configuration options for the event bus.
+type Events struct {
+	Endpoint             string `yaml:"endpoint" env:"OCIS_EVENTS_ENDPOINT;THUMBNAILS_EVENTS_ENDPOINT" desc:"The address of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Cluster              string `yaml:"cluster" env:"OCIS_EVENTS_CLUSTER;THUMBNAILS_EVENTS_CLUSTER" desc:"The clusterID of the event system. The event system is the message queuing service. It is used as message broker for the microservice architecture. Mandatory when using NATS as event system." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSInsecure          bool   `yaml:"tls_insecure" env:"OCIS_INSECURE;THUMBNAILS_EVENTS_TLS_INSECURE" desc:"Whether to verify the server TLS certificates." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	TLSRootCACertificate string `yaml:"tls_root_ca_certificate" env:"OCIS_EVENTS_TLS_ROOT_CA_CERTIFICATE;THUMBNAILS_EVENTS_TLS_ROOT_CA_CERTIFICATE" desc:"The root CA certificate used to validate the server's TLS certificate. If provided THUMBNAILS_EVENTS_TLS_INSECURE will be seen as false." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	EnableTLS            bool   `yaml:"enable_tls" env:"OCIS_EVENTS_ENABLE_TLS;THUMBNAILS_EVENTS_ENABLE_TLS" desc:"Enable TLS for the connection to the events broker. The events broker is the ocis service which receives and delivers events between the services." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthUsername         string `yaml:"username" env:"OCIS_EVENTS_AUTH_USERNAME;THUMBNAILS_EVENTS_AUTH_USERNAME" desc:"The username to authenticate with the events broker. The events broker is the ocis service which receives and delivers events between the services." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AuthPassword         string `yaml:"password" env:"OCIS_EVENTS_AUTH_PASSWORD;THUMBNAILS_EVENTS_AUTH_PASSWORD" desc:"The password to authenticate with the events broker. The events broker is the ocis service which receives and delivers events between the services." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// ServiceAccount is the configuration for the used service account
+type ServiceAccount struct {
+	ServiceAccountID     string `yaml:"service_account_id" env:"OCIS_SERVICE_ACCOUNT_ID;THUMBNAILS_SERVICE_ACCOUNT_ID" desc:"The ID of the service account the service should use. See the 'auth-service' service description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	ServiceAccountSecret string `yaml:"service_account_secret" env:"OCIS_SERVICE_ACCOUNT_SECRET;THUMBNAILS_SERVICE_ACCOUNT_SECRET" desc:"The service account secret." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%" mask:"password"`
+}
+
 // FileSystemStorage defines the available filesystem storage configuration.
 type FileSystemStorage struct {
 	RootDirectory string `yaml:"root_directory" env:"THUMBNAILS_FILESYSTEMSTORAGE_ROOT" desc:"The directory where the filesystem storage will store the thumbnails. If not defined, the root directory derives from $OCIS_BASE_DATA_PATH/thumbnails." introductionVersion:"pre5.0"`
 }
 
+// S3Storage defines the available s3 storage configuration.
+type S3Storage struct {
+	Endpoint  string `yaml:"endpoint" env:"THUMBNAILS_S3STORAGE_ENDPOINT" desc:"Endpoint for the S3 bucket, for example https://s3.example.com." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Region    string `yaml:"region" env:"THUMBNAILS_S3STORAGE_REGION" desc:"Region of the S3 bucket." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	AccessKey string `yaml:"access_key" env:"THUMBNAILS_S3STORAGE_ACCESS_KEY" desc:"Access key for the S3 bucket." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	SecretKey string `yaml:"secret_key" env:"THUMBNAILS_S3STORAGE_SECRET_KEY" desc:"Secret key for the S3 bucket." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Bucket    string `yaml:"bucket" env:"THUMBNAILS_S3STORAGE_BUCKET" desc:"Name of the S3 bucket where the thumbnails are stored." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Prefix    string `yaml:"prefix" env:"THUMBNAILS_S3STORAGE_PREFIX" desc:"A prefix prepended to the object keys of the thumbnails. Use it to share a bucket with other data." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// Eviction defines when thumbnails are removed from the storage.
+type Eviction struct {
+	MaxSize  string        `yaml:"max_size" env:"THUMBNAILS_EVICTION_MAX_SIZE" desc:"The maximum size of all stored thumbnails. When it is exceeded, the least recently created thumbnails are removed. Usable common abbreviations: [KB, KiB, MB, MiB, GB, GiB, TB, TiB, PB, PiB, EB, EiB], example: 2GB. Leave empty to not limit the size." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	MaxAge   time.Duration `yaml:"max_age" env:"THUMBNAILS_EVICTION_MAX_AGE" desc:"Thumbnails older than this are removed. Set to 0 to keep thumbnails regardless of their age. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Interval time.Duration `yaml:"interval" env:"THUMBNAILS_EVICTION_INTERVAL" desc:"The interval in which the eviction checks the storage. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
+// Pregeneration defines the generation of thumbnails for uploaded files.
+type Pregeneration struct {
+	Enabled bool          `yaml:"enabled" env:"THUMBNAILS_PREGENERATION_ENABLED" desc:"Generate the thumbnails of all configured resolutions when a file has been uploaded instead of waiting for the first request. Requires a service account." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Workers int           `yaml:"workers" env:"THUMBNAILS_PREGENERATION_WORKERS" desc:"The number of files for which thumbnails are generated concurrently." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+	Timeout time.Duration `yaml:"timeout" env:"THUMBNAILS_PREGENERATION_TIMEOUT" desc:"The time after which the pregeneration of the thumbnails of a file is cancelled. See the Environment Variable Types description for more details." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
+}
+
 // Thumbnail defines the available thumbnail related configuration.
 type Thumbnail struct {
 	Resolutions           []string          `yaml:"resolutions" env:"THUMBNAILS_RESOLUTIONS" desc:"The supported list of target resolutions in the format WidthxHeight like 32x32. You can define any resolution as required. See the Environment Variable Types description for more details." introductionVersion:"pre5.0"`
+	Storage               string            `yaml:"storage" env:"THUMBNAILS_STORAGE" desc:"The storage for the generated thumbnails. Supported values are 'filesystem' and 's3'. Use 's3' to share the thumbnails between multiple instances of the service." introductionVersion:"%%NEXT_PRODUCTION_VERSION%%"`
 	FileSystemStorage     FileSystemStorage `yaml:"filesystem_storage"`
+	S3Storage             S3Storage         `yaml:"s3_storage"`
+	Eviction              Eviction          `yaml:"eviction"`
+	Pregeneration         Pregeneration     `yaml:"pregeneration"`
 	WebdavAllowInsecure   bool              `yaml:"webdav_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_WEBDAVSOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the webdav source." introductionVersion:"pre5.0"`
 	CS3AllowInsecure      bool              `yaml:"cs3_allow_insecure" env:"OCIS_INSECURE;THUMBNAILS_CS3SOURCE_INSECURE" desc:"Ignore untrusted SSL certificates when connecting to the CS3 source." introductionVersion:"pre5.0"`
 	RevaGateway           string            `yaml:"reva_gateway" env:"OCIS_REVA_GATEWAY" desc:"CS3 gateway used to look up user metadata" introductionVersion:"pre5.0"`
@@ -3,6 +3,7 @@ package defaults
 import (
 	"path"
 	"strings"
+	"time"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/defaults"
 	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
@@ -46,11 +47,23 @@ func DefaultConfig() *config.Config {
 		Service: config.Service{
 			Name: "thumbnails",
 		},
+		Events: config.Events{
+			Endpoint: "127.0.0.1:9233",
+			Cluster:  "ocis-cluster",
+		},
 		Thumbnail: config.Thumbnail{
 			Resolutions: []string{"16x16", "32x32", "64x64", "128x128", "1080x1920", "1920x1080", "2160x3840", "3840x2160", "4320x7680", "7680x4320"},
+			Storage:     "filesystem",
 			FileSystemStorage: config.FileSystemStorage{
 				RootDirectory: path.Join(defaults.BaseDataPath(), "thumbnails"),
 			},
+			Eviction: config.Eviction{
+				Interval: time.Hour,
+			},
+			Pregeneration: config.Pregeneration{
+				Workers: 2,
+				Timeout: 2 * time.Minute,
+			},
 			WebdavAllowInsecure:   false,
 			RevaGateway:           shared.DefaultRevaConfig().Address,
 			CS3AllowInsecure:      false,
@@ -89,6 +102,8 @@ func EnsureDefaults(cfg *config.Config) {
 
 	if cfg.GRPCClientTLS == nil && cfg.Commons != nil {
 		cfg.GRPCClientTLS = structs.CopyOrZeroValue(cfg.Commons.GRPCClientTLS)
+	} else if cfg.GRPCClientTLS == nil {
+		cfg.GRPCClientTLS = &shared.GRPCClientTLS{}
 	}
 	if cfg.GRPC.TLS == nil && cfg.Commons != nil {
 		cfg.GRPC.TLS = structs.CopyOrZeroValue(cfg.Commons.GRPCServiceTLS)
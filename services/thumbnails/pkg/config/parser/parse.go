@@ -2,12 +2,15 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 
 	ociscfg "github.com/owncloud/ocis/v2/ocis-pkg/config"
+	"github.com/owncloud/ocis/v2/ocis-pkg/shared"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
 	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config/defaults"
 
 	"github.com/owncloud/ocis/v2/ocis-pkg/config/envdecode"
+	"github.com/owncloud/reva/v2/pkg/bytesize"
 )
 
 // ParseConfig loads configuration from known paths.
@@ -34,6 +37,42 @@ func ParseConfig(cfg *config.Config) error {
 }
 
 // Validate can validate the configuration
-func Validate(_ *config.Config) error {
+func Validate(cfg *config.Config) error {
+	switch cfg.Thumbnail.Storage {
+	case "filesystem":
+	case "s3":
+		if cfg.Thumbnail.S3Storage.Endpoint == "" || cfg.Thumbnail.S3Storage.Bucket == "" {
+			return fmt.Errorf("The S3 storage of %s requires an endpoint and a bucket. "+
+				"Make sure your %s config contains the proper values "+
+				"(e.g. by running ocis init or setting it manually in "+
+				"the config/corresponding environment variable).",
+				cfg.Service.Name, cfg.Service.Name)
+		}
+	default:
+		return fmt.Errorf("The storage '%s' of %s is unknown, use 'filesystem' or 's3'.",
+			cfg.Thumbnail.Storage, cfg.Service.Name)
+	}
+
+	if cfg.Thumbnail.Pregeneration.Enabled {
+		if cfg.ServiceAccount.ServiceAccountID == "" {
+			return shared.MissingServiceAccountID(cfg.Service.Name)
+		}
+		if cfg.ServiceAccount.ServiceAccountSecret == "" {
+			return shared.MissingServiceAccountSecret(cfg.Service.Name)
+		}
+		if cfg.Thumbnail.Pregeneration.Workers <= 0 {
+			return fmt.Errorf("The number of pregeneration workers of %s must be positive.", cfg.Service.Name)
+		}
+	}
+
+	if cfg.Thumbnail.Eviction.MaxSize != "" {
+		if _, err := bytesize.Parse(cfg.Thumbnail.Eviction.MaxSize); err != nil {
+			return fmt.Errorf("The eviction max size of %s is invalid: %w", cfg.Service.Name, err)
+		}
+	}
+	if cfg.Thumbnail.Eviction.Interval <= 0 && (cfg.Thumbnail.Eviction.MaxSize != "" || cfg.Thumbnail.Eviction.MaxAge > 0) {
+		return fmt.Errorf("The eviction interval of %s must be positive when a max size or max age is set.",
+			cfg.Service.Name)
+	}
 	return nil
 }
@@ -15,6 +15,14 @@ var (
 	ErrNoImageFromAudioFile = errors.New("thumbnails: could not extract image from audio file")
 	// ErrNoConverterForExtractedImageFromGgsFile defines an error when the extracted image from an ggs file could not be converted
 	ErrNoConverterForExtractedImageFromGgsFile = errors.New("thumbnails: could not find converter for image extracted from ggs file")
+	// ErrNoImageFromVideoFile defines an error when an image cannot be extracted from a video file
+	ErrNoImageFromVideoFile = errors.New("thumbnails: could not extract image from video file")
+	// ErrNoImageFromOfficeDocument defines an error when an office document has no embedded thumbnail
+	ErrNoImageFromOfficeDocument = errors.New("thumbnails: could not find a thumbnail in the office document")
+	// ErrNoConverterForExtractedImageFromVideoFile defines an error when the extracted image from a video file could not be converted
+	ErrNoConverterForExtractedImageFromVideoFile = errors.New("thumbnails: could not find converter for image extracted from video file")
+	// ErrNoConverterForExtractedImageFromOfficeDocument defines an error when the extracted image from an office document could not be converted
+	ErrNoConverterForExtractedImageFromOfficeDocument = errors.New("thumbnails: could not find converter for image extracted from office document")
 	// ErrNoConverterForExtractedImageFromAudioFile defines an error when the extracted image from an audio file could not be converted
 	ErrNoConverterForExtractedImageFromAudioFile = errors.New("thumbnails: could not find converter for image extracted from audio file")
 	// ErrCS3AuthorizationMissing defines an error when the CS3 authorization is missing
@@ -0,0 +1,300 @@
+// Package pregeneration generates the thumbnails of uploaded files before they are requested.
+package pregeneration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	rpc "github.com/cs3org/go-cs3apis/cs3/rpc/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	revactx "github.com/owncloud/reva/v2/pkg/ctx"
+	"github.com/owncloud/reva/v2/pkg/events"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storage/utils/walker"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	"github.com/owncloud/reva/v2/pkg/utils"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	terrors "github.com/owncloud/ocis/v2/services/thumbnails/pkg/errors"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/preprocessor"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/imgsource"
+)
+
+// ErrSkipped is returned for files which don't get thumbnails, like folders or unsupported mime types.
+var ErrSkipped = errors.New("no thumbnails for this file")
+
+// Pregenerator generates the thumbnails of all configured resolutions for files.
+type Pregenerator struct {
+	manager         thumbnail.Manager
+	source          imgsource.Source
+	gatewaySelector pool.Selectable[gateway.GatewayAPIClient]
+	resolutions     thumbnail.Resolutions
+	serviceAccount  config.ServiceAccount
+	fontFileMap     string
+	workers         int
+	timeout         time.Duration
+	logger          log.Logger
+	stop            chan struct{}
+}
+
+// New creates a Pregenerator, the thumbnails are read from the source and stored by the manager.
+func New(cfg *config.Config, manager thumbnail.Manager, source imgsource.Source, gatewaySelector pool.Selectable[gateway.GatewayAPIClient], logger log.Logger) (*Pregenerator, error) {
+	resolutions, err := thumbnail.ParseResolutions(cfg.Thumbnail.Resolutions)
+	if err != nil {
+		return nil, err
+	}
+	return &Pregenerator{
+		manager:         manager,
+		source:          source,
+		gatewaySelector: gatewaySelector,
+		resolutions:     resolutions,
+		serviceAccount:  cfg.ServiceAccount,
+		fontFileMap:     cfg.Thumbnail.FontMapFile,
+		workers:         cfg.Thumbnail.Pregeneration.Workers,
+		timeout:         cfg.Thumbnail.Pregeneration.Timeout,
+		logger:          logger,
+		stop:            make(chan struct{}),
+	}, nil
+}
+
+// Generate generates the missing thumbnails of a file and returns how many were generated.
+// The thumbnails use the default processor and the types a client gets with or without asking for webp.
+// A panic while the file is decoded is returned as an error.
+func (p *Pregenerator) Generate(ctx context.Context, ref *provider.Reference) (n int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not generate the thumbnails: %v", r)
+		}
+	}()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	gwc, err := p.gatewaySelector.Next()
+	if err != nil {
+		return 0, err
+	}
+	token, err := utils.GetServiceUserToken(ctx, gwc, p.serviceAccount.ServiceAccountID, p.serviceAccount.ServiceAccountSecret)
+	if err != nil {
+		return 0, fmt.Errorf("could not authenticate the service account: %w", err)
+	}
+
+	sRes, err := gwc.Stat(metadata.AppendToOutgoingContext(ctx, revactx.TokenHeader, token), &provider.StatRequest{Ref: ref})
+	switch {
+	case err != nil:
+		return 0, err
+	case sRes.GetStatus().GetCode() != rpc.Code_CODE_OK:
+		return 0, fmt.Errorf("could not stat file: %s", sRes.GetStatus().GetMessage())
+	}
+	info := sRes.GetInfo()
+	if info.GetType() != provider.ResourceType_RESOURCE_TYPE_FILE || !thumbnail.IsMimeTypeSupported(info.GetMimeType()) ||
+		info.GetChecksum().GetSum() == "" || utils.ReadPlainFromOpaque(info.GetOpaque(), "status") == "processing" {
+		return 0, ErrSkipped
+	}
+
+	tTypes := []string{thumbnail.TypeFor(info.GetMimeType(), "jpg")}
+	if t := thumbnail.TypeFor(info.GetMimeType(), "webp"); t != tTypes[0] {
+		tTypes = append(tTypes, t)
+	}
+	var missing []thumbnail.Request
+	for _, tType := range tTypes {
+		for _, r := range p.resolutions {
+			tr, err := thumbnail.PrepareRequest(r.Dx(), r.Dy(), tType, info.GetChecksum().GetSum(), "")
+			if err != nil {
+				return 0, err
+			}
+			if _, exists := p.manager.CheckThumbnail(tr); !exists {
+				missing = append(missing, tr)
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	r, err := p.source.Get(imgsource.ContextSetAuthorization(ctx, token), storagespace.FormatResourceID(info.GetId()))
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	pp := preprocessor.ForType(info.GetMimeType(), map[string]interface{}{
+		"fontFileMap": p.fontFileMap,
+	})
+	img, err := pp.Convert(r)
+	if err != nil {
+		return 0, err
+	}
+	if img == nil {
+		return 0, errors.New("could not get image")
+	}
+
+	for _, tr := range missing {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+		// several resolutions can match the same stored thumbnail, it is only generated once
+		if _, exists := p.manager.CheckThumbnail(tr); exists {
+			continue
+		}
+		if _, err := p.manager.Generate(tr, img); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Run generates the thumbnails of uploaded files until Close is called. The uploads are
+// processed by the configured number of workers.
+func (p *Pregenerator) Run(bus events.Consumer) error {
+	ch, err := events.Consume(bus, "thumbnails", events.UploadReady{})
+	if err != nil {
+		return err
+	}
+
+	refs := make(chan *provider.Reference)
+	var wg sync.WaitGroup
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range refs {
+				p.generate(ref)
+			}
+		}()
+	}
+	defer func() {
+		close(refs)
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case <-p.stop:
+			return nil
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			ev, ok := e.Event.(events.UploadReady)
+			if !ok || ev.Failed {
+				continue
+			}
+			ref := ev.FileRef
+			if ev.ResourceID != nil {
+				ref = &provider.Reference{ResourceId: ev.ResourceID}
+			}
+			select {
+			case refs <- ref:
+			case <-p.stop:
+				return nil
+			}
+		}
+	}
+}
+
+// Close stops Run, the thumbnails which are being generated are finished.
+func (p *Pregenerator) Close() {
+	close(p.stop)
+}
+
+func (p *Pregenerator) generate(ref *provider.Reference) {
+	n, err := p.Generate(context.Background(), ref)
+	switch {
+	case errors.Is(err, ErrSkipped):
+	case errors.Is(err, terrors.ErrImageTooLarge):
+		p.logger.Debug().Interface("ref", ref).Msg("image too large to pregenerate thumbnails")
+	case err != nil:
+		p.logger.Error().Err(err).Interface("ref", ref).Msg("could not pregenerate thumbnails")
+	case n > 0:
+		p.logger.Debug().Interface("ref", ref).Int("count", n).Msg("pregenerated thumbnails")
+	}
+}
+
+// BackfillResult sums up a backfill.
+type BackfillResult struct {
+	Files      int
+	Thumbnails int
+	Failed     int
+}
+
+// Backfill generates the missing thumbnails of all files in a space. The files are processed by
+// the configured number of workers, failures are logged and counted.
+func (p *Pregenerator) Backfill(ctx context.Context, spaceID string) (BackfillResult, error) {
+	var result BackfillResult
+	rid, err := storagespace.ParseID(spaceID)
+	if err != nil {
+		return result, err
+	}
+	if rid.GetOpaqueId() == "" {
+		// the root of a space has the id of the space
+		rid.OpaqueId = rid.GetSpaceId()
+	}
+
+	gwc, err := p.gatewaySelector.Next()
+	if err != nil {
+		return result, err
+	}
+	// Generate authenticates on its own, the context of the service user is only used to walk the space
+	walkCtx, err := utils.GetServiceUserContextWithContext(ctx, gwc, p.serviceAccount.ServiceAccountID, p.serviceAccount.ServiceAccountSecret)
+	if err != nil {
+		return result, fmt.Errorf("could not authenticate the service account: %w", err)
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		refs = make(chan *provider.Reference)
+	)
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range refs {
+				n, err := p.Generate(ctx, ref)
+				if errors.Is(err, ErrSkipped) {
+					continue
+				}
+				if err != nil {
+					p.logger.Error().Err(err).Interface("ref", ref).Msg("could not generate thumbnails")
+				}
+				mu.Lock()
+				result.Files++
+				result.Thumbnails += n
+				if err != nil {
+					result.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	err = walker.NewWalker(p.gatewaySelector).Walk(walkCtx, &rid, func(_ string, info *provider.ResourceInfo, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case info.GetType() == provider.ResourceType_RESOURCE_TYPE_CONTAINER:
+			return nil
+		case !thumbnail.IsMimeTypeSupported(info.GetMimeType()):
+			return nil
+		}
+		select {
+		case refs <- &provider.Reference{ResourceId: info.GetId()}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(refs)
+	wg.Wait()
+	return result, err
+}
@@ -0,0 +1,159 @@
+package pregeneration_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/pregeneration"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+// source serves a png for every path and counts the downloads
+type source struct {
+	downloads atomic.Int32
+	panics    bool
+}
+
+func (s *source) Get(_ context.Context, _ string) (io.ReadCloser, error) {
+	s.downloads.Add(1)
+	if s.panics {
+		panic("broken source")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func resource(id, mimeType string, typ provider.ResourceType) *provider.ResourceInfo {
+	return &provider.ResourceInfo{
+		Id:       &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: id},
+		Path:     id,
+		Type:     typ,
+		MimeType: mimeType,
+		Checksum: &provider.ResourceChecksum{Sum: "97f9c4c8db98f7b82e768ef478d3c8612" + id},
+	}
+}
+
+func setup(t *testing.T, resources ...*provider.ResourceInfo) (*pregeneration.Pregenerator, *source, storage.Storage) {
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: status.NewOK(context.Background()), Token: "token"}, nil)
+	for _, r := range resources {
+		r := r
+		gatewayClient.On("Stat", mock.Anything, mock.MatchedBy(func(req *provider.StatRequest) bool {
+			return req.GetRef().GetResourceId().GetOpaqueId() == r.GetId().GetOpaqueId()
+		})).Return(&provider.StatResponse{Status: status.NewOK(context.Background()), Info: r}, nil)
+	}
+	gatewayClient.On("ListContainer", mock.Anything, mock.Anything).Return(&provider.ListContainerResponse{Status: status.NewOK(context.Background()), Infos: resources[1:]}, nil)
+
+	cfg := &config.Config{
+		Thumbnail: config.Thumbnail{
+			Resolutions:   []string{"16x16", "32x32"},
+			Pregeneration: config.Pregeneration{Workers: 2},
+		},
+	}
+	s := storage.NewFileSystemStorage(config.FileSystemStorage{RootDirectory: t.TempDir()}, log.NopLogger())
+	resolutions, err := thumbnail.ParseResolutions(cfg.Thumbnail.Resolutions)
+	require.NoError(t, err)
+	src := &source{}
+	p, err := pregeneration.New(cfg, thumbnail.NewSimpleManager(resolutions, s, log.NopLogger(), 7680, 7680), src, gatewaySelector, log.NopLogger())
+	require.NoError(t, err)
+	return p, src, s
+}
+
+// count returns the number of stored thumbnails, the dimensions of the sources are not counted
+func count(t *testing.T, s storage.Storage) int {
+	n := 0
+	require.NoError(t, s.Walk(func(e storage.Entry) error {
+		if k, ok := storage.ParseKey(e.Key); ok && k.Type != storage.TypeSource {
+			n++
+		}
+		return nil
+	}))
+	return n
+}
+
+func TestGenerate(t *testing.T) {
+	file := resource("file", "image/png", provider.ResourceType_RESOURCE_TYPE_FILE)
+	p, src, s := setup(t, file)
+	ref := &provider.Reference{ResourceId: file.GetId()}
+
+	n, err := p.Generate(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 2, count(t, s))
+	assert.Equal(t, int32(1), src.downloads.Load(), "the source is downloaded once for all resolutions")
+
+	// the thumbnails exist now
+	n, err = p.Generate(context.Background(), ref)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, int32(1), src.downloads.Load())
+}
+
+func TestGeneratePanic(t *testing.T) {
+	file := resource("file", "image/png", provider.ResourceType_RESOURCE_TYPE_FILE)
+	p, src, s := setup(t, file)
+	src.panics = true
+
+	n, err := p.Generate(context.Background(), &provider.Reference{ResourceId: file.GetId()})
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.Zero(t, count(t, s))
+}
+
+func TestGenerateSkipped(t *testing.T) {
+	for _, r := range []*provider.ResourceInfo{
+		resource("folder", "httpd/unix-directory", provider.ResourceType_RESOURCE_TYPE_CONTAINER),
+		resource("zip", "application/zip", provider.ResourceType_RESOURCE_TYPE_FILE),
+	} {
+		p, src, _ := setup(t, r)
+		_, err := p.Generate(context.Background(), &provider.Reference{ResourceId: r.GetId()})
+		assert.ErrorIs(t, err, pregeneration.ErrSkipped)
+		assert.Zero(t, src.downloads.Load())
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	root := resource("space", "httpd/unix-directory", provider.ResourceType_RESOURCE_TYPE_CONTAINER)
+	p, _, s := setup(t,
+		root,
+		resource("a", "image/png", provider.ResourceType_RESOURCE_TYPE_FILE),
+		resource("b", "image/jpeg", provider.ResourceType_RESOURCE_TYPE_FILE),
+		resource("c", "application/zip", provider.ResourceType_RESOURCE_TYPE_FILE),
+	)
+
+	result, err := p.Backfill(context.Background(), storagespace.FormatStorageID("storage", "space"))
+	require.NoError(t, err)
+	assert.Equal(t, pregeneration.BackfillResult{Files: 2, Thumbnails: 4}, result)
+	assert.Equal(t, 4, count(t, s))
+}
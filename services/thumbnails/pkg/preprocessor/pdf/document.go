@@ -0,0 +1,490 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrEncrypted is returned for encrypted documents, they can't be rendered
+	ErrEncrypted = errors.New("pdf: encrypted documents are not supported")
+	// ErrNoPage is returned if the document has no page
+	ErrNoPage = errors.New("pdf: no page found")
+	// ErrUnsupportedFilter is returned for streams which are encoded with a filter that is not supported
+	ErrUnsupportedFilter = errors.New("pdf: unsupported filter")
+
+	_objectHeader = regexp.MustCompile(`(?:^|[\s>\]}])(\d{1,10})\s+(\d{1,5})\s+obj\b`)
+)
+
+// maximum size of a decoded stream, protects against decompression bombs
+const _maxStreamSize = 256 << 20
+
+// document gives access to the objects of a pdf file. The objects are located by scanning the file
+// instead of reading the cross-reference table, so damaged files and files with cross-reference streams
+// are read the same way.
+type document struct {
+	data    []byte
+	offsets map[int]int
+	// compressed are the objects stored in object streams, it maps the object number to the stream number
+	compressed map[int]int
+	objStreams map[int]*objectStream
+	cache      map[int]interface{}
+	resolving  map[int]bool
+}
+
+// objectStream is a decoded object stream, index maps the object numbers to their offset in data
+type objectStream struct {
+	data  []byte
+	index map[int]int
+}
+
+func newDocument(data []byte) (*document, error) {
+	d := &document{
+		data:       data,
+		offsets:    map[int]int{},
+		objStreams: map[int]*objectStream{},
+		cache:      map[int]interface{}{},
+		resolving:  map[int]bool{},
+	}
+
+	// later definitions of an object replace earlier ones, that's how incremental updates work
+	for _, m := range _objectHeader.FindAllSubmatchIndex(data, -1) {
+		num, err := strconv.Atoi(string(data[m[2]:m[3]]))
+		if err != nil {
+			continue
+		}
+		d.offsets[num] = m[2]
+	}
+	if len(d.offsets) == 0 {
+		return nil, errSyntax
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) && d.trailerValue("Encrypt") != nil {
+		return nil, ErrEncrypted
+	}
+	return d, nil
+}
+
+// trailerValue returns a value of the trailer, the trailer dictionary of the last update or the
+// dictionary of the last cross-reference stream is used
+func (d *document) trailerValue(key name) interface{} {
+	k := []byte("/" + string(key))
+	for end := len(d.data); end > 0; {
+		i := bytes.LastIndex(d.data[:end], k)
+		if i < 0 {
+			return nil
+		}
+		end = i
+		// the key belongs to the dictionary starting at one of the preceding '<<', nested dictionaries
+		// like /DecodeParms may start in between
+		start := i
+		for tries := 0; tries < 8; tries++ {
+			start = bytes.LastIndex(d.data[:start], []byte("<<"))
+			if start < 0 {
+				return nil
+			}
+			if hdr := d.dict(d.objectAt(start)); hdr != nil && hdr["Root"] != nil && hdr[key] != nil {
+				return hdr[key]
+			}
+		}
+	}
+	return nil
+}
+
+func (d *document) objectAt(pos int) interface{} {
+	obj, err := d.parser(pos).object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+func (d *document) parser(pos int) *parser {
+	return &parser{
+		lexer: lexer{data: d.data, pos: pos},
+		length: func(v interface{}) (int, bool) {
+			n, ok := d.resolve(v).(float64)
+			return int(n), ok
+		},
+	}
+}
+
+// resolve returns the object a reference points to, other values are returned as they are
+func (d *document) resolve(v interface{}) interface{} {
+	r, ok := v.(ref)
+	if !ok {
+		return v
+	}
+	if obj, ok := d.cache[r.num]; ok {
+		return obj
+	}
+	if d.resolving[r.num] {
+		// reference cycle
+		return nil
+	}
+	d.resolving[r.num] = true
+	defer delete(d.resolving, r.num)
+
+	obj := d.load(r.num)
+	d.cache[r.num] = obj
+	return obj
+}
+
+func (d *document) load(num int) interface{} {
+	if off, ok := d.offsets[num]; ok {
+		p := d.parser(off)
+		// skip 'num gen obj'
+		for i := 0; i < 3; i++ {
+			if _, err := p.token(); err != nil {
+				return nil
+			}
+		}
+		obj, err := p.object()
+		if err != nil {
+			return nil
+		}
+		return obj
+	}
+
+	if d.compressed == nil {
+		d.indexObjectStreams()
+	}
+	if stm, ok := d.compressed[num]; ok {
+		return d.loadCompressed(stm, num)
+	}
+	return nil
+}
+
+// indexObjectStreams finds the objects which are stored in object streams
+func (d *document) indexObjectStreams() {
+	d.compressed = map[int]int{}
+
+	nums := make([]int, 0, len(d.offsets))
+	for num := range d.offsets {
+		nums = append(nums, num)
+	}
+	// later streams replace the objects of earlier ones
+	sort.Slice(nums, func(i, j int) bool { return d.offsets[nums[i]] < d.offsets[nums[j]] })
+
+	for _, num := range nums {
+		off := d.offsets[num]
+		// cheap check before parsing the object
+		head := d.data[off:min(off+512, len(d.data))]
+		if !bytes.Contains(head, []byte("/ObjStm")) {
+			continue
+		}
+		ostm := d.objectStream(num)
+		if ostm == nil {
+			continue
+		}
+		for objNum := range ostm.index {
+			d.compressed[objNum] = num
+		}
+	}
+}
+
+// objectStream returns the decoded object stream with the given object number
+func (d *document) objectStream(num int) *objectStream {
+	if ostm, ok := d.objStreams[num]; ok {
+		return ostm
+	}
+	d.objStreams[num] = nil
+
+	s, ok := d.resolve(ref{num: num}).(stream)
+	if !ok || s.hdr["Type"] != name("ObjStm") {
+		return nil
+	}
+	data, err := d.decode(s)
+	if err != nil {
+		return nil
+	}
+	n, _ := d.number(s.hdr["N"])
+	first, _ := d.number(s.hdr["First"])
+
+	p := parser{lexer: lexer{data: data}}
+	ostm := &objectStream{data: data, index: map[int]int{}}
+	for i := 0; i < int(n); i++ {
+		num, err1 := p.token()
+		off, err2 := p.token()
+		if err1 != nil || err2 != nil {
+			break
+		}
+		numF, ok1 := num.(float64)
+		offF, ok2 := off.(float64)
+		if !ok1 || !ok2 {
+			break
+		}
+		ostm.index[int(numF)] = int(first) + int(offF)
+	}
+	d.objStreams[num] = ostm
+	return ostm
+}
+
+func (d *document) loadCompressed(stm, num int) interface{} {
+	ostm := d.objectStream(stm)
+	if ostm == nil {
+		return nil
+	}
+	pos, ok := ostm.index[num]
+	if !ok || pos < 0 || pos >= len(ostm.data) {
+		return nil
+	}
+	p := parser{lexer: lexer{data: ostm.data, pos: pos}}
+	obj, err := p.object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// dict resolves a value to a dictionary, the dictionary of a stream is returned for streams
+func (d *document) dict(v interface{}) dict {
+	switch v := d.resolve(v).(type) {
+	case dict:
+		return v
+	case stream:
+		return v.hdr
+	}
+	return nil
+}
+
+func (d *document) number(v interface{}) (float64, bool) {
+	f, ok := d.resolve(v).(float64)
+	return f, ok
+}
+
+func (d *document) array(v interface{}) array {
+	a, _ := d.resolve(v).(array)
+	return a
+}
+
+func (d *document) numbers(v interface{}) []float64 {
+	a := d.array(v)
+	out := make([]float64, 0, len(a))
+	for _, e := range a {
+		f, ok := d.number(e)
+		if !ok {
+			return nil
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// firstPage returns the first page of the document with its inherited attributes
+func (d *document) firstPage() (dict, error) {
+	catalog := d.dict(d.trailerValue("Root"))
+	if catalog == nil {
+		catalog = d.findCatalog()
+	}
+	if catalog == nil {
+		return nil, ErrNoPage
+	}
+
+	inherited := dict{}
+	node := d.dict(catalog["Pages"])
+	for depth := 0; node != nil && depth < 64; depth++ {
+		for _, k := range []name{"Resources", "MediaBox", "CropBox", "Rotate"} {
+			if v, ok := node[k]; ok {
+				inherited[k] = v
+			}
+		}
+		kids := d.array(node["Kids"])
+		if node["Type"] == name("Page") || kids == nil {
+			page := dict{}
+			for k, v := range inherited {
+				page[k] = v
+			}
+			for k, v := range node {
+				page[k] = v
+			}
+			return page, nil
+		}
+		if len(kids) == 0 {
+			break
+		}
+		node = d.dict(kids[0])
+	}
+	return nil, ErrNoPage
+}
+
+// findCatalog searches the catalog of a document without a readable trailer
+func (d *document) findCatalog() dict {
+	for num := range d.offsets {
+		if c := d.dict(ref{num: num}); c["Type"] == name("Catalog") {
+			return c
+		}
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream, image filters are left in place
+func (d *document) decode(s stream) ([]byte, error) {
+	data := s.data
+	filters, params := d.filters(s.hdr)
+	for i, f := range filters {
+		var err error
+		switch f {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil {
+				data, err = d.unpredict(data, params[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data, err = asciiHex(data)
+		case "ASCII85Decode", "A85":
+			data, err = ascii85Decode(data)
+		default:
+			return data, ErrUnsupportedFilter
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// filters returns the filters of a stream and their parameters
+func (d *document) filters(hdr dict) ([]name, []dict) {
+	var filters []name
+	var params []dict
+	switch f := d.resolve(hdr["Filter"]).(type) {
+	case name:
+		filters = []name{f}
+		params = []dict{d.dict(hdr["DecodeParms"])}
+	case array:
+		ps := d.array(hdr["DecodeParms"])
+		for i, v := range f {
+			n, _ := d.resolve(v).(name)
+			filters = append(filters, n)
+			var p dict
+			if i < len(ps) {
+				p = d.dict(ps[i])
+			}
+			params = append(params, p)
+		}
+	}
+	return filters, params
+}
+
+// unpredict reverses the png predictors of flate encoded data
+func (d *document) unpredict(data []byte, params dict) ([]byte, error) {
+	predictor, _ := d.number(params["Predictor"])
+	if predictor < 10 {
+		return data, nil
+	}
+	columns, ok := d.number(params["Columns"])
+	if !ok {
+		columns = 1
+	}
+	colors, ok := d.number(params["Colors"])
+	if !ok {
+		colors = 1
+	}
+	bits, ok := d.number(params["BitsPerComponent"])
+	if !ok {
+		bits = 8
+	}
+	// the parameters come from the file, they are checked before they are used for allocations
+	if !(columns >= 1 && colors >= 1 && bits >= 1 && columns*colors*bits <= _maxStreamSize*8) {
+		return nil, errSyntax
+	}
+	bpp := max(1, int(colors*bits+7)/8)
+	rowLen := (int(columns*colors*bits) + 7) / 8
+	if rowLen <= 0 || rowLen > _maxStreamSize {
+		return nil, errSyntax
+	}
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for len(data) > rowLen {
+		typ, row := data[0], append([]byte{}, data[1:rowLen+1]...)
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			switch typ {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += prev[i]
+			case 3:
+				row[i] += byte((int(left) + int(prev[i])) / 2)
+			case 4:
+				row[i] += paeth(left, prev[i], upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, _maxStreamSize))
+	// many producers write truncated streams, use what could be read
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func asciiHex(data []byte) ([]byte, error) {
+	if i := bytes.IndexByte(data, '>'); i >= 0 {
+		data = data[:i]
+	}
+	digits := make([]byte, 0, len(data))
+	for _, c := range data {
+		if !isWhitespace(c) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	_, err := hex.Decode(out, digits)
+	return out, err
+}
+
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
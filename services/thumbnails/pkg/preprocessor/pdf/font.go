@@ -0,0 +1,206 @@
+package pdf
+
+import (
+	"unicode/utf16"
+)
+
+// font maps the strings shown with a pdf font to text and glyph widths. The glyphs themselves are
+// not rendered, the text is drawn with a default font instead.
+type font struct {
+	// twoByte is set for composite fonts with two byte codes
+	twoByte   bool
+	toUnicode map[uint32]string
+	// widths are in thousandths of the font size
+	widths       map[uint32]float64
+	defaultWidth float64
+}
+
+// glyph is a single character code of a shown string
+type glyph struct {
+	code  uint32
+	text  string
+	width float64
+	// space is set for the single byte code 32, word spacing applies to it
+	space bool
+}
+
+func (d *document) loadFont(v interface{}) *font {
+	fd := d.dict(v)
+	if fd == nil {
+		return &font{}
+	}
+
+	f := &font{widths: map[uint32]float64{}}
+	if fd["Subtype"] == name("Type0") {
+		f.twoByte = true
+		descendants := d.array(fd["DescendantFonts"])
+		if len(descendants) > 0 {
+			cid := d.dict(descendants[0])
+			if dw, ok := d.number(cid["DW"]); ok {
+				f.defaultWidth = dw
+			} else {
+				f.defaultWidth = 1000
+			}
+			f.loadCIDWidths(d, d.array(cid["W"]))
+		}
+	} else {
+		first, _ := d.number(fd["FirstChar"])
+		for i, w := range d.numbers(fd["Widths"]) {
+			f.widths[uint32(int(first)+i)] = w
+		}
+	}
+
+	if s, ok := d.resolve(fd["ToUnicode"]).(stream); ok {
+		if data, err := d.decode(s); err == nil {
+			f.toUnicode = parseToUnicode(data)
+		}
+	}
+	return f
+}
+
+// loadCIDWidths reads the /W array of a CID font, it has the forms 'c [w1 w2 ...]' and 'cfirst clast w'
+func (f *font) loadCIDWidths(d *document, w array) {
+	for i := 0; i < len(w); {
+		first, ok := d.number(w[i])
+		if !ok || i+1 >= len(w) {
+			return
+		}
+		if ws, ok := d.resolve(w[i+1]).(array); ok {
+			for j, v := range ws {
+				if n, ok := d.number(v); ok {
+					f.widths[uint32(int(first)+j)] = n
+				}
+			}
+			i += 2
+			continue
+		}
+		last, ok1 := d.number(w[i+1])
+		if i+2 >= len(w) {
+			return
+		}
+		width, ok2 := d.number(w[i+2])
+		if !ok1 || !ok2 || last-first > 0xffff {
+			return
+		}
+		for c := int(first); c <= int(last); c++ {
+			f.widths[uint32(c)] = width
+		}
+		i += 3
+	}
+}
+
+// glyphs splits a shown string into its glyphs, the width of glyphs without a known width is 0
+func (f *font) glyphs(s []byte) []glyph {
+	var out []glyph
+	for i := 0; i < len(s); {
+		var code uint32
+		if f.twoByte && i+1 < len(s) {
+			code = uint32(s[i])<<8 | uint32(s[i+1])
+			i += 2
+		} else {
+			code = uint32(s[i])
+			i++
+		}
+
+		g := glyph{code: code, space: !f.twoByte && code == 32}
+		if t, ok := f.toUnicode[code]; ok {
+			g.text = t
+		} else if !f.twoByte {
+			g.text = string(winAnsi(byte(code)))
+		}
+		if w, ok := f.widths[code]; ok {
+			g.width = w
+		} else if f.twoByte {
+			g.width = f.defaultWidth
+		}
+		out = append(out, g)
+	}
+	return out
+}
+
+// the characters of WinAnsiEncoding which differ from Latin-1, it's used for simple fonts without
+// a ToUnicode map
+var _winAnsi = map[byte]rune{
+	0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡', 0x88: 'ˆ', 0x89: '‰',
+	0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•',
+	0x96: '–', 0x97: '—', 0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+}
+
+func winAnsi(c byte) rune {
+	if r, ok := _winAnsi[c]; ok {
+		return r
+	}
+	return rune(c)
+}
+
+// parseToUnicode reads the bfchar and bfrange mappings of a ToUnicode CMap
+func parseToUnicode(data []byte) map[uint32]string {
+	m := map[uint32]string{}
+	p := parser{lexer: lexer{data: data}}
+
+	var operands []interface{}
+	for {
+		obj, err := p.object()
+		if err != nil {
+			return m
+		}
+		kw, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].([]byte)
+				dst, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 {
+					m[code(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if !ok1 || !ok2 || code(hi) < code(lo) || code(hi)-code(lo) > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case []byte:
+					base := []rune(utf16BE(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for c := code(lo); c <= code(hi); c++ {
+						r := append([]rune{}, base...)
+						r[len(r)-1] += rune(c - code(lo))
+						m[c] = string(r)
+					}
+				case array:
+					for j, v := range dst {
+						if b, ok := v.([]byte); ok {
+							m[code(lo)+uint32(j)] = utf16BE(b)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func code(b []byte) uint32 {
+	var c uint32
+	for _, v := range b {
+		c = c<<8 | uint32(v)
+	}
+	return c
+}
+
+func utf16BE(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
@@ -0,0 +1,241 @@
+package pdf
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+// colorSpace describes how the samples of an image are mapped to colors
+type colorSpace struct {
+	components int
+	// lookup is the color table of an indexed color space, it holds rgb triples
+	lookup []byte
+}
+
+// image decodes an image xobject, images with unsupported filters or color spaces are nil. Stencil
+// masks are painted with the fill color.
+func (d *document) image(s stream, resources dict, fill color.RGBA) image.Image {
+	filters, _ := d.filters(s.hdr)
+	if len(filters) > 0 {
+		switch filters[len(filters)-1] {
+		case "DCTDecode", "DCT":
+			data := s.data
+			if len(filters) > 1 {
+				var err error
+				inner := stream{hdr: dict{"Filter": namesToArray(filters[:len(filters)-1])}, data: s.data}
+				if data, err = d.decode(inner); err != nil {
+					return nil
+				}
+			}
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil
+			}
+			return img
+		case "JPXDecode", "CCITTFaxDecode", "JBIG2Decode", "RunLengthDecode", "LZWDecode", "LZW", "RL", "CCF":
+			return nil
+		}
+	}
+
+	data, err := d.decode(s)
+	if err != nil {
+		return nil
+	}
+
+	w, _ := d.number(s.hdr["Width"])
+	h, _ := d.number(s.hdr["Height"])
+	width, height := int(w), int(h)
+	if width <= 0 || height <= 0 || width > 1<<14 || height > 1<<14 {
+		return nil
+	}
+
+	decode := d.numbers(s.hdr["Decode"])
+	if mask, _ := d.resolve(s.hdr["ImageMask"]).(bool); mask {
+		return stencil(data, width, height, fill, len(decode) == 2 && decode[0] == 1)
+	}
+
+	bpc, ok := d.number(s.hdr["BitsPerComponent"])
+	if !ok {
+		bpc = 8
+	}
+	cs, ok := d.colorSpace(s.hdr["ColorSpace"], resources)
+	if !ok {
+		return nil
+	}
+	return samples(data, width, height, int(bpc), cs)
+}
+
+func namesToArray(names []name) array {
+	a := make(array, len(names))
+	for i, n := range names {
+		a[i] = n
+	}
+	return a
+}
+
+// colorSpace resolves the supported color spaces: device, calibrated, icc based and indexed color spaces
+func (d *document) colorSpace(v interface{}, resources dict) (colorSpace, bool) {
+	v = d.resolve(v)
+	if n, ok := v.(name); ok {
+		switch n {
+		case "DeviceGray", "G", "CalGray":
+			return colorSpace{components: 1}, true
+		case "DeviceRGB", "RGB", "CalRGB":
+			return colorSpace{components: 3}, true
+		case "DeviceCMYK", "CMYK":
+			return colorSpace{components: 4}, true
+		}
+		// a named color space of the resources
+		if named, ok := d.dict(resources["ColorSpace"])[n]; ok {
+			return d.colorSpace(named, nil)
+		}
+		return colorSpace{}, false
+	}
+
+	a, ok := v.(array)
+	if !ok || len(a) == 0 {
+		return colorSpace{}, false
+	}
+	switch d.resolve(a[0]) {
+	case name("CalGray"):
+		return colorSpace{components: 1}, true
+	case name("CalRGB"), name("Lab"):
+		return colorSpace{components: 3}, true
+	case name("ICCBased"):
+		if len(a) < 2 {
+			return colorSpace{}, false
+		}
+		hdr := d.dict(a[1])
+		if n, ok := d.number(hdr["N"]); ok && (n == 1 || n == 3 || n == 4) {
+			return colorSpace{components: int(n)}, true
+		}
+		if alt, ok := hdr["Alternate"]; ok {
+			return d.colorSpace(alt, resources)
+		}
+	case name("Indexed"), name("I"):
+		if len(a) < 4 {
+			return colorSpace{}, false
+		}
+		base, ok := d.colorSpace(a[1], resources)
+		if !ok || base.lookup != nil {
+			return colorSpace{}, false
+		}
+		var table []byte
+		switch t := d.resolve(a[3]).(type) {
+		case []byte:
+			table = t
+		case stream:
+			table, _ = d.decode(t)
+		}
+		// the lookup table is converted to rgb
+		lookup := make([]byte, 0, len(table)/base.components*3)
+		for i := 0; i+base.components <= len(table); i += base.components {
+			c := sampleColor(table[i:i+base.components], 8)
+			lookup = append(lookup, c.R, c.G, c.B)
+		}
+		return colorSpace{components: 1, lookup: lookup}, true
+	}
+	return colorSpace{}, false
+}
+
+// sampleColor converts the samples of a pixel to a color, the number of samples defines the color space
+func sampleColor(s []byte, bpc int) color.RGBA {
+	scale := func(v byte) uint8 {
+		switch bpc {
+		case 1:
+			return v * 0xff
+		case 2:
+			return v * 0x55
+		case 4:
+			return v * 0x11
+		}
+		return v
+	}
+	switch len(s) {
+	case 1:
+		g := scale(s[0])
+		return color.RGBA{R: g, G: g, B: g, A: 0xff}
+	case 3:
+		return color.RGBA{R: scale(s[0]), G: scale(s[1]), B: scale(s[2]), A: 0xff}
+	case 4:
+		k := 0xff - uint32(scale(s[3]))
+		return color.RGBA{
+			R: uint8((0xff - uint32(scale(s[0]))) * k / 0xff),
+			G: uint8((0xff - uint32(scale(s[1]))) * k / 0xff),
+			B: uint8((0xff - uint32(scale(s[2]))) * k / 0xff),
+			A: 0xff,
+		}
+	}
+	return color.RGBA{A: 0xff}
+}
+
+// samples builds an image from raw samples, the rows are padded to full bytes
+func samples(data []byte, width, height, bpc int, cs colorSpace) image.Image {
+	if bpc != 1 && bpc != 2 && bpc != 4 && bpc != 8 {
+		return nil
+	}
+	rowLen := (width*cs.components*bpc + 7) / 8
+	if len(data) < rowLen*height {
+		// use the rows which are there
+		height = len(data) / rowLen
+		if height == 0 {
+			return nil
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	px := make([]byte, cs.components)
+	for y := 0; y < height; y++ {
+		row := data[y*rowLen : (y+1)*rowLen]
+		for x := 0; x < width; x++ {
+			for c := range px {
+				px[c] = sampleAt(row, x*cs.components+c, bpc)
+			}
+			if cs.lookup != nil {
+				i := int(px[0]) * 3
+				if i+3 > len(cs.lookup) {
+					continue
+				}
+				img.SetRGBA(x, y, color.RGBA{R: cs.lookup[i], G: cs.lookup[i+1], B: cs.lookup[i+2], A: 0xff})
+				continue
+			}
+			img.SetRGBA(x, y, sampleColor(px, bpc))
+		}
+	}
+	return img
+}
+
+// sampleAt returns the i-th sample of a row
+func sampleAt(row []byte, i, bpc int) byte {
+	if bpc == 8 {
+		return row[i]
+	}
+	bit := i * bpc
+	shift := 8 - bpc - bit%8
+	return row[bit/8] >> shift & (1<<bpc - 1)
+}
+
+// stencil builds the image of a stencil mask, the samples with value 0 are painted unless the mask is
+// inverted
+func stencil(data []byte, width, height int, fill color.RGBA, inverted bool) image.Image {
+	rowLen := (width + 7) / 8
+	if len(data) < rowLen*height {
+		height = len(data) / rowLen
+		if height == 0 {
+			return nil
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := data[y*rowLen : (y+1)*rowLen]
+		for x := 0; x < width; x++ {
+			if (sampleAt(row, x, 1) == 0) != inverted {
+				img.SetRGBA(x, y, fill)
+			}
+		}
+	}
+	return img
+}
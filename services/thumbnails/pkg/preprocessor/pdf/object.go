@@ -0,0 +1,348 @@
+package pdf
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"strconv"
+)
+
+// the objects of a pdf file, numbers are always float64 and strings are []byte
+type (
+	name    string
+	keyword string
+	array   []interface{}
+	dict    map[name]interface{}
+	ref     struct{ num, gen int }
+	stream  struct {
+		hdr  dict
+		data []byte
+	}
+)
+
+var errSyntax = errors.New("pdf: syntax error")
+
+func isWhitespace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// lexer splits pdf data into tokens
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isWhitespace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the next token, the kind of a token is given by its go type: float64, name, []byte,
+// keyword or one of the delimiter keywords "<<", ">>", "[" and "]"
+func (l *lexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errSyntax
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		start := l.pos
+		for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return name(unescapeName(l.data[start:l.pos])), nil
+	case c == '(':
+		return l.literalString()
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return keyword("<<"), nil
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return keyword(">>"), nil
+	case c == '<':
+		return l.hexString()
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return keyword(l.data[l.pos-1 : l.pos]), nil
+	case c == ')' || c == '>':
+		l.pos++
+		return nil, errSyntax
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isWhitespace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	tok := l.data[start:l.pos]
+	if c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9') {
+		if f, err := strconv.ParseFloat(string(tok), 64); err == nil {
+			return f, nil
+		}
+		// some producers write numbers like '--1' or '1.2.3'
+		if f, ok := lenientNumber(tok); ok {
+			return f, nil
+		}
+	}
+	return keyword(tok), nil
+}
+
+func lenientNumber(tok []byte) (float64, bool) {
+	neg := false
+	for len(tok) > 0 && (tok[0] == '-' || tok[0] == '+') {
+		neg = neg || tok[0] == '-'
+		tok = tok[1:]
+	}
+	end := 0
+	dot := false
+	for end < len(tok) && (tok[end] >= '0' && tok[end] <= '9' || tok[end] == '.' && !dot) {
+		dot = dot || tok[end] == '.'
+		end++
+	}
+	f, err := strconv.ParseFloat(string(tok[:end]), 64)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
+
+func unescapeName(b []byte) string {
+	if bytes.IndexByte(b, '#') < 0 {
+		return string(b)
+	}
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] == '#' && i+2 < len(b) {
+			if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+				out = append(out, byte(v))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, b[i])
+	}
+	return string(out)
+}
+
+func (l *lexer) literalString() ([]byte, error) {
+	l.pos++ // (
+	var out []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, nil
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out, nil
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					v := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (l *lexer) hexString() ([]byte, error) {
+	l.pos++ // <
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isWhitespace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // >
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	if _, err := hex.Decode(out, digits); err != nil {
+		return nil, errSyntax
+	}
+	return out, nil
+}
+
+// parser builds objects from the tokens of a lexer
+type parser struct {
+	lexer
+	// length resolves indirect stream lengths, it may be nil
+	length func(v interface{}) (int, bool)
+}
+
+// object parses the next object, keywords which aren't part of an object are returned as they are
+func (p *parser) object() (interface{}, error) {
+	return p.objectDepth(0)
+}
+
+func (p *parser) objectDepth(depth int) (interface{}, error) {
+	if depth > 64 {
+		return nil, errSyntax
+	}
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case float64:
+		// a number may start an indirect reference 'num gen R'
+		save := p.pos
+		if gen, err := p.token(); err == nil {
+			if g, ok := gen.(float64); ok {
+				if r, err := p.token(); err == nil && r == keyword("R") {
+					return ref{num: int(t), gen: int(g)}, nil
+				}
+			}
+		}
+		p.pos = save
+		return t, nil
+	case keyword:
+		switch t {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "[":
+			arr := array{}
+			for {
+				p.skipSpace()
+				if p.pos < len(p.data) && p.data[p.pos] == ']' {
+					p.pos++
+					return arr, nil
+				}
+				v, err := p.objectDepth(depth + 1)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, v)
+			}
+		case "<<":
+			d := dict{}
+			for {
+				k, err := p.token()
+				if err != nil {
+					return d, err
+				}
+				if k == keyword(">>") {
+					break
+				}
+				key, ok := k.(name)
+				if !ok {
+					return d, errSyntax
+				}
+				v, err := p.objectDepth(depth + 1)
+				if err != nil {
+					return d, err
+				}
+				d[key] = v
+			}
+			return p.streamAfter(d)
+		}
+	}
+	return tok, nil
+}
+
+// streamAfter returns a stream if the dictionary is followed by stream data
+func (p *parser) streamAfter(d dict) (interface{}, error) {
+	save := p.pos
+	p.skipSpace()
+	if !bytes.HasPrefix(p.data[p.pos:], []byte("stream")) {
+		p.pos = save
+		return d, nil
+	}
+	p.pos += len("stream")
+	if p.pos < len(p.data) && p.data[p.pos] == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+		p.pos++
+	}
+
+	start := p.pos
+	if p.length != nil {
+		if n, ok := p.length(d[name("Length")]); ok && n >= 0 && start+n <= len(p.data) {
+			rest := p.data[start+n:]
+			rest = bytes.TrimLeft(rest, "\r\n \t")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				p.pos = start + n
+				return stream{hdr: d, data: p.data[start : start+n]}, nil
+			}
+		}
+	}
+
+	// the length is wrong or unknown, the stream ends at the next 'endstream'
+	end := bytes.Index(p.data[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, errSyntax
+	}
+	data := bytes.TrimRight(p.data[start:start+end], "\r\n")
+	p.pos = start + end
+	return stream{hdr: d, data: data}, nil
+}
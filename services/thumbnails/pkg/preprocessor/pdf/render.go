@@ -0,0 +1,845 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"sync"
+
+	xfont "golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+const (
+	// _maxOperations limits the work spent on a single page
+	_maxOperations = 2000000
+	// _maxFormDepth limits the nesting of form xobjects
+	_maxFormDepth = 8
+	// _maxFontSize is the largest font size in pixels text is drawn with
+	_maxFontSize = 512
+)
+
+var (
+	errTooComplex = errors.New("pdf: page is too complex")
+
+	_letter = []float64{0, 0, 612, 792}
+
+	_textFont     *opentype.Font
+	_textFontOnce sync.Once
+)
+
+// Render renders the first page of a pdf document. The longer side of the returned image has the given
+// size. Vector graphics, text and images are rendered, text is drawn with a default font.
+func Render(r io.Reader, size int) (*image.RGBA, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := newDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	page, err := doc.firstPage()
+	if err != nil {
+		return nil, err
+	}
+
+	box := doc.numbers(page["CropBox"])
+	if len(box) != 4 {
+		box = doc.numbers(page["MediaBox"])
+	}
+	if len(box) != 4 || box[0] == box[2] || box[1] == box[3] {
+		box = _letter
+	}
+	x0, y0 := math.Min(box[0], box[2]), math.Min(box[1], box[3])
+	w, h := math.Abs(box[2]-box[0]), math.Abs(box[3]-box[1])
+
+	rotate, _ := doc.number(page["Rotate"])
+	rot := ((int(rotate)%360)/90*90 + 360) % 360
+	if rot == 90 || rot == 270 {
+		w, h = h, w
+	}
+
+	s := float64(size) / math.Max(w, h)
+	dw, dh := max(1, int(math.Ceil(w*s))), max(1, int(math.Ceil(h*s)))
+	W, H := float64(dw), float64(dh)
+
+	// page space to device space, the device y axis points down
+	var device matrix
+	switch rot {
+	case 90:
+		device = matrix{0, s, s, 0, 0, 0}
+	case 180:
+		device = matrix{-s, 0, 0, s, W, 0}
+	case 270:
+		device = matrix{0, -s, -s, 0, W, H}
+	default:
+		device = matrix{s, 0, 0, -s, 0, H}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	rd := &renderer{
+		doc:   doc,
+		img:   img,
+		ras:   vector.NewRasterizer(dw, dh),
+		faces: map[int]xfont.Face{},
+		fonts: map[ref]*font{},
+	}
+	rd.state = graphicsState{
+		ctm:       matrix{1, 0, 0, 1, -x0, -y0}.mul(device),
+		fill:      color.RGBA{A: 0xff},
+		stroke:    color.RGBA{A: 0xff},
+		lineWidth: 1,
+		hScale:    1,
+	}
+
+	// a page which is too complex is returned as far as it has been rendered
+	_ = rd.execute(rd.contents(page["Contents"]), doc.dict(page["Resources"]))
+	return img, nil
+}
+
+// contents returns the decoded content of a page
+func (rd *renderer) contents(v interface{}) []byte {
+	var streams []interface{}
+	switch c := rd.doc.resolve(v).(type) {
+	case stream:
+		streams = []interface{}{c}
+	case array:
+		streams = c
+	}
+
+	// the streams of an array are concatenated, operators may span them
+	var buf bytes.Buffer
+	for _, v := range streams {
+		s, ok := rd.doc.resolve(v).(stream)
+		if !ok {
+			continue
+		}
+		data, err := rd.doc.decode(s)
+		if err != nil {
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// matrix is a pdf transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var _identity = matrix{1, 0, 0, 1, 0, 0}
+
+// mul returns the matrix which applies m and then n
+func (m matrix) mul(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func (m matrix) apply(x, y float64) (float64, float64) {
+	return m[0]*x + m[2]*y + m[4], m[1]*x + m[3]*y + m[5]
+}
+
+func (m matrix) inverse() (matrix, bool) {
+	det := m[0]*m[3] - m[1]*m[2]
+	if det == 0 || math.IsNaN(det) || math.IsInf(det, 0) {
+		return matrix{}, false
+	}
+	return matrix{
+		m[3] / det, -m[1] / det,
+		-m[2] / det, m[0] / det,
+		(m[2]*m[5] - m[3]*m[4]) / det,
+		(m[1]*m[4] - m[0]*m[5]) / det,
+	}, true
+}
+
+// scale returns the average factor the matrix scales lengths with
+func (m matrix) scale() float64 {
+	return math.Sqrt(math.Abs(m[0]*m[3] - m[1]*m[2]))
+}
+
+type graphicsState struct {
+	ctm       matrix
+	fill      color.RGBA
+	stroke    color.RGBA
+	lineWidth float64
+
+	font       *font
+	fontSize   float64
+	charSpace  float64
+	wordSpace  float64
+	hScale     float64
+	leading    float64
+	rise       float64
+	textRender int
+}
+
+// pathSegment is a segment of the current path in device space
+type pathSegment struct {
+	op  byte // 'M'oveTo, 'L'ineTo, 'C'ubeTo or 'Z' for close
+	pts [3][2]float64
+}
+
+type renderer struct {
+	doc   *document
+	img   *image.RGBA
+	ras   *vector.Rasterizer
+	faces map[int]xfont.Face
+	fonts map[ref]*font
+
+	state graphicsState
+	stack []graphicsState
+
+	path       []pathSegment
+	cur, start [2]float64
+	tm, tlm    matrix
+	operations int
+	formDepth  int
+}
+
+// execute runs the operators of a content stream
+func (rd *renderer) execute(content []byte, resources dict) error {
+	p := parser{lexer: lexer{data: content}}
+	var operands []interface{}
+	for {
+		obj, err := p.object()
+		if err != nil {
+			// the end of the stream or data which can't be parsed
+			return nil
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		rd.operations++
+		if rd.operations > _maxOperations {
+			return errTooComplex
+		}
+
+		if op == "BI" {
+			skipInlineImage(&p)
+		} else if err := rd.operator(op, operands, resources); err != nil {
+			return err
+		}
+		operands = operands[:0]
+	}
+}
+
+// skipInlineImage moves the parser behind the data of an inline image
+func skipInlineImage(p *parser) {
+	for {
+		obj, err := p.object()
+		if err != nil {
+			return
+		}
+		if obj == keyword("ID") {
+			break
+		}
+	}
+	for i := p.pos + 1; i+2 <= len(p.data); i++ {
+		if p.data[i] == 'E' && p.data[i+1] == 'I' && isWhitespace(p.data[i-1]) && (i+2 == len(p.data) || isWhitespace(p.data[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.data)
+}
+
+func numbers(operands []interface{}) []float64 {
+	out := make([]float64, 0, len(operands))
+	for _, o := range operands {
+		f, ok := o.(float64)
+		if !ok {
+			return nil
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+//nolint:gocyclo
+func (rd *renderer) operator(op keyword, operands []interface{}, resources dict) error {
+	n := numbers(operands)
+	st := &rd.state
+
+	switch op {
+	// graphics state
+	case "q":
+		rd.stack = append(rd.stack, rd.state)
+	case "Q":
+		if len(rd.stack) > 0 {
+			rd.state = rd.stack[len(rd.stack)-1]
+			rd.stack = rd.stack[:len(rd.stack)-1]
+		}
+	case "cm":
+		if len(n) == 6 {
+			st.ctm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}.mul(st.ctm)
+		}
+	case "w":
+		if len(n) == 1 {
+			st.lineWidth = n[0]
+		}
+
+	// colors
+	case "g", "rg", "k", "sc", "scn":
+		if c, ok := deviceColor(n); ok {
+			st.fill = c
+		}
+	case "G", "RG", "K", "SC", "SCN":
+		if c, ok := deviceColor(n); ok {
+			st.stroke = c
+		}
+	case "cs":
+		st.fill = color.RGBA{A: 0xff}
+	case "CS":
+		st.stroke = color.RGBA{A: 0xff}
+
+	// path construction
+	case "m":
+		if len(n) == 2 {
+			rd.moveTo(n[0], n[1])
+		}
+	case "l":
+		if len(n) == 2 {
+			rd.lineTo(n[0], n[1])
+		}
+	case "c":
+		if len(n) == 6 {
+			rd.curveTo(n[0], n[1], n[2], n[3], n[4], n[5])
+		}
+	case "v":
+		if len(n) == 4 {
+			x, y := rd.userPoint(rd.cur)
+			rd.curveTo(x, y, n[0], n[1], n[2], n[3])
+		}
+	case "y":
+		if len(n) == 4 {
+			rd.curveTo(n[0], n[1], n[2], n[3], n[2], n[3])
+		}
+	case "h":
+		rd.closePath()
+	case "re":
+		if len(n) == 4 {
+			rd.moveTo(n[0], n[1])
+			rd.lineTo(n[0]+n[2], n[1])
+			rd.lineTo(n[0]+n[2], n[1]+n[3])
+			rd.lineTo(n[0], n[1]+n[3])
+			rd.closePath()
+		}
+
+	// path painting, clipping is not supported
+	case "f", "F", "f*":
+		rd.fillPath(st.fill)
+		rd.path = rd.path[:0]
+	case "S":
+		rd.strokePath(st.stroke)
+		rd.path = rd.path[:0]
+	case "s":
+		rd.closePath()
+		rd.strokePath(st.stroke)
+		rd.path = rd.path[:0]
+	case "B", "B*":
+		rd.fillPath(st.fill)
+		rd.strokePath(st.stroke)
+		rd.path = rd.path[:0]
+	case "b", "b*":
+		rd.closePath()
+		rd.fillPath(st.fill)
+		rd.strokePath(st.stroke)
+		rd.path = rd.path[:0]
+	case "n":
+		rd.path = rd.path[:0]
+
+	// text
+	case "BT":
+		rd.tm, rd.tlm = _identity, _identity
+	case "Tf":
+		if len(operands) == 2 {
+			if fn, ok := operands[0].(name); ok {
+				st.font = rd.font(resources, fn)
+			}
+			if size, ok := operands[1].(float64); ok {
+				st.fontSize = size
+			}
+		}
+	case "Tc":
+		if len(n) == 1 {
+			st.charSpace = n[0]
+		}
+	case "Tw":
+		if len(n) == 1 {
+			st.wordSpace = n[0]
+		}
+	case "Tz":
+		if len(n) == 1 {
+			st.hScale = n[0] / 100
+		}
+	case "TL":
+		if len(n) == 1 {
+			st.leading = n[0]
+		}
+	case "Ts":
+		if len(n) == 1 {
+			st.rise = n[0]
+		}
+	case "Tr":
+		if len(n) == 1 {
+			st.textRender = int(n[0])
+		}
+	case "Td":
+		if len(n) == 2 {
+			rd.nextLine(n[0], n[1])
+		}
+	case "TD":
+		if len(n) == 2 {
+			st.leading = -n[1]
+			rd.nextLine(n[0], n[1])
+		}
+	case "Tm":
+		if len(n) == 6 {
+			rd.tm = matrix{n[0], n[1], n[2], n[3], n[4], n[5]}
+			rd.tlm = rd.tm
+		}
+	case "T*":
+		rd.nextLine(0, -st.leading)
+	case "Tj":
+		if len(operands) == 1 {
+			if s, ok := operands[0].([]byte); ok {
+				rd.showText(s)
+			}
+		}
+	case "'":
+		if len(operands) == 1 {
+			rd.nextLine(0, -st.leading)
+			if s, ok := operands[0].([]byte); ok {
+				rd.showText(s)
+			}
+		}
+	case "\"":
+		if len(operands) == 3 {
+			if aw, ok := operands[0].(float64); ok {
+				st.wordSpace = aw
+			}
+			if ac, ok := operands[1].(float64); ok {
+				st.charSpace = ac
+			}
+			rd.nextLine(0, -st.leading)
+			if s, ok := operands[2].([]byte); ok {
+				rd.showText(s)
+			}
+		}
+	case "TJ":
+		if len(operands) == 1 {
+			arr, _ := operands[0].(array)
+			for _, e := range arr {
+				switch e := e.(type) {
+				case []byte:
+					rd.showText(e)
+				case float64:
+					tx := -e / 1000 * st.fontSize * st.hScale
+					rd.tm = matrix{1, 0, 0, 1, tx, 0}.mul(rd.tm)
+				}
+			}
+		}
+
+	// xobjects
+	case "Do":
+		if len(operands) == 1 {
+			if xn, ok := operands[0].(name); ok {
+				return rd.xobject(resources, xn)
+			}
+		}
+	}
+	return nil
+}
+
+// deviceColor returns the color given in a gray, rgb or cmyk color space
+func deviceColor(n []float64) (color.RGBA, bool) {
+	c := func(v float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(1, v)) * 0xff))
+	}
+	switch len(n) {
+	case 1:
+		return color.RGBA{R: c(n[0]), G: c(n[0]), B: c(n[0]), A: 0xff}, true
+	case 3:
+		return color.RGBA{R: c(n[0]), G: c(n[1]), B: c(n[2]), A: 0xff}, true
+	case 4:
+		k := 1 - n[3]
+		return color.RGBA{R: c((1 - n[0]) * k), G: c((1 - n[1]) * k), B: c((1 - n[2]) * k), A: 0xff}, true
+	}
+	return color.RGBA{}, false
+}
+
+func (rd *renderer) devicePoint(x, y float64) [2]float64 {
+	dx, dy := rd.state.ctm.apply(x, y)
+	return [2]float64{dx, dy}
+}
+
+func (rd *renderer) userPoint(p [2]float64) (float64, float64) {
+	inv, ok := rd.state.ctm.inverse()
+	if !ok {
+		return 0, 0
+	}
+	return inv.apply(p[0], p[1])
+}
+
+func (rd *renderer) moveTo(x, y float64) {
+	rd.cur = rd.devicePoint(x, y)
+	rd.start = rd.cur
+	rd.path = append(rd.path, pathSegment{op: 'M', pts: [3][2]float64{rd.cur}})
+}
+
+func (rd *renderer) lineTo(x, y float64) {
+	rd.cur = rd.devicePoint(x, y)
+	rd.path = append(rd.path, pathSegment{op: 'L', pts: [3][2]float64{rd.cur}})
+}
+
+func (rd *renderer) curveTo(x1, y1, x2, y2, x3, y3 float64) {
+	p1, p2 := rd.devicePoint(x1, y1), rd.devicePoint(x2, y2)
+	rd.cur = rd.devicePoint(x3, y3)
+	rd.path = append(rd.path, pathSegment{op: 'C', pts: [3][2]float64{p1, p2, rd.cur}})
+}
+
+func (rd *renderer) closePath() {
+	rd.path = append(rd.path, pathSegment{op: 'Z'})
+	rd.cur = rd.start
+}
+
+// clamp keeps coordinates in a range the rasterizer can handle
+func (rd *renderer) clamp(p [2]float64) (float32, float32) {
+	b := rd.img.Bounds()
+	lim := func(v, size float64) float32 {
+		if math.IsNaN(v) {
+			return 0
+		}
+		return float32(math.Max(-size, math.Min(2*size, v)))
+	}
+	return lim(p[0], float64(b.Dx())), lim(p[1], float64(b.Dy()))
+}
+
+func (rd *renderer) fillPath(c color.RGBA) {
+	if len(rd.path) == 0 {
+		return
+	}
+	b := rd.img.Bounds()
+	rd.ras.Reset(b.Dx(), b.Dy())
+	open := false
+	for _, seg := range rd.path {
+		switch seg.op {
+		case 'M':
+			if open {
+				rd.ras.ClosePath()
+			}
+			rd.ras.MoveTo(rd.clamp(seg.pts[0]))
+			open = true
+		case 'L':
+			if open {
+				rd.ras.LineTo(rd.clamp(seg.pts[0]))
+			}
+		case 'C':
+			if open {
+				x1, y1 := rd.clamp(seg.pts[0])
+				x2, y2 := rd.clamp(seg.pts[1])
+				x3, y3 := rd.clamp(seg.pts[2])
+				rd.ras.CubeTo(x1, y1, x2, y2, x3, y3)
+			}
+		case 'Z':
+			if open {
+				rd.ras.ClosePath()
+			}
+		}
+	}
+	if open {
+		rd.ras.ClosePath()
+	}
+	rd.ras.Draw(rd.img, b, image.NewUniform(c), image.Point{})
+}
+
+// strokePath strokes the path by filling a quad for every (flattened) segment
+func (rd *renderer) strokePath(c color.RGBA) {
+	if len(rd.path) == 0 {
+		return
+	}
+	half := math.Max(rd.state.lineWidth*rd.state.ctm.scale(), 1) / 2
+
+	b := rd.img.Bounds()
+	rd.ras.Reset(b.Dx(), b.Dy())
+	var cur, start [2]float64
+	line := func(p0, p1 [2]float64) {
+		dx, dy := p1[0]-p0[0], p1[1]-p0[1]
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			return
+		}
+		// the normal, the quads are always wound the same way, so overlapping quads don't cancel out
+		nx, ny := -dy/l*half, dx/l*half
+		rd.ras.MoveTo(rd.clamp([2]float64{p0[0] + nx, p0[1] + ny}))
+		rd.ras.LineTo(rd.clamp([2]float64{p1[0] + nx, p1[1] + ny}))
+		rd.ras.LineTo(rd.clamp([2]float64{p1[0] - nx, p1[1] - ny}))
+		rd.ras.LineTo(rd.clamp([2]float64{p0[0] - nx, p0[1] - ny}))
+		rd.ras.ClosePath()
+	}
+	for _, seg := range rd.path {
+		switch seg.op {
+		case 'M':
+			cur, start = seg.pts[0], seg.pts[0]
+		case 'L':
+			line(cur, seg.pts[0])
+			cur = seg.pts[0]
+		case 'C':
+			const steps = 8
+			p0 := cur
+			for i := 1; i <= steps; i++ {
+				t := float64(i) / steps
+				p := cubic(cur, seg.pts[0], seg.pts[1], seg.pts[2], t)
+				line(p0, p)
+				p0 = p
+			}
+			cur = seg.pts[2]
+		case 'Z':
+			line(cur, start)
+			cur = start
+		}
+	}
+	rd.ras.Draw(rd.img, b, image.NewUniform(c), image.Point{})
+}
+
+func cubic(p0, p1, p2, p3 [2]float64, t float64) [2]float64 {
+	u := 1 - t
+	var p [2]float64
+	for i := range p {
+		p[i] = u*u*u*p0[i] + 3*u*u*t*p1[i] + 3*u*t*t*p2[i] + t*t*t*p3[i]
+	}
+	return p
+}
+
+func (rd *renderer) nextLine(tx, ty float64) {
+	rd.tlm = matrix{1, 0, 0, 1, tx, ty}.mul(rd.tlm)
+	rd.tm = rd.tlm
+}
+
+// font returns the font with the given name of the resources
+func (rd *renderer) font(resources dict, fn name) *font {
+	v := rd.doc.dict(resources["Font"])[fn]
+	if r, ok := v.(ref); ok {
+		if f, ok := rd.fonts[r]; ok {
+			return f
+		}
+		f := rd.doc.loadFont(r)
+		rd.fonts[r] = f
+		return f
+	}
+	return rd.doc.loadFont(v)
+}
+
+// face returns the face to draw text with the given size in pixels
+func (rd *renderer) face(px int) xfont.Face {
+	if f, ok := rd.faces[px]; ok {
+		return f
+	}
+	_textFontOnce.Do(func() {
+		_textFont, _ = opentype.Parse(goregular.TTF)
+	})
+	if _textFont == nil {
+		return nil
+	}
+	f, err := opentype.NewFace(_textFont, &opentype.FaceOptions{Size: float64(px), DPI: 72, Hinting: xfont.HintingNone})
+	if err != nil {
+		return nil
+	}
+	rd.faces[px] = f
+	return f
+}
+
+// showText draws a string and advances the text matrix
+func (rd *renderer) showText(s []byte) {
+	st := &rd.state
+	f := st.font
+	if f == nil {
+		f = &font{}
+	}
+
+	var src image.Image
+	switch st.textRender {
+	case 0, 2, 4, 6:
+		src = image.NewUniform(st.fill)
+	case 1, 5:
+		src = image.NewUniform(st.stroke)
+	}
+
+	for _, g := range f.glyphs(s) {
+		trm := matrix{st.fontSize * st.hScale, 0, 0, st.fontSize, 0, st.rise}.mul(rd.tm).mul(st.ctm)
+		px := math.Hypot(trm[2], trm[3])
+
+		width := g.width / 1000
+		if width == 0 {
+			width = rd.measure(g.text)
+		}
+
+		if src != nil && g.text != "" && px >= 1 {
+			if face := rd.face(min(int(math.Round(px)), _maxFontSize)); face != nil {
+				d := xfont.Drawer{
+					Dst:  rd.img,
+					Src:  src,
+					Face: face,
+					Dot:  fixed.Point26_6{X: fixed.Int26_6(trm[4] * 64), Y: fixed.Int26_6(trm[5] * 64)},
+				}
+				d.DrawString(g.text)
+			}
+		}
+
+		tx := width*st.fontSize + st.charSpace
+		if g.space {
+			tx += st.wordSpace
+		}
+		rd.tm = matrix{1, 0, 0, 1, tx * st.hScale, 0}.mul(rd.tm)
+	}
+}
+
+// measure returns the width of a text in the default font in text space units of a font of size 1
+func (rd *renderer) measure(text string) float64 {
+	const size = 100
+	face := rd.face(size)
+	if face == nil || text == "" {
+		return 0.5
+	}
+	return float64(xfont.MeasureString(face, text)) / 64 / size
+}
+
+// xobject draws the image or form xobject with the given name of the resources
+func (rd *renderer) xobject(resources dict, xn name) error {
+	s, ok := rd.doc.resolve(rd.doc.dict(resources["XObject"])[xn]).(stream)
+	if !ok {
+		return nil
+	}
+
+	switch s.hdr["Subtype"] {
+	case name("Image"):
+		img := rd.doc.image(s, resources, rd.state.fill)
+		if img != nil {
+			rd.drawImage(img)
+		}
+	case name("Form"):
+		if rd.formDepth >= _maxFormDepth {
+			return nil
+		}
+		data, err := rd.doc.decode(s)
+		if err != nil {
+			return nil
+		}
+		formResources := rd.doc.dict(s.hdr["Resources"])
+		if formResources == nil {
+			formResources = resources
+		}
+
+		saved, savedStack := rd.state, len(rd.stack)
+		if m := rd.doc.numbers(s.hdr["Matrix"]); len(m) == 6 {
+			rd.state.ctm = matrix{m[0], m[1], m[2], m[3], m[4], m[5]}.mul(rd.state.ctm)
+		}
+		rd.formDepth++
+		err = rd.execute(data, formResources)
+		rd.formDepth--
+		rd.state, rd.stack = saved, rd.stack[:savedStack]
+		return err
+	}
+	return nil
+}
+
+// drawImage draws an image into the unit square of the current transformation
+func (rd *renderer) drawImage(img image.Image) {
+	ib := img.Bounds()
+	iw, ih := float64(ib.Dx()), float64(ib.Dy())
+	if iw == 0 || ih == 0 {
+		return
+	}
+
+	// image space to device space, the first row of the image is at the top of the unit square
+	m := matrix{1 / iw, 0, 0, -1 / ih, 0, 1}.mul(rd.state.ctm)
+	inv, ok := m.inverse()
+	if !ok {
+		return
+	}
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, c := range [][2]float64{{0, 0}, {iw, 0}, {0, ih}, {iw, ih}} {
+		x, y := m.apply(c[0], c[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	area := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(rd.img.Bounds())
+	if area.Empty() {
+		return
+	}
+
+	// large images are scaled down first, sampling them directly would drop details like thin lines
+	dw, dh := math.Hypot(m[0], m[1])*iw, math.Hypot(m[2], m[3])*ih
+	if iw > 2*dw && ih > 2*dh {
+		img = downscale(img, max(1, int(dw)), max(1, int(dh)))
+		ib = img.Bounds()
+		sx, sy := float64(ib.Dx())/iw, float64(ib.Dy())/ih
+		inv = inv.mul(matrix{sx, 0, 0, sy, 0, 0})
+	}
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			u, v := inv.apply(float64(x)+0.5, float64(y)+0.5)
+			ix, iy := int(math.Floor(u)), int(math.Floor(v))
+			if ix < 0 || iy < 0 || ix >= ib.Dx() || iy >= ib.Dy() {
+				continue
+			}
+			c := color.RGBAModel.Convert(img.At(ib.Min.X+ix, ib.Min.Y+iy)).(color.RGBA)
+			switch c.A {
+			case 0:
+			case 0xff:
+				rd.img.SetRGBA(x, y, c)
+			default:
+				d := rd.img.RGBAAt(x, y)
+				a := uint32(0xff - c.A)
+				rd.img.SetRGBA(x, y, color.RGBA{
+					R: c.R + uint8(uint32(d.R)*a/0xff),
+					G: c.G + uint8(uint32(d.G)*a/0xff),
+					B: c.B + uint8(uint32(d.B)*a/0xff),
+					A: 0xff,
+				})
+			}
+		}
+	}
+}
+
+// downscale scales an image down by averaging the pixels of boxes
+func downscale(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					c := color.RGBAModel.Convert(img.At(sx, sy)).(color.RGBA)
+					r, g, bl, a, n = r+uint32(c.R), g+uint32(c.G), bl+uint32(c.B), a+uint32(c.A), n+1
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,229 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// build returns a pdf file with the given objects, the first object is the catalog
+func build(objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func contentStream(content string) string {
+	return fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)
+}
+
+func deflate(data string) string {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte(data))
+	_ = w.Close()
+	return buf.String()
+}
+
+func page(attributes string, content string) []byte {
+	return build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R "+attributes+" >>",
+		contentStream(content),
+	)
+}
+
+func assertColor(t *testing.T, img *image.RGBA, x, y int, want color.RGBA) {
+	t.Helper()
+	if got := img.RGBAAt(x, y); got != want {
+		t.Errorf("pixel %d,%d is %v, want %v", x, y, got, want)
+	}
+}
+
+var (
+	white = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	red   = color.RGBA{R: 0xff, A: 0xff}
+	blue  = color.RGBA{B: 0xff, A: 0xff}
+)
+
+func TestRender(t *testing.T) {
+	// a red rectangle on the left half and a blue one in the upper right quarter
+	data := page("", "1 0 0 rg 0 0 100 100 re f 0 0 1 rg 100 50 100 50 re f")
+
+	img, err := Render(bytes.NewReader(data), 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 200 {
+		t.Fatalf("unexpected size %v", b)
+	}
+	assertColor(t, img, 10, 10, red)
+	assertColor(t, img, 10, 190, red)
+	assertColor(t, img, 390, 10, blue)
+	assertColor(t, img, 390, 190, white)
+}
+
+func TestRenderRotated(t *testing.T) {
+	data := page("/Rotate 90", "1 0 0 rg 0 0 100 100 re f")
+
+	img, err := Render(bytes.NewReader(data), 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 400 {
+		t.Fatalf("unexpected size %v", b)
+	}
+	// the left half of the page is the upper half after a clockwise rotation
+	assertColor(t, img, 100, 10, red)
+	assertColor(t, img, 100, 390, white)
+}
+
+func TestRenderText(t *testing.T) {
+	data := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 100] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		contentStream("BT /F1 40 Tf 10 30 Td (Hello) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	img, err := Render(bytes.NewReader(data), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark := 0
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			if img.RGBAAt(x, y).R < 0x80 {
+				dark++
+			}
+		}
+	}
+	if dark < 100 {
+		t.Errorf("expected the text to be drawn, found %d dark pixels", dark)
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	// a 2x1 rgb image with a red and a blue pixel scaled to the whole page
+	samples := string([]byte{0xff, 0, 0, 0, 0, 0xff})
+	data := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 100] /Contents 4 0 R /Resources << /XObject << /Im1 5 0 R >> >> >>",
+		contentStream("q 200 0 0 100 0 0 cm /Im1 Do Q"),
+		fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 2 /Height 1 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(deflate(samples)), deflate(samples)),
+	)
+
+	img, err := Render(bytes.NewReader(data), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertColor(t, img, 50, 50, red)
+	assertColor(t, img, 150, 50, blue)
+}
+
+func TestRenderObjectStream(t *testing.T) {
+	// the page tree is stored in a compressed object stream
+	objs := []string{
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+	}
+	var index, body strings.Builder
+	for i, o := range objs {
+		fmt.Fprintf(&index, "%d %d ", i+2, body.Len())
+		body.WriteString(o + "\n")
+	}
+	ostm := deflate(index.String() + body.String())
+
+	data := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		// placeholder, objects 2 and 3 live in object 5
+		"null",
+		"null",
+		contentStream("1 0 0 rg 0 0 200 100 re f"),
+		fmt.Sprintf("<< /Type /ObjStm /N 2 /First %d /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream", len(index.String()), len(ostm), ostm),
+	)
+	// drop the placeholders so the objects are looked up in the object stream
+	data = bytes.Replace(data, []byte("2 0 obj\nnull\nendobj\n"), nil, 1)
+	data = bytes.Replace(data, []byte("3 0 obj\nnull\nendobj\n"), nil, 1)
+
+	img, err := Render(bytes.NewReader(data), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertColor(t, img, 100, 50, red)
+}
+
+func TestUnpredictInvalidParameters(t *testing.T) {
+	d := &document{}
+	for n, params := range map[string]dict{
+		"too many columns":  {"Predictor": 12.0, "Columns": 1e16},
+		"overflowing row":   {"Predictor": 12.0, "Columns": 4294967296.0, "Colors": 4294967296.0, "BitsPerComponent": 16.0},
+		"too large row":     {"Predictor": 12.0, "Columns": 1e9, "Colors": 4.0},
+		"negative columns":  {"Predictor": 12.0, "Columns": -8.0},
+		"no bits per color": {"Predictor": 12.0, "BitsPerComponent": 0.0},
+	} {
+		t.Run(n, func(t *testing.T) {
+			if _, err := d.unpredict([]byte{2, 0, 0, 0}, params); !errors.Is(err, errSyntax) {
+				t.Errorf("expected %v, got %v", errSyntax, err)
+			}
+		})
+	}
+
+	// the page is rendered without the content which can't be decoded
+	content := deflate("1 0 0 rg 0 0 200 100 re f")
+	data := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /MediaBox [0 0 200 100] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		fmt.Sprintf("<< /Filter /FlateDecode /DecodeParms << /Predictor 12 /Columns 10000000000000000 >> /Length %d >>\nstream\n%s\nendstream", len(content), content),
+	)
+	img, err := Render(bytes.NewReader(data), 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertColor(t, img, 100, 50, white)
+}
+
+func TestRenderErrors(t *testing.T) {
+	encrypted := build(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Filter /Standard /V 1 /R 2 >>",
+	)
+	encrypted = bytes.Replace(encrypted, []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 3 0 R"), 1)
+
+	tests := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"encrypted": {data: encrypted, err: ErrEncrypted},
+		"no page":   {data: build("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>"), err: ErrNoPage},
+		"no pdf":    {data: []byte("not a pdf"), err: errSyntax},
+	}
+	for n, tc := range tests {
+		t.Run(n, func(t *testing.T) {
+			if _, err := Render(bytes.NewReader(tc.data), 100); !errors.Is(err, tc.err) {
+				t.Errorf("expected %v, got %v", tc.err, err)
+			}
+		})
+	}
+}
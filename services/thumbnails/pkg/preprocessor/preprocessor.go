@@ -6,12 +6,14 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/xml"
 	"image"
 	"image/draw"
 	"image/gif"
 	"io"
 	"math"
 	"mime"
+	"path"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -21,8 +23,19 @@ import (
 
 	"github.com/dhowden/tag"
 	thumbnailerErrors "github.com/owncloud/ocis/v2/services/thumbnails/pkg/errors"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/preprocessor/pdf"
 )
 
+// _pdfRenderSize is the size of the longer side of rendered pdf pages, the thumbnails are scaled down from it
+const _pdfRenderSize = 1920
+
+// _officeThumbnails are the default locations of the thumbnails in OOXML and ODF documents
+var _officeThumbnails = []string{
+	"docProps/thumbnail.jpeg",
+	"docProps/thumbnail.png",
+	"Thumbnails/thumbnail.png",
+}
+
 // FileConverter is the interface for the file converter
 type FileConverter interface {
 	Convert(r io.Reader) (interface{}, error)
@@ -74,6 +87,99 @@ func (g GgsDecoder) Convert(r io.Reader) (interface{}, error) {
 	return nil, errors.Errorf("%s not found", g.thumbnailpath)
 }
 
+// PdfDecoder is a converter for the pdf file
+type PdfDecoder struct{}
+
+// Convert renders the first page of the pdf file as thumbnail image
+func (p PdfDecoder) Convert(r io.Reader) (_ interface{}, err error) {
+	// the renderer parses untrusted documents, a malformed file must not crash the service
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("could not render the pdf: %v", r)
+		}
+	}()
+
+	img, err := pdf.Render(r, _pdfRenderSize)
+	if err != nil {
+		return nil, errors.Wrap(err, `could not render the pdf`)
+	}
+	return img, nil
+}
+
+// OfficeDecoder is a converter for OOXML and ODF office documents, it uses the thumbnail embedded
+// in the document package
+type OfficeDecoder struct{}
+
+// Convert reads the office document and returns the embedded thumbnail image
+func (o OfficeDecoder) Convert(r io.Reader) (interface{}, error) {
+	var buf bytes.Buffer
+	_, err := io.Copy(&buf, r)
+	if err != nil {
+		return nil, err
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(zipReader.File))
+	for _, file := range zipReader.File {
+		files[file.Name] = file
+	}
+	candidates := append(ooxmlThumbnails(files["_rels/.rels"]), _officeThumbnails...)
+	for _, name := range candidates {
+		file, ok := files[name]
+		if !ok {
+			continue
+		}
+		thumbnail, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer thumbnail.Close()
+
+		converter := ForType(mime.TypeByExtension(path.Ext(name)), nil)
+		if converter == nil {
+			return nil, thumbnailerErrors.ErrNoConverterForExtractedImageFromOfficeDocument
+		}
+		img, err := converter.Convert(thumbnail)
+		if err != nil {
+			return nil, errors.Wrap(err, `could not decode the image`)
+		}
+		return img, nil
+	}
+	return nil, thumbnailerErrors.ErrNoImageFromOfficeDocument
+}
+
+// ooxmlThumbnails returns the thumbnails referenced by the package relationships of an OOXML document
+func ooxmlThumbnails(rels *zip.File) []string {
+	if rels == nil {
+		return nil
+	}
+	f, err := rels.Open()
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	relationships := struct {
+		Relationship []struct {
+			Type   string `xml:",attr"`
+			Target string `xml:",attr"`
+		}
+	}{}
+	if err := xml.NewDecoder(io.LimitReader(f, 1<<20)).Decode(&relationships); err != nil {
+		return nil
+	}
+	var thumbnails []string
+	for _, rel := range relationships.Relationship {
+		if strings.HasSuffix(rel.Type, "/metadata/thumbnail") {
+			thumbnails = append(thumbnails, strings.TrimPrefix(path.Clean("/"+rel.Target), "/"))
+		}
+	}
+	return thumbnails
+}
+
 // AudioDecoder is a converter for the audio file
 type AudioDecoder struct{}
 
@@ -315,6 +421,20 @@ func ForType(mimeType string, opts map[string]interface{}) FileConverter {
 		return GgpDecoder{}
 	case "image/gif":
 		return GifDecoder{}
+	case "application/pdf":
+		return PdfDecoder{}
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"application/vnd.oasis.opendocument.text",
+		"application/vnd.oasis.opendocument.spreadsheet",
+		"application/vnd.oasis.opendocument.presentation",
+		"application/vnd.oasis.opendocument.graphics":
+		return OfficeDecoder{}
+	case "video/mp4":
+		fallthrough
+	case "video/webm":
+		return VideoDecoder{}
 	case "audio/flac":
 		fallthrough
 	case "audio/mpeg":
@@ -2,6 +2,8 @@ package preprocessor
 
 import (
 	"bytes"
+	"encoding/binary"
+	"image"
 	"io"
 	"os"
 	"testing"
@@ -11,6 +13,8 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+
+	thumbnailerErrors "github.com/owncloud/ocis/v2/services/thumbnails/pkg/errors"
 )
 
 func TestImageDecoder(t *testing.T) {
@@ -37,6 +41,15 @@ var _ = Describe("ImageDecoder", func() {
 			Expect(img).ToNot(BeNil())
 		})
 
+		It("should decode a webp", func() {
+			fileContent, err := os.ReadFile("test_assets/test.webp")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := ImageDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).ToNot(BeNil())
+		})
+
 		It("should return an error if the image is invalid", func() {
 			decoder := ImageDecoder{}
 			img, err := decoder.Convert(bytes.NewReader([]byte("not an image")))
@@ -127,6 +140,108 @@ var _ = Describe("ImageDecoder", func() {
 		})
 	})
 
+	Describe("PdfDecoder", func() {
+		It("should render a pdf", func() {
+			fileContent, err := os.ReadFile("test_assets/test.pdf")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := PdfDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).To(BeAssignableToTypeOf(&image.RGBA{}))
+			Expect(img.(*image.RGBA).Bounds().Dy()).To(Equal(_pdfRenderSize))
+		})
+
+		It("should return an error if the pdf is invalid", func() {
+			decoder := PdfDecoder{}
+			img, err := decoder.Convert(bytes.NewReader([]byte("not a pdf")))
+			Expect(err).To(HaveOccurred())
+			Expect(img).To(BeNil())
+		})
+	})
+
+	Describe("OfficeDecoder", func() {
+		It("should decode the thumbnail of an odf document", func() {
+			fileContent, err := os.ReadFile("test_assets/test.odt")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := OfficeDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).ToNot(BeNil())
+		})
+
+		It("should decode the thumbnail referenced by an ooxml document", func() {
+			fileContent, err := os.ReadFile("test_assets/test.docx")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := OfficeDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).ToNot(BeNil())
+		})
+
+		It("should return an error if the document has no thumbnail", func() {
+			fileContent, err := os.ReadFile("test_assets/ggs_test.ggs")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := OfficeDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).To(MatchError(thumbnailerErrors.ErrNoImageFromOfficeDocument))
+			Expect(img).To(BeNil())
+		})
+
+		It("should return an error if the document is invalid", func() {
+			decoder := OfficeDecoder{}
+			img, err := decoder.Convert(bytes.NewReader([]byte("not a document")))
+			Expect(err).To(HaveOccurred())
+			Expect(img).To(BeNil())
+		})
+	})
+
+	Describe("VideoDecoder", func() {
+		It("should decode the cover art of a mp4", func() {
+			fileContent, err := os.ReadFile("test_assets/test.mp4")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := VideoDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).ToNot(BeNil())
+		})
+
+		It("should decode the first keyframe of a webm", func() {
+			fileContent, err := os.ReadFile("test_assets/test.webm")
+			Expect(err).ToNot(HaveOccurred())
+			decoder := VideoDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(fileContent))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(img).To(BeAssignableToTypeOf(&image.RGBA{}))
+		})
+
+		It("should return an error if a chunk offset of a mp4 is out of range", func() {
+			box := func(typ string, content ...[]byte) []byte {
+				b := bytes.Join(content, nil)
+				return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(b))), append([]byte(typ), b...)...)
+			}
+			co64 := binary.BigEndian.AppendUint64([]byte{0, 0, 0, 0, 0, 0, 0, 1}, 0xFFFFFFFFFFFFFFF0)
+			stbl := box("stbl",
+				box("stsd", []byte("\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x10jpeg")),
+				box("co64", co64),
+				box("stsz", []byte("\x00\x00\x00\x00\x00\x00\x00\x10\x00\x00\x00\x01")),
+			)
+			mdia := box("mdia", box("hdlr", []byte("\x00\x00\x00\x00\x00\x00\x00\x00vide")), box("minf", stbl))
+			video := append(box("ftyp", []byte("isom")), box("moov", box("trak", mdia))...)
+
+			decoder := VideoDecoder{}
+			img, err := decoder.Convert(bytes.NewReader(video))
+			Expect(err).To(MatchError(thumbnailerErrors.ErrNoImageFromVideoFile))
+			Expect(img).To(BeNil())
+		})
+
+		It("should return an error if the video is invalid", func() {
+			decoder := VideoDecoder{}
+			img, err := decoder.Convert(bytes.NewReader([]byte("not a video")))
+			Expect(err).To(MatchError(thumbnailerErrors.ErrNoImageFromVideoFile))
+			Expect(img).To(BeNil())
+		})
+	})
+
 	Describe("should decode text", func() {
 		var decoder TxtToImageConverter
 		BeforeEach(func() {
@@ -178,6 +293,25 @@ var _ = Describe("ImageDecoder", func() {
 			Expect(decoder).To(BeAssignableToTypeOf(TxtToImageConverter{}))
 		})
 
+		It("should return a PdfDecoder for pdf types", func() {
+			decoder := ForType("application/pdf", nil)
+			Expect(decoder).To(BeAssignableToTypeOf(PdfDecoder{}))
+		})
+
+		It("should return an OfficeDecoder for office types", func() {
+			decoder := ForType("application/vnd.openxmlformats-officedocument.wordprocessingml.document", nil)
+			Expect(decoder).To(BeAssignableToTypeOf(OfficeDecoder{}))
+			decoder = ForType("application/vnd.oasis.opendocument.presentation", nil)
+			Expect(decoder).To(BeAssignableToTypeOf(OfficeDecoder{}))
+		})
+
+		It("should return a VideoDecoder for video types", func() {
+			decoder := ForType("video/mp4", nil)
+			Expect(decoder).To(BeAssignableToTypeOf(VideoDecoder{}))
+			decoder = ForType("video/webm", nil)
+			Expect(decoder).To(BeAssignableToTypeOf(VideoDecoder{}))
+		})
+
 		It("should return an ImageDecoder for unknown types", func() {
 			decoder := ForType("unknown", nil)
 			Expect(decoder).To(BeAssignableToTypeOf(ImageDecoder{}))
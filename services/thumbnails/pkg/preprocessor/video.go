@@ -0,0 +1,352 @@
+package preprocessor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/image/vp8"
+
+	thumbnailerErrors "github.com/owncloud/ocis/v2/services/thumbnails/pkg/errors"
+)
+
+// VideoDecoder is a converter for mp4 and webm videos. It uses the cover art of the video if there is one,
+// otherwise the first frame is decoded if it is a vp8 or jpeg keyframe. Other codecs are not decoded.
+type VideoDecoder struct{}
+
+// Convert reads the video file and returns the cover art or the first frame as thumbnail image
+func (v VideoDecoder) Convert(r io.Reader) (img interface{}, err error) {
+	// the containers are parsed by hand, a malformed file must not crash the service
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, errors.Errorf("could not decode the video: %v", r)
+		}
+	}()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var f *frame
+	switch {
+	case bytes.HasPrefix(b, _ebmlMagic):
+		f = webmFrame(b)
+	case len(b) >= 8 && string(b[4:8]) == "ftyp":
+		f = mp4Frame(b)
+	}
+	if f == nil {
+		return nil, thumbnailerErrors.ErrNoImageFromVideoFile
+	}
+
+	if f.codec != "vp8" {
+		converter := ForType(f.codec, nil)
+		if converter == nil {
+			return nil, thumbnailerErrors.ErrNoConverterForExtractedImageFromVideoFile
+		}
+		return converter.Convert(bytes.NewReader(f.data))
+	}
+
+	d := vp8.NewDecoder()
+	d.Init(bytes.NewReader(f.data), len(f.data))
+	fh, err := d.DecodeFrameHeader()
+	if err != nil {
+		return nil, errors.Wrap(err, `could not decode the frame`)
+	}
+	if !fh.KeyFrame {
+		return nil, thumbnailerErrors.ErrNoImageFromVideoFile
+	}
+	yuv, err := d.DecodeFrame()
+	if err != nil {
+		return nil, errors.Wrap(err, `could not decode the frame`)
+	}
+	rgba := image.NewRGBA(yuv.Bounds())
+	draw.Draw(rgba, rgba.Bounds(), yuv, yuv.Bounds().Min, draw.Src)
+	return rgba, nil
+}
+
+// frame is an image found in a video container, codec is either "vp8" or the mime type of the image
+type frame struct {
+	codec string
+	data  []byte
+}
+
+// mp4Frame returns the cover art of an mp4 file or the first frame of its first video track
+func mp4Frame(b []byte) *frame {
+	moov := mp4Box(b, "moov")
+	if covr := mp4Box(moov, "udta", "meta", "ilst", "covr", "data"); len(covr) > 8 {
+		// the data box starts with the type of the data and the locale
+		mimeType := "image/jpeg"
+		if binary.BigEndian.Uint32(covr[:4]) == 14 {
+			mimeType = "image/png"
+		}
+		return &frame{codec: mimeType, data: covr[8:]}
+	}
+
+	for _, trak := range mp4Boxes(moov, "trak") {
+		hdlr := mp4Box(trak, "mdia", "hdlr")
+		if len(hdlr) < 12 || string(hdlr[8:12]) != "vide" {
+			continue
+		}
+		stbl := mp4Box(trak, "mdia", "minf", "stbl")
+
+		// the sample description holds the codec of the track
+		stsd := mp4Box(stbl, "stsd")
+		if len(stsd) < 16 {
+			continue
+		}
+		var codec string
+		switch string(stsd[12:16]) {
+		case "vp08":
+			codec = "vp8"
+		case "jpeg", "mjpa":
+			codec = "image/jpeg"
+		default:
+			continue
+		}
+
+		// the first sample starts at the offset of the first chunk
+		var offset uint64
+		if stco := mp4Box(stbl, "stco"); len(stco) >= 12 && binary.BigEndian.Uint32(stco[4:8]) > 0 {
+			offset = uint64(binary.BigEndian.Uint32(stco[8:12]))
+		} else if co64 := mp4Box(stbl, "co64"); len(co64) >= 16 && binary.BigEndian.Uint32(co64[4:8]) > 0 {
+			offset = binary.BigEndian.Uint64(co64[8:16])
+		} else {
+			continue
+		}
+		stsz := mp4Box(stbl, "stsz")
+		if len(stsz) < 12 {
+			continue
+		}
+		size := uint64(binary.BigEndian.Uint32(stsz[4:8]))
+		if size == 0 && len(stsz) >= 16 && binary.BigEndian.Uint32(stsz[8:12]) > 0 {
+			size = uint64(binary.BigEndian.Uint32(stsz[12:16]))
+		}
+		if size == 0 || offset > uint64(len(b)) || size > uint64(len(b))-offset {
+			continue
+		}
+		return &frame{codec: codec, data: b[offset : offset+size]}
+	}
+	return nil
+}
+
+// mp4Box returns the content of the first box found at the given path
+func mp4Box(b []byte, path ...string) []byte {
+	for _, typ := range path {
+		boxes := mp4Boxes(b, typ)
+		if len(boxes) == 0 {
+			return nil
+		}
+		b = boxes[0]
+	}
+	return b
+}
+
+// mp4Boxes returns the content of all boxes with the given type, the version and flags of full boxes
+// are part of the content except for the meta box whose children follow them
+func mp4Boxes(b []byte, typ string) [][]byte {
+	var boxes [][]byte
+	for len(b) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(b[:4]))
+		header := uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return boxes
+			}
+			size = binary.BigEndian.Uint64(b[8:16])
+			header = 16
+		}
+		if size < header || size > uint64(len(b)) {
+			return boxes
+		}
+		if string(b[4:8]) == typ {
+			content := b[header:size]
+			if typ == "meta" && len(content) >= 12 && string(content[4:8]) != "hdlr" {
+				// the meta box of mp4 files is a full box, quicktime files omit the version and flags
+				content = content[4:]
+			}
+			boxes = append(boxes, content)
+		}
+		b = b[size:]
+	}
+	return boxes
+}
+
+var _ebmlMagic = []byte{0x1a, 0x45, 0xdf, 0xa3}
+
+// the matroska elements needed to find a cover or the first frame
+const (
+	_ebmlSegment      = 0x18538067
+	_ebmlTracks       = 0x1654ae6b
+	_ebmlTrackEntry   = 0xae
+	_ebmlTrackNumber  = 0xd7
+	_ebmlTrackType    = 0x83
+	_ebmlCodecID      = 0x86
+	_ebmlAttachments  = 0x1941a469
+	_ebmlAttachedFile = 0x61a7
+	_ebmlFileMimeType = 0x4660
+	_ebmlFileData     = 0x465c
+	_ebmlCluster      = 0x1f43b675
+	_ebmlBlockGroup   = 0xa0
+	_ebmlBlock        = 0xa1
+	_ebmlSimpleBlock  = 0xa3
+)
+
+// webmFrame returns the first attached image of a webm or matroska file or the first keyframe of its
+// first vp8 track
+func webmFrame(b []byte) *frame {
+	type track struct {
+		number uint64
+		video  bool
+		codec  string
+	}
+	var (
+		tracks     []*track
+		cover      *frame
+		attachment *frame
+		keyframe   *frame
+	)
+	vp8Track := func(n uint64) bool {
+		for _, t := range tracks {
+			if t.number == n {
+				return t.video && t.codec == "V_VP8"
+			}
+		}
+		return false
+	}
+
+	// the ids of matroska elements are unique across all levels, so the master elements of interest are
+	// entered instead of parsed recursively. This also works for elements with an unknown size.
+	for pos := 0; pos < len(b) && cover == nil; {
+		id, n := ebmlID(b[pos:])
+		if n == 0 {
+			break
+		}
+		size, m := ebmlSize(b[pos+n:])
+		if m == 0 {
+			break
+		}
+		pos += n + m
+		switch id {
+		case _ebmlSegment, _ebmlTracks, _ebmlAttachments, _ebmlCluster, _ebmlBlockGroup:
+			continue
+		case _ebmlTrackEntry:
+			tracks = append(tracks, &track{})
+			continue
+		case _ebmlAttachedFile:
+			attachment = &frame{}
+			continue
+		}
+
+		if size < 0 || size > int64(len(b)-pos) {
+			size = int64(len(b) - pos)
+		}
+		data := b[pos : pos+int(size)]
+		pos += int(size)
+
+		switch id {
+		case _ebmlTrackNumber:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].number = ebmlUint(data)
+			}
+		case _ebmlTrackType:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].video = ebmlUint(data) == 1
+			}
+		case _ebmlCodecID:
+			if len(tracks) > 0 {
+				tracks[len(tracks)-1].codec = string(bytes.TrimRight(data, "\x00"))
+			}
+		case _ebmlFileMimeType:
+			if attachment != nil {
+				attachment.codec = string(bytes.TrimRight(data, "\x00"))
+			}
+		case _ebmlFileData:
+			if attachment != nil {
+				attachment.data = data
+			}
+		case _ebmlSimpleBlock, _ebmlBlock:
+			if keyframe != nil {
+				continue
+			}
+			// the block starts with the track number, a timecode and flags
+			tn, k := ebmlSize(data)
+			if k == 0 || len(data) < k+4 || !vp8Track(uint64(tn)) {
+				continue
+			}
+			if data[k+2]&0x06 != 0 || data[k+3]&0x01 != 0 {
+				// laced frames or the frame tag of an interframe
+				continue
+			}
+			keyframe = &frame{codec: "vp8", data: data[k+3:]}
+		}
+
+		if attachment != nil && attachment.data != nil && strings.HasPrefix(attachment.codec, "image/") {
+			cover = attachment
+		}
+	}
+
+	if cover != nil {
+		return cover
+	}
+	return keyframe
+}
+
+// ebmlID reads an element id, the length marker is part of the id
+func ebmlID(b []byte) (uint32, int) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0
+	}
+	n := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 4 || len(b) < n {
+		return 0, 0
+	}
+	var id uint32
+	for _, c := range b[:n] {
+		id = id<<8 | uint32(c)
+	}
+	return id, n
+}
+
+// ebmlSize reads a variable size integer, -1 is returned for an unknown size
+func ebmlSize(b []byte) (int64, int) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0
+	}
+	n := 1
+	mask := byte(0x80)
+	for ; b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if len(b) < n {
+		return 0, 0
+	}
+	v := uint64(b[0] & (mask - 1))
+	unknown := v == uint64(mask-1)
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+		unknown = unknown && c == 0xff
+	}
+	if unknown || v > 1<<62 {
+		return -1, n
+	}
+	return int64(v), n
+}
+
+func ebmlUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
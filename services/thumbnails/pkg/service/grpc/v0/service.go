@@ -123,10 +123,7 @@ func (g Thumbnail) checkThumbnail(req *thumbnailssvc.GetThumbnailRequest, sRes *
 		return "", tr, merrors.Forbidden(g.serviceID, "no download permission")
 	}
 
-	tType := thumbnail.GetExtForMime(sRes.GetInfo().GetMimeType())
-	if tType == "" {
-		tType = req.GetThumbnailType().String()
-	}
+	tType := thumbnail.TypeFor(sRes.GetInfo().GetMimeType(), req.GetThumbnailType().String())
 	tr, err := thumbnail.PrepareRequest(int(req.GetWidth()), int(req.GetHeight()), tType, sRes.GetInfo().GetChecksum().GetSum(), req.GetProcessor())
 	if err != nil {
 		return "", tr, merrors.BadRequest(g.serviceID, "%s", err.Error())
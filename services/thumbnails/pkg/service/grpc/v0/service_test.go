@@ -0,0 +1,131 @@
+package svc_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	gateway "github.com/cs3org/go-cs3apis/cs3/gateway/v1beta1"
+	provider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/status"
+	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
+	"github.com/owncloud/reva/v2/pkg/storagespace"
+	cs3mocks "github.com/owncloud/reva/v2/tests/cs3mocks/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	thumbnailsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/thumbnails/v0"
+	thumbnailssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/thumbnails/v0"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/pregeneration"
+	svc "github.com/owncloud/ocis/v2/services/thumbnails/pkg/service/grpc/v0"
+	tjwt "github.com/owncloud/ocis/v2/services/thumbnails/pkg/service/jwt"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+// source serves a landscape png for every path and counts the downloads
+type source struct {
+	downloads atomic.Int32
+}
+
+func (s *source) Get(_ context.Context, _ string) (io.ReadCloser, error) {
+	s.downloads.Add(1)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 128, 64))); err != nil {
+		return nil, err
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func TestGetThumbnailPregenerated(t *testing.T) {
+	info := &provider.ResourceInfo{
+		Id:            &provider.ResourceId{StorageId: "storage", SpaceId: "space", OpaqueId: "file"},
+		Type:          provider.ResourceType_RESOURCE_TYPE_FILE,
+		MimeType:      "image/png",
+		Checksum:      &provider.ResourceChecksum{Sum: "97f9c4c8db98f7b82e768ef478d3c8612"},
+		PermissionSet: &provider.ResourcePermissions{InitiateFileDownload: true},
+	}
+
+	pool.RemoveSelector("GatewaySelector" + "com.owncloud.api.gateway")
+	gatewayClient := &cs3mocks.GatewayAPIClient{}
+	gatewaySelector := pool.GetSelector[gateway.GatewayAPIClient](
+		"GatewaySelector",
+		"com.owncloud.api.gateway",
+		func(cc grpc.ClientConnInterface) gateway.GatewayAPIClient {
+			return gatewayClient
+		},
+	)
+	gatewayClient.On("Authenticate", mock.Anything, mock.Anything).Return(&gateway.AuthenticateResponse{Status: status.NewOK(context.Background()), Token: "token"}, nil)
+	gatewayClient.On("Stat", mock.Anything, mock.Anything).Return(&provider.StatResponse{Status: status.NewOK(context.Background()), Info: info}, nil)
+
+	cfg := &config.Config{
+		Thumbnail: config.Thumbnail{
+			Resolutions:    []string{"16x16", "32x32"},
+			Pregeneration:  config.Pregeneration{Workers: 1},
+			TransferSecret: "secret",
+			MaxInputWidth:  7680,
+			MaxInputHeight: 7680,
+		},
+	}
+	resolutions, err := thumbnail.ParseResolutions(cfg.Thumbnail.Resolutions)
+	require.NoError(t, err)
+	s := storage.NewFileSystemStorage(config.FileSystemStorage{RootDirectory: t.TempDir()}, log.NopLogger())
+	src := &source{}
+
+	p, err := pregeneration.New(cfg, thumbnail.NewSimpleManager(resolutions, s, log.NopLogger(), 7680, 7680), src, gatewaySelector, log.NopLogger())
+	require.NoError(t, err)
+	_, err = p.Generate(context.Background(), &provider.Reference{ResourceId: info.GetId()})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), src.downloads.Load())
+
+	service := svc.NewService(
+		svc.Config(cfg),
+		svc.Logger(log.NopLogger()),
+		svc.ThumbnailStorage(s),
+		svc.CS3Source(src),
+		svc.GatewaySelector(gatewaySelector),
+	)
+	for _, tt := range []struct {
+		name          string
+		width, height int32
+		thumbnailType thumbnailsmsg.ThumbnailType
+		resolution    string
+	}{
+		{name: "configured resolution", width: 32, height: 32, thumbnailType: thumbnailsmsg.ThumbnailType_PNG, resolution: "32x32"},
+		{name: "resolution between the configured ones", width: 20, height: 10, thumbnailType: thumbnailsmsg.ThumbnailType_PNG, resolution: "32x32"},
+		{name: "webp requested", width: 12, height: 36, thumbnailType: thumbnailsmsg.ThumbnailType_WEBP, resolution: "16x16"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp := &thumbnailssvc.GetThumbnailResponse{}
+			err := service.GetThumbnail(context.Background(), &thumbnailssvc.GetThumbnailRequest{
+				Width:         tt.width,
+				Height:        tt.height,
+				ThumbnailType: tt.thumbnailType,
+				Source: &thumbnailssvc.GetThumbnailRequest_Cs3Source{
+					Cs3Source: &thumbnailsmsg.CS3Source{Path: storagespace.FormatResourceID(info.GetId()), Authorization: "token"},
+				},
+			}, rsp)
+			require.NoError(t, err)
+			assert.Equal(t, int32(1), src.downloads.Load(), "the pregenerated thumbnail is used")
+
+			claims := &tjwt.ThumbnailClaims{}
+			_, err = jwt.ParseWithClaims(rsp.GetTransferToken(), claims, func(*jwt.Token) (interface{}, error) {
+				return []byte("secret"), nil
+			})
+			require.NoError(t, err)
+			k, ok := storage.ParseKey(claims.Key)
+			require.True(t, ok)
+			assert.Equal(t, tt.resolution, k.Resolution)
+			assert.True(t, s.Stat(claims.Key))
+		})
+	}
+}
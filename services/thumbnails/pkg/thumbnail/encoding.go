@@ -13,6 +13,7 @@ const (
 	typeJpg  = "jpg"
 	typeJpeg = "jpeg"
 	typeGif  = "gif"
+	typeWebp = "webp"
 	typeGgs  = "ggs"
 	typeGgp  = "ggp"
 )
@@ -59,11 +60,30 @@ func EncoderForType(fileType string) (Encoder, error) {
 		return JpegEncoder{}, nil
 	case typeGif:
 		return GifEncoder{}, nil
+	case typeWebp:
+		return webpEncoder()
 	default:
 		return nil, errors.ErrNoEncoderForType
 	}
 }
 
+// TypeFor returns the type of the thumbnail of a source with the mime type. Sources which have a thumbnail
+// type keep it unless webp is requested, gifs always keep it to preserve animations. Webp falls back to jpeg
+// if it can't be encoded.
+func TypeFor(mimeType, requested string) string {
+	tType := GetExtForMime(mimeType)
+	if strings.EqualFold(requested, typeWebp) && tType != typeGif {
+		if _, err := EncoderForType(requested); err == nil {
+			return requested
+		}
+		requested = typeJpg
+	}
+	if tType == "" {
+		tType = requested
+	}
+	return tType
+}
+
 // GetExtForMime return the supported extension by mime
 func GetExtForMime(fileType string) string {
 	ext := strings.TrimPrefix(strings.TrimSpace(strings.ToLower(fileType)), "image/")
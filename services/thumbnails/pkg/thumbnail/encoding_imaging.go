@@ -33,6 +33,11 @@ func (e PngEncoder) MimeType() string {
 	return "image/png"
 }
 
+// webpEncoder returns an error, there is no pure go webp encoder. Webp thumbnails need libvips.
+func webpEncoder() (Encoder, error) {
+	return nil, errors.ErrNoEncoderForType
+}
+
 // JpegEncoder encodes to jpg
 type JpegEncoder struct{}
 
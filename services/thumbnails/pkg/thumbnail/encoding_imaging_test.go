@@ -0,0 +1,19 @@
+//go:build !enable_vips
+
+package thumbnail
+
+import (
+	"errors"
+	"testing"
+
+	terrors "github.com/owncloud/ocis/v2/services/thumbnails/pkg/errors"
+)
+
+func TestWebpEncoderNotAvailable(t *testing.T) {
+	if _, err := EncoderForType("webp"); !errors.Is(err, terrors.ErrNoEncoderForType) {
+		t.Errorf("expected %v, got %v", terrors.ErrNoEncoderForType, err)
+	}
+	if _, err := GeneratorFor("webp", ""); err != nil {
+		t.Errorf("expected a generator for webp, got %v", err)
+	}
+}
@@ -64,3 +64,35 @@ func (e JpegEncoder) Types() []string {
 func (e JpegEncoder) MimeType() string {
 	return "image/jpeg"
 }
+
+// WebpEncoder encodes to webp
+type WebpEncoder struct{}
+
+func webpEncoder() (Encoder, error) {
+	return WebpEncoder{}, nil
+}
+
+// Encode encodes to webp
+func (e WebpEncoder) Encode(w io.Writer, img interface{}) error {
+	m, ok := img.(*vips.ImageRef)
+	if !ok {
+		return errors.ErrInvalidType
+	}
+
+	buf, _, err := m.ExportWebp(vips.NewWebpExportParams())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// Types returns the webp suffix
+func (e WebpEncoder) Types() []string {
+	return []string{typeWebp}
+}
+
+// MimeType returns the mimetype for webp files.
+func (e WebpEncoder) MimeType() string {
+	return "image/webp"
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/owncloud/reva/v2/pkg/bytesize"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+)
+
+// Filter selects stored thumbnails, empty fields match all thumbnails.
+type Filter struct {
+	// OlderThan matches thumbnails which were stored before this time
+	OlderThan time.Time
+	Checksum  string
+	// Resolution is formatted as <width>x<height>
+	Resolution string
+}
+
+// Match returns if the entry is selected by the filter. Entries with keys which can't be parsed
+// only match filters without checksum and resolution.
+func (f Filter) Match(e Entry) bool {
+	if !f.OlderThan.IsZero() && !e.ModTime.Before(f.OlderThan) {
+		return false
+	}
+	if f.Checksum == "" && f.Resolution == "" {
+		return true
+	}
+	k, ok := ParseKey(e.Key)
+	if !ok {
+		return false
+	}
+	return (f.Checksum == "" || strings.EqualFold(f.Checksum, k.Checksum)) && (f.Resolution == "" || f.Resolution == k.Resolution)
+}
+
+// Stats sums up the size of thumbnails.
+type Stats struct {
+	Count int64
+	Size  int64
+}
+
+func (s *Stats) add(e Entry) {
+	s.Count++
+	s.Size += e.Size
+}
+
+// Usage describes the stored thumbnails.
+type Usage struct {
+	Stats
+	Oldest time.Time
+	Newest time.Time
+	// ByResolution holds the stats per resolution, thumbnails with unknown keys and the dimensions of sources are not included
+	ByResolution map[string]Stats
+}
+
+// GetUsage walks the storage and sums up the stored thumbnails.
+func GetUsage(s Storage) (Usage, error) {
+	u := Usage{ByResolution: map[string]Stats{}}
+	err := s.Walk(func(e Entry) error {
+		u.add(e)
+		if u.Oldest.IsZero() || e.ModTime.Before(u.Oldest) {
+			u.Oldest = e.ModTime
+		}
+		if e.ModTime.After(u.Newest) {
+			u.Newest = e.ModTime
+		}
+		if k, ok := ParseKey(e.Key); ok && k.Type != TypeSource {
+			r := u.ByResolution[k.Resolution]
+			r.add(e)
+			u.ByResolution[k.Resolution] = r
+		}
+		return nil
+	})
+	return u, err
+}
+
+// Purge deletes all thumbnails matched by the filter. The thumbnails are only counted if dryRun is set.
+func Purge(s Storage, f Filter, dryRun bool) (Stats, error) {
+	var matched []Entry
+	if err := s.Walk(func(e Entry) error {
+		if f.Match(e) {
+			matched = append(matched, e)
+		}
+		return nil
+	}); err != nil {
+		return Stats{}, err
+	}
+
+	var removed Stats
+	for _, e := range matched {
+		if !dryRun {
+			if err := s.Delete(e.Key); err != nil {
+				return removed, err
+			}
+		}
+		removed.add(e)
+	}
+	return removed, nil
+}
+
+// Evict deletes the thumbnails older than maxAge and then the oldest thumbnails until the
+// remaining thumbnails don't exceed maxSize. A maxAge or maxSize of 0 disables the limit.
+func Evict(s Storage, maxSize int64, maxAge time.Duration, now time.Time) (Stats, error) {
+	var (
+		entries []Entry
+		total   int64
+		evicted Stats
+	)
+	err := s.Walk(func(e Entry) error {
+		if maxAge > 0 && e.ModTime.Before(now.Add(-maxAge)) {
+			if err := s.Delete(e.Key); err != nil {
+				return err
+			}
+			evicted.add(e)
+			return nil
+		}
+		if maxSize > 0 {
+			entries = append(entries, e)
+			total += e.Size
+		}
+		return nil
+	})
+	if err != nil || maxSize <= 0 || total <= maxSize {
+		return evicted, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+	for _, e := range entries {
+		if total <= maxSize {
+			break
+		}
+		if err := s.Delete(e.Key); err != nil {
+			return evicted, err
+		}
+		evicted.add(e)
+		total -= e.Size
+	}
+	return evicted, nil
+}
+
+// Evictor periodically evicts thumbnails from a storage.
+type Evictor struct {
+	storage  Storage
+	maxSize  int64
+	maxAge   time.Duration
+	interval time.Duration
+	logger   log.Logger
+	stop     chan struct{}
+}
+
+// NewEvictor creates an Evictor for the eviction configuration, nil is returned if no limit is configured.
+func NewEvictor(s Storage, cfg config.Eviction, logger log.Logger) (*Evictor, error) {
+	var maxSize int64
+	if cfg.MaxSize != "" {
+		b, err := bytesize.Parse(cfg.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		maxSize = int64(b.Bytes())
+	}
+	if maxSize <= 0 && cfg.MaxAge <= 0 {
+		return nil, nil
+	}
+	return &Evictor{
+		storage:  s,
+		maxSize:  maxSize,
+		maxAge:   cfg.MaxAge,
+		interval: cfg.Interval,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Run evicts thumbnails in the configured interval until Close is called.
+// Errors are logged and retried in the next interval.
+func (e *Evictor) Run() error {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.evict()
+		select {
+		case <-e.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close stops Run.
+func (e *Evictor) Close() {
+	close(e.stop)
+}
+
+func (e *Evictor) evict() {
+	evicted, err := Evict(e.storage, e.maxSize, e.maxAge, time.Now())
+	if err != nil {
+		e.logger.Error().Err(err).Int64("count", evicted.Count).Msg("could not evict thumbnails")
+		return
+	}
+	if evicted.Count > 0 {
+		e.logger.Info().Int64("count", evicted.Count).Int64("size", evicted.Size).Msg("evicted thumbnails")
+	}
+}
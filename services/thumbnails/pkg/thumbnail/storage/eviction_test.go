@@ -0,0 +1,139 @@
+package storage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tAssert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+var _now = time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+// newFileSystem returns a storage with thumbnails of the given sizes, the n-th thumbnail is n days old
+func newFileSystem(t *testing.T, keys []string, sizes []int) storage.FileSystem {
+	root := t.TempDir()
+	s := storage.NewFileSystemStorage(config.FileSystemStorage{RootDirectory: root}, log.NopLogger())
+	for i, key := range keys {
+		require.NoError(t, s.Put(key, make([]byte, sizes[i])))
+		mtime := _now.Add(-time.Duration(i) * 24 * time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(root, "files", key), mtime, mtime))
+	}
+	return s
+}
+
+func keys(t *testing.T, s storage.Storage) []string {
+	var k []string
+	require.NoError(t, s.Walk(func(e storage.Entry) error {
+		k = append(k, e.Key)
+		return nil
+	}))
+	return k
+}
+
+var _keys = []string{
+	"aa/bb/cc/32x32.png",
+	"aa/bb/cc/64x64-fill.png",
+	"dd/ee/ff/32x32.jpg",
+	"dd/ee/ff/64x64.png",
+}
+
+func TestEvict(t *testing.T) {
+	tests := map[string]struct {
+		maxSize int64
+		maxAge  time.Duration
+		want    []string
+		evicted storage.Stats
+	}{
+		"no limit":   {want: _keys},
+		"max age":    {maxAge: 36 * time.Hour, want: _keys[:2], evicted: storage.Stats{Count: 2, Size: 70}},
+		"max size":   {maxSize: 35, want: _keys[:2], evicted: storage.Stats{Count: 2, Size: 70}},
+		"within":     {maxSize: 105, want: _keys},
+		"both":       {maxSize: 10, maxAge: 60 * time.Hour, want: _keys[:1], evicted: storage.Stats{Count: 3, Size: 95}},
+		"everything": {maxSize: 1, want: nil, evicted: storage.Stats{Count: 4, Size: 105}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newFileSystem(t, _keys, []int{10, 25, 30, 40})
+			evicted, err := storage.Evict(s, tc.maxSize, tc.maxAge, _now)
+			require.NoError(t, err)
+			tAssert.Equal(t, tc.evicted, evicted)
+			tAssert.ElementsMatch(t, tc.want, keys(t, s))
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := map[string]struct {
+		filter storage.Filter
+		want   []string
+	}{
+		"checksum":   {filter: storage.Filter{Checksum: "aabbcc"}, want: _keys[2:]},
+		"resolution": {filter: storage.Filter{Resolution: "64x64"}, want: []string{_keys[0], _keys[2]}},
+		"older than": {filter: storage.Filter{OlderThan: _now.Add(-time.Hour)}, want: _keys[:1]},
+		"combined":   {filter: storage.Filter{Checksum: "ddeeff", Resolution: "32x32"}, want: []string{_keys[0], _keys[1], _keys[3]}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newFileSystem(t, _keys, []int{1, 1, 1, 1})
+
+			removed, err := storage.Purge(s, tc.filter, true)
+			require.NoError(t, err)
+			tAssert.Len(t, keys(t, s), len(_keys), "dry run must not delete")
+
+			_, err = storage.Purge(s, tc.filter, false)
+			require.NoError(t, err)
+			tAssert.ElementsMatch(t, tc.want, keys(t, s))
+			tAssert.Equal(t, int64(len(_keys)-len(tc.want)), removed.Count)
+		})
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	s := newFileSystem(t, _keys, []int{10, 25, 30, 40})
+	u, err := storage.GetUsage(s)
+	require.NoError(t, err)
+
+	assert := tAssert.New(t)
+	assert.Equal(storage.Stats{Count: 4, Size: 105}, u.Stats)
+	assert.Equal(map[string]storage.Stats{"32x32": {Count: 2, Size: 40}, "64x64": {Count: 2, Size: 65}}, u.ByResolution)
+	assert.True(u.Newest.Equal(_now))
+	assert.True(u.Oldest.Equal(_now.Add(-72 * time.Hour)))
+}
+
+func TestFileSystem_Delete(t *testing.T) {
+	s := newFileSystem(t, _keys[:2], []int{1, 1})
+	require.NoError(t, s.Delete(_keys[0]))
+	tAssert.False(t, s.Stat(_keys[0]))
+	tAssert.True(t, s.Stat(_keys[1]))
+	require.NoError(t, s.Delete(_keys[1]))
+	require.NoError(t, s.Delete(_keys[1]), "deleting a missing thumbnail is no error")
+	tAssert.Empty(t, keys(t, s))
+}
+
+func TestParseKey(t *testing.T) {
+	tests := map[string]struct {
+		key  string
+		want storage.Key
+		ok   bool
+	}{
+		"plain":          {key: "12/0E/A8A2/32x32.png", want: storage.Key{Checksum: "120EA8A2", Resolution: "32x32", Type: "png"}, ok: true},
+		"characteristic": {key: "12/0E/A8A2/2x2-fill.jpg", want: storage.Key{Checksum: "120EA8A2", Resolution: "2x2", Characteristic: "fill", Type: "jpg"}, ok: true},
+		"no type":        {key: "12/0E/A8A2/32x32"},
+		"no resolution":  {key: "12/0E/A8A2/abc.png"},
+		"short":          {key: "12/A8A2/32x32.png"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			k, ok := storage.ParseKey(tc.key)
+			tAssert.Equal(t, tc.ok, ok)
+			tAssert.Equal(t, tc.want, k)
+		})
+	}
+}
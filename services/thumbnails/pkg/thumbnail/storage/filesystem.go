@@ -4,7 +4,6 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -103,16 +102,51 @@ func (s FileSystem) Put(key string, img []byte) error {
 //
 // The key also represents the path to the thumbnail in the filesystem under the configured root directory.
 func (s FileSystem) BuildKey(r Request) string {
-	checksum := r.Checksum
-	filetype := r.Types[0]
-
-	parts := []string{strconv.Itoa(r.Resolution.Dx()), "x", strconv.Itoa(r.Resolution.Dy())}
+	return filepath.FromSlash(buildKey(r))
+}
 
-	if r.Characteristic != "" {
-		parts = append(parts, "-", r.Characteristic)
+// Delete removes the thumbnail and the directories which became empty
+func (s FileSystem) Delete(key string) error {
+	files := filepath.Join(s.root, filesDir)
+	img := filepath.Join(files, key)
+	if err := os.Remove(img); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return errors.Wrapf(err, "could not delete thumbnail \"%s\"", key)
 	}
 
-	parts = append(parts, ".", filetype)
+	// removing a directory fails if it isn't empty, which ends the cleanup
+	for dir := filepath.Dir(img); dir != files && strings.HasPrefix(dir, files); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			break
+		}
+	}
+	return nil
+}
 
-	return filepath.Join(checksum[:2], checksum[2:4], checksum[4:], strings.Join(parts, ""))
+// Walk calls fn for every thumbnail in the file system, temporary files of unfinished uploads are skipped
+func (s FileSystem) Walk(fn func(Entry) error) error {
+	files := filepath.Join(s.root, filesDir)
+	return filepath.WalkDir(files, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// nothing has been stored yet or the file was deleted while walking
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), "tmpthumb") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(files, p)
+		if err != nil {
+			return err
+		}
+		return fn(Entry{Key: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime()})
+	})
 }
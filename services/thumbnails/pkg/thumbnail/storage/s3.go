@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/pkg/errors"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+)
+
+// NewS3Storage creates a new instance of S3
+func NewS3Storage(cfg config.S3Storage, logger log.Logger) (S3, error) {
+	endpoint, secure := cfg.Endpoint, true
+	if u, err := url.Parse(cfg.Endpoint); err == nil && u.Host != "" {
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: secure,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return S3{}, errors.Wrap(err, "could not create the s3 client")
+	}
+
+	return S3{
+		client: client,
+		bucket: cfg.Bucket,
+		prefix: strings.Trim(cfg.Prefix, "/"),
+		logger: logger,
+	}, nil
+}
+
+// S3 represents a storage for the thumbnails using an s3 compatible object store.
+// Multiple instances of the service can share the thumbnails by using the same bucket.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+	logger log.Logger
+}
+
+// Stat returns if an object for the given key exists in the bucket
+func (s S3) Stat(key string) bool {
+	if _, err := s.client.StatObject(context.Background(), s.bucket, s.objectName(key), minio.StatObjectOptions{}); err != nil {
+		if !isNotFound(err) {
+			s.logger.Debug().Err(err).Str("key", key).Msg("could not stat thumbnail in store")
+		}
+		return false
+	}
+	return true
+}
+
+// Get returns the object content for the given key
+func (s S3) Get(key string) ([]byte, error) {
+	obj, err := s.client.GetObject(context.Background(), s.bucket, s.objectName(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	// errors of the request are returned when reading
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		if !isNotFound(err) {
+			s.logger.Debug().Err(err).Str("key", key).Msg("could not load thumbnail from store")
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// Put stores image data in the bucket for the given key
+func (s S3) Put(key string, img []byte) error {
+	_, err := s.client.PutObject(context.Background(), s.bucket, s.objectName(key), bytes.NewReader(img), int64(len(img)), minio.PutObjectOptions{
+		ContentType: mimeType(key),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not upload thumbnail \"%s\"", key)
+	}
+	return nil
+}
+
+// BuildKey generates the unique key for a thumbnail, see FileSystem.BuildKey for the structure.
+func (s S3) BuildKey(r Request) string {
+	return buildKey(r)
+}
+
+// Delete removes the object for the given key
+func (s S3) Delete(key string) error {
+	if err := s.client.RemoveObject(context.Background(), s.bucket, s.objectName(key), minio.RemoveObjectOptions{}); err != nil && !isNotFound(err) {
+		return errors.Wrapf(err, "could not delete thumbnail \"%s\"", key)
+	}
+	return nil
+}
+
+// Walk calls fn for every thumbnail in the bucket below the configured prefix
+func (s S3) Walk(fn func(Entry) error) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	// stops the listing when fn returns early
+	defer cancel()
+
+	prefix := ""
+	if s.prefix != "" {
+		prefix = s.prefix + "/"
+	}
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return errors.Wrap(obj.Err, "could not list thumbnails")
+		}
+		if err := fn(Entry{Key: strings.TrimPrefix(obj.Key, prefix), Size: obj.Size, ModTime: obj.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s S3) objectName(key string) string {
+	return path.Join(s.prefix, key)
+}
+
+func isNotFound(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey"
+}
+
+// mimeType returns the content type of the object by the file type of the key
+func mimeType(key string) string {
+	switch path.Ext(key) {
+	case ".png":
+		return "image/png"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".webp":
+		return "image/webp"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,210 @@
+package storage_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tAssert "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/thumbnail/storage"
+)
+
+type fakeObject struct {
+	data    []byte
+	modTime time.Time
+}
+
+// fakeS3 is a stand-in for an s3 server which supports the requests used by the storage,
+// the requests aren't authenticated.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string]fakeObject
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != f.bucket {
+		f.error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, err := readBody(r)
+		if err != nil {
+			f.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		f.objects[key] = fakeObject{data: data, modTime: time.Now()}
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			f.error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", obj.modTime.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(obj.data)
+		}
+	default:
+		f.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	result := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []content
+	}{Name: f.bucket, Prefix: prefix}
+	for k, o := range f.objects {
+		if strings.HasPrefix(k, prefix) {
+			result.Contents = append(result.Contents, content{Key: k, LastModified: o.modTime.UTC().Format(time.RFC3339), ETag: `"etag"`, Size: len(o.data)})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(result)
+}
+
+func (f *fakeS3) error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+// readBody reads the request body, uploads over http use the aws-chunked encoding
+func readBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return data, nil
+		}
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk[:n]...)
+	}
+}
+
+func newS3(t *testing.T, prefix string) (storage.S3, *fakeS3) {
+	fake := &fakeS3{bucket: "thumbnails", objects: map[string]fakeObject{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	s, err := storage.NewS3Storage(config.S3Storage{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		AccessKey: "access",
+		SecretKey: "secret",
+		Bucket:    "thumbnails",
+		Prefix:    prefix,
+	}, log.NopLogger())
+	require.NoError(t, err)
+	return s, fake
+}
+
+func TestS3(t *testing.T) {
+	assert := tAssert.New(t)
+	s, fake := newS3(t, "cache")
+
+	key := "12/0E/A8A25E5D487BF68B5F7096440019/32x32.png"
+	assert.False(s.Stat(key))
+	_, err := s.Get(key)
+	assert.Error(err)
+
+	require.NoError(t, s.Put(key, []byte("thumbnail")))
+	assert.Contains(fake.objects, "cache/"+key)
+	assert.True(s.Stat(key))
+	img, err := s.Get(key)
+	assert.NoError(err)
+	assert.Equal([]byte("thumbnail"), img)
+
+	// objects outside of the prefix are not walked
+	fake.objects["other/file"] = fakeObject{data: []byte("other"), modTime: time.Now()}
+	var entries []storage.Entry
+	assert.NoError(s.Walk(func(e storage.Entry) error {
+		entries = append(entries, e)
+		return nil
+	}))
+	if assert.Len(entries, 1) {
+		assert.Equal(key, entries[0].Key)
+		assert.Equal(int64(len("thumbnail")), entries[0].Size)
+	}
+
+	assert.NoError(s.Delete(key))
+	assert.False(s.Stat(key))
+	assert.NoError(s.Delete(key))
+	assert.Contains(fake.objects, "other/file")
+}
+
+func TestS3_BuildKey(t *testing.T) {
+	s, _ := newS3(t, "")
+	fs := storage.FileSystem{}
+	r := storage.Request{
+		Checksum: "120EA8A25E5D487BF68B5F7096440019",
+		Types:    []string{"png"},
+	}
+	tAssert.Equal(t, fs.BuildKey(r), s.BuildKey(r))
+	tAssert.Equal(t, "12/0E/A8A25E5D487BF68B5F7096440019/0x0.png", s.BuildKey(r))
+}
+
+func TestS3_Large(t *testing.T) {
+	s, fake := newS3(t, "")
+	// larger than a single chunk of the streaming upload
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	require.NoError(t, s.Put("ab/cd/ef/1x1.png", data))
+	tAssert.Equal(t, data, fake.objects["ab/cd/ef/1x1.png"].data)
+}
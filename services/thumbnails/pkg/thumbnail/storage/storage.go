@@ -1,9 +1,20 @@
 package storage
 
 import (
+	"fmt"
 	"image"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/owncloud/ocis/v2/ocis-pkg/log"
+	"github.com/owncloud/ocis/v2/services/thumbnails/pkg/config"
 )
 
+// TypeSource is the type of the keys which hold the dimensions of a source instead of a thumbnail.
+const TypeSource = "source"
+
 // Request combines different attributes needed for storage operations.
 type Request struct {
 	// The checksum of the source file
@@ -23,10 +34,77 @@ type Request struct {
 	Characteristic string
 }
 
+// Entry describes a stored thumbnail.
+type Entry struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
 // Storage defines the interface for a thumbnail store.
 type Storage interface {
 	Stat(key string) bool
 	Get(key string) ([]byte, error)
 	Put(key string, img []byte) error
 	BuildKey(r Request) string
+	// Delete removes the thumbnail, deleting a missing thumbnail is not an error.
+	Delete(key string) error
+	// Walk calls fn for every stored thumbnail and stops at the first error returned by fn.
+	Walk(fn func(Entry) error) error
+}
+
+// New returns the storage selected in the configuration.
+func New(cfg config.Thumbnail, logger log.Logger) (Storage, error) {
+	switch cfg.Storage {
+	case "", "filesystem":
+		return NewFileSystemStorage(cfg.FileSystemStorage, logger), nil
+	case "s3":
+		return NewS3Storage(cfg.S3Storage, logger)
+	default:
+		return nil, fmt.Errorf("unknown thumbnail storage %s", cfg.Storage)
+	}
+}
+
+// Key holds the parts of a key built by BuildKey.
+type Key struct {
+	Checksum string
+	// Resolution is formatted as <width>x<height>
+	Resolution     string
+	Characteristic string
+	Type           string
+}
+
+// ParseKey splits a key into its parts, ok is false if the key wasn't built by BuildKey.
+func ParseKey(key string) (k Key, ok bool) {
+	parts := strings.Split(key, "/")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 2 || parts[2] == "" {
+		return Key{}, false
+	}
+	k.Checksum = parts[0] + parts[1] + parts[2]
+
+	name, typ, found := strings.Cut(parts[3], ".")
+	if !found || typ == "" {
+		return Key{}, false
+	}
+	k.Type = typ
+	k.Resolution, k.Characteristic, _ = strings.Cut(name, "-")
+
+	var w, h int
+	if n, err := fmt.Sscanf(k.Resolution, "%dx%d", &w, &h); err != nil || n != 2 || fmt.Sprintf("%dx%d", w, h) != k.Resolution {
+		return Key{}, false
+	}
+	return k, true
+}
+
+// buildKey generates the key described at FileSystem.BuildKey, the parts are separated by slashes.
+func buildKey(r Request) string {
+	parts := []string{strconv.Itoa(r.Resolution.Dx()), "x", strconv.Itoa(r.Resolution.Dy())}
+
+	if r.Characteristic != "" {
+		parts = append(parts, "-", r.Characteristic)
+	}
+
+	parts = append(parts, ".", r.Types[0])
+
+	return path.Join(r.Checksum[:2], r.Checksum[2:4], r.Checksum[4:], strings.Join(parts, ""))
 }
@@ -2,6 +2,7 @@ package thumbnail
 
 import (
 	"bytes"
+	"fmt"
 	"image"
 	"mime"
 
@@ -20,12 +21,23 @@ var (
 		"image/bmp":                         {},
 		"image/x-ms-bmp":                    {},
 		"image/tiff":                        {},
+		"image/webp":                        {},
 		"text/plain":                        {},
 		"audio/flac":                        {},
 		"audio/mpeg":                        {},
 		"audio/ogg":                         {},
 		"application/vnd.geogebra.slides":   {},
 		"application/vnd.geogebra.pinboard": {},
+		"application/pdf":                   {},
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   {},
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         {},
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation": {},
+		"application/vnd.oasis.opendocument.text":                                   {},
+		"application/vnd.oasis.opendocument.spreadsheet":                            {},
+		"application/vnd.oasis.opendocument.presentation":                           {},
+		"application/vnd.oasis.opendocument.graphics":                               {},
+		"video/mp4":  {},
+		"video/webm": {},
 	}
 )
 
@@ -92,17 +104,31 @@ func (s SimpleManager) Generate(r Request, img interface{}) (string, error) {
 		return "", err
 	}
 
-	k := s.storage.BuildKey(mapToStorageRequest(r))
+	k := s.storage.BuildKey(mapToStorageRequest(r, match))
 	if err := s.storage.Put(k, buf.Bytes()); err != nil {
 		s.logger.Error().Err(err).Msg("could not store thumbnail")
 		return "", err
 	}
+	// without the dimensions of the source the thumbnail can't be found by CheckThumbnail,
+	// it is generated again for the next request
+	if err := s.storage.Put(sourceKey(s.storage, r.Checksum), []byte(fmt.Sprintf("%dx%d", inputDimensions.Dx(), inputDimensions.Dy()))); err != nil {
+		s.logger.Error().Err(err).Msg("could not store the source dimensions")
+	}
 	return k, nil
 }
 
-// CheckThumbnail checks if a thumbnail with the requested attributes exists.
+// CheckThumbnail checks if a thumbnail with the requested attributes exists. Thumbnails are stored
+// under the resolution which matched the request, it depends on the dimensions of the source.
 func (s SimpleManager) CheckThumbnail(r Request) (string, bool) {
-	k := s.storage.BuildKey(mapToStorageRequest(r))
+	b, err := s.storage.Get(sourceKey(s.storage, r.Checksum))
+	if err != nil {
+		return "", false
+	}
+	inputDimensions, err := ParseResolution(string(b))
+	if err != nil {
+		return "", false
+	}
+	k := s.storage.BuildKey(mapToStorageRequest(r, s.resolutions.ClosestMatch(r.Resolution, inputDimensions)))
 	return k, s.storage.Stat(k)
 }
 
@@ -111,15 +137,24 @@ func (s SimpleManager) GetThumbnail(key string) ([]byte, error) {
 	return s.storage.Get(key)
 }
 
-func mapToStorageRequest(r Request) storage.Request {
+func mapToStorageRequest(r Request, resolution image.Rectangle) storage.Request {
 	return storage.Request{
 		Checksum:       r.Checksum,
-		Resolution:     r.Resolution,
+		Resolution:     resolution,
 		Types:          r.Encoder.Types(),
 		Characteristic: r.Generator.ProcessorID(),
 	}
 }
 
+// sourceKey returns the key of the dimensions of the source with the checksum. The key is removed
+// together with the thumbnails of the source when the cache is purged by checksum.
+func sourceKey(s storage.Storage, checksum string) string {
+	return s.BuildKey(storage.Request{
+		Checksum: checksum,
+		Types:    []string{storage.TypeSource},
+	})
+}
+
 // IsMimeTypeSupported validate if the mime type is supported
 func IsMimeTypeSupported(m string) bool {
 	mimeType, _, err := mime.ParseMediaType(m)
@@ -0,0 +1,10 @@
+//go:build enable_vips
+
+package thumbnail
+
+func init() {
+	// heic and avif images are decoded by libvips, it needs to be built with libheif
+	for _, m := range []string{"image/heic", "image/heif", "image/avif"} {
+		SupportedMimeTypes[m] = struct{}{}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/owncloud/ocis/v2/ocis-pkg/version"
 	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	searchevent "github.com/owncloud/ocis/v2/services/search/pkg/event"
 	"github.com/owncloud/ocis/v2/services/userlog/pkg/config"
 	"github.com/owncloud/ocis/v2/services/userlog/pkg/config/parser"
 	"github.com/owncloud/ocis/v2/services/userlog/pkg/logging"
@@ -47,6 +48,9 @@ var _registeredEvents = []events.Unmarshaller{
 	events.ShareExpired{},
 	events.OCMCoreShareCreated{},
 	events.OCMCoreShareDelete{},
+
+	// search related
+	searchevent.SavedSearchMatched{},
 }
 
 // Server is the entrypoint for the server command.
@@ -18,6 +18,7 @@ import (
 	collaboration "github.com/cs3org/go-cs3apis/cs3/sharing/collaboration/v1beta1"
 	storageprovider "github.com/cs3org/go-cs3apis/cs3/storage/provider/v1beta1"
 	"github.com/owncloud/ocis/v2/ocis-pkg/l10n"
+	searchevent "github.com/owncloud/ocis/v2/services/search/pkg/event"
 	"github.com/owncloud/reva/v2/pkg/events"
 	"github.com/owncloud/reva/v2/pkg/rgrpc/todo/pool"
 	"github.com/owncloud/reva/v2/pkg/storagespace"
@@ -121,6 +122,10 @@ func (c *Converter) ConvertEvent(eventid string, event interface{}) (OC10Notific
 		return c.omcShareCreatedMessage(ev, eventid)
 	case events.OCMCoreShareDelete:
 		return c.omcShareDeleteMessage(ev, eventid)
+
+	// search related
+	case searchevent.SavedSearchMatched:
+		return c.savedSearchMessage(eventid, SavedSearchMatched, ev)
 	}
 }
 
@@ -390,6 +395,40 @@ func (c *Converter) policiesMessage(eventid string, nt NotificationTemplate, exe
 	}, nil
 }
 
+func (c *Converter) savedSearchMessage(eventid string, nt NotificationTemplate, ev searchevent.SavedSearchMatched) (OC10Notification, error) {
+	subj, subjraw, msg, msgraw, err := composeMessage(nt, c.locale, c.defaultLanguage, c.translationPath, map[string]interface{}{
+		"resourcename": ev.ResourceName,
+		"searchname":   ev.SavedSearchName,
+	})
+	if err != nil {
+		return OC10Notification{}, err
+	}
+
+	dets := map[string]interface{}{
+		"resource": map[string]string{
+			"id":   storagespace.FormatResourceID(ev.ResourceID),
+			"name": ev.ResourceName,
+		},
+		"savedSearch": map[string]string{
+			"id":   ev.SavedSearchID,
+			"name": ev.SavedSearchName,
+		},
+	}
+
+	return OC10Notification{
+		EventID:        eventid,
+		Service:        c.serviceName,
+		Timestamp:      ev.Timestamp.Format(time.RFC3339Nano),
+		ResourceID:     storagespace.FormatResourceID(ev.ResourceID),
+		ResourceType:   _resourceTypeResource,
+		Subject:        subj,
+		SubjectRaw:     subjraw,
+		Message:        msg,
+		MessageRaw:     msgraw,
+		MessageDetails: dets,
+	}, nil
+}
+
 func (c *Converter) deprovisionMessage(nt NotificationTemplate, deproDate string) (OC10Notification, error) {
 	subj, subjraw, msg, msgraw, err := composeMessage(nt, c.locale, c.defaultLanguage, c.translationPath, map[string]interface{}{
 		"date": deproDate,
@@ -24,6 +24,7 @@ import (
 	ehmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/eventhistory/v0"
 	ehsvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/eventhistory/v0"
 	settingssvc "github.com/owncloud/ocis/v2/protogen/gen/ocis/services/settings/v0"
+	searchevent "github.com/owncloud/ocis/v2/services/search/pkg/event"
 	"github.com/owncloud/ocis/v2/services/userlog/pkg/config"
 )
 
@@ -198,6 +199,10 @@ func (ul *UserlogService) processEvent(event events.Event) {
 	case events.OCMCoreShareDelete:
 		executant = e.Sharer
 		users = append(users, e.Grantee.GetOpaqueId())
+
+	// search related
+	case searchevent.SavedSearchMatched:
+		users = append(users, e.UserID.GetOpaqueId())
 	}
 	if err != nil {
 		// TODO: Find out why this errors on ci pipeline
@@ -244,7 +244,7 @@ func (g Webdav) SpacesThumbnail(w http.ResponseWriter, r *http.Request) {
 	fullPath := filepath.Join(tr.Identifier, tr.Filepath)
 	rsp, err := g.thumbnailsClient.GetThumbnail(r.Context(), &thumbnailssvc.GetThumbnailRequest{
 		Filepath:      strings.TrimLeft(tr.Filepath, "/"),
-		ThumbnailType: extensionToThumbnailType(strings.TrimLeft(tr.Extension, ".")),
+		ThumbnailType: thumbnailType(r, tr.Extension),
 		Width:         tr.Width,
 		Height:        tr.Height,
 		Processor:     tr.Processor,
@@ -346,7 +346,7 @@ func (g Webdav) Thumbnail(w http.ResponseWriter, r *http.Request) {
 	fullPath := filepath.Join(templates.WithUser(user, g.config.WebdavNamespace), tr.Filepath)
 	rsp, err := g.thumbnailsClient.GetThumbnail(r.Context(), &thumbnailssvc.GetThumbnailRequest{
 		Filepath:      strings.TrimLeft(tr.Filepath, "/"),
-		ThumbnailType: extensionToThumbnailType(strings.TrimLeft(tr.Extension, ".")),
+		ThumbnailType: thumbnailType(r, tr.Extension),
 		Width:         tr.Width,
 		Height:        tr.Height,
 		Processor:     tr.Processor,
@@ -400,7 +400,7 @@ func (g Webdav) PublicThumbnail(w http.ResponseWriter, r *http.Request) {
 
 	rsp, err := g.thumbnailsClient.GetThumbnail(r.Context(), &thumbnailssvc.GetThumbnailRequest{
 		Filepath:      strings.TrimLeft(tr.Filepath, "/"),
-		ThumbnailType: extensionToThumbnailType(strings.TrimLeft(tr.Extension, ".")),
+		ThumbnailType: thumbnailType(r, tr.Extension),
 		Width:         tr.Width,
 		Height:        tr.Height,
 		Processor:     tr.Processor,
@@ -449,7 +449,7 @@ func (g Webdav) PublicThumbnailHead(w http.ResponseWriter, r *http.Request) {
 
 	_, err = g.thumbnailsClient.GetThumbnail(r.Context(), &thumbnailssvc.GetThumbnailRequest{
 		Filepath:      strings.TrimLeft(tr.Filepath, "/"),
-		ThumbnailType: extensionToThumbnailType(strings.TrimLeft(tr.Extension, ".")),
+		ThumbnailType: thumbnailType(r, tr.Extension),
 		Width:         tr.Width,
 		Height:        tr.Height,
 		Processor:     tr.Processor,
@@ -519,14 +519,49 @@ func (g Webdav) sendThumbnailResponse(rsp *thumbnailssvc.GetThumbnailResponse, w
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", dlRsp.Header.Get("Content-Type"))
+	// the type of the thumbnail depends on the accept header
+	w.Header().Add("Vary", "Accept")
+	w.WriteHeader(http.StatusOK)
 	_, err = io.Copy(w, dlRsp.Body)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to write thumbnail to response writer")
 	}
 }
 
+// thumbnailType returns the requested thumbnail type, webp is requested if the client accepts it and the
+// type of the file extension otherwise
+func thumbnailType(r *http.Request, ext string) thumbnailsmsg.ThumbnailType {
+	if acceptsMediaType(r.Header.Values("Accept"), "image/webp") {
+		return thumbnailsmsg.ThumbnailType_WEBP
+	}
+	return extensionToThumbnailType(strings.TrimLeft(ext, "."))
+}
+
+// acceptsMediaType checks if the media type is explicitly listed in the accept headers with a quality
+// above zero, wildcards like image/* are ignored
+func acceptsMediaType(accept []string, mediaType string) bool {
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			params := strings.Split(mediaRange, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), mediaType) {
+				continue
+			}
+			q := 1.0
+			for _, param := range params[1:] {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(k, "q") {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						q = f
+					}
+				}
+			}
+			return q > 0
+		}
+	}
+	return false
+}
+
 func extensionToThumbnailType(ext string) thumbnailsmsg.ThumbnailType {
 	switch strings.ToUpper(ext) {
 	case "GIF":
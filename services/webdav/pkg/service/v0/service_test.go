@@ -3,6 +3,8 @@ package svc
 import (
 	"net/http"
 	"testing"
+
+	thumbnailsmsg "github.com/owncloud/ocis/v2/protogen/gen/ocis/messages/thumbnails/v0"
 )
 
 func TestNewErrResponseException(t *testing.T) {
@@ -48,3 +50,31 @@ func TestNewErrResponseEmptyMessage(t *testing.T) {
 		t.Errorf("unexpected exception %q", rsp.Exception)
 	}
 }
+
+func TestThumbnailType(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   []string
+		ext      string
+		expected thumbnailsmsg.ThumbnailType
+	}{
+		{"no accept header", nil, ".png", thumbnailsmsg.ThumbnailType_PNG},
+		{"extension without accept", nil, ".jpeg", thumbnailsmsg.ThumbnailType_JPG},
+		{"webp accepted", []string{"image/avif,image/webp,*/*"}, ".png", thumbnailsmsg.ThumbnailType_WEBP},
+		{"webp in a second header", []string{"image/png", "image/webp;q=0.8"}, ".gif", thumbnailsmsg.ThumbnailType_WEBP},
+		{"webp with quality zero", []string{"image/webp;q=0, image/png"}, ".png", thumbnailsmsg.ThumbnailType_PNG},
+		{"wildcards only", []string{"image/*,*/*;q=0.8"}, ".gif", thumbnailsmsg.ThumbnailType_GIF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			for _, a := range tt.accept {
+				r.Header.Add("Accept", a)
+			}
+			if got := thumbnailType(r, tt.ext); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}